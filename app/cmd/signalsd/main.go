@@ -20,6 +20,7 @@ import (
 	"github.com/information-sharing-networks/signalsd/app/internal/schemas"
 	"github.com/information-sharing-networks/signalsd/app/internal/server"
 	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/information-sharing-networks/signalsd/app/internal/signingkeys"
 	"github.com/information-sharing-networks/signalsd/app/internal/version"
 	"github.com/jackc/pgx/v5/pgxpool"
 
@@ -109,6 +110,26 @@ import (
 //	@description	**Response Bodies**: All date/time fields in JSON responses use RFC3339 format (ISO 8601):
 //	@description	- Example: "2025-06-03T13:47:47.331787+01:00"
 //	@description
+//	@description	## Webhooks
+//	@description
+//	@description	Accounts with read access to an ISN can register webhooks to receive new signals as they are stored (instead of polling the search endpoints).
+//	@description	Each delivery is a POST request signed with the `Signalsd-Signature` header - see the **Create webhook subscription** endpoint for details.
+//	@description
+//	@description	## Signal stream
+//	@description
+//	@description	ISN members can also receive new signals over a long-lived [server-sent events](https://html.spec.whatwg.org/multipage/server-sent-events.html) connection - see the **Signal stream** endpoint.
+//	@description	Clients that reconnect with the `Last-Event-ID` header resume from the last event they received.
+//	@description
+//	@description	## Signal changes feed
+//	@description
+//	@description	Systems that keep a local copy of an ISN should use the **Signal changes feed** endpoint rather than date-based searches.
+//	@description	The feed returns new signal versions and withdrawals in the order they were made, and the cursor supplied with each response ensures no changes are missed.
+//	@description
+//	@description	## Signal search exports
+//	@description
+//	@description	The signal search endpoints can stream all the matching signals as NDJSON (`Accept: application/x-ndjson`) or CSV (`Accept: text/csv`).
+//	@description	CSV exports have a column for each field in the signal type schema, so they can be opened directly in a spreadsheet.
+//	@description
 //	@description	# UI Endpoints
 //	@description
 //	@description	UI endpoints serve the browser-based management interface.
@@ -333,7 +354,7 @@ func run(mode string) error {
 
 	appLogger.Info("Loaded public ISN cache", slog.Int("count", publicIsnCache.Len()))
 
-	// set up the router cache - holds compiled ISN routing config, polled for changes every CachePollInterval
+	// set up the router cache - holds compiled ISN routing config, reloaded on cache invalidation notifications and polled for changes every CachePollInterval
 	signalRouterCache := router.NewCache(queries)
 	if err := signalRouterCache.Load(dbCtx); err != nil {
 		appLogger.Error("Failed to load router cache", slog.String("error", err.Error()))
@@ -346,8 +367,21 @@ func run(mode string) error {
 		appLogger.Warn("rate limiting disabled")
 	}
 
+	// set up the access token signing keys - creates the first key (or rotates the keys) when an asymmetric signing algorithm is configured
+	// and loads the keys used to verify tokens. The keys are checked for rotation every SigningKeyRotationCheckInterval
+	signingKeys, err := signingkeys.NewKeySet(pool, queries, cfg.SecretKey, cfg.AccessTokenSigningAlg, cfg.SigningKeyRotationInterval)
+	if err != nil {
+		appLogger.Error("Failed to set up signing keys", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	if err := signingKeys.Rotate(dbCtx); err != nil {
+		appLogger.Error("Failed to rotate signing keys", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	appLogger.Info("Loaded access token signing keys", slog.String("algorithm", signingKeys.Algorithm()), slog.Int("count", signingKeys.Len()))
+
 	// set up the authentication service (this provides functions for managing logins, tokens and auth middleware)
-	authService := auth.NewAuthService(cfg.SecretKey, cfg.Environment, queries)
+	authService := auth.NewAuthService(cfg.SecretKey, cfg.Environment, queries, signingKeys, cfg.PublicBaseURL, cfg.TLSClientCertHeader, cfg.TLSClientCertTrustedProxies)
 
 	// run the http server
 	appLogger.Info("service mode", slog.String("mode", cfg.ServiceMode))
@@ -25,6 +25,12 @@ const (
 	// ErrCodeForbidden used when the authenticated account lacks the required role or permission (403)
 	ErrCodeForbidden ErrorCode = "forbidden"
 
+	// ErrCodeIdempotencyKeyInProgress used when a request is repeated while the original request with the same idempotency key is still being processed (409)
+	ErrCodeIdempotencyKeyInProgress ErrorCode = "idempotency_key_in_progress"
+
+	// ErrCodeIdempotencyKeyReused used when an idempotency key is supplied with a different request to the one it was first used with (422)
+	ErrCodeIdempotencyKeyReused ErrorCode = "idempotency_key_reused"
+
 	// ErrCodeInvalidCorrelationID used when a signal's correlation_id can't be found in the specified ISN
 	ErrCodeInvalidCorrelationID ErrorCode = "invalid_correlation_id"
 
@@ -34,6 +40,9 @@ const (
 	// ErrCodeInvalidRequest used when request parameters are present but semantically invalid
 	ErrCodeInvalidRequest ErrorCode = "invalid_request"
 
+	// ErrCodeInvalidScope used when the scope requested on a token request is malformed or exceeds the access granted to the account
+	ErrCodeInvalidScope ErrorCode = "invalid_scope"
+
 	// ErrCodeInvalidURLParam used when a URL path parameter cannot be parsed (e.g. malformed UUID)
 	ErrCodeInvalidURLParam ErrorCode = "invalid_url_param"
 
@@ -94,6 +94,16 @@ func ResourceInUse(message string, err error) *HTTPError {
 	return &HTTPError{Status: http.StatusConflict, Code: ErrCodeResourceInUse, Message: message, Err: err}
 }
 
+// IdempotencyKeyInProgress responds with 409 + idempotency_key_in_progress.
+func IdempotencyKeyInProgress(message string, err error) *HTTPError {
+	return &HTTPError{Status: http.StatusConflict, Code: ErrCodeIdempotencyKeyInProgress, Message: message, Err: err}
+}
+
+// IdempotencyKeyReused responds with 422 + idempotency_key_reused.
+func IdempotencyKeyReused(message string, err error) *HTTPError {
+	return &HTTPError{Status: http.StatusUnprocessableEntity, Code: ErrCodeIdempotencyKeyReused, Message: message, Err: err}
+}
+
 // OAuthError is returned by OAuth 2.0 endpoints (/oauth/token, /oauth/revoke).
 // responses.Render writes it as an RFC 6749 §5.2 compliant response
 type OAuthError struct {
@@ -143,6 +153,11 @@ func OAuthInvalidGrant(description string, appCode ErrorCode, err error) *OAuthE
 	return &OAuthError{Status: http.StatusBadRequest, OAuthCode: "invalid_grant", Description: description, AppCode: appCode, Err: err}
 }
 
+// OAuthInvalidScope responds with 400 + invalid_scope.
+func OAuthInvalidScope(description string, appCode ErrorCode, err error) *OAuthError {
+	return &OAuthError{Status: http.StatusBadRequest, OAuthCode: "invalid_scope", Description: description, AppCode: appCode, Err: err}
+}
+
 // OAuthUnsupportedGrantType responds with 400 + unsupported_grant_type.
 func OAuthUnsupportedGrantType(description string, appCode ErrorCode, err error) *OAuthError {
 	return &OAuthError{Status: http.StatusBadRequest, OAuthCode: "unsupported_grant_type", Description: description, AppCode: appCode, Err: err}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/netip"
 	"strings"
 	"time"
 
@@ -16,26 +17,45 @@ import (
 	"github.com/google/uuid"
 	"github.com/information-sharing-networks/signalsd/app/internal/database"
 	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/information-sharing-networks/signalsd/app/internal/signingkeys"
 	"github.com/jackc/pgx/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthService struct {
-	// secretKey is the key used to sign access tokens (set by the SECRET_KEY environment variable)
+	// secretKey is the key used to sign HS256 access tokens (set by the SECRET_KEY environment variable)
 	secretKey string
 
+	// signingKeys are the keys used to sign access tokens when ACCESS_TOKEN_SIGNING_ALG is RS256, ES256 or EdDSA
+	// (nil when the service only uses HS256)
+	signingKeys *signingkeys.KeySet
+
 	// environment is the server environment ( prod, test etc - set by the ENVIRONMENT environment variable)
 	environment string
 
 	// queries is the sqlc generated database queries
 	queries *database.Queries
+
+	// publicBaseURL is the public URL of the service (PUBLIC_BASE_URL) - private_key_jwt client assertions must use this, or an endpoint on it, as the audience
+	publicBaseURL string
+
+	// tlsClientCertHeader is the header used by the TLS terminating proxy to forward client certificates (TLS_CLIENT_CERT_HEADER).
+	// Mutual TLS client authentication is disabled when this is empty.
+	tlsClientCertHeader string
+
+	// tlsClientCertTrustedProxies are the proxy addresses the tlsClientCertHeader is accepted from (TLS_CLIENT_CERT_TRUSTED_PROXIES).
+	tlsClientCertTrustedProxies []netip.Prefix
 }
 
-func NewAuthService(secretKey string, environment string, queries *database.Queries) *AuthService {
+func NewAuthService(secretKey string, environment string, queries *database.Queries, signingKeys *signingkeys.KeySet, publicBaseURL string, tlsClientCertHeader string, tlsClientCertTrustedProxies []netip.Prefix) *AuthService {
 	return &AuthService{
-		secretKey:   secretKey,
-		environment: environment,
-		queries:     queries,
+		secretKey:                   secretKey,
+		signingKeys:                 signingKeys,
+		environment:                 environment,
+		queries:                     queries,
+		publicBaseURL:               publicBaseURL,
+		tlsClientCertHeader:         tlsClientCertHeader,
+		tlsClientCertTrustedProxies: tlsClientCertTrustedProxies,
 	}
 }
 
@@ -65,6 +85,9 @@ type AccessTokenResponse struct {
 
 	// IsnPerms is a map of the ISNs the account has access to and the permissions granted (the map key is the isn_slug)
 	IsnPerms map[string]IsnPerm `json:"isn_perms,omitempty"`
+
+	// Scope is the scope requested by the service account (not set for unscoped tokens). IsnPerms only includes the ISNs in the scope.
+	Scope string `json:"scope,omitempty" example:"isn:sample-isn:read isn:sample-isn:write"`
 }
 
 // IsnPerm contains the permissions and signal types available to the account for a specific ISN.
@@ -132,6 +155,19 @@ type Claims struct {
 
 	// IsnPerms is a map of the ISNs and signal types the account has access to and the permissions they have been granted (the map key is the isn slug)
 	IsnPerms map[string]IsnPerm `json:"isn_perms,omitempty" example:"sample-isn"`
+
+	// Scope is set when a service account requested a token limited to specific ISNs (see Scope).
+	// IsnPerms only contains the ISNs and permissions in the scope.
+	Scope string `json:"scope,omitempty" example:"isn:sample-isn:read"`
+
+	// Confirmation is set when a service account authenticated with a TLS client certificate (RFC 8705).
+	// The token can only be used on requests made with the same certificate.
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+
+	// SessionID identifies the credential used to obtain the token: the client secret or public key the service account authenticated with,
+	// or the web user's login session (the session is kept when the refresh token is rotated).
+	// Token introspection reports the token as inactive once this credential has been revoked.
+	SessionID *uuid.UUID `json:"sid,omitempty" example:"0198c1e5-5b7a-7c4e-9a43-3f2f1e6d8b21"`
 }
 
 // stucts to hold a temporary full list of isns and signal types used when building the AccessTokenResponse.
@@ -212,43 +248,156 @@ func toSignalTypesClaims(details []signalTypeDetails, isnInUse bool) map[string]
 //	Note that since the tokens last 30 mins, there is the potential for the permissions to become stale.
 //	if there are particular requests that *must* have the latest permissions the handler should check the db rather than using the claims info.
 func (a *AuthService) CreateAccessToken(ctx context.Context) (AccessTokenResponse, error) {
+	return a.CreateScopedAccessToken(ctx, nil)
+}
+
+// CreateScopedAccessToken creates an access token limited to the ISNs and permissions in the scope (see CreateAccessToken).
+// A nil scope creates a token with all the account's permissions.
+//
+// Scopes are only supported for service accounts. An ErrInvalidScope error is returned if the scope
+// includes access the account has not been granted - handlers should report these as invalid_scope rather than server errors.
+func (a *AuthService) CreateScopedAccessToken(ctx context.Context, scope Scope) (AccessTokenResponse, error) {
 
 	issuedAt := time.Now()
 	expiresAt := issuedAt.Add(signalsd.AccessTokenExpiry)
 
-	// detailed perms list for the response body
-	isnPerms := make(map[string]IsnPerm) // key is the isn slug
-
-	// simplified perms list for the claims
-	isnPermsClaims := make(map[string]IsnPerm) // key is the isn slug
-
 	accountID, ok := ContextAccountID(ctx)
 	if !ok {
 		return AccessTokenResponse{}, fmt.Errorf("unexpected error - accountID not in context")
 	}
 
+	account, isnPerms, isnList, err := a.accountPermissions(ctx, accountID)
+	if err != nil {
+		return AccessTokenResponse{}, err
+	}
+
+	// narrow the permissions to the requested scope
+	if scope != nil {
+		if account.AccountType != "service_account" {
+			return AccessTokenResponse{}, fmt.Errorf("%w: scopes can only be requested by service accounts", ErrInvalidScope)
+		}
+		isnPerms, err = scope.restrict(isnPerms)
+		if err != nil {
+			return AccessTokenResponse{}, err
+		}
+	}
+
+	// claims
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   accountID.String(),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			Issuer:    signalsd.TokenIssuerName,
+		},
+		AccountID:   account.ID,
+		AccountType: account.AccountType,
+		Role:        account.AccountRole,
+		IsnPerms:    isnPermsClaims(isnPerms, isnList),
+		Scope:       scope.String(),
+	}
+
+	// bind the token to the TLS client certificate used to authenticate (self_signed_tls_client_auth)
+	if thumbprint, ok := ContextCertificateThumbprint(ctx); ok {
+		claims.Confirmation = &Confirmation{X5tS256: thumbprint}
+	}
+
+	// the credential used to obtain the token (checked by token introspection)
+	if sessionID, ok := ContextSessionID(ctx); ok {
+		claims.SessionID = &sessionID
+	}
+
+	email, err := a.queries.GetEmailByAccountID(ctx, accountID)
+	if err != nil {
+		return AccessTokenResponse{}, fmt.Errorf("database error getting email for the account: %v", err)
+	}
+
+	// create a new signed token
+	signedAccessToken, err := a.signAccessToken(claims)
+	if err != nil {
+		return AccessTokenResponse{}, fmt.Errorf("failed to sign JWT: %v", err)
+	}
+
+	return AccessTokenResponse{
+		AccessToken: signedAccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(signalsd.AccessTokenExpiry.Seconds()),
+		AccountID:   account.ID,
+		Email:       email,
+		AccountType: account.AccountType,
+		Role:        account.AccountRole,
+		IsnPerms:    isnPerms,
+		Scope:       scope.String(),
+	}, nil
+}
+
+// CurrentClaims returns the claims for the account based on its current role and ISN permissions (the claims are not signed).
+//
+// Queued submissions and unroutable signals are stored some time after they were submitted, so they are processed with these claims
+// rather than the claims from the original access token - permissions revoked in the meantime are applied.
+// scope is the scope of the original access token: the current permissions are narrowed to the scope (access in the scope that
+// has since been revoked is removed).
+//
+// ErrAccountInactive is returned when the account has been disabled.
+func (a *AuthService) CurrentClaims(ctx context.Context, accountID uuid.UUID, scope string) (*Claims, error) {
+	account, isnPerms, isnList, err := a.accountPermissions(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenScope, err := ParseScope(scope)
+	if err != nil {
+		return nil, err
+	}
+	if tokenScope != nil {
+		isnPerms = tokenScope.intersect(isnPerms)
+	}
+
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: accountID.String(),
+			Issuer:  signalsd.TokenIssuerName,
+		},
+		AccountID:   account.ID,
+		AccountType: account.AccountType,
+		Role:        account.AccountRole,
+		IsnPerms:    isnPermsClaims(isnPerms, isnList),
+		Scope:       tokenScope.String(),
+	}, nil
+}
+
+// ErrAccountInactive is returned by CurrentClaims when the account has been disabled
+var ErrAccountInactive = errors.New("account is disabled")
+
+// accountPermissions returns the account, the ISNs it has access to and the permissions granted (key is the isn slug),
+// and the details of all the ISNs on the site.
+func (a *AuthService) accountPermissions(ctx context.Context, accountID uuid.UUID) (database.GetAccountByIDRow, map[string]IsnPerm, isnList, error) {
+
+	// detailed perms list for the response body
+	isnPerms := make(map[string]IsnPerm) // key is the isn slug
+
 	//get user role
 	account, err := a.queries.GetAccountByID(ctx, accountID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return AccessTokenResponse{}, fmt.Errorf("user not found: %v", accountID)
+			return database.GetAccountByIDRow{}, nil, nil, fmt.Errorf("user not found: %v", accountID)
 		}
-		return AccessTokenResponse{}, fmt.Errorf("database error getting user %v: %v", accountID, err)
+		return database.GetAccountByIDRow{}, nil, nil, fmt.Errorf("database error getting user %v: %v", accountID, err)
 	}
 
 	// this should be caught by the middleware, but double check here in case of bugs elsewhere
 	if !account.IsActive {
-		return AccessTokenResponse{}, fmt.Errorf("account %v is disabled", accountID)
+		return database.GetAccountByIDRow{}, nil, nil, fmt.Errorf("%w: %v", ErrAccountInactive, accountID)
 	}
 
 	if !signalsd.ValidRoles[account.AccountRole] {
-		return AccessTokenResponse{}, fmt.Errorf("invalid user role %v for user %v", account.AccountRole, accountID)
+		return database.GetAccountByIDRow{}, nil, nil, fmt.Errorf("invalid user role %v for user %v", account.AccountRole, accountID)
 	}
 
 	// get all the isns on the site
 	dbIsnList, err := a.queries.GetIsns(ctx)
 	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		return AccessTokenResponse{}, fmt.Errorf("database error getting ISNs: %v", err)
+		return database.GetAccountByIDRow{}, nil, nil, fmt.Errorf("database error getting ISNs: %v", err)
 	}
 
 	// create a list of all the isns and their signal types
@@ -265,7 +414,7 @@ func (a *AuthService) CreateAccessToken(ctx context.Context) (AccessTokenRespons
 		// get the signal types for this isn
 		dbSignalTypes, err := a.queries.GetSignalTypesByIsnID(ctx, dbIsn.ID)
 		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-			return AccessTokenResponse{}, fmt.Errorf("database error getting signal_types: %v", err)
+			return database.GetAccountByIDRow{}, nil, nil, fmt.Errorf("database error getting signal_types: %v", err)
 		}
 
 		signalTypes := make([]signalTypeDetails, 0)
@@ -297,7 +446,7 @@ func (a *AuthService) CreateAccessToken(ctx context.Context) (AccessTokenRespons
 	// get the isns this account's has been granted access to.
 	isnsAccessibleByAccount, err := a.queries.GetIsnAccountsByAccountID(ctx, accountID)
 	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		return AccessTokenResponse{}, fmt.Errorf("database error getting ISN accounts: %v", err)
+		return database.GetAccountByIDRow{}, nil, nil, fmt.Errorf("database error getting ISN accounts: %v", err)
 	}
 
 	// build isnPerms: filter the isnList to the ISNs this account can access, with their permissions
@@ -359,55 +508,23 @@ func (a *AuthService) CreateAccessToken(ctx context.Context) (AccessTokenRespons
 		}
 
 	default:
-		return AccessTokenResponse{}, fmt.Errorf("unexpected role: %v", account.AccountRole)
+		return database.GetAccountByIDRow{}, nil, nil, fmt.Errorf("unexpected role: %v", account.AccountRole)
 	}
 
-	// build isnPermsClaims from isnPerms - identical fields, but with simplified signal types.
-	// Signal types are marked in_use=false when the parent ISN is not in use, since they are
-	// unreachable regardless of their own flag.
+	return account, isnPerms, isnList, nil
+}
+
+// isnPermsClaims returns the simplified perms list used in the claims - identical fields to isnPerms, but with simplified signal types.
+// Signal types are marked in_use=false when the parent ISN is not in use, since they are
+// unreachable regardless of their own flag.
+func isnPermsClaims(isnPerms map[string]IsnPerm, isnList isnList) map[string]IsnPerm {
+	claims := make(map[string]IsnPerm, len(isnPerms)) // key is the isn slug
 	for slug, perm := range isnPerms {
 		claimPerm := perm
 		claimPerm.SignalTypes = toSignalTypesClaims(isnList[slug].signalTypes, perm.InUse)
-		isnPermsClaims[slug] = claimPerm
+		claims[slug] = claimPerm
 	}
-
-	// claims
-	claims := Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   accountID.String(),
-			IssuedAt:  jwt.NewNumericDate(issuedAt),
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			Issuer:    signalsd.TokenIssuerName,
-		},
-		AccountID:   account.ID,
-		AccountType: account.AccountType,
-		Role:        account.AccountRole,
-		IsnPerms:    isnPermsClaims,
-	}
-
-	email, err := a.queries.GetEmailByAccountID(ctx, accountID)
-	if err != nil {
-		return AccessTokenResponse{}, fmt.Errorf("database error getting email for the account: %v", err)
-	}
-
-	// create a new signed token
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	signedAccessToken, err := accessToken.SignedString([]byte(a.secretKey))
-	if err != nil {
-		return AccessTokenResponse{}, fmt.Errorf("failed to sign JWT: %v", err)
-	}
-
-	return AccessTokenResponse{
-		AccessToken: signedAccessToken,
-		TokenType:   "Bearer",
-		ExpiresIn:   int(signalsd.AccessTokenExpiry.Seconds()),
-		AccountID:   account.ID,
-		Email:       email,
-		AccountType: account.AccountType,
-		Role:        account.AccountRole,
-		IsnPerms:    isnPerms,
-	}, nil
+	return claims
 }
 
 // rerturn the JWT access token from Authorization header
@@ -426,7 +543,7 @@ func (a *AuthService) GetAccessTokenFromHeader(headers http.Header) (string, err
 }
 
 // revoke any open refresh tokens for the user contained in the shared context
-// stores the hashed token
+// stores the hashed token with the session id from the context (see ContextWithSessionID)
 // returns the new token as plain text
 func (a *AuthService) RotateRefreshToken(ctx context.Context) (string, error) {
 	userAccountID, ok := ContextAccountID(ctx)
@@ -434,6 +551,11 @@ func (a *AuthService) RotateRefreshToken(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("authservice: did not receive userAccountID from middleware")
 	}
 
+	sessionID, ok := ContextSessionID(ctx)
+	if !ok {
+		return "", fmt.Errorf("authservice: session id not in context")
+	}
+
 	_, err := a.queries.RevokeAllRefreshTokensForUser(ctx, userAccountID)
 	if err != nil {
 		return "", fmt.Errorf("authservice: could not revoke previous refresh tokens for user %v", userAccountID)
@@ -452,6 +574,7 @@ func (a *AuthService) RotateRefreshToken(ctx context.Context) (string, error) {
 		HashedToken:   hashedToken,
 		UserAccountID: userAccountID,
 		ExpiresAt:     time.Now().Add(signalsd.RefreshTokenExpiry),
+		SessionID:     sessionID,
 	})
 	if err != nil {
 		return "", fmt.Errorf("authservice: could not insert refresh token: %v", err)
@@ -508,3 +631,69 @@ func (a *AuthService) HashToken(token string) string {
 	hasher.Write([]byte(token))
 	return base64.URLEncoding.EncodeToString(hasher.Sum(nil))
 }
+
+// signAccessToken signs the claims with the current signing key (the key id is set in the kid header)
+// or with the secret key when the service uses HS256.
+func (a *AuthService) signAccessToken(claims Claims) (string, error) {
+	if a.signingKeys == nil || a.signingKeys.Algorithm() == signingkeys.HS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(a.secretKey))
+	}
+
+	key := a.signingKeys.SigningKey()
+	if key == nil {
+		return "", fmt.Errorf("no %s signing key available", a.signingKeys.Algorithm())
+	}
+
+	token := jwt.NewWithClaims(key.SigningMethod(), claims)
+	token.Header["kid"] = key.ID.String()
+	return token.SignedString(key.PrivateKey())
+}
+
+// validSigningMethods are the algorithms accepted in access tokens
+var validSigningMethods = []string{
+	jwt.SigningMethodHS256.Alg(),
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodES256.Alg(),
+	jwt.SigningMethodEdDSA.Alg(),
+}
+
+// ParseAccessToken checks the access token signature and expiry and returns the claims.
+//
+// The claims are returned with the error when the token has expired (jwt.ErrTokenExpired) so they can be logged.
+func (a *AuthService) ParseAccessToken(accessToken string) (*Claims, error) {
+	claims := &Claims{}
+
+	// WithValidMethods restricts the accepted algorithms and verificationKey checks the key matches the algorithm, preventing algorithm confusion attacks
+	_, err := jwt.ParseWithClaims(accessToken, claims, a.verificationKey, jwt.WithValidMethods(validSigningMethods))
+	return claims, err
+}
+
+// verificationKey is the jwt.Keyfunc used to check access token signatures.
+//
+// HS256 tokens are checked with the secret key. After switching to an asymmetric algorithm these are only accepted until the tokens
+// issued before the switch have expired (see signingkeys.KeySet.HS256AcceptedUntil). Other tokens are checked with the public key identified by the kid header -
+// the key must have been created for the algorithm in the token header, which prevents algorithm confusion attacks.
+func (a *AuthService) verificationKey(token *jwt.Token) (any, error) {
+	if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		if a.signingKeys != nil {
+			if until, ok := a.signingKeys.HS256AcceptedUntil(); ok && !time.Now().Before(until) {
+				return nil, fmt.Errorf("%s tokens are no longer accepted (the service switched to %s)", jwt.SigningMethodHS256.Alg(), a.signingKeys.Algorithm())
+			}
+		}
+		return []byte(a.secretKey), nil
+	}
+	if a.signingKeys == nil {
+		return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+	}
+
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("kid header missing")
+	}
+	return a.signingKeys.VerificationKey(kid, token.Method.Alg())
+}
+
+// SigningKeys returns the asymmetric signing key set (nil when the service only uses HS256)
+func (a *AuthService) SigningKeys() *signingkeys.KeySet {
+	return a.signingKeys
+}
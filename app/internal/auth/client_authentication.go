@@ -0,0 +1,419 @@
+package auth
+
+// service account authentication
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/information-sharing-networks/signalsd/app/internal/signingkeys"
+	"github.com/jackc/pgx/v5"
+)
+
+// client authentication methods (the values used in the OAuth authorization server metadata - RFC 8414)
+const (
+	ClientAuthMethodSecretBasic   = "client_secret_basic"
+	ClientAuthMethodSecretPost    = "client_secret_post"
+	ClientAuthMethodPrivateKeyJWT = "private_key_jwt"
+	ClientAuthMethodSelfSignedTLS = "self_signed_tls_client_auth"
+
+	// ClientAssertionTypeJWTBearer is the client_assertion_type used with private_key_jwt (RFC 7523 section 2.2)
+	ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+// ClientAssertionSigningAlgs are the algorithms service accounts can use to sign client assertions.
+// The algorithm is determined by the type of key registered for the service account.
+var ClientAssertionSigningAlgs = []string{signingkeys.RS256, signingkeys.ES256, signingkeys.EdDSA}
+
+// minClientRSAKeyBits is the smallest RSA key that can be registered for a service account
+const minClientRSAKeyBits = 2048
+
+// Confirmation is the cnf claim in access tokens that are bound to a TLS client certificate (RFC 8705 section 3.1)
+type Confirmation struct {
+	// X5tS256 is the base64url encoded SHA-256 hash of the DER encoded client certificate
+	X5tS256 string `json:"x5t#S256" example:"bwcK0esc3ACC3DB2Y5_lESsXE8o9ltc05O89jdN-dg2"`
+}
+
+// authenticatedClient is the service account that authenticated the request
+type authenticatedClient struct {
+	serviceAccount database.ServiceAccount
+	method         string
+
+	// credentialID is the id of the client secret or service account key used to authenticate
+	credentialID uuid.UUID
+
+	// certThumbprint is the x5t#S256 thumbprint of the TLS client certificate (self_signed_tls_client_auth only)
+	certThumbprint string
+}
+
+// ClientAuthMethods returns the methods service accounts can use to authenticate.
+// Mutual TLS is only available when the TLS terminating proxy forwards client certificates (TLS_CLIENT_CERT_HEADER).
+func (a *AuthService) ClientAuthMethods() []string {
+	methods := []string{ClientAuthMethodSecretBasic, ClientAuthMethodSecretPost, ClientAuthMethodPrivateKeyJWT}
+	if a.MutualTLSEnabled() {
+		methods = append(methods, ClientAuthMethodSelfSignedTLS)
+	}
+	return methods
+}
+
+// MutualTLSEnabled reports whether service accounts can authenticate with TLS client certificates
+func (a *AuthService) MutualTLSEnabled() bool {
+	return a.tlsClientCertHeader != ""
+}
+
+// authenticateClient authenticates a service account using one of:
+//   - client_secret_basic / client_secret_post: the client id and secret in the Authorization header or the form body
+//   - private_key_jwt (RFC 7523): a client assertion signed with a key registered for the service account
+//   - self_signed_tls_client_auth (RFC 8705): the client_id form field and a TLS client certificate for a key registered for the service account
+//
+// The returned errors are OAuth errors that can be rendered directly.
+func (a *AuthService) authenticateClient(r *http.Request) (authenticatedClient, error) {
+	ctx := r.Context()
+
+	assertionType := r.PostFormValue("client_assertion_type")
+	assertion := r.PostFormValue("client_assertion")
+	basicID, basicSecret, hasBasicAuth := r.BasicAuth()
+
+	switch {
+	case assertionType != "" || assertion != "":
+		if assertionType != ClientAssertionTypeJWTBearer {
+			return authenticatedClient{}, apperrors.OAuthInvalidRequest("client_assertion_type must be "+ClientAssertionTypeJWTBearer, apperrors.ErrCodeInvalidRequest, nil)
+		}
+		if assertion == "" {
+			return authenticatedClient{}, apperrors.OAuthInvalidRequest("client_assertion is required", apperrors.ErrCodeInvalidRequest, nil)
+		}
+
+		// the client is identified by the iss claim - the signature is checked once the client's keys have been retrieved
+		unverified := &jwt.RegisteredClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(assertion, unverified); err != nil || unverified.Issuer == "" {
+			return authenticatedClient{}, apperrors.OAuthInvalidClient("client_assertion is not a valid JWT with an iss claim", apperrors.ErrCodeAuthenticationFailure, err)
+		}
+		if formClientID := r.PostFormValue("client_id"); formClientID != "" && formClientID != unverified.Issuer {
+			return authenticatedClient{}, apperrors.OAuthInvalidClient("client_id does not match the client_assertion issuer", apperrors.ErrCodeAuthenticationFailure, nil)
+		}
+
+		serviceAccount, err := a.activeServiceAccount(ctx, unverified.Issuer)
+		if err != nil {
+			return authenticatedClient{}, err
+		}
+		keyID, err := a.verifyClientAssertion(r, assertion, serviceAccount)
+		if err != nil {
+			return authenticatedClient{}, err
+		}
+		return authenticatedClient{serviceAccount: serviceAccount, method: ClientAuthMethodPrivateKeyJWT, credentialID: keyID}, nil
+
+	case hasBasicAuth || r.PostFormValue("client_secret") != "":
+		clientID, clientSecret, method := basicID, basicSecret, ClientAuthMethodSecretBasic
+		if !hasBasicAuth {
+			clientID, clientSecret, method = r.PostFormValue("client_id"), r.PostFormValue("client_secret"), ClientAuthMethodSecretPost
+		}
+		if clientID == "" || clientSecret == "" {
+			return authenticatedClient{}, apperrors.OAuthInvalidRequest("client_id and client_secret are required", apperrors.ErrCodeInvalidRequest, nil)
+		}
+
+		serviceAccount, err := a.activeServiceAccount(ctx, clientID)
+		if err != nil {
+			return authenticatedClient{}, err
+		}
+
+		// check the secret is valid and was issued to this client
+		secret, err := a.queries.GetValidClientSecretByHashedSecret(ctx, a.HashToken(clientSecret))
+		if err != nil || secret.ServiceAccountAccountID != serviceAccount.AccountID {
+			return authenticatedClient{}, apperrors.OAuthInvalidClient("invalid client secret", apperrors.ErrCodeAuthenticationFailure, nil)
+		}
+		return authenticatedClient{serviceAccount: serviceAccount, method: method, credentialID: secret.ID}, nil
+
+	default:
+		cert, err := a.clientCertificate(r)
+		if err != nil {
+			return authenticatedClient{}, apperrors.OAuthInvalidClient("invalid TLS client certificate", apperrors.ErrCodeAuthenticationFailure, err)
+		}
+		clientID := r.PostFormValue("client_id")
+		if cert == nil || clientID == "" {
+			return authenticatedClient{}, apperrors.OAuthInvalidRequest("client authentication is required - supply a client_id and client_secret, a client_assertion or a TLS client certificate", apperrors.ErrCodeInvalidRequest, nil)
+		}
+
+		serviceAccount, err := a.activeServiceAccount(ctx, clientID)
+		if err != nil {
+			return authenticatedClient{}, err
+		}
+		keyID, err := a.verifyClientCertificate(ctx, cert, serviceAccount)
+		if err != nil {
+			return authenticatedClient{}, err
+		}
+		return authenticatedClient{serviceAccount: serviceAccount, method: ClientAuthMethodSelfSignedTLS, credentialID: keyID, certThumbprint: CertificateThumbprint(cert)}, nil
+	}
+}
+
+// activeServiceAccount returns the service account for the client id - disabled accounts are rejected
+func (a *AuthService) activeServiceAccount(ctx context.Context, clientID string) (database.ServiceAccount, error) {
+	logger.ContextWithLogAttrs(ctx,
+		slog.String("client_id", clientID),
+	)
+
+	serviceAccount, err := a.queries.GetServiceAccountByClientID(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.ServiceAccount{}, apperrors.OAuthInvalidClient("invalid client_id", apperrors.ErrCodeAuthenticationFailure, nil)
+		}
+		return database.ServiceAccount{}, apperrors.OAuthServerError("database error", apperrors.ErrCodeDatabaseError, err)
+	}
+
+	account, err := a.queries.GetAccountByID(ctx, serviceAccount.AccountID)
+	if err != nil {
+		return database.ServiceAccount{}, apperrors.OAuthServerError("database error getting account", apperrors.ErrCodeDatabaseError, err)
+	}
+	if !account.IsActive {
+		return database.ServiceAccount{}, apperrors.OAuthInvalidClient("account is disabled", apperrors.ErrCodeAuthorizationFailure, nil)
+	}
+	return serviceAccount, nil
+}
+
+// verifyClientAssertion checks a private_key_jwt client assertion (RFC 7523 section 3):
+//   - the assertion is signed with one of the keys registered for the service account (selected using the kid header if present)
+//   - iss and sub are the client id
+//   - aud is the signalsd issuer (PUBLIC_BASE_URL), the token endpoint or the endpoint being called
+//   - exp is set and no more than ClientAssertionMaxAge in the future
+//   - jti is set and the assertion has not been used before
+//
+// The id of the key that verified the assertion is returned.
+func (a *AuthService) verifyClientAssertion(r *http.Request, assertion string, serviceAccount database.ServiceAccount) (uuid.UUID, error) {
+	ctx := r.Context()
+
+	keys, err := a.queries.GetServiceAccountKeys(ctx, serviceAccount.AccountID)
+	if err != nil {
+		return uuid.Nil, apperrors.OAuthServerError("database error getting service account keys", apperrors.ErrCodeDatabaseError, err)
+	}
+	if len(keys) == 0 {
+		return uuid.Nil, apperrors.OAuthInvalidClient("no public keys are registered for this client", apperrors.ErrCodeAuthenticationFailure, nil)
+	}
+
+	// each key that could have signed the assertion is tried in turn so the key used to authenticate is known
+	var (
+		claims *jwt.RegisteredClaims
+		keyID  uuid.UUID
+	)
+	for _, key := range keys {
+		claims = &jwt.RegisteredClaims{}
+		_, err = jwt.ParseWithClaims(assertion, claims, func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			if key.Algorithm != token.Method.Alg() || (kid != "" && kid != key.ID.String()) {
+				return nil, fmt.Errorf("no %s key registered for the client (kid %q)", token.Method.Alg(), kid)
+			}
+			publicKey, err := x509.ParsePKIXPublicKey(key.PublicKey)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse key %s: %v", key.ID, err)
+			}
+			return publicKey, nil
+		},
+			jwt.WithValidMethods(ClientAssertionSigningAlgs),
+			jwt.WithIssuer(serviceAccount.ClientID),
+			jwt.WithSubject(serviceAccount.ClientID),
+			jwt.WithAudience(a.publicBaseURL, a.publicBaseURL+"/oauth/token", a.publicBaseURL+r.URL.Path),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(signalsd.ClientAssertionLeeway),
+		)
+		if err == nil {
+			keyID = key.ID
+			break
+		}
+	}
+	if err != nil {
+		return uuid.Nil, apperrors.OAuthInvalidClient("invalid client_assertion", apperrors.ErrCodeAuthenticationFailure, err)
+	}
+
+	if time.Until(claims.ExpiresAt.Time) > signalsd.ClientAssertionMaxAge+signalsd.ClientAssertionLeeway {
+		return uuid.Nil, apperrors.OAuthInvalidClient(fmt.Sprintf("client_assertion must expire within %v", signalsd.ClientAssertionMaxAge), apperrors.ErrCodeAuthenticationFailure, nil)
+	}
+	if claims.ID == "" {
+		return uuid.Nil, apperrors.OAuthInvalidClient("client_assertion must include a jti claim", apperrors.ErrCodeAuthenticationFailure, nil)
+	}
+
+	// replay protection - each assertion can only be used once
+	if _, err := a.queries.DeleteExpiredClientAssertions(ctx, serviceAccount.AccountID); err != nil {
+		return uuid.Nil, apperrors.OAuthServerError("database error deleting expired client assertions", apperrors.ErrCodeDatabaseError, err)
+	}
+	recorded, err := a.queries.RecordClientAssertion(ctx, database.RecordClientAssertionParams{
+		ServiceAccountAccountID: serviceAccount.AccountID,
+		Jti:                     claims.ID,
+		ExpiresAt:               claims.ExpiresAt.Time.Add(signalsd.ClientAssertionLeeway),
+	})
+	if err != nil {
+		return uuid.Nil, apperrors.OAuthServerError("database error recording client assertion", apperrors.ErrCodeDatabaseError, err)
+	}
+	if recorded == 0 {
+		return uuid.Nil, apperrors.OAuthInvalidClient("client_assertion has already been used", apperrors.ErrCodeAuthenticationFailure, nil)
+	}
+	return keyID, nil
+}
+
+// verifyClientCertificate checks the TLS client certificate is for one of the keys registered for the service account (RFC 8705 section 2.2).
+// The certificate does not need to be issued by a CA but must be within its validity period.
+// The id of the matching key is returned.
+func (a *AuthService) verifyClientCertificate(ctx context.Context, cert *x509.Certificate, serviceAccount database.ServiceAccount) (uuid.UUID, error) {
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return uuid.Nil, apperrors.OAuthInvalidClient("TLS client certificate has expired or is not yet valid", apperrors.ErrCodeAuthenticationFailure, nil)
+	}
+
+	certPublicKey, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		return uuid.Nil, apperrors.OAuthInvalidClient("unsupported TLS client certificate key", apperrors.ErrCodeAuthenticationFailure, err)
+	}
+
+	keys, err := a.queries.GetServiceAccountKeys(ctx, serviceAccount.AccountID)
+	if err != nil {
+		return uuid.Nil, apperrors.OAuthServerError("database error getting service account keys", apperrors.ErrCodeDatabaseError, err)
+	}
+	for _, key := range keys {
+		if string(key.PublicKey) == string(certPublicKey) {
+			return key.ID, nil
+		}
+	}
+	return uuid.Nil, apperrors.OAuthInvalidClient("TLS client certificate does not match a key registered for this client", apperrors.ErrCodeAuthenticationFailure, nil)
+}
+
+// clientCertificate returns the TLS client certificate presented with the request (nil if there isn't one).
+//
+// The certificate is read from the connection when signalsd terminates TLS, otherwise from the TLS_CLIENT_CERT_HEADER header
+// set by the TLS terminating proxy (URL encoded PEM). The header is ignored unless TLS_CLIENT_CERT_HEADER is configured and
+// an error is returned if the header is included in a request that did not come from one of the TLS_CLIENT_CERT_TRUSTED_PROXIES
+// (the proxy must overwrite the header, so the connection has to come from the proxy for the value to be trusted).
+func (a *AuthService) clientCertificate(r *http.Request) (*x509.Certificate, error) {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return r.TLS.PeerCertificates[0], nil
+	}
+	if !a.MutualTLSEnabled() {
+		return nil, nil
+	}
+
+	value := r.Header.Get(a.tlsClientCertHeader)
+	if value == "" {
+		return nil, nil
+	}
+	if !a.fromTrustedCertificateProxy(r) {
+		return nil, fmt.Errorf("%s header received from an untrusted source: %s", a.tlsClientCertHeader, r.RemoteAddr)
+	}
+
+	// PathUnescape rather than QueryUnescape: '+' is a valid base64 character
+	decoded, err := url.PathUnescape(value)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s header: %v", a.tlsClientCertHeader, err)
+	}
+	block, _ := pem.Decode([]byte(decoded))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s header does not contain a PEM encoded certificate", a.tlsClientCertHeader)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// fromTrustedCertificateProxy reports whether the request was received from one of the TLS_CLIENT_CERT_TRUSTED_PROXIES.
+// The connection address is used rather than the client IP in X-Forwarded-For, since that header can also be set by the client.
+func (a *AuthService) fromTrustedCertificateProxy(r *http.Request) bool {
+	addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	addr := addrPort.Addr().Unmap()
+	for _, prefix := range a.tlsClientCertTrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCertificateBinding checks a certificate bound access token is presented with the TLS client certificate it was issued to (RFC 8705 section 3)
+func (a *AuthService) checkCertificateBinding(r *http.Request, cnf *Confirmation) error {
+	cert, err := a.clientCertificate(r)
+	if err != nil {
+		return err
+	}
+	if cert == nil {
+		return fmt.Errorf("access token is bound to a TLS client certificate but no certificate was presented")
+	}
+	if CertificateThumbprint(cert) != cnf.X5tS256 {
+		return fmt.Errorf("access token is bound to a different TLS client certificate")
+	}
+	return nil
+}
+
+// CertificateThumbprint returns the x5t#S256 thumbprint of the certificate (base64url encoded SHA-256 hash of the DER encoding)
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ParseClientPublicKey parses a PEM encoded public key (PUBLIC KEY) or certificate (CERTIFICATE) supplied when registering a service account key.
+//
+// It returns the PKIX (DER) encoded public key and the JWT signing algorithm used with the key:
+// RS256 for RSA keys (at least 2048 bits), ES256 for P-256 EC keys and EdDSA for Ed25519 keys.
+func ParseClientPublicKey(pemData string) ([]byte, string, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, "", fmt.Errorf("public_key must be a PEM encoded public key or certificate")
+	}
+
+	var publicKey crypto.PublicKey
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, "", fmt.Errorf("could not parse public key: %v", err)
+		}
+		publicKey = key
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, "", fmt.Errorf("could not parse certificate: %v", err)
+		}
+		publicKey = cert.PublicKey
+	default:
+		return nil, "", fmt.Errorf("unsupported PEM block type %q (must be PUBLIC KEY or CERTIFICATE)", block.Type)
+	}
+
+	var algorithm string
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minClientRSAKeyBits {
+			return nil, "", fmt.Errorf("RSA keys must be at least %d bits", minClientRSAKeyBits)
+		}
+		algorithm = signingkeys.RS256
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return nil, "", fmt.Errorf("EC keys must use the P-256 curve")
+		}
+		algorithm = signingkeys.ES256
+	case ed25519.PublicKey:
+		algorithm = signingkeys.EdDSA
+	default:
+		return nil, "", fmt.Errorf("unsupported key type %T (must be RSA, EC P-256 or Ed25519)", publicKey)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not encode public key: %v", err)
+	}
+	return der, algorithm, nil
+}
@@ -16,6 +16,8 @@ var (
 	accountTypeKey        = contextKey{"account-type"}
 	claimsKey             = contextKey{"claims"}
 	hashedRefreshTokenKey = contextKey{"hashed_refresh_token"}
+	certThumbprintKey     = contextKey{"cert_thumbprint"}
+	sessionIDKey          = contextKey{"session_id"}
 )
 
 func ContextWithAccountID(ctx context.Context, id uuid.UUID) context.Context {
@@ -53,3 +55,25 @@ func ContextClaims(ctx context.Context) (*Claims, bool) {
 	claims, ok := ctx.Value(claimsKey).(*Claims)
 	return claims, ok
 }
+
+// ContextWithCertificateThumbprint records the thumbprint of the TLS client certificate used to authenticate a service account -
+// access tokens created with this context are bound to the certificate.
+func ContextWithCertificateThumbprint(ctx context.Context, thumbprint string) context.Context {
+	return context.WithValue(ctx, certThumbprintKey, thumbprint)
+}
+
+func ContextCertificateThumbprint(ctx context.Context) (string, bool) {
+	thumbprint, ok := ctx.Value(certThumbprintKey).(string)
+	return thumbprint, ok
+}
+
+// ContextWithSessionID records the id of the credential used to authenticate (the client secret or public key for service accounts,
+// the login session for web users) - access tokens created with this context include the id in the sid claim.
+func ContextWithSessionID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, sessionIDKey, id)
+}
+
+func ContextSessionID(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(sessionIDKey).(uuid.UUID)
+	return id, ok
+}
@@ -16,20 +16,61 @@
 // Access tokens contain custom JWT claims that list the ISNs and Signal Types the account has access to.
 // (for conveninence, the claims are reproduced as json in the response body returned from the /login and /oauth/refresh endpoints)
 //
-// Access tokens are signed with the service's secret key - the client does not need to know the secret, it is just used by the
+// By default access tokens are signed with the service's secret key (HS256) - the client does not need to know the secret, it is just used by the
 // server to ensure the claims have not been tampered with.
 //
+// When ACCESS_TOKEN_SIGNING_ALG is set to RS256, ES256 or EdDSA the tokens are signed with a rotating private key instead (see the signingkeys package)
+// and the public keys are published at /.well-known/jwks.json so that other services can verify the tokens without calling signalsd.
+// The kid header identifies the key used to sign the token. HS256 tokens are still accepted until the tokens issued before the algorithm was changed
+// have expired (the switch time plus the access token lifetime) and are rejected after that.
+//
 // Access tokens expire after 30 mins.
 // Refresh tokens expire after 30 days and are rotated on login or refresh request.
 // These time limits are set in config.go
 //
 // Permissions are revalidated on the database when access tokens are refreshed, the rest of the time auth checks are done using the token claims.
 //
+// # Scoped access tokens
+//
+// Service accounts can request a token limited to specific ISNs by supplying a scope on the client_credentials grant
+// (e.g scope=isn:port-isn:write isn:customs-isn:read, see [Scope]). The claims only include the ISNs and permissions in the scope,
+// so the usual claims based permission checks apply the scope. Scopes can only remove access - requesting access the account has
+// not been granted is rejected with invalid_scope.
+//
+// # Service account client authentication
+//
+// As well as client secrets, service accounts can authenticate with a public key registered by an admin
+// (POST /api/auth/service-accounts/{client_id}/keys):
+//   - private_key_jwt (RFC 7523): the client signs a short lived JWT client assertion with its private key. Assertions are single use (the jti is recorded until the assertion expires).
+//   - self_signed_tls_client_auth (RFC 8705): the client presents a TLS client certificate for the key. signalsd does not terminate TLS, so this is only available when
+//     the TLS terminating proxy forwards the certificate in the header configured with TLS_CLIENT_CERT_HEADER. The proxy must overwrite the header on every request and
+//     the header is only accepted on connections from TLS_CLIENT_CERT_TRUSTED_PROXIES (requests from other sources that include it are rejected).
+//     Access tokens issued this way include a cnf claim and [AuthService.RequireValidAccessToken] rejects them unless the request is made with the same certificate.
+//
+// See [AuthService.RequireValidClientCredentials].
+//
+// # Federated login
+//
+// Web users can also log in with an OpenID Connect identity provider (e.g. a partner organisation's corporate SSO) configured in OIDC_PROVIDERS
+// (see the oidc package and handlers.OIDCLoginHandler). Provider accounts are linked to users by their verified email address on first login,
+// and users that are not registered can be provisioned as members. Once the provider has authenticated the user, they are issued the same
+// access and refresh tokens as users that log in with a password.
+//
+// # Token introspection
+//
+// Service accounts can check the status of a token with POST /oauth/introspect (RFC 7662, see [AuthService.IntrospectToken]).
+// Unlike the middleware checks, introspection consults the database: access tokens are only reported as active if the account is active and
+// the credential used to obtain the token has not been revoked. The credential is identified by the sid claim: the id of the client secret or
+// public key the service account authenticated with, or the web user's login session (created at login and kept when the refresh token is rotated,
+// so the access tokens issued in a session are active until the user logs out or logs in again).
+//
+// The server metadata (RFC 8414) is published at /.well-known/oauth-authorization-server.
+//
 // # isActive status
 //
 // marking an account  is_active= false:
 //   - prevents new access tokens being created.
-//   - revokes any client secrets/one time secrets (service accounts)
+//   - revokes any client secrets/one time secrets and public keys (service accounts)
 //   - prevents login and revokes refresh tokens (users)
 //   - closes any open batches
 //
@@ -57,7 +98,7 @@
 //
 //   - [AuthService.RequireValidRefreshToken]: validates the HttpOnly refresh token cookie against the database; adds accountID and hashedRefreshToken to context.
 //
-//   - [AuthService.RequireValidClientCredentials]: validates client_id/client_secret from the JSON body; adds accountID to context.
+//   - [AuthService.RequireValidClientCredentials]: authenticates service accounts (client secret, private_key_jwt client assertion or TLS client certificate); adds accountID to context.
 //
 //   - [AuthService.RequireRole]: checks the role claim matches one of the supplied roles. Must follow RequireValidAccessToken.
 //
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/jackc/pgx/v5"
+)
+
+// token type hints (RFC 7662 section 2.1)
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// TokenIntrospection is the response from the token introspection endpoint (RFC 7662).
+//
+// Only active is returned for tokens that are not active (expired, revoked, unknown or issued to a disabled account).
+type TokenIntrospection struct {
+
+	// Active is true if the token can be used
+	Active bool `json:"active" example:"true"`
+
+	// TokenType is Bearer for access tokens and refresh_token for refresh tokens
+	TokenType string `json:"token_type,omitempty" enums:"Bearer,refresh_token" example:"Bearer"`
+
+	// ClientID is the client id of the service account the token was issued to (not set for web users)
+	ClientID string `json:"client_id,omitempty" example:"sa_exampleorg_k7j2m9x1"`
+
+	// Username is the email address of the web user the token was issued to (not set for service accounts)
+	Username string `json:"username,omitempty" example:"user@example.com"`
+
+	// Exp and Iat are the token expiry and issue times (seconds since the epoch)
+	Exp int64 `json:"exp,omitempty" example:"1774859853"`
+	Iat int64 `json:"iat,omitempty" example:"1774858053"`
+
+	// Sub is the account id the token was issued to
+	Sub string `json:"sub,omitempty" example:"a38c99ed-c75c-4a4a-a901-c9485cf93cf3"`
+
+	// Iss is the token issuer (access tokens only)
+	Iss string `json:"iss,omitempty" example:"Signalsd"`
+
+	// AccountID, AccountType and Role describe the account the token was issued to
+	AccountID   *uuid.UUID `json:"account_id,omitempty" example:"a38c99ed-c75c-4a4a-a901-c9485cf93cf3"`
+	AccountType string     `json:"account_type,omitempty" enums:"user,service_account"`
+	Role        string     `json:"role,omitempty" enums:"siteadmin,isnadmin,member" example:"isnadmin"`
+
+	// IsnPerms are the ISN permissions in the access token claims (not set for refresh tokens - permissions are worked out when the access token is issued)
+	IsnPerms map[string]IsnPerm `json:"isn_perms,omitempty"`
+
+	// Scope is the scope of a service account access token (not set for unscoped tokens)
+	Scope string `json:"scope,omitempty" example:"isn:sample-isn:read"`
+
+	// Confirmation is set for access tokens bound to a TLS client certificate (RFC 8705 section 3.2)
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+}
+
+// IntrospectToken reports whether an access token or refresh token is active and describes the account it was issued to.
+//
+// An access token is active if it is correctly signed and has not expired, the account is active and
+// the credential identified by the sid claim (the client secret or public key, or the web user's login session) has not been revoked.
+// A refresh token is active if it has not expired or been revoked and the account is active.
+//
+// tokenTypeHint (access_token or refresh_token) is the type to try first - both types are tried regardless of the hint.
+// Errors are only returned for database errors - tokens that can't be used are reported as inactive.
+func (a *AuthService) IntrospectToken(ctx context.Context, token string, tokenTypeHint string) (TokenIntrospection, error) {
+	if tokenTypeHint == TokenTypeHintRefreshToken {
+		res, found, err := a.introspectRefreshToken(ctx, token)
+		if err != nil || found {
+			return res, err
+		}
+		return a.introspectAccessToken(ctx, token)
+	}
+
+	res, err := a.introspectAccessToken(ctx, token)
+	if err != nil || res.Active {
+		return res, err
+	}
+	res, _, err = a.introspectRefreshToken(ctx, token)
+	return res, err
+}
+
+// introspectAccessToken checks the token is a valid access token and the credential used to obtain it is still valid.
+// Tokens without a sid claim are reported as inactive.
+func (a *AuthService) introspectAccessToken(ctx context.Context, token string) (TokenIntrospection, error) {
+	claims, err := a.ParseAccessToken(token)
+	if err != nil || claims.IssuedAt == nil || claims.ExpiresAt == nil || claims.SessionID == nil {
+		return TokenIntrospection{Active: false}, nil
+	}
+
+	active, err := a.isAccountActive(ctx, claims.AccountID)
+	if err != nil || !active {
+		return TokenIntrospection{Active: false}, err
+	}
+
+	sessionActive, err := a.queries.IsSessionActive(ctx, database.IsSessionActiveParams{
+		SessionID: *claims.SessionID,
+		AccountID: claims.AccountID,
+	})
+	if err != nil {
+		return TokenIntrospection{}, fmt.Errorf("database error checking credential %v for account %v: %v", *claims.SessionID, claims.AccountID, err)
+	}
+	if !sessionActive {
+		return TokenIntrospection{Active: false}, nil
+	}
+
+	res := TokenIntrospection{
+		Active:       true,
+		TokenType:    "Bearer",
+		Exp:          claims.ExpiresAt.Unix(),
+		Iat:          claims.IssuedAt.Unix(),
+		Sub:          claims.Subject,
+		Iss:          claims.Issuer,
+		AccountID:    &claims.AccountID,
+		AccountType:  claims.AccountType,
+		Role:         claims.Role,
+		IsnPerms:     claims.IsnPerms,
+		Scope:        claims.Scope,
+		Confirmation: claims.Confirmation,
+	}
+	if err := a.addAccountIdentifiers(ctx, &res, claims.AccountID, claims.AccountType); err != nil {
+		return TokenIntrospection{}, err
+	}
+	return res, nil
+}
+
+// introspectRefreshToken looks up a refresh token - found is false if the token is not a refresh token
+func (a *AuthService) introspectRefreshToken(ctx context.Context, token string) (res TokenIntrospection, found bool, err error) {
+	refreshToken, err := a.queries.GetRefreshToken(ctx, a.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TokenIntrospection{Active: false}, false, nil
+		}
+		return TokenIntrospection{}, false, fmt.Errorf("database error getting refresh token: %v", err)
+	}
+
+	if refreshToken.RevokedAt != nil || !refreshToken.ExpiresAt.After(time.Now()) {
+		return TokenIntrospection{Active: false}, true, nil
+	}
+
+	account, err := a.queries.GetAccountByID(ctx, refreshToken.UserAccountID)
+	if err != nil {
+		return TokenIntrospection{}, true, fmt.Errorf("database error getting account %v: %v", refreshToken.UserAccountID, err)
+	}
+	if !account.IsActive {
+		return TokenIntrospection{Active: false}, true, nil
+	}
+
+	res = TokenIntrospection{
+		Active:      true,
+		TokenType:   TokenTypeHintRefreshToken,
+		Exp:         refreshToken.ExpiresAt.Unix(),
+		Sub:         account.ID.String(),
+		AccountID:   &account.ID,
+		AccountType: account.AccountType,
+		Role:        account.AccountRole,
+	}
+	if err := a.addAccountIdentifiers(ctx, &res, account.ID, account.AccountType); err != nil {
+		return TokenIntrospection{}, true, err
+	}
+	return res, true, nil
+}
+
+// isAccountActive returns false for disabled and deleted accounts
+func (a *AuthService) isAccountActive(ctx context.Context, accountID uuid.UUID) (bool, error) {
+	account, err := a.queries.GetAccountByID(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("database error getting account %v: %v", accountID, err)
+	}
+	return account.IsActive, nil
+}
+
+// addAccountIdentifiers sets the client_id (service accounts) or username (web users)
+func (a *AuthService) addAccountIdentifiers(ctx context.Context, res *TokenIntrospection, accountID uuid.UUID, accountType string) error {
+	if accountType == "service_account" {
+		serviceAccount, err := a.queries.GetServiceAccountByAccountID(ctx, accountID)
+		if err != nil {
+			return fmt.Errorf("database error getting service account %v: %v", accountID, err)
+		}
+		res.ClientID = serviceAccount.ClientID
+		return nil
+	}
+
+	email, err := a.queries.GetEmailByAccountID(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("database error getting email for account %v: %v", accountID, err)
+	}
+	res.Username = email
+	return nil
+}
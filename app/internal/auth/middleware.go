@@ -40,13 +40,7 @@ func (a *AuthService) RequireValidAccessToken(next http.Handler) http.Handler {
 			return
 		}
 
-		claims := &Claims{}
-
-		// extract the claims from the jwt and validate the signature
-		// WithValidMethods ensures only HS256 tokens are accepted, preventing algorithm confusion attacks
-		_, err = jwt.ParseWithClaims(accessToken, claims, func(token *jwt.Token) (any, error) {
-			return []byte(a.secretKey), nil
-		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+		claims, err := a.ParseAccessToken(accessToken)
 		if err != nil {
 			if errors.Is(err, jwt.ErrTokenExpired) {
 				logger.ContextWithLogAttrs(r.Context(),
@@ -69,6 +63,22 @@ func (a *AuthService) RequireValidAccessToken(next http.Handler) http.Handler {
 			return
 		}
 
+		// certificate bound tokens can only be used with the TLS client certificate they were issued to (RFC 8705)
+		if claims.Confirmation != nil {
+			if err := a.checkCertificateBinding(r, claims.Confirmation); err != nil {
+				logger.ContextWithLogAttrs(r.Context(),
+					slog.String("account_id", claims.AccountID.String()),
+				)
+				responses.RenderError(w, r, &apperrors.HTTPError{
+					Status:  http.StatusUnauthorized,
+					Code:    apperrors.ErrCodeAuthorizationFailure,
+					Message: "unauthorized - access token is bound to a TLS client certificate",
+					Err:     err,
+				})
+				return
+			}
+		}
+
 		accountIDString := claims.Subject
 
 		accountID, err := uuid.Parse(accountIDString)
@@ -167,91 +177,37 @@ func (a *AuthService) RequireValidRefreshToken(next http.Handler) http.Handler {
 			slog.String("account_id", userAccountID.String()),
 		)
 
-		// add userId, accountType and hashedRefreshToken to context
+		// add userId, accountType, hashedRefreshToken and the login session to context
 		ctx := ContextWithAccountID(r.Context(), userAccountID)
 		ctx = ContextWithAccountType(ctx, "user")
 		ctx = ContextWithHashedRefreshToken(ctx, hashedToken) // needed by RevokeRefreshTokenHandler
+		ctx = ContextWithSessionID(ctx, refreshTokenRow.SessionID)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// RequireValidClientCredentials checks the client credentials used to authenticate service accounts.
+// RequireValidClientCredentials authenticates service accounts.
+//
+// Service accounts can authenticate with:
+//   - a client ID/client secret, supplied using HTTP Basic Auth or in the request body (client_secret_basic, client_secret_post)
+//   - a JWT client assertion signed with a key registered for the service account (private_key_jwt - RFC 7523)
+//   - a TLS client certificate for a key registered for the service account (self_signed_tls_client_auth - RFC 8705).
+//     This is only available when TLS_CLIENT_CERT_HEADER is configured.
 //
-// The Client ID/Client Secret is extracted from the request body.
 // No bearer token required - authentication is done using the client credentials alone.
+// When the client authenticates with a TLS client certificate the certificate thumbprint is added to the context,
+// and access tokens created for the request are bound to the certificate.
+// The id of the client secret or key used to authenticate is added to the context (the sid claim in access tokens created for the request).
 //
 // This middleware adds the client_id to the log attributes in context so they
 // are included in the request log for any requests authenticated with client credentials.
 func (a *AuthService) RequireValidClientCredentials(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		var clientID, clientSecret string
-
-		// support HTTP Basic Auth or form body (RFC 6749)
-		if id, secret, ok := r.BasicAuth(); ok {
-			clientID = id
-			clientSecret = secret
-		} else {
-			clientID = r.PostFormValue("client_id")
-			clientSecret = r.PostFormValue("client_secret")
-		}
-
-		if clientID == "" || clientSecret == "" {
-			responses.RenderError(w, r, apperrors.OAuthInvalidRequest("client_id and client_secret are required", apperrors.ErrCodeInvalidRequest, nil))
-			return
-		}
-
-		serviceAccount, err := a.queries.GetServiceAccountByClientID(r.Context(), clientID)
+		client, err := a.authenticateClient(r)
 		if err != nil {
-			logger.ContextWithLogAttrs(r.Context(),
-				slog.String("invalid_client_id", clientID),
-			)
-
-			responses.RenderError(w, r, apperrors.OAuthInvalidClient("invalid client_id", apperrors.ErrCodeAuthenticationFailure, nil))
-			return
-		}
-
-		// get account
-		account, err := a.queries.GetAccountByID(r.Context(), serviceAccount.AccountID)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				logger.ContextWithLogAttrs(r.Context(),
-					slog.String("client_id", clientID),
-				)
-
-				responses.RenderError(w, r, apperrors.InternalError("account not found", err))
-				return
-			}
-			logger.ContextWithLogAttrs(r.Context(),
-				slog.String("client_id", clientID),
-			)
-
-			responses.RenderError(w, r, apperrors.InternalError("database error", err))
-			return
-		}
-
-		// check for disabled accounts
-		if !account.IsActive {
-			logger.ContextWithLogAttrs(r.Context(),
-				slog.String("client_id", clientID),
-				slog.String("account_id", serviceAccount.AccountID.String()),
-			)
-
-			responses.RenderError(w, r, apperrors.OAuthInvalidClient("account is disabled", apperrors.ErrCodeAuthorizationFailure, nil))
-			return
-		}
-
-		// check the secret is valid
-		hashedSecret := a.HashToken(clientSecret)
-
-		_, err = a.queries.GetValidClientSecretByHashedSecret(r.Context(), hashedSecret)
-		if err != nil {
-			logger.ContextWithLogAttrs(r.Context(),
-				slog.String("client_id", clientID),
-			)
-
-			responses.RenderError(w, r, apperrors.OAuthInvalidClient("invalid client secret", apperrors.ErrCodeAuthenticationFailure, nil))
+			responses.RenderError(w, r, err)
 			return
 		}
 
@@ -260,17 +216,22 @@ func (a *AuthService) RequireValidClientCredentials(next http.Handler) http.Hand
 		// Log successful client credentials validation
 		reqLogger.Debug("Client credentials validation successful",
 			slog.String("component", "signalsd.RequireValidClientCredentials"),
-			slog.String("client_id", clientID),
-			slog.String("account_id", serviceAccount.AccountID.String()),
+			slog.String("client_id", client.serviceAccount.ClientID),
+			slog.String("account_id", client.serviceAccount.AccountID.String()),
+			slog.String("client_auth_method", client.method),
 		)
 
 		logger.ContextWithLogAttrs(r.Context(),
-			slog.String("client_id", clientID),
-			slog.String("account_id", serviceAccount.AccountID.String()),
+			slog.String("account_id", client.serviceAccount.AccountID.String()),
+			slog.String("client_auth_method", client.method),
 		)
 
-		ctx := ContextWithAccountID(r.Context(), serviceAccount.AccountID)
+		ctx := ContextWithAccountID(r.Context(), client.serviceAccount.AccountID)
 		ctx = ContextWithAccountType(ctx, "service_account")
+		ctx = ContextWithSessionID(ctx, client.credentialID)
+		if client.certThumbprint != "" {
+			ctx = ContextWithCertificateThumbprint(ctx, client.certThumbprint)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -337,11 +298,11 @@ func (a *AuthService) RequireNonRevokedClientCredentials(next http.Handler) http
 			return
 		}
 
-		// check the secret is valid
+		// check the secret is valid and was issued to this client
 		hashedSecret := a.HashToken(clientSecret)
 
-		_, err = a.queries.GetNonRevokedClientSecretByHashedSecret(r.Context(), hashedSecret)
-		if err != nil {
+		secret, err := a.queries.GetNonRevokedClientSecretByHashedSecret(r.Context(), hashedSecret)
+		if err != nil || secret.ServiceAccountAccountID != serviceAccount.AccountID {
 			logger.ContextWithLogAttrs(r.Context(),
 				slog.String("client_id", clientID),
 			)
@@ -485,7 +446,7 @@ func (a *AuthService) RequireIsnMembership(next http.Handler) http.Handler {
 
 		perms, ok := claims.IsnPerms[isnSlug]
 		if !ok {
-			responses.RenderError(w, r, apperrors.Forbidden("account does not have access to this isn", nil))
+			responses.RenderError(w, r, apperrors.Forbidden("account does not have access to this isn"+scopeNote(claims), nil))
 			return
 		}
 
@@ -12,7 +12,7 @@ import (
 func checkIsnAccess(claims *Claims, isnSlug string) (IsnPerm, error) {
 	perms, ok := claims.IsnPerms[isnSlug]
 	if !ok {
-		return IsnPerm{}, apperrors.Forbidden(fmt.Sprintf("account does not have access to ISN %q", isnSlug), nil)
+		return IsnPerm{}, apperrors.Forbidden(fmt.Sprintf("account does not have access to ISN %q%s", isnSlug, scopeNote(claims)), nil)
 	}
 	if !perms.InUse {
 		return IsnPerm{}, apperrors.NotFound(fmt.Sprintf("ISN not in use (%s)", isnSlug), nil)
@@ -45,7 +45,7 @@ func CheckIsnReadPermission(claims *Claims, isnSlug, signalTypePath string) erro
 		return err
 	}
 	if !perms.CanRead {
-		return apperrors.Forbidden(fmt.Sprintf("account does not have read permission on ISN %q", isnSlug), nil)
+		return apperrors.Forbidden(fmt.Sprintf("account does not have read permission on ISN %q%s", isnSlug, scopeNote(claims)), nil)
 	}
 	return checkSignalType(perms, isnSlug, signalTypePath)
 }
@@ -59,7 +59,16 @@ func CheckIsnWritePermission(claims *Claims, isnSlug, signalTypePath string) err
 		return err
 	}
 	if !perms.CanWrite {
-		return apperrors.Forbidden(fmt.Sprintf("account does not have write permission on ISN %q", isnSlug), nil)
+		return apperrors.Forbidden(fmt.Sprintf("account does not have write permission on ISN %q%s", isnSlug, scopeNote(claims)), nil)
 	}
 	return checkSignalType(perms, isnSlug, signalTypePath)
 }
+
+// scopeNote is added to permission errors for scoped access tokens -
+// the account may have been granted access that was not included in the token
+func scopeNote(claims *Claims) string {
+	if claims.Scope == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (access token scope: %s)", claims.Scope)
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Scope limits a service account access token to a subset of the ISN permissions granted to the account.
+//
+// Scopes are requested on the client_credentials grant as a space separated list of isn:{isn_slug}:{permission} values,
+// where permission is read or write - e.g "isn:port-isn:write isn:customs-isn:read".
+// Request both isn:{isn_slug}:read and isn:{isn_slug}:write for read-write access.
+//
+// The access token claims only include the ISNs in the scope, so the permission checks in
+// RequireAccessPermission and RequireIsnMembership apply the scope without any further checks.
+type Scope map[string]IsnScope // key is the isn slug
+
+// IsnScope is the access requested for an ISN
+type IsnScope struct {
+	Read  bool
+	Write bool
+}
+
+// ErrInvalidScope is returned when the scope is malformed or requests access the account has not been granted
+var ErrInvalidScope = errors.New("invalid scope")
+
+// ParseScope parses the scope parameter supplied on the token request - nil is returned if no scope was supplied.
+func ParseScope(scope string) (Scope, error) {
+	values := strings.Fields(scope)
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	s := make(Scope)
+	for _, value := range values {
+		parts := strings.Split(value, ":")
+		if len(parts) != 3 || parts[0] != "isn" || parts[1] == "" {
+			return nil, fmt.Errorf("%w: %q is not in the format isn:{isn_slug}:{read|write}", ErrInvalidScope, value)
+		}
+
+		isnScope := s[parts[1]]
+		switch parts[2] {
+		case "read":
+			isnScope.Read = true
+		case "write":
+			isnScope.Write = true
+		default:
+			return nil, fmt.Errorf("%w: unknown permission %q in %q (must be read or write)", ErrInvalidScope, parts[2], value)
+		}
+		s[parts[1]] = isnScope
+	}
+	return s, nil
+}
+
+// String returns the scope in the format used in the scope parameter (values are sorted so the same scope always produces the same string)
+func (s Scope) String() string {
+	values := make([]string, 0, len(s))
+	for isnSlug, isnScope := range s {
+		if isnScope.Read {
+			values = append(values, fmt.Sprintf("isn:%s:read", isnSlug))
+		}
+		if isnScope.Write {
+			values = append(values, fmt.Sprintf("isn:%s:write", isnSlug))
+		}
+	}
+	slices.Sort(values)
+	return strings.Join(values, " ")
+}
+
+// restrict narrows the account's ISN permissions to the scope.
+//
+// An ErrInvalidScope error is returned if the scope includes an ISN or permission the account has not been granted -
+// the scope can only remove access, never add it.
+func (s Scope) restrict(isnPerms map[string]IsnPerm) (map[string]IsnPerm, error) {
+	restricted := make(map[string]IsnPerm, len(s))
+	for isnSlug, isnScope := range s {
+		perm, ok := isnPerms[isnSlug]
+		if !ok {
+			return nil, fmt.Errorf("%w: account does not have access to ISN %q", ErrInvalidScope, isnSlug)
+		}
+		if isnScope.Read && !perm.CanRead {
+			return nil, fmt.Errorf("%w: account does not have read permission on ISN %q", ErrInvalidScope, isnSlug)
+		}
+		if isnScope.Write && !perm.CanWrite {
+			return nil, fmt.Errorf("%w: account does not have write permission on ISN %q", ErrInvalidScope, isnSlug)
+		}
+
+		perm.CanRead = isnScope.Read
+		perm.CanWrite = isnScope.Write
+		perm.CanAdminister = false
+		restricted[isnSlug] = perm
+	}
+	return restricted, nil
+}
+
+// intersect narrows isnPerms to the scope. Unlike restrict, access in the scope that the account no longer has is dropped
+// rather than treated as an error (used when the permissions are reloaded after the token was issued).
+func (s Scope) intersect(isnPerms map[string]IsnPerm) map[string]IsnPerm {
+	intersected := make(map[string]IsnPerm, len(s))
+	for isnSlug, isnScope := range s {
+		perm, ok := isnPerms[isnSlug]
+		if !ok {
+			continue
+		}
+		perm.CanRead = isnScope.Read && perm.CanRead
+		perm.CanWrite = isnScope.Write && perm.CanWrite
+		perm.CanAdminister = false
+		if !perm.CanRead && !perm.CanWrite {
+			continue
+		}
+		intersected[isnSlug] = perm
+	}
+	return intersected
+}
@@ -108,3 +108,39 @@ func (q *Queries) GetAccountByID(ctx context.Context, id uuid.UUID) (GetAccountB
 	)
 	return i, err
 }
+
+const IsSessionActive = `-- name: IsSessionActive :one
+SELECT (
+    EXISTS (
+        SELECT 1 FROM client_secrets cs
+        WHERE cs.id = $1
+            AND cs.service_account_account_id = $2
+            AND (cs.revoked_at IS NULL OR cs.revoked_at > NOW())
+    ) OR EXISTS (
+        SELECT 1 FROM service_account_keys sak
+        WHERE sak.id = $1
+            AND sak.service_account_account_id = $2
+            AND sak.revoked_at IS NULL
+    ) OR EXISTS (
+        SELECT 1 FROM refresh_tokens rt
+        WHERE rt.session_id = $1
+            AND rt.user_account_id = $2
+            AND rt.revoked_at IS NULL
+    )
+)::boolean AS is_active
+`
+
+type IsSessionActiveParams struct {
+	SessionID uuid.UUID `json:"session_id"`
+	AccountID uuid.UUID `json:"account_id"`
+}
+
+// Reports whether the credential identified by the sid claim of an access token is still valid for the account:
+// the client secret or public key (service accounts) has not been revoked, or the session has a refresh token that has not been revoked (web users).
+// Used by token introspection: access tokens are reported as inactive once the credential used to obtain them has been revoked.
+func (q *Queries) IsSessionActive(ctx context.Context, arg IsSessionActiveParams) (bool, error) {
+	row := q.db.QueryRow(ctx, IsSessionActive, arg.SessionID, arg.AccountID)
+	var is_active bool
+	err := row.Scan(&is_active)
+	return is_active, err
+}
@@ -21,32 +21,36 @@ INSERT INTO routing_rules (
     operator,
     is_case_insensitive,
     isn_id,
-    rule_sequence
-) VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6)
-RETURNING id, signal_routing_config_id, match_pattern, operator, is_case_insensitive, isn_id, rule_sequence, created_at
+    rule_sequence,
+    condition
+) VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6, $7)
+RETURNING id, signal_routing_config_id, match_pattern, operator, is_case_insensitive, isn_id, rule_sequence, condition, created_at
 `
 
 type CreateIsnRouteParams struct {
 	SignalRoutingConfigID uuid.UUID `json:"signal_routing_config_id"`
-	MatchPattern          string    `json:"match_pattern"`
-	Operator              string    `json:"operator"`
+	MatchPattern          *string   `json:"match_pattern"`
+	Operator              *string   `json:"operator"`
 	IsCaseInsensitive     bool      `json:"is_case_insensitive"`
 	IsnID                 uuid.UUID `json:"isn_id"`
 	RuleSequence          int32     `json:"rule_sequence"`
+	Condition             []byte    `json:"condition"`
 }
 
 type CreateIsnRouteRow struct {
 	ID                    uuid.UUID `json:"id"`
 	SignalRoutingConfigID uuid.UUID `json:"signal_routing_config_id"`
-	MatchPattern          string    `json:"match_pattern"`
-	Operator              string    `json:"operator"`
+	MatchPattern          *string   `json:"match_pattern"`
+	Operator              *string   `json:"operator"`
 	IsCaseInsensitive     bool      `json:"is_case_insensitive"`
 	IsnID                 uuid.UUID `json:"isn_id"`
 	RuleSequence          int32     `json:"rule_sequence"`
+	Condition             []byte    `json:"condition"`
 	CreatedAt             time.Time `json:"created_at"`
 }
 
 // Create a route (match pattern -> isn) for a specific signal type routing field
+// Compound rules supply a condition instead of the match pattern and operator.
 func (q *Queries) CreateIsnRoute(ctx context.Context, arg CreateIsnRouteParams) (CreateIsnRouteRow, error) {
 	row := q.db.QueryRow(ctx, CreateIsnRoute,
 		arg.SignalRoutingConfigID,
@@ -55,6 +59,7 @@ func (q *Queries) CreateIsnRoute(ctx context.Context, arg CreateIsnRouteParams)
 		arg.IsCaseInsensitive,
 		arg.IsnID,
 		arg.RuleSequence,
+		arg.Condition,
 	)
 	var i CreateIsnRouteRow
 	err := row.Scan(
@@ -65,6 +70,7 @@ func (q *Queries) CreateIsnRoute(ctx context.Context, arg CreateIsnRouteParams)
 		&i.IsCaseInsensitive,
 		&i.IsnID,
 		&i.RuleSequence,
+		&i.Condition,
 		&i.CreatedAt,
 	)
 	return i, err
@@ -75,32 +81,45 @@ INSERT INTO signal_routing_configs (
     id,
     created_at,
     signal_type_id, 
-    routing_field 
-) VALUES (gen_random_uuid(), NOW(), $1, $2)
-RETURNING id, signal_type_id, routing_field, created_at
+    routing_field,
+    routing_mode,
+    fallback_isn_id
+) VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4)
+RETURNING id, signal_type_id, routing_field, routing_mode, fallback_isn_id, created_at
 `
 
 type CreateSignalRoutingConfigParams struct {
-	SignalTypeID uuid.UUID `json:"signal_type_id"`
-	RoutingField string    `json:"routing_field"`
+	SignalTypeID  uuid.UUID  `json:"signal_type_id"`
+	RoutingField  *string    `json:"routing_field"`
+	RoutingMode   string     `json:"routing_mode"`
+	FallbackIsnID *uuid.UUID `json:"fallback_isn_id"`
 }
 
 type CreateSignalRoutingConfigRow struct {
-	ID           uuid.UUID `json:"id"`
-	SignalTypeID uuid.UUID `json:"signal_type_id"`
-	RoutingField string    `json:"routing_field"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            uuid.UUID  `json:"id"`
+	SignalTypeID  uuid.UUID  `json:"signal_type_id"`
+	RoutingField  *string    `json:"routing_field"`
+	RoutingMode   string     `json:"routing_mode"`
+	FallbackIsnID *uuid.UUID `json:"fallback_isn_id"`
+	CreatedAt     time.Time  `json:"created_at"`
 }
 
 // Creates the routing field entry for a signal_type path.
 // Note RouteConfigs are always loaded atomically - use DeleteSignalRoutingConfigBySignalTypeID to clear any previous config before using.
 func (q *Queries) CreateSignalRoutingConfig(ctx context.Context, arg CreateSignalRoutingConfigParams) (CreateSignalRoutingConfigRow, error) {
-	row := q.db.QueryRow(ctx, CreateSignalRoutingConfig, arg.SignalTypeID, arg.RoutingField)
+	row := q.db.QueryRow(ctx, CreateSignalRoutingConfig,
+		arg.SignalTypeID,
+		arg.RoutingField,
+		arg.RoutingMode,
+		arg.FallbackIsnID,
+	)
 	var i CreateSignalRoutingConfigRow
 	err := row.Scan(
 		&i.ID,
 		&i.SignalTypeID,
 		&i.RoutingField,
+		&i.RoutingMode,
+		&i.FallbackIsnID,
 		&i.CreatedAt,
 	)
 	return i, err
@@ -129,7 +148,8 @@ SELECT
     ir.operator,
     ir.is_case_insensitive,
     ir.rule_sequence,
-    i.slug AS isn_slug
+    i.slug AS isn_slug,
+    ir.condition
 FROM routing_rules ir
 JOIN isn i ON i.id = ir.isn_id
 WHERE ir.signal_routing_config_id = $1
@@ -138,11 +158,12 @@ ORDER BY ir.rule_sequence ASC
 
 type GetIsnRoutesByFieldIDRow struct {
 	ID                uuid.UUID `json:"id"`
-	MatchPattern      string    `json:"match_pattern"`
-	Operator          string    `json:"operator"`
+	MatchPattern      *string   `json:"match_pattern"`
+	Operator          *string   `json:"operator"`
 	IsCaseInsensitive bool      `json:"is_case_insensitive"`
 	RuleSequence      int32     `json:"rule_sequence"`
 	IsnSlug           string    `json:"isn_slug"`
+	Condition         []byte    `json:"condition"`
 }
 
 // Fetches all routes for a specific field ID, ordered by their evaluation sequence
@@ -162,6 +183,7 @@ func (q *Queries) GetIsnRoutesByFieldID(ctx context.Context, signalRoutingConfig
 			&i.IsCaseInsensitive,
 			&i.RuleSequence,
 			&i.IsnSlug,
+			&i.Condition,
 		); err != nil {
 			return nil, err
 		}
@@ -174,7 +196,7 @@ func (q *Queries) GetIsnRoutesByFieldID(ctx context.Context, signalRoutingConfig
 }
 
 const GetSignalRoutingConfigBySignalType = `-- name: GetSignalRoutingConfigBySignalType :one
-SELECT irf.id, irf.created_at, irf.signal_type_id, irf.routing_field
+SELECT irf.id, irf.created_at, irf.signal_type_id, irf.routing_field, irf.routing_mode, irf.fallback_isn_id
 FROM signal_routing_configs irf
 JOIN signal_types st ON st.id = irf.signal_type_id
 WHERE st.slug = $1
@@ -195,6 +217,8 @@ func (q *Queries) GetSignalRoutingConfigBySignalType(ctx context.Context, arg Ge
 		&i.CreatedAt,
 		&i.SignalTypeID,
 		&i.RoutingField,
+		&i.RoutingMode,
+		&i.FallbackIsnID,
 	)
 	return i, err
 }
@@ -209,27 +233,37 @@ SELECT
     ir.is_case_insensitive,
     ir.isn_id,
     i.slug AS isn_slug,
-    ir.rule_sequence
+    ir.rule_sequence,
+    ir.condition,
+    irf.routing_mode,
+    irf.fallback_isn_id,
+    fb.slug AS fallback_isn_slug
 FROM signal_routing_configs irf
 JOIN signal_types st ON st.id = irf.signal_type_id
 JOIN routing_rules ir ON ir.signal_routing_config_id = irf.id
 JOIN isn i ON i.id = ir.isn_id
+LEFT JOIN isn fb ON fb.id = irf.fallback_isn_id
 ORDER BY st.slug, st.sem_ver, ir.rule_sequence ASC
 `
 
 type GetSignalRoutingConfigsRow struct {
-	SignalTypeSlug    string    `json:"signal_type_slug"`
-	SemVer            string    `json:"sem_ver"`
-	RoutingField      string    `json:"routing_field"`
-	MatchPattern      string    `json:"match_pattern"`
-	Operator          string    `json:"operator"`
-	IsCaseInsensitive bool      `json:"is_case_insensitive"`
-	IsnID             uuid.UUID `json:"isn_id"`
-	IsnSlug           string    `json:"isn_slug"`
-	RuleSequence      int32     `json:"rule_sequence"`
+	SignalTypeSlug    string     `json:"signal_type_slug"`
+	SemVer            string     `json:"sem_ver"`
+	RoutingField      *string    `json:"routing_field"`
+	MatchPattern      *string    `json:"match_pattern"`
+	Operator          *string    `json:"operator"`
+	IsCaseInsensitive bool       `json:"is_case_insensitive"`
+	IsnID             uuid.UUID  `json:"isn_id"`
+	IsnSlug           string     `json:"isn_slug"`
+	RuleSequence      int32      `json:"rule_sequence"`
+	Condition         []byte     `json:"condition"`
+	RoutingMode       string     `json:"routing_mode"`
+	FallbackIsnID     *uuid.UUID `json:"fallback_isn_id"`
+	FallbackIsnSlug   *string    `json:"fallback_isn_slug"`
 }
 
-// Loads all signal types that have a routing field and at least one route defined
+// Loads all signal types that have a routing config and at least one route defined
+// (the fallback ISN is repeated on each rule row)
 func (q *Queries) GetSignalRoutingConfigs(ctx context.Context) ([]GetSignalRoutingConfigsRow, error) {
 	rows, err := q.db.Query(ctx, GetSignalRoutingConfigs)
 	if err != nil {
@@ -249,6 +283,10 @@ func (q *Queries) GetSignalRoutingConfigs(ctx context.Context) ([]GetSignalRouti
 			&i.IsnID,
 			&i.IsnSlug,
 			&i.RuleSequence,
+			&i.Condition,
+			&i.RoutingMode,
+			&i.FallbackIsnID,
+			&i.FallbackIsnSlug,
 		); err != nil {
 			return nil, err
 		}
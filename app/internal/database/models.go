@@ -19,6 +19,12 @@ type Account struct {
 	IsActive    bool      `json:"is_active"`
 }
 
+type ClientAssertion struct {
+	ServiceAccountAccountID uuid.UUID `json:"service_account_account_id"`
+	Jti                     string    `json:"jti"`
+	ExpiresAt               time.Time `json:"expires_at"`
+}
+
 type ClientSecret struct {
 	HashedSecret            string     `json:"hashed_secret"`
 	CreatedAt               time.Time  `json:"created_at"`
@@ -26,6 +32,18 @@ type ClientSecret struct {
 	ServiceAccountAccountID uuid.UUID  `json:"service_account_account_id"`
 	ExpiresAt               time.Time  `json:"expires_at"`
 	RevokedAt               *time.Time `json:"revoked_at"`
+	ID                      uuid.UUID  `json:"id"`
+}
+
+type IdempotencyKey struct {
+	AccountID      uuid.UUID `json:"account_id"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	RequestHash    string    `json:"request_hash"`
+	ResponseStatus *int32    `json:"response_status"`
+	ResponseBody   []byte    `json:"response_body"`
+	LockedUntil    time.Time `json:"locked_until"`
 }
 
 type Isn struct {
@@ -69,6 +87,24 @@ type LatestSignalVersion struct {
 	Content       json.RawMessage `json:"content"`
 }
 
+type OidcLoginCode struct {
+	HashedCode    string    `json:"hashed_code"`
+	CreatedAt     time.Time `json:"created_at"`
+	UserAccountID uuid.UUID `json:"user_account_id"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+type OidcLoginRequest struct {
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	ProviderID   string    `json:"provider_id"`
+	State        string    `json:"state"`
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"code_verifier"`
+	ReturnTo     *string   `json:"return_to"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
 type OneTimeClientSecret struct {
 	ID                      uuid.UUID `json:"id"`
 	CreatedAt               time.Time `json:"created_at"`
@@ -92,17 +128,19 @@ type RefreshToken struct {
 	UpdatedAt     time.Time  `json:"updated_at"`
 	ExpiresAt     time.Time  `json:"expires_at"`
 	RevokedAt     *time.Time `json:"revoked_at"`
+	SessionID     uuid.UUID  `json:"session_id"`
 }
 
 type RoutingRule struct {
 	ID                    uuid.UUID `json:"id"`
 	CreatedAt             time.Time `json:"created_at"`
 	SignalRoutingConfigID uuid.UUID `json:"signal_routing_config_id"`
-	MatchPattern          string    `json:"match_pattern"`
-	Operator              string    `json:"operator"`
+	MatchPattern          *string   `json:"match_pattern"`
+	Operator              *string   `json:"operator"`
 	IsCaseInsensitive     bool      `json:"is_case_insensitive"`
 	IsnID                 uuid.UUID `json:"isn_id"`
 	RuleSequence          int32     `json:"rule_sequence"`
+	Condition             []byte    `json:"condition"`
 }
 
 type ServiceAccount struct {
@@ -114,6 +152,15 @@ type ServiceAccount struct {
 	ClientOrganization string    `json:"client_organization"`
 }
 
+type ServiceAccountKey struct {
+	ID                      uuid.UUID  `json:"id"`
+	CreatedAt               time.Time  `json:"created_at"`
+	ServiceAccountAccountID uuid.UUID  `json:"service_account_account_id"`
+	Algorithm               string     `json:"algorithm"`
+	PublicKey               []byte     `json:"public_key"`
+	RevokedAt               *time.Time `json:"revoked_at"`
+}
+
 type Signal struct {
 	ID            uuid.UUID `json:"id"`
 	CreatedAt     time.Time `json:"created_at"`
@@ -134,22 +181,70 @@ type SignalBatch struct {
 	AccountID uuid.UUID `json:"account_id"`
 }
 
+type SignalEvent struct {
+	ID              uuid.UUID  `json:"id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	TxID            int64      `json:"tx_id"`
+	SignalID        uuid.UUID  `json:"signal_id"`
+	AccountID       uuid.UUID  `json:"account_id"`
+	EventType       string     `json:"event_type"`
+	SignalVersionID *uuid.UUID `json:"signal_version_id"`
+	VersionNumber   *int32     `json:"version_number"`
+	ActorAccountID  *uuid.UUID `json:"actor_account_id"`
+	Reason          *string    `json:"reason"`
+}
+
 type SignalProcessingFailure struct {
-	ID               uuid.UUID `json:"id"`
-	CreatedAt        time.Time `json:"created_at"`
-	SignalBatchID    uuid.UUID `json:"signal_batch_id"`
-	SignalTypeSlug   string    `json:"signal_type_slug"`
-	SignalTypeSemVer string    `json:"signal_type_sem_ver"`
-	LocalRef         string    `json:"local_ref"`
-	ErrorCode        string    `json:"error_code"`
-	ErrorMessage     string    `json:"error_message"`
+	ID               uuid.UUID  `json:"id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	SignalBatchID    uuid.UUID  `json:"signal_batch_id"`
+	SignalTypeSlug   string     `json:"signal_type_slug"`
+	SignalTypeSemVer string     `json:"signal_type_sem_ver"`
+	LocalRef         string     `json:"local_ref"`
+	ErrorCode        string     `json:"error_code"`
+	ErrorMessage     string     `json:"error_message"`
+	IsnID            *uuid.UUID `json:"isn_id"`
 }
 
 type SignalRoutingConfig struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	SignalTypeID uuid.UUID `json:"signal_type_id"`
-	RoutingField string    `json:"routing_field"`
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SignalTypeID  uuid.UUID  `json:"signal_type_id"`
+	RoutingField  *string    `json:"routing_field"`
+	RoutingMode   string     `json:"routing_mode"`
+	FallbackIsnID *uuid.UUID `json:"fallback_isn_id"`
+}
+
+type SignalRoutingConfigRevision struct {
+	ID                   uuid.UUID  `json:"id"`
+	CreatedAt            time.Time  `json:"created_at"`
+	SignalTypeID         uuid.UUID  `json:"signal_type_id"`
+	Revision             int32      `json:"revision"`
+	AccountID            *uuid.UUID `json:"account_id"`
+	Action               string     `json:"action"`
+	Config               []byte     `json:"config"`
+	RolledBackToRevision *int32     `json:"rolled_back_to_revision"`
+}
+
+type SignalSubmissionQueue struct {
+	ID             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	AccountID      uuid.UUID       `json:"account_id"`
+	SignalBatchID  uuid.UUID       `json:"signal_batch_id"`
+	IsnSlug        *string         `json:"isn_slug"`
+	SignalTypeSlug string          `json:"signal_type_slug"`
+	SemVer         string          `json:"sem_ver"`
+	Claims         json.RawMessage `json:"claims"`
+	RequestBody    json.RawMessage `json:"request_body"`
+	Status         string          `json:"status"`
+	AttemptCount   int32           `json:"attempt_count"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	CompletedAt    *time.Time      `json:"completed_at"`
+	ResponseStatus *int32          `json:"response_status"`
+	ResponseBody   []byte          `json:"response_body"`
+	LastError      *string         `json:"last_error"`
+	RoutingContext []byte          `json:"routing_context"`
 }
 
 type SignalType struct {
@@ -173,6 +268,7 @@ type SignalVersion struct {
 	SignalID      uuid.UUID       `json:"signal_id"`
 	VersionNumber int32           `json:"version_number"`
 	Content       json.RawMessage `json:"content"`
+	TxID          int64           `json:"tx_id"`
 }
 
 type SignalVersionsP0 struct {
@@ -183,6 +279,7 @@ type SignalVersionsP0 struct {
 	SignalID      uuid.UUID       `json:"signal_id"`
 	VersionNumber int32           `json:"version_number"`
 	Content       json.RawMessage `json:"content"`
+	TxID          int64           `json:"tx_id"`
 }
 
 type SignalVersionsP1 struct {
@@ -193,6 +290,7 @@ type SignalVersionsP1 struct {
 	SignalID      uuid.UUID       `json:"signal_id"`
 	VersionNumber int32           `json:"version_number"`
 	Content       json.RawMessage `json:"content"`
+	TxID          int64           `json:"tx_id"`
 }
 
 type SignalVersionsP2 struct {
@@ -203,6 +301,7 @@ type SignalVersionsP2 struct {
 	SignalID      uuid.UUID       `json:"signal_id"`
 	VersionNumber int32           `json:"version_number"`
 	Content       json.RawMessage `json:"content"`
+	TxID          int64           `json:"tx_id"`
 }
 
 type SignalVersionsP3 struct {
@@ -213,6 +312,7 @@ type SignalVersionsP3 struct {
 	SignalID      uuid.UUID       `json:"signal_id"`
 	VersionNumber int32           `json:"version_number"`
 	Content       json.RawMessage `json:"content"`
+	TxID          int64           `json:"tx_id"`
 }
 
 type SignalVersionsP4 struct {
@@ -223,6 +323,7 @@ type SignalVersionsP4 struct {
 	SignalID      uuid.UUID       `json:"signal_id"`
 	VersionNumber int32           `json:"version_number"`
 	Content       json.RawMessage `json:"content"`
+	TxID          int64           `json:"tx_id"`
 }
 
 type SignalVersionsP5 struct {
@@ -233,6 +334,7 @@ type SignalVersionsP5 struct {
 	SignalID      uuid.UUID       `json:"signal_id"`
 	VersionNumber int32           `json:"version_number"`
 	Content       json.RawMessage `json:"content"`
+	TxID          int64           `json:"tx_id"`
 }
 
 type SignalVersionsP6 struct {
@@ -243,6 +345,7 @@ type SignalVersionsP6 struct {
 	SignalID      uuid.UUID       `json:"signal_id"`
 	VersionNumber int32           `json:"version_number"`
 	Content       json.RawMessage `json:"content"`
+	TxID          int64           `json:"tx_id"`
 }
 
 type SignalVersionsP7 struct {
@@ -253,6 +356,7 @@ type SignalVersionsP7 struct {
 	SignalID      uuid.UUID       `json:"signal_id"`
 	VersionNumber int32           `json:"version_number"`
 	Content       json.RawMessage `json:"content"`
+	TxID          int64           `json:"tx_id"`
 }
 
 type SignalsP0 struct {
@@ -359,6 +463,32 @@ type SignalsP7 struct {
 	IsArchived    bool      `json:"is_archived"`
 }
 
+type SigningKey struct {
+	ID                  uuid.UUID  `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	Algorithm           string     `json:"algorithm"`
+	PublicKey           []byte     `json:"public_key"`
+	EncryptedPrivateKey []byte     `json:"encrypted_private_key"`
+	ActivatesAt         time.Time  `json:"activates_at"`
+	RetiresAt           *time.Time `json:"retires_at"`
+}
+
+type UnroutableSignal struct {
+	ID                  uuid.UUID       `json:"id"`
+	CreatedAt           time.Time       `json:"created_at"`
+	AccountID           uuid.UUID       `json:"account_id"`
+	SignalBatchID       uuid.UUID       `json:"signal_batch_id"`
+	SignalTypeSlug      string          `json:"signal_type_slug"`
+	SemVer              string          `json:"sem_ver"`
+	LocalRef            string          `json:"local_ref"`
+	Content             json.RawMessage `json:"content"`
+	Claims              json.RawMessage `json:"claims"`
+	ErrorMessage        string          `json:"error_message"`
+	ReroutedAt          *time.Time      `json:"rerouted_at"`
+	ReroutedByAccountID *uuid.UUID      `json:"rerouted_by_account_id"`
+	RoutingContext      []byte          `json:"routing_context"`
+}
+
 type User struct {
 	AccountID      uuid.UUID `json:"account_id"`
 	CreatedAt      time.Time `json:"created_at"`
@@ -367,3 +497,40 @@ type User struct {
 	HashedPassword string    `json:"hashed_password"`
 	UserRole       string    `json:"user_role"`
 }
+
+type UserIdentity struct {
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UserAccountID uuid.UUID `json:"user_account_id"`
+	ProviderID    string    `json:"provider_id"`
+	Subject       string    `json:"subject"`
+	Email         string    `json:"email"`
+	LastLoginAt   time.Time `json:"last_login_at"`
+}
+
+type WebhookDelivery struct {
+	ID                    uuid.UUID  `json:"id"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+	WebhookSubscriptionID uuid.UUID  `json:"webhook_subscription_id"`
+	SignalVersionID       uuid.UUID  `json:"signal_version_id"`
+	SignalAccountID       uuid.UUID  `json:"signal_account_id"`
+	Status                string     `json:"status"`
+	AttemptCount          int32      `json:"attempt_count"`
+	NextAttemptAt         time.Time  `json:"next_attempt_at"`
+	LastAttemptAt         *time.Time `json:"last_attempt_at"`
+	LastResponseStatus    *int32     `json:"last_response_status"`
+	LastError             *string    `json:"last_error"`
+}
+
+type WebhookSubscription struct {
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	AccountID     uuid.UUID `json:"account_id"`
+	IsnID         uuid.UUID `json:"isn_id"`
+	SignalTypeID  uuid.UUID `json:"signal_type_id"`
+	TargetURL     string    `json:"target_url"`
+	SigningSecret string    `json:"signing_secret"`
+	IsActive      bool      `json:"is_active"`
+}
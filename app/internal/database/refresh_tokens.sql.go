@@ -45,7 +45,7 @@ func (q *Queries) GetRefreshToken(ctx context.Context, hashedToken string) (GetR
 }
 
 const GetValidRefreshTokenByHashedToken = `-- name: GetValidRefreshTokenByHashedToken :one
-SELECT user_account_id, expires_at
+SELECT user_account_id, expires_at, session_id
 FROM refresh_tokens
 WHERE hashed_token = $1
   AND revoked_at IS NULL
@@ -55,12 +55,13 @@ WHERE hashed_token = $1
 type GetValidRefreshTokenByHashedTokenRow struct {
 	UserAccountID uuid.UUID `json:"user_account_id"`
 	ExpiresAt     time.Time `json:"expires_at"`
+	SessionID     uuid.UUID `json:"session_id"`
 }
 
 func (q *Queries) GetValidRefreshTokenByHashedToken(ctx context.Context, hashedToken string) (GetValidRefreshTokenByHashedTokenRow, error) {
 	row := q.db.QueryRow(ctx, GetValidRefreshTokenByHashedToken, hashedToken)
 	var i GetValidRefreshTokenByHashedTokenRow
-	err := row.Scan(&i.UserAccountID, &i.ExpiresAt)
+	err := row.Scan(&i.UserAccountID, &i.ExpiresAt, &i.SessionID)
 	return i, err
 }
 
@@ -85,8 +86,8 @@ func (q *Queries) GetValidRefreshTokenByUserAccountId(ctx context.Context, userA
 }
 
 const InsertRefreshToken = `-- name: InsertRefreshToken :one
-INSERT INTO refresh_tokens (hashed_token, user_account_id, created_at, updated_at, expires_at)
-VALUES ( $1,$2, NOW(), NOW(), $3)
+INSERT INTO refresh_tokens (hashed_token, user_account_id, created_at, updated_at, expires_at, session_id)
+VALUES ( $1,$2, NOW(), NOW(), $3, $4)
 RETURNING hashed_token, user_account_id
 `
 
@@ -94,6 +95,7 @@ type InsertRefreshTokenParams struct {
 	HashedToken   string    `json:"hashed_token"`
 	UserAccountID uuid.UUID `json:"user_account_id"`
 	ExpiresAt     time.Time `json:"expires_at"`
+	SessionID     uuid.UUID `json:"session_id"`
 }
 
 type InsertRefreshTokenRow struct {
@@ -101,8 +103,14 @@ type InsertRefreshTokenRow struct {
 	UserAccountID uuid.UUID `json:"user_account_id"`
 }
 
+// session_id is the id of the login session (kept when the refresh token is rotated - see the sid access token claim)
 func (q *Queries) InsertRefreshToken(ctx context.Context, arg InsertRefreshTokenParams) (InsertRefreshTokenRow, error) {
-	row := q.db.QueryRow(ctx, InsertRefreshToken, arg.HashedToken, arg.UserAccountID, arg.ExpiresAt)
+	row := q.db.QueryRow(ctx, InsertRefreshToken,
+		arg.HashedToken,
+		arg.UserAccountID,
+		arg.ExpiresAt,
+		arg.SessionID,
+	)
 	var i InsertRefreshTokenRow
 	err := row.Scan(&i.HashedToken, &i.UserAccountID)
 	return i, err
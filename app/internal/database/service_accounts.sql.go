@@ -169,7 +169,7 @@ func (q *Queries) ExistsServiceAccountWithOrganizationAndEmail(ctx context.Conte
 }
 
 const GetNonRevokedClientSecretByHashedSecret = `-- name: GetNonRevokedClientSecretByHashedSecret :one
-SELECT hashed_secret, created_at, updated_at, service_account_account_id, expires_at, revoked_at FROM client_secrets
+SELECT hashed_secret, created_at, updated_at, service_account_account_id, expires_at, revoked_at, id FROM client_secrets
 WHERE hashed_secret = $1
 AND revoked_at IS NULL
 `
@@ -185,6 +185,7 @@ func (q *Queries) GetNonRevokedClientSecretByHashedSecret(ctx context.Context, h
 		&i.ServiceAccountAccountID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.ID,
 	)
 	return i, err
 }
@@ -310,7 +311,7 @@ func (q *Queries) GetServiceAccounts(ctx context.Context) ([]ServiceAccount, err
 }
 
 const GetValidClientSecretByHashedSecret = `-- name: GetValidClientSecretByHashedSecret :one
-SELECT hashed_secret, created_at, updated_at, service_account_account_id, expires_at, revoked_at FROM client_secrets
+SELECT hashed_secret, created_at, updated_at, service_account_account_id, expires_at, revoked_at, id FROM client_secrets
 WHERE hashed_secret = $1
 AND revoked_at IS NULL
 AND expires_at > NOW()
@@ -327,6 +328,7 @@ func (q *Queries) GetValidClientSecretByHashedSecret(ctx context.Context, hashed
 		&i.ServiceAccountAccountID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.ID,
 	)
 	return i, err
 }
@@ -26,7 +26,7 @@ WITH ids AS (
         AND st.sem_ver = $6
         AND i.is_in_use = true
         AND ist.is_in_use = true
-)
+), upserted AS (
 INSERT INTO signals (
     id,
     created_at,
@@ -50,7 +50,7 @@ SELECT
     false,
     false
 FROM ids 
-ON CONFLICT (account_id, signal_type_id, local_ref)
+ON CONFLICT (account_id, isn_id, signal_type_id, local_ref)
 DO UPDATE SET
     correlation_id = CASE
         WHEN signals.correlation_id != EXCLUDED.correlation_id THEN EXCLUDED.correlation_id
@@ -61,7 +61,14 @@ DO UPDATE SET
         ELSE signals.is_withdrawn
     END,
     updated_at = now()
-RETURNING id
+RETURNING id, account_id, old.is_withdrawn AS was_withdrawn
+), reinstated AS (
+    INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type, actor_account_id)
+    SELECT uuidv7(), now(), u.id, u.account_id, 'reinstated', u.account_id
+    FROM upserted u
+    WHERE u.was_withdrawn = true
+)
+SELECT id FROM upserted
 `
 
 type CreateOrUpdateSignalWithCorrelationIDParams struct {
@@ -75,7 +82,7 @@ type CreateOrUpdateSignalWithCorrelationIDParams struct {
 
 // note if there is already a master record for this local_ref, then:
 // 1. correlation_id is updated with the supplied value (assuming it is different to the existing value)
-// 2. if the signal was withdrawn it is reactivated (is_withdrawn = false).
+// 2. if the signal was withdrawn it is reactivated (is_withdrawn = false) and a reinstated event is recorded in signal_events.
 // Only creates/updates signals if ISN and signal type are in use (this is a defence against stale access tokens).
 func (q *Queries) CreateOrUpdateSignalWithCorrelationID(ctx context.Context, arg CreateOrUpdateSignalWithCorrelationIDParams) (uuid.UUID, error) {
 	row := q.db.QueryRow(ctx, CreateOrUpdateSignalWithCorrelationID,
@@ -104,7 +111,7 @@ WITH ids AS (
         AND st.sem_ver = $5
         AND i.is_in_use = true
         AND ist.is_in_use = true
-)
+), upserted AS (
 INSERT INTO signals (
     id,
     created_at,
@@ -128,14 +135,21 @@ SELECT
     false,
     false
 FROM ids
-ON CONFLICT (account_id, signal_type_id, local_ref)
+ON CONFLICT (account_id, isn_id, signal_type_id, local_ref)
 DO UPDATE SET
     is_withdrawn = false,
     updated_at = CASE 
         WHEN signals.is_withdrawn = true THEN now()
         ELSE signals.updated_at
     END
-RETURNING id
+RETURNING id, account_id, old.is_withdrawn AS was_withdrawn
+), reinstated AS (
+    INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type, actor_account_id)
+    SELECT uuidv7(), now(), u.id, u.account_id, 'reinstated', u.account_id
+    FROM upserted u
+    WHERE u.was_withdrawn = true
+)
+SELECT id FROM upserted
 `
 
 type CreateSignalParams struct {
@@ -146,8 +160,8 @@ type CreateSignalParams struct {
 	SemVer         string    `json:"sem_ver"`
 }
 
-// This query creates one row in the signals table for every new combination of account_id, signal_type_id, local_ref.
-// If a withdrawn signal is received again it is reactivated (is_withdrawn = false).
+// This query creates one row in the signals table for every new combination of account_id, isn_id, signal_type_id, local_ref.
+// If a withdrawn signal is received again it is reactivated (is_withdrawn = false) and a reinstated event is recorded in signal_events.
 // Only creates signals if ISN and signal type are in use (this is a defence against stale access tokens).
 // Returns the new signal_id.
 // deactivated records (is_withdrawn = true) are reactivated by resubmitting them - the update below ensures the updated_at timestamp is only changed if the record is reactivated
@@ -169,6 +183,7 @@ const CreateSignalVersion = `-- name: CreateSignalVersion :one
 WITH ver AS (
     SELECT 
         st.id AS signal_type_id,
+        i.id AS isn_id,
         COALESCE(
             (SELECT MAX(sv.version_number)
              FROM signal_versions sv
@@ -176,12 +191,14 @@ WITH ver AS (
                 ON s.id = sv.signal_id
              WHERE s.local_ref = $4
                 AND s.account_id = $1
-                AND s.signal_type_id = st.id)
+                AND s.signal_type_id = st.id
+                AND s.isn_id = i.id)
             , 0) + 1 as version_number
     FROM signal_types st
+    JOIN isn i ON i.slug = $7
     WHERE st.slug = $5
         AND st.sem_ver = $6
-)
+), new_version AS (
 INSERT INTO signal_versions (
     id,
     created_at,
@@ -202,9 +219,18 @@ SELECT
 FROM ver 
 JOIN signals s 
     ON s.signal_type_id = ver.signal_type_id
+    AND s.isn_id = ver.isn_id
     AND s.account_id = $1
     AND s.local_ref = $4
-RETURNING id, version_number
+RETURNING id, signal_id, account_id, version_number
+), version_event AS (
+    INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type, signal_version_id, version_number, actor_account_id)
+    SELECT uuidv7(), now(), v.signal_id, v.account_id,
+        CASE WHEN v.version_number = 1 THEN 'created' ELSE 'version_created' END,
+        v.id, v.version_number, v.account_id
+    FROM new_version v
+)
+SELECT id, version_number FROM new_version
 `
 
 type CreateSignalVersionParams struct {
@@ -214,6 +240,7 @@ type CreateSignalVersionParams struct {
 	LocalRef       string          `json:"local_ref"`
 	SignalTypeSlug string          `json:"signal_type_slug"`
 	SemVer         string          `json:"sem_ver"`
+	IsnSlug        string          `json:"isn_slug"`
 }
 
 type CreateSignalVersionRow struct {
@@ -222,6 +249,7 @@ type CreateSignalVersionRow struct {
 }
 
 // if there is already a version of this signal, create a new one with an incremented version_number
+// the new version is recorded in signal_events (created for the first version, otherwise version_created)
 func (q *Queries) CreateSignalVersion(ctx context.Context, arg CreateSignalVersionParams) (CreateSignalVersionRow, error) {
 	row := q.db.QueryRow(ctx, CreateSignalVersion,
 		arg.AccountID,
@@ -230,6 +258,7 @@ func (q *Queries) CreateSignalVersion(ctx context.Context, arg CreateSignalVersi
 		arg.LocalRef,
 		arg.SignalTypeSlug,
 		arg.SemVer,
+		arg.IsnSlug,
 	)
 	var i CreateSignalVersionRow
 	err := row.Scan(&i.ID, &i.VersionNumber)
@@ -308,16 +337,18 @@ FROM signals s
 JOIN signal_types st ON st.id = s.signal_type_id
 JOIN isn i ON i.id = s.isn_id
 WHERE s.account_id = $1
-    AND st.slug = $2
-    AND st.sem_ver = $3
-    AND s.local_ref = $4
+    AND i.slug = $2
+    AND st.slug = $3
+    AND st.sem_ver = $4
+    AND s.local_ref = $5
 `
 
 type GetSignalByAccountAndLocalRefParams struct {
-	AccountID uuid.UUID `json:"account_id"`
-	Slug      string    `json:"slug"`
-	SemVer    string    `json:"sem_ver"`
-	LocalRef  string    `json:"local_ref"`
+	AccountID      uuid.UUID `json:"account_id"`
+	IsnSlug        string    `json:"isn_slug"`
+	SignalTypeSlug string    `json:"signal_type_slug"`
+	SemVer         string    `json:"sem_ver"`
+	LocalRef       string    `json:"local_ref"`
 }
 
 type GetSignalByAccountAndLocalRefRow struct {
@@ -339,7 +370,8 @@ type GetSignalByAccountAndLocalRefRow struct {
 func (q *Queries) GetSignalByAccountAndLocalRef(ctx context.Context, arg GetSignalByAccountAndLocalRefParams) (GetSignalByAccountAndLocalRefRow, error) {
 	row := q.db.QueryRow(ctx, GetSignalByAccountAndLocalRef,
 		arg.AccountID,
-		arg.Slug,
+		arg.IsnSlug,
+		arg.SignalTypeSlug,
 		arg.SemVer,
 		arg.LocalRef,
 	)
@@ -362,6 +394,139 @@ func (q *Queries) GetSignalByAccountAndLocalRef(ctx context.Context, arg GetSign
 	return i, err
 }
 
+const GetSignalChanges = `-- name: GetSignalChanges :many
+SELECT
+    c.tx_id,
+    c.change_id,
+    c.change_type,
+    c.changed_at,
+    a.id AS account_id,
+    a.account_type,
+    COALESCE(u.email, si.client_contact_email) AS email,
+    s.id as signal_id,
+    s.local_ref,
+    s.created_at signal_created_at,
+    sv.id AS signal_version_id,
+    sv.version_number,
+    sv.created_at version_created_at,
+    s.correlation_id as correlated_to_signal_id,
+    s.is_withdrawn,
+    sv.content
+FROM (
+    SELECT v.tx_id, v.id AS change_id, 'version_created'::text AS change_type, v.created_at AS changed_at, v.signal_id, v.id AS signal_version_id
+    FROM signal_versions v
+    UNION ALL
+    SELECT se.tx_id, se.id, se.event_type, se.created_at, se.signal_id, NULL::uuid
+    FROM signal_events se
+    WHERE se.event_type IN ('withdrawn', 'reinstated')
+) c
+JOIN
+    signals s ON s.id = c.signal_id
+JOIN
+    accounts a ON a.id = s.account_id
+JOIN
+    signal_types st on st.id = s.signal_type_id
+JOIN
+    isn_signal_types ist ON ist.signal_type_id = st.id
+JOIN
+    isn i ON i.id = ist.isn_id
+LEFT OUTER JOIN
+    signal_versions sv ON sv.id = c.signal_version_id AND sv.account_id = s.account_id
+LEFT OUTER JOIN
+    users u ON u.account_id = a.id
+LEFT OUTER JOIN
+    service_accounts si ON si.account_id = a.id
+WHERE
+    i.slug = $1
+    AND s.isn_id = i.id
+    AND st.slug = $2
+    AND st.sem_ver = $3
+    AND i.is_in_use = true
+    AND ist.is_in_use = true
+    AND (c.tx_id, c.change_id) > ($4::bigint, $5::uuid)
+    AND c.tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+ORDER BY
+    c.tx_id,
+    c.change_id
+LIMIT $6
+`
+
+type GetSignalChangesParams struct {
+	IsnSlug        string    `json:"isn_slug"`
+	SignalTypeSlug string    `json:"signal_type_slug"`
+	SemVer         string    `json:"sem_ver"`
+	AfterTxID      int64     `json:"after_tx_id"`
+	AfterChangeID  uuid.UUID `json:"after_change_id"`
+	RowLimit       int32     `json:"row_limit"`
+}
+
+type GetSignalChangesRow struct {
+	TxID                 int64           `json:"tx_id"`
+	ChangeID             uuid.UUID       `json:"change_id"`
+	ChangeType           string          `json:"change_type"`
+	ChangedAt            time.Time       `json:"changed_at"`
+	AccountID            uuid.UUID       `json:"account_id"`
+	AccountType          string          `json:"account_type"`
+	Email                string          `json:"email"`
+	SignalID             uuid.UUID       `json:"signal_id"`
+	LocalRef             string          `json:"local_ref"`
+	SignalCreatedAt      time.Time       `json:"signal_created_at"`
+	SignalVersionID      *uuid.UUID      `json:"signal_version_id"`
+	VersionNumber        *int32          `json:"version_number"`
+	VersionCreatedAt     *time.Time      `json:"version_created_at"`
+	CorrelatedToSignalID uuid.UUID       `json:"correlated_to_signal_id"`
+	IsWithdrawn          bool            `json:"is_withdrawn"`
+	Content              json.RawMessage `json:"content"`
+}
+
+// returns the signal versions and withdrawals stored after the supplied (tx_id, change_id) position, in the order they were committed.
+// change_type is 'version_created' for new signal versions, or 'withdrawn'/'reinstated' for changes to the signal status (the withdrawn and reinstated signal_events).
+// Changes made by transactions that may still be in progress are excluded (see GetSignalVersionsAfterPosition).
+// signals for inactive isns or signal_types are not returned (is_in_use = false)
+func (q *Queries) GetSignalChanges(ctx context.Context, arg GetSignalChangesParams) ([]GetSignalChangesRow, error) {
+	rows, err := q.db.Query(ctx, GetSignalChanges,
+		arg.IsnSlug,
+		arg.SignalTypeSlug,
+		arg.SemVer,
+		arg.AfterTxID,
+		arg.AfterChangeID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSignalChangesRow
+	for rows.Next() {
+		var i GetSignalChangesRow
+		if err := rows.Scan(
+			&i.TxID,
+			&i.ChangeID,
+			&i.ChangeType,
+			&i.ChangedAt,
+			&i.AccountID,
+			&i.AccountType,
+			&i.Email,
+			&i.SignalID,
+			&i.LocalRef,
+			&i.SignalCreatedAt,
+			&i.SignalVersionID,
+			&i.VersionNumber,
+			&i.VersionCreatedAt,
+			&i.CorrelatedToSignalID,
+			&i.IsWithdrawn,
+			&i.Content,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const GetSignalCorrelationDetails = `-- name: GetSignalCorrelationDetails :one
 SELECT
     s.id,
@@ -376,13 +541,15 @@ JOIN signal_types st ON st.id = s.signal_type_id
 JOIN isn i ON i.id = s.isn_id
 join signals sc on sc.id = s.correlation_id
 WHERE s.account_id = $1
-    AND st.slug = $2
-    AND st.sem_ver = $3
-    AND s.local_ref = $4
+    AND i.slug = $2
+    AND st.slug = $3
+    AND st.sem_ver = $4
+    AND s.local_ref = $5
 `
 
 type GetSignalCorrelationDetailsParams struct {
 	AccountID uuid.UUID `json:"account_id"`
+	IsnSlug   string    `json:"isn_slug"`
 	Slug      string    `json:"slug"`
 	SemVer    string    `json:"sem_ver"`
 	LocalRef  string    `json:"local_ref"`
@@ -399,9 +566,11 @@ type GetSignalCorrelationDetailsRow struct {
 }
 
 // Get signal with its correlation details for verification during integration tests
+// (the same local_ref can be used in more than one ISN, so the ISN must be supplied)
 func (q *Queries) GetSignalCorrelationDetails(ctx context.Context, arg GetSignalCorrelationDetailsParams) (GetSignalCorrelationDetailsRow, error) {
 	row := q.db.QueryRow(ctx, GetSignalCorrelationDetails,
 		arg.AccountID,
+		arg.IsnSlug,
 		arg.Slug,
 		arg.SemVer,
 		arg.LocalRef,
@@ -419,6 +588,196 @@ func (q *Queries) GetSignalCorrelationDetails(ctx context.Context, arg GetSignal
 	return i, err
 }
 
+const GetSignalEvents = `-- name: GetSignalEvents :many
+SELECT se.signal_id,
+    se.id AS signal_event_id,
+    se.created_at,
+    se.event_type,
+    se.signal_version_id,
+    se.version_number,
+    se.actor_account_id,
+    COALESCE(u.email, si.client_contact_email) AS actor_email,
+    se.reason
+FROM signal_events se
+LEFT OUTER JOIN users u ON u.account_id = se.actor_account_id
+LEFT OUTER JOIN service_accounts si ON si.account_id = se.actor_account_id
+WHERE se.signal_id = ANY($1)
+ORDER BY se.signal_id, se.created_at, se.id
+`
+
+type GetSignalEventsRow struct {
+	SignalID        uuid.UUID  `json:"signal_id"`
+	SignalEventID   uuid.UUID  `json:"signal_event_id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	EventType       string     `json:"event_type"`
+	SignalVersionID *uuid.UUID `json:"signal_version_id"`
+	VersionNumber   *int32     `json:"version_number"`
+	ActorAccountID  *uuid.UUID `json:"actor_account_id"`
+	ActorEmail      *string    `json:"actor_email"`
+	Reason          *string    `json:"reason"`
+}
+
+// get the lifecycle events for the supplied signals, oldest first.
+// actor_email is the email of the user (or the client contact email of the service account) that made the change.
+func (q *Queries) GetSignalEvents(ctx context.Context, signalIds []uuid.UUID) ([]GetSignalEventsRow, error) {
+	rows, err := q.db.Query(ctx, GetSignalEvents, signalIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSignalEventsRow
+	for rows.Next() {
+		var i GetSignalEventsRow
+		if err := rows.Scan(
+			&i.SignalID,
+			&i.SignalEventID,
+			&i.CreatedAt,
+			&i.EventType,
+			&i.SignalVersionID,
+			&i.VersionNumber,
+			&i.ActorAccountID,
+			&i.ActorEmail,
+			&i.Reason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const GetSignalStreamStartPosition = `-- name: GetSignalStreamStartPosition :one
+SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint AS tx_id
+`
+
+// returns the oldest transaction id that may still be in progress.
+// All signal versions created by later transactions have tx_id >= the returned value.
+func (q *Queries) GetSignalStreamStartPosition(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, GetSignalStreamStartPosition)
+	var tx_id int64
+	err := row.Scan(&tx_id)
+	return tx_id, err
+}
+
+const GetSignalVersionsAfterPosition = `-- name: GetSignalVersionsAfterPosition :many
+SELECT
+    sv.tx_id,
+    a.id AS account_id,
+    a.account_type,
+    COALESCE(u.email, si.client_contact_email) AS email,
+    s.id as signal_id,
+    s.local_ref,
+    s.created_at signal_created_at,
+    sv.id AS signal_version_id,
+    sv.version_number,
+    sv.created_at version_created_at,
+    s.correlation_id as correlated_to_signal_id,
+    s.is_withdrawn,
+    sv.content
+FROM
+    signal_versions sv
+JOIN
+    signals s ON s.id = sv.signal_id
+JOIN
+    accounts a ON a.id = s.account_id
+JOIN
+    signal_types st on st.id = s.signal_type_id
+JOIN
+    isn_signal_types ist ON ist.signal_type_id = st.id
+JOIN
+    isn i ON i.id = ist.isn_id
+LEFT OUTER JOIN
+    users u ON u.account_id = a.id
+LEFT OUTER JOIN
+    service_accounts si ON si.account_id = a.id
+WHERE
+    i.slug = $1
+    AND s.isn_id = i.id
+    AND st.slug = $2
+    AND st.sem_ver = $3
+    AND i.is_in_use = true
+    AND ist.is_in_use = true
+    AND (sv.tx_id, sv.id) > ($4::bigint, $5::uuid)
+    AND sv.tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+ORDER BY
+    sv.tx_id,
+    sv.id
+LIMIT $6
+`
+
+type GetSignalVersionsAfterPositionParams struct {
+	IsnSlug              string    `json:"isn_slug"`
+	SignalTypeSlug       string    `json:"signal_type_slug"`
+	SemVer               string    `json:"sem_ver"`
+	AfterTxID            int64     `json:"after_tx_id"`
+	AfterSignalVersionID uuid.UUID `json:"after_signal_version_id"`
+	RowLimit             int32     `json:"row_limit"`
+}
+
+type GetSignalVersionsAfterPositionRow struct {
+	TxID                 int64           `json:"tx_id"`
+	AccountID            uuid.UUID       `json:"account_id"`
+	AccountType          string          `json:"account_type"`
+	Email                string          `json:"email"`
+	SignalID             uuid.UUID       `json:"signal_id"`
+	LocalRef             string          `json:"local_ref"`
+	SignalCreatedAt      time.Time       `json:"signal_created_at"`
+	SignalVersionID      uuid.UUID       `json:"signal_version_id"`
+	VersionNumber        int32           `json:"version_number"`
+	VersionCreatedAt     time.Time       `json:"version_created_at"`
+	CorrelatedToSignalID uuid.UUID       `json:"correlated_to_signal_id"`
+	IsWithdrawn          bool            `json:"is_withdrawn"`
+	Content              json.RawMessage `json:"content"`
+}
+
+// returns the signal versions stored after the supplied (tx_id, signal_version_id) position, in the order they were committed.
+// Versions created by transactions that may still be in progress are excluded (tx_id >= pg_snapshot_xmin) - these are
+// returned by a later call once all the earlier transactions have completed, so no versions are skipped.
+// signals for inactive isns or signal_types are not returned (is_in_use = false)
+func (q *Queries) GetSignalVersionsAfterPosition(ctx context.Context, arg GetSignalVersionsAfterPositionParams) ([]GetSignalVersionsAfterPositionRow, error) {
+	rows, err := q.db.Query(ctx, GetSignalVersionsAfterPosition,
+		arg.IsnSlug,
+		arg.SignalTypeSlug,
+		arg.SemVer,
+		arg.AfterTxID,
+		arg.AfterSignalVersionID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSignalVersionsAfterPositionRow
+	for rows.Next() {
+		var i GetSignalVersionsAfterPositionRow
+		if err := rows.Scan(
+			&i.TxID,
+			&i.AccountID,
+			&i.AccountType,
+			&i.Email,
+			&i.SignalID,
+			&i.LocalRef,
+			&i.SignalCreatedAt,
+			&i.SignalVersionID,
+			&i.VersionNumber,
+			&i.VersionCreatedAt,
+			&i.CorrelatedToSignalID,
+			&i.IsWithdrawn,
+			&i.Content,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const GetSignalsByCorrelationIDs = `-- name: GetSignalsByCorrelationIDs :many
 SELECT
     a.id AS account_id,
@@ -561,20 +920,62 @@ WHERE
     AND ($7::text IS NULL OR s.local_ref = $7::text)
     AND ($8::timestamptz IS NULL OR lsv.created_at >= $8::timestamptz)
     AND ($9::timestamptz IS NULL OR lsv.created_at <= $9::timestamptz)
+    AND ($10::timestamptz IS NULL
+        OR ($11::boolean = false
+            AND (lsv.created_at, lsv.id) > ($10::timestamptz, $12::uuid))
+        OR ($11::boolean = true
+            AND (lsv.created_at, lsv.id) < ($10::timestamptz, $12::uuid)))
+    AND NOT EXISTS (
+        SELECT 1
+        FROM jsonb_to_recordset($13::jsonb) AS f(path text[], operator text, operands text[], is_number boolean)
+        WHERE NOT COALESCE(
+            CASE
+                WHEN f.operator = 'in' THEN lsv.content #>> f.path = ANY(f.operands)
+                WHEN f.is_number AND jsonb_typeof(lsv.content #> f.path) = 'number' THEN
+                    CASE f.operator
+                        WHEN 'eq' THEN (lsv.content #>> f.path)::numeric = f.operands[1]::numeric
+                        WHEN 'ne' THEN (lsv.content #>> f.path)::numeric <> f.operands[1]::numeric
+                        WHEN 'gt' THEN (lsv.content #>> f.path)::numeric > f.operands[1]::numeric
+                        WHEN 'gte' THEN (lsv.content #>> f.path)::numeric >= f.operands[1]::numeric
+                        WHEN 'lt' THEN (lsv.content #>> f.path)::numeric < f.operands[1]::numeric
+                        WHEN 'lte' THEN (lsv.content #>> f.path)::numeric <= f.operands[1]::numeric
+                    END
+                ELSE
+                    CASE f.operator
+                        WHEN 'eq' THEN lsv.content #>> f.path = f.operands[1]
+                        WHEN 'ne' THEN lsv.content #>> f.path IS DISTINCT FROM f.operands[1]
+                        WHEN 'gt' THEN lsv.content #>> f.path > f.operands[1]
+                        WHEN 'gte' THEN lsv.content #>> f.path >= f.operands[1]
+                        WHEN 'lt' THEN lsv.content #>> f.path < f.operands[1]
+                        WHEN 'lte' THEN lsv.content #>> f.path <= f.operands[1]
+                    END
+            END, false)
+    )
+    AND ($14::uuid IS NULL OR a.id = $14::uuid)
 ORDER BY
-    s.updated_at ASC
+    CASE WHEN $11::boolean = false THEN lsv.created_at END ASC,
+    CASE WHEN $11::boolean = false THEN lsv.id END ASC,
+    CASE WHEN $11::boolean = true THEN lsv.created_at END DESC,
+    CASE WHEN $11::boolean = true THEN lsv.id END DESC
+LIMIT $15
 `
 
 type GetSignalsWithOptionalFiltersParams struct {
-	IsnSlug          string     `json:"isn_slug"`
-	SignalTypeSlug   string     `json:"signal_type_slug"`
-	SemVer           string     `json:"sem_ver"`
-	IncludeWithdrawn *bool      `json:"include_withdrawn"`
-	AccountID        *uuid.UUID `json:"account_id"`
-	SignalID         *uuid.UUID `json:"signal_id"`
-	LocalRef         *string    `json:"local_ref"`
-	StartDate        *time.Time `json:"start_date"`
-	EndDate          *time.Time `json:"end_date"`
+	IsnSlug             string          `json:"isn_slug"`
+	SignalTypeSlug      string          `json:"signal_type_slug"`
+	SemVer              string          `json:"sem_ver"`
+	IncludeWithdrawn    *bool           `json:"include_withdrawn"`
+	AccountID           *uuid.UUID      `json:"account_id"`
+	SignalID            *uuid.UUID      `json:"signal_id"`
+	LocalRef            *string         `json:"local_ref"`
+	StartDate           *time.Time      `json:"start_date"`
+	EndDate             *time.Time      `json:"end_date"`
+	PageCreatedAt       *time.Time      `json:"page_created_at"`
+	OrderDesc           bool            `json:"order_desc"`
+	PageSignalVersionID *uuid.UUID      `json:"page_signal_version_id"`
+	ContentFilters      json.RawMessage `json:"content_filters"`
+	WriteOnlyAccountID  *uuid.UUID      `json:"write_only_account_id"`
+	RowLimit            int32           `json:"row_limit"`
 }
 
 type GetSignalsWithOptionalFiltersRow struct {
@@ -594,6 +995,12 @@ type GetSignalsWithOptionalFiltersRow struct {
 
 // you must supply the isn_slug,signal_type_slug & sem_ver params - other filters are optional
 // signals for inactive isns or signal_types are not returned (is_in_use = false)
+// results are ordered by version_created_at (then signal_version_id) - ascending unless order_desc is true.
+// page_created_at/page_signal_version_id are the values from the last row of the previous page (keyset pagination)
+// content_filters is a json array of {path, operator, operands, is_number} objects (use [] for no filters) - signals are
+// only returned when their content matches all the filters. When is_number is true and the content field is a json number
+// the values are compared numerically, otherwise the comparison uses the text value of the field.
+// write_only_account_id is used for accounts that only have write permission on the isn (they can only see the signals they created).
 func (q *Queries) GetSignalsWithOptionalFilters(ctx context.Context, arg GetSignalsWithOptionalFiltersParams) ([]GetSignalsWithOptionalFiltersRow, error) {
 	rows, err := q.db.Query(ctx, GetSignalsWithOptionalFilters,
 		arg.IsnSlug,
@@ -605,6 +1012,12 @@ func (q *Queries) GetSignalsWithOptionalFilters(ctx context.Context, arg GetSign
 		arg.LocalRef,
 		arg.StartDate,
 		arg.EndDate,
+		arg.PageCreatedAt,
+		arg.OrderDesc,
+		arg.PageSignalVersionID,
+		arg.ContentFilters,
+		arg.WriteOnlyAccountID,
+		arg.RowLimit,
 	)
 	if err != nil {
 		return nil, err
@@ -637,6 +1050,162 @@ func (q *Queries) GetSignalsWithOptionalFilters(ctx context.Context, arg GetSign
 	return items, nil
 }
 
+const GetUnchangedSignalVersion = `-- name: GetUnchangedSignalVersion :one
+SELECT s.id AS signal_id,
+    lsv.id AS signal_version_id,
+    lsv.version_number
+FROM signals s
+JOIN signal_types st ON st.id = s.signal_type_id
+JOIN isn i ON i.id = s.isn_id
+JOIN latest_signal_versions lsv ON lsv.signal_id = s.id
+WHERE s.account_id = $1
+    AND s.local_ref = $2
+    AND i.slug = $3
+    AND st.slug = $4
+    AND st.sem_ver = $5
+    AND s.is_withdrawn = false
+    AND ($6::uuid IS NULL OR s.correlation_id = $6::uuid)
+    AND lsv.content = $7::jsonb
+`
+
+type GetUnchangedSignalVersionParams struct {
+	AccountID      uuid.UUID       `json:"account_id"`
+	LocalRef       string          `json:"local_ref"`
+	IsnSlug        string          `json:"isn_slug"`
+	SignalTypeSlug string          `json:"signal_type_slug"`
+	SemVer         string          `json:"sem_ver"`
+	CorrelationID  *uuid.UUID      `json:"correlation_id"`
+	Content        json.RawMessage `json:"content"`
+}
+
+type GetUnchangedSignalVersionRow struct {
+	SignalID        uuid.UUID `json:"signal_id"`
+	SignalVersionID uuid.UUID `json:"signal_version_id"`
+	VersionNumber   int32     `json:"version_number"`
+}
+
+// returns the latest version of an active signal when the supplied content is the same as the content of that version
+// (the jsonb comparison ignores differences in key order and whitespace).
+// When a correlation_id is supplied it must also match the signal's existing correlation_id.
+// No rows are returned when a new version is required (new, withdrawn or changed signals).
+func (q *Queries) GetUnchangedSignalVersion(ctx context.Context, arg GetUnchangedSignalVersionParams) (GetUnchangedSignalVersionRow, error) {
+	row := q.db.QueryRow(ctx, GetUnchangedSignalVersion,
+		arg.AccountID,
+		arg.LocalRef,
+		arg.IsnSlug,
+		arg.SignalTypeSlug,
+		arg.SemVer,
+		arg.CorrelationID,
+		arg.Content,
+	)
+	var i GetUnchangedSignalVersionRow
+	err := row.Scan(&i.SignalID, &i.SignalVersionID, &i.VersionNumber)
+	return i, err
+}
+
+const SetSignalsWithdrawnStatus = `-- name: SetSignalsWithdrawnStatus :many
+WITH selected AS (
+    SELECT s.id, s.account_id
+    FROM signals s
+    JOIN isn i ON i.id = s.isn_id
+    JOIN signal_types st ON st.id = s.signal_type_id
+    JOIN isn_signal_types ist ON ist.isn_id = i.id AND ist.signal_type_id = st.id
+    WHERE i.slug = $1
+        AND st.slug = $2
+        AND st.sem_ver = $3
+        AND i.is_in_use = true
+        AND ist.is_in_use = true
+        AND s.is_withdrawn <> $4::boolean
+        AND ($5::uuid IS NULL OR s.account_id = $5::uuid)
+        AND (
+            s.id = ANY($6::uuid[])
+            OR s.local_ref = ANY($7::text[])
+            OR EXISTS (
+                SELECT 1
+                FROM signal_versions sv
+                JOIN signal_batches sb ON sb.id = sv.signal_batch_id
+                WHERE sv.signal_id = s.id
+                    AND sv.account_id = s.account_id
+                    AND sb.account_id = s.account_id
+                    AND sb.batch_ref = $8::text
+            )
+        )
+), changed AS (
+    UPDATE signals s
+    SET is_withdrawn = $4::boolean, updated_at = NOW()
+    FROM selected
+    WHERE s.id = selected.id
+        AND s.account_id = selected.account_id
+        AND s.is_withdrawn <> $4::boolean
+    RETURNING s.id, s.account_id, s.local_ref
+), recorded AS (
+    INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type, actor_account_id, reason)
+    SELECT uuidv7(), NOW(), c.id, c.account_id,
+        CASE WHEN $4::boolean THEN 'withdrawn' ELSE 'reinstated' END,
+        $9::uuid, $10::text
+    FROM changed c
+)
+SELECT c.id AS signal_id, c.account_id, c.local_ref
+FROM changed c
+ORDER BY c.local_ref
+`
+
+type SetSignalsWithdrawnStatusParams struct {
+	IsnSlug            string      `json:"isn_slug"`
+	SignalTypeSlug     string      `json:"signal_type_slug"`
+	SemVer             string      `json:"sem_ver"`
+	IsWithdrawn        bool        `json:"is_withdrawn"`
+	OwnerAccountID     *uuid.UUID  `json:"owner_account_id"`
+	SignalIds          []uuid.UUID `json:"signal_ids"`
+	LocalRefs          []string    `json:"local_refs"`
+	BatchRef           *string     `json:"batch_ref"`
+	ChangedByAccountID uuid.UUID   `json:"changed_by_account_id"`
+	Reason             string      `json:"reason"`
+}
+
+type SetSignalsWithdrawnStatusRow struct {
+	SignalID  uuid.UUID `json:"signal_id"`
+	AccountID uuid.UUID `json:"account_id"`
+	LocalRef  string    `json:"local_ref"`
+}
+
+// Withdraws (is_withdrawn = true) or reinstates (is_withdrawn = false) signals of a signal type in an ISN.
+// Signals are selected by id, by local ref or by batch (signals that have a version stored in the batch) - use empty arrays/null for the selectors that are not used.
+// When owner_account_id is supplied only signals owned by that account are changed (local refs and batch refs are only unique per account).
+// Signals that already have the requested status are not changed.
+// Only changes signals if the ISN and signal type are in use (this is a defence against stale access tokens).
+// Each change is recorded in signal_events with the acting account and the reason (the withdrawn/reinstated events are used by the signal changes feed).
+func (q *Queries) SetSignalsWithdrawnStatus(ctx context.Context, arg SetSignalsWithdrawnStatusParams) ([]SetSignalsWithdrawnStatusRow, error) {
+	rows, err := q.db.Query(ctx, SetSignalsWithdrawnStatus,
+		arg.IsnSlug,
+		arg.SignalTypeSlug,
+		arg.SemVer,
+		arg.IsWithdrawn,
+		arg.OwnerAccountID,
+		arg.SignalIds,
+		arg.LocalRefs,
+		arg.BatchRef,
+		arg.ChangedByAccountID,
+		arg.Reason,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SetSignalsWithdrawnStatusRow
+	for rows.Next() {
+		var i SetSignalsWithdrawnStatusRow
+		if err := rows.Scan(&i.SignalID, &i.AccountID, &i.LocalRef); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const ValidateCorrelationID = `-- name: ValidateCorrelationID :one
 SELECT EXISTS(
     SELECT 1
@@ -663,11 +1232,18 @@ func (q *Queries) ValidateCorrelationID(ctx context.Context, arg ValidateCorrela
 }
 
 const WithdrawSignalByID = `-- name: WithdrawSignalByID :execrows
-UPDATE signals
-SET is_withdrawn = true, updated_at = NOW()
-WHERE id = $1
+WITH withdrawn AS (
+    UPDATE signals
+    SET is_withdrawn = true, updated_at = NOW()
+    WHERE id = $1
+    RETURNING id, account_id
+)
+INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type)
+SELECT uuidv7(), NOW(), w.id, w.account_id, 'withdrawn'
+FROM withdrawn w
 `
 
+// the withdrawal is recorded in signal_events (used by the signal changes feed)
 func (q *Queries) WithdrawSignalByID(ctx context.Context, id uuid.UUID) (int64, error) {
 	result, err := q.db.Exec(ctx, WithdrawSignalByID, id)
 	if err != nil {
@@ -677,27 +1253,33 @@ func (q *Queries) WithdrawSignalByID(ctx context.Context, id uuid.UUID) (int64,
 }
 
 const WithdrawSignalByLocalRef = `-- name: WithdrawSignalByLocalRef :execrows
-UPDATE signals
-SET is_withdrawn = true, updated_at = NOW()
-WHERE account_id = $1
-    AND isn_id = (
-        SELECT i.id
-        FROM isn i
-        JOIN isn_signal_types ist ON ist.isn_id = i.id
-        JOIN signal_types st ON st.id = ist.signal_type_id
-        WHERE i.slug = $2
-            AND st.slug = $3
-            AND st.sem_ver = $4
-            AND i.is_in_use = true
-            AND ist.is_in_use = true
-    )
-    AND signal_type_id = (
-        SELECT st.id
-        FROM signal_types st
-        WHERE st.slug = $3
-            AND st.sem_ver = $4
-    )
-    AND local_ref = $5
+WITH withdrawn AS (
+    UPDATE signals
+    SET is_withdrawn = true, updated_at = NOW()
+    WHERE account_id = $1
+        AND isn_id = (
+            SELECT i.id
+            FROM isn i
+            JOIN isn_signal_types ist ON ist.isn_id = i.id
+            JOIN signal_types st ON st.id = ist.signal_type_id
+            WHERE i.slug = $2
+                AND st.slug = $3
+                AND st.sem_ver = $4
+                AND i.is_in_use = true
+                AND ist.is_in_use = true
+        )
+        AND signal_type_id = (
+            SELECT st.id
+            FROM signal_types st
+            WHERE st.slug = $3
+                AND st.sem_ver = $4
+        )
+        AND local_ref = $5
+    RETURNING id, account_id
+)
+INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type, actor_account_id, reason)
+SELECT uuidv7(), NOW(), w.id, w.account_id, 'withdrawn', w.account_id, $6::text
+FROM withdrawn w
 `
 
 type WithdrawSignalByLocalRefParams struct {
@@ -706,10 +1288,12 @@ type WithdrawSignalByLocalRefParams struct {
 	Slug      string    `json:"slug"`
 	SemVer    string    `json:"sem_ver"`
 	LocalRef  string    `json:"local_ref"`
+	Reason    *string   `json:"reason"`
 }
 
 // only withdraws if the ISN and signal type are in use
 // Only withdraws signals if ISN and signal type are in use (this is a defence against stale access tokens).
+// the withdrawal is recorded in signal_events (used by the signal changes feed) together with the optional reason
 func (q *Queries) WithdrawSignalByLocalRef(ctx context.Context, arg WithdrawSignalByLocalRefParams) (int64, error) {
 	result, err := q.db.Exec(ctx, WithdrawSignalByLocalRef,
 		arg.AccountID,
@@ -717,6 +1301,7 @@ func (q *Queries) WithdrawSignalByLocalRef(ctx context.Context, arg WithdrawSign
 		arg.Slug,
 		arg.SemVer,
 		arg.LocalRef,
+		arg.Reason,
 	)
 	if err != nil {
 		return 0, err
@@ -0,0 +1,14 @@
+// package ingestion processes signal submissions that were made asynchronously.
+//
+// Clients can ask for a submission to be processed in the background by including a Prefer: respond-async header
+// with a signal submission request. The handler validates the request, stores it in the signal_submission_queue table and
+// returns 202 Accepted with the batch_ref of the submission.
+//
+// The Worker polls the queue and passes each submission to a Processor (the signal handlers), which stores the signals
+// in the same way as a synchronous request. The outcome - the response that would have been returned to the client - is recorded
+// against the queued submission and reported by the batch status endpoint.
+// Submissions that can't be processed (e.g. because the database is unavailable) are retried with exponential backoff
+// until SignalSubmissionMaxAttempts is reached.
+//
+// Submissions are claimed with FOR UPDATE SKIP LOCKED, so it is safe to run a worker on every signalsd instance that accepts signal submissions.
+package ingestion
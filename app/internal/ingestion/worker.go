@@ -0,0 +1,131 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	"github.com/information-sharing-networks/signalsd/app/internal/poller"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+)
+
+// Processor stores the signals in a queued submission.
+//
+// It returns the http status and response body that would have been returned if the submission had been made synchronously.
+// An error is returned when the submission as a whole could not be processed - the submission is retried later.
+type Processor interface {
+	ProcessSubmission(ctx context.Context, submission database.SignalSubmissionQueue) (status int, response any, err error)
+}
+
+// Worker processes the queued signal submissions
+type Worker struct {
+	*poller.Poller[database.SignalSubmissionQueue]
+	queries   *database.Queries
+	processor Processor
+}
+
+// retryBackoff is the schedule used to retry submissions that could not be processed
+var retryBackoff = poller.Backoff{
+	BaseDelay: signalsd.SignalSubmissionRetryBaseDelay,
+	MaxDelay:  signalsd.SignalSubmissionRetryMaxDelay,
+}
+
+// NewWorker creates a worker that uses processor to store the signals in queued submissions.
+//
+// Use Start to process the queued submissions in the background (or ProcessDue to process the submissions that are currently due).
+func NewWorker(queries *database.Queries, processor Processor) *Worker {
+	w := &Worker{
+		queries:   queries,
+		processor: processor,
+	}
+	w.Poller = poller.New("ingestion worker", signalsd.SignalSubmissionBatchSize, signalsd.SignalSubmissionWorkers, w.claim, w.process)
+	return w
+}
+
+// claim claims the queued submissions that are due.
+func (w *Worker) claim(ctx context.Context) ([]database.SignalSubmissionQueue, error) {
+	submissions, err := w.queries.ClaimQueuedSignalSubmissions(ctx, database.ClaimQueuedSignalSubmissionsParams{
+		BatchSize:    signalsd.SignalSubmissionBatchSize,
+		LeaseSeconds: int32(signalsd.SignalSubmissionLease.Seconds()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not claim queued signal submissions: %v", err)
+	}
+	return submissions, nil
+}
+
+// process passes the submission to the processor and records the outcome.
+func (w *Worker) process(ctx context.Context, submission database.SignalSubmissionQueue) {
+	submissionLogger := slog.Default().With(
+		slog.String("type", "signal_submission"),
+		slog.String("submission_id", submission.ID.String()),
+	)
+	processCtx := logger.ContextWithBackgroundLogger(ctx, submissionLogger)
+
+	start := time.Now()
+	status, response, processErr := w.processor.ProcessSubmission(processCtx, submission)
+
+	// the server is shutting down - the submission will be processed again when the lease expires
+	if ctx.Err() != nil {
+		return
+	}
+
+	attempts := submission.AttemptCount + 1
+	params := database.RecordQueuedSignalSubmissionAttemptParams{
+		ID:            submission.ID,
+		NextAttemptAt: time.Now(),
+	}
+
+	if processErr == nil {
+		responseBody, err := json.Marshal(response)
+		if err != nil {
+			processErr = fmt.Errorf("could not marshal response: %v", err)
+		} else {
+			responseStatus := int32(status)
+			params.ResponseStatus = &responseStatus
+			params.ResponseBody = responseBody
+		}
+	}
+
+	now := time.Now()
+	switch {
+	case processErr == nil:
+		params.Status = "completed"
+		params.CompletedAt = &now
+	case attempts >= signalsd.SignalSubmissionMaxAttempts:
+		errMsg := processErr.Error()
+		params.Status = "failed"
+		params.CompletedAt = &now
+		params.LastError = &errMsg
+	default:
+		errMsg := processErr.Error()
+		params.Status = "queued"
+		params.NextAttemptAt = now.Add(retryBackoff.Delay(attempts))
+		params.LastError = &errMsg
+	}
+
+	if err := w.queries.RecordQueuedSignalSubmissionAttempt(ctx, params); err != nil {
+		submissionLogger.Error("ingestion worker: could not record submission attempt", slog.String("error", err.Error()))
+		return
+	}
+
+	logAttrs := []slog.Attr{
+		slog.String("account_id", submission.AccountID.String()),
+		slog.Int("attempt", int(attempts)),
+		slog.String("status", params.Status),
+	}
+	logAttrs = append(logAttrs, logger.ContextLogAttrs(processCtx)...)
+	logAttrs = append(logAttrs, slog.String("duration", time.Since(start).String()))
+
+	if processErr != nil {
+		logAttrs = append(logAttrs, slog.String("error", processErr.Error()))
+		submissionLogger.LogAttrs(ctx, slog.LevelWarn, "queued signal submission attempt failed", logAttrs...)
+		return
+	}
+	logAttrs = append(logAttrs, slog.Int("response_status", status))
+	submissionLogger.LogAttrs(ctx, slog.LevelInfo, "queued signal submission processed", logAttrs...)
+}
@@ -0,0 +1,133 @@
+// Package invalidation keeps the in-memory caches on each signalsd instance up to date when the underlying data is changed.
+//
+// Admin handlers call Notify after changing data that is cached (signal types, routing configs, ISN visibility etc).
+// The notification is sent using Postgres NOTIFY and every signalsd instance runs a Listener that reloads the affected cache.
+// The caches are still refreshed by polling, so a missed notification only delays the update until the next poll.
+package invalidation
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Channel is the Postgres notification channel used for cache invalidation (must match the NotifyCacheInvalidation query)
+const Channel = "signalsd_cache_invalidation"
+
+// CacheName identifies a cache in an invalidation notification (the name is sent as the notification payload)
+type CacheName string
+
+const (
+	SchemaCache    CacheName = "schemas"
+	RouterCache    CacheName = "router"
+	PublicIsnCache CacheName = "public_isns"
+	SigningKeys    CacheName = "signing_keys"
+)
+
+// Loader is implemented by the caches - Load replaces the cache contents with the latest data from the database
+type Loader interface {
+	Load(ctx context.Context) error
+}
+
+// Notify tells every signalsd instance (including this one) to reload the named caches.
+//
+// Pass the transaction queries when the change is made in a transaction - the notification is only sent if the transaction commits.
+// Errors are logged rather than returned: the change has already been made and the caches will catch up when they are next polled.
+func Notify(ctx context.Context, queries *database.Queries, caches ...CacheName) {
+	for _, cache := range caches {
+		if err := queries.NotifyCacheInvalidation(ctx, string(cache)); err != nil {
+			logger.ContextWithLogAttrs(ctx,
+				slog.String("cache_invalidation_error", err.Error()),
+				slog.String("cache", string(cache)),
+			)
+		}
+	}
+}
+
+// Listener reloads the caches when an invalidation notification is received
+type Listener struct {
+	pool   *pgxpool.Pool
+	caches map[CacheName]Loader
+}
+
+// NewListener creates a listener for the supplied caches
+func NewListener(pool *pgxpool.Pool, caches map[CacheName]Loader) *Listener {
+	return &Listener{pool: pool, caches: caches}
+}
+
+// Start starts a background goroutine that listens for cache invalidation notifications.
+//
+// The listener holds a dedicated database connection. If the connection is lost the listener reconnects after retryInterval
+// and reloads all the caches (notifications sent while the listener was disconnected are lost).
+// The goroutine exits when ctx is cancelled.
+func (l *Listener) Start(ctx context.Context, retryInterval time.Duration) {
+
+	go func() {
+		reconnecting := false
+		for {
+			err := l.listen(ctx, reconnecting)
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("cache invalidation listener: connection lost", slog.String("error", err.Error()))
+
+			select {
+			case <-time.After(retryInterval):
+				reconnecting = true
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// listen waits for notifications until the connection fails or ctx is cancelled
+func (l *Listener) listen(ctx context.Context, reloadAll bool) error {
+	poolConn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the connection is taken out of the pool so it is not reused with the LISTEN still active
+	conn := poolConn.Hijack()
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+
+	if reloadAll {
+		for name := range l.caches {
+			l.reload(ctx, name)
+		}
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		l.reload(ctx, CacheName(notification.Payload))
+	}
+}
+
+// reload reloads the named cache - unknown cache names are ignored
+// (these are expected when instances running different versions of the service share a database).
+func (l *Listener) reload(ctx context.Context, name CacheName) {
+	cache, ok := l.caches[name]
+	if !ok {
+		slog.Warn("cache invalidation listener: ignoring notification for unknown cache", slog.String("cache", string(name)))
+		return
+	}
+	if err := cache.Load(ctx); err != nil {
+		slog.Error("cache invalidation listener: reload failed", slog.String("cache", string(name)), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,35 @@
+package invalidation
+
+import (
+	"context"
+	"testing"
+)
+
+type countingLoader struct {
+	loads int
+}
+
+func (c *countingLoader) Load(ctx context.Context) error {
+	c.loads++
+	return nil
+}
+
+func TestReload(t *testing.T) {
+	schemas := &countingLoader{}
+	router := &countingLoader{}
+	listener := NewListener(nil, map[CacheName]Loader{
+		SchemaCache: schemas,
+		RouterCache: router,
+	})
+
+	listener.reload(context.Background(), RouterCache)
+	listener.reload(context.Background(), RouterCache)
+	listener.reload(context.Background(), "unknown")
+
+	if router.loads != 2 {
+		t.Errorf("router cache: want 2 loads, got %d", router.loads)
+	}
+	if schemas.loads != 0 {
+		t.Errorf("schema cache: only the notified cache should be reloaded, got %d loads", schemas.loads)
+	}
+}
@@ -77,6 +77,15 @@ func ContextRequestLogger(ctx context.Context) *slog.Logger {
 	return slog.Default()
 }
 
+// ContextWithBackgroundLogger prepares a context for work done outside of an http request (e.g. processing queued signal submissions)
+// so that code shared with the http handlers can use ContextWithLogAttrs and ContextRequestLogger.
+//
+// Attributes added with ContextWithLogAttrs are not logged automatically - use ContextLogAttrs to include them in the log message for the completed work.
+func ContextWithBackgroundLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	ctx = context.WithValue(ctx, logAttrsKey, &[]slog.Attr{})
+	return context.WithValue(ctx, RequestLoggerKey, logger)
+}
+
 // ParseLogLevel converts a string log level to slog.Level
 func ParseLogLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
@@ -0,0 +1,14 @@
+// Package oidc implements the relying party side of OpenID Connect so that web users can log in with an external identity provider
+// (e.g. a partner organisation's corporate SSO). The providers are configured with the OIDC_PROVIDERS env variable.
+//
+// Only the authorization code flow is supported:
+//   - [Provider.AuthCodeURL] returns the URL the user is redirected to. The request includes a state, a nonce and a PKCE (S256) code challenge.
+//   - the provider redirects the user back to the site's callback URL with an authorization code
+//   - [Provider.Exchange] redeems the code (authenticating with the provider using client_secret_basic) and returns the id_token
+//   - [Provider.VerifyIDToken] checks the id_token signature and the iss, aud, azp, exp and nonce claims
+//
+// The provider configuration is discovered from {issuer}/.well-known/openid-configuration and the id_token signing keys are read from the provider's jwks_uri.
+// Both are cached for OIDCMetadataCacheTTL - the keys are also reloaded when an id_token is signed with an unknown key (providers rotate their keys).
+//
+// The login requests (state, nonce and code verifier) and the links between users and their provider identities are stored by the handlers package.
+package oidc
@@ -0,0 +1,429 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/information-sharing-networks/signalsd/app/internal/signingkeys"
+	"golang.org/x/sync/singleflight"
+)
+
+// IDTokenSigningAlgs are the id_token signing algorithms accepted from identity providers (RS256 is required by the OpenID Connect spec)
+var IDTokenSigningAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// defaultScopes are requested (in addition to openid) when the provider config does not include scopes
+var defaultScopes = []string{"email", "profile"}
+
+// maxResponseSize limits the size of the responses read from the identity providers
+const maxResponseSize = 1 << 20 // 1MB
+
+// ErrInvalidIDToken is returned when the id_token can't be verified
+var ErrInvalidIDToken = errors.New("invalid id_token")
+
+// Provider is an OpenID Connect identity provider configured in OIDC_PROVIDERS.
+// Providers are safe for concurrent use.
+type Provider struct {
+	config      signalsd.OIDCProvider
+	redirectURL string
+	httpClient  *http.Client
+
+	// loads combines concurrent requests to reload the provider config and keys.
+	// The requests to the provider are made without holding mu, so cached values can be read while they are reloaded.
+	loads singleflight.Group
+
+	mu               sync.Mutex
+	metadata         *providerMetadata
+	metadataLoadedAt time.Time
+	keys             map[string]crypto.PublicKey
+	keysLoadedAt     time.Time
+}
+
+// providerMetadata is the subset of the OpenID Provider Metadata used by the relying party
+type providerMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	JWKSURI                       string   `json:"jwks_uri"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+// IDTokenClaims are the id_token claims used to identify the user
+type IDTokenClaims struct {
+	Email           string       `json:"email"`
+	EmailVerified   flexibleBool `json:"email_verified"`
+	Name            string       `json:"name"`
+	Nonce           string       `json:"nonce"`
+	AuthorizedParty string       `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// flexibleBool accepts JSON booleans and the strings "true" and "false" (some providers return email_verified as a string)
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	case "false", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean value: %s", data)
+	}
+	return nil
+}
+
+// NewProvider returns a Provider for the supplied config. The provider's metadata is loaded when it is first used.
+//
+// redirectURL is the site's callback URL for the provider - it must be registered with the provider.
+func NewProvider(config signalsd.OIDCProvider, redirectURL string) *Provider {
+	return &Provider{
+		config:      config,
+		redirectURL: redirectURL,
+		httpClient: &http.Client{
+			Timeout: signalsd.OIDCRequestTimeout,
+		},
+	}
+}
+
+// ID returns the provider id used in the login URLs
+func (p *Provider) ID() string {
+	return p.config.ID
+}
+
+// Name returns the provider name displayed on the login page
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// AutoProvision reports whether users that are not registered on the site are created when they log in with the provider
+func (p *Provider) AutoProvision() bool {
+	return p.config.AutoProvision
+}
+
+// EmailAllowed reports whether the email address can be linked to a user or provisioned (checked against the provider's allowed_email_domains)
+func (p *Provider) EmailAllowed(email string) bool {
+	if len(p.config.AllowedEmailDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	return slices.Contains(p.config.AllowedEmailDomains, strings.ToLower(email[at+1:]))
+}
+
+// NewCodeVerifier returns a random PKCE code verifier (RFC 7636)
+func NewCodeVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge returns the S256 code challenge for the code verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider URL used to start the login.
+// The state and nonce are returned by the provider in the callback and id_token respectively and must be checked by the caller.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	metadata, err := p.getMetadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.config.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+	if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.redirectURL)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", CodeChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("provider %s: invalid authorization_endpoint: %v", p.config.ID, err)
+	}
+
+	// keep any query params included in the provider's endpoint
+	query := authURL.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange redeems the authorization code at the provider's token endpoint and returns the id_token
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	metadata, err := p.getMetadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("provider %s: %v", p.config.ID, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	// client_secret_basic (RFC 6749 section 2.3.1): the client id and secret are form encoded before they are base64 encoded
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("provider %s: token request failed: %v", p.config.ID, err)
+	}
+	defer res.Body.Close()
+
+	var tokenResponse struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&tokenResponse); err != nil {
+		return "", fmt.Errorf("provider %s: invalid token response (status %d): %v", p.config.ID, res.StatusCode, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("provider %s: token request failed (status %d): %s %s", p.config.ID, res.StatusCode, tokenResponse.Error, tokenResponse.ErrorDescription)
+	}
+	if tokenResponse.IDToken == "" {
+		return "", fmt.Errorf("provider %s: the token response does not include an id_token", p.config.ID)
+	}
+
+	return tokenResponse.IDToken, nil
+}
+
+// VerifyIDToken checks the id_token was issued by the provider for this site and returns its claims.
+//
+// The nonce must be the value supplied to AuthCodeURL. Errors wrap ErrInvalidIDToken when the token is rejected.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDTokenClaims, error) {
+	metadata, err := p.getMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &IDTokenClaims{}
+
+	var keyErr error
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.getKey(ctx, metadata, kid)
+		if err != nil {
+			keyErr = err
+		}
+		return key, err
+	},
+		jwt.WithValidMethods(IDTokenSigningAlgs),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(signalsd.OIDCIDTokenLeeway),
+	)
+	if err != nil {
+		if keyErr != nil {
+			return nil, keyErr
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	// the azp claim identifies the client the token was issued to when the token has more than one audience (OpenID Connect Core section 3.1.3.7)
+	if (len(claims.Audience) > 1 || claims.AuthorizedParty != "") && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("%w: azp does not match the client id", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: the sub claim is missing", ErrInvalidIDToken)
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match the login request", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+// getMetadata returns the provider metadata (loaded from the discovery endpoint when not cached)
+func (p *Provider) getMetadata(ctx context.Context) (*providerMetadata, error) {
+	p.mu.Lock()
+	if p.metadata != nil && time.Since(p.metadataLoadedAt) < signalsd.OIDCMetadataCacheTTL {
+		metadata := p.metadata
+		p.mu.Unlock()
+		return metadata, nil
+	}
+	p.mu.Unlock()
+
+	// the load is shared with other requests, so it is not cancelled when this request is (the http client timeout still applies)
+	metadata, err, _ := p.loads.Do("metadata", func() (any, error) {
+		return p.loadMetadata(context.WithoutCancel(ctx))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metadata.(*providerMetadata), nil
+}
+
+// loadMetadata gets the provider metadata from the discovery endpoint and caches it
+func (p *Provider) loadMetadata(ctx context.Context) (*providerMetadata, error) {
+	discoveryURL := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+
+	var metadata providerMetadata
+	if err := p.getJSON(ctx, discoveryURL, &metadata); err != nil {
+		return nil, err
+	}
+
+	// the issuer must match the configured issuer exactly (OpenID Connect Discovery section 4.3)
+	if metadata.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("provider %s: the discovered issuer %q does not match the configured issuer %q", p.config.ID, metadata.Issuer, p.config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("provider %s: the provider config does not include the authorization_endpoint, token_endpoint and jwks_uri", p.config.ID)
+	}
+	if len(metadata.CodeChallengeMethodsSupported) > 0 && !slices.Contains(metadata.CodeChallengeMethodsSupported, "S256") {
+		return nil, fmt.Errorf("provider %s: the provider does not support the S256 code challenge method", p.config.ID)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// reload the keys if the jwks_uri has changed
+	if p.metadata != nil && p.metadata.JWKSURI != metadata.JWKSURI {
+		p.keys = nil
+	}
+
+	p.metadata = &metadata
+	p.metadataLoadedAt = time.Now()
+
+	return p.metadata, nil
+}
+
+// getKey returns the provider's public key with the supplied kid.
+// The keys are reloaded if the kid is not in the cached key set, but no more than once a minute so that tokens with random kids can't be used to flood the provider with requests.
+func (p *Provider) getKey(ctx context.Context, metadata *providerMetadata, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	expired := time.Since(p.keysLoadedAt) >= signalsd.OIDCMetadataCacheTTL
+	key, ok := p.lookupKey(kid)
+	reload := p.keys == nil || expired || (!ok && time.Since(p.keysLoadedAt) >= time.Minute)
+	p.mu.Unlock()
+
+	if reload {
+		_, err, _ := p.loads.Do("keys "+metadata.JWKSURI, func() (any, error) {
+			return nil, p.loadKeys(context.WithoutCancel(ctx), metadata.JWKSURI)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		p.mu.Lock()
+		key, ok = p.lookupKey(kid)
+		p.mu.Unlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidIDToken, kid)
+	}
+	return key, nil
+}
+
+// loadKeys gets the provider's signing keys from the jwks_uri and caches them
+func (p *Provider) loadKeys(ctx context.Context, jwksURI string) error {
+	var jwks signingkeys.JWKSet
+	if err := p.getJSON(ctx, jwksURI, &jwks); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		publicKey, err := jwk.PublicKey()
+		if err != nil {
+			continue // ignore key types that can't be used to verify tokens
+		}
+		keys[jwk.Kid] = publicKey
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// discard the keys if the provider config was reloaded with a different jwks_uri while they were loading
+	if p.metadata != nil && p.metadata.JWKSURI != jwksURI {
+		return nil
+	}
+	p.keys = keys
+	p.keysLoadedAt = time.Now()
+	return nil
+}
+
+// lookupKey returns the cached key with the supplied kid.
+// Tokens without a kid can only be verified when the provider publishes a single key. The caller must hold p.mu.
+func (p *Provider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" {
+		if len(p.keys) != 1 {
+			return nil, false
+		}
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("provider %s: %v", p.config.ID, err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("provider %s: request to %s failed: %v", p.config.ID, endpoint, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("provider %s: request to %s failed with status %d", p.config.ID, endpoint, res.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(v); err != nil {
+		return fmt.Errorf("provider %s: invalid response from %s: %v", p.config.ID, endpoint, err)
+	}
+	return nil
+}
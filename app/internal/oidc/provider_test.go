@@ -0,0 +1,433 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/information-sharing-networks/signalsd/app/internal/signingkeys"
+)
+
+// testIdP is a minimal identity provider (discovery, jwks and token endpoints)
+type testIdP struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	kid       string
+	jwksCalls atomic.Int32
+
+	// jwksBlock (if set) holds up the jwks responses until it is closed
+	jwksBlock chan struct{}
+
+	// idToken is returned by the token endpoint
+	idToken string
+
+	// tokenRequest is the last request made to the token endpoint
+	tokenRequest *http.Request
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	idp := &testIdP{key: key, kid: "key-1"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                           idp.server.URL,
+			"authorization_endpoint":           idp.server.URL + "/authorize?prompt=login",
+			"token_endpoint":                   idp.server.URL + "/token",
+			"jwks_uri":                         idp.server.URL + "/jwks",
+			"code_challenge_methods_supported": []string{"S256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.jwksCalls.Add(1)
+		if idp.jwksBlock != nil {
+			<-idp.jwksBlock
+		}
+		_ = json.NewEncoder(w).Encode(signingkeys.JWKSet{Keys: []signingkeys.JWK{{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: idp.kid,
+			N:   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		idp.tokenRequest = r
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"id_token":     idp.idToken,
+		})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (idp *testIdP) provider() *Provider {
+	return NewProvider(signalsd.OIDCProvider{
+		ID:           "test",
+		Name:         "Test IdP",
+		Issuer:       idp.server.URL,
+		ClientID:     "signalsd",
+		ClientSecret: "secret:with/special chars",
+	}, "http://localhost:8080/api/auth/oidc/test/callback")
+}
+
+func (idp *testIdP) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = idp.kid
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		t.Fatalf("failed to sign id_token: %v", err)
+	}
+	return signed
+}
+
+func (idp *testIdP) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"sub":            "user-123",
+		"aud":            "signalsd",
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          "test-nonce",
+		"email":          "jane@example.com",
+		"email_verified": true,
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := idp.provider()
+
+	authCodeURL, err := provider.AuthCodeURL(context.Background(), "test-state", "test-nonce", "test-verifier")
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+
+	u, err := url.Parse(authCodeURL)
+	if err != nil {
+		t.Fatalf("invalid URL %q: %v", authCodeURL, err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != idp.server.URL+"/authorize" {
+		t.Errorf("expected the authorization endpoint, got %s", got)
+	}
+
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "signalsd",
+		"redirect_uri":          "http://localhost:8080/api/auth/oidc/test/callback",
+		"scope":                 "openid email profile",
+		"state":                 "test-state",
+		"nonce":                 "test-nonce",
+		"code_challenge":        CodeChallenge("test-verifier"),
+		"code_challenge_method": "S256",
+		"prompt":                "login",
+	}
+	for param, value := range want {
+		if got := u.Query().Get(param); got != value {
+			t.Errorf("%s: got %q, want %q", param, got, value)
+		}
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// example from RFC 7636 appendix B
+	got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("CodeChallenge() = %s, want %s", got, want)
+	}
+
+	verifier, err := NewCodeVerifier()
+	if err != nil {
+		t.Fatalf("NewCodeVerifier() error = %v", err)
+	}
+	if len(verifier) != 43 {
+		t.Errorf("expected a 43 character code verifier, got %d", len(verifier))
+	}
+}
+
+func TestExchange(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := idp.provider()
+	idp.idToken = "test-id-token"
+
+	idToken, err := provider.Exchange(context.Background(), "test-code", "test-verifier")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if idToken != "test-id-token" {
+		t.Errorf("expected the id_token from the token response, got %q", idToken)
+	}
+
+	clientID, clientSecret, ok := idp.tokenRequest.BasicAuth()
+	if !ok || clientID != "signalsd" || clientSecret != url.QueryEscape("secret:with/special chars") {
+		t.Errorf("expected client_secret_basic authentication, got %q %q", clientID, clientSecret)
+	}
+	form := idp.tokenRequest.PostForm
+	if form.Get("grant_type") != "authorization_code" || form.Get("code") != "test-code" || form.Get("code_verifier") != "test-verifier" ||
+		form.Get("redirect_uri") != "http://localhost:8080/api/auth/oidc/test/callback" {
+		t.Errorf("unexpected token request %v", form)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	idp := newTestIdP(t)
+
+	tests := []struct {
+		name    string
+		modify  func(claims jwt.MapClaims)
+		nonce   string
+		wantErr bool
+	}{
+		{
+			name:   "valid token",
+			modify: func(claims jwt.MapClaims) {},
+			nonce:  "test-nonce",
+		},
+		{
+			name: "email_verified as a string",
+			modify: func(claims jwt.MapClaims) {
+				claims["email_verified"] = "true"
+			},
+			nonce: "test-nonce",
+		},
+		{
+			name: "multiple audiences with matching azp",
+			modify: func(claims jwt.MapClaims) {
+				claims["aud"] = []string{"signalsd", "other-client"}
+				claims["azp"] = "signalsd"
+			},
+			nonce: "test-nonce",
+		},
+		{
+			name: "multiple audiences without azp",
+			modify: func(claims jwt.MapClaims) {
+				claims["aud"] = []string{"signalsd", "other-client"}
+			},
+			nonce:   "test-nonce",
+			wantErr: true,
+		},
+		{
+			name: "wrong audience",
+			modify: func(claims jwt.MapClaims) {
+				claims["aud"] = "other-client"
+			},
+			nonce:   "test-nonce",
+			wantErr: true,
+		},
+		{
+			name: "wrong issuer",
+			modify: func(claims jwt.MapClaims) {
+				claims["iss"] = "https://attacker.example.com"
+			},
+			nonce:   "test-nonce",
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			modify: func(claims jwt.MapClaims) {
+				claims["exp"] = time.Now().Add(-5 * time.Minute).Unix()
+			},
+			nonce:   "test-nonce",
+			wantErr: true,
+		},
+		{
+			name: "missing exp",
+			modify: func(claims jwt.MapClaims) {
+				delete(claims, "exp")
+			},
+			nonce:   "test-nonce",
+			wantErr: true,
+		},
+		{
+			name:    "nonce does not match",
+			modify:  func(claims jwt.MapClaims) {},
+			nonce:   "other-nonce",
+			wantErr: true,
+		},
+		{
+			name: "missing sub",
+			modify: func(claims jwt.MapClaims) {
+				delete(claims, "sub")
+			},
+			nonce:   "test-nonce",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := idp.claims()
+			tt.modify(claims)
+
+			got, err := idp.provider().VerifyIDToken(context.Background(), idp.sign(t, claims), tt.nonce)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyIDToken() error = %v", err)
+			}
+			if got.Subject != "user-123" || got.Email != "jane@example.com" || !bool(got.EmailVerified) {
+				t.Errorf("unexpected claims %+v", got)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenSignature(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := idp.provider()
+
+	t.Run("token signed with another key", func(t *testing.T) {
+		otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims())
+		token.Header["kid"] = idp.kid
+		signed, _ := token.SignedString(otherKey)
+
+		if _, err := provider.VerifyIDToken(context.Background(), signed, "test-nonce"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+		}
+	})
+
+	t.Run("unsigned token", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, idp.claims())
+		signed, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+		if _, err := provider.VerifyIDToken(context.Background(), signed, "test-nonce"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+		}
+	})
+
+	t.Run("keys are reloaded when the provider rotates its key", func(t *testing.T) {
+		if _, err := provider.VerifyIDToken(context.Background(), idp.sign(t, idp.claims()), "test-nonce"); err != nil {
+			t.Fatalf("VerifyIDToken() error = %v", err)
+		}
+		calls := idp.jwksCalls.Load()
+
+		newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		idp.key = newKey
+		idp.kid = "key-2"
+
+		// the key set was loaded less than a minute ago so the unknown kid does not trigger a reload yet
+		if _, err := provider.VerifyIDToken(context.Background(), idp.sign(t, idp.claims()), "test-nonce"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+		}
+
+		provider.mu.Lock()
+		provider.keysLoadedAt = time.Now().Add(-2 * time.Minute)
+		provider.mu.Unlock()
+
+		if _, err := provider.VerifyIDToken(context.Background(), idp.sign(t, idp.claims()), "test-nonce"); err != nil {
+			t.Fatalf("VerifyIDToken() error = %v", err)
+		}
+		if idp.jwksCalls.Load() != calls+1 {
+			t.Errorf("expected the keys to be reloaded once, got %d requests", idp.jwksCalls.Load()-calls)
+		}
+	})
+}
+
+func TestConcurrentKeyLoads(t *testing.T) {
+	idp := newTestIdP(t)
+	provider := idp.provider()
+
+	// load the provider config
+	if _, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier"); err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+
+	idp.jwksBlock = make(chan struct{})
+	release := sync.OnceFunc(func() { close(idp.jwksBlock) })
+	t.Cleanup(release) // the test server can't be closed while a request is blocked
+
+	token := idp.sign(t, idp.claims())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Go(func() {
+			_, err := provider.VerifyIDToken(context.Background(), token, "test-nonce")
+			errs <- err
+		})
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for idp.jwksCalls.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the keys to be requested")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the cached provider config can be used while the keys are loading
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier"); err != nil {
+		t.Errorf("AuthCodeURL() while the keys are loading: error = %v", err)
+	}
+
+	release()
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("VerifyIDToken() error = %v", err)
+		}
+	}
+	if calls := idp.jwksCalls.Load(); calls != 1 {
+		t.Errorf("expected the keys to be loaded once, got %d requests", calls)
+	}
+}
+
+func TestEmailAllowed(t *testing.T) {
+	provider := NewProvider(signalsd.OIDCProvider{
+		ID:                  "test",
+		AllowedEmailDomains: []string{"example.com"},
+	}, "")
+
+	tests := map[string]bool{
+		"jane@example.com":          true,
+		"Jane@Example.COM":          true,
+		"jane@sub.example.com":      false,
+		"jane@example.com.evil.org": false,
+		"jane":                      false,
+	}
+	for email, want := range tests {
+		if got := provider.EmailAllowed(email); got != want {
+			t.Errorf("EmailAllowed(%q) = %v, want %v", email, got, want)
+		}
+	}
+
+	if !NewProvider(signalsd.OIDCProvider{ID: "any"}, "").EmailAllowed("jane@anywhere.org") {
+		t.Error("expected any domain to be allowed when allowed_email_domains is not set")
+	}
+}
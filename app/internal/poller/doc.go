@@ -0,0 +1,7 @@
+// package poller runs the background loops that process the rows in a database queue table.
+//
+// A Poller periodically claims the rows that are due (the queries use FOR UPDATE SKIP LOCKED and a lease, so a poller can run on every signalsd instance)
+// and processes them concurrently. Rows that fail are rescheduled by the caller using a Backoff.
+//
+// It is used by the webhook dispatcher (webhook_deliveries) and the ingestion worker (signal_submission_queue).
+package poller
@@ -0,0 +1,99 @@
+package poller
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Poller claims the due rows in a queue and processes them.
+type Poller[T any] struct {
+	name      string
+	batchSize int
+	workers   int
+	claim     func(ctx context.Context) ([]T, error)
+	process   func(ctx context.Context, row T)
+}
+
+// New creates a poller.
+//
+// claim should claim (lease) up to batchSize due rows and process should handle a single row, including recording the outcome.
+// Up to workers rows are processed concurrently. name is used in log messages.
+func New[T any](name string, batchSize, workers int, claim func(ctx context.Context) ([]T, error), process func(ctx context.Context, row T)) *Poller[T] {
+	return &Poller[T]{
+		name:      name,
+		batchSize: batchSize,
+		workers:   workers,
+		claim:     claim,
+		process:   process,
+	}
+}
+
+// Start starts a background goroutine that processes the due rows every interval.
+// Errors are logged but do not stop the polling loop.
+// The goroutine exits when ctx is cancelled.
+func (p *Poller[T]) Start(ctx context.Context, interval time.Duration) {
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.ProcessDue(ctx); err != nil {
+					slog.Error(p.name+": failed to process due rows", slog.String("error", err.Error()))
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// ProcessDue claims the rows that are due and processes them.
+// Batches are claimed until there are no more due rows, so a backlog is cleared without waiting for the next poll.
+func (p *Poller[T]) ProcessDue(ctx context.Context) error {
+	for {
+		rows, err := p.claim(ctx)
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, p.workers)
+		for _, row := range rows {
+			sem <- struct{}{}
+			wg.Go(func() {
+				defer func() { <-sem }()
+				p.process(ctx, row)
+			})
+		}
+		wg.Wait()
+
+		if len(rows) < p.batchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Backoff is an exponential retry schedule for failed rows.
+type Backoff struct {
+	// BaseDelay is the delay before the first retry (doubled after each failed attempt)
+	BaseDelay time.Duration
+
+	// MaxDelay is the longest delay between attempts
+	MaxDelay time.Duration
+}
+
+// Delay returns the delay before the next attempt: BaseDelay doubled after each failed attempt, up to MaxDelay.
+func (b Backoff) Delay(attempts int32) time.Duration {
+	delay := b.BaseDelay
+	for i := int32(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= b.MaxDelay {
+			return b.MaxDelay
+		}
+	}
+	return delay
+}
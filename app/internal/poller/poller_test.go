@@ -0,0 +1,56 @@
+package poller
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{BaseDelay: 10 * time.Second, MaxDelay: 10 * time.Minute}
+
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 100, want: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := backoff.Delay(tt.attempts); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestProcessDue(t *testing.T) {
+	// 5 due rows claimed in batches of 2 - the poller should keep claiming until a short batch is returned
+	due := []int{1, 2, 3, 4, 5}
+	claimCalls := 0
+	var processed atomic.Int32
+
+	p := New("test poller", 2, 2,
+		func(ctx context.Context) ([]int, error) {
+			claimCalls++
+			batch := due[:min(2, len(due))]
+			due = due[len(batch):]
+			return batch, nil
+		},
+		func(ctx context.Context, row int) {
+			processed.Add(1)
+		},
+	)
+
+	if err := p.ProcessDue(context.Background()); err != nil {
+		t.Fatalf("ProcessDue returned an error: %v", err)
+	}
+	if processed.Load() != 5 {
+		t.Errorf("expected 5 rows to be processed, got %d", processed.Load())
+	}
+	if claimCalls != 3 {
+		t.Errorf("expected 3 batches to be claimed, got %d", claimCalls)
+	}
+}
@@ -1,10 +1,14 @@
 package router
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -19,29 +23,73 @@ import (
 // routingRule holds the matching criteria and target ISN (used to route signals based on their content)
 type routingRule struct {
 
-	// matchPattern - when operator is anything other than equals the string is treated as a glob pattern: * = any chars, ? = single char
+	// condition is the compiled matching criteria.
+	// Simple rules (a match pattern for the routing field) are compiled to a single field comparison.
+	condition condition
+
+	// isnID is the target ISN for signals matching this rule
+	isnID uuid.UUID
+
+	// IsnSlug is also held in the cache to avoid DB lookups during signal processing.
+	isnSlug string
+
+	// sequence is the rule sequence number from the routing config (rules are evaluated in sequence order)
+	sequence int32
+}
+
+// condition is a compiled routing condition (see Condition).
+// Exactly one of all, any, not or field is set.
+type condition struct {
+	all []condition
+	any []condition
+	not *condition
+
+	// field is the JSON path of the content field that is compared with the matchPattern, or a routing context field (see RoutingContext)
+	field string
+
+	// matchPattern - for the matches and does_not_match operators the string is treated as a glob pattern: * = any chars, ? = single char
 	matchPattern string
 
-	// operator "matches", "equals", "does_not_match", "does_not_equal"
+	// operator is one of the ValidRouteMatchingOperators
 	operator string
 
 	// isCaseInsensitive when true do a case insensitive match
 	isCaseInsensitive bool
 
-	// isnID is the target ISN for signals matching this rule
-	isnID uuid.UUID
+	// the operands below are parsed from the matchPattern when the rule is compiled so the pattern is not re-parsed for every signal
 
-	// IsnSlug is also held in the cache to avoid DB lookups during signal processing.
-	isnSlug string
+	// regex is the compiled pattern for the regex operator
+	regex *regexp.Regexp
+
+	// values is the list of values for the in operator
+	values []string
+
+	// number is the bound for numeric gt/gte/lt/lte comparisons
+	number *float64
+
+	// date is the bound for ISO date gt/gte/lt/lte comparisons
+	date *time.Time
 }
 
 // signalRoutingConfig is the routing configuration for a signal type path.
-// A signalRoutingConfig defines the routingField + the RoutingRules for the signal type path.
-// For instance:  payload.PortOfEntry + [ "*Felistowe*",... ]
 // Ordering of the routes determined by the SQL query and preserved in the slice.
 type signalRoutingConfig struct {
-	routingField string
+	// routingMode "first_match" or "all_matches"
+	routingMode string
+
 	routingRules []routingRule
+
+	// fallback is the ISN used for signals that don't match any of the routing rules (nil if the config has no fallback ISN)
+	fallback *Target
+
+	// headerNames are the request headers referenced by the routing rules (@header fields)
+	headerNames []string
+}
+
+// Target is an ISN resolved by the routing rules
+type Target struct {
+	IsnID   uuid.UUID
+	IsnSlug string
 }
 
 // Cache holds all the routing configs defined on the server.
@@ -74,31 +122,40 @@ func (c *Cache) Load(ctx context.Context) error {
 	for _, row := range rows {
 		key := fmt.Sprintf("%s/v%s", row.SignalTypeSlug, row.SemVer)
 
-		config, exists := configs[key]
-		if !exists {
-			config = signalRoutingConfig{routingField: row.RoutingField}
-		}
-
-		if !signalsd.ValidRouteMatchingOperators[row.Operator] {
-			slog.Warn("routing cache: skipping mapping with invalid operator",
+		ruleCondition, err := compileRule(row)
+		if err != nil {
+			slog.Warn("routing cache: skipping invalid routing rule",
 				slog.String("signal_type", key),
-				slog.String("operator", row.Operator),
-				slog.String("pattern", row.MatchPattern),
+				slog.Int("rule_sequence", int(row.RuleSequence)),
+				slog.String("error", err.Error()),
 			)
 			continue
 		}
 
+		config := configs[key]
+		config.routingMode = row.RoutingMode
+		if row.FallbackIsnID != nil && row.FallbackIsnSlug != nil {
+			config.fallback = &Target{IsnID: *row.FallbackIsnID, IsnSlug: *row.FallbackIsnSlug}
+		}
 		config.routingRules = append(config.routingRules, routingRule{
-			matchPattern:      row.MatchPattern,
-			operator:          row.Operator,
-			isCaseInsensitive: row.IsCaseInsensitive,
-			isnID:             row.IsnID,
-			isnSlug:           row.IsnSlug,
+			condition: ruleCondition,
+			isnID:     row.IsnID,
+			isnSlug:   row.IsnSlug,
+			sequence:  row.RuleSequence,
 		})
 
 		configs[key] = config
 	}
 
+	for key, config := range configs {
+		var fields []string
+		for _, rule := range config.routingRules {
+			fields = append(fields, rule.condition.fields()...)
+		}
+		config.headerNames = headerNames(fields)
+		configs[key] = config
+	}
+
 	c.mu.Lock()
 	c.SignalRoutingConfigs = configs
 	c.mu.Unlock()
@@ -106,6 +163,100 @@ func (c *Cache) Load(ctx context.Context) error {
 	return nil
 }
 
+// compileRule returns the compiled condition for a routing rule.
+// Compound rules are compiled from the stored condition and simple rules are compiled to a comparison on the config's routing field.
+func compileRule(row database.GetSignalRoutingConfigsRow) (condition, error) {
+	if row.Condition != nil {
+		var ruleCondition Condition
+		if err := json.Unmarshal(row.Condition, &ruleCondition); err != nil {
+			return condition{}, fmt.Errorf("invalid condition: %v", err)
+		}
+		if err := ruleCondition.Validate(); err != nil {
+			return condition{}, fmt.Errorf("invalid condition: %v", err)
+		}
+		return ruleCondition.compile()
+	}
+
+	if row.RoutingField == nil || row.MatchPattern == nil || row.Operator == nil {
+		return condition{}, fmt.Errorf("simple rules require a routing field, match pattern and operator")
+	}
+	return compileSimpleRule(*row.RoutingField, *row.MatchPattern, *row.Operator, row.IsCaseInsensitive)
+}
+
+// compileSimpleRule returns the field comparison used for a simple rule (a match pattern for the routing field)
+func compileSimpleRule(routingField, matchPattern, operator string, isCaseInsensitive bool) (condition, error) {
+	if routingField == "" {
+		return condition{}, fmt.Errorf("simple rules require a routing field, match pattern and operator")
+	}
+	return compileComparison(routingField, matchPattern, operator, isCaseInsensitive)
+}
+
+// compileComparison validates the operator and pattern for a field comparison and parses the pattern into the operand used by the operator.
+func compileComparison(field, matchPattern, operator string, isCaseInsensitive bool) (condition, error) {
+	if !signalsd.ValidRouteMatchingOperators[operator] {
+		return condition{}, fmt.Errorf("invalid route matching operator %q", operator)
+	}
+
+	c := condition{
+		field:             field,
+		matchPattern:      matchPattern,
+		operator:          operator,
+		isCaseInsensitive: isCaseInsensitive,
+	}
+
+	switch operator {
+	case "exists", "not_exists":
+		if matchPattern != "" {
+			return condition{}, fmt.Errorf("the %s operator does not use a pattern", operator)
+		}
+		return c, nil
+	}
+
+	if matchPattern == "" {
+		return condition{}, fmt.Errorf("the %s operator requires a pattern", operator)
+	}
+
+	switch operator {
+	case "regex":
+		expr := matchPattern
+		if isCaseInsensitive {
+			expr = "(?i)" + expr
+		}
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return condition{}, fmt.Errorf("invalid regular expression %q: %v", matchPattern, err)
+		}
+		c.regex = regex
+	case "in":
+		for value := range strings.SplitSeq(matchPattern, ",") {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				return condition{}, fmt.Errorf("the in operator requires a comma-separated list of values (got %q)", matchPattern)
+			}
+			c.values = append(c.values, value)
+		}
+	case "gt", "gte", "lt", "lte":
+		if number, err := strconv.ParseFloat(matchPattern, 64); err == nil {
+			c.number = &number
+		} else if date, ok := parseDate(matchPattern); ok {
+			c.date = &date
+		} else {
+			return condition{}, fmt.Errorf("the %s operator requires a number or an ISO 8601 date (got %q)", operator, matchPattern)
+		}
+	}
+	return c, nil
+}
+
+// parseDate parses ISO 8601 dates (2006-01-02) and date-times (RFC 3339)
+func parseDate(s string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
 // StartPolling starts a background goroutine that reloads the routing rules from the
 // database every interval. Errors are logged but do not stop the polling loop.
 // The goroutine exits when ctx is cancelled.
@@ -132,45 +283,140 @@ func (c *Cache) Len() int {
 	return len(c.SignalRoutingConfigs)
 }
 
-// Resolve extracts the Routing Field from the json content and returns the target ISN
-// for the first matching pattern.
+// HeaderNames returns the request headers referenced by the routing rules for the signal type path (@header fields).
+// Only these headers are kept in the routing context for signals of this type (see NewRoutingContext).
+func (c *Cache) HeaderNames(signalTypePath string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.SignalRoutingConfigs[signalTypePath].headerNames
+}
+
+// Resolve returns the target ISNs for the routing rules defined for the Signal Type Path that match the json content.
+//
+// In first_match mode only the ISN for the first matching rule is returned.
+// In all_matches mode the ISNs for every matching rule are returned, in rule order (each ISN is only returned once).
+//
+// Simple rules compare the Routing Field specified in the Routing Config with the rule's match pattern.
+// Compound rules evaluate the rule's condition (comparisons on any number of fields combined with all/any/not groups).
+//
+// If a field resolves to a JSON array, the comparison applies if any element matches.
 //
-// The Routing Field specified in the Routing Config for the supplied Signal Type Path.
+// Fields starting with @ are read from the routing context (the sender of the signal and the request headers) rather than the content.
 //
-// If the field resolves to a JSON array, the rule applies if any element matches.
+// If no rule matches the content the config's fallback ISN is returned (when the config has one).
 //
-// Returns ok=false if no rule exists for the signal type or no pattern matches field content.
-// Both isnID and isnSlug are returned from the cache to avoid DB lookups during signal processing.
-func (c *Cache) Resolve(signalTypePath string, content json.RawMessage) (isnID uuid.UUID, isnSlug string, ok bool) {
+// Returns an empty slice if no rule exists for the signal type or no rule matches the content (and there is no fallback ISN).
+// Both the isn ID and slug are returned from the cache to avoid DB lookups during signal processing.
+func (c *Cache) Resolve(signalTypePath string, content json.RawMessage, routingContext RoutingContext) []Target {
 	c.mu.RLock()
 	SignalRoutingConfig, exists := c.SignalRoutingConfigs[signalTypePath]
 	c.mu.RUnlock()
 
 	if !exists {
-		return uuid.Nil, "", false
+		return nil
 	}
 
-	result := gjson.GetBytes(content, SignalRoutingConfig.routingField)
-	if !result.Exists() {
-		return uuid.Nil, "", false
+	var targets []Target
+	for _, rule := range SignalRoutingConfig.matchingRules(content, routingContext) {
+		if !slices.ContainsFunc(targets, func(t Target) bool { return t.IsnSlug == rule.isnSlug }) {
+			targets = append(targets, Target{IsnID: rule.isnID, IsnSlug: rule.isnSlug})
+		}
+	}
+
+	if len(targets) == 0 && SignalRoutingConfig.fallback != nil {
+		targets = append(targets, *SignalRoutingConfig.fallback)
+	}
+
+	return targets
+}
+
+// fields returns the fields compared by the condition
+func (c condition) fields() []string {
+	var fields []string
+	for _, child := range c.all {
+		fields = append(fields, child.fields()...)
+	}
+	for _, child := range c.any {
+		fields = append(fields, child.fields()...)
+	}
+	if c.not != nil {
+		fields = append(fields, c.not.fields()...)
+	}
+	if c.field != "" {
+		fields = append(fields, c.field)
+	}
+	return fields
+}
+
+// matchingRules returns the rules that match the content and routing context, in sequence order.
+// In first_match mode only the first matching rule is returned.
+func (c signalRoutingConfig) matchingRules(content []byte, routingContext RoutingContext) []routingRule {
+	var matched []routingRule
+	for _, rule := range c.routingRules {
+		if !rule.condition.matches(content, routingContext) {
+			continue
+		}
+		matched = append(matched, rule)
+		if c.routingMode != signalsd.RoutingModeAllMatches {
+			break
+		}
+	}
+	return matched
+}
+
+// matches returns true if the content and routing context satisfy the condition.
+// Field comparisons are false when the field is not present in the content (or the value is not set in the routing context).
+func (c condition) matches(content []byte, routingContext RoutingContext) bool {
+	switch {
+	case c.all != nil:
+		for _, child := range c.all {
+			if !child.matches(content, routingContext) {
+				return false
+			}
+		}
+		return true
+	case c.any != nil:
+		for _, child := range c.any {
+			if child.matches(content, routingContext) {
+				return true
+			}
+		}
+		return false
+	case c.not != nil:
+		return !c.not.matches(content, routingContext)
 	}
 
-	for _, rule := range SignalRoutingConfig.routingRules {
-		if matchesResult(rule, result) {
-			return rule.isnID, rule.isnSlug, true
+	if IsContextField(c.field) {
+		values := routingContext.values(c.field)
+		switch c.operator {
+		case "exists":
+			return len(values) > 0
+		case "not_exists":
+			return len(values) == 0
 		}
+		return slices.ContainsFunc(values, func(v string) bool { return matchesString(c, v) })
 	}
 
-	return uuid.Nil, "", false
+	result := gjson.GetBytes(content, c.field)
+	switch c.operator {
+	case "exists":
+		return result.Exists()
+	case "not_exists":
+		return !result.Exists()
+	}
+	if !result.Exists() {
+		return false
+	}
+	return matchesResult(c, result)
 }
 
-// matchesResult returns true if the route's criteria match the gjson result.
+// matchesResult returns true if the comparison criteria match the gjson result.
 // For array results, it returns true if any element matches.
-func matchesResult(r routingRule, result gjson.Result) bool {
+func matchesResult(c condition, result gjson.Result) bool {
 	if result.IsArray() {
 		matched := false
 		result.ForEach(func(_, v gjson.Result) bool {
-			if matchesString(r, v.String()) {
+			if matchesString(c, v.String()) {
 				matched = true
 				return false // stop iteration
 			}
@@ -178,10 +424,10 @@ func matchesResult(r routingRule, result gjson.Result) bool {
 		})
 		return matched
 	}
-	return matchesString(r, result.String())
+	return matchesString(c, result.String())
 }
 
-func matchesString(r routingRule, s string) bool {
+func matchesString(r condition, s string) bool {
 	switch r.operator {
 	case "equals":
 		if r.isCaseInsensitive {
@@ -203,7 +449,62 @@ func matchesString(r routingRule, s string) bool {
 			return !strings.EqualFold(s, r.matchPattern)
 		}
 		return s != r.matchPattern
+	case "starts_with":
+		if r.isCaseInsensitive {
+			return strings.HasPrefix(strings.ToLower(s), strings.ToLower(r.matchPattern))
+		}
+		return strings.HasPrefix(s, r.matchPattern)
+	case "ends_with":
+		if r.isCaseInsensitive {
+			return strings.HasSuffix(strings.ToLower(s), strings.ToLower(r.matchPattern))
+		}
+		return strings.HasSuffix(s, r.matchPattern)
+	case "regex":
+		return r.regex != nil && r.regex.MatchString(s)
+	case "in":
+		return slices.ContainsFunc(r.values, func(v string) bool {
+			if r.isCaseInsensitive {
+				return strings.EqualFold(s, v)
+			}
+			return s == v
+		})
+	case "gt", "gte", "lt", "lte":
+		return matchesRange(r, s)
+	default:
+		return false
+	}
+}
+
+// matchesRange compares the value with the numeric or date bound for the gt/gte/lt/lte operators.
+// Values that can't be parsed as the same type as the bound do not match.
+func matchesRange(r condition, s string) bool {
+	var order int
+	switch {
+	case r.number != nil:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return false
+		}
+		order = cmp.Compare(n, *r.number)
+	case r.date != nil:
+		d, ok := parseDate(s)
+		if !ok {
+			return false
+		}
+		order = d.Compare(*r.date)
 	default:
 		return false
 	}
+
+	switch r.operator {
+	case "gt":
+		return order > 0
+	case "gte":
+		return order >= 0
+	case "lt":
+		return order < 0
+	case "lte":
+		return order <= 0
+	}
+	return false
 }
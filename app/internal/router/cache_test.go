@@ -1,8 +1,10 @@
 package router
 
 import (
+	"slices"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/tidwall/gjson"
 )
 
@@ -42,7 +44,7 @@ func TestMatchesString(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r := routingRule{
+			r := condition{
 				matchPattern:      tt.pattern,
 				operator:          tt.operator,
 				isCaseInsensitive: tt.caseInsensitive,
@@ -55,6 +57,51 @@ func TestMatchesString(t *testing.T) {
 	}
 }
 
+func TestCompiledOperators(t *testing.T) {
+	content := []byte(`{"tariffCode": "0201300090", "weight": 1250.5, "arrival": "2026-04-01T09:30:00Z", "port": "Felixstowe", "tags": ["chilled", "beef"]}`)
+
+	tests := []struct {
+		name            string
+		field           string
+		operator        string
+		pattern         string
+		caseInsensitive bool
+		want            bool
+	}{
+		{name: "starts_with: match", field: "tariffCode", operator: "starts_with", pattern: "0201", want: true},
+		{name: "starts_with: no match", field: "tariffCode", operator: "starts_with", pattern: "0202", want: false},
+		{name: "ends_with case-insensitive: match", field: "port", operator: "ends_with", pattern: "STOWE", caseInsensitive: true, want: true},
+		{name: "regex: match", field: "tariffCode", operator: "regex", pattern: `^020[1-3]\d{6}$`, want: true},
+		{name: "regex: no match", field: "tariffCode", operator: "regex", pattern: `^0202`, want: false},
+		{name: "regex case-insensitive: match", field: "port", operator: "regex", pattern: `^felix`, caseInsensitive: true, want: true},
+		{name: "in: match", field: "port", operator: "in", pattern: "Dover, Felixstowe", want: true},
+		{name: "in: no match", field: "port", operator: "in", pattern: "Dover,Southampton", want: false},
+		{name: "in: array element match", field: "tags", operator: "in", pattern: "frozen,chilled", want: true},
+		{name: "gt: number", field: "weight", operator: "gt", pattern: "1000", want: true},
+		{name: "gte: equal number", field: "weight", operator: "gte", pattern: "1250.5", want: true},
+		{name: "lt: number", field: "weight", operator: "lt", pattern: "1000", want: false},
+		{name: "lte: non numeric value", field: "port", operator: "lte", pattern: "1000", want: false},
+		{name: "gt: date", field: "arrival", operator: "gt", pattern: "2026-04-01", want: true},
+		{name: "lt: date-time", field: "arrival", operator: "lt", pattern: "2026-04-01T09:00:00Z", want: false},
+		{name: "exists: present", field: "port", operator: "exists", want: true},
+		{name: "exists: missing", field: "missing", operator: "exists", want: false},
+		{name: "not_exists: missing", field: "missing", operator: "not_exists", want: true},
+		{name: "not_exists: present", field: "port", operator: "not_exists", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := compileComparison(tt.field, tt.pattern, tt.operator, tt.caseInsensitive)
+			if err != nil {
+				t.Fatalf("compileComparison() error = %v", err)
+			}
+			if got := c.matches(content, RoutingContext{}); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestMatchesResult(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -92,7 +139,7 @@ func TestMatchesResult(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r := routingRule{
+			r := condition{
 				matchPattern: tt.pattern,
 				operator:     tt.operator,
 			}
@@ -103,3 +150,96 @@ func TestMatchesResult(t *testing.T) {
 		})
 	}
 }
+
+func TestConditionMatches(t *testing.T) {
+	portOfEntry := func(pattern string) condition {
+		return condition{field: "payload.portOfEntry", operator: "matches", matchPattern: pattern, isCaseInsensitive: true}
+	}
+	commodityCode := func(pattern string) condition {
+		return condition{field: "payload.commodityCode", operator: "matches", matchPattern: pattern}
+	}
+	content := []byte(`{"payload": {"portOfEntry": "Port of Felixstowe", "commodityCode": "020130"}}`)
+
+	tests := []struct {
+		name      string
+		condition condition
+		want      bool
+	}{
+		{name: "field comparison: match", condition: portOfEntry("*felixstowe*"), want: true},
+		{name: "field comparison: no match", condition: portOfEntry("*rotterdam*"), want: false},
+		{name: "field comparison: missing field", condition: condition{field: "payload.missing", operator: "does_not_equal", matchPattern: "x"}, want: false},
+		{name: "all: every condition matches", condition: condition{all: []condition{portOfEntry("*felixstowe*"), commodityCode("0201*")}}, want: true},
+		{name: "all: one condition does not match", condition: condition{all: []condition{portOfEntry("*felixstowe*"), commodityCode("0202*")}}, want: false},
+		{name: "any: one condition matches", condition: condition{any: []condition{portOfEntry("*rotterdam*"), commodityCode("0201*")}}, want: true},
+		{name: "any: no condition matches", condition: condition{any: []condition{portOfEntry("*rotterdam*"), commodityCode("0202*")}}, want: false},
+		{name: "not: negates match", condition: condition{not: &condition{all: []condition{portOfEntry("*rotterdam*")}}}, want: true},
+		{name: "not: missing field", condition: condition{not: &condition{field: "payload.missing", operator: "equals", matchPattern: "x"}}, want: true},
+		{
+			name: "nested groups",
+			condition: condition{all: []condition{
+				portOfEntry("*felixstowe*"),
+				{any: []condition{commodityCode("0202*"), {not: &condition{field: "payload.commodityCode", operator: "equals", matchPattern: "999999"}}}},
+			}},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.condition.matches(content, RoutingContext{}); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	portIsnID := uuid.New()
+	commodityIsnID := uuid.New()
+
+	rules := []routingRule{
+		{condition: condition{field: "port", operator: "equals", matchPattern: "felixstowe"}, isnID: portIsnID, isnSlug: "port-isn"},
+		{condition: condition{field: "commodity", operator: "matches", matchPattern: "0201*"}, isnID: commodityIsnID, isnSlug: "commodity-isn"},
+		{condition: condition{field: "port", operator: "matches", matchPattern: "felix*"}, isnID: portIsnID, isnSlug: "port-isn"},
+	}
+
+	cache := &Cache{
+		SignalRoutingConfigs: map[string]signalRoutingConfig{
+			"first/v1.0.0": {routingMode: "first_match", routingRules: rules},
+			"all/v1.0.0":   {routingMode: "all_matches", routingRules: rules},
+			"fallback/v1.0.0": {
+				routingMode:  "first_match",
+				routingRules: rules,
+				fallback:     &Target{IsnID: uuid.New(), IsnSlug: "fallback-isn"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		signalTypePath string
+		content        string
+		want           []string
+	}{
+		{"first match returns the first matching rule", "first/v1.0.0", `{"port": "felixstowe", "commodity": "020110"}`, []string{"port-isn"}},
+		{"first match uses rule order", "first/v1.0.0", `{"port": "dover", "commodity": "020110"}`, []string{"commodity-isn"}},
+		{"all matches returns every matching isn", "all/v1.0.0", `{"port": "felixstowe", "commodity": "020110"}`, []string{"port-isn", "commodity-isn"}},
+		{"all matches returns each isn once", "all/v1.0.0", `{"port": "felixstowe"}`, []string{"port-isn"}},
+		{"no match", "all/v1.0.0", `{"port": "dover"}`, nil},
+		{"no config", "other/v1.0.0", `{"port": "felixstowe"}`, nil},
+		{"fallback isn used when no rule matches", "fallback/v1.0.0", `{"port": "dover"}`, []string{"fallback-isn"}},
+		{"fallback isn not used when a rule matches", "fallback/v1.0.0", `{"port": "felixstowe"}`, []string{"port-isn"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, target := range cache.Resolve(tt.signalTypePath, []byte(tt.content), RoutingContext{}) {
+				got = append(got, target.IsnSlug)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
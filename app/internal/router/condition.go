@@ -0,0 +1,192 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxConditionDepth limits the nesting of all/any/not groups in a routing condition
+const maxConditionDepth = 8
+
+// Condition is the boolean expression used by compound routing rules.
+//
+// A condition is either a group or a field comparison:
+//   - all: true when every condition in the list is true
+//   - any: true when at least one condition in the list is true
+//   - not: true when the condition is false
+//   - field/operator/pattern: compares the value of a field in the signal content with the pattern (same operators as simple routing rules).
+//     The field can also be a routing context field such as @sender.client_organization or @header.X-Origin (see RoutingContext)
+//
+// Operators:
+//   - matches/does_not_match: glob pattern match (* = any chars, ? = single char)
+//   - equals/does_not_equal: string equality
+//   - starts_with/ends_with: string prefix/suffix
+//   - regex: regular expression (RE2 syntax, not anchored - use ^ and $ to match the whole value)
+//   - in: the value equals one of the values in a comma-separated list
+//   - gt/gte/lt/lte: numeric comparison, or date comparison when the pattern is an ISO 8601 date (2006-01-02) or date-time (RFC 3339)
+//   - exists/not_exists: the field is (or is not) present in the signal content - no pattern is used
+//
+// Example - route signals for Felixstowe with a commodity code starting 0201:
+//
+//	{"all": [
+//	  {"field": "payload.portOfEntry", "operator": "matches", "pattern": "*felixstowe*", "is_case_insensitive": true},
+//	  {"field": "payload.commodityCode", "operator": "matches", "pattern": "0201*"}
+//	]}
+//
+// Example - route signals sent by HMRC service accounts:
+//
+//	{"field": "@sender.client_organization", "operator": "equals", "pattern": "HMRC", "is_case_insensitive": true}
+//
+// Field comparisons are false when the field is not present in the signal content (except for not_exists).
+type Condition struct {
+	All               []Condition `json:"all,omitempty"`
+	Any               []Condition `json:"any,omitempty"`
+	Not               *Condition  `json:"not,omitempty"`
+	Field             string      `json:"field,omitempty" example:"payload.portOfEntry"`
+	Operator          string      `json:"operator,omitempty" enums:"matches,equals,does_not_match,does_not_equal,starts_with,ends_with,regex,in,gt,gte,lt,lte,exists,not_exists" example:"matches"`
+	Pattern           string      `json:"pattern,omitempty" example:"*felixstowe*"`
+	IsCaseInsensitive bool        `json:"is_case_insensitive,omitempty" example:"true"`
+}
+
+// Validate checks the condition is well formed: each condition must be exactly one of an all group, an any group, a not group or a field comparison,
+// groups must not be empty and field comparisons must have a valid field path, operator and pattern (see ValidateComparison).
+func (c Condition) Validate() error {
+	return c.validate(1)
+}
+
+func (c Condition) validate(depth int) error {
+	if depth > maxConditionDepth {
+		return fmt.Errorf("conditions can't be nested more than %d levels deep", maxConditionDepth)
+	}
+
+	kinds := 0
+	if c.All != nil {
+		kinds++
+	}
+	if c.Any != nil {
+		kinds++
+	}
+	if c.Not != nil {
+		kinds++
+	}
+	isComparison := c.Field != "" || c.Operator != "" || c.Pattern != ""
+	if isComparison {
+		kinds++
+	}
+	if kinds != 1 {
+		return errors.New("each condition must contain exactly one of all, any, not or a field comparison (field, operator and pattern)")
+	}
+
+	switch {
+	case c.All != nil:
+		return validateGroup("all", c.All, depth)
+	case c.Any != nil:
+		return validateGroup("any", c.Any, depth)
+	case c.Not != nil:
+		return c.Not.validate(depth + 1)
+	}
+
+	if c.Field == "" {
+		return errors.New("field comparisons require a field")
+	}
+	if err := ValidateFieldPath(c.Field); err != nil {
+		return fmt.Errorf("field %q %v", c.Field, err)
+	}
+	_, err := compileComparison(c.Field, c.Pattern, c.Operator, c.IsCaseInsensitive)
+	return err
+}
+
+func validateGroup(name string, conditions []Condition, depth int) error {
+	if len(conditions) == 0 {
+		return fmt.Errorf("%s must contain at least one condition", name)
+	}
+	for i, condition := range conditions {
+		if err := condition.validate(depth + 1); err != nil {
+			return fmt.Errorf("%s[%d]: %v", name, i, err)
+		}
+	}
+	return nil
+}
+
+// Fields returns the field paths referenced by the condition (used to check the fields are defined in the signal type schema).
+// Routing context fields are included.
+func (c Condition) Fields() []string {
+	var fields []string
+	for _, condition := range c.All {
+		fields = append(fields, condition.Fields()...)
+	}
+	for _, condition := range c.Any {
+		fields = append(fields, condition.Fields()...)
+	}
+	if c.Not != nil {
+		fields = append(fields, c.Not.Fields()...)
+	}
+	if c.Field != "" {
+		fields = append(fields, c.Field)
+	}
+	return fields
+}
+
+// ValidateFieldPath checks a routing field path is a plain dot notation JSON path or a routing context field (see RoutingContext).
+//
+// Under the covers the router uses gjson paths, however the special pattern matching symbols (*?#@|!()[]%<>=)
+// and numeric path segments (array index access) are not allowed.
+func ValidateFieldPath(path string) error {
+	if IsContextField(path) {
+		return validateContextField(path)
+	}
+
+	// prevent the use of chars with special meaning in gjson
+	if strings.ContainsAny(path, `*?#@|!()[]%<>=`) {
+		return errors.New("must be a plain JSON path (e.g. payload.portOfEntry) - wildcards and gjson operators are not supported")
+	}
+
+	// prevent numeric path segments (gjson array index access e.g. payload.0.item)
+	for seg := range strings.SplitSeq(path, ".") {
+		if _, err := strconv.Atoi(seg); err == nil {
+			return errors.New("must not contain numeric segments - routing by array index is not supported")
+		}
+	}
+	return nil
+}
+
+// compile converts a validated condition to the form used by the cache.
+func (c Condition) compile() (condition, error) {
+	switch {
+	case c.All != nil:
+		compiled := condition{all: make([]condition, len(c.All))}
+		for i, child := range c.All {
+			var err error
+			if compiled.all[i], err = child.compile(); err != nil {
+				return condition{}, err
+			}
+		}
+		return compiled, nil
+	case c.Any != nil:
+		compiled := condition{any: make([]condition, len(c.Any))}
+		for i, child := range c.Any {
+			var err error
+			if compiled.any[i], err = child.compile(); err != nil {
+				return condition{}, err
+			}
+		}
+		return compiled, nil
+	case c.Not != nil:
+		not, err := c.Not.compile()
+		if err != nil {
+			return condition{}, err
+		}
+		return condition{not: &not}, nil
+	default:
+		return compileComparison(c.Field, c.Pattern, c.Operator, c.IsCaseInsensitive)
+	}
+}
+
+// ValidateComparison checks the operator is supported and the pattern is valid for the operator
+// (e.g. regex patterns must compile and the gt/gte/lt/lte operators require a number or an ISO 8601 date).
+func ValidateComparison(operator, pattern string) error {
+	_, err := compileComparison("", pattern, operator, false)
+	return err
+}
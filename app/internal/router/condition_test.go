@@ -0,0 +1,63 @@
+package router
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConditionValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		wantErr   bool
+	}{
+		{name: "field comparison", condition: `{"field": "payload.portOfEntry", "operator": "matches", "pattern": "*felixstowe*"}`},
+		{name: "all group", condition: `{"all": [{"field": "a", "operator": "equals", "pattern": "x"}, {"field": "b", "operator": "matches", "pattern": "y*"}]}`},
+		{name: "any group with nested not", condition: `{"any": [{"not": {"field": "a", "operator": "equals", "pattern": "x"}}]}`},
+		{name: "empty condition", condition: `{}`, wantErr: true},
+		{name: "empty group", condition: `{"all": []}`, wantErr: true},
+		{name: "group and field comparison", condition: `{"all": [{"field": "a", "operator": "equals", "pattern": "x"}], "field": "b"}`, wantErr: true},
+		{name: "missing pattern", condition: `{"field": "a", "operator": "equals"}`, wantErr: true},
+		{name: "invalid operator", condition: `{"field": "a", "operator": "like", "pattern": "x"}`, wantErr: true},
+		{name: "gjson wildcard in field", condition: `{"field": "a.*", "operator": "equals", "pattern": "x"}`, wantErr: true},
+		{name: "numeric field segment", condition: `{"field": "a.0.b", "operator": "equals", "pattern": "x"}`, wantErr: true},
+		{name: "invalid nested condition", condition: `{"any": [{"field": "a", "operator": "equals", "pattern": "x"}, {"not": {}}]}`, wantErr: true},
+		{name: "exists without pattern", condition: `{"field": "a", "operator": "exists"}`},
+		{name: "exists with pattern", condition: `{"field": "a", "operator": "exists", "pattern": "x"}`, wantErr: true},
+		{name: "invalid regex", condition: `{"field": "a", "operator": "regex", "pattern": "^(0201"}`, wantErr: true},
+		{name: "in with empty value", condition: `{"field": "a", "operator": "in", "pattern": "0201,,0203"}`, wantErr: true},
+		{name: "gt with date", condition: `{"field": "a", "operator": "gt", "pattern": "2026-04-01"}`},
+		{name: "gt with non numeric pattern", condition: `{"field": "a", "operator": "gt", "pattern": "heavy"}`, wantErr: true},
+		{name: "nested too deep", condition: `{"not": {"not": {"not": {"not": {"not": {"not": {"not": {"not": {"field": "a", "operator": "equals", "pattern": "x"}}}}}}}}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Condition
+			if err := json.Unmarshal([]byte(tt.condition), &c); err != nil {
+				t.Fatalf("invalid test condition: %v", err)
+			}
+			err := c.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConditionFields(t *testing.T) {
+	var c Condition
+	if err := json.Unmarshal([]byte(`{"all": [{"field": "a", "operator": "equals", "pattern": "x"}, {"any": [{"field": "b", "operator": "equals", "pattern": "y"}, {"not": {"field": "c", "operator": "equals", "pattern": "z"}}]}]}`), &c); err != nil {
+		t.Fatalf("invalid test condition: %v", err)
+	}
+	got := c.Fields()
+	want := []string{"a", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("Fields() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Fields() = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/tidwall/gjson"
+)
+
+// DryRunConfig is a routing config that is evaluated against sample signals without being saved or loaded into the cache.
+// It is used to test draft (and saved) routing configs before they are used to route signals.
+type DryRunConfig struct {
+	RoutingField string
+	RoutingMode  string
+	Rules        []DryRunRule
+
+	// FallbackIsnSlug is the ISN used when no rule matches (optional)
+	FallbackIsnSlug string
+}
+
+// DryRunRule is a routing rule in a DryRunConfig.
+// Simple rules supply a MatchPattern and Operator (compared with the config's RoutingField), compound rules supply a Condition.
+type DryRunRule struct {
+	Sequence          int32
+	IsnSlug           string
+	MatchPattern      string
+	Operator          string
+	IsCaseInsensitive bool
+	Condition         *Condition
+}
+
+// DryRunResult describes how the routing config applies to a signal.
+type DryRunResult struct {
+	// FieldValues are the values of the fields used by the routing rules (in the order they are first used)
+	FieldValues []FieldValue
+
+	// MatchedRules are the sequence numbers of the rules that matched (in first_match mode this is at most one rule)
+	MatchedRules []int32
+
+	// IsnSlugs are the ISNs the signal would be routed to (each ISN is only listed once)
+	IsnSlugs []string
+
+	// UsedFallback is true when no rule matched and the signal would be routed to the fallback ISN
+	UsedFallback bool
+}
+
+// FieldValue is the value of a field extracted from the signal content (or the routing context for fields starting with @).
+// Value is nil when the field is not present.
+type FieldValue struct {
+	Field string
+	Value json.RawMessage
+}
+
+// HeaderNames returns the request headers referenced by the routing rules in the config (@header fields).
+func (config DryRunConfig) HeaderNames() []string {
+	var fields []string
+	for _, rule := range config.Rules {
+		if rule.Condition != nil {
+			fields = append(fields, rule.Condition.Fields()...)
+		} else {
+			fields = append(fields, config.RoutingField)
+		}
+	}
+	return headerNames(fields)
+}
+
+// DryRun returns the routing outcome for each of the supplied signal contents when they are sent with the routing context.
+// Rules are evaluated in sequence order using the same matching logic as Resolve.
+func DryRun(config DryRunConfig, contents []json.RawMessage, routingContext RoutingContext) ([]DryRunResult, error) {
+	routingMode := config.RoutingMode
+	if routingMode == "" {
+		routingMode = signalsd.RoutingModeFirstMatch
+	}
+	if !signalsd.ValidRoutingModes[routingMode] {
+		return nil, fmt.Errorf("invalid routing mode %q", routingMode)
+	}
+
+	compiled := signalRoutingConfig{routingMode: routingMode}
+	var fields []string
+	for i, rule := range config.Rules {
+		var ruleCondition condition
+		if rule.Condition != nil {
+			if err := rule.Condition.Validate(); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid condition: %v", i, err)
+			}
+			var err error
+			if ruleCondition, err = rule.Condition.compile(); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid condition: %v", i, err)
+			}
+			fields = append(fields, rule.Condition.Fields()...)
+		} else {
+			var err error
+			ruleCondition, err = compileSimpleRule(config.RoutingField, rule.MatchPattern, rule.Operator, rule.IsCaseInsensitive)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %v", i, err)
+			}
+			fields = append(fields, config.RoutingField)
+		}
+		compiled.routingRules = append(compiled.routingRules, routingRule{
+			condition: ruleCondition,
+			isnSlug:   rule.IsnSlug,
+			sequence:  rule.Sequence,
+		})
+	}
+
+	// saved configs are loaded in sequence order - draft configs are sorted the same way
+	slices.SortStableFunc(compiled.routingRules, func(a, b routingRule) int {
+		return int(a.sequence) - int(b.sequence)
+	})
+
+	// report each field once
+	var uniqueFields []string
+	for _, field := range fields {
+		if !slices.Contains(uniqueFields, field) {
+			uniqueFields = append(uniqueFields, field)
+		}
+	}
+
+	results := make([]DryRunResult, len(contents))
+	for i, content := range contents {
+		result := DryRunResult{
+			FieldValues:  make([]FieldValue, len(uniqueFields)),
+			MatchedRules: []int32{},
+			IsnSlugs:     []string{},
+		}
+		for j, field := range uniqueFields {
+			result.FieldValues[j].Field = field
+			if IsContextField(field) {
+				result.FieldValues[j].Value = routingContext.rawValue(field)
+			} else if value := gjson.GetBytes(content, field); value.Exists() {
+				result.FieldValues[j].Value = json.RawMessage(value.Raw)
+			}
+		}
+		for _, rule := range compiled.matchingRules(content, routingContext) {
+			result.MatchedRules = append(result.MatchedRules, rule.sequence)
+			if !slices.Contains(result.IsnSlugs, rule.isnSlug) {
+				result.IsnSlugs = append(result.IsnSlugs, rule.isnSlug)
+			}
+		}
+		if len(result.IsnSlugs) == 0 && config.FallbackIsnSlug != "" {
+			result.IsnSlugs = append(result.IsnSlugs, config.FallbackIsnSlug)
+			result.UsedFallback = true
+		}
+		results[i] = result
+	}
+
+	return results, nil
+}
@@ -0,0 +1,96 @@
+package router
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	config := DryRunConfig{
+		RoutingField: "port",
+		Rules: []DryRunRule{
+			// supplied out of order - rules are evaluated in sequence order
+			{Sequence: 2, IsnSlug: "felixstowe-isn", MatchPattern: "felix*", Operator: "matches"},
+			{Sequence: 1, IsnSlug: "dover-isn", MatchPattern: "dover", Operator: "equals", IsCaseInsensitive: true},
+			{Sequence: 3, IsnSlug: "meat-isn", Condition: &Condition{Field: "commodity", Operator: "matches", Pattern: "0201*"}},
+		},
+	}
+
+	contents := []json.RawMessage{
+		json.RawMessage(`{"port": "DOVER", "commodity": "020110"}`),
+		json.RawMessage(`{"port": "felixstowe"}`),
+		json.RawMessage(`{"commodity": "0101"}`),
+	}
+
+	t.Run("first_match", func(t *testing.T) {
+		results, err := DryRun(config, contents, RoutingContext{})
+		if err != nil {
+			t.Fatalf("DryRun() error = %v", err)
+		}
+
+		want := []DryRunResult{
+			{
+				FieldValues:  []FieldValue{{Field: "port", Value: json.RawMessage(`"DOVER"`)}, {Field: "commodity", Value: json.RawMessage(`"020110"`)}},
+				MatchedRules: []int32{1},
+				IsnSlugs:     []string{"dover-isn"},
+			},
+			{
+				FieldValues:  []FieldValue{{Field: "port", Value: json.RawMessage(`"felixstowe"`)}, {Field: "commodity"}},
+				MatchedRules: []int32{2},
+				IsnSlugs:     []string{"felixstowe-isn"},
+			},
+			{
+				FieldValues:  []FieldValue{{Field: "port"}, {Field: "commodity", Value: json.RawMessage(`"0101"`)}},
+				MatchedRules: []int32{},
+				IsnSlugs:     []string{},
+			},
+		}
+		if !reflect.DeepEqual(results, want) {
+			t.Errorf("DryRun() = %+v, want %+v", results, want)
+		}
+	})
+
+	t.Run("all_matches", func(t *testing.T) {
+		allMatches := config
+		allMatches.RoutingMode = "all_matches"
+
+		results, err := DryRun(allMatches, contents[:1], RoutingContext{})
+		if err != nil {
+			t.Fatalf("DryRun() error = %v", err)
+		}
+		if !reflect.DeepEqual(results[0].MatchedRules, []int32{1, 3}) || !reflect.DeepEqual(results[0].IsnSlugs, []string{"dover-isn", "meat-isn"}) {
+			t.Errorf("DryRun() = %+v, want rules 1 and 3 matched", results[0])
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		withFallback := config
+		withFallback.FallbackIsnSlug = "fallback-isn"
+
+		results, err := DryRun(withFallback, contents, RoutingContext{})
+		if err != nil {
+			t.Fatalf("DryRun() error = %v", err)
+		}
+		if results[0].UsedFallback || !reflect.DeepEqual(results[0].IsnSlugs, []string{"dover-isn"}) {
+			t.Errorf("DryRun() = %+v, want the matching rule to be used", results[0])
+		}
+		if !results[2].UsedFallback || !reflect.DeepEqual(results[2].IsnSlugs, []string{"fallback-isn"}) {
+			t.Errorf("DryRun() = %+v, want the fallback ISN to be used", results[2])
+		}
+	})
+
+	t.Run("invalid_config", func(t *testing.T) {
+		invalid := []DryRunConfig{
+			{RoutingMode: "best_match"},
+			{Rules: []DryRunRule{{Sequence: 1, IsnSlug: "dover-isn", MatchPattern: "dover", Operator: "equals"}}},
+			{RoutingField: "port", Rules: []DryRunRule{{Sequence: 1, IsnSlug: "dover-isn", MatchPattern: "dover", Operator: "like"}}},
+			{Rules: []DryRunRule{{Sequence: 1, IsnSlug: "dover-isn", Condition: &Condition{All: []Condition{}}}}},
+		}
+		for i, config := range invalid {
+			if _, err := DryRun(config, contents, RoutingContext{}); err == nil {
+				t.Errorf("config %d: expected an error", i)
+			}
+		}
+	})
+}
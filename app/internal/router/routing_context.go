@@ -0,0 +1,160 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Routing rules can use information about the sender of a signal as well as the signal content.
+// Context fields start with @ (this character is not allowed in content field paths, so the two can't be confused):
+//   - @sender.account_id: the ID of the account that submitted the signal
+//   - @sender.account_type: user or service_account
+//   - @sender.client_organization: the organization of the service account that submitted the signal (not set for user accounts)
+//   - @header.{Header-Name}: the value of a header on the submission request (header names are case insensitive)
+const (
+	SenderFieldPrefix = "@sender."
+	HeaderFieldPrefix = "@header."
+)
+
+// senderFields are the account details that can be used with SenderFieldPrefix
+var senderFields = []string{"account_id", "account_type", "client_organization"}
+
+// excludedHeaders can't be used in routing rules.
+// The routing context is stored with queued and unroutable signals, so credentials must not be kept.
+var excludedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+// RoutingContext describes the sender of a signal and the request used to submit it.
+type RoutingContext struct {
+	AccountID   uuid.UUID `json:"account_id"`
+	AccountType string    `json:"account_type"`
+
+	// ClientOrganization is only set for service accounts
+	ClientOrganization string `json:"client_organization,omitempty"`
+
+	// Headers are the headers on the submission request that are used by the routing rules (see NewRoutingContext)
+	Headers http.Header `json:"headers,omitempty"`
+}
+
+// NewRoutingContext returns the routing context for a request.
+//
+// Only the headers in headerNames (the headers referenced by the routing rules - see Cache.HeaderNames) are included:
+// the routing context is stored with queued and unroutable signals, so other headers (which may contain credentials or personal data) are not kept.
+func NewRoutingContext(accountID uuid.UUID, accountType, clientOrganization string, headers http.Header, headerNames []string) RoutingContext {
+	var kept http.Header
+	for _, name := range headerNames {
+		if slices.ContainsFunc(excludedHeaders, func(h string) bool { return strings.EqualFold(h, name) }) {
+			continue
+		}
+		if values := headers.Values(name); len(values) > 0 {
+			if kept == nil {
+				kept = http.Header{}
+			}
+			kept[http.CanonicalHeaderKey(name)] = slices.Clone(values)
+		}
+	}
+	return RoutingContext{
+		AccountID:          accountID,
+		AccountType:        accountType,
+		ClientOrganization: clientOrganization,
+		Headers:            kept,
+	}
+}
+
+// headerNames returns the header names referenced by @header fields (each header is only listed once).
+func headerNames(fields []string) []string {
+	var names []string
+	for _, field := range fields {
+		name, ok := strings.CutPrefix(field, HeaderFieldPrefix)
+		if !ok {
+			continue
+		}
+		name = http.CanonicalHeaderKey(name)
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// IsContextField returns true if the field refers to the routing context rather than the signal content.
+func IsContextField(field string) bool {
+	return strings.HasPrefix(field, "@")
+}
+
+// validateContextField checks the field is a supported sender field or a valid header name.
+func validateContextField(field string) error {
+	if name, ok := strings.CutPrefix(field, SenderFieldPrefix); ok {
+		if !slices.Contains(senderFields, name) {
+			return fmt.Errorf("is not a sender field - use one of %s%s", SenderFieldPrefix, strings.Join(senderFields, ", "+SenderFieldPrefix))
+		}
+		return nil
+	}
+	if name, ok := strings.CutPrefix(field, HeaderFieldPrefix); ok {
+		if !isValidHeaderName(name) {
+			return errors.New("must be followed by a header name (letters, digits and hyphens)")
+		}
+		if slices.ContainsFunc(excludedHeaders, func(h string) bool { return strings.EqualFold(h, name) }) {
+			return fmt.Errorf("can't be used for routing - the %s header is not kept with the signal", name)
+		}
+		return nil
+	}
+	return fmt.Errorf("fields starting with @ must start with %s or %s", SenderFieldPrefix, HeaderFieldPrefix)
+}
+
+func isValidHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// values returns the values of a context field (an empty slice when the value is not set).
+// Headers can have more than one value - comparisons apply if any value matches (in the same way as JSON arrays in the signal content).
+func (rc RoutingContext) values(field string) []string {
+	if name, ok := strings.CutPrefix(field, HeaderFieldPrefix); ok {
+		return rc.Headers.Values(name)
+	}
+
+	var value string
+	switch strings.TrimPrefix(field, SenderFieldPrefix) {
+	case "account_id":
+		if rc.AccountID != uuid.Nil {
+			value = rc.AccountID.String()
+		}
+	case "account_type":
+		value = rc.AccountType
+	case "client_organization":
+		value = rc.ClientOrganization
+	}
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+// rawValue returns the value of a context field as JSON (used to report field values in dry runs).
+// Headers with more than one value are returned as an array. Returns nil if the value is not set.
+func (rc RoutingContext) rawValue(field string) json.RawMessage {
+	values := rc.values(field)
+	var raw []byte
+	switch len(values) {
+	case 0:
+		return nil
+	case 1:
+		raw, _ = json.Marshal(values[0])
+	default:
+		raw, _ = json.Marshal(values)
+	}
+	return raw
+}
@@ -0,0 +1,126 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateContextField(t *testing.T) {
+	tests := []struct {
+		field   string
+		wantErr bool
+	}{
+		{field: "@sender.account_id"},
+		{field: "@sender.account_type"},
+		{field: "@sender.client_organization"},
+		{field: "@header.X-Origin-System"},
+		{field: "@sender.role", wantErr: true},
+		{field: "@sender.", wantErr: true},
+		{field: "@header.", wantErr: true},
+		{field: "@header.X Origin", wantErr: true},
+		{field: "@header.authorization", wantErr: true},
+		{field: "@header.Cookie", wantErr: true},
+		{field: "@payload.port", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			err := ValidateFieldPath(tt.field)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFieldPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewRoutingContext(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer secret")
+	headers.Set("Cookie", "session=secret")
+	headers.Set("X-Origin-System", "chief")
+	headers.Set("X-Unused", "not referenced by the routing rules")
+
+	routingContext := NewRoutingContext(uuid.New(), "service_account", "HMRC", headers, []string{"x-origin-system", "Authorization"})
+
+	if routingContext.Headers.Get("Authorization") != "" || routingContext.Headers.Get("Cookie") != "" {
+		t.Errorf("expected credentials to be removed, got %v", routingContext.Headers)
+	}
+	if routingContext.Headers.Get("X-Unused") != "" {
+		t.Errorf("expected headers that are not used by the routing rules to be removed, got %v", routingContext.Headers)
+	}
+	if routingContext.Headers.Get("X-Origin-System") != "chief" {
+		t.Errorf("expected X-Origin-System header to be kept, got %v", routingContext.Headers)
+	}
+	if headers.Get("Authorization") == "" {
+		t.Error("expected the request headers not to be modified")
+	}
+}
+
+func TestContextFieldMatches(t *testing.T) {
+	content := []byte(`{"payload": {"portOfEntry": "felixstowe"}}`)
+
+	headers := http.Header{}
+	headers.Add("X-Origin-System", "chief")
+	headers.Add("X-Origin-System", "cds")
+	hmrc := NewRoutingContext(uuid.New(), "service_account", "HMRC", headers, []string{"X-Origin-System"})
+	user := NewRoutingContext(uuid.New(), "user", "", nil, nil)
+
+	organization := condition{field: "@sender.client_organization", operator: "equals", matchPattern: "hmrc", isCaseInsensitive: true}
+	serviceAccount := condition{field: "@sender.account_type", operator: "equals", matchPattern: "service_account"}
+	header := condition{field: "@header.x-origin-system", operator: "equals", matchPattern: "cds"}
+
+	tests := []struct {
+		name           string
+		condition      condition
+		routingContext RoutingContext
+		want           bool
+	}{
+		{"client organization", organization, hmrc, true},
+		{"client organization not set for users", organization, user, false},
+		{"account type", serviceAccount, user, false},
+		{"any header value matches", header, hmrc, true},
+		{"missing header", header, user, false},
+		{"header exists", condition{field: "@header.X-Origin-System", operator: "exists"}, hmrc, true},
+		{"organization not_exists", condition{field: "@sender.client_organization", operator: "not_exists"}, user, true},
+		{"combined with content", condition{all: []condition{organization, {field: "payload.portOfEntry", operator: "equals", matchPattern: "felixstowe"}}}, hmrc, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.condition.matches(content, tt.routingContext); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("dry run field values", func(t *testing.T) {
+		results, err := DryRun(DryRunConfig{
+			RoutingField: "@header.X-Origin-System",
+			Rules:        []DryRunRule{{Sequence: 1, IsnSlug: "customs-isn", MatchPattern: "cds", Operator: "equals"}},
+		}, []json.RawMessage{content}, hmrc)
+		if err != nil {
+			t.Fatalf("DryRun() error = %v", err)
+		}
+		if got := string(results[0].FieldValues[0].Value); got != `["chief","cds"]` {
+			t.Errorf("field value = %s, want both header values", got)
+		}
+		if len(results[0].IsnSlugs) != 1 || results[0].IsnSlugs[0] != "customs-isn" {
+			t.Errorf("DryRun() = %+v, want the signal to be routed to customs-isn", results[0])
+		}
+	})
+}
+
+func TestHeaderNames(t *testing.T) {
+	fields := []string{"@header.x-origin-system", "port", "@sender.account_type", "@header.X-Origin-System", "@header.X-Priority"}
+
+	got := headerNames(fields)
+
+	want := []string{"X-Origin-System", "X-Priority"}
+	if !slices.Equal(got, want) {
+		t.Errorf("headerNames() = %v, want %v", got, want)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -193,6 +195,50 @@ func (c *Cache) FieldPathExistsInSchema(signalTypePath, fieldPath string) (bool,
 	return true, nil
 }
 
+// ContentFieldPaths returns the path to each field defined in the compiled schema for signalTypePath (used to flatten signal content into columns).
+// Each path is a list of property names, and the paths are returned in alphabetical order.
+//
+// Objects are expanded into their properties. Where the properties can't be seen (arrays, $ref, allOf etc - see FieldPathExistsInSchema)
+// the path to the field itself is returned.
+//
+// Returns (nil, nil) when the signal type uses skip-validation (no schema to read the fields from).
+// Returns (nil, error) when the signal type is not in the cache (unexpected err)
+func (c *Cache) ContentFieldPaths(signalTypePath string) ([][]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	schemaURL, ok := c.schemaURLs[signalTypePath]
+	if !ok {
+		return nil, fmt.Errorf("signal type %q not found in schema cache", signalTypePath)
+	}
+	if SkipValidation(schemaURL) {
+		return nil, nil
+	}
+
+	schema, ok := c.schemas[signalTypePath]
+	if !ok {
+		return nil, fmt.Errorf("schema missing from cache for signal type %q", signalTypePath)
+	}
+
+	var paths [][]string
+	collectFieldPaths(schema, nil, &paths)
+	return paths, nil
+}
+
+// collectFieldPaths adds the paths to the fields below schema to paths
+func collectFieldPaths(schema *jsonschema.Schema, prefix []string, paths *[][]string) {
+	if len(schema.Properties) == 0 {
+		if len(prefix) > 0 {
+			*paths = append(*paths, prefix)
+		}
+		return
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(schema.Properties)) {
+		collectFieldPaths(schema.Properties[name], append(slices.Clone(prefix), name), paths)
+	}
+}
+
 // ValidateAndCompileSchema validates schema content and returns the compiled schema
 func ValidateAndCompileSchema(schemaURL, content string) (*jsonschema.Schema, error) {
 	// Parse the schema content using UnmarshalJSON
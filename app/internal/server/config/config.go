@@ -1,9 +1,12 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
@@ -35,6 +38,65 @@ type ServerEnvironment struct {
 	DBMaxConnIdleTime    time.Duration `env:"DB_MAX_CONN_IDLE_TIME" envDefault:"30m"`
 	DBConnectTimeout     time.Duration `env:"DB_CONNECT_TIMEOUT"   envDefault:"5s"`
 	TrustedProxies       int           `env:"TRUSTED_PROXIES"      envDefault:"1"` // number of reverse proxies in front of the service (e.g. 1 for a single ALB)
+
+	// AccessTokenSigningAlg is the algorithm used to sign access tokens (HS256, RS256, ES256 or EdDSA).
+	// HS256 tokens are signed with SECRET_KEY - use one of the asymmetric algorithms if other services need to verify the tokens (the public keys are published at /.well-known/jwks.json)
+	AccessTokenSigningAlg      string        `env:"ACCESS_TOKEN_SIGNING_ALG"      envDefault:"HS256"`
+	SigningKeyRotationInterval time.Duration `env:"SIGNING_KEY_ROTATION_INTERVAL" envDefault:"720h"` // 30 days
+
+	// TLSClientCertHeader is the request header used by the TLS terminating proxy to forward the client certificate (URL encoded PEM) - e.g X-Amzn-Mtls-Clientcert for AWS ALBs.
+	// Mutual TLS client authentication is disabled when this is not set.
+	// The proxy must overwrite the header on every request (removing any value supplied by the client), otherwise clients can forge certificates.
+	TLSClientCertHeader string `env:"TLS_CLIENT_CERT_HEADER"`
+
+	// TLSClientCertTrustedProxies are the addresses of the TLS terminating proxies (comma separated CIDRs, e.g. the ALB subnets) that are allowed to set TLSClientCertHeader.
+	// Requests from any other source that include the header are rejected. Required when TLS_CLIENT_CERT_HEADER is set.
+	TLSClientCertTrustedProxies []netip.Prefix `env:"TLS_CLIENT_CERT_TRUSTED_PROXIES"`
+
+	// OIDCProviders are the OpenID Connect identity providers web users can log in with (a JSON array - see OIDCProvider).
+	// Federated login is disabled when this is not set.
+	OIDCProviders OIDCProviders `env:"OIDC_PROVIDERS"`
+}
+
+// OIDCProvider is an OpenID Connect identity provider configured in OIDC_PROVIDERS.
+//
+// The site must be registered with the provider as a confidential client (client_secret_basic authentication)
+// with the redirect URI {PUBLIC_BASE_URL}/api/auth/oidc/{id}/callback
+type OIDCProvider struct {
+	// ID identifies the provider in the login URLs (lowercase letters, numbers and hyphens)
+	ID string `json:"id"`
+
+	// Name is displayed on the login page (e.g. "Example Org SSO")
+	Name string `json:"name"`
+
+	// Issuer is the provider's issuer URL - the provider config is discovered from {issuer}/.well-known/openid-configuration
+	Issuer string `json:"issuer"`
+
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+
+	// Scopes are requested in addition to openid (defaults to email and profile)
+	Scopes []string `json:"scopes,omitempty"`
+
+	// AllowedEmailDomains restricts the email addresses that can be linked to existing users or provisioned (any domain is allowed when empty)
+	AllowedEmailDomains []string `json:"allowed_email_domains,omitempty"`
+
+	// AutoProvision creates a member account when a user logs in with an email address that is not registered on the site.
+	// When false, only existing users can log in with the provider.
+	AutoProvision bool `json:"auto_provision"`
+}
+
+// OIDCProviders is the list of providers configured in OIDC_PROVIDERS
+type OIDCProviders []OIDCProvider
+
+// UnmarshalText parses the OIDC_PROVIDERS JSON array
+func (p *OIDCProviders) UnmarshalText(text []byte) error {
+	var providers []OIDCProvider
+	if err := json.Unmarshal(text, &providers); err != nil {
+		return fmt.Errorf("OIDC_PROVIDERS is not a valid JSON array of providers: %v", err)
+	}
+	*p = providers
+	return nil
 }
 
 // CORSConfigs holds the CORS middleware instances for different endpoint types
@@ -55,16 +117,99 @@ const (
 	OneTimeSecretExpiry   = 48 * time.Hour       // Service account setup tokens
 	PasswordResetExpiry   = 30 * time.Minute     // Password reset tokens
 	ClientSecretExpiry    = 365 * 24 * time.Hour // Client secret expiration (1 year)
+	ClientAssertionMaxAge = 5 * time.Minute      // max lifetime of the private_key_jwt client assertions used to authenticate service accounts
+	ClientAssertionLeeway = 30 * time.Second     // allowed clock skew when checking client assertions
 	MinimumPasswordLength = 11
 
+	// OpenID Connect federated login
+	OIDCLoginRequestExpiry = 10 * time.Minute // users must complete the login at the identity provider within this time
+	OIDCLoginCookieName    = "oidc_login"     // identifies the login request when the identity provider redirects back to the site
+	OIDCLoginCodeExpiry    = 1 * time.Minute  // one-time codes issued to web clients at the end of the login must be redeemed within this time
+	OIDCRequestTimeout     = 10 * time.Second // timeout for requests to the identity providers
+	OIDCMetadataCacheTTL   = 1 * time.Hour    // provider config and signing keys are reloaded after this period (keys are also reloaded when an id_token uses an unknown key)
+	OIDCIDTokenLeeway      = 1 * time.Minute  // allowed clock skew when checking id_tokens
+
+	// Access token signing keys (used when ACCESS_TOKEN_SIGNING_ALG is RS256, ES256 or EdDSA)
+	SigningKeyPublishLeadTime       = 1 * time.Hour   // new keys are published this long before they are used to sign tokens
+	SigningKeyRotationCheckInterval = 1 * time.Minute // how often each instance checks if the keys need rotating (and reloads the key set)
+	SigningKeyRSABits               = 2048
+	JWKSCacheMaxAge                 = 15 * time.Minute // Cache-Control max-age for the JWKS endpoint (must be less than SigningKeyPublishLeadTime)
+
 	// Operational timeouts
 	ServerShutdownTimeout = 10 * time.Second // Server graceful shutdown timeout
 	DatabasePingTimeout   = 10 * time.Second
 	ReadinessTimeout      = 2 * time.Second // Health check timeout
 
 	// CachePollInterval is how often each instance checks the DB for cache changes
+	// (caches are also reloaded when a cache invalidation notification is received - polling is the fallback if notifications are missed)
 	CachePollInterval = 30 * time.Second
 
+	// CacheListenerRetryInterval is how long the cache invalidation listener waits before reconnecting after losing its database connection
+	CacheListenerRetryInterval = 5 * time.Second
+
+	// Webhook delivery settings
+	WebhookPollInterval      = 2 * time.Second  // how often the dispatcher checks for pending deliveries
+	WebhookDeliveryBatchSize = 100              // max deliveries claimed per poll
+	WebhookDeliveryWorkers   = 8                // max concurrent deliveries
+	WebhookRequestTimeout    = 10 * time.Second // timeout for each delivery attempt
+	WebhookMaxAttempts       = 10               // deliveries are marked as failed after this many attempts
+	WebhookRetryBaseDelay    = 10 * time.Second // delay before the first retry (doubled after each attempt)
+	WebhookRetryMaxDelay     = 1 * time.Hour
+	WebhookDeliveryLogLimit  = 100 // max delivery log entries returned by the API
+	WebhookSigningSecretSize = 32  // bytes
+
+	// Signal stream (server-sent events) settings
+	SignalStreamPollInterval      = 1 * time.Second  // how often each open stream checks for new signal versions
+	SignalStreamHeartbeatInterval = 15 * time.Second // comment lines are sent at this interval to stop proxies closing idle connections
+	SignalStreamBatchSize         = 500              // max signal versions read per poll
+	SignalStreamRetry             = 3 * time.Second  // reconnection delay sent to clients in the SSE retry field
+
+	// Signal search settings
+	SignalSearchMaxPageSize       = 1000 // max signals returned per page (also used when the limit param is not supplied)
+	SignalSearchMaxContentFilters = 20   // max content.<field_path> filters in a single search
+	SignalSearchExportFlushRows   = 100  // streamed (ndjson/csv) search exports are flushed to the client after this many rows
+	SignalSearchExportBatchSize   = 1000 // streamed search exports read the signals from the database in batches of this size
+
+	// SignalSearchNextPageTokenHeader is returned by the search endpoints when there are more results - supply the value in the page_token param to get the next page
+	SignalSearchNextPageTokenHeader = "Signalsd-Next-Page-Token"
+
+	// Signal changes feed settings
+	SignalChangesDefaultLimit = 100  // changes returned per request when the limit param is not supplied
+	SignalChangesMaxLimit     = 1000 // max value for the limit param
+
+	// Unroutable signals (signals submitted to the signal router that did not match any routing rule)
+	UnroutableSignalsDefaultLimit = 100  // unroutable signals listed per request when the limit param is not supplied
+	UnroutableSignalsMaxLimit     = 1000 // max value for the limit param
+
+	// Bulk signal withdrawal and reinstatement
+	SignalStatusChangeMaxSignals      = 10000 // max local refs or signal ids in a single request (signals selected by batch_ref are not limited)
+	SignalStatusChangeReasonMaxLength = 1000
+
+	// Signal submission idempotency keys
+	IdempotencyKeyRetention = 24 * time.Hour // repeated requests with the same key receive the original response until the key expires
+	IdempotencyKeyMaxLength = 255
+	IdempotencyKeyLease     = 2 * time.Minute // requests that have not completed after this time are assumed to have failed and the key can be taken over by a retry
+
+	// IdempotencyKeyHeader is supplied by clients to make signal submission requests safe to retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader is set to true when the response is the stored response to an earlier request with the same idempotency key
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// Async signal submission settings (submissions made with a Prefer: respond-async header are queued and processed by the submission workers)
+	SignalSubmissionPollInterval   = 2 * time.Second  // how often the workers check for queued submissions
+	SignalSubmissionBatchSize      = 10               // max submissions claimed per poll
+	SignalSubmissionWorkers        = 4                // max submissions processed concurrently by each instance
+	SignalSubmissionLease          = 10 * time.Minute // claimed submissions are not claimed again until the lease expires (i.e. the worker stopped before recording the outcome)
+	SignalSubmissionMaxAttempts    = 5                // submissions are marked as failed after this many attempts
+	SignalSubmissionRetryBaseDelay = 10 * time.Second // delay before the first retry (doubled after each attempt)
+	SignalSubmissionRetryMaxDelay  = 10 * time.Minute
+
+	// PreferHeader is used by clients to request async processing of signal submissions (Prefer: respond-async)
+	PreferHeader            = "Prefer"
+	PreferenceAppliedHeader = "Preference-Applied"
+	PreferRespondAsync      = "respond-async"
+
 	// CORS settings
 	CORSMaxAgeInSeconds = 86400 // 24 hours
 
@@ -105,6 +250,14 @@ var ValidAccountTypes = map[string]bool{
 	"service-account": true,
 }
 
+// ValidAccessTokenSigningAlgs lists the algorithms that can be used to sign access tokens (ACCESS_TOKEN_SIGNING_ALG)
+var ValidAccessTokenSigningAlgs = map[string]bool{
+	"HS256": true,
+	"RS256": true,
+	"ES256": true,
+	"EdDSA": true,
+}
+
 // ValidServiceModes is the list of modes that can be used when starting the signalsd service
 var ValidServiceModes = map[string]bool{ // service modes for CLI
 	// signalsd backend + embeded UI
@@ -121,12 +274,53 @@ var ValidServiceModes = map[string]bool{ // service modes for CLI
 	"signals-write": true,
 }
 
-// ValidRouteMatchingOperators list the limited set of operations supported for isn routes
+// ValidRouteMatchingOperators list the operations supported for isn routes (see router.Condition for a description of each operator)
 var ValidRouteMatchingOperators = map[string]bool{
 	"matches":        true,
 	"equals":         true,
 	"does_not_match": true,
 	"does_not_equal": true,
+	"starts_with":    true,
+	"ends_with":      true,
+	"regex":          true,
+	"in":             true,
+	"gt":             true,
+	"gte":            true,
+	"lt":             true,
+	"lte":            true,
+	"exists":         true,
+	"not_exists":     true,
+}
+
+// routing modes for signal routing configs:
+// first_match routes signals to the ISN for the first matching rule, all_matches routes signals to the ISNs for every matching rule
+const (
+	RoutingModeFirstMatch = "first_match"
+	RoutingModeAllMatches = "all_matches"
+)
+
+// ValidRoutingModes lists the supported routing modes
+var ValidRoutingModes = map[string]bool{
+	RoutingModeFirstMatch: true,
+	RoutingModeAllMatches: true,
+}
+
+// routing config revisions record how the routing config for a signal type was changed
+const (
+	RoutingConfigRevisionUpdated    = "updated"
+	RoutingConfigRevisionDeleted    = "deleted"
+	RoutingConfigRevisionRolledBack = "rolled_back"
+)
+
+// ValidContentFilterOperators lists the operators that can be used in signal search content filters (content.<field_path>__<operator>=value)
+var ValidContentFilterOperators = map[string]bool{
+	"eq":  true,
+	"ne":  true,
+	"gt":  true,
+	"gte": true,
+	"lt":  true,
+	"lte": true,
+	"in":  true,
 }
 
 // NewServerConfig loads environment variables and returns a ServerEnvironment struct and CORSConfigs
@@ -203,6 +397,13 @@ func validateConfig(cfg *ServerEnvironment) error {
 		return fmt.Errorf("DB_MIN_CONNECTIONS (%d) cannot be greater than DB_MAX_CONNECTIONS (%d)", cfg.DBMinConnections, cfg.DBMaxConnections)
 	}
 
+	if !ValidAccessTokenSigningAlgs[cfg.AccessTokenSigningAlg] {
+		return fmt.Errorf("invalid ACCESS_TOKEN_SIGNING_ALG: %s (must be HS256, RS256, ES256 or EdDSA)", cfg.AccessTokenSigningAlg)
+	}
+	if cfg.SigningKeyRotationInterval < 2*SigningKeyPublishLeadTime {
+		return fmt.Errorf("SIGNING_KEY_ROTATION_INTERVAL must be at least %v", 2*SigningKeyPublishLeadTime)
+	}
+
 	u, err := url.ParseRequestURI(cfg.PublicBaseURL)
 	if err != nil {
 		return fmt.Errorf("PUBLIC_BASE_URL is not a valid URL: %s", cfg.PublicBaseURL)
@@ -242,6 +443,14 @@ func validateConfig(cfg *ServerEnvironment) error {
 		}
 	}
 
+	if cfg.TLSClientCertHeader != "" && len(cfg.TLSClientCertTrustedProxies) == 0 {
+		return fmt.Errorf("TLS_CLIENT_CERT_TRUSTED_PROXIES must be set when TLS_CLIENT_CERT_HEADER is set")
+	}
+
+	if err := validateOIDCProviders(cfg); err != nil {
+		return err
+	}
+
 	// default to all origins when not in prod/staging
 	if len(cfg.AllowedOrigins) == 0 {
 		cfg.AllowedOrigins = []string{"*"}
@@ -249,6 +458,46 @@ func validateConfig(cfg *ServerEnvironment) error {
 	return nil
 }
 
+var oidcProviderIDRegexp = regexp.MustCompile(`^[a-z0-9-]{1,64}$`)
+
+// validateOIDCProviders checks the OIDC_PROVIDERS settings
+func validateOIDCProviders(cfg *ServerEnvironment) error {
+	ids := make(map[string]bool)
+
+	for i, provider := range cfg.OIDCProviders {
+		if !oidcProviderIDRegexp.MatchString(provider.ID) {
+			return fmt.Errorf("OIDC_PROVIDERS[%d]: id must contain only lowercase letters, numbers and hyphens: %q", i, provider.ID)
+		}
+		if ids[provider.ID] {
+			return fmt.Errorf("OIDC_PROVIDERS: duplicate provider id: %s", provider.ID)
+		}
+		ids[provider.ID] = true
+
+		if provider.Name == "" {
+			return fmt.Errorf("OIDC_PROVIDERS[%s]: name is required", provider.ID)
+		}
+		if provider.ClientID == "" || provider.ClientSecret == "" {
+			return fmt.Errorf("OIDC_PROVIDERS[%s]: client_id and client_secret are required", provider.ID)
+		}
+
+		u, err := url.ParseRequestURI(provider.Issuer)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+			return fmt.Errorf("OIDC_PROVIDERS[%s]: issuer is not a valid URL: %s", provider.ID, provider.Issuer)
+		}
+		if (cfg.Environment == "prod" || cfg.Environment == "staging") && u.Scheme != "https" {
+			return fmt.Errorf("OIDC_PROVIDERS[%s]: issuer must use https in %v: %s", provider.ID, cfg.Environment, provider.Issuer)
+		}
+
+		for j, domain := range provider.AllowedEmailDomains {
+			if domain == "" || strings.Contains(domain, "@") {
+				return fmt.Errorf("OIDC_PROVIDERS[%s]: invalid allowed_email_domains entry: %q", provider.ID, domain)
+			}
+			cfg.OIDCProviders[i].AllowedEmailDomains[j] = strings.ToLower(domain)
+		}
+	}
+	return nil
+}
+
 // CreateCORSConfigs creates the CORS configurations based on the server config
 func CreateCORSConfigs(cfg *ServerEnvironment) (*CORSConfigs, error) {
 	// Trim whitespace from all origins
@@ -268,6 +517,9 @@ func CreateCORSConfigs(cfg *ServerEnvironment) (*CORSConfigs, error) {
 			"Accept",
 			"Content-Type",
 		},
+		ResponseHeaders: []string{
+			SignalSearchNextPageTokenHeader,
+		},
 		MaxAgeInSeconds: CORSMaxAgeInSeconds,
 	}
 
@@ -288,6 +540,14 @@ func CreateCORSConfigs(cfg *ServerEnvironment) (*CORSConfigs, error) {
 		RequestHeaders: []string{
 			"Content-Type",
 			"Authorization",
+			IdempotencyKeyHeader,
+			PreferHeader,
+		},
+		ResponseHeaders: []string{
+			SignalSearchNextPageTokenHeader,
+			IdempotentReplayedHeader,
+			PreferenceAppliedHeader,
+			"Location",
 		},
 		MaxAgeInSeconds: CORSMaxAgeInSeconds,
 	}
@@ -227,6 +227,12 @@ func (a *AdminHandler) DisableAccount(w http.ResponseWriter, r *http.Request) er
 			return apperrors.DatabaseError("database error", err)
 		}
 
+		// Revoke the public keys used for private_key_jwt and mutual TLS authentication
+		_, err = txQueries.RevokeAllServiceAccountKeys(r.Context(), accountID)
+		if err != nil {
+			return apperrors.DatabaseError("database error", err)
+		}
+
 		// Delete any one-time client secrets
 		serviceAccount, err := txQueries.GetServiceAccountByAccountID(r.Context(), accountID)
 		if err != nil {
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/jackc/pgx/v5"
+)
+
+// idempotentRequest is a signal submission request made with an Idempotency-Key header.
+//
+// The key is recorded before the signals are processed and the response is stored against the key when the request completes.
+// When the request is repeated with the same key the stored response is returned and the signals are not processed again.
+type idempotentRequest struct {
+	queries   *database.Queries
+	accountID uuid.UUID
+	key       string
+	completed bool
+}
+
+// startIdempotentRequest records the Idempotency-Key supplied with a signal submission request.
+//
+// Returns a nil request when the header is not supplied.
+// When the key has already been used for this request, replayed is true and the stored response has been written to w
+// (the handler should return without writing anything else).
+//
+// Keys can't be reused with a different request, and requests can't be repeated while the original request is in progress.
+// The original request holds a lease on the key (see signalsd.IdempotencyKeyLease): if the request did not complete before the lease expired
+// (e.g. the server stopped while it was being processed) the key is taken over by the next retry.
+func startIdempotentRequest(w http.ResponseWriter, r *http.Request, queries *database.Queries, accountID uuid.UUID, body []byte) (req *idempotentRequest, replayed bool, err error) {
+	key := r.Header.Get(signalsd.IdempotencyKeyHeader)
+	if key == "" {
+		return nil, false, nil
+	}
+	if len(key) > signalsd.IdempotencyKeyMaxLength {
+		return nil, false, apperrors.InvalidRequest(fmt.Sprintf("%s must be no more than %d characters", signalsd.IdempotencyKeyHeader, signalsd.IdempotencyKeyMaxLength), nil)
+	}
+
+	logger.ContextWithLogAttrs(r.Context(),
+		slog.String("idempotency_key", key),
+	)
+
+	// the request hash covers the endpoint as well as the body, so a key can't be reused for a different signal type
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", r.Method, r.URL.Path)
+	hash.Write(body)
+	requestHash := hex.EncodeToString(hash.Sum(nil))
+
+	if _, err := queries.DeleteExpiredIdempotencyKeys(r.Context(), accountID); err != nil {
+		return nil, false, apperrors.DatabaseError("database error", err)
+	}
+
+	_, err = queries.CreateIdempotencyKey(r.Context(), database.CreateIdempotencyKeyParams{
+		AccountID:      accountID,
+		IdempotencyKey: key,
+		ExpiresAt:      time.Now().Add(signalsd.IdempotencyKeyRetention),
+		RequestHash:    requestHash,
+		LockedUntil:    time.Now().Add(signalsd.IdempotencyKeyLease),
+	})
+	if err == nil {
+		return &idempotentRequest{queries: queries, accountID: accountID, key: key}, false, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, apperrors.DatabaseError("database error", err)
+	}
+
+	// the key has been used before
+	existing, err := queries.GetIdempotencyKey(r.Context(), database.GetIdempotencyKeyParams{
+		AccountID:      accountID,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// the original request failed and released the key after this request tried to create it
+			return nil, false, apperrors.IdempotencyKeyInProgress(fmt.Sprintf("the original request with this %s did not complete - retry the request", signalsd.IdempotencyKeyHeader), nil)
+		}
+		return nil, false, apperrors.DatabaseError("database error", err)
+	}
+
+	if existing.RequestHash != requestHash {
+		return nil, false, apperrors.IdempotencyKeyReused(fmt.Sprintf("this %s has already been used with a different request", signalsd.IdempotencyKeyHeader), nil)
+	}
+	if existing.ResponseStatus == nil {
+		_, err := queries.TakeOverIdempotencyKey(r.Context(), database.TakeOverIdempotencyKeyParams{
+			LockedUntil:    time.Now().Add(signalsd.IdempotencyKeyLease),
+			AccountID:      accountID,
+			IdempotencyKey: key,
+			RequestHash:    requestHash,
+		})
+		if err == nil {
+			// the original request did not complete before its lease expired
+			return &idempotentRequest{queries: queries, accountID: accountID, key: key}, false, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, apperrors.DatabaseError("database error", err)
+		}
+		return nil, false, apperrors.IdempotencyKeyInProgress(fmt.Sprintf("the original request with this %s is still being processed", signalsd.IdempotencyKeyHeader), nil)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set(signalsd.IdempotentReplayedHeader, "true")
+	w.WriteHeader(int(*existing.ResponseStatus))
+	_, _ = w.Write(existing.ResponseBody)
+	return nil, true, nil
+}
+
+// complete stores the response to the original request so that it can be returned when the request is repeated.
+// If the response can't be stored the key is released so the request can be retried.
+func (req *idempotentRequest) complete(ctx context.Context, status int, response any) {
+	if req == nil {
+		return
+	}
+
+	// the signals have been processed, so the response is stored even if the client has disconnected
+	ctx = context.WithoutCancel(ctx)
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		logger.ContextRequestLogger(ctx).Error("could not marshal response for idempotency key", slog.String("error", err.Error()))
+		return
+	}
+
+	responseStatus := int32(status)
+	if _, err := req.queries.CompleteIdempotencyKey(ctx, database.CompleteIdempotencyKeyParams{
+		ResponseStatus: &responseStatus,
+		ResponseBody:   body,
+		AccountID:      req.accountID,
+		IdempotencyKey: req.key,
+	}); err != nil {
+		logger.ContextRequestLogger(ctx).Error("could not store response for idempotency key", slog.String("error", err.Error()))
+		return
+	}
+	req.completed = true
+}
+
+// release deletes the key if the request did not complete (e.g. the handler returned an error), so the request can be retried with the same key.
+func (req *idempotentRequest) release(ctx context.Context) {
+	if req == nil || req.completed {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	if _, err := req.queries.DeleteIdempotencyKey(ctx, database.DeleteIdempotencyKeyParams{
+		AccountID:      req.accountID,
+		IdempotencyKey: req.key,
+	}); err != nil {
+		logger.ContextRequestLogger(ctx).Error("could not release idempotency key", slog.String("error", err.Error()))
+	}
+}
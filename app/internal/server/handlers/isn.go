@@ -12,6 +12,7 @@ import (
 	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
 	"github.com/information-sharing-networks/signalsd/app/internal/auth"
 	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/invalidation"
 	"github.com/information-sharing-networks/signalsd/app/internal/logger"
 	"github.com/information-sharing-networks/signalsd/app/internal/responses"
 	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
@@ -309,6 +310,9 @@ func (i *IsnHandler) UpdateIsn(w http.ResponseWriter, r *http.Request) error {
 		return apperrors.DatabaseError("database error", err)
 	}
 
+	// the public ISN cache depends on the ISN visibility and in use status
+	invalidation.Notify(r.Context(), i.queries, invalidation.PublicIsnCache)
+
 	return responses.NoContent(w, http.StatusNoContent)
 }
 
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
 	"github.com/information-sharing-networks/signalsd/app/internal/auth"
 	"github.com/information-sharing-networks/signalsd/app/internal/database"
@@ -92,8 +93,15 @@ func (l *LoginHandler) Login(w http.ResponseWriter, r *http.Request) error {
 		return apperrors.AuthenticationFailure("account is disabled", nil)
 	}
 
+	// new login session (the access token sid claim and the session id stored with the refresh token)
+	sessionID, err := uuid.NewV7()
+	if err != nil {
+		return apperrors.TokenCreationFailure("error creating session id", err)
+	}
+
 	// new access token
 	ctx := auth.ContextWithAccountID(r.Context(), user.AccountID)
+	ctx = auth.ContextWithSessionID(ctx, sessionID)
 
 	accessTokenResponse, err := l.authService.CreateAccessToken(ctx)
 	if err != nil {
@@ -0,0 +1,600 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	"github.com/information-sharing-networks/signalsd/app/internal/oidc"
+	"github.com/information-sharing-networks/signalsd/app/internal/responses"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OIDCLoginHandler handles federated logins for web users using the OpenID Connect identity providers configured in OIDC_PROVIDERS
+type OIDCLoginHandler struct {
+	queries       *database.Queries
+	authService   *auth.AuthService
+	pool          *pgxpool.Pool
+	environment   string
+	publicBaseURL string
+	providers     []*oidc.Provider
+}
+
+func NewOIDCLoginHandler(queries *database.Queries, authService *auth.AuthService, pool *pgxpool.Pool, environment string, publicBaseURL string, providers signalsd.OIDCProviders) *OIDCLoginHandler {
+	h := &OIDCLoginHandler{
+		queries:       queries,
+		authService:   authService,
+		pool:          pool,
+		environment:   environment,
+		publicBaseURL: publicBaseURL,
+	}
+	for _, provider := range providers {
+		h.providers = append(h.providers, oidc.NewProvider(provider, h.callbackURL(provider.ID)))
+	}
+	return h
+}
+
+type IdentityProvider struct {
+	ID   string `json:"id" example:"example-org"`
+	Name string `json:"name" example:"Example Org SSO"`
+
+	// LoginURL starts the login (add a return_to param to redirect the user back to a page on the site when the login completes)
+	LoginURL string `json:"login_url" example:"https://api.example.com/api/auth/oidc/example-org/login"`
+}
+
+type OIDCTokenRequest struct {
+	// Code is the one-time code added to the return_to URL when the login completed
+	Code string `json:"code" example:"rLp3sK7vYx1Qm9Zt2Wb8Nc4Hd6Jf0Ga5Ue3Xo7Ri1Ty"`
+}
+
+// GetIdentityProviders godoc
+//
+//	@Summary		Get identity providers
+//	@Description	Returns the OpenID Connect identity providers that web users can log in with (the list is empty when federated login is not configured).
+//	@Tags			auth
+//
+//	@Success		200	{array}	handlers.IdentityProvider
+//
+//	@Router			/api/auth/oidc/providers [get]
+func (o *OIDCLoginHandler) GetIdentityProviders(w http.ResponseWriter, r *http.Request) error {
+	res := make([]IdentityProvider, 0, len(o.providers))
+	for _, provider := range o.providers {
+		res = append(res, IdentityProvider{
+			ID:       provider.ID(),
+			Name:     provider.Name(),
+			LoginURL: fmt.Sprintf("%s/api/auth/oidc/%s/login", o.publicBaseURL, provider.ID()),
+		})
+	}
+	return responses.JSON(w, http.StatusOK, res)
+}
+
+// Login godoc
+//
+//	@Summary		Login with an identity provider
+//	@Description	Starts a federated login: the user is redirected to the identity provider to sign in (authorization code flow with PKCE).
+//	@Description	When the user has signed in, the provider redirects them back to the /api/auth/oidc/{provider_id}/callback endpoint.
+//	@Description
+//	@Description	Use the optional `return_to` param to send the user back to a page on this site when the login completes (must be a path, e.g. /login/sso).
+//	@Description	The callback adds a one-time `code` param to the return_to URL that can be exchanged for tokens using the /api/auth/oidc/token endpoint,
+//	@Description	or an `error` param if the login failed.
+//	@Description	When return_to is not supplied the callback responds with the same response as the /api/auth/login endpoint.
+//	@Description
+//	@Description	This endpoint must be opened in the user's browser (it sets a cookie that identifies the login request).
+//	@Tags			auth
+//
+//	@Param			provider_id	path	string	true	"identity provider id"	example(example-org)
+//	@Param			return_to	query	string	false	"path to redirect to when the login completes"	example(/login/sso)
+//
+//	@Success		302
+//	@Failure		400	{object}	responses.ErrorResponse	"invalid_request"
+//	@Failure		404	{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure		500	{object}	responses.ErrorResponse	"database_error | internal_error"
+//
+//	@Router			/api/auth/oidc/{provider_id}/login [get]
+func (o *OIDCLoginHandler) Login(w http.ResponseWriter, r *http.Request) error {
+	provider, err := o.getProvider(r)
+	if err != nil {
+		return err
+	}
+
+	var returnTo *string
+	if value := r.URL.Query().Get("return_to"); value != "" {
+		if !validReturnTo(value) {
+			return apperrors.InvalidRequest("return_to must be a path on this site (e.g. /login/sso)", nil)
+		}
+		returnTo = &value
+	}
+
+	if _, err := o.queries.DeleteExpiredOIDCLoginRequests(r.Context()); err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	state, err := o.authService.GenerateSecureToken(32)
+	if err != nil {
+		return apperrors.InternalError("internal error", err)
+	}
+	nonce, err := o.authService.GenerateSecureToken(32)
+	if err != nil {
+		return apperrors.InternalError("internal error", err)
+	}
+	codeVerifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return apperrors.InternalError("internal error", err)
+	}
+
+	authCodeURL, err := provider.AuthCodeURL(r.Context(), state, nonce, codeVerifier)
+	if err != nil {
+		return apperrors.InternalError("could not contact the identity provider", err)
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return apperrors.InternalError("internal error", err)
+	}
+
+	err = o.queries.CreateOIDCLoginRequest(r.Context(), database.CreateOIDCLoginRequestParams{
+		ID:           id,
+		ProviderID:   provider.ID(),
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ReturnTo:     returnTo,
+		ExpiresAt:    time.Now().Add(signalsd.OIDCLoginRequestExpiry),
+	})
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	logger.ContextWithLogAttrs(r.Context(),
+		slog.String("provider_id", provider.ID()),
+	)
+
+	http.SetCookie(w, o.newLoginRequestCookie(id.String(), int(signalsd.OIDCLoginRequestExpiry.Seconds())))
+	http.Redirect(w, r, authCodeURL, http.StatusFound)
+	return nil
+}
+
+// Callback godoc
+//
+//	@Summary		Identity provider callback
+//	@Description	The identity provider redirects the user to this endpoint when they have signed in.
+//	@Description	The authorization code is exchanged for an id_token, which is used to find the user:
+//	@Description	- users that have logged in with the provider before are identified by their provider account (the id_token sub claim)
+//	@Description	- otherwise the verified email address from the id_token is used to link the provider account to the registered user with the same email address
+//	@Description	- if there is no user with the email address and the provider is configured with auto_provision, a new member account is created
+//	@Description
+//	@Description	Providers can be restricted to email addresses in particular domains (see the allowed_email_domains setting).
+//	@Description
+//	@Description	If the login was started with a return_to path, the user is redirected to return_to with a one-time `code` param (or an `error` param if the login failed).
+//	@Description	Otherwise the response is the same as the /api/auth/login response (the access token is in the body and the refresh token is set in a http-only cookie).
+//	@Tags			auth
+//
+//	@Param			provider_id	path	string	true	"identity provider id"	example(example-org)
+//	@Param			code		query	string	false	"authorization code"
+//	@Param			state		query	string	true	"state from the login request"
+//	@Param			error		query	string	false	"error returned by the identity provider"
+//
+//	@Success		200	{object}	auth.AccessTokenResponse
+//	@Success		303	"redirect to return_to"
+//	@Failure		401	{object}	responses.ErrorResponse	"authentication_error"
+//	@Failure		404	{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure		500	{object}	responses.ErrorResponse	"database_error | internal_error | token_creation_failed"
+//
+//	@Router			/api/auth/oidc/{provider_id}/callback [get]
+func (o *OIDCLoginHandler) Callback(w http.ResponseWriter, r *http.Request) error {
+	provider, err := o.getProvider(r)
+	if err != nil {
+		return err
+	}
+
+	loginRequest, err := o.consumeLoginRequest(w, r, provider)
+	if err != nil {
+		return err
+	}
+
+	logger.ContextWithLogAttrs(r.Context(),
+		slog.String("provider_id", provider.ID()),
+	)
+
+	accountID, err := o.authenticate(r, provider, loginRequest)
+	if err != nil {
+		if loginRequest.ReturnTo == nil {
+			return err
+		}
+
+		// the user is redirected back to the site so the error can be displayed - the details are logged
+		message := "login failed"
+		var httpErr *apperrors.HTTPError
+		if errors.As(err, &httpErr) {
+			logger.ContextWithLogAttrs(r.Context(),
+				slog.String("error_code", httpErr.Code.String()),
+				slog.String("error", httpErr.Error()),
+			)
+			if httpErr.Status < http.StatusInternalServerError {
+				message = httpErr.Message
+			}
+		}
+		http.Redirect(w, r, appendQueryParam(*loginRequest.ReturnTo, "error", message), http.StatusSeeOther)
+		return nil
+	}
+
+	logger.ContextWithLogAttrs(r.Context(),
+		slog.String("account_id", accountID.String()),
+	)
+
+	if loginRequest.ReturnTo == nil {
+		return o.issueTokens(w, r, accountID)
+	}
+
+	// browser clients are given a one-time code that is exchanged for the tokens (see the Token handler)
+	code, err := o.authService.GenerateSecureToken(32)
+	if err != nil {
+		return apperrors.InternalError("internal error", err)
+	}
+
+	if _, err := o.queries.DeleteExpiredOIDCLoginCodes(r.Context()); err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	err = o.queries.CreateOIDCLoginCode(r.Context(), database.CreateOIDCLoginCodeParams{
+		HashedCode:    o.authService.HashToken(code),
+		UserAccountID: accountID,
+		ExpiresAt:     time.Now().Add(signalsd.OIDCLoginCodeExpiry),
+	})
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	http.Redirect(w, r, appendQueryParam(*loginRequest.ReturnTo, "code", code), http.StatusSeeOther)
+	return nil
+}
+
+// Token godoc
+//
+//	@Summary		Complete a federated login
+//	@Description	Exchanges the one-time code added to the return_to URL at the end of a federated login for an access token.
+//	@Description	The code can only be used once and expires after 1 minute.
+//	@Description
+//	@Description	The response is the same as the /api/auth/login response (the access token is in the body and the refresh token is set in a http-only cookie).
+//	@Tags			auth
+//
+//	@Param			request	body		handlers.OIDCTokenRequest	true	"login code"
+//
+//	@Success		200		{object}	auth.AccessTokenResponse
+//	@Failure		400		{object}	responses.ErrorResponse	"malformed_body"
+//	@Failure		401		{object}	responses.ErrorResponse	"authentication_error"
+//	@Failure		500		{object}	responses.ErrorResponse	"database_error | token_creation_failed"
+//
+//	@Router			/api/auth/oidc/token [post]
+func (o *OIDCLoginHandler) Token(w http.ResponseWriter, r *http.Request) error {
+	var req OIDCTokenRequest
+
+	defer r.Body.Close()
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperrors.MalformedBody("invalid JSON body", err)
+	}
+	if req.Code == "" {
+		return apperrors.MalformedBody("code is required", nil)
+	}
+
+	loginCode, err := o.queries.ConsumeOIDCLoginCode(r.Context(), o.authService.HashToken(req.Code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apperrors.AuthenticationFailure("invalid login code", nil)
+		}
+		return apperrors.DatabaseError("database error", err)
+	}
+	if time.Now().After(loginCode.ExpiresAt) {
+		return apperrors.AuthenticationFailure("the login code has expired - please log in again", nil)
+	}
+
+	logger.ContextWithLogAttrs(r.Context(),
+		slog.String("account_id", loginCode.UserAccountID.String()),
+	)
+
+	// the account could have been disabled since the code was issued
+	account, err := o.queries.GetAccountByID(r.Context(), loginCode.UserAccountID)
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+	if !account.IsActive {
+		return apperrors.AuthenticationFailure("account is disabled", nil)
+	}
+
+	return o.issueTokens(w, r, loginCode.UserAccountID)
+}
+
+// consumeLoginRequest returns the login request identified by the login request cookie.
+// The request is deleted so that the callback can't be replayed.
+func (o *OIDCLoginHandler) consumeLoginRequest(w http.ResponseWriter, r *http.Request, provider *oidc.Provider) (database.OidcLoginRequest, error) {
+	cookie, err := r.Cookie(signalsd.OIDCLoginCookieName)
+	if err != nil {
+		return database.OidcLoginRequest{}, apperrors.AuthenticationFailure("login request not found - please start the login again", nil)
+	}
+
+	// clear the cookie
+	http.SetCookie(w, o.newLoginRequestCookie("", -1))
+
+	id, err := uuid.Parse(cookie.Value)
+	if err != nil {
+		return database.OidcLoginRequest{}, apperrors.AuthenticationFailure("login request not found - please start the login again", nil)
+	}
+
+	loginRequest, err := o.queries.ConsumeOIDCLoginRequest(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.OidcLoginRequest{}, apperrors.AuthenticationFailure("login request not found - please start the login again", nil)
+		}
+		return database.OidcLoginRequest{}, apperrors.DatabaseError("database error", err)
+	}
+
+	if loginRequest.ProviderID != provider.ID() {
+		return database.OidcLoginRequest{}, apperrors.AuthenticationFailure("the login request was for a different identity provider", nil)
+	}
+	if time.Now().After(loginRequest.ExpiresAt) {
+		return database.OidcLoginRequest{}, apperrors.AuthenticationFailure("the login request has expired - please start the login again", nil)
+	}
+
+	// the state param protects against login CSRF - it must match the value sent to the provider by this browser
+	state := r.URL.Query().Get("state")
+	if subtle.ConstantTimeCompare([]byte(state), []byte(loginRequest.State)) != 1 {
+		return database.OidcLoginRequest{}, apperrors.AuthenticationFailure("state does not match the login request", nil)
+	}
+
+	return loginRequest, nil
+}
+
+// authenticate redeems the authorization code, verifies the id_token and returns the account id of the user
+func (o *OIDCLoginHandler) authenticate(r *http.Request, provider *oidc.Provider, loginRequest database.OidcLoginRequest) (uuid.UUID, error) {
+	query := r.URL.Query()
+
+	if providerError := query.Get("error"); providerError != "" {
+		return uuid.Nil, apperrors.AuthenticationFailure(fmt.Sprintf("the identity provider returned an error: %s", providerError),
+			fmt.Errorf("%s: %s", providerError, query.Get("error_description")))
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		return uuid.Nil, apperrors.AuthenticationFailure("the identity provider did not return an authorization code", nil)
+	}
+
+	rawIDToken, err := provider.Exchange(r.Context(), code, loginRequest.CodeVerifier)
+	if err != nil {
+		return uuid.Nil, apperrors.AuthenticationFailure("could not complete the login with the identity provider", err)
+	}
+
+	claims, err := provider.VerifyIDToken(r.Context(), rawIDToken, loginRequest.Nonce)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidIDToken) {
+			return uuid.Nil, apperrors.AuthenticationFailure("the identity provider returned an invalid id_token", err)
+		}
+		return uuid.Nil, apperrors.InternalError("could not contact the identity provider", err)
+	}
+
+	accountID, err := o.findOrCreateUser(r, provider, claims)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	account, err := o.queries.GetAccountByID(r.Context(), accountID)
+	if err != nil {
+		return uuid.Nil, apperrors.DatabaseError("database error", err)
+	}
+	if !account.IsActive {
+		return uuid.Nil, apperrors.AuthenticationFailure("account is disabled", nil)
+	}
+
+	return accountID, nil
+}
+
+// findOrCreateUser returns the account id of the user linked to the provider account identified by the id_token.
+//
+// When the provider account is not linked to a user, it is linked to the user with the same (verified) email address,
+// or - if the provider is configured to auto provision users - a new member account is created.
+func (o *OIDCLoginHandler) findOrCreateUser(r *http.Request, provider *oidc.Provider, claims *oidc.IDTokenClaims) (uuid.UUID, error) {
+	email := strings.ToLower(claims.Email)
+
+	identity, err := o.queries.GetUserIdentity(r.Context(), database.GetUserIdentityParams{
+		ProviderID: provider.ID(),
+		Subject:    claims.Subject,
+	})
+	if err == nil {
+		if email == "" {
+			email = identity.Email
+		}
+		err = o.queries.UpdateUserIdentityLogin(r.Context(), database.UpdateUserIdentityLoginParams{
+			ID:    identity.ID,
+			Email: email,
+		})
+		if err != nil {
+			return uuid.Nil, apperrors.DatabaseError("database error", err)
+		}
+		return identity.UserAccountID, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, apperrors.DatabaseError("database error", err)
+	}
+
+	// first login with this provider account - the email address is used to find the user
+	if email == "" || !claims.EmailVerified {
+		return uuid.Nil, apperrors.AuthenticationFailure("the identity provider did not supply a verified email address", nil)
+	}
+	if !provider.EmailAllowed(email) {
+		return uuid.Nil, apperrors.AuthenticationFailure("your email address can't be used to log in with this identity provider", nil)
+	}
+
+	tx, err := o.pool.BeginTx(r.Context(), pgx.TxOptions{})
+	if err != nil {
+		return uuid.Nil, apperrors.DatabaseError("database error", err)
+	}
+
+	defer func() {
+		if err := tx.Rollback(r.Context()); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logger.ContextWithLogAttrs(r.Context(),
+				slog.String("rollback_error", err.Error()),
+			)
+		}
+	}()
+
+	txQueries := o.queries.WithTx(tx)
+
+	var accountID uuid.UUID
+
+	user, err := txQueries.GetUserByEmail(r.Context(), email)
+	switch {
+	case err == nil:
+		accountID = user.AccountID
+
+		logger.ContextWithLogAttrs(r.Context(),
+			slog.Bool("identity_linked", true),
+		)
+	case errors.Is(err, pgx.ErrNoRows):
+		if !provider.AutoProvision() {
+			return uuid.Nil, apperrors.AuthenticationFailure("there is no account registered for your email address", nil)
+		}
+
+		// provisioned users can only log in with the provider - the password is a random value that is not disclosed
+		// (the user can set a password using the password reset process)
+		password, err := o.authService.GenerateSecureToken(32)
+		if err != nil {
+			return uuid.Nil, apperrors.InternalError("internal error", err)
+		}
+		hashedPassword, err := o.authService.HashPassword(password)
+		if err != nil {
+			return uuid.Nil, apperrors.InternalError("internal error", err)
+		}
+
+		account, err := txQueries.CreateUserAccount(r.Context())
+		if err != nil {
+			return uuid.Nil, apperrors.DatabaseError("database error", err)
+		}
+
+		// provisioned users are always members (unlike users that register, the first provisioned user is not made the site admin)
+		_, err = txQueries.CreateUser(r.Context(), database.CreateUserParams{
+			AccountID:      account.ID,
+			HashedPassword: hashedPassword,
+			Email:          email,
+		})
+		if err != nil {
+			return uuid.Nil, apperrors.DatabaseError("database error", err)
+		}
+		accountID = account.ID
+
+		logger.ContextWithLogAttrs(r.Context(),
+			slog.Bool("user_provisioned", true),
+		)
+	default:
+		return uuid.Nil, apperrors.DatabaseError("database error", err)
+	}
+
+	identityID, err := uuid.NewV7()
+	if err != nil {
+		return uuid.Nil, apperrors.InternalError("internal error", err)
+	}
+
+	_, err = txQueries.CreateUserIdentity(r.Context(), database.CreateUserIdentityParams{
+		ID:            identityID,
+		UserAccountID: accountID,
+		ProviderID:    provider.ID(),
+		Subject:       claims.Subject,
+		Email:         email,
+	})
+	if err != nil {
+		return uuid.Nil, apperrors.DatabaseError("database error", err)
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		return uuid.Nil, apperrors.DatabaseError("database error", err)
+	}
+
+	return accountID, nil
+}
+
+// issueTokens responds with a new access token and sets the refresh token cookie (see LoginHandler.Login)
+func (o *OIDCLoginHandler) issueTokens(w http.ResponseWriter, r *http.Request, accountID uuid.UUID) error {
+	sessionID, err := uuid.NewV7()
+	if err != nil {
+		return apperrors.TokenCreationFailure("error creating session id", err)
+	}
+
+	ctx := auth.ContextWithAccountID(r.Context(), accountID)
+	ctx = auth.ContextWithSessionID(ctx, sessionID)
+
+	accessTokenResponse, err := o.authService.CreateAccessToken(ctx)
+	if err != nil {
+		return apperrors.TokenCreationFailure("error creating access token", err)
+	}
+
+	refreshToken, err := o.authService.RotateRefreshToken(ctx)
+	if err != nil {
+		return apperrors.TokenCreationFailure("error creating refresh token", err)
+	}
+
+	http.SetCookie(w, o.authService.NewRefreshTokenCookie(refreshToken))
+
+	return responses.JSON(w, http.StatusOK, accessTokenResponse)
+}
+
+// getProvider returns the provider identified by the provider_id URL param
+func (o *OIDCLoginHandler) getProvider(r *http.Request) (*oidc.Provider, error) {
+	providerID := r.PathValue("provider_id")
+	for _, provider := range o.providers {
+		if provider.ID() == providerID {
+			return provider, nil
+		}
+	}
+	return nil, apperrors.NotFound("identity provider not found", nil)
+}
+
+func (o *OIDCLoginHandler) callbackURL(providerID string) string {
+	return fmt.Sprintf("%s/api/auth/oidc/%s/callback", o.publicBaseURL, providerID)
+}
+
+// newLoginRequestCookie returns the cookie used to identify the login request when the identity provider redirects the user back to the site.
+// SameSite must be Lax so the cookie is included in the redirect from the provider.
+func (o *OIDCLoginHandler) newLoginRequestCookie(value string, maxAge int) *http.Cookie {
+	isProdOrStaging := o.environment == "prod" || o.environment == "staging" //secure flag only true on prod and staging
+
+	// #nosec G124 - Secure flag is conditionally true on prod/staging
+	return &http.Cookie{
+		Name:     signalsd.OIDCLoginCookieName,
+		Value:    value,
+		Path:     "/api/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   isProdOrStaging,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// validReturnTo reports whether the return_to value is a path on this site (absolute URLs and protocol relative URLs such as //example.com are not allowed so the login can't be used to redirect users to other sites)
+func validReturnTo(returnTo string) bool {
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.ContainsAny(returnTo, "\\#\r\n") {
+		return false
+	}
+	u, err := url.Parse(returnTo)
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
+
+// appendQueryParam adds the param to the (validated) return_to path
+func appendQueryParam(path string, key string, value string) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	"github.com/information-sharing-networks/signalsd/app/internal/responses"
+	"github.com/jackc/pgx/v5"
+)
+
+type RegisterServiceAccountKeyRequest struct {
+	// PublicKey is a PEM encoded public key (PUBLIC KEY) or certificate (CERTIFICATE). RSA (2048 bits or more), EC P-256 and Ed25519 keys are supported.
+	PublicKey string `json:"public_key" example:"-----BEGIN PUBLIC KEY-----\nMCowBQYDK2VwAyEAGb9ECWmEzf6FQbrBZ9w7lshQhqowtrbLDFw4rXAxZuE=\n-----END PUBLIC KEY-----\n"`
+
+	// RevokeClientSecrets revokes the service account's client secrets so the account can only authenticate with its registered keys
+	RevokeClientSecrets bool `json:"revoke_client_secrets" example:"false"`
+}
+
+type ServiceAccountKey struct {
+	// KeyID is used as the kid header in client assertions signed with the key
+	KeyID     uuid.UUID `json:"key_id" example:"0198c5a1-7a40-7d1e-9a0e-9a3b8b1b2f11"`
+	ClientID  string    `json:"client_id" example:"sa_example-org_k7j2m9x1"`
+	Algorithm string    `json:"algorithm" enums:"RS256,ES256,EdDSA" example:"EdDSA"`
+	PublicKey string    `json:"public_key" example:"-----BEGIN PUBLIC KEY-----\nMCowBQYDK2VwAyEAGb9ECWmEzf6FQbrBZ9w7lshQhqowtrbLDFw4rXAxZuE=\n-----END PUBLIC KEY-----\n"`
+	CreatedAt time.Time `json:"created_at" example:"2025-06-03T13:47:47.331787+01:00"`
+}
+
+// RegisterServiceAccountKey godocs
+//
+//	@Summary		Register Service Account Key
+//	@Description	Registers a public key for a service account.
+//	@Description	Once registered, the service account can authenticate without a client secret, using either:
+//	@Description	- `private_key_jwt` (RFC 7523): a JWT client assertion signed with the private key (see the /oauth/token endpoint)
+//	@Description	- `self_signed_tls_client_auth` (RFC 8705): a TLS client certificate for the key. Access tokens issued this way are bound to the certificate.
+//	@Description	Only available when the TLS terminating proxy forwards client certificates (see the TLS_CLIENT_CERT_HEADER setting).
+//	@Description
+//	@Description	The key algorithm is determined by the key type: RSA keys (at least 2048 bits) use RS256, EC P-256 keys use ES256 and Ed25519 keys use EdDSA.
+//	@Description
+//	@Description	Set `revoke_client_secrets` to revoke the account's existing client secrets so that it can only authenticate with its keys.
+//	@Description
+//	@Description	You have to be an site or ISN admin to use this endpoint
+//	@Tags		Account Management
+//
+//	@Param		client_id	path		string										true	"service account client id"	example(sa_example-org_k7j2m9x1)
+//	@Param		request		body		handlers.RegisterServiceAccountKeyRequest	true	"public key"
+//
+//	@Success	201			{object}	handlers.ServiceAccountKey
+//	@Failure	400			{object}	responses.ErrorResponse	"malformed_body"
+//	@Failure	401			{object}	responses.ErrorResponse	"authentication_error"
+//	@Failure	404			{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure	409			{object}	responses.ErrorResponse	"resource_already_exists"
+//	@Failure	500			{object}	responses.ErrorResponse	"database_error | internal_error"
+//
+//	@Security	BearerAccessToken
+//
+//	@Router		/api/auth/service-accounts/{client_id}/keys [post]
+func (s *ServiceAccountHandler) RegisterServiceAccountKey(w http.ResponseWriter, r *http.Request) error {
+	var req RegisterServiceAccountKeyRequest
+
+	defer r.Body.Close()
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperrors.MalformedBody("invalid JSON body", err)
+	}
+
+	if req.PublicKey == "" {
+		return apperrors.MalformedBody("public_key is required", nil)
+	}
+
+	publicKey, algorithm, err := auth.ParseClientPublicKey(req.PublicKey)
+	if err != nil {
+		return apperrors.MalformedBody(err.Error(), nil)
+	}
+
+	serviceAccount, err := s.getServiceAccount(r)
+	if err != nil {
+		return err
+	}
+
+	existingKeys, err := s.queries.GetServiceAccountKeys(r.Context(), serviceAccount.AccountID)
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+	for _, key := range existingKeys {
+		if bytes.Equal(key.PublicKey, publicKey) {
+			return apperrors.AlreadyExists("this key is already registered for the service account", nil)
+		}
+	}
+
+	tx, err := s.pool.BeginTx(r.Context(), pgx.TxOptions{})
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	defer func() {
+		if err := tx.Rollback(r.Context()); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logger.ContextWithLogAttrs(r.Context(),
+				slog.String("rollback_error", err.Error()),
+			)
+		}
+	}()
+
+	txQueries := s.queries.WithTx(tx)
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return apperrors.InternalError("internal error", err)
+	}
+
+	key, err := txQueries.CreateServiceAccountKey(r.Context(), database.CreateServiceAccountKeyParams{
+		ID:                      id,
+		ServiceAccountAccountID: serviceAccount.AccountID,
+		Algorithm:               algorithm,
+		PublicKey:               publicKey,
+	})
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	if req.RevokeClientSecrets {
+		if _, err := txQueries.RevokeAllClientSecretsForAccount(r.Context(), serviceAccount.AccountID); err != nil {
+			return apperrors.DatabaseError("database error", err)
+		}
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	logger.ContextWithLogAttrs(r.Context(),
+		slog.String("client_id", serviceAccount.ClientID),
+		slog.String("key_id", key.ID.String()),
+		slog.Bool("revoke_client_secrets", req.RevokeClientSecrets),
+	)
+
+	return responses.JSON(w, http.StatusCreated, toServiceAccountKey(serviceAccount, key))
+}
+
+// GetServiceAccountKeys godocs
+//
+//	@Summary		Get Service Account Keys
+//	@Description	Returns the public keys the service account can use to authenticate (revoked keys are not included).
+//	@Description
+//	@Description	You have to be an site or ISN admin to use this endpoint
+//	@Tags		Account Management
+//
+//	@Param		client_id	path	string	true	"service account client id"	example(sa_example-org_k7j2m9x1)
+//
+//	@Success	200			{array}		handlers.ServiceAccountKey
+//	@Failure	401			{object}	responses.ErrorResponse	"authentication_error"
+//	@Failure	404			{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure	500			{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security	BearerAccessToken
+//
+//	@Router		/api/auth/service-accounts/{client_id}/keys [get]
+func (s *ServiceAccountHandler) GetServiceAccountKeys(w http.ResponseWriter, r *http.Request) error {
+	serviceAccount, err := s.getServiceAccount(r)
+	if err != nil {
+		return err
+	}
+
+	keys, err := s.queries.GetServiceAccountKeys(r.Context(), serviceAccount.AccountID)
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	res := make([]ServiceAccountKey, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, toServiceAccountKey(serviceAccount, key))
+	}
+
+	return responses.JSON(w, http.StatusOK, res)
+}
+
+// RevokeServiceAccountKey godocs
+//
+//	@Summary		Revoke Service Account Key
+//	@Description	Revokes a service account key. Client assertions signed with the key and TLS client certificates for the key are no longer accepted.
+//	@Description
+//	@Description	Access tokens that were already issued are not revoked (they expire after 30 minutes).
+//	@Description
+//	@Description	You have to be an site or ISN admin to use this endpoint
+//	@Tags		Account Management
+//
+//	@Param		client_id	path	string	true	"service account client id"	example(sa_example-org_k7j2m9x1)
+//	@Param		key_id		path	string	true	"key id"						example(0198c5a1-7a40-7d1e-9a0e-9a3b8b1b2f11)
+//
+//	@Success	204
+//	@Failure	400	{object}	responses.ErrorResponse	"invalid_url_param"
+//	@Failure	401	{object}	responses.ErrorResponse	"authentication_error"
+//	@Failure	404	{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure	500	{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security	BearerAccessToken
+//
+//	@Router		/api/auth/service-accounts/{client_id}/keys/{key_id} [delete]
+func (s *ServiceAccountHandler) RevokeServiceAccountKey(w http.ResponseWriter, r *http.Request) error {
+	keyID, err := uuid.Parse(r.PathValue("key_id"))
+	if err != nil {
+		return apperrors.InvalidURLParam("invalid key_id", err)
+	}
+
+	serviceAccount, err := s.getServiceAccount(r)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := s.queries.RevokeServiceAccountKey(r.Context(), database.RevokeServiceAccountKeyParams{
+		ID:                      keyID,
+		ServiceAccountAccountID: serviceAccount.AccountID,
+	})
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+	if rowsAffected == 0 {
+		return apperrors.NotFound("key not found", nil)
+	}
+
+	logger.ContextWithLogAttrs(r.Context(),
+		slog.String("client_id", serviceAccount.ClientID),
+		slog.String("key_id", keyID.String()),
+	)
+
+	return responses.NoContent(w, http.StatusNoContent)
+}
+
+// getServiceAccount returns the service account identified by the client_id URL param
+func (s *ServiceAccountHandler) getServiceAccount(r *http.Request) (database.ServiceAccount, error) {
+	serviceAccount, err := s.queries.GetServiceAccountByClientID(r.Context(), r.PathValue("client_id"))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.ServiceAccount{}, apperrors.NotFound("service account not found", nil)
+		}
+		return database.ServiceAccount{}, apperrors.DatabaseError("database error", err)
+	}
+	return serviceAccount, nil
+}
+
+func toServiceAccountKey(serviceAccount database.ServiceAccount, key database.ServiceAccountKey) ServiceAccountKey {
+	return ServiceAccountKey{
+		KeyID:     key.ID,
+		ClientID:  serviceAccount.ClientID,
+		Algorithm: key.Algorithm,
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: key.PublicKey})),
+		CreatedAt: key.CreatedAt,
+	}
+}
@@ -43,14 +43,30 @@ type BatchStatus struct {
 	UnresolvedFailures []FailureRow `json:"unresolved_failures,omitempty"`
 }
 
+// QueuedSubmissionStatus reports the progress of a signal submission made asynchronously (Prefer: respond-async) in the batch.
+// isn_slug is omitted for submissions made using the signal router.
+type QueuedSubmissionStatus struct {
+	SubmissionID   uuid.UUID  `json:"submission_id"`
+	IsnSlug        string     `json:"isn_slug,omitempty" example:"sample-isn--example-org"`
+	SignalTypePath string     `json:"signal_type_path" example:"sample-signal--example-org/v0.0.1"`
+	SignalCount    int32      `json:"signal_count" example:"100"`
+	Status         string     `json:"status" enums:"queued,completed,failed"`
+	SubmittedAt    time.Time  `json:"submitted_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	AttemptCount   int32      `json:"attempt_count"`
+	ResponseStatus *int32     `json:"response_status,omitempty" example:"200"`
+	LastError      *string    `json:"last_error,omitempty"`
+}
+
 // BatchStatusResponse is the full status for a batch across all ISNs and signal types
 type BatchStatusResponse struct {
-	BatchID          uuid.UUID     `json:"batch_id"`
-	BatchRef         string        `json:"batch_ref"`
-	AccountID        uuid.UUID     `json:"account_id"`
-	CreatedAt        time.Time     `json:"created_at"`
-	ContainsFailures bool          `json:"contains_failures"`
-	BatchStatus      []BatchStatus `json:"batch_status"`
+	BatchID           uuid.UUID                `json:"batch_id"`
+	BatchRef          string                   `json:"batch_ref"`
+	AccountID         uuid.UUID                `json:"account_id"`
+	CreatedAt         time.Time                `json:"created_at"`
+	ContainsFailures  bool                     `json:"contains_failures"`
+	BatchStatus       []BatchStatus            `json:"batch_status"`
+	QueuedSubmissions []QueuedSubmissionStatus `json:"queued_submissions,omitempty"`
 }
 
 // BatchSearchParams holds validated query parameters for batch search
@@ -68,6 +84,12 @@ type BatchSearchParams struct {
 //	@Description	The response shows stored and failed signal counts broken down by ISN and signal type.
 //	@Description	Where a signal is listed as 'rejected' this means the signal failed to load in this batch and has not been successfully resubmitted subsequently.
 //	@Description
+//	@Description	Signals submitted asynchronously (`Prefer: respond-async`) are included in the counts once they have been processed.
+//	@Description	The progress of each async submission is listed in `queued_submissions`:
+//	@Description	- `queued` - the submission is waiting to be processed (or is being retried after an error)
+//	@Description	- `completed` - the signals have been processed. `response_status` is the status that would have been returned by a synchronous request (200, 207 or 422)
+//	@Description	- `failed` - the submission could not be processed (`last_error` contains the reason) and the signals must be resubmitted
+//	@Description
 //	@Description	Members can view their own batches. Site admins can supply ?account_id= to view another account's batch.
 //	@Description
 //
@@ -186,13 +208,43 @@ func (s *SignalsBatchHandler) getBatchStatusDetails(ctx context.Context, batchID
 		batchStatus = append(batchStatus, status)
 	}
 
+	queuedRows, err := s.queries.GetQueuedSignalSubmissionsByBatchID(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queued submissions: %v", err)
+	}
+
+	containsFailures := len(failedRows) > 0
+
+	queuedSubmissions := make([]QueuedSubmissionStatus, 0, len(queuedRows))
+	for _, row := range queuedRows {
+		submission := QueuedSubmissionStatus{
+			SubmissionID:   row.ID,
+			SignalTypePath: fmt.Sprintf("%s/v%s", row.SignalTypeSlug, row.SemVer),
+			SignalCount:    row.SignalCount,
+			Status:         row.Status,
+			SubmittedAt:    row.CreatedAt,
+			CompletedAt:    row.CompletedAt,
+			AttemptCount:   row.AttemptCount,
+			ResponseStatus: row.ResponseStatus,
+			LastError:      row.LastError,
+		}
+		if row.IsnSlug != nil {
+			submission.IsnSlug = *row.IsnSlug
+		}
+		if row.Status == "failed" {
+			containsFailures = true
+		}
+		queuedSubmissions = append(queuedSubmissions, submission)
+	}
+
 	return &BatchStatusResponse{
-		BatchID:          batchID,
-		BatchRef:         signalBatch.BatchRef,
-		AccountID:        signalBatch.AccountID,
-		CreatedAt:        signalBatch.CreatedAt,
-		ContainsFailures: len(failedRows) > 0,
-		BatchStatus:      batchStatus,
+		BatchID:           batchID,
+		BatchRef:          signalBatch.BatchRef,
+		AccountID:         signalBatch.AccountID,
+		CreatedAt:         signalBatch.CreatedAt,
+		ContainsFailures:  containsFailures,
+		BatchStatus:       batchStatus,
+		QueuedSubmissions: queuedSubmissions,
 	}, nil
 }
 
@@ -0,0 +1,179 @@
+package handlers
+
+// this handler is used when submitting signals of more than one signal type to an ISN in a single request.
+// Each signal in the request names its own signal type (signal_type_path) and the results are grouped by signal type.
+//
+// the response and request structs are shared with signals.go (which handles standard signal submission)
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/responses"
+)
+
+// CreateBulkSignalsRequest contains the http request body used when submitting signals of different types to an ISN
+type CreateBulkSignalsRequest struct {
+
+	// BatchRef groups signals under a sender-chosen label
+	BatchRef string `json:"batch_ref" example:"daily-sync-2026-04-02"`
+
+	// Signals - the list of signals to be loaded
+	Signals []BulkSignal `json:"signals"`
+}
+
+// BulkSignal is a signal submitted using the bulk endpoint
+type BulkSignal struct {
+
+	// SignalTypePath identifies the signal type ({signal_type_slug}/v{sem_ver})
+	SignalTypePath string `json:"signal_type_path" example:"signal-type-1/v0.0.1"`
+
+	Signal
+}
+
+// CreateBulkSignals godocs
+//
+//	@Summary		Submit Signals (multiple signal types)
+//	@Tags			Signal Exchange
+//
+//	@Description	Submit signals of more than one signal type to an ISN in a single request.
+//	@Description
+//	@Description	Each signal in the `signals` array includes the `signal_type_path` ({signal_type_slug}/v{sem_ver}) of its signal type.
+//	@Description	Otherwise the signals are processed in the same way as the standard _Submit Signals_ endpoint (local refs, versions, correlation ids, schema validation and idempotency keys work the same way).
+//	@Description
+//	@Description	**Results**
+//	@Description
+//	@Description	The response contains one `results` entry for each signal type in the request.
+//	@Description	All the signals in the request are tracked under the supplied batch_ref.
+//	@Description
+//	@Description	**Error handling**
+//	@Description
+//	@Description	Signals for signal types that are not available on the ISN, or that can't be used by the account, are rejected individually
+//	@Description	(the other signals in the request are still processed) and the response will contain a 207 or 422 status code.
+//	@Description
+//	@Description	Requests with a missing or malformed `signal_type_path` are rejected with a 400 error.
+//
+//	@Param		isn_slug			path		string								true	"isn slug"	example(sample-isn--example-org)
+//	@Param		request				body		handlers.CreateBulkSignalsRequest	true	"create signals"
+//	@Param		Idempotency-Key		header		string								false	"unique key for the request - repeated requests with the same key return the original response"
+//
+//	@Success	200					{object}	handlers.SignalSubmissionResponse	"All signals processed successfully"
+//	@Success	207					{object}	handlers.SignalSubmissionResponse	"Partial success - some signals succeeded, some failed"
+//	@Success	422					{object}	handlers.SignalSubmissionResponse	"Valid request format but all signals failed processing - returns detailed error information"
+//	@Header		200,207,422			{string}	Idempotent-Replayed					"true when the response is the original response to an earlier request with the same Idempotency-Key"
+//	@Failure	400					{object}	responses.ErrorResponse				"malformed_body"
+//	@Failure	401					{object}	responses.ErrorResponse				"authentication_error"
+//	@Failure	403					{object}	responses.ErrorResponse				"forbidden"
+//	@Failure	404					{object}	responses.ErrorResponse				"resource_not_found"
+//	@Failure	409					{object}	responses.ErrorResponse				"idempotency_key_in_progress"
+//	@Failure	500					{object}	responses.ErrorResponse				"database_error | internal_error"
+//
+//	@Security	BearerAccessToken
+//
+//	@Router		/api/isn/{isn_slug}/signals [post]
+//
+// this function should be called after the RequireAccessPermission middleware has checked the account has write permission for the ISN.
+// The signal types are not in the URL, so the handler checks each signal type against the claims using auth.CheckIsnWritePermission.
+func (s *SignalsHandler) CreateBulkSignals(w http.ResponseWriter, r *http.Request) error {
+	isnSlug := r.PathValue("isn_slug")
+
+	claims, ok := auth.ContextClaims(r.Context())
+	if !ok {
+		return apperrors.InternalError("could not get claims from context", nil)
+	}
+
+	accountID, ok := auth.ContextAccountID(r.Context())
+	if !ok {
+		return apperrors.InternalError("could not get accountID from context", nil)
+	}
+
+	defer r.Body.Close()
+
+	// the body is read before it is unmarshalled so that it can be compared with earlier requests using the same idempotency key
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return apperrors.MalformedBody("could not read request body", err)
+	}
+
+	var req CreateBulkSignalsRequest
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return apperrors.MalformedBody("invalid JSON body", nil)
+	}
+
+	// check for mandatory fields in request (the same fields as a standard submission request, plus the signal type path of each signal)
+	standardReq := CreateSignalsRequest{BatchRef: req.BatchRef}
+	if req.Signals != nil {
+		standardReq.Signals = make([]Signal, 0, len(req.Signals))
+		for _, signal := range req.Signals {
+			standardReq.Signals = append(standardReq.Signals, signal.Signal)
+		}
+	}
+	if err := validateCreateSignalsRequest(standardReq); err != nil {
+		return err
+	}
+
+	for i, signal := range req.Signals {
+		if signal.SignalTypePath == "" {
+			return apperrors.MalformedBody(fmt.Sprintf("signal[%d] (local_ref=%q) is missing required field 'signal_type_path'", i, signal.LocalRef), nil)
+		}
+		if _, _, ok := splitSignalTypePath(signal.SignalTypePath); !ok {
+			return apperrors.MalformedBody(fmt.Sprintf("signal[%d] (local_ref=%q) has an invalid signal_type_path %q - expected {signal_type_slug}/v{sem_ver}", i, signal.LocalRef, signal.SignalTypePath), nil)
+		}
+	}
+
+	// requests with an Idempotency-Key are only processed once - repeated requests receive the original response
+	idempotentReq, replayed, err := startIdempotentRequest(w, r, s.queries, accountID, body)
+	if err != nil {
+		return err
+	}
+	if replayed {
+		return nil
+	}
+	defer idempotentReq.release(r.Context())
+
+	submission, err := s.store.startSubmission(r.Context(), accountID, req.BatchRef, len(req.Signals))
+	if err != nil {
+		return err
+	}
+
+	// results are grouped by signal type (in the order the signal types first appear in the request)
+	for _, signal := range req.Signals {
+		signalTypeSlug, semVer, _ := splitSignalTypePath(signal.SignalTypePath)
+
+		// the middleware has checked the ISN write permission - this checks the signal type is available on the ISN
+		if err := auth.CheckIsnWritePermission(claims, isnSlug, signal.SignalTypePath); err != nil {
+			submission.reject(r.Context(), isnSlug, signalTypeSlug, semVer, FailedSignal{
+				LocalRef:     signal.LocalRef,
+				ErrorCode:    string(apperrors.ErrCodeForbidden),
+				ErrorMessage: err.Error(),
+			})
+			continue
+		}
+
+		submission.store(r.Context(), isnSlug, signalTypeSlug, semVer, signal.Signal)
+	}
+
+	httpStatus, response := submission.complete(r.Context())
+
+	idempotentReq.complete(r.Context(), httpStatus, response)
+
+	return responses.JSON(w, httpStatus, response)
+}
+
+// signalTypePathRegexp - signal type paths are {signal_type_slug}/v{sem_ver}
+var signalTypePathRegexp = regexp.MustCompile(`^([a-z0-9-]+)/v([0-9]+\.[0-9]+\.[0-9]+)$`)
+
+// splitSignalTypePath returns the signal type slug and sem_ver from a signal type path
+func splitSignalTypePath(signalTypePath string) (signalTypeSlug, semVer string, ok bool) {
+	matches := signalTypePathRegexp.FindStringSubmatch(signalTypePath)
+	if matches == nil {
+		return "", "", false
+	}
+	return matches[1], matches[2], true
+}
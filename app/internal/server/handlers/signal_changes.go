@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	"github.com/information-sharing-networks/signalsd/app/internal/responses"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+)
+
+// SignalChange is an entry in the signal changes feed
+type SignalChange struct {
+
+	// ChangeType is version_created when a signal version is stored (new signals and updates to existing signals),
+	// withdrawn when a signal is withdrawn or reinstated when a withdrawn signal is reinstated
+	ChangeType string `json:"change_type" enums:"version_created,withdrawn,reinstated" example:"version_created"`
+
+	// ChangedAt is the time the change was made
+	ChangedAt time.Time `json:"changed_at"`
+
+	// AccountID is the account that owns the signal
+	AccountID uuid.UUID `json:"account_id" example:"a38c99ed-c75c-4a4a-a901-c9485cf93cf3"`
+
+	// SignalID is the signal that was changed
+	SignalID uuid.UUID `json:"signal_id" example:"4cedf4fa-2a01-4cbf-8668-6b44f8ac6e19"`
+
+	// LocalRef is the sender's reference for the signal
+	LocalRef string `json:"local_ref" example:"item_id_#1"`
+
+	// Signal contains the new signal version (only supplied for version_created changes)
+	// Note that is_withdrawn is the current status of the signal - later changes in the feed may have changed it.
+	Signal *SearchSignal `json:"signal,omitempty"`
+}
+
+// SignalChangesResponse is the response body for the signal changes feed
+type SignalChangesResponse struct {
+
+	// Changes is the list of changes in the order they were made
+	Changes []SignalChange `json:"changes"`
+
+	// NextCursor should be supplied in the cursor param of the next request to get the changes made after this page
+	NextCursor string `json:"next_cursor" example:"MTAzNy0wMTlhMmZjMi02ZGI1LTc4ZDEtYTJlNi1jNjE4OGYxYjM0NTE"`
+
+	// HasMore is true when there are further changes available - request the next page immediately rather than waiting to poll again
+	HasMore bool `json:"has_more" example:"false"`
+}
+
+// encodeChangesCursor returns the opaque cursor for a position in the changes feed
+func encodeChangesCursor(position streamPosition) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(position.String()))
+}
+
+// decodeChangesCursor parses a cursor created by encodeChangesCursor
+func decodeChangesCursor(cursor string) (streamPosition, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return streamPosition{}, fmt.Errorf("cursor %q is not valid", cursor)
+	}
+
+	position, err := parseStreamPosition(string(decoded))
+	if err != nil {
+		return streamPosition{}, fmt.Errorf("cursor %q is not valid", cursor)
+	}
+	return position, nil
+}
+
+// GetSignalChanges godoc
+//
+//	@Summary		Signal changes feed
+//	@Tags			Signal Exchange
+//
+//	@Description	Returns the changes to the signals in an ISN (new signal versions, withdrawals and reinstatements) in the order they were made.
+//	@Description	This endpoint is intended for systems that keep a local copy of the signals in an ISN up to date.
+//	@Description
+//	@Description	The first request should be made without a cursor - this returns the changes from the start of the feed.
+//	@Description	Each response includes a `next_cursor` - supply this in the `cursor` param of the next request to get the changes made since the previous response.
+//	@Description	When `has_more` is true there are further changes available and the next page can be requested immediately.
+//	@Description
+//	@Description	The cursor is opaque and does not expire. Changes are never skipped or repeated when following the cursor, even when several signals are stored at the same time.
+//	@Description
+//	@Description	Change types:
+//	@Description	- `version_created` - a signal version was stored (the first version of a new signal, or an update to an existing signal). The version is supplied in the `signal` field.
+//	@Description	- `withdrawn` - the signal was withdrawn.
+//	@Description	- `reinstated` - the withdrawn signal was reinstated (either explicitly or because it was resubmitted).
+//	@Description
+//	@Description	Withdrawn signals are reactivated when they are resupplied - resubmitting a withdrawn signal produces a `reinstated` change followed by a `version_created` change for the new version.
+//	@Description
+//	@Description	Any ISN member can use the feed. Accounts that only have write permission receive only the changes to the signals they created.
+//
+//	@Param			isn_slug			path		string	true	"ISN slug"												example(sample-isn--example-org)
+//	@Param			signal_type_slug	path		string	true	"Signal type slug"										example(sample-signal--example-org)
+//	@Param			sem_ver				path		string	true	"Signal type version"									example(0.0.1)
+//	@Param			cursor				query		string	false	"next_cursor from the previous response (omit to start from the beginning)"
+//	@Param			limit				query		int		false	"max number of changes to return (default 100, max 1000)"	example(100)
+//
+//	@Success		200					{object}	handlers.SignalChangesResponse
+//	@Failure		400					{object}	responses.ErrorResponse	"invalid_url_param"
+//	@Failure		401					{object}	responses.ErrorResponse	"authentication_error"
+//	@Failure		403					{object}	responses.ErrorResponse	"forbidden"
+//	@Failure		500					{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/signals/changes [get]
+//
+// This function should be called after the RequireIsnMembership middleware has checked the account is a member of the ISN
+// (the middleware also checks the isn and signal type are in use)
+func (s *SignalsHandler) GetSignalChanges(w http.ResponseWriter, r *http.Request) error {
+	isnSlug := r.PathValue("isn_slug")
+	signalTypeSlug := r.PathValue("signal_type_slug")
+	semVer := r.PathValue("sem_ver")
+
+	claims, ok := auth.ContextClaims(r.Context())
+	if !ok {
+		return apperrors.AuthenticationFailure("authentication required for private ISN access", nil)
+	}
+
+	accountID, ok := auth.ContextAccountID(r.Context())
+	if !ok {
+		return apperrors.InternalError("could not get accountID from context", nil)
+	}
+
+	limit := signalsd.SignalChangesDefaultLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > signalsd.SignalChangesMaxLimit {
+			return apperrors.InvalidURLParam(fmt.Sprintf("limit must be a number between 1 and %d", signalsd.SignalChangesMaxLimit), err)
+		}
+	}
+
+	// the feed starts from the beginning when no cursor is supplied
+	position := streamPosition{txID: 0, id: uuid.Nil}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		var err error
+		position, err = decodeChangesCursor(cursor)
+		if err != nil {
+			return apperrors.InvalidURLParam("invalid cursor", err)
+		}
+	}
+
+	// request an extra row to find out if there are more changes after this page
+	rows, err := s.queries.GetSignalChanges(r.Context(), database.GetSignalChangesParams{
+		IsnSlug:        isnSlug,
+		SignalTypeSlug: signalTypeSlug,
+		SemVer:         semVer,
+		AfterTxID:      position.txID,
+		AfterChangeID:  position.id,
+		RowLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		logger.ContextWithLogAttrs(r.Context(),
+			slog.String("isn_slug", isnSlug),
+		)
+
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	// Write-only accounts can only see signals they created
+	isnPerms := claims.IsnPerms[isnSlug]
+	writeOnly := !isnPerms.CanRead && isnPerms.CanWrite
+
+	changes := make([]SignalChange, 0, len(rows))
+	for _, row := range rows {
+		position = streamPosition{txID: row.TxID, id: row.ChangeID}
+
+		if writeOnly && row.AccountID != accountID {
+			continue
+		}
+
+		change := SignalChange{
+			ChangeType: row.ChangeType,
+			ChangedAt:  row.ChangedAt,
+			AccountID:  row.AccountID,
+			SignalID:   row.SignalID,
+			LocalRef:   row.LocalRef,
+		}
+
+		if row.SignalVersionID != nil {
+			change.Signal = &SearchSignal{
+				AccountID:            row.AccountID,
+				AccountType:          row.AccountType,
+				Email:                row.Email,
+				SignalID:             row.SignalID,
+				LocalRef:             row.LocalRef,
+				SignalCreatedAt:      row.SignalCreatedAt,
+				SignalVersionID:      *row.SignalVersionID,
+				VersionNumber:        *row.VersionNumber,
+				VersionCreatedAt:     *row.VersionCreatedAt,
+				CorrelatedToSignalID: row.CorrelatedToSignalID,
+				IsWithdrawn:          row.IsWithdrawn,
+				Content:              row.Content,
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	return responses.JSON(w, http.StatusOK, SignalChangesResponse{
+		Changes:    changes,
+		NextCursor: encodeChangesCursor(position),
+		HasMore:    hasMore,
+	})
+}
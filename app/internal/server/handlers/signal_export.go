@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/jackc/pgx/v5"
+)
+
+// search export formats (requested using the Accept header)
+const (
+	exportFormatNDJSON = "application/x-ndjson"
+	exportFormatCSV    = "text/csv"
+)
+
+// SearchExportFormat returns the streamed export format requested in the Accept header of a signal search request
+// (application/x-ndjson or text/csv), or an empty string when the standard json response is required.
+//
+// The first supported media type in the header is used.
+func SearchExportFormat(r *http.Request) string {
+	for mediaRange := range strings.SplitSeq(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case exportFormatNDJSON, exportFormatCSV:
+			return mediaType
+		case "application/json":
+			return ""
+		}
+	}
+	return ""
+}
+
+// signalExportColumns are the csv columns used for the signal fields (the content fields follow these columns)
+var signalExportColumns = []string{
+	"account_id",
+	"account_type",
+	"email",
+	"signal_id",
+	"local_ref",
+	"signal_created_at",
+	"signal_version_id",
+	"version_number",
+	"version_created_at",
+	"correlated_to_signal_id",
+	"is_withdrawn",
+}
+
+// exportSignals streams the search results to the client as ndjson or csv.
+//
+// Rows are written as each batch is read from the database rather than being collected into a response, so exports are not limited by the page size
+// and are not subject to the server write timeout.
+//
+// includeEmail should be false for public ISNs.
+func (s *SignalsHandler) exportSignals(w http.ResponseWriter, r *http.Request, params SearchParams, format string, includeEmail bool) error {
+	query := r.URL.Query()
+	if query.Has("limit") || query.Has("page_token") {
+		return apperrors.InvalidURLParam("limit and page_token can't be used with ndjson or csv exports - all the matching signals are returned", nil)
+	}
+	if params.includeCorrelated || params.includePreviousSignalVersions || params.includeHistory {
+		return apperrors.InvalidURLParam("include_correlated, include_previous_versions and include_history can't be used with ndjson or csv exports", nil)
+	}
+
+	var csvWriter *csv.Writer
+	var contentPaths [][]string
+	if format == exportFormatCSV {
+		signalTypePath := fmt.Sprintf("%s/v%s", params.signalTypeSlug, params.semVer)
+
+		var err error
+		contentPaths, err = s.schemaCache.ContentFieldPaths(signalTypePath)
+		if err != nil {
+			return apperrors.InternalError("schema cache error", err)
+		}
+		csvWriter = csv.NewWriter(w)
+	}
+
+	queryParams, err := params.getSignalsParams()
+	if err != nil {
+		return apperrors.InternalError("could not create search query", err)
+	}
+	queryParams.RowLimit = signalsd.SignalSearchExportBatchSize
+
+	// the signals are read in batches (using the same keyset pagination as the search pages) so the whole export is not held in memory.
+	// The batches are read in a read only repeatable read transaction so the export is a consistent snapshot of the signals
+	tx, err := s.pool.BeginTx(r.Context(), pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+	defer func() {
+		if err := tx.Rollback(r.Context()); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logger.ContextWithLogAttrs(r.Context(),
+				slog.String("rollback_error", err.Error()),
+			)
+		}
+	}()
+
+	txQueries := s.queries.WithTx(tx)
+
+	batch, err := txQueries.GetSignalsWithOptionalFilters(r.Context(), queryParams)
+	if err != nil {
+		logger.ContextWithLogAttrs(r.Context(),
+			slog.String("isn_slug", params.isnSlug),
+		)
+
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	// exports can take longer than the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		return apperrors.InternalError("streaming is not supported", err)
+	}
+
+	w.Header().Set("Content-Type", format)
+	w.Header().Set("X-Accel-Buffering", "no") // disable response buffering in nginx
+	if format == exportFormatCSV {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_v%s.csv"`, params.signalTypeSlug, params.semVer))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	if csvWriter != nil {
+		if err := csvWriter.Write(csvExportHeader(contentPaths)); err != nil {
+			abortExport(r, err)
+		}
+	}
+
+	ndjsonEncoder := json.NewEncoder(w)
+	rowCount := 0
+
+	for {
+		for _, row := range batch {
+			if !includeEmail {
+				row.Email = ""
+			}
+
+			if csvWriter != nil {
+				err = csvWriter.Write(csvExportRecord(row, contentPaths))
+			} else {
+				err = ndjsonEncoder.Encode(SearchSignal{
+					AccountID:            row.AccountID,
+					AccountType:          row.AccountType,
+					Email:                row.Email,
+					SignalID:             row.SignalID,
+					LocalRef:             row.LocalRef,
+					SignalCreatedAt:      row.SignalCreatedAt,
+					SignalVersionID:      row.SignalVersionID,
+					VersionNumber:        row.VersionNumber,
+					VersionCreatedAt:     row.VersionCreatedAt,
+					CorrelatedToSignalID: row.CorrelatedToSignalID,
+					IsWithdrawn:          row.IsWithdrawn,
+					Content:              row.Content,
+				})
+			}
+			if err != nil {
+				abortExport(r, err)
+			}
+
+			rowCount++
+			if rowCount%signalsd.SignalSearchExportFlushRows == 0 {
+				if csvWriter != nil {
+					csvWriter.Flush()
+				}
+				if err := rc.Flush(); err != nil {
+					abortExport(r, err)
+				}
+			}
+		}
+
+		if len(batch) < int(queryParams.RowLimit) {
+			break
+		}
+
+		// the next batch starts after the last signal in this one
+		last := batch[len(batch)-1]
+		queryParams.PageCreatedAt = &last.VersionCreatedAt
+		queryParams.PageSignalVersionID = &last.SignalVersionID
+
+		batch, err = txQueries.GetSignalsWithOptionalFilters(r.Context(), queryParams)
+		if err != nil {
+			abortExport(r, err)
+		}
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			abortExport(r, err)
+		}
+	}
+
+	logger.ContextWithLogAttrs(r.Context(),
+		slog.String("export_format", format),
+		slog.Int("export_rows", rowCount),
+	)
+	return nil
+}
+
+// abortExport is used when an export fails after the response has started.
+// The connection is closed without completing the response so the client does not mistake a partial export for a complete one.
+func abortExport(r *http.Request, err error) {
+	if r.Context().Err() == nil {
+		logger.ContextRequestLogger(r.Context()).Error("signal export failed", slog.String("error", err.Error()))
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// csvExportHeader returns the csv header row.
+// The content columns are named content.<field_path>, or there is a single content column containing the json content when the
+// signal type has no schema fields.
+func csvExportHeader(contentPaths [][]string) []string {
+	header := make([]string, 0, len(signalExportColumns)+len(contentPaths)+1)
+	header = append(header, signalExportColumns...)
+
+	if len(contentPaths) == 0 {
+		return append(header, "content")
+	}
+	for _, path := range contentPaths {
+		header = append(header, "content."+strings.Join(path, "."))
+	}
+	return header
+}
+
+// csvExportRecord returns the csv record for a signal - the columns match csvExportHeader
+func csvExportRecord(row database.GetSignalsWithOptionalFiltersRow, contentPaths [][]string) []string {
+	record := make([]string, 0, len(signalExportColumns)+len(contentPaths)+1)
+	record = append(record,
+		row.AccountID.String(),
+		row.AccountType,
+		row.Email,
+		row.SignalID.String(),
+		row.LocalRef,
+		row.SignalCreatedAt.Format(time.RFC3339Nano),
+		row.SignalVersionID.String(),
+		strconv.Itoa(int(row.VersionNumber)),
+		row.VersionCreatedAt.Format(time.RFC3339Nano),
+		row.CorrelatedToSignalID.String(),
+		strconv.FormatBool(row.IsWithdrawn),
+	)
+
+	if len(contentPaths) == 0 {
+		return append(record, string(row.Content))
+	}
+
+	var content any
+	decoder := json.NewDecoder(bytes.NewReader(row.Content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&content); err != nil {
+		content = nil // the content was validated when the signal was stored, so this is not expected
+	}
+
+	for _, path := range contentPaths {
+		record = append(record, csvContentValue(content, path))
+	}
+	return record
+}
+
+// csvContentValue returns the value of the field at path as a csv value.
+// Strings, numbers and booleans are returned as they are; objects and arrays are returned as json; missing and null fields are empty.
+func csvContentValue(content any, path []string) string {
+	value := content
+	for _, seg := range path {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return ""
+		}
+		value = object[seg]
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(encoded)
+	}
+}
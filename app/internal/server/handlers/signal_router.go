@@ -7,17 +7,17 @@ package handlers
 // the response and request structs are shared with signals.go (which handles standard signal submission)
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log/slog"
+	"io"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
 	"github.com/information-sharing-networks/signalsd/app/internal/auth"
 	"github.com/information-sharing-networks/signalsd/app/internal/database"
-	"github.com/information-sharing-networks/signalsd/app/internal/logger"
 	"github.com/information-sharing-networks/signalsd/app/internal/responses"
 	"github.com/information-sharing-networks/signalsd/app/internal/router"
 	"github.com/information-sharing-networks/signalsd/app/internal/schemas"
@@ -34,19 +34,27 @@ type SignalRouter struct {
 	// pool postgress connection
 	pool *pgxpool.Pool
 
+	// authService is used to load the current permissions of the account that submitted an unroutable signal when it is re-routed
+	authService *auth.AuthService
+
 	// schemaCache contains the pre-compiled json schemas used to check the signal data is correct
 	schemaCache *schemas.Cache
 
 	// signalRouterCache contains the rule configs for each signal type path that has been set up for routing
 	signalRouterCache *router.Cache
+
+	// store is used to store the signals once the target ISNs are resolved
+	store signalStore
 }
 
-func NewSignalRouter(queries *database.Queries, pool *pgxpool.Pool, schemaCache *schemas.Cache, signalRouterCache *router.Cache) *SignalRouter {
+func NewSignalRouter(queries *database.Queries, pool *pgxpool.Pool, authService *auth.AuthService, schemaCache *schemas.Cache, signalRouterCache *router.Cache) *SignalRouter {
 	return &SignalRouter{
 		queries:           queries,
 		pool:              pool,
+		authService:       authService,
 		schemaCache:       schemaCache,
 		signalRouterCache: signalRouterCache,
+		store:             signalStore{queries: queries, pool: pool, schemaCache: schemaCache},
 	}
 }
 
@@ -57,6 +65,34 @@ type resolvedSignal struct {
 	isnID   uuid.UUID
 }
 
+// getRoutingContext returns the sender details and request headers used by routing rules (the client organization is looked up for service accounts).
+// Only the headers in headerNames (the headers referenced by the routing rules) are kept.
+// headers is nil when the original request headers are not available.
+func getRoutingContext(ctx context.Context, queries *database.Queries, claims *auth.Claims, headers http.Header, headerNames []string) (router.RoutingContext, error) {
+	clientOrganization := ""
+	if claims.AccountType == "service_account" {
+		serviceAccount, err := queries.GetServiceAccountByAccountID(ctx, claims.AccountID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return router.RoutingContext{}, apperrors.DatabaseError("database error", err)
+		}
+		clientOrganization = serviceAccount.ClientOrganization
+	}
+	return router.NewRoutingContext(claims.AccountID, claims.AccountType, clientOrganization, headers, headerNames), nil
+}
+
+// storedRoutingContext returns the routing context stored with a queued submission or unroutable signal.
+// Where no routing context was stored (signals submitted before routing contexts were kept) the context is created from the claims, without any request headers.
+func storedRoutingContext(ctx context.Context, queries *database.Queries, claims *auth.Claims, stored []byte) (router.RoutingContext, error) {
+	if stored == nil {
+		return getRoutingContext(ctx, queries, claims, nil, nil)
+	}
+	var routingContext router.RoutingContext
+	if err := json.Unmarshal(stored, &routingContext); err != nil {
+		return router.RoutingContext{}, apperrors.InternalError("could not unmarshal routing context", err)
+	}
+	return routingContext, nil
+}
+
 // RouteSignals godoc
 //
 //	@Summary		Submit Signals via Router
@@ -77,12 +113,30 @@ type resolvedSignal struct {
 //	@Description	defined for the _Signal Type_.
 //	@Description	The rules are applied in the order defined in the _Routing Rules Config_ (first match is accepted).
 //	@Description
+//	@Description	**ISN resolution by sender**
+//	@Description
+//	@Description	Routing rules can also use the account that submitted the signals and the request headers (e.g. route signals sent by HMRC service accounts to a customs ISN):
+//	@Description	- `@sender.account_id`, `@sender.account_type` (user or service_account) and `@sender.client_organization` (service accounts only)
+//	@Description	- `@header.{Header-Name}` - the value of a request header (the Authorization and Cookie headers can't be used)
+//	@Description	Only the request headers used by the routing rules are kept with unroutable signals (these are used when the signal is re-routed).
+//	@Description
+//	@Description	**Fan-out routing**
+//	@Description
+//	@Description	When the _Routing Rules Config_ uses the `all_matches` routing mode, signals are stored in every ISN with a matching rule
+//	@Description	and the response contains a result entry for each ISN. The write permission check is applied separately for each ISN
+//	@Description	(signals can be stored in some of the ISNs and rejected for others).
+//	@Description	Note that in this mode the stored and rejected counts in the summary count each ISN the signal was routed to.
+//	@Description
 //	@Description	**Resolution Faiulres**
 //	@Description
 //	@Description	Signals subject to pattern matches are rejected when:
 //	@Description	- they do not contain the routing field defined in the _Routing Rules Config_
 //	@Description	- they do not satisfy any of the routing rules
 //	@Description
+//	@Description	unless the _Routing Rules Config_ has a fallback ISN, in which case the signals are routed to the fallback ISN.
+//	@Description	Rejected signals are reported in `unroutable_signals` and are stored so that a site admin can re-route them
+//	@Description	once the routing rules have been fixed (the `unroutable_signal_id` identifies the stored signal).
+//	@Description
 //	@Description	Signals that resolve to an ISN where the account lacks write permission are rejected.
 //	@Description
 //	@Description	**Usage**
@@ -129,6 +183,8 @@ type resolvedSignal struct {
 //	@Description
 //	@Description	New versions are created when signals are resupplied using the same local_ref, e.g. because the client wants to correct a previously publsihed signal.
 //	@Description	If a signal has been withdrawn it will be reactivated if you resubmit it using the same local_ref.
+//	@Description	If the content of a resupplied signal is the same as the latest version (ignoring differences in json key order and whitespace) no new version is created:
+//	@Description	the existing version is returned in the response with `unchanged: true` (withdrawn signals are always reactivated with a new version).
 //	@Description
 //	@Description	**Correlating signals**
 //	@Description
@@ -136,17 +192,38 @@ type resolvedSignal struct {
 //	@Description	Signals can only be correlated within the same ISN.
 //	@Description	If the supplied correlation_id is not found in the same ISN as the signal being submitted,
 //	@Description	the response will contain a 422 or 207 status code and the error_code for the failed signal will be `invalid_correlation_id`.
+//	@Description
+//	@Description	**Retrying requests**
+//	@Description
+//	@Description	Resubmitting a request processes every signal in it again (e.g. signals that have since been withdrawn are reactivated). To make retries safe (e.g. after a network timeout) supply a unique `Idempotency-Key` header with each request.
+//	@Description	When a request is repeated with the same key (within 24 hours) the original response is returned with an `Idempotent-Replayed: true` header and the signals are not processed again.
+//	@Description	Keys are unique per account. Using a key with a different request returns a 422 (`idempotency_key_reused`), and repeating a request while the original is still being processed returns a 409 (`idempotency_key_in_progress`).
+//	@Description
+//	@Description	**Async submissions**
+//	@Description
+//	@Description	Large payloads can be submitted without waiting for the signals to be stored by including a `Prefer: respond-async` header.
+//	@Description	The request format is checked (invalid requests are rejected with a 400 as normal) and the request is queued for processing in the background.
+//	@Description	The response is a 202 with a `Preference-Applied: respond-async` header and a `Location` header containing the URL of the *Get Batch Status* endpoint for the batch_ref.
+//	@Description	The outcome of the submission - the status code that would have been returned by a synchronous request and any failed signals - is reported by the batch status endpoint once the signals have been processed.
+//	@Description	Queued submissions are retried if they can't be processed (e.g. because the database is unavailable) and are reported as `failed` if the retries are exhausted.
 //
 //	@Param			signal_type_slug	path		string								true	"signal type slug"	example(sample-signal-type)
 //	@Param			sem_ver				path		string								true	"version"			example(1.0.0)
 //	@Param			request				body		handlers.CreateSignalsRequest		true	"signals to submit"
+//	@Param			Idempotency-Key		header		string								false	"unique key for the request - repeated requests with the same key return the original response"
+//	@Param			Prefer				header		string								false	"respond-async - queue the signals for processing and return a 202 without waiting for them to be stored"	Enums(respond-async)
 //
 //	@Success		200					{object}	handlers.SignalSubmissionResponse	"All signals processed successfully"
+//	@Success		202					{object}	handlers.QueuedSignalSubmissionResponse	"Request queued for async processing (Prefer: respond-async)"
 //	@Success		207					{object}	handlers.SignalSubmissionResponse	"Partial success - some signals succeeded, some failed"
 //	@Success		422					{object}	handlers.SignalSubmissionResponse	"Valid request format but all signals failed processing - returns detailed error information"
+//	@Header			200,202,207,422		{string}	Idempotent-Replayed					"true when the response is the original response to an earlier request with the same Idempotency-Key"
+//	@Header			202					{string}	Preference-Applied					"respond-async"
+//	@Header			202					{string}	Location							"batch status URL for the batch_ref"
 //	@Failure		400					{object}	responses.ErrorResponse				"malformed_body"
 //	@Failure		401					{object}	responses.ErrorResponse				"authentication_error"
 //	@Failure		404					{object}	responses.ErrorResponse				"resource_not_found"
+//	@Failure		409					{object}	responses.ErrorResponse				"idempotency_key_in_progress"
 //	@Failure		500					{object}	responses.ErrorResponse				"database_error"
 //
 //	@Security		BearerAccessToken
@@ -159,7 +236,6 @@ type resolvedSignal struct {
 func (s *SignalRouter) RouteSignals(w http.ResponseWriter, r *http.Request) error {
 	signalTypeSlug := r.PathValue("signal_type_slug")
 	semVer := r.PathValue("sem_ver")
-	signalTypePath := fmt.Sprintf("%s/v%s", signalTypeSlug, semVer)
 
 	claims, ok := auth.ContextClaims(r.Context())
 	if !ok {
@@ -173,257 +249,158 @@ func (s *SignalRouter) RouteSignals(w http.ResponseWriter, r *http.Request) erro
 
 	defer r.Body.Close()
 
+	// the body is read before it is unmarshalled so that it can be compared with earlier requests using the same idempotency key
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return apperrors.MalformedBody("could not read request body", err)
+	}
+
 	var req CreateSignalsRequest
 
 	// unmarshal the req body
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
 		return apperrors.MalformedBody("invalid JSON body", nil)
 	}
 
 	// check for mandatory fields in request
-	if req.BatchRef == "" {
-		return apperrors.MalformedBody("batch_ref is required", nil)
-	}
-	if !batchRefRegexp.MatchString(req.BatchRef) {
-		return apperrors.MalformedBody("batch_ref must be less than 128 characters and can only contain alphanumeric characters, hyphens, and underscores", nil)
+	if err := validateCreateSignalsRequest(req); err != nil {
+		return err
 	}
 
-	if req.Signals == nil {
-		return apperrors.MalformedBody("request must contain a 'signals' array", nil)
+	// requests with an Idempotency-Key are only processed once - repeated requests receive the original response
+	idempotentReq, replayed, err := startIdempotentRequest(w, r, s.queries, accountID, body)
+	if err != nil {
+		return err
 	}
-	if len(req.Signals) == 0 {
-		return apperrors.MalformedBody("request must contain must contain at least one signal in the 'signals' array", nil)
+	if replayed {
+		return nil
 	}
+	defer idempotentReq.release(r.Context())
 
-	for i, signal := range req.Signals {
-		if signal.LocalRef == "" {
-			return apperrors.MalformedBody(fmt.Sprintf("signal[%d] is missing required field 'local_ref'", i), nil)
-		}
-		if len(signal.Content) == 0 {
-			return apperrors.MalformedBody(fmt.Sprintf("signal[%d] (local_ref=%q) is missing required field 'content'", i, signal.LocalRef), nil)
+	// only the headers used by the routing rules for the signal type are kept with the signals
+	signalTypePath := fmt.Sprintf("%s/v%s", signalTypeSlug, semVer)
+	routingContext, err := getRoutingContext(r.Context(), s.queries, claims, r.Header, s.signalRouterCache.HeaderNames(signalTypePath))
+	if err != nil {
+		return err
+	}
+
+	// requests with a Prefer: respond-async header are queued and processed in the background by the ingestion workers
+	if prefersAsync(r) {
+		queuedResponse, err := queueSignalSubmission(r.Context(), s.queries, claims, &routingContext, nil, signalTypeSlug, semVer, req)
+		if err != nil {
+			return err
 		}
+		idempotentReq.complete(r.Context(), http.StatusAccepted, queuedResponse)
+
+		return writeQueuedSubmissionResponse(w, queuedResponse)
+	}
+
+	httpStatus, response, err := s.processRoutedSignals(r.Context(), claims, routingContext, signalTypeSlug, semVer, req)
+	if err != nil {
+		return err
 	}
 
-	// start the batch (a new batch is started if the batch ref has not been received previously)
-	batch, err := s.queries.UpsertSignalBatch(r.Context(), database.UpsertSignalBatchParams{
-		BatchRef:  req.BatchRef,
-		AccountID: accountID,
-	})
+	idempotentReq.complete(r.Context(), httpStatus, response)
+
+	return responses.JSON(w, httpStatus, response)
+}
+
+// processRoutedSignals resolves the target ISN for each signal in a router submission request, stores the signals and returns the response status and body.
+// It is used by RouteSignals and by the ingestion workers that process queued (async) submissions.
+//
+// The request must have been checked with validateCreateSignalsRequest before calling this function (write permissions are checked for each signal once the ISN is resolved).
+func (s *SignalRouter) processRoutedSignals(ctx context.Context, claims *auth.Claims, routingContext router.RoutingContext, signalTypeSlug, semVer string, req CreateSignalsRequest) (int, SignalSubmissionResponse, error) {
+	signalTypePath := fmt.Sprintf("%s/v%s", signalTypeSlug, semVer)
+
+	submission, err := s.store.startSubmission(ctx, claims.AccountID, req.BatchRef, len(req.Signals))
 	if err != nil {
-		return apperrors.DatabaseError("database error", err)
+		return 0, SignalSubmissionResponse{}, err
 	}
 
 	var routed []resolvedSignal
-	var routeFailures []FailedSignal
 
 	// Resolve target ISN for each signal.
 	for _, signal := range req.Signals {
 
 		// route by correlation ID
 		if signal.CorrelationID != nil {
-			isnRow, err := s.queries.GetIsnBySignalID(r.Context(), *signal.CorrelationID)
+			isnRow, err := s.queries.GetIsnBySignalID(ctx, *signal.CorrelationID)
 			if err != nil {
 				if errors.Is(err, pgx.ErrNoRows) {
-					routeFailures = append(routeFailures, FailedSignal{
+					submission.rejectUnroutable(ctx, signalTypeSlug, semVer, FailedSignal{
 						LocalRef:     signal.LocalRef,
 						ErrorCode:    string(apperrors.ErrCodeInvalidCorrelationID),
 						ErrorMessage: fmt.Sprintf("correlation_id %s not found or its ISN is not in use", signal.CorrelationID),
 					})
 					continue
 				}
-				return apperrors.DatabaseError("database error", err)
+				return 0, SignalSubmissionResponse{}, apperrors.DatabaseError("database error", err)
 			}
 			routed = append(routed, resolvedSignal{signal: signal, isnSlug: isnRow.Slug, isnID: isnRow.ID})
 		} else {
 
-			// route by matching rules
-			isnID, isnSlug, ok := s.signalRouterCache.Resolve(signalTypePath, signal.Content)
-			if !ok {
-				routeFailures = append(routeFailures, FailedSignal{
-					LocalRef:     signal.LocalRef,
-					ErrorCode:    string(apperrors.ErrCodeInvalidRequest),
-					ErrorMessage: fmt.Sprintf("no routing rule matched for signal type %s", signalTypePath),
+			// route by matching rules (signal types using the all_matches routing mode can resolve to more than one ISN)
+			targets := s.signalRouterCache.Resolve(signalTypePath, signal.Content, routingContext)
+			if len(targets) == 0 {
+				errMsg := fmt.Sprintf("no routing rule matched for signal type %s", signalTypePath)
+
+				// the signal is kept so it can be re-routed by a site admin once the routing rules are fixed
+				unroutableSignalID, err := s.storeUnroutableSignal(ctx, claims, routingContext, submission.batch.ID, signalTypeSlug, semVer, signal, errMsg)
+				if err != nil {
+					return 0, SignalSubmissionResponse{}, err
+				}
+				submission.rejectUnroutable(ctx, signalTypeSlug, semVer, FailedSignal{
+					LocalRef:           signal.LocalRef,
+					ErrorCode:          string(apperrors.ErrCodeInvalidRequest),
+					ErrorMessage:       errMsg,
+					UnroutableSignalID: &unroutableSignalID,
 				})
 				continue
 			}
-			routed = append(routed, resolvedSignal{signal: signal, isnSlug: isnSlug, isnID: isnID})
+			for _, target := range targets {
+				routed = append(routed, resolvedSignal{signal: signal, isnSlug: target.IsnSlug, isnID: target.IsnID})
+			}
 		}
 	}
 
-	// For each resolved signal: check permissions, check signal type availability, validate, and insert.
-	isnResults := make(map[string]*IsnResult)
-	totalStored := 0
-	totalRejected := 0
-
+	// For each resolved signal: check permissions and signal type availability, then store the signal.
 	for _, rs := range routed {
 
-		if _, exists := isnResults[rs.isnSlug]; !exists {
-			isnResults[rs.isnSlug] = &IsnResult{
-				IsnSlug:        rs.isnSlug,
-				SignalTypePath: signalTypePath,
-				StoredSignals:  make([]StoredSignal, 0),
-				FailedSignals:  make([]FailedSignal, 0),
-			}
-		}
-		result := isnResults[rs.isnSlug]
-
-		// check the account has write permission
-		// Check ISN-level write permission only — signal type availability is checked separately below.
-		if err := auth.CheckIsnWritePermission(claims, rs.isnSlug, ""); err != nil {
-			errMsg := err.Error()
-			if rs.signal.CorrelationID != nil {
+		// check the account has write permission and the signal type is available on the ISN
+		if errCode, errMsg := checkRoutedIsnAccess(claims, rs.isnSlug, signalTypePath); errCode != "" {
+			if errCode == apperrors.ErrCodeForbidden && rs.signal.CorrelationID != nil {
 				errMsg = fmt.Sprintf("%s (ISN %s was resolved via correlation_id %s)", errMsg, rs.isnSlug, rs.signal.CorrelationID)
 			}
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
+			submission.reject(ctx, rs.isnSlug, signalTypeSlug, semVer, FailedSignal{
 				LocalRef:     rs.signal.LocalRef,
-				ErrorCode:    string(apperrors.ErrCodeForbidden),
+				ErrorCode:    string(errCode),
 				ErrorMessage: errMsg,
 			})
-			totalRejected++
 			continue
 		}
 
-		perms := claims.IsnPerms[rs.isnSlug]
-		st, ok := perms.SignalTypes[signalTypePath]
-		if !ok {
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
-				LocalRef:     rs.signal.LocalRef,
-				ErrorCode:    string(apperrors.ErrCodeInvalidRequest),
-				ErrorMessage: fmt.Sprintf("signal type %s is not registered on ISN %s", signalTypePath, rs.isnSlug),
-			})
-			totalRejected++
-			continue
-		}
-		if !st.InUse {
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
-				LocalRef:     rs.signal.LocalRef,
-				ErrorCode:    string(apperrors.ErrCodeInvalidRequest),
-				ErrorMessage: fmt.Sprintf("signal type %s is not active on ISN %s", signalTypePath, rs.isnSlug),
-			})
-			totalRejected++
-			continue
-		}
-
-		if err := s.schemaCache.ValidateSignal(r.Context(), s.queries, signalTypePath, rs.signal.Content); err != nil {
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
-				LocalRef: rs.signal.LocalRef, ErrorCode: string(apperrors.ErrCodeMalformedBody),
-				ErrorMessage: fmt.Sprintf("validation failed: %v", err),
-			})
-			totalRejected++
-			continue
-		}
-
-		tx, err := s.pool.BeginTx(r.Context(), pgx.TxOptions{})
-		if err != nil {
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
-				LocalRef: rs.signal.LocalRef, ErrorCode: string(apperrors.ErrCodeDatabaseError), ErrorMessage: "failed to begin transaction",
-			})
-			totalRejected++
-			continue
-		}
-
-		var signalID uuid.UUID
-		var signalErr error
-
-		if rs.signal.CorrelationID != nil {
-			signalID, signalErr = s.queries.WithTx(tx).CreateOrUpdateSignalWithCorrelationID(r.Context(), database.CreateOrUpdateSignalWithCorrelationIDParams{
-				AccountID: claims.AccountID, LocalRef: rs.signal.LocalRef, CorrelationID: *rs.signal.CorrelationID,
-				IsnSlug: rs.isnSlug, SignalTypeSlug: signalTypeSlug, SemVer: semVer,
-			})
-		} else {
-			signalID, signalErr = s.queries.WithTx(tx).CreateSignal(r.Context(), database.CreateSignalParams{
-				AccountID: claims.AccountID, LocalRef: rs.signal.LocalRef,
-				IsnSlug: rs.isnSlug, SignalTypeSlug: signalTypeSlug, SemVer: semVer,
-			})
-		}
-
-		if signalErr != nil {
-			// rollback partial load of the signal
-			if rollbackErr := tx.Rollback(r.Context()); rollbackErr != nil {
-				logger.ContextWithLogAttrs(r.Context(),
-					slog.String("rollback_error", rollbackErr.Error()),
-				)
-			}
-			errMsg := fmt.Sprintf("failed to create signal: %v", signalErr)
-			if errors.Is(signalErr, pgx.ErrNoRows) {
-				errMsg = "failed to create signal - signal type may no longer be active on this ISN"
-			}
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
-				LocalRef: rs.signal.LocalRef, ErrorCode: string(apperrors.ErrCodeDatabaseError), ErrorMessage: errMsg,
-			})
-			totalRejected++
-			continue
-		}
-
-		versionResult, versionErr := s.queries.WithTx(tx).CreateSignalVersion(r.Context(), database.CreateSignalVersionParams{
-			AccountID: claims.AccountID, SignalBatchID: batch.ID, Content: rs.signal.Content,
-			LocalRef: rs.signal.LocalRef, SignalTypeSlug: signalTypeSlug, SemVer: semVer,
-		})
-		if versionErr != nil {
-			// rollback partial load of the signal
-			if rollbackErr := tx.Rollback(r.Context()); rollbackErr != nil {
-				logger.ContextWithLogAttrs(r.Context(),
-					slog.String("rollback_error", rollbackErr.Error()),
-				)
-			}
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
-				LocalRef: rs.signal.LocalRef, ErrorCode: string(apperrors.ErrCodeDatabaseError),
-				ErrorMessage: fmt.Sprintf("failed to create signal version: %v", versionErr),
-			})
-			totalRejected++
-			continue
-		}
-
-		if err := tx.Commit(r.Context()); err != nil {
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
-				LocalRef: rs.signal.LocalRef, ErrorCode: string(apperrors.ErrCodeDatabaseError), ErrorMessage: "failed to commit transaction",
-			})
-			totalRejected++
-			continue
-		}
-
-		result.StoredSignals = append(result.StoredSignals, StoredSignal{
-			LocalRef: rs.signal.LocalRef, SignalID: signalID,
-			SignalVersionID: versionResult.ID, VersionNumber: versionResult.VersionNumber,
-		})
-		totalStored++
+		submission.store(ctx, rs.isnSlug, signalTypeSlug, semVer, rs.signal)
 	}
 
-	// Log per-signal failures to the processing failures table
-	for _, failed := range routeFailures {
-		_, _ = s.queries.CreateSignalProcessingFailureDetail(r.Context(), database.CreateSignalProcessingFailureDetailParams{
-			SignalBatchID: batch.ID, SignalTypeSlug: signalTypeSlug, SignalTypeSemVer: semVer,
-			LocalRef: failed.LocalRef, ErrorCode: failed.ErrorCode, ErrorMessage: failed.ErrorMessage,
-		})
-	}
-	for _, isnResult := range isnResults {
-		for _, failed := range isnResult.FailedSignals {
-			_, _ = s.queries.CreateSignalProcessingFailureDetail(r.Context(), database.CreateSignalProcessingFailureDetailParams{
-				SignalBatchID: batch.ID, SignalTypeSlug: signalTypeSlug, SignalTypeSemVer: semVer,
-				LocalRef: failed.LocalRef, ErrorCode: failed.ErrorCode, ErrorMessage: failed.ErrorMessage,
-			})
-		}
-	}
+	httpStatus, response := submission.complete(ctx)
+	return httpStatus, response, nil
+}
 
-	// Assemble response
-	results := make([]IsnResult, 0, len(isnResults))
-	for _, isnResult := range isnResults {
-		results = append(results, *isnResult)
+// checkRoutedIsnAccess checks the account can store signals of the signal type in an ISN resolved by the router.
+// Returns the error code and message used to report the failure - the error code is empty when the account has access.
+func checkRoutedIsnAccess(claims *auth.Claims, isnSlug, signalTypePath string) (apperrors.ErrorCode, string) {
+	// Check ISN-level write permission only — signal type availability is checked separately below.
+	if err := auth.CheckIsnWritePermission(claims, isnSlug, ""); err != nil {
+		return apperrors.ErrCodeForbidden, err.Error()
 	}
 
-	httpStatus := http.StatusOK
-	switch {
-	case totalStored == 0:
-		httpStatus = http.StatusUnprocessableEntity
-	case totalRejected > 0 || len(routeFailures) > 0:
-		httpStatus = http.StatusMultiStatus
+	perms := claims.IsnPerms[isnSlug]
+	st, ok := perms.SignalTypes[signalTypePath]
+	if !ok {
+		return apperrors.ErrCodeInvalidRequest, fmt.Sprintf("signal type %s is not registered on ISN %s", signalTypePath, isnSlug)
 	}
-
-	return responses.JSON(w, httpStatus, SignalSubmissionResponse{
-		BatchRef:          batch.BatchRef,
-		AccountID:         accountID,
-		Results:           results,
-		UnroutableSignals: routeFailures,
-		Summary:           CreateSignalsSummary{TotalSubmitted: len(req.Signals), StoredCount: totalStored, RejectedCount: totalRejected, UnroutableCount: len(routeFailures)},
-	})
+	if !st.InUse {
+		return apperrors.ErrCodeInvalidRequest, fmt.Sprintf("signal type %s is not active on ISN %s", signalTypePath, isnSlug)
+	}
+	return "", ""
 }
@@ -3,16 +3,18 @@ package handlers
 // these handlers support the management of the rules to route signals to ISNs based on their content
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"strconv"
-	"strings"
 
+	"github.com/google/uuid"
 	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
 	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/invalidation"
 	"github.com/information-sharing-networks/signalsd/app/internal/logger"
 	"github.com/information-sharing-networks/signalsd/app/internal/responses"
 	"github.com/information-sharing-networks/signalsd/app/internal/router"
@@ -25,35 +27,46 @@ import (
 type RoutingConfigHandler struct {
 	queries           *database.Queries
 	pool              *pgxpool.Pool
+	authService       *auth.AuthService
 	signalRouterCache *router.Cache
 	schemaCache       *schemas.Cache
 }
 
-func NewRoutingConfigHandler(queries *database.Queries, pool *pgxpool.Pool, signalRouterCache *router.Cache, schemaCache *schemas.Cache) *RoutingConfigHandler {
-	return &RoutingConfigHandler{queries: queries, pool: pool, signalRouterCache: signalRouterCache, schemaCache: schemaCache}
+func NewRoutingConfigHandler(queries *database.Queries, pool *pgxpool.Pool, authService *auth.AuthService, signalRouterCache *router.Cache, schemaCache *schemas.Cache) *RoutingConfigHandler {
+	return &RoutingConfigHandler{queries: queries, pool: pool, authService: authService, signalRouterCache: signalRouterCache, schemaCache: schemaCache}
 }
 
 // SignalRoutingRule is the mapping between a pattern and a isn.
 // When linked to a signal type/Routing field this forms part of the Isn route config
+//
+// Simple rules compare the routing field with the match_pattern.
+// Compound rules supply a condition (comparisons on one or more fields combined with all/any/not groups) instead of the match_pattern and operator.
 type SignalRoutingRule struct {
-	MatchPattern     string `json:"match_pattern" example:"*felixstowe*"`
-	Operator         string `json:"operator" enums:"matches,equals,does_not_match,does_not_equal" example:"matches"`
-	IsCaseInsensitve bool   `json:"is_case_insensitive" example:"true"`
-	IsnSlug          string `json:"isn_slug" example:"felixstowe-isn"`
-	Sequence         int32  `json:"sequence" example:"1"`
+	MatchPattern     string            `json:"match_pattern,omitempty" example:"*felixstowe*"`
+	Operator         string            `json:"operator,omitempty" enums:"matches,equals,does_not_match,does_not_equal,starts_with,ends_with,regex,in,gt,gte,lt,lte,exists,not_exists" example:"matches"`
+	IsCaseInsensitve bool              `json:"is_case_insensitive" example:"true"`
+	Condition        *router.Condition `json:"condition,omitempty"`
+	IsnSlug          string            `json:"isn_slug" example:"felixstowe-isn"`
+	Sequence         int32             `json:"sequence" example:"1"`
 }
 
 // UpdateSignalRoutingConfigRequest replaces the full rule + mapping set for a signal type path
 type UpdateSignalRoutingConfigRequest struct {
-	RoutingField string              `json:"routing_field" example:"payload.portOfEntry"`
+	RoutingField string              `json:"routing_field,omitempty" example:"payload.portOfEntry"`
+	RoutingMode  string              `json:"routing_mode,omitempty" enums:"first_match,all_matches" example:"first_match"` // defaults to first_match
 	RoutingRules []SignalRoutingRule `json:"routing_rules"`
+
+	// FallbackIsnSlug is the ISN that receives signals that don't match any rule (optional - unroutable signals are stored for review when there is no fallback ISN)
+	FallbackIsnSlug string `json:"fallback_isn_slug,omitempty" example:"unmatched-signals-isn"`
 }
 
 // SignalRoutingConfigResponse contains the full set of isn routes for a signal type path
 type SignalRoutingConfigResponse struct {
-	SignalTypePath string              `json:"signal_type_path" example:"sample-signal-type/v1.0.0"`
-	RoutingField   string              `json:"routing_field" example:"payload.PorfOfEntry"`
-	RoutingRules   []SignalRoutingRule `json:"routing_rules"`
+	SignalTypePath  string              `json:"signal_type_path" example:"sample-signal-type/v1.0.0"`
+	RoutingField    string              `json:"routing_field,omitempty" example:"payload.PorfOfEntry"`
+	RoutingMode     string              `json:"routing_mode" enums:"first_match,all_matches" example:"first_match"`
+	RoutingRules    []SignalRoutingRule `json:"routing_rules"`
+	FallbackIsnSlug string              `json:"fallback_isn_slug,omitempty" example:"unmatched-signals-isn"`
 }
 
 // GetSignalRoutingConfig godoc
@@ -79,40 +92,71 @@ func (h *RoutingConfigHandler) GetSignalRoutingConfig(w http.ResponseWriter, r *
 	slug := r.PathValue("signal_type_slug")
 	semVer := r.PathValue("sem_ver")
 
-	routesConfig, err := h.queries.GetSignalRoutingConfigBySignalType(r.Context(), database.GetSignalRoutingConfigBySignalTypeParams{
+	res, err := h.getSavedRoutingConfig(r.Context(), slug, semVer)
+	if err != nil {
+		return err
+	}
+	return responses.JSON(w, http.StatusOK, res)
+}
+
+// getSavedRoutingConfig returns the routing config stored for a signal type (returns a not found error if there is no config)
+func (h *RoutingConfigHandler) getSavedRoutingConfig(ctx context.Context, slug, semVer string) (SignalRoutingConfigResponse, error) {
+	routesConfig, err := h.queries.GetSignalRoutingConfigBySignalType(ctx, database.GetSignalRoutingConfigBySignalTypeParams{
 		SignalTypeSlug: slug,
 		SemVer:         semVer,
 	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return apperrors.NotFound(fmt.Sprintf("no routing rule found for %s/v%s", slug, semVer), nil)
+			return SignalRoutingConfigResponse{}, apperrors.NotFound(fmt.Sprintf("no routing rule found for %s/v%s", slug, semVer), nil)
 		}
-		return apperrors.DatabaseError("database error", err)
+		return SignalRoutingConfigResponse{}, apperrors.DatabaseError("database error", err)
 	}
 
-	dbRules, err := h.queries.GetIsnRoutesByFieldID(r.Context(), routesConfig.ID)
+	dbRules, err := h.queries.GetIsnRoutesByFieldID(ctx, routesConfig.ID)
 	if err != nil {
-		return apperrors.DatabaseError("database error", err)
+		return SignalRoutingConfigResponse{}, apperrors.DatabaseError("database error", err)
 	}
 
 	res := SignalRoutingConfigResponse{}
 
 	res.SignalTypePath = fmt.Sprintf("%s/v%s", slug, semVer)
 
-	res.RoutingField = routesConfig.RoutingField
+	if routesConfig.RoutingField != nil {
+		res.RoutingField = *routesConfig.RoutingField
+	}
+	res.RoutingMode = routesConfig.RoutingMode
+
+	if routesConfig.FallbackIsnID != nil {
+		fallbackIsn, err := h.queries.GetIsnByID(ctx, *routesConfig.FallbackIsnID)
+		if err != nil {
+			return SignalRoutingConfigResponse{}, apperrors.DatabaseError("database error", err)
+		}
+		res.FallbackIsnSlug = fallbackIsn.Slug
+	}
 
 	rules := make([]SignalRoutingRule, len(dbRules))
 	for i, rule := range dbRules {
 		rules[i] = SignalRoutingRule{
-			MatchPattern:     rule.MatchPattern,
 			IsnSlug:          rule.IsnSlug,
-			Operator:         rule.Operator,
 			IsCaseInsensitve: rule.IsCaseInsensitive,
 			Sequence:         rule.RuleSequence,
 		}
+		if rule.MatchPattern != nil {
+			rules[i].MatchPattern = *rule.MatchPattern
+		}
+		if rule.Operator != nil {
+			rules[i].Operator = *rule.Operator
+		}
+		if rule.Condition != nil {
+			var condition router.Condition
+			if err := json.Unmarshal(rule.Condition, &condition); err != nil {
+				return SignalRoutingConfigResponse{}, apperrors.InternalError("could not unmarshal routing rule condition", err)
+			}
+			rules[i].Condition = &condition
+		}
 	}
 	res.RoutingRules = rules
-	return responses.JSON(w, http.StatusOK, res)
+	return res, nil
 }
 
 // UpdateSignalRoutingConfig godoc
@@ -123,13 +167,73 @@ func (h *RoutingConfigHandler) GetSignalRoutingConfig(w http.ResponseWriter, r *
 //	@Description
 //	@Description	the routing_field must be a plain Dot Notation path -
 //	@Description	under the covers the service uses gjson paths, however the special patern matching symbols (*?#@|!()[]%<>=) are not currently allowed.
+//	@Description	(fields starting with @ refer to the sender of the signal - see _Routing on the sender_ below).
 //	@Description
 //	@Description	When using the 'matches' and 'not matches' operator, any occurance of '*' and '?' in the matching pattern will be treated as a wildcard.
 //	@Description	The pattern is always compared to the full contents of the specified routing field.
 //	@Description
+//	@Description	**Operators**
+//	@Description
+//	@Description	- `matches`, `does_not_match`: glob pattern match
+//	@Description	- `equals`, `does_not_equal`: string equality
+//	@Description	- `starts_with`, `ends_with`: string prefix or suffix
+//	@Description	- `regex`: regular expression (RE2 syntax). Regex patterns are not anchored - use `^` and `$` to match the whole value
+//	@Description	- `in`: the value equals one of the values in a comma-separated list (e.g. `0201,0202,0203`)
+//	@Description	- `gt`, `gte`, `lt`, `lte`: numeric comparison, or a date comparison when the pattern is an ISO 8601 date (`2026-04-01`) or date-time (`2026-04-01T09:00:00Z`).
+//	@Description	Values that are not numbers (or dates) do not match.
+//	@Description	- `exists`, `not_exists`: the field is (or is not) present in the signal - the match_pattern must be omitted
+//	@Description
+//	@Description	is_case_insensitive applies to the string operators (including regex and in). Patterns are validated when the config is saved.
+//	@Description
 //	@Description	Patterns are matched in order according to the supplied sequence number (smallest sequence first)
 //	@Description	and the first match is accepted. Where the routing field is an array, as long as one or more elements match
 //	@Description	the match is accepted.
+//	@Description
+//	@Description	**Routing mode**
+//	@Description
+//	@Description	The optional routing_mode controls what happens when more than one rule matches a signal:
+//	@Description	- `first_match` (default): the signal is routed to the ISN for the first matching rule
+//	@Description	- `all_matches`: the signal is routed to the ISNs for every matching rule (the signal is stored separately in each ISN)
+//	@Description
+//	@Description	**Fallback ISN**
+//	@Description
+//	@Description	The optional fallback_isn_slug is the ISN that receives signals that don't match any of the rules.
+//	@Description	When there is no fallback ISN, unroutable signals are stored so that site admins can review them and re-route them once the rules have been fixed
+//	@Description	(see the _Unroutable Signals_ endpoints).
+//	@Description
+//	@Description	**Compound rules**
+//	@Description
+//	@Description	Rules that depend on more than one field can supply a `condition` instead of the `match_pattern` and `operator`.
+//	@Description	A condition is either a field comparison - `{"field": "payload.commodityCode", "operator": "matches", "pattern": "0201*", "is_case_insensitive": false}` -
+//	@Description	or a group that combines other conditions:
+//	@Description	- `{"all": [...]}` matches when every condition in the list matches
+//	@Description	- `{"any": [...]}` matches when at least one condition in the list matches
+//	@Description	- `{"not": {...}}` matches when the condition does not match
+//	@Description
+//	@Description	For example, to route signals for Felixstowe with a commodity code starting 0201:
+//	@Description	`{"all": [{"field": "payload.portOfEntry", "operator": "matches", "pattern": "*felixstowe*", "is_case_insensitive": true}, {"field": "payload.commodityCode", "operator": "matches", "pattern": "0201*"}]}`
+//	@Description
+//	@Description	Field comparisons use the same operators and path rules as the routing_field and (except for not_exists) do not match when the field is not present in the signal.
+//	@Description	Groups can be nested up to 8 levels deep. The routing_field is only required when the config contains simple (match_pattern) rules.
+//	@Description	Simple and compound rules can be mixed in the same config and are evaluated in sequence order.
+//	@Description
+//	@Description	**Routing on the sender**
+//	@Description
+//	@Description	The routing_field and condition fields can refer to the account that submitted the signal or the request headers instead of the signal content:
+//	@Description	- `@sender.account_id`: the account ID
+//	@Description	- `@sender.account_type`: `user` or `service_account`
+//	@Description	- `@sender.client_organization`: the organization of the service account (not set for user accounts)
+//	@Description	- `@header.{Header-Name}`: the value of a request header, e.g. `@header.X-Origin-System` (header names are case insensitive and the Authorization and Cookie headers can't be used)
+//	@Description
+//	@Description	For example, to route signals sent by HMRC service accounts to a customs ISN:
+//	@Description	`{"field": "@sender.client_organization", "operator": "equals", "pattern": "HMRC", "is_case_insensitive": true}`
+//	@Description
+//	@Description	These fields are not checked against the signal type schema. They can be combined with content fields in compound rules.
+//	@Description
+//	@Description	**Revisions**
+//	@Description
+//	@Description	Each saved config is kept as a revision, together with the account that saved it.
+//	@Description	Use the _Routing Config Revisions_ endpoints to see what changed and to roll back to an earlier revision.
 //
 //	@Param			signal_type_slug	path	string										true	"signal type slug"	example(sample-signal-type)
 //	@Param			sem_ver				path	string										true	"version"			example(1.0.0)
@@ -155,124 +259,249 @@ func (h *RoutingConfigHandler) UpdateSignalRoutingConfig(w http.ResponseWriter,
 		return apperrors.MalformedBody("invalid JSON body", nil)
 	}
 
-	if req.RoutingField == "" {
-		return apperrors.MalformedBody("routing_field is required", nil)
+	validated, err := h.validateRoutingConfigRequest(r.Context(), slug, semVer, &req)
+	if err != nil {
+		return err
+	}
+
+	accountID, ok := auth.ContextAccountID(r.Context())
+	if !ok {
+		return apperrors.InternalError("could not get accountID from context", nil)
 	}
 
-	// prevent the use of chars with special meaning in gjson
-	if strings.ContainsAny(req.RoutingField, `*?#@|!()[]%<>=`) {
-		return apperrors.MalformedBody("routing_field must be a plain JSON path (e.g. payload.portOfEntry) - wildcards and gjson operators are not supported", nil)
+	if _, err := h.saveRoutingConfig(r.Context(), accountID, validated, req, signalsd.RoutingConfigRevisionUpdated, nil); err != nil {
+		return err
 	}
 
-	// prevent numeric path segments (gjson array index access e.g. payload.0.item)
-	for seg := range strings.SplitSeq(req.RoutingField, ".") {
-		if _, err := strconv.Atoi(seg); err == nil {
-			return apperrors.MalformedBody("routing_field must not contain numeric segments - routing by array index is not supported", nil)
+	return responses.NoContent(w, http.StatusNoContent)
+}
+
+// saveRoutingConfig replaces the routing config for the signal type and records the new config as a revision.
+// The request must have been checked with validateRoutingConfigRequest.
+func (h *RoutingConfigHandler) saveRoutingConfig(ctx context.Context, accountID uuid.UUID, validated validatedRoutingConfig, req UpdateSignalRoutingConfigRequest, action string, rolledBackToRevision *int32) (database.SignalRoutingConfigRevision, error) {
+	//  start transaction
+	tx, err := h.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return database.SignalRoutingConfigRevision{}, apperrors.DatabaseError("database error", err)
+	}
+
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logger.ContextWithLogAttrs(ctx,
+				slog.String("rollback_error", err.Error()),
+			)
+
+		}
+	}()
+
+	txQueries := h.queries.WithTx(tx)
+
+	// Delete the old routes
+	if _, err := txQueries.DeleteSignalRoutingConfigBySignalTypeID(ctx, validated.signalType.ID); err != nil {
+		return database.SignalRoutingConfigRevision{}, apperrors.DatabaseError("database error", err)
+	}
+
+	// create the new routing field entry
+	var routingField *string
+	if req.RoutingField != "" {
+		routingField = &req.RoutingField
+	}
+	var fallbackIsnID *uuid.UUID
+	if validated.fallbackIsn != nil {
+		fallbackIsnID = &validated.fallbackIsn.ID
+	}
+	signalRoutingConfig, err := txQueries.CreateSignalRoutingConfig(ctx, database.CreateSignalRoutingConfigParams{
+		SignalTypeID:  validated.signalType.ID,
+		RoutingField:  routingField,
+		RoutingMode:   req.RoutingMode,
+		FallbackIsnID: fallbackIsnID,
+	})
+	if err != nil {
+		return database.SignalRoutingConfigRevision{}, apperrors.DatabaseError("database error", err)
+	}
+
+	for i, rule := range req.RoutingRules {
+		params := database.CreateIsnRouteParams{
+			SignalRoutingConfigID: signalRoutingConfig.ID,
+			IsCaseInsensitive:     rule.IsCaseInsensitve,
+			IsnID:                 validated.ruleIsns[i].ID,
+			RuleSequence:          rule.Sequence,
 		}
+		if rule.Condition != nil {
+			condition, err := json.Marshal(rule.Condition)
+			if err != nil {
+				return database.SignalRoutingConfigRevision{}, apperrors.InternalError("could not marshal routing rule condition", err)
+			}
+			params.Condition = condition
+		} else {
+			params.MatchPattern = &rule.MatchPattern
+			params.Operator = &rule.Operator
+		}
+
+		if _, err := txQueries.CreateIsnRoute(ctx, params); err != nil {
+			return database.SignalRoutingConfigRevision{}, apperrors.DatabaseError("database error", err)
+		}
+	}
+
+	// record the change
+	config, err := json.Marshal(req)
+	if err != nil {
+		return database.SignalRoutingConfigRevision{}, apperrors.InternalError("could not marshal routing config", err)
+	}
+	revision, err := txQueries.CreateSignalRoutingConfigRevision(ctx, database.CreateSignalRoutingConfigRevisionParams{
+		SignalTypeID:         validated.signalType.ID,
+		AccountID:            &accountID,
+		Action:               action,
+		Config:               config,
+		RolledBackToRevision: rolledBackToRevision,
+	})
+	if err != nil {
+		return database.SignalRoutingConfigRevision{}, apperrors.DatabaseError("database error", err)
+	}
+
+	// sent when the transaction commits
+	invalidation.Notify(ctx, txQueries, invalidation.RouterCache)
+
+	if err := tx.Commit(ctx); err != nil {
+		return database.SignalRoutingConfigRevision{}, apperrors.DatabaseError("database error", err)
 	}
 
+	return revision, nil
+
+}
+
+// validatedRoutingConfig contains the signal type and ISNs referenced by a valid routing config request
+type validatedRoutingConfig struct {
+	signalType database.SignalType
+
+	// ruleIsns are the target ISNs for each rule (in the same order as the request rules)
+	ruleIsns []database.Isn
+
+	// fallbackIsn is nil when the config does not have a fallback ISN
+	fallbackIsn *database.Isn
+}
+
+// validateRoutingConfigRequest checks a routing config request is valid for the signal type and sets the default routing mode.
+// Returns the signal type and the ISNs used by the config.
+func (h *RoutingConfigHandler) validateRoutingConfigRequest(ctx context.Context, slug, semVer string, req *UpdateSignalRoutingConfigRequest) (validatedRoutingConfig, error) {
 	if len(req.RoutingRules) == 0 {
-		return apperrors.MalformedBody("at least one mapping is required", nil)
+		return validatedRoutingConfig{}, apperrors.MalformedBody("at least one mapping is required", nil)
 	}
 
 	// Validate routes
+	usesRoutingField := false
+	var conditionFields []string
 	for i, rule := range req.RoutingRules {
 
-		if rule.MatchPattern == "" || rule.IsnSlug == "" {
-			return apperrors.MalformedBody(fmt.Sprintf("mapping[%d]: match_pattern and isn_slug are required", i), nil)
+		if rule.IsnSlug == "" {
+			return validatedRoutingConfig{}, apperrors.MalformedBody(fmt.Sprintf("mapping[%d]: isn_slug is required", i), nil)
+		}
+
+		// compound rule
+		if rule.Condition != nil {
+			if rule.MatchPattern != "" || rule.Operator != "" {
+				return validatedRoutingConfig{}, apperrors.MalformedBody(fmt.Sprintf("mapping[%d]: match_pattern and operator can't be used with a condition", i), nil)
+			}
+			if err := rule.Condition.Validate(); err != nil {
+				return validatedRoutingConfig{}, apperrors.MalformedBody(fmt.Sprintf("mapping[%d]: invalid condition: %v", i, err), nil)
+			}
+			conditionFields = append(conditionFields, rule.Condition.Fields()...)
+			continue
 		}
 
-		if !signalsd.ValidRouteMatchingOperators[rule.Operator] {
-			return apperrors.MalformedBody(fmt.Sprintf("invalid route matching operator %s", rule.Operator), nil)
+		// simple rule
+		if err := router.ValidateComparison(rule.Operator, rule.MatchPattern); err != nil {
+			return validatedRoutingConfig{}, apperrors.MalformedBody(fmt.Sprintf("mapping[%d]: %v", i, err), nil)
+		}
+		usesRoutingField = true
+	}
+
+	if req.RoutingMode == "" {
+		req.RoutingMode = signalsd.RoutingModeFirstMatch
+	}
+	if !signalsd.ValidRoutingModes[req.RoutingMode] {
+		return validatedRoutingConfig{}, apperrors.MalformedBody(fmt.Sprintf("invalid routing_mode %s", req.RoutingMode), nil)
+	}
+
+	if usesRoutingField && req.RoutingField == "" {
+		return validatedRoutingConfig{}, apperrors.MalformedBody("routing_field is required", nil)
+	}
+
+	if req.RoutingField != "" {
+		if err := router.ValidateFieldPath(req.RoutingField); err != nil {
+			return validatedRoutingConfig{}, apperrors.MalformedBody(fmt.Sprintf("routing_field %v", err), nil)
 		}
 	}
 
 	// Resolve the signal type and all ISN slugs before starting the transaction.
-	signalType, err := h.queries.GetSignalTypeBySlugAndVersion(r.Context(), database.GetSignalTypeBySlugAndVersionParams{
+	signalType, err := h.queries.GetSignalTypeBySlugAndVersion(ctx, database.GetSignalTypeBySlugAndVersionParams{
 		Slug:   slug,
 		SemVer: semVer,
 	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return apperrors.NotFound(fmt.Sprintf("signal type %s/v%s not found", slug, semVer), nil)
+			return validatedRoutingConfig{}, apperrors.NotFound(fmt.Sprintf("signal type %s/v%s not found", slug, semVer), nil)
 		}
-		return apperrors.DatabaseError("database error", err)
+		return validatedRoutingConfig{}, apperrors.DatabaseError("database error", err)
 	}
 
-	// Validate the routing field against the cached schema for this signal type.
+	// Validate the routing field and the fields used in conditions against the cached schema for this signal type
+	// (routing context fields such as @sender.client_organization are not part of the signal content and are not checked).
 	// The cache is guaranteed to be populated for any existing signal type, so a
 	// miss here is an unexpected internal error rather than a user error.
 	signalTypePath := fmt.Sprintf("%s/v%s", slug, semVer)
-	fieldExists, err := h.schemaCache.FieldPathExistsInSchema(signalTypePath, req.RoutingField)
-	if err != nil {
-		return apperrors.InternalError("schema cache error", err)
+	if req.RoutingField != "" && !router.IsContextField(req.RoutingField) {
+		fieldExists, err := h.schemaCache.FieldPathExistsInSchema(signalTypePath, req.RoutingField)
+		if err != nil {
+			return validatedRoutingConfig{}, apperrors.InternalError("schema cache error", err)
+		}
+		if !fieldExists {
+			return validatedRoutingConfig{}, apperrors.MalformedBody(fmt.Sprintf("routing_field %q is not defined in the schema for %s", req.RoutingField, signalTypePath), nil)
+		}
 	}
-	if !fieldExists {
-		return apperrors.MalformedBody(fmt.Sprintf("routing_field %q is not defined in the schema for %s", req.RoutingField, signalTypePath), nil)
+	for _, field := range conditionFields {
+		if router.IsContextField(field) {
+			continue
+		}
+		fieldExists, err := h.schemaCache.FieldPathExistsInSchema(signalTypePath, field)
+		if err != nil {
+			return validatedRoutingConfig{}, apperrors.InternalError("schema cache error", err)
+		}
+		if !fieldExists {
+			return validatedRoutingConfig{}, apperrors.MalformedBody(fmt.Sprintf("condition field %q is not defined in the schema for %s", field, signalTypePath), nil)
+		}
 	}
 
 	// check slugs exist and get the ids
 	isnIDs := make([]database.Isn, len(req.RoutingRules))
 	for i, rule := range req.RoutingRules {
-		isn, err := h.queries.GetIsnBySlug(r.Context(), rule.IsnSlug)
+		isn, err := h.queries.GetIsnBySlug(ctx, rule.IsnSlug)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return apperrors.NotFound(fmt.Sprintf("mapping[%d]: ISN %q not found", i, rule.IsnSlug), nil)
+				return validatedRoutingConfig{}, apperrors.NotFound(fmt.Sprintf("mapping[%d]: ISN %q not found", i, rule.IsnSlug), nil)
 			}
-			return apperrors.DatabaseError("database error", err)
+			return validatedRoutingConfig{}, apperrors.DatabaseError("database error", err)
 		}
 		isnIDs[i] = isn
 	}
 
-	//  start transaction
-	tx, err := h.pool.BeginTx(r.Context(), pgx.TxOptions{})
-	if err != nil {
-		return apperrors.DatabaseError("database error", err)
-	}
-
-	defer func() {
-		if err := tx.Rollback(r.Context()); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
-			logger.ContextWithLogAttrs(r.Context(),
-				slog.String("rollback_error", err.Error()),
-			)
-
-		}
-	}()
-
-	txQueries := h.queries.WithTx(tx)
-
-	// Delete the old routes
-	if _, err := txQueries.DeleteSignalRoutingConfigBySignalTypeID(r.Context(), signalType.ID); err != nil {
-		return apperrors.DatabaseError("database error", err)
+	validated := validatedRoutingConfig{
+		signalType: signalType,
+		ruleIsns:   isnIDs,
 	}
 
-	// create the new routing field entry
-	signalRoutingConfig, err := txQueries.CreateSignalRoutingConfig(r.Context(), database.CreateSignalRoutingConfigParams{
-		SignalTypeID: signalType.ID,
-		RoutingField: req.RoutingField,
-	})
-	if err != nil {
-		return apperrors.DatabaseError("database error", err)
-	}
-
-	for i, rule := range req.RoutingRules {
-		if _, err := txQueries.CreateIsnRoute(r.Context(), database.CreateIsnRouteParams{
-			SignalRoutingConfigID: signalRoutingConfig.ID,
-			MatchPattern:          rule.MatchPattern,
-			Operator:              rule.Operator,
-			IsCaseInsensitive:     rule.IsCaseInsensitve,
-			IsnID:                 isnIDs[i].ID,
-			RuleSequence:          rule.Sequence,
-		}); err != nil {
-			return apperrors.DatabaseError("database error", err)
+	if req.FallbackIsnSlug != "" {
+		fallbackIsn, err := h.queries.GetIsnBySlug(ctx, req.FallbackIsnSlug)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return validatedRoutingConfig{}, apperrors.NotFound(fmt.Sprintf("fallback ISN %q not found", req.FallbackIsnSlug), nil)
+			}
+			return validatedRoutingConfig{}, apperrors.DatabaseError("database error", err)
 		}
+		validated.fallbackIsn = &fallbackIsn
 	}
 
-	if err := tx.Commit(r.Context()); err != nil {
-		return apperrors.DatabaseError("database error", err)
-	}
-
-	return responses.NoContent(w, http.StatusNoContent)
+	return validated, nil
 }
 
 // DeleteSignalRoutingConfig godoc
@@ -280,6 +509,7 @@ func (h *RoutingConfigHandler) UpdateSignalRoutingConfig(w http.ResponseWriter,
 //	@Summary		Delete Signals Routing Config
 //
 //	@Description	Removes all routing information for a signal type version.
+//	@Description	The deletion is recorded in the routing config revision history.
 //
 //	@Tags			Signals Routing
 //
@@ -320,12 +550,46 @@ func (h *RoutingConfigHandler) DeleteSignalRoutingConfig(w http.ResponseWriter,
 		return apperrors.DatabaseError("database error", err)
 	}
 
-	_, err = h.queries.DeleteSignalRoutingConfigBySignalTypeID(r.Context(), signalType.ID)
+	accountID, ok := auth.ContextAccountID(r.Context())
+	if !ok {
+		return apperrors.InternalError("could not get accountID from context", nil)
+	}
+
+	tx, err := h.pool.BeginTx(r.Context(), pgx.TxOptions{})
 	if err != nil {
 		return apperrors.DatabaseError("database error", err)
 	}
 
-	// refresh the cache for this instance (polling will catch-up the other instances eventually)
+	defer func() {
+		if err := tx.Rollback(r.Context()); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logger.ContextWithLogAttrs(r.Context(),
+				slog.String("rollback_error", err.Error()),
+			)
+		}
+	}()
+
+	txQueries := h.queries.WithTx(tx)
+
+	if _, err := txQueries.DeleteSignalRoutingConfigBySignalTypeID(r.Context(), signalType.ID); err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	// the deletion is recorded as a revision without a config
+	if _, err := txQueries.CreateSignalRoutingConfigRevision(r.Context(), database.CreateSignalRoutingConfigRevisionParams{
+		SignalTypeID: signalType.ID,
+		AccountID:    &accountID,
+		Action:       signalsd.RoutingConfigRevisionDeleted,
+	}); err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	invalidation.Notify(r.Context(), txQueries, invalidation.RouterCache)
+
+	if err := tx.Commit(r.Context()); err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	// refresh the cache for this instance straight away (the other instances reload the cache when they receive the invalidation notification)
 	if err := h.signalRouterCache.Load(r.Context()); err != nil {
 		logger.ContextWithLogAttrs(r.Context(), slog.String("router_cache_reload_error", err.Error()))
 	}
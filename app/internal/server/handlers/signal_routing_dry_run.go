@@ -0,0 +1,285 @@
+package handlers
+
+// routing config dry runs.
+// site admins can test a draft routing config (or the saved config) against sample signals before the config is used to route signals.
+// nothing is stored - the response shows where each signal would be routed and whether the sender could write to the target ISNs.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/responses"
+	"github.com/information-sharing-networks/signalsd/app/internal/router"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxDryRunSignals is the maximum number of sample signals that can be supplied in a routing config dry run
+const maxDryRunSignals = 100
+
+// SignalRoutingDryRunRequest contains the sample signals to route and, optionally, a draft routing config.
+type SignalRoutingDryRunRequest struct {
+	// RoutingConfig is the draft config to test - the saved config for the signal type is used when this is omitted
+	RoutingConfig *UpdateSignalRoutingConfigRequest `json:"routing_config,omitempty"`
+	Signals       []SignalRoutingDryRunSignal       `json:"signals"`
+
+	// Sender is used by rules that route on the sender (@sender and @header fields) and to check the write permission on the target ISNs -
+	// your own account and request headers are used when this is omitted
+	Sender *SignalRoutingDryRunSender `json:"sender,omitempty"`
+}
+
+// SignalRoutingDryRunSender is the sender of the sample signals (fields that are omitted are treated as not set).
+// The write permissions reported for the targets are the current permissions of the account_id (when the account_id is omitted the sender has no permissions).
+type SignalRoutingDryRunSender struct {
+	AccountID          *uuid.UUID        `json:"account_id,omitempty" example:"a38c99ed-c75c-4a4a-a901-c9485cf93cf3"`
+	AccountType        string            `json:"account_type,omitempty" enums:"user,service_account" example:"service_account"`
+	ClientOrganization string            `json:"client_organization,omitempty" example:"HMRC"`
+	Headers            map[string]string `json:"headers,omitempty"`
+}
+
+// SignalRoutingDryRunSignal is a sample signal (the local_ref is optional and is only used to label the result)
+type SignalRoutingDryRunSignal struct {
+	LocalRef string          `json:"local_ref,omitempty" example:"item_id_#1"`
+	Content  json.RawMessage `json:"content" swaggertype:"object"`
+}
+
+// SignalRoutingDryRunResponse reports how the routing config applies to each sample signal
+type SignalRoutingDryRunResponse struct {
+	SignalTypePath string                      `json:"signal_type_path" example:"sample-signal-type/v1.0.0"`
+	ConfigSource   string                      `json:"config_source" enums:"draft,saved" example:"draft"`
+	RoutingMode    string                      `json:"routing_mode" enums:"first_match,all_matches" example:"first_match"`
+	Results        []SignalRoutingDryRunResult `json:"results"`
+}
+
+// SignalRoutingDryRunResult is the routing outcome for a sample signal (results are returned in the same order as the signals in the request)
+type SignalRoutingDryRunResult struct {
+	LocalRef string `json:"local_ref,omitempty" example:"item_id_#1"`
+
+	// FieldValues are the values extracted from the signal for each field used by the routing rules
+	FieldValues []RoutingFieldValue `json:"field_values"`
+
+	// MatchedRules are the sequence numbers of the matching rules (at most one rule in first_match mode)
+	MatchedRules []int32 `json:"matched_rules" example:"1"`
+
+	// UsedFallback is true when no rule matched and the signal would be routed to the fallback ISN
+	UsedFallback bool `json:"used_fallback" example:"false"`
+
+	// Targets are the ISNs the signal would be routed to - empty when the signal would be unroutable
+	Targets []RoutingDryRunTarget `json:"targets"`
+}
+
+// RoutingFieldValue is the value of a field in the signal content or, for @sender and @header fields, the sender details (found is false when the field is not present)
+type RoutingFieldValue struct {
+	Field string          `json:"field" example:"payload.portOfEntry"`
+	Found bool            `json:"found" example:"true"`
+	Value json.RawMessage `json:"value,omitempty" swaggertype:"string" example:"felixstowe"`
+}
+
+// RoutingDryRunTarget is an ISN that a signal would be routed to and whether the sender could store signals there
+type RoutingDryRunTarget struct {
+	IsnSlug  string `json:"isn_slug" example:"felixstowe-isn"`
+	CanWrite bool   `json:"can_write" example:"true"`
+	Reason   string `json:"reason,omitempty" example:"account does not have write permission on ISN \"felixstowe-isn\""`
+}
+
+// DryRunSignalRoutingConfig godoc
+//
+//	@Summary		Test Signals Routing Config
+//
+//	@Description	Shows where sample signals would be routed without storing anything.
+//	@Description
+//	@Description	Supply a draft `routing_config` (same format as the _Update Signals Routing Config_ request) to test a config before saving it.
+//	@Description	If the routing_config is omitted the saved config for the signal type is used.
+//	@Description	Draft configs are validated in the same way as configs that are being saved.
+//	@Description
+//	@Description	For each sample signal the response lists:
+//	@Description	- the values extracted from the signal for the fields used by the routing rules
+//	@Description	- the sequence numbers of the rules that matched
+//	@Description	- the ISNs the signal would be routed to and whether the sender can write signals of this type to each ISN
+//	@Description
+//	@Description	Rules that route on the sender (`@sender` and `@header` fields) use the optional `sender` - when this is omitted your own account and request headers are used.
+//	@Description	When a `sender` is supplied the write permissions are checked using the current permissions of the sender's `account_id`.
+//	@Description
+//	@Description	Signals that don't match any rule are routed to the fallback ISN (if the config has one), otherwise they have no targets and would be stored as unroutable signals.
+//	@Description	Correlation IDs are not taken into account.
+//	@Description	Up to 100 sample signals can be supplied in a request.
+//
+//	@Tags			Signals Routing
+//
+//	@Param			signal_type_slug	path		string								true	"signal type slug"	example(sample-signal-type)
+//	@Param			sem_ver				path		string								true	"version"			example(1.0.0)
+//	@Param			request				body		handlers.SignalRoutingDryRunRequest	true	"sample signals and optional draft config"
+//
+//	@Success		200					{object}	handlers.SignalRoutingDryRunResponse
+//	@Failure		400					{object}	responses.ErrorResponse	"malformed_body | invalid_request"
+//	@Failure		404					{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure		500					{object}	responses.ErrorResponse	"database_error | internal_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/admin/signal-types/{signal_type_slug}/v{sem_ver}/routes/dry-run [post]
+//
+// Should only be used with RequireRole (siteadmin) middleware.
+func (h *RoutingConfigHandler) DryRunSignalRoutingConfig(w http.ResponseWriter, r *http.Request) error {
+	slug := r.PathValue("signal_type_slug")
+	semVer := r.PathValue("sem_ver")
+
+	claims, ok := auth.ContextClaims(r.Context())
+	if !ok {
+		return apperrors.InternalError("could not get claims from context", nil)
+	}
+
+	defer r.Body.Close()
+	var req SignalRoutingDryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperrors.MalformedBody("invalid JSON body", nil)
+	}
+
+	if len(req.Signals) == 0 {
+		return apperrors.MalformedBody("request must contain at least one signal in the 'signals' array", nil)
+	}
+	if len(req.Signals) > maxDryRunSignals {
+		return apperrors.MalformedBody(fmt.Sprintf("request can't contain more than %d signals", maxDryRunSignals), nil)
+	}
+	contents := make([]json.RawMessage, len(req.Signals))
+	for i, signal := range req.Signals {
+		if len(signal.Content) == 0 {
+			return apperrors.MalformedBody(fmt.Sprintf("signal[%d] is missing required field 'content'", i), nil)
+		}
+		contents[i] = signal.Content
+	}
+
+	var config UpdateSignalRoutingConfigRequest
+	configSource := "draft"
+	if req.RoutingConfig != nil {
+		config = *req.RoutingConfig
+		if _, err := h.validateRoutingConfigRequest(r.Context(), slug, semVer, &config); err != nil {
+			return err
+		}
+	} else {
+		saved, err := h.getSavedRoutingConfig(r.Context(), slug, semVer)
+		if err != nil {
+			return err
+		}
+		config = UpdateSignalRoutingConfigRequest{
+			RoutingField:    saved.RoutingField,
+			RoutingMode:     saved.RoutingMode,
+			RoutingRules:    saved.RoutingRules,
+			FallbackIsnSlug: saved.FallbackIsnSlug,
+		}
+		configSource = "saved"
+	}
+
+	dryRunConfig := router.DryRunConfig{
+		RoutingField:    config.RoutingField,
+		RoutingMode:     config.RoutingMode,
+		Rules:           make([]router.DryRunRule, len(config.RoutingRules)),
+		FallbackIsnSlug: config.FallbackIsnSlug,
+	}
+	for i, rule := range config.RoutingRules {
+		dryRunConfig.Rules[i] = router.DryRunRule{
+			Sequence:          rule.Sequence,
+			IsnSlug:           rule.IsnSlug,
+			MatchPattern:      rule.MatchPattern,
+			Operator:          rule.Operator,
+			IsCaseInsensitive: rule.IsCaseInsensitve,
+			Condition:         rule.Condition,
+		}
+	}
+
+	// the routing context only includes the headers used by the config's rules (in the same way as signals submitted to the router)
+	headerNames := dryRunConfig.HeaderNames()
+	routingContext, err := getRoutingContext(r.Context(), h.queries, claims, r.Header, headerNames)
+	if err != nil {
+		return err
+	}
+
+	// the write permissions for the targets are checked using the sender's permissions
+	senderClaims := claims
+	if req.Sender != nil {
+		if req.Sender.AccountType != "" && req.Sender.AccountType != "user" && req.Sender.AccountType != "service_account" {
+			return apperrors.MalformedBody("sender account_type must be user or service_account", nil)
+		}
+		headers := http.Header{}
+		for name, value := range req.Sender.Headers {
+			headers.Set(name, value)
+		}
+		routingContext = router.NewRoutingContext(uuid.Nil, req.Sender.AccountType, req.Sender.ClientOrganization, headers, headerNames)
+		if req.Sender.AccountID != nil {
+			routingContext.AccountID = *req.Sender.AccountID
+		}
+		if senderClaims, err = h.dryRunSenderClaims(r.Context(), req.Sender.AccountID); err != nil {
+			return err
+		}
+	}
+
+	dryRunResults, err := router.DryRun(dryRunConfig, contents, routingContext)
+	if err != nil {
+		// draft configs have already been validated so this is only expected if the saved config is invalid
+		return apperrors.InternalError("could not evaluate routing config", err)
+	}
+
+	signalTypePath := fmt.Sprintf("%s/v%s", slug, semVer)
+	res := SignalRoutingDryRunResponse{
+		SignalTypePath: signalTypePath,
+		ConfigSource:   configSource,
+		RoutingMode:    config.RoutingMode,
+		Results:        make([]SignalRoutingDryRunResult, len(dryRunResults)),
+	}
+	for i, dryRunResult := range dryRunResults {
+		result := SignalRoutingDryRunResult{
+			LocalRef:     req.Signals[i].LocalRef,
+			FieldValues:  make([]RoutingFieldValue, len(dryRunResult.FieldValues)),
+			MatchedRules: dryRunResult.MatchedRules,
+			UsedFallback: dryRunResult.UsedFallback,
+			Targets:      make([]RoutingDryRunTarget, len(dryRunResult.IsnSlugs)),
+		}
+		for j, fieldValue := range dryRunResult.FieldValues {
+			result.FieldValues[j] = RoutingFieldValue{
+				Field: fieldValue.Field,
+				Found: fieldValue.Value != nil,
+				Value: fieldValue.Value,
+			}
+		}
+		for j, isnSlug := range dryRunResult.IsnSlugs {
+			_, reason := checkRoutedIsnAccess(senderClaims, isnSlug, signalTypePath)
+			result.Targets[j] = RoutingDryRunTarget{
+				IsnSlug:  isnSlug,
+				CanWrite: reason == "",
+				Reason:   reason,
+			}
+		}
+		res.Results[i] = result
+	}
+
+	return responses.JSON(w, http.StatusOK, res)
+}
+
+// dryRunSenderClaims returns the claims used to check the sender's write permission on the target ISNs.
+// These are based on the account's current permissions - a sender without an account_id has no permissions.
+func (h *RoutingConfigHandler) dryRunSenderClaims(ctx context.Context, accountID *uuid.UUID) (*auth.Claims, error) {
+	if accountID == nil {
+		return &auth.Claims{}, nil
+	}
+
+	if _, err := h.queries.GetAccountByID(ctx, *accountID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apperrors.NotFound(fmt.Sprintf("sender account %v not found", *accountID), nil)
+		}
+		return nil, apperrors.DatabaseError("database error", err)
+	}
+
+	claims, err := h.authService.CurrentClaims(ctx, *accountID, "")
+	if err != nil {
+		if errors.Is(err, auth.ErrAccountInactive) {
+			return nil, apperrors.InvalidRequest(fmt.Sprintf("sender account %v is disabled", *accountID), nil)
+		}
+		return nil, apperrors.InternalError("could not load the sender's permissions", err)
+	}
+	return claims, nil
+}
@@ -0,0 +1,369 @@
+package handlers
+
+// routing config revisions.
+// every change to the routing config for a signal type (updates, deletions and rollbacks) is recorded as an immutable revision together with the account that made the change.
+// site admins can list the revisions, see what changed in each revision and roll back to an earlier revision.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	"github.com/information-sharing-networks/signalsd/app/internal/responses"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/jackc/pgx/v5"
+)
+
+// RoutingConfigRevision describes a change to the routing config for a signal type
+type RoutingConfigRevision struct {
+	Revision  int32     `json:"revision" example:"3"`
+	CreatedAt time.Time `json:"created_at" example:"2026-04-02T09:30:00Z"`
+
+	// AccountID is the account that made the change (omitted for revisions recorded before revision history was introduced, or if the account has been deleted)
+	AccountID *uuid.UUID `json:"account_id,omitempty" example:"a38c99ed-c75c-4a4a-a901-c9485cf93cf3"`
+	Action    string     `json:"action" enums:"updated,deleted,rolled_back" example:"updated"`
+
+	// RolledBackToRevision is the revision restored by a rollback
+	RolledBackToRevision *int32 `json:"rolled_back_to_revision,omitempty" example:"1"`
+}
+
+// RoutingConfigRevisionDetails includes the routing config saved in the revision and the changes from the previous revision
+type RoutingConfigRevisionDetails struct {
+	RoutingConfigRevision
+	SignalTypePath string `json:"signal_type_path" example:"sample-signal-type/v1.0.0"`
+
+	// Config is omitted when the revision deleted the routing config
+	Config  *UpdateSignalRoutingConfigRequest `json:"config,omitempty"`
+	Changes []RoutingConfigChange             `json:"changes"`
+}
+
+// RoutingConfigChange is a difference between a revision and the previous revision.
+// Rule changes are identified by the rule sequence number - previous and new contain the full rule.
+type RoutingConfigChange struct {
+	Field    string `json:"field" enums:"routing_field,routing_mode,fallback_isn_slug,routing_rule" example:"routing_rule"`
+	Sequence *int32 `json:"sequence,omitempty" example:"2"`
+	Change   string `json:"change" enums:"added,removed,changed" example:"changed"`
+	Previous any    `json:"previous,omitempty"`
+	New      any    `json:"new,omitempty"`
+}
+
+// ListSignalRoutingConfigRevisions godoc
+//
+//	@Summary		List Routing Config Revisions
+//
+//	@Description	Lists the changes made to the routing config for a signal type (newest first).
+//	@Description
+//	@Description	A revision is recorded each time the config is saved, deleted or rolled back, together with the account that made the change.
+//
+//	@Tags			Signals Routing
+//
+//	@Param			signal_type_slug	path		string	true	"signal type slug"	example(sample-signal-type)
+//	@Param			sem_ver				path		string	true	"version"			example(1.0.0)
+//
+//	@Success		200					{array}		handlers.RoutingConfigRevision
+//	@Failure		404					{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure		500					{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/admin/signal-types/{signal_type_slug}/v{sem_ver}/routes/revisions [get]
+//
+// Should only be used with RequireRole (siteadmin) middleware.
+func (h *RoutingConfigHandler) ListSignalRoutingConfigRevisions(w http.ResponseWriter, r *http.Request) error {
+	signalType, err := h.getRevisionsSignalType(r.Context(), r.PathValue("signal_type_slug"), r.PathValue("sem_ver"))
+	if err != nil {
+		return err
+	}
+
+	rows, err := h.queries.GetSignalRoutingConfigRevisions(r.Context(), signalType.ID)
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	res := make([]RoutingConfigRevision, len(rows))
+	for i, row := range rows {
+		res[i] = RoutingConfigRevision{
+			Revision:             row.Revision,
+			CreatedAt:            row.CreatedAt,
+			AccountID:            row.AccountID,
+			Action:               row.Action,
+			RolledBackToRevision: row.RolledBackToRevision,
+		}
+	}
+
+	return responses.JSON(w, http.StatusOK, res)
+}
+
+// GetSignalRoutingConfigRevision godoc
+//
+//	@Summary		Get Routing Config Revision
+//
+//	@Description	Returns a routing config revision, including the config that was saved and the changes from the previous revision.
+//	@Description
+//	@Description	Changes to rules are identified by the rule sequence number and include the full rule before and after the change.
+//	@Description	The config is omitted for revisions that deleted the routing config.
+//
+//	@Tags			Signals Routing
+//
+//	@Param			signal_type_slug	path		string	true	"signal type slug"	example(sample-signal-type)
+//	@Param			sem_ver				path		string	true	"version"			example(1.0.0)
+//	@Param			revision			path		int		true	"revision number"	example(3)
+//
+//	@Success		200					{object}	handlers.RoutingConfigRevisionDetails
+//	@Failure		400					{object}	responses.ErrorResponse	"invalid_url_param"
+//	@Failure		404					{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure		500					{object}	responses.ErrorResponse	"database_error | internal_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/admin/signal-types/{signal_type_slug}/v{sem_ver}/routes/revisions/{revision} [get]
+//
+// Should only be used with RequireRole (siteadmin) middleware.
+func (h *RoutingConfigHandler) GetSignalRoutingConfigRevision(w http.ResponseWriter, r *http.Request) error {
+	slug := r.PathValue("signal_type_slug")
+	semVer := r.PathValue("sem_ver")
+
+	signalType, err := h.getRevisionsSignalType(r.Context(), slug, semVer)
+	if err != nil {
+		return err
+	}
+
+	revision, config, err := h.getRevision(r, signalType)
+	if err != nil {
+		return err
+	}
+
+	// compare with the previous revision (the first revision is compared with an empty config)
+	var previousConfig *UpdateSignalRoutingConfigRequest
+	if revision.Revision > 1 {
+		previous, err := h.queries.GetSignalRoutingConfigRevision(r.Context(), database.GetSignalRoutingConfigRevisionParams{
+			SignalTypeID: signalType.ID,
+			Revision:     revision.Revision - 1,
+		})
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return apperrors.DatabaseError("database error", err)
+		}
+		if err == nil {
+			if previousConfig, err = unmarshalRevisionConfig(previous); err != nil {
+				return err
+			}
+		}
+	}
+
+	return responses.JSON(w, http.StatusOK, RoutingConfigRevisionDetails{
+		RoutingConfigRevision: RoutingConfigRevision{
+			Revision:             revision.Revision,
+			CreatedAt:            revision.CreatedAt,
+			AccountID:            revision.AccountID,
+			Action:               revision.Action,
+			RolledBackToRevision: revision.RolledBackToRevision,
+		},
+		SignalTypePath: fmt.Sprintf("%s/v%s", slug, semVer),
+		Config:         config,
+		Changes:        diffRoutingConfigs(previousConfig, config),
+	})
+}
+
+// RollbackSignalRoutingConfig godoc
+//
+//	@Summary		Roll Back Routing Config
+//
+//	@Description	Restores the routing config saved in an earlier revision.
+//	@Description
+//	@Description	The restored config is validated in the same way as a config supplied to the _Update Signals Routing Config_ endpoint
+//	@Description	(for example, the rollback fails if one of the ISNs used by the config has been deleted).
+//	@Description	The rollback is recorded as a new revision and the routing rules used by the signal router are reloaded.
+//	@Description
+//	@Description	Revisions that deleted the routing config can't be restored - use the _Delete Signals Routing Config_ endpoint instead.
+//
+//	@Tags			Signals Routing
+//
+//	@Param			signal_type_slug	path		string	true	"signal type slug"			example(sample-signal-type)
+//	@Param			sem_ver				path		string	true	"version"					example(1.0.0)
+//	@Param			revision			path		int		true	"revision number to restore"	example(1)
+//
+//	@Success		200					{object}	handlers.RoutingConfigRevision	"the new revision"
+//	@Failure		400					{object}	responses.ErrorResponse			"invalid_url_param | invalid_request | malformed_body"
+//	@Failure		404					{object}	responses.ErrorResponse			"resource_not_found"
+//	@Failure		500					{object}	responses.ErrorResponse			"database_error | internal_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/admin/signal-types/{signal_type_slug}/v{sem_ver}/routes/revisions/{revision}/rollback [post]
+//
+// Should only be used with RequireRole (siteadmin) middleware.
+func (h *RoutingConfigHandler) RollbackSignalRoutingConfig(w http.ResponseWriter, r *http.Request) error {
+	slug := r.PathValue("signal_type_slug")
+	semVer := r.PathValue("sem_ver")
+
+	accountID, ok := auth.ContextAccountID(r.Context())
+	if !ok {
+		return apperrors.InternalError("could not get accountID from context", nil)
+	}
+
+	signalType, err := h.getRevisionsSignalType(r.Context(), slug, semVer)
+	if err != nil {
+		return err
+	}
+
+	revision, config, err := h.getRevision(r, signalType)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return apperrors.InvalidRequest(fmt.Sprintf("revision %d deleted the routing config and can't be restored", revision.Revision), nil)
+	}
+
+	validated, err := h.validateRoutingConfigRequest(r.Context(), slug, semVer, config)
+	if err != nil {
+		return err
+	}
+
+	newRevision, err := h.saveRoutingConfig(r.Context(), accountID, validated, *config, signalsd.RoutingConfigRevisionRolledBack, &revision.Revision)
+	if err != nil {
+		return err
+	}
+
+	// refresh the cache for this instance straight away (the other instances reload the cache when they receive the invalidation notification)
+	if err := h.signalRouterCache.Load(r.Context()); err != nil {
+		logger.ContextWithLogAttrs(r.Context(), slog.String("router_cache_reload_error", err.Error()))
+	}
+
+	return responses.JSON(w, http.StatusOK, RoutingConfigRevision{
+		Revision:             newRevision.Revision,
+		CreatedAt:            newRevision.CreatedAt,
+		AccountID:            newRevision.AccountID,
+		Action:               newRevision.Action,
+		RolledBackToRevision: newRevision.RolledBackToRevision,
+	})
+}
+
+// getRevisionsSignalType returns the signal type for the revision endpoints (returns a not found error if the signal type does not exist)
+func (h *RoutingConfigHandler) getRevisionsSignalType(ctx context.Context, slug, semVer string) (database.SignalType, error) {
+	signalType, err := h.queries.GetSignalTypeBySlugAndVersion(ctx, database.GetSignalTypeBySlugAndVersionParams{
+		Slug:   slug,
+		SemVer: semVer,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.SignalType{}, apperrors.NotFound(fmt.Sprintf("signal type %s/v%s not found", slug, semVer), nil)
+		}
+		return database.SignalType{}, apperrors.DatabaseError("database error", err)
+	}
+	return signalType, nil
+}
+
+// getRevision returns the revision identified by the revision path param and the config saved in the revision (the config is nil for deletions)
+func (h *RoutingConfigHandler) getRevision(r *http.Request, signalType database.SignalType) (database.SignalRoutingConfigRevision, *UpdateSignalRoutingConfigRequest, error) {
+	revisionNumber, err := strconv.Atoi(r.PathValue("revision"))
+	if err != nil || revisionNumber < 1 {
+		return database.SignalRoutingConfigRevision{}, nil, apperrors.InvalidURLParam("revision must be a positive number", nil)
+	}
+
+	revision, err := h.queries.GetSignalRoutingConfigRevision(r.Context(), database.GetSignalRoutingConfigRevisionParams{
+		SignalTypeID: signalType.ID,
+		Revision:     int32(revisionNumber),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.SignalRoutingConfigRevision{}, nil, apperrors.NotFound(fmt.Sprintf("revision %d not found for %s/v%s", revisionNumber, signalType.Slug, signalType.SemVer), nil)
+		}
+		return database.SignalRoutingConfigRevision{}, nil, apperrors.DatabaseError("database error", err)
+	}
+
+	config, err := unmarshalRevisionConfig(revision)
+	if err != nil {
+		return database.SignalRoutingConfigRevision{}, nil, err
+	}
+	return revision, config, nil
+}
+
+// unmarshalRevisionConfig returns the routing config saved in a revision (nil when the revision deleted the config)
+func unmarshalRevisionConfig(revision database.SignalRoutingConfigRevision) (*UpdateSignalRoutingConfigRequest, error) {
+	if revision.Config == nil {
+		return nil, nil
+	}
+	var config UpdateSignalRoutingConfigRequest
+	if err := json.Unmarshal(revision.Config, &config); err != nil {
+		return nil, apperrors.InternalError("could not unmarshal routing config revision", err)
+	}
+	return &config, nil
+}
+
+// diffRoutingConfigs lists the changes between two routing configs (a nil config is treated as an empty config).
+// Rules are matched by sequence number and are listed in sequence order after the config level changes.
+func diffRoutingConfigs(previous, current *UpdateSignalRoutingConfigRequest) []RoutingConfigChange {
+	if previous == nil {
+		previous = &UpdateSignalRoutingConfigRequest{}
+	}
+	if current == nil {
+		current = &UpdateSignalRoutingConfigRequest{}
+	}
+
+	changes := []RoutingConfigChange{}
+	diffField := func(field, previousValue, newValue string) {
+		switch {
+		case previousValue == newValue:
+			return
+		case previousValue == "":
+			changes = append(changes, RoutingConfigChange{Field: field, Change: "added", New: newValue})
+		case newValue == "":
+			changes = append(changes, RoutingConfigChange{Field: field, Change: "removed", Previous: previousValue})
+		default:
+			changes = append(changes, RoutingConfigChange{Field: field, Change: "changed", Previous: previousValue, New: newValue})
+		}
+	}
+	diffField("routing_field", previous.RoutingField, current.RoutingField)
+	diffField("routing_mode", previous.RoutingMode, current.RoutingMode)
+	diffField("fallback_isn_slug", previous.FallbackIsnSlug, current.FallbackIsnSlug)
+
+	previousRules := make(map[int32]SignalRoutingRule, len(previous.RoutingRules))
+	currentRules := make(map[int32]SignalRoutingRule, len(current.RoutingRules))
+	var sequences []int32
+	for _, rule := range previous.RoutingRules {
+		previousRules[rule.Sequence] = rule
+		sequences = append(sequences, rule.Sequence)
+	}
+	for _, rule := range current.RoutingRules {
+		currentRules[rule.Sequence] = rule
+		if _, ok := previousRules[rule.Sequence]; !ok {
+			sequences = append(sequences, rule.Sequence)
+		}
+	}
+	slices.Sort(sequences)
+
+	for _, sequence := range sequences {
+		previousRule, inPrevious := previousRules[sequence]
+		currentRule, inCurrent := currentRules[sequence]
+		change := RoutingConfigChange{Field: "routing_rule", Sequence: &sequence}
+		switch {
+		case !inCurrent:
+			change.Change = "removed"
+			change.Previous = previousRule
+		case !inPrevious:
+			change.Change = "added"
+			change.New = currentRule
+		case reflect.DeepEqual(previousRule, currentRule):
+			continue
+		default:
+			change.Change = "changed"
+			change.Previous = previousRule
+			change.New = currentRule
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
@@ -0,0 +1,309 @@
+package handlers
+
+// the signal store is shared by the signal submission endpoints (standard, bulk and router submissions), the ingestion workers that
+// process queued submissions and the re-routing of unroutable signals.
+//
+// the callers check the account's permissions and resolve the target ISN and signal type of each signal - the store validates
+// the signals, stores them and records the outcome of each signal against the batch.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	"github.com/information-sharing-networks/signalsd/app/internal/schemas"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// signalStore holds the dependencies used to store signals
+type signalStore struct {
+	queries     *database.Queries
+	pool        *pgxpool.Pool
+	schemaCache *schemas.Cache
+}
+
+// signalSubmission records the outcome of each signal in a submission request.
+// The results are grouped by ISN and signal type (in the order they first appear in the request).
+type signalSubmission struct {
+	signalStore signalStore
+	accountID   uuid.UUID
+	batch       database.UpsertSignalBatchRow
+	results     []*IsnResult
+	unroutable  []FailedSignal
+	summary     CreateSignalsSummary
+}
+
+// startSubmission starts the batch for a submission request (a new batch is started if the batch ref has not been received previously).
+// Note this is done outside the transactions used to store the signals - the batch is used to track failures, even if the signals can't be stored.
+func (st signalStore) startSubmission(ctx context.Context, accountID uuid.UUID, batchRef string, signalCount int) (*signalSubmission, error) {
+	batch, err := st.queries.UpsertSignalBatch(ctx, database.UpsertSignalBatchParams{
+		BatchRef:  batchRef,
+		AccountID: accountID,
+	})
+	if err != nil {
+		return nil, apperrors.DatabaseError("database error", err)
+	}
+
+	logger.ContextWithLogAttrs(ctx,
+		slog.String("batch_ref", batch.BatchRef),
+		slog.String("batch_id", batch.ID.String()),
+	)
+
+	return &signalSubmission{
+		signalStore: st,
+		accountID:   accountID,
+		batch:       batch,
+		results:     make([]*IsnResult, 0),
+		summary:     CreateSignalsSummary{TotalSubmitted: signalCount},
+	}, nil
+}
+
+// result returns the result for the ISN and signal type
+func (sub *signalSubmission) result(isnSlug, signalTypePath string) *IsnResult {
+	for _, result := range sub.results {
+		if result.IsnSlug == isnSlug && result.SignalTypePath == signalTypePath {
+			return result
+		}
+	}
+
+	result := &IsnResult{
+		IsnSlug:        isnSlug,
+		SignalTypePath: signalTypePath,
+		StoredSignals:  make([]StoredSignal, 0),
+		FailedSignals:  make([]FailedSignal, 0),
+	}
+	sub.results = append(sub.results, result)
+	return result
+}
+
+// reject records a signal that could not be stored in the ISN
+func (sub *signalSubmission) reject(ctx context.Context, isnSlug, signalTypeSlug, semVer string, failed FailedSignal) {
+	result := sub.result(isnSlug, fmt.Sprintf("%s/v%s", signalTypeSlug, semVer))
+	result.FailedSignals = append(result.FailedSignals, failed)
+	sub.summary.RejectedCount++
+
+	sub.recordFailure(ctx, &isnSlug, signalTypeSlug, semVer, failed)
+}
+
+// rejectUnroutable records a signal router submission that could not be routed to an ISN
+func (sub *signalSubmission) rejectUnroutable(ctx context.Context, signalTypeSlug, semVer string, failed FailedSignal) {
+	sub.unroutable = append(sub.unroutable, failed)
+	sub.summary.UnroutableCount++
+
+	sub.recordFailure(ctx, nil, signalTypeSlug, semVer, failed)
+}
+
+// recordFailure logs the failure in the signal_processing_failures table so it is reported by the batch status endpoint.
+// isnSlug is nil when the signal could not be routed to an ISN.
+func (sub *signalSubmission) recordFailure(ctx context.Context, isnSlug *string, signalTypeSlug, semVer string, failed FailedSignal) {
+	if _, err := sub.signalStore.queries.CreateSignalProcessingFailureDetail(ctx, database.CreateSignalProcessingFailureDetailParams{
+		SignalBatchID:    sub.batch.ID,
+		IsnSlug:          isnSlug,
+		SignalTypeSlug:   signalTypeSlug,
+		SignalTypeSemVer: semVer,
+		LocalRef:         failed.LocalRef,
+		ErrorCode:        failed.ErrorCode,
+		ErrorMessage:     failed.ErrorMessage,
+	}); err != nil {
+		// Log the error but don't fail the operation
+		logger.ContextWithLogAttrs(ctx,
+			slog.String("local_ref", failed.LocalRef),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// store validates the signal against the signal type schema and stores it in the ISN.
+//
+// The caller must check the account can write signals of the signal type to the ISN before calling this function.
+func (sub *signalSubmission) store(ctx context.Context, isnSlug, signalTypeSlug, semVer string, signal Signal) {
+	signalTypePath := fmt.Sprintf("%s/v%s", signalTypeSlug, semVer)
+
+	if err := sub.signalStore.schemaCache.ValidateSignal(ctx, sub.signalStore.queries, signalTypePath, signal.Content); err != nil {
+		sub.reject(ctx, isnSlug, signalTypeSlug, semVer, FailedSignal{
+			LocalRef:     signal.LocalRef,
+			ErrorCode:    string(apperrors.ErrCodeMalformedBody),
+			ErrorMessage: fmt.Sprintf("validation failed: %v", err),
+		})
+		return
+	}
+
+	stored, failed := sub.signalStore.storeSignal(ctx, sub.accountID, sub.batch.ID, isnSlug, signalTypeSlug, semVer, signal)
+	if failed != nil {
+		sub.reject(ctx, isnSlug, signalTypeSlug, semVer, *failed)
+		return
+	}
+
+	result := sub.result(isnSlug, signalTypePath)
+	result.StoredSignals = append(result.StoredSignals, stored)
+	sub.summary.StoredCount++
+	if stored.Unchanged {
+		sub.summary.UnchangedCount++
+	}
+}
+
+// complete returns the response status and body for the submission request
+func (sub *signalSubmission) complete(ctx context.Context) (int, SignalSubmissionResponse) {
+	// Log signal processing summary with failure details for debugging
+	if sub.summary.RejectedCount > 0 {
+		reqLogger := logger.ContextRequestLogger(ctx)
+		failureErrors := make(map[string]int) // Track error types for summary
+		for _, result := range sub.results {
+			for _, failed := range result.FailedSignals {
+				failureErrors[failed.ErrorMessage]++
+			}
+		}
+		reqLogger.Warn("Signal processing failures",
+			slog.Int("stored_count", sub.summary.StoredCount),
+			slog.Int("rejected_count", sub.summary.RejectedCount),
+			slog.Int("unique_errors", len(failureErrors)),
+		)
+		for errMsg, count := range failureErrors {
+			reqLogger.Warn("Failure type",
+				slog.String("error_message", errMsg),
+				slog.Int("count", count),
+			)
+		}
+	}
+
+	results := make([]IsnResult, 0, len(sub.results))
+	for _, result := range sub.results {
+		results = append(results, *result)
+	}
+
+	response := SignalSubmissionResponse{
+		BatchRef:          sub.batch.BatchRef,
+		AccountID:         sub.accountID,
+		Results:           results,
+		UnroutableSignals: sub.unroutable,
+		Summary:           sub.summary,
+	}
+
+	var httpStatus int
+	switch {
+	case sub.summary.StoredCount == 0:
+		// All signals failed
+		httpStatus = http.StatusUnprocessableEntity
+	case sub.summary.RejectedCount > 0 || sub.summary.UnroutableCount > 0:
+		// Partial success
+		httpStatus = http.StatusMultiStatus
+	default:
+		// All signals processed successfully
+		httpStatus = http.StatusOK
+	}
+
+	return httpStatus, response
+}
+
+// storeSignal stores a validated signal in its own transaction.
+// A new version is created unless the content is the same as the latest version of the signal.
+// Returns a FailedSignal when the signal could not be stored.
+func (st signalStore) storeSignal(ctx context.Context, accountID, batchID uuid.UUID, isnSlug, signalTypeSlug, semVer string, signal Signal) (StoredSignal, *FailedSignal) {
+	failure := func(errorCode apperrors.ErrorCode, errMsg string) (StoredSignal, *FailedSignal) {
+		return StoredSignal{}, &FailedSignal{LocalRef: signal.LocalRef, ErrorCode: string(errorCode), ErrorMessage: errMsg}
+	}
+
+	// resubmitted signals with unchanged content do not create a new version
+	unchanged, err := getUnchangedSignal(ctx, st.queries, accountID, isnSlug, signalTypeSlug, semVer, signal)
+	if err != nil {
+		return failure(apperrors.ErrCodeDatabaseError, fmt.Sprintf("failed to check for unchanged signal: %v", err))
+	}
+	if unchanged != nil {
+		return *unchanged, nil
+	}
+
+	tx, err := st.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return failure(apperrors.ErrCodeDatabaseError, "failed to begin transaction")
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logger.ContextWithLogAttrs(ctx,
+				slog.String("rollback_error", err.Error()),
+			)
+		}
+	}()
+
+	txQueries := st.queries.WithTx(tx)
+
+	// Create the signal master record
+	var signalID uuid.UUID
+	var signalErr error
+	if signal.CorrelationID == nil {
+		signalID, signalErr = txQueries.CreateSignal(ctx, database.CreateSignalParams{
+			AccountID:      accountID,
+			LocalRef:       signal.LocalRef,
+			IsnSlug:        isnSlug,
+			SignalTypeSlug: signalTypeSlug,
+			SemVer:         semVer,
+		})
+	} else {
+		// Validate that correlation_id references a valid signal in the same ISN
+		isValid, err := txQueries.ValidateCorrelationID(ctx, database.ValidateCorrelationIDParams{
+			CorrelationID: *signal.CorrelationID,
+			IsnSlug:       isnSlug,
+		})
+		if err != nil {
+			return failure(apperrors.ErrCodeInternalError, "Failed to validate correlation_id")
+		}
+		if !isValid {
+			return failure(apperrors.ErrCodeInvalidCorrelationID, fmt.Sprintf("invalid correlation_id %v - signal does not exist in this ISN", signal.CorrelationID))
+		}
+
+		signalID, signalErr = txQueries.CreateOrUpdateSignalWithCorrelationID(ctx, database.CreateOrUpdateSignalWithCorrelationIDParams{
+			AccountID:      accountID,
+			LocalRef:       signal.LocalRef,
+			CorrelationID:  *signal.CorrelationID,
+			IsnSlug:        isnSlug,
+			SignalTypeSlug: signalTypeSlug,
+			SemVer:         semVer,
+		})
+	}
+	if signalErr != nil {
+		// The insert checks that the signal type is enabled for the ISN - if not it returns ErrNoRows
+		if errors.Is(signalErr, pgx.ErrNoRows) {
+			return failure(apperrors.ErrCodeMalformedBody, "failed to create signal master record - no rows affected (possibly the signal type was disabled afer the acccess token was issued)")
+		}
+		return failure(apperrors.ErrCodeMalformedBody, fmt.Sprintf("failed to create signal master record: %v", signalErr))
+	}
+
+	// Signal creation succeeded, now create the signal_version entry
+	versionResult, err := txQueries.CreateSignalVersion(ctx, database.CreateSignalVersionParams{
+		AccountID:      accountID,
+		SignalBatchID:  batchID,
+		Content:        signal.Content,
+		LocalRef:       signal.LocalRef,
+		SignalTypeSlug: signalTypeSlug,
+		SemVer:         semVer,
+		IsnSlug:        isnSlug,
+	})
+	if err != nil {
+		return failure(apperrors.ErrCodeDatabaseError, fmt.Sprintf("failed to create signal version: %v", err))
+	}
+
+	// queue the new version for delivery to webhook subscribers (committed with the signal version)
+	if _, err := txQueries.CreateWebhookDeliveries(ctx, database.CreateWebhookDeliveriesParams{
+		SignalVersionID: versionResult.ID,
+		AccountID:       accountID,
+	}); err != nil {
+		return failure(apperrors.ErrCodeDatabaseError, fmt.Sprintf("failed to queue webhook deliveries: %v", err))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return failure(apperrors.ErrCodeDatabaseError, "failed to commit transaction")
+	}
+
+	return StoredSignal{
+		LocalRef:        signal.LocalRef,
+		SignalID:        signalID,
+		SignalVersionID: versionResult.ID,
+		VersionNumber:   versionResult.VersionNumber,
+	}, nil
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+)
+
+// streamPosition identifies the last row sent to a client of the signal stream or changes feed.
+// Rows are returned in (tx_id, id) order - see the GetSignalVersionsAfterPosition and GetSignalChanges queries.
+// The id is the signal version id (or the status change id for withdrawals in the changes feed).
+type streamPosition struct {
+	txID int64
+	id   uuid.UUID
+}
+
+// String returns the position as an SSE event id ("<tx_id>-<id>")
+func (p streamPosition) String() string {
+	return fmt.Sprintf("%d-%s", p.txID, p.id)
+}
+
+// parseStreamPosition parses the SSE event id supplied in the Last-Event-ID header
+func parseStreamPosition(eventID string) (streamPosition, error) {
+	txIDPart, idPart, found := strings.Cut(eventID, "-")
+	if !found {
+		return streamPosition{}, fmt.Errorf("event id %q is not in the expected format", eventID)
+	}
+
+	txID, err := strconv.ParseInt(txIDPart, 10, 64)
+	if err != nil || txID < 0 {
+		return streamPosition{}, fmt.Errorf("event id %q is not in the expected format", eventID)
+	}
+
+	id, err := uuid.Parse(idPart)
+	if err != nil {
+		return streamPosition{}, fmt.Errorf("event id %q is not in the expected format", eventID)
+	}
+
+	return streamPosition{txID: txID, id: id}, nil
+}
+
+// StreamSignals godoc
+//
+//	@Summary		Signal stream (server-sent events)
+//	@Tags			Signal Exchange
+//
+//	@Description	Opens a long-lived [server-sent events](https://html.spec.whatwg.org/multipage/server-sent-events.html) connection and sends each new signal version as it is stored.
+//	@Description
+//	@Description	Each event contains a single signal version, in the same format as the signal search response:
+//	@Description	```
+//	@Description	id: 4711-0198f2a5-7a1c-7c3e-9b7d-5d0e6f1b2c3d
+//	@Description	data: {"account_id":"...","signal_id":"...","local_ref":"item_id_#1","version_number":1,...,"content":{...}}
+//	@Description	```
+//	@Description	Comment lines (starting with ':') are sent periodically to keep the connection open and should be ignored.
+//	@Description
+//	@Description	**Resuming the stream**
+//	@Description
+//	@Description	When a client reconnects it should supply the id of the last event it received in the `Last-Event-ID` header
+//	@Description	(browser EventSource clients do this automatically).
+//	@Description	The stream resumes from that point, so no signal versions are missed while the client was disconnected.
+//	@Description	When the header is not supplied, the stream starts with the signal versions stored after the connection was opened.
+//	@Description
+//	@Description	The stream is closed when the access token expires - reconnect with a new access token and the `Last-Event-ID` header to resume.
+//	@Description
+//	@Description	Any ISN member can open the stream. Accounts that only have write permission receive only the signals they created.
+//
+//	@Param			isn_slug			path		string	true	"ISN slug"				example(sample-isn--example-org)
+//	@Param			signal_type_slug	path		string	true	"Signal type slug"		example(sample-signal--example-org)
+//	@Param			sem_ver				path		string	true	"Signal type version"	example(0.0.1)
+//	@Param			Last-Event-ID		header		string	false	"id of the last event received (used to resume the stream)"
+//
+//	@Success		200					{object}	handlers.SearchSignal	"text/event-stream - one event per signal version"
+//	@Failure		400					{object}	responses.ErrorResponse	"invalid_request"
+//	@Failure		401					{object}	responses.ErrorResponse	"authentication_error"
+//	@Failure		403					{object}	responses.ErrorResponse	"forbidden"
+//	@Failure		500					{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/signals/stream [get]
+//
+// This function should be called after the RequireIsnMembership middleware has checked the account is a member of the ISN
+// (the middleware also checks the isn and signal type are in use)
+func (s *SignalsHandler) StreamSignals(w http.ResponseWriter, r *http.Request) error {
+	isnSlug := r.PathValue("isn_slug")
+	signalTypeSlug := r.PathValue("signal_type_slug")
+	semVer := r.PathValue("sem_ver")
+
+	claims, ok := auth.ContextClaims(r.Context())
+	if !ok {
+		return apperrors.AuthenticationFailure("authentication required for private ISN access", nil)
+	}
+
+	accountID, ok := auth.ContextAccountID(r.Context())
+	if !ok {
+		return apperrors.InternalError("could not get accountID from context", nil)
+	}
+
+	// Write-only accounts can only see signals they created
+	isnPerms := claims.IsnPerms[isnSlug]
+	writeOnly := !isnPerms.CanRead && isnPerms.CanWrite
+
+	var position streamPosition
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		var err error
+		position, err = parseStreamPosition(lastEventID)
+		if err != nil {
+			return apperrors.InvalidRequest("invalid Last-Event-ID header", err)
+		}
+	} else {
+		txID, err := s.queries.GetSignalStreamStartPosition(r.Context())
+		if err != nil {
+			return apperrors.DatabaseError("database error", err)
+		}
+		position = streamPosition{txID: txID, id: uuid.Nil}
+	}
+
+	// the connection is held open for longer than the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		return apperrors.InternalError("streaming is not supported", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable response buffering in nginx
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", signalsd.SignalStreamRetry.Milliseconds()); err != nil {
+		return nil
+	}
+	if err := rc.Flush(); err != nil {
+		return nil
+	}
+
+	// close the stream when the access token expires - clients reconnect with a new token
+	var tokenExpired <-chan time.Time
+	if claims.ExpiresAt != nil {
+		expiryTimer := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+		defer expiryTimer.Stop()
+		tokenExpired = expiryTimer.C
+	}
+
+	pollTicker := time.NewTicker(signalsd.SignalStreamPollInterval)
+	defer pollTicker.Stop()
+
+	heartbeatTicker := time.NewTicker(signalsd.SignalStreamHeartbeatInterval)
+	defer heartbeatTicker.Stop()
+
+	// the response has started, so errors are logged rather than returned
+	reqLogger := logger.ContextRequestLogger(r.Context())
+
+	for {
+		var err error
+		position, err = s.sendNewSignalVersions(r.Context(), w, rc, database.GetSignalVersionsAfterPositionParams{
+			IsnSlug:        isnSlug,
+			SignalTypeSlug: signalTypeSlug,
+			SemVer:         semVer,
+		}, position, writeOnly, accountID)
+		if err != nil {
+			if r.Context().Err() == nil {
+				reqLogger.Error("signal stream closed", slog.String("error", err.Error()))
+			}
+			return nil
+		}
+
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-tokenExpired:
+			return nil
+		case <-heartbeatTicker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			if err := rc.Flush(); err != nil {
+				return nil
+			}
+		case <-pollTicker.C:
+		}
+	}
+}
+
+// sendNewSignalVersions writes an event for each signal version stored after the supplied position and returns the new position.
+// Versions are read in batches until there are no more to send.
+func (s *SignalsHandler) sendNewSignalVersions(ctx context.Context, w http.ResponseWriter, rc *http.ResponseController, params database.GetSignalVersionsAfterPositionParams, position streamPosition, writeOnly bool, accountID uuid.UUID) (streamPosition, error) {
+	params.RowLimit = signalsd.SignalStreamBatchSize
+
+	for {
+		params.AfterTxID = position.txID
+		params.AfterSignalVersionID = position.id
+
+		rows, err := s.queries.GetSignalVersionsAfterPosition(ctx, params)
+		if err != nil {
+			return position, fmt.Errorf("could not get signal versions: %v", err)
+		}
+
+		for _, row := range rows {
+			position = streamPosition{txID: row.TxID, id: row.SignalVersionID}
+
+			if writeOnly && row.AccountID != accountID {
+				continue
+			}
+
+			data, err := json.Marshal(SearchSignal{
+				AccountID:            row.AccountID,
+				AccountType:          row.AccountType,
+				Email:                row.Email,
+				SignalID:             row.SignalID,
+				LocalRef:             row.LocalRef,
+				SignalCreatedAt:      row.SignalCreatedAt,
+				SignalVersionID:      row.SignalVersionID,
+				VersionNumber:        row.VersionNumber,
+				VersionCreatedAt:     row.VersionCreatedAt,
+				CorrelatedToSignalID: row.CorrelatedToSignalID,
+				IsWithdrawn:          row.IsWithdrawn,
+				Content:              row.Content,
+			})
+			if err != nil {
+				return position, fmt.Errorf("could not marshal signal: %v", err)
+			}
+
+			if _, err := fmt.Fprintf(w, "id: %s\ndata: %s\n\n", position, data); err != nil {
+				return position, err
+			}
+		}
+
+		if len(rows) > 0 {
+			if err := rc.Flush(); err != nil {
+				return position, err
+			}
+		}
+
+		if len(rows) < signalsd.SignalStreamBatchSize {
+			return position, nil
+		}
+	}
+}
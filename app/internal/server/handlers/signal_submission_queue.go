@@ -0,0 +1,179 @@
+package handlers
+
+// async signal submissions.
+// clients can include a Prefer: respond-async header with requests to the Submit Signals and Signal Router endpoints.
+// the request is validated and queued, and a 202 Accepted response is returned without waiting for the signals to be stored.
+// the queued submissions are processed by the ingestion workers (see the ingestion package) using the same code as synchronous requests.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/responses"
+	"github.com/information-sharing-networks/signalsd/app/internal/router"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+)
+
+// QueuedSignalSubmissionResponse is returned when a signal submission is queued for async processing
+type QueuedSignalSubmissionResponse struct {
+	SubmissionID uuid.UUID `json:"submission_id" example:"0198e3a4-5b6c-7d8e-9f01-234567890abc"`
+	BatchRef     string    `json:"batch_ref" example:"daily-sync-2026-04-02"`
+	AccountID    uuid.UUID `json:"account_id" example:"a38c99ed-c75c-4a4a-a901-c9485cf93cf3"`
+	Status       string    `json:"status" example:"queued"`
+	SignalCount  int       `json:"signal_count" example:"100"`
+	StatusURL    string    `json:"status_url" example:"/api/batches/daily-sync-2026-04-02/status"`
+}
+
+// prefersAsync returns true if the request includes a Prefer: respond-async header
+func prefersAsync(r *http.Request) bool {
+	for _, header := range r.Header.Values(signalsd.PreferHeader) {
+		for preference := range strings.SplitSeq(header, ",") {
+			// preferences can have parameters (e.g. respond-async; wait=10) - these are ignored
+			token, _, _ := strings.Cut(preference, ";")
+			if strings.EqualFold(strings.TrimSpace(token), signalsd.PreferRespondAsync) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// queueSignalSubmission stores a validated signal submission request in the queue for processing by the ingestion workers.
+//
+// The batch is started before the request is queued so that the batch status endpoint can be used to track the submission straight away.
+// isnSlug should be nil for requests made using the signal router and routingContext should only be supplied for requests made using the signal router.
+func queueSignalSubmission(ctx context.Context, queries *database.Queries, claims *auth.Claims, routingContext *router.RoutingContext, isnSlug *string, signalTypeSlug, semVer string, req CreateSignalsRequest) (QueuedSignalSubmissionResponse, error) {
+	batch, err := queries.UpsertSignalBatch(ctx, database.UpsertSignalBatchParams{
+		BatchRef:  req.BatchRef,
+		AccountID: claims.AccountID,
+	})
+	if err != nil {
+		return QueuedSignalSubmissionResponse{}, apperrors.DatabaseError("database error", err)
+	}
+
+	// the claims are stored with the request so the workers can apply the access token scope -
+	// the account's permissions are reloaded when the submission is processed (see ProcessSubmission)
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return QueuedSignalSubmissionResponse{}, apperrors.InternalError("could not marshal claims", err)
+	}
+
+	requestBody, err := json.Marshal(req)
+	if err != nil {
+		return QueuedSignalSubmissionResponse{}, apperrors.InternalError("could not marshal request", err)
+	}
+
+	// the routing context is stored so that routing rules using the sender or request headers are applied in the same way as a synchronous request
+	var routingContextJSON []byte
+	if routingContext != nil {
+		if routingContextJSON, err = json.Marshal(routingContext); err != nil {
+			return QueuedSignalSubmissionResponse{}, apperrors.InternalError("could not marshal routing context", err)
+		}
+	}
+
+	submission, err := queries.CreateQueuedSignalSubmission(ctx, database.CreateQueuedSignalSubmissionParams{
+		AccountID:      claims.AccountID,
+		SignalBatchID:  batch.ID,
+		IsnSlug:        isnSlug,
+		SignalTypeSlug: signalTypeSlug,
+		SemVer:         semVer,
+		Claims:         claimsJSON,
+		RequestBody:    requestBody,
+		RoutingContext: routingContextJSON,
+	})
+	if err != nil {
+		return QueuedSignalSubmissionResponse{}, apperrors.DatabaseError("database error", err)
+	}
+
+	return QueuedSignalSubmissionResponse{
+		SubmissionID: submission.ID,
+		BatchRef:     batch.BatchRef,
+		AccountID:    claims.AccountID,
+		Status:       "queued",
+		SignalCount:  len(req.Signals),
+		StatusURL:    fmt.Sprintf("/api/batches/%s/status", batch.BatchRef),
+	}, nil
+}
+
+// writeQueuedSubmissionResponse writes the 202 Accepted response for a queued submission.
+func writeQueuedSubmissionResponse(w http.ResponseWriter, response QueuedSignalSubmissionResponse) error {
+	w.Header().Set(signalsd.PreferenceAppliedHeader, signalsd.PreferRespondAsync)
+	w.Header().Set("Location", response.StatusURL)
+	return responses.JSON(w, http.StatusAccepted, response)
+}
+
+// SignalSubmissionProcessor processes the queued signal submissions on behalf of the ingestion workers.
+// Submissions to an ISN are processed by the SignalsHandler and submissions made using the signal router are processed by the SignalRouter.
+type SignalSubmissionProcessor struct {
+	authService *auth.AuthService
+	signals     *SignalsHandler
+	router      *SignalRouter
+}
+
+func NewSignalSubmissionProcessor(authService *auth.AuthService, signals *SignalsHandler, router *SignalRouter) *SignalSubmissionProcessor {
+	return &SignalSubmissionProcessor{
+		authService: authService,
+		signals:     signals,
+		router:      router,
+	}
+}
+
+// ProcessSubmission stores the signals in a queued submission and returns the response that would have been returned to a synchronous request.
+//
+// The account's permissions are reloaded before the signals are stored, so access revoked (or an account disabled) after the request was queued is applied.
+// Submissions that are no longer permitted are completed with the error response a synchronous request would receive.
+func (p *SignalSubmissionProcessor) ProcessSubmission(ctx context.Context, submission database.SignalSubmissionQueue) (int, any, error) {
+	var queuedClaims auth.Claims
+	if err := json.Unmarshal(submission.Claims, &queuedClaims); err != nil {
+		return 0, nil, fmt.Errorf("could not unmarshal claims: %v", err)
+	}
+
+	claims, err := p.authService.CurrentClaims(ctx, submission.AccountID, queuedClaims.Scope)
+	if err != nil {
+		if errors.Is(err, auth.ErrAccountInactive) {
+			return rejectedSubmission(apperrors.Forbidden("account is disabled", err))
+		}
+		return 0, nil, fmt.Errorf("could not load the account permissions: %v", err)
+	}
+
+	var req CreateSignalsRequest
+	if err := json.Unmarshal(submission.RequestBody, &req); err != nil {
+		return 0, nil, fmt.Errorf("could not unmarshal request: %v", err)
+	}
+
+	if submission.IsnSlug == nil {
+		routingContext, err := storedRoutingContext(ctx, p.router.queries, claims, submission.RoutingContext)
+		if err != nil {
+			return 0, nil, err
+		}
+		// the router checks the account's write permission on each resolved ISN
+		return p.router.processRoutedSignals(ctx, claims, routingContext, submission.SignalTypeSlug, submission.SemVer, req)
+	}
+
+	signalTypePath := fmt.Sprintf("%s/v%s", submission.SignalTypeSlug, submission.SemVer)
+	if err := auth.CheckIsnWritePermission(claims, *submission.IsnSlug, signalTypePath); err != nil {
+		var httpErr *apperrors.HTTPError
+		if errors.As(err, &httpErr) {
+			return rejectedSubmission(httpErr)
+		}
+		return 0, nil, err
+	}
+
+	return p.signals.processSignals(ctx, claims, *submission.IsnSlug, submission.SignalTypeSlug, submission.SemVer, req)
+}
+
+// rejectedSubmission returns the status and error response for a queued submission that can no longer be processed.
+func rejectedSubmission(err *apperrors.HTTPError) (int, any, error) {
+	return err.Status, responses.ErrorResponse{
+		ErrorCode: err.Code,
+		Message:   err.Message,
+	}, nil
+}
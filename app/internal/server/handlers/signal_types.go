@@ -13,6 +13,7 @@ import (
 	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
 	"github.com/information-sharing-networks/signalsd/app/internal/auth"
 	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/invalidation"
 	"github.com/information-sharing-networks/signalsd/app/internal/logger"
 	"github.com/information-sharing-networks/signalsd/app/internal/responses"
 	"github.com/information-sharing-networks/signalsd/app/internal/schemas"
@@ -244,6 +245,9 @@ func (s *SignalTypeHandler) CreateSignalType(w http.ResponseWriter, r *http.Requ
 		return apperrors.DatabaseError("database error", err)
 	}
 
+	// other instances need the new schema to validate signals of this type
+	invalidation.Notify(r.Context(), s.queries, invalidation.SchemaCache)
+
 	return responses.JSON(w, http.StatusCreated, NewSignalTypeResponse{
 		Slug:   returnedSignalType.Slug,
 		SemVer: returnedSignalType.SemVer,
@@ -410,6 +414,9 @@ func (s *SignalTypeHandler) RegisterNewSignalTypeSchema(w http.ResponseWriter, r
 		return apperrors.DatabaseError("database error", err)
 	}
 
+	// other instances need the new schema to validate signals of this type
+	invalidation.Notify(r.Context(), s.queries, invalidation.SchemaCache)
+
 	return responses.JSON(w, http.StatusCreated, NewSignalTypeResponse{
 		Slug:   returnedSignalType.Slug,
 		SemVer: returnedSignalType.SemVer,
@@ -593,6 +600,9 @@ func (s *SignalTypeHandler) DeleteSignalType(w http.ResponseWriter, r *http.Requ
 		return apperrors.NotFound(fmt.Sprintf("Signal type %s/v%s not found", signalTypeSlug, semVer), nil)
 	}
 
+	// the routing config and ISN associations for the signal type are deleted with it
+	invalidation.Notify(r.Context(), s.queries, invalidation.SchemaCache, invalidation.RouterCache, invalidation.PublicIsnCache)
+
 	return responses.NoContent(w, http.StatusNoContent)
 }
 
@@ -802,6 +812,8 @@ func (s *SignalTypeHandler) AddSignalTypeToISN(w http.ResponseWriter, r *http.Re
 		return apperrors.DatabaseError("database error", err)
 	}
 
+	invalidation.Notify(r.Context(), s.queries, invalidation.PublicIsnCache)
+
 	w.WriteHeader(http.StatusNoContent)
 	return nil
 }
@@ -918,6 +930,8 @@ func (s *SignalTypeHandler) UpdateIsnSignalTypeStatus(w http.ResponseWriter, r *
 		return apperrors.NotFound("Signal type not available on this ISN", nil)
 	}
 
+	invalidation.Notify(r.Context(), s.queries, invalidation.PublicIsnCache)
+
 	w.WriteHeader(http.StatusNoContent)
 	return nil
 }
@@ -0,0 +1,263 @@
+package handlers
+
+// bulk withdrawal and reinstatement of signals.
+// signals can be selected by local ref, signal id or batch ref (e.g. to withdraw every signal in a bad batch sent by a partner).
+// accounts can change their own signals and ISN admins can change any signal in their ISN.
+// the acting account and the reason are recorded with each change in signal_events (see GetSignalEvents and the signal changes feed).
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	"github.com/information-sharing-networks/signalsd/app/internal/responses"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+)
+
+// SignalStatusChangeRequest selects the signals to withdraw or reinstate.
+// Supply exactly one of local_refs, signal_ids or batch_ref.
+type SignalStatusChangeRequest struct {
+	LocalRefs []string    `json:"local_refs,omitempty" example:"item_id_#1,item_id_#2"`
+	SignalIDs []uuid.UUID `json:"signal_ids,omitempty"`
+
+	// BatchRef selects every signal that has a version stored in the batch
+	BatchRef string `json:"batch_ref,omitempty" example:"daily-sync-2026-04-02"`
+
+	// AccountID is the account that owns the signals identified by local_refs or batch_ref.
+	// Defaults to your own account - only ISN admins can change signals owned by other accounts.
+	AccountID *uuid.UUID `json:"account_id,omitempty" example:"a38c99ed-c75c-4a4a-a901-c9485cf93cf3"`
+
+	// Reason is recorded with each change
+	Reason string `json:"reason" example:"partner sent the wrong batch"`
+}
+
+// SignalStatusChangeResponse lists the signals that were withdrawn or reinstated
+type SignalStatusChangeResponse struct {
+	Action         string          `json:"action" enums:"withdrawn,reinstated" example:"withdrawn"`
+	ChangedCount   int             `json:"changed_count" example:"2"`
+	ChangedSignals []ChangedSignal `json:"changed_signals"`
+
+	// NotChanged lists the requested local refs or signal ids that were not changed
+	// (the signal was not found, already had the requested status, or is owned by another account)
+	NotChanged []string `json:"not_changed,omitempty" example:"item_id_#3"`
+}
+
+// ChangedSignal is a signal that was withdrawn or reinstated
+type ChangedSignal struct {
+	SignalID  uuid.UUID `json:"signal_id" example:"b51faf05-aaed-4250-b334-2258ccdf1ff2"`
+	AccountID uuid.UUID `json:"account_id" example:"a38c99ed-c75c-4a4a-a901-c9485cf93cf3"`
+	LocalRef  string    `json:"local_ref" example:"item_id_#1"`
+}
+
+// BulkWithdrawSignals godoc
+//
+//	@Summary		Withdraw Signals in Bulk
+//	@Description	Withdraws the signals identified by a list of local refs, a list of signal IDs or a batch ref (every signal with a version stored in the batch).
+//	@Description
+//	@Description	Withdrawn signals are hidden from search results by default but remain in the database and can be reinstated using the _Reinstate Signals_ endpoint.
+//	@Description	A reason must be supplied - the reason and your account are recorded with each withdrawal and the withdrawals are included in the signal changes feed.
+//	@Description
+//	@Description	You can withdraw your own signals. ISN admins can withdraw any signal in the ISN
+//	@Description	(supply the `account_id` of the account that owns the signals when selecting them by local ref or batch ref).
+//	@Description
+//	@Description	Up to 10,000 local refs or signal IDs can be supplied in a request (there is no limit on the number of signals in a batch).
+//	@Description	Signals that are not found or are already withdrawn are listed in `not_changed`.
+//
+//	@Tags			Signal Exchange
+//
+//	@Param			isn_slug			path		string								true	"ISN slug"			example(sample-isn)
+//	@Param			signal_type_slug	path		string								true	"Signal type slug"	example(signal-type-1)
+//	@Param			sem_ver				path		string								true	"version"			example(1.0.0)
+//	@Param			request				body		handlers.SignalStatusChangeRequest	true	"signals to withdraw"
+//
+//	@Success		200					{object}	handlers.SignalStatusChangeResponse
+//	@Failure		400					{object}	responses.ErrorResponse	"malformed_body"
+//	@Failure		401					{object}	responses.ErrorResponse	"authentication_error"
+//	@Failure		403					{object}	responses.ErrorResponse	"forbidden"
+//	@Failure		500					{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/signals/bulk-withdraw [put]
+func (s *SignalsHandler) BulkWithdrawSignals(w http.ResponseWriter, r *http.Request) error {
+	return s.changeSignalsStatus(w, r, true)
+}
+
+// BulkReinstateSignals godoc
+//
+//	@Summary		Reinstate Signals
+//	@Description	Reinstates withdrawn signals identified by a list of local refs, a list of signal IDs or a batch ref (every signal with a version stored in the batch).
+//	@Description
+//	@Description	The latest version of each signal becomes visible again - no new version is created.
+//	@Description	A reason must be supplied - the reason and your account are recorded with each reinstatement and the reinstatements are included in the signal changes feed.
+//	@Description
+//	@Description	You can reinstate your own signals. ISN admins can reinstate any signal in the ISN
+//	@Description	(supply the `account_id` of the account that owns the signals when selecting them by local ref or batch ref).
+//	@Description
+//	@Description	Up to 10,000 local refs or signal IDs can be supplied in a request (there is no limit on the number of signals in a batch).
+//	@Description	Signals that are not found or are not withdrawn are listed in `not_changed`.
+//
+//	@Tags			Signal Exchange
+//
+//	@Param			isn_slug			path		string								true	"ISN slug"			example(sample-isn)
+//	@Param			signal_type_slug	path		string								true	"Signal type slug"	example(signal-type-1)
+//	@Param			sem_ver				path		string								true	"version"			example(1.0.0)
+//	@Param			request				body		handlers.SignalStatusChangeRequest	true	"signals to reinstate"
+//
+//	@Success		200					{object}	handlers.SignalStatusChangeResponse
+//	@Failure		400					{object}	responses.ErrorResponse	"malformed_body"
+//	@Failure		401					{object}	responses.ErrorResponse	"authentication_error"
+//	@Failure		403					{object}	responses.ErrorResponse	"forbidden"
+//	@Failure		500					{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/signals/bulk-reinstate [put]
+func (s *SignalsHandler) BulkReinstateSignals(w http.ResponseWriter, r *http.Request) error {
+	return s.changeSignalsStatus(w, r, false)
+}
+
+// changeSignalsStatus withdraws (isWithdrawn = true) or reinstates the signals selected in the request.
+//
+// Should only be used with RequireAccessPermission("write") middleware.
+func (s *SignalsHandler) changeSignalsStatus(w http.ResponseWriter, r *http.Request, isWithdrawn bool) error {
+	isnSlug := r.PathValue("isn_slug")
+	signalTypeSlug := r.PathValue("signal_type_slug")
+	semVer := r.PathValue("sem_ver")
+
+	claims, ok := auth.ContextClaims(r.Context())
+	if !ok {
+		return apperrors.InternalError("could not get claims from context", nil)
+	}
+
+	var req SignalStatusChangeRequest
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperrors.MalformedBody("invalid JSON body", err)
+	}
+
+	if err := validateSignalStatusChangeRequest(&req); err != nil {
+		return err
+	}
+
+	// accounts can only change their own signals - ISN admins can change any signal in the ISN
+	canAdminister := claims.IsnPerms[isnSlug].CanAdminister
+	if req.AccountID != nil && *req.AccountID != claims.AccountID && !canAdminister {
+		return apperrors.Forbidden("only ISN admins can change signals owned by other accounts", nil)
+	}
+
+	var ownerAccountID *uuid.UUID
+	switch {
+	case req.AccountID != nil:
+		ownerAccountID = req.AccountID
+	case len(req.SignalIDs) == 0 || !canAdminister:
+		// local refs and batch refs are only unique per account
+		ownerAccountID = &claims.AccountID
+	}
+
+	params := database.SetSignalsWithdrawnStatusParams{
+		IsnSlug:            isnSlug,
+		SignalTypeSlug:     signalTypeSlug,
+		SemVer:             semVer,
+		IsWithdrawn:        isWithdrawn,
+		OwnerAccountID:     ownerAccountID,
+		SignalIds:          []uuid.UUID{},
+		LocalRefs:          []string{},
+		ChangedByAccountID: claims.AccountID,
+		Reason:             req.Reason,
+	}
+	if req.SignalIDs != nil {
+		params.SignalIds = req.SignalIDs
+	}
+	if req.LocalRefs != nil {
+		params.LocalRefs = req.LocalRefs
+	}
+	if req.BatchRef != "" {
+		params.BatchRef = &req.BatchRef
+	}
+
+	rows, err := s.queries.SetSignalsWithdrawnStatus(r.Context(), params)
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	res := SignalStatusChangeResponse{
+		Action:         "reinstated",
+		ChangedCount:   len(rows),
+		ChangedSignals: make([]ChangedSignal, len(rows)),
+	}
+	if isWithdrawn {
+		res.Action = "withdrawn"
+	}
+
+	changedLocalRefs := make(map[string]bool, len(rows))
+	changedIDs := make(map[uuid.UUID]bool, len(rows))
+	for i, row := range rows {
+		res.ChangedSignals[i] = ChangedSignal{
+			SignalID:  row.SignalID,
+			AccountID: row.AccountID,
+			LocalRef:  row.LocalRef,
+		}
+		changedLocalRefs[row.LocalRef] = true
+		changedIDs[row.SignalID] = true
+	}
+	for _, localRef := range req.LocalRefs {
+		if !changedLocalRefs[localRef] {
+			res.NotChanged = append(res.NotChanged, localRef)
+		}
+	}
+	for _, id := range req.SignalIDs {
+		if !changedIDs[id] {
+			res.NotChanged = append(res.NotChanged, id.String())
+		}
+	}
+
+	logger.ContextWithLogAttrs(r.Context(),
+		slog.String("action", res.Action),
+		slog.Int("changed_count", res.ChangedCount),
+		slog.String("changed_by", claims.AccountID.String()),
+	)
+
+	return responses.JSON(w, http.StatusOK, res)
+}
+
+// validateSignalStatusChangeRequest checks exactly one selector is supplied and a reason is given
+func validateSignalStatusChangeRequest(req *SignalStatusChangeRequest) error {
+	selectors := 0
+	if len(req.LocalRefs) > 0 {
+		selectors++
+	}
+	if len(req.SignalIDs) > 0 {
+		selectors++
+	}
+	if req.BatchRef != "" {
+		selectors++
+	}
+	if selectors != 1 {
+		return apperrors.MalformedBody("you must supply exactly one of local_refs, signal_ids or batch_ref", nil)
+	}
+
+	if len(req.LocalRefs) > signalsd.SignalStatusChangeMaxSignals || len(req.SignalIDs) > signalsd.SignalStatusChangeMaxSignals {
+		return apperrors.MalformedBody(fmt.Sprintf("you can't supply more than %d local_refs or signal_ids in a request", signalsd.SignalStatusChangeMaxSignals), nil)
+	}
+
+	if len(req.SignalIDs) > 0 && req.AccountID != nil {
+		return apperrors.MalformedBody("account_id can only be used with local_refs or batch_ref", nil)
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return apperrors.MalformedBody("you must supply a reason", nil)
+	}
+	if len(req.Reason) > signalsd.SignalStatusChangeReasonMaxLength {
+		return apperrors.MalformedBody(fmt.Sprintf("reason can't be longer than %d characters", signalsd.SignalStatusChangeReasonMaxLength), nil)
+	}
+	return nil
+}
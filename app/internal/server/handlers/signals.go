@@ -2,12 +2,18 @@ package handlers
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"net/http"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +24,7 @@ import (
 	"github.com/information-sharing-networks/signalsd/app/internal/publicisns"
 	"github.com/information-sharing-networks/signalsd/app/internal/responses"
 	"github.com/information-sharing-networks/signalsd/app/internal/schemas"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
 	"github.com/information-sharing-networks/signalsd/app/internal/utils"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -28,6 +35,7 @@ type SignalsHandler struct {
 	pool           *pgxpool.Pool
 	schemaCache    *schemas.Cache
 	publicIsnCache *publicisns.Cache
+	store          signalStore
 }
 
 func NewSignalsHandler(queries *database.Queries, pool *pgxpool.Pool, schemaCache *schemas.Cache, publicIsnCache *publicisns.Cache) *SignalsHandler {
@@ -36,6 +44,7 @@ func NewSignalsHandler(queries *database.Queries, pool *pgxpool.Pool, schemaCach
 		pool:           pool,
 		schemaCache:    schemaCache,
 		publicIsnCache: publicIsnCache,
+		store:          signalStore{queries: queries, pool: pool, schemaCache: schemaCache},
 	}
 }
 
@@ -66,7 +75,7 @@ type CreateSignalsRequest struct {
 var batchRefRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,128}$`)
 
 // IsnResult holds stored and failed signals
-// This is also used by Signals Router (which can route to more than one ISN)
+// This is also used by Signals Router (which can route to more than one ISN) and by bulk submissions (one result per signal type)
 type IsnResult struct {
 
 	// IsnSlug is the target ISN
@@ -115,12 +124,19 @@ type StoredSignal struct {
 	// VersionNumber is the version created by the server
 	//(where the same localRef is received in subsequent loads the versionNumber is incremented)
 	VersionNumber int32 `json:"version_number" example:"1"`
+
+	// Unchanged is true when the content was the same as the latest version of the signal.
+	// No new version is created and the existing version is returned.
+	Unchanged bool `json:"unchanged" example:"false"`
 }
 
 type FailedSignal struct {
 	LocalRef     string `json:"local_ref" example:"item_id_#2"`
 	ErrorCode    string `json:"error_code" example:"validation_error"`
 	ErrorMessage string `json:"error_message" example:"field 'name' is required"`
+
+	// UnroutableSignalID is only included for signal router submissions that did not match any routing rule (the signal is stored for review by a site admin)
+	UnroutableSignalID *uuid.UUID `json:"unroutable_signal_id,omitempty" example:"0198e3a4-5b6c-7d8e-9f01-234567890abc"`
 }
 
 // CreateSignalsSummary is included in the response to summarise the outcome of the load
@@ -136,6 +152,9 @@ type CreateSignalsSummary struct {
 	// Rejected Count is the list of records rejected due to issues with the contents of the record
 	RejectedCount int `json:"rejected_count" example:"1"`
 
+	// UnchangedCount is the number of stored signals that were the same as the latest version of the signal (these are included in StoredCount)
+	UnchangedCount int `json:"unchanged_count" example:"0"`
+
 	// UnroutableCount is the count of signals sent to the Signal Router that could not be routed.
 	// (e.g because no routing rule matched the data, or the account lacks write permission to the resolved ISN)
 	//
@@ -143,9 +162,10 @@ type CreateSignalsSummary struct {
 	UnroutableCount int `json:"unroutable_count,omitempty" example:"1"`
 }
 
-// WithdrawSignalsRequest contains the local ref for the signal being withdrawn
+// WithdrawSignalsRequest contains the local ref for the signal being withdrawn and an optional reason for the withdrawal
 type WithdrawSignalRequest struct {
 	LocalRef *string `json:"local_ref,omitempty" example:"item_id_#1"`
+	Reason   *string `json:"reason,omitempty" example:"sent in error"`
 }
 
 // structs used by the search endpoint
@@ -161,6 +181,102 @@ type SearchParams struct {
 	includeWithdrawn              bool
 	includeCorrelated             bool
 	includePreviousSignalVersions bool
+	includeHistory                bool
+	limit                         int
+	orderDesc                     bool
+	pageToken                     *searchPageToken
+	contentFilters                []contentFilter
+	writeOnlyAccountID            *uuid.UUID // set when the requesting account can only see its own signals
+}
+
+// searchPageToken identifies the last signal returned in the previous page of search results (keyset pagination)
+type searchPageToken struct {
+	versionCreatedAt time.Time
+	signalVersionID  uuid.UUID
+}
+
+// encode returns the opaque page_token value ("<version_created_at as unix microseconds>-<signal_version_id>", base64 encoded)
+func (p searchPageToken) encode() string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d-%s", p.versionCreatedAt.UnixMicro(), p.signalVersionID))
+}
+
+// decodeSearchPageToken parses a page_token created by searchPageToken.encode
+func decodeSearchPageToken(pageToken string) (searchPageToken, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(pageToken)
+	if err != nil {
+		return searchPageToken{}, fmt.Errorf("page_token is not valid")
+	}
+
+	microsPart, signalVersionIDPart, found := strings.Cut(string(decoded), "-")
+	if !found {
+		return searchPageToken{}, fmt.Errorf("page_token is not valid")
+	}
+
+	micros, err := strconv.ParseInt(microsPart, 10, 64)
+	if err != nil {
+		return searchPageToken{}, fmt.Errorf("page_token is not valid")
+	}
+
+	signalVersionID, err := uuid.Parse(signalVersionIDPart)
+	if err != nil {
+		return searchPageToken{}, fmt.Errorf("page_token is not valid")
+	}
+
+	return searchPageToken{versionCreatedAt: time.UnixMicro(micros), signalVersionID: signalVersionID}, nil
+}
+
+// contentFilter is a search filter on a field in the signal content.
+// Filters are supplied as content.<field_path>=value or content.<field_path>__<operator>=value query params (see ValidContentFilterOperators)
+// and are passed to the database query as json.
+type contentFilter struct {
+	Path     []string `json:"path"`
+	Operator string   `json:"operator"`
+	Operands []string `json:"operands"` // the comma separated values for the in operator, otherwise a single value
+	IsNumber bool     `json:"is_number"`
+}
+
+// contentFilterNumberRegex matches operands that are compared numerically when the content field is a json number
+var contentFilterNumberRegex = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// parseContentFilter creates a filter from a content.<field_path>[__<operator>] query param
+func parseContentFilter(param, value string) (contentFilter, error) {
+	fieldPath := strings.TrimPrefix(param, "content.")
+	operator := "eq"
+
+	if i := strings.LastIndex(fieldPath, "__"); i != -1 {
+		operator = fieldPath[i+2:]
+		fieldPath = fieldPath[:i]
+		if !signalsd.ValidContentFilterOperators[operator] {
+			return contentFilter{}, fmt.Errorf("%s: unsupported operator %q", param, operator)
+		}
+	}
+
+	if fieldPath == "" {
+		return contentFilter{}, fmt.Errorf("%s: a field path is required (e.g. content.payload.portOfEntry)", param)
+	}
+
+	path := strings.Split(fieldPath, ".")
+	for _, seg := range path {
+		if seg == "" {
+			return contentFilter{}, fmt.Errorf("%s: empty path segment", param)
+		}
+		// array index access is not supported
+		if _, err := strconv.Atoi(seg); err == nil {
+			return contentFilter{}, fmt.Errorf("%s: field paths must not contain numeric segments", param)
+		}
+	}
+
+	filter := contentFilter{
+		Path:     path,
+		Operator: operator,
+		Operands: []string{value},
+	}
+	if operator == "in" {
+		filter.Operands = strings.Split(value, ",")
+	} else {
+		filter.IsNumber = contentFilterNumberRegex.MatchString(value)
+	}
+	return filter, nil
 }
 
 // search signals reponse
@@ -189,11 +305,27 @@ type PreviousSignalVersion struct {
 	Content         json.RawMessage `json:"content" swaggertype:"object"`
 }
 
+// optionally the search can return the lifecycle events of a signal (who created, updated, withdrew or reinstated it, when and why)
+type SignalHistoryEvent struct {
+	EventType string    `json:"event_type" enums:"created,version_created,withdrawn,reinstated,archived,unarchived" example:"withdrawn"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// SignalVersionID and VersionNumber are only supplied for created and version_created events
+	SignalVersionID *uuid.UUID `json:"signal_version_id,omitempty"`
+	VersionNumber   *int32     `json:"version_number,omitempty"`
+
+	// ActorAccountID is the account that made the change (not supplied when the change was made outside the API)
+	ActorAccountID *uuid.UUID `json:"actor_account_id,omitempty"`
+	ActorEmail     string     `json:"actor_email,omitempty"` // not included in public ISN searches
+	Reason         string     `json:"reason,omitempty" example:"sent in error"`
+}
+
 // optionally the search can return the signals correlated with a returned signal
 type SearchSignalWithCorrelationsAndVersions struct {
 	SearchSignal
 	CorrelatedSignals      []SearchSignal          `json:"correlated_signals,omitempty"`
 	PreviousSignalVersions []PreviousSignalVersion `json:"previous_signal_versions,omitempty"`
+	History                []SignalHistoryEvent    `json:"history,omitempty"`
 }
 
 type SearchSignalResponse struct {
@@ -209,6 +341,10 @@ func parseSearchParams(r *http.Request) (SearchParams, error) {
 		includeWithdrawn:              false,
 		includeCorrelated:             false,
 		includePreviousSignalVersions: false,
+		includeHistory:                false,
+		limit:                         signalsd.SignalSearchMaxPageSize,
+		orderDesc:                     false,
+		contentFilters:                []contentFilter{},
 	}
 
 	// account_id
@@ -266,6 +402,58 @@ func parseSearchParams(r *http.Request) (SearchParams, error) {
 	if includePreviousString := r.URL.Query().Get("include_previous_versions"); includePreviousString != "" {
 		searchParams.includePreviousSignalVersions = includePreviousString == "true"
 	}
+
+	// include_history
+	if includeHistoryString := r.URL.Query().Get("include_history"); includeHistoryString != "" {
+		searchParams.includeHistory = includeHistoryString == "true"
+	}
+
+	// limit
+	if limitString := r.URL.Query().Get("limit"); limitString != "" {
+		limit, err := strconv.Atoi(limitString)
+		if err != nil {
+			return searchParams, fmt.Errorf("limit is not a valid number")
+		}
+		searchParams.limit = limit
+	}
+
+	// order
+	if order := r.URL.Query().Get("order"); order != "" {
+		switch order {
+		case "asc":
+			searchParams.orderDesc = false
+		case "desc":
+			searchParams.orderDesc = true
+		default:
+			return searchParams, fmt.Errorf("order must be asc or desc")
+		}
+	}
+
+	// page_token
+	if pageTokenString := r.URL.Query().Get("page_token"); pageTokenString != "" {
+		pageToken, err := decodeSearchPageToken(pageTokenString)
+		if err != nil {
+			return searchParams, err
+		}
+		searchParams.pageToken = &pageToken
+	}
+
+	// content filters (params are sorted so the filters are applied in a consistent order)
+	for _, param := range slices.Sorted(maps.Keys(r.URL.Query())) {
+		if !strings.HasPrefix(param, "content.") {
+			continue
+		}
+		for _, value := range r.URL.Query()[param] {
+			filter, err := parseContentFilter(param, value)
+			if err != nil {
+				return searchParams, err
+			}
+			searchParams.contentFilters = append(searchParams.contentFilters, filter)
+		}
+	}
+	if len(searchParams.contentFilters) > signalsd.SignalSearchMaxContentFilters {
+		return searchParams, fmt.Errorf("no more than %d content filters can be used in a search", signalsd.SignalSearchMaxContentFilters)
+	}
 	return searchParams, nil
 }
 
@@ -280,14 +468,85 @@ func validateSearchParams(params SearchParams) error {
 	hasAccount := params.accountID != nil
 	hasSignalID := params.signalID != nil
 	hasLocalRef := params.localRef != nil
+	hasContentFilter := len(params.contentFilters) > 0
 
-	if !hasDateRange && !hasAccount && !hasSignalID && !hasLocalRef {
+	if !hasDateRange && !hasAccount && !hasSignalID && !hasLocalRef && !hasContentFilter {
 		return fmt.Errorf("you must supply a search parameter")
 	}
 
+	if params.limit < 1 || params.limit > signalsd.SignalSearchMaxPageSize {
+		return fmt.Errorf("limit must be between 1 and %d", signalsd.SignalSearchMaxPageSize)
+	}
+
 	return nil
 }
 
+// validateContentFilters checks that the field used in each content filter is defined in the schema for the signal type
+// (see schemas.Cache.FieldPathExistsInSchema for the limitations of this check)
+func (s *SignalsHandler) validateContentFilters(params SearchParams) error {
+	signalTypePath := fmt.Sprintf("%s/v%s", params.signalTypeSlug, params.semVer)
+
+	for _, filter := range params.contentFilters {
+		fieldPath := strings.Join(filter.Path, ".")
+
+		fieldExists, err := s.schemaCache.FieldPathExistsInSchema(signalTypePath, fieldPath)
+		if err != nil {
+			return apperrors.InternalError("schema cache error", err)
+		}
+		if !fieldExists {
+			return apperrors.InvalidURLParam(fmt.Sprintf("content filter field %q is not defined in the schema for %s", fieldPath, signalTypePath), nil)
+		}
+	}
+	return nil
+}
+
+// getSignalsParams returns the database query params for the search.
+// One more row than the page size is requested so that the handler can tell if there is a further page (see paginateSearchResults)
+func (params SearchParams) getSignalsParams() (database.GetSignalsWithOptionalFiltersParams, error) {
+	contentFilters, err := json.Marshal(params.contentFilters)
+	if err != nil {
+		return database.GetSignalsWithOptionalFiltersParams{}, fmt.Errorf("could not marshal content filters: %v", err)
+	}
+
+	queryParams := database.GetSignalsWithOptionalFiltersParams{
+		IsnSlug:            params.isnSlug,
+		SignalTypeSlug:     params.signalTypeSlug,
+		SemVer:             params.semVer,
+		StartDate:          params.startDate,
+		EndDate:            params.endDate,
+		AccountID:          params.accountID,
+		SignalID:           params.signalID,
+		LocalRef:           params.localRef,
+		IncludeWithdrawn:   &params.includeWithdrawn,
+		OrderDesc:          params.orderDesc,
+		ContentFilters:     contentFilters,
+		WriteOnlyAccountID: params.writeOnlyAccountID,
+		RowLimit:           int32(params.limit + 1),
+	}
+	if params.pageToken != nil {
+		queryParams.PageCreatedAt = &params.pageToken.versionCreatedAt
+		queryParams.PageSignalVersionID = &params.pageToken.signalVersionID
+	}
+	return queryParams, nil
+}
+
+// paginateSearchResults removes the extra row requested by getSignalsParams.
+// When there are more results, the page token for the next page is returned in the Signalsd-Next-Page-Token header.
+func paginateSearchResults(w http.ResponseWriter, rows []database.GetSignalsWithOptionalFiltersRow, limit int) []database.GetSignalsWithOptionalFiltersRow {
+	if len(rows) <= limit {
+		return rows
+	}
+
+	rows = rows[:limit]
+	last := rows[len(rows)-1]
+	nextPageToken := searchPageToken{
+		versionCreatedAt: last.VersionCreatedAt,
+		signalVersionID:  last.SignalVersionID,
+	}
+	w.Header().Set(signalsd.SignalSearchNextPageTokenHeader, nextPageToken.encode())
+	return rows
+}
+
 // getSignals version fetches all the previous versions for a set of signals and returns them as a map of signal_id to versions
 func (s *SignalsHandler) getPreviousSignalVersions(ctx context.Context, signalIDs []uuid.UUID) (map[uuid.UUID][]PreviousSignalVersion, error) {
 	if len(signalIDs) == 0 {
@@ -317,6 +576,67 @@ func (s *SignalsHandler) getPreviousSignalVersions(ctx context.Context, signalID
 	return result, nil
 }
 
+// getSignalHistory fetches the lifecycle events for a set of signals and returns them as a map of signal_id to events (oldest first).
+// The email address of the account that made each change is only included when includeEmails is true (it is not shown in public ISNs)
+func (s *SignalsHandler) getSignalHistory(ctx context.Context, signalIDs []uuid.UUID, includeEmails bool) (map[uuid.UUID][]SignalHistoryEvent, error) {
+	result := make(map[uuid.UUID][]SignalHistoryEvent)
+	if len(signalIDs) == 0 {
+		return result, nil
+	}
+
+	events, err := s.queries.GetSignalEvents(ctx, signalIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving signal events %v", err)
+	}
+
+	for _, event := range events {
+		historyEvent := SignalHistoryEvent{
+			EventType:       event.EventType,
+			CreatedAt:       event.CreatedAt,
+			SignalVersionID: event.SignalVersionID,
+			VersionNumber:   event.VersionNumber,
+			ActorAccountID:  event.ActorAccountID,
+		}
+		if includeEmails && event.ActorEmail != nil {
+			historyEvent.ActorEmail = *event.ActorEmail
+		}
+		if event.Reason != nil {
+			historyEvent.Reason = *event.Reason
+		}
+		result[event.SignalID] = append(result[event.SignalID], historyEvent)
+	}
+
+	return result, nil
+}
+
+// getUnchangedSignal returns the latest version of the signal when the submitted content is the same as the content of that version.
+// Returns nil when a new version should be created (new, withdrawn or changed signals).
+func getUnchangedSignal(ctx context.Context, queries *database.Queries, accountID uuid.UUID, isnSlug, signalTypeSlug, semVer string, signal Signal) (*StoredSignal, error) {
+	latest, err := queries.GetUnchangedSignalVersion(ctx, database.GetUnchangedSignalVersionParams{
+		AccountID:      accountID,
+		LocalRef:       signal.LocalRef,
+		IsnSlug:        isnSlug,
+		SignalTypeSlug: signalTypeSlug,
+		SemVer:         semVer,
+		CorrelationID:  signal.CorrelationID,
+		Content:        signal.Content,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &StoredSignal{
+		LocalRef:        signal.LocalRef,
+		SignalID:        latest.SignalID,
+		SignalVersionID: latest.SignalVersionID,
+		VersionNumber:   latest.VersionNumber,
+		Unchanged:       true,
+	}, nil
+}
+
 // getCorrelatedSignals fetches all signals that have a correlated_id that references one of the provided signal IDs - returns a map of signal_id to correlated signals
 func (s *SignalsHandler) getCorrelatedSignals(ctx context.Context, signalIDs []uuid.UUID, params SearchParams) (map[uuid.UUID][]SearchSignal, error) {
 	if len(signalIDs) == 0 {
@@ -407,6 +727,8 @@ func (s *SignalsHandler) getCorrelatedSignals(ctx context.Context, signalIDs []u
 //	@Description
 //	@Description	New versions are created when signals are resupplied using the same local_ref, e.g. because the client wants to correct a previously publsihed signal.
 //	@Description	If a signal has been withdrawn it will be reactivated if you resubmit it using the same local_ref.
+//	@Description	If the content of a resupplied signal is the same as the latest version (ignoring differences in json key order and whitespace) no new version is created:
+//	@Description	the existing version is returned in the response with `unchanged: true` (withdrawn signals are always reactivated with a new version).
 //	@Description
 //	@Description	**Correlating signals**
 //	@Description
@@ -415,19 +737,39 @@ func (s *SignalsHandler) getCorrelatedSignals(ctx context.Context, signalIDs []u
 //	@Description	If the supplied correlation_id is not found in the same ISN as the signal being submitted,
 //	@Description	the response will contain a 422 or 207 status code and the error_code for the failed signal will be `invalid_correlation_id`.
 //	@Description
+//	@Description	**Retrying requests**
+//	@Description
+//	@Description	Resubmitting a request processes every signal in it again (e.g. signals that have since been withdrawn are reactivated). To make retries safe (e.g. after a network timeout) supply a unique `Idempotency-Key` header with each request.
+//	@Description	When a request is repeated with the same key (within 24 hours) the original response is returned with an `Idempotent-Replayed: true` header and the signals are not processed again.
+//	@Description	Keys are unique per account. Using a key with a different request returns a 422 (`idempotency_key_reused`), and repeating a request while the original is still being processed returns a 409 (`idempotency_key_in_progress`).
+//	@Description
+//	@Description	**Async submissions**
+//	@Description
+//	@Description	Large payloads can be submitted without waiting for the signals to be stored by including a `Prefer: respond-async` header.
+//	@Description	The request format is checked (invalid requests are rejected with a 400 as normal) and the request is queued for processing in the background.
+//	@Description	The response is a 202 with a `Preference-Applied: respond-async` header and a `Location` header containing the URL of the *Get Batch Status* endpoint for the batch_ref.
+//	@Description	The outcome of the submission - the status code that would have been returned by a synchronous request and any failed signals - is reported by the batch status endpoint once the signals have been processed.
+//	@Description	Queued submissions are retried if they can't be processed (e.g. because the database is unavailable) and are reported as `failed` if the retries are exhausted.
 //
 //	@Param		isn_slug			path		string								true	"ISN slug"			example(sample-isn)
 //	@Param		signal_type_slug	path		string								true	"signal type slug"	example(sample-signal-type)
 //	@Param		sem_ver				path		string								true	"version"			example(1.0.0)
 //	@Param		request				body		handlers.CreateSignalsRequest		true	"create signals"
+//	@Param		Idempotency-Key		header		string								false	"unique key for the request - repeated requests with the same key return the original response"
+//	@Param		Prefer				header		string								false	"respond-async - queue the signals for processing and return a 202 without waiting for them to be stored"	Enums(respond-async)
 //
 //	@Success	200					{object}	handlers.SignalSubmissionResponse	"All signals processed successfully"
+//	@Success	202					{object}	handlers.QueuedSignalSubmissionResponse	"Request queued for async processing (Prefer: respond-async)"
 //	@Success	207					{object}	handlers.SignalSubmissionResponse	"Partial success - some signals succeeded, some failed"
 //	@Success	422					{object}	handlers.SignalSubmissionResponse	"Valid request format but all signals failed processing - returns detailed error information"
+//	@Header		200,202,207,422		{string}	Idempotent-Replayed					"true when the response is the original response to an earlier request with the same Idempotency-Key"
+//	@Header		202					{string}	Preference-Applied					"respond-async"
+//	@Header		202					{string}	Location							"batch status URL for the batch_ref"
 //	@Failure	400					{object}	responses.ErrorResponse				"malformed_body"
 //	@Failure	401					{object}	responses.ErrorResponse				"authentication_error"
 //	@Failure	403					{object}	responses.ErrorResponse				"forbidden"
 //	@Failure	404					{object}	responses.ErrorResponse				"resource_not_found"
+//	@Failure	409					{object}	responses.ErrorResponse				"idempotency_key_in_progress"
 //	@Failure	500					{object}	responses.ErrorResponse				"database_error | internal_error"
 //
 //	@Security	BearerAccessToken
@@ -445,7 +787,6 @@ func (s *SignalsHandler) CreateSignals(w http.ResponseWriter, r *http.Request) e
 	isnSlug := r.PathValue("isn_slug")
 	signalTypeSlug := r.PathValue("signal_type_slug")
 	semVer := r.PathValue("sem_ver")
-	signalTypePath := fmt.Sprintf("%v/v%v", signalTypeSlug, semVer)
 
 	claims, ok := auth.ContextClaims(r.Context())
 	if !ok {
@@ -459,14 +800,58 @@ func (s *SignalsHandler) CreateSignals(w http.ResponseWriter, r *http.Request) e
 
 	defer r.Body.Close()
 
+	// the body is read before it is unmarshalled so that it can be compared with earlier requests using the same idempotency key
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return apperrors.MalformedBody("could not read request body", err)
+	}
+
 	var req CreateSignalsRequest
 
 	// unmarshal the req body
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
 		return apperrors.MalformedBody("invalid JSON body", nil)
 	}
 
 	// check for mandatory fields in request
+	if err := validateCreateSignalsRequest(req); err != nil {
+		return err
+	}
+
+	// requests with an Idempotency-Key are only processed once - repeated requests receive the original response
+	idempotentReq, replayed, err := startIdempotentRequest(w, r, s.queries, accountID, body)
+	if err != nil {
+		return err
+	}
+	if replayed {
+		return nil
+	}
+	defer idempotentReq.release(r.Context())
+
+	// requests with a Prefer: respond-async header are queued and processed in the background by the ingestion workers
+	if prefersAsync(r) {
+		queuedResponse, err := queueSignalSubmission(r.Context(), s.queries, claims, nil, &isnSlug, signalTypeSlug, semVer, req)
+		if err != nil {
+			return err
+		}
+		idempotentReq.complete(r.Context(), http.StatusAccepted, queuedResponse)
+
+		return writeQueuedSubmissionResponse(w, queuedResponse)
+	}
+
+	httpStatus, createSignalsResponse, err := s.processSignals(r.Context(), claims, isnSlug, signalTypeSlug, semVer, req)
+	if err != nil {
+		return err
+	}
+
+	idempotentReq.complete(r.Context(), httpStatus, createSignalsResponse)
+
+	return responses.JSON(w, httpStatus, createSignalsResponse)
+}
+
+// validateCreateSignalsRequest checks the request contains the mandatory fields (batch_ref and, for each signal, local_ref and content).
+// Requests that fail these checks are rejected as a whole.
+func validateCreateSignalsRequest(req CreateSignalsRequest) error {
 	if req.BatchRef == "" {
 		return apperrors.MalformedBody("batch_ref is required", nil)
 	}
@@ -489,265 +874,29 @@ func (s *SignalsHandler) CreateSignals(w http.ResponseWriter, r *http.Request) e
 			return apperrors.MalformedBody(fmt.Sprintf("signal[%d] (local_ref=%q) is missing required field 'content'", i, signal.LocalRef), nil)
 		}
 	}
+	return nil
+}
 
-	// start the batch (a new batch is started if the batch ref has not been received previously)
-	// note this is done outside the main db transaction - the batch is used to track failures,
-	// even if the main signal load fails.
-	batch, err := s.queries.UpsertSignalBatch(r.Context(), database.UpsertSignalBatchParams{
-		BatchRef:  req.BatchRef,
-		AccountID: accountID,
-	})
+// processSignals stores the signals in a submission request and returns the response status and body.
+// It is used by CreateSignals and by the ingestion workers that process queued (async) submissions.
+//
+// The request must have been checked with validateCreateSignalsRequest and the account's write permission on the ISN checked before calling this function.
+// An error is only returned when the request as a whole could not be processed (failures for individual signals are reported in the response).
+func (s *SignalsHandler) processSignals(ctx context.Context, claims *auth.Claims, isnSlug, signalTypeSlug, semVer string, req CreateSignalsRequest) (int, SignalSubmissionResponse, error) {
+	submission, err := s.store.startSubmission(ctx, claims.AccountID, req.BatchRef, len(req.Signals))
 	if err != nil {
-		return apperrors.DatabaseError("database error", err)
+		return 0, SignalSubmissionResponse{}, err
 	}
 
-	logger.ContextWithLogAttrs(r.Context(),
-		slog.String("batch_ref", batch.BatchRef),
-		slog.String("batch_id", batch.ID.String()),
-	)
+	// the response always contains a result for the ISN, even if all the signals fail
+	submission.result(isnSlug, fmt.Sprintf("%s/v%s", signalTypeSlug, semVer))
 
-	// prepare response
-	result := IsnResult{
-		IsnSlug:        isnSlug,
-		SignalTypePath: signalTypePath,
-		StoredSignals:  make([]StoredSignal, 0),
-		FailedSignals:  make([]FailedSignal, 0),
-	}
-	createSignalsResponse := SignalSubmissionResponse{
-		BatchRef:  batch.BatchRef,
-		AccountID: accountID,
-		Summary:   CreateSignalsSummary{TotalSubmitted: len(req.Signals)},
-	}
-
-	// Validate all signals against schema - record validation failures
-	validSignals := make([]Signal, 0)
 	for _, signal := range req.Signals {
-		err = s.schemaCache.ValidateSignal(r.Context(), s.queries, signalTypePath, signal.Content)
-		if err != nil {
-			errMsg := fmt.Sprintf("validation failed: %v", err)
-			// Add to failed signals list
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
-				LocalRef:     signal.LocalRef,
-				ErrorCode:    string(apperrors.ErrCodeMalformedBody),
-				ErrorMessage: errMsg,
-			})
-		} else {
-			// Add to valid signals for processing
-			validSignals = append(validSignals, signal)
-		}
+		submission.store(ctx, isnSlug, signalTypeSlug, semVer, signal)
 	}
 
-	// Process each valid signal in its own transaction
-	for _, signal := range validSignals {
-		// Start a new transaction for this signal
-		tx, err := s.pool.BeginTx(r.Context(), pgx.TxOptions{})
-		if err != nil {
-			// If we can't start a transaction, record as failure and continue
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
-				LocalRef:     signal.LocalRef,
-				ErrorCode:    string(apperrors.ErrCodeMalformedBody),
-				ErrorMessage: "failed to begin transaction",
-			})
-			continue
-		}
-
-		// Create the signal master record
-		var signalErr error
-		var signalID uuid.UUID
-		if signal.CorrelationID == nil {
-			signalID, signalErr = s.queries.WithTx(tx).CreateSignal(r.Context(), database.CreateSignalParams{
-				AccountID:      claims.AccountID,
-				LocalRef:       signal.LocalRef,
-				IsnSlug:        isnSlug,
-				SignalTypeSlug: signalTypeSlug,
-				SemVer:         semVer,
-			})
-		} else {
-			// Validate that correlation_id references a valid signal in the same ISN
-			isValid, err := s.queries.WithTx(tx).ValidateCorrelationID(r.Context(), database.ValidateCorrelationIDParams{
-				CorrelationID: *signal.CorrelationID,
-				IsnSlug:       isnSlug,
-			})
-
-			if err != nil {
-				// roll back partial transaction
-				if rollbackErr := tx.Rollback(r.Context()); rollbackErr != nil {
-					logger.ContextWithLogAttrs(r.Context(),
-						slog.String("rollback_error", rollbackErr.Error()),
-					)
-
-				}
-				// log the failure
-				result.FailedSignals = append(result.FailedSignals, FailedSignal{
-					LocalRef:     signal.LocalRef,
-					ErrorCode:    string(apperrors.ErrCodeInternalError),
-					ErrorMessage: "Failed to validate correlation_id",
-				})
-				continue
-			}
-
-			if !isValid {
-				// rollback partial transaction
-				if rollbackErr := tx.Rollback(r.Context()); rollbackErr != nil {
-					logger.ContextWithLogAttrs(r.Context(),
-						slog.String("rollback_error", rollbackErr.Error()),
-					)
-				}
-				// log the failure
-				result.FailedSignals = append(result.FailedSignals, FailedSignal{
-					LocalRef:     signal.LocalRef,
-					ErrorCode:    string(apperrors.ErrCodeInvalidCorrelationID),
-					ErrorMessage: fmt.Sprintf("invalid correlation_id %v - signal does not exist in this ISN", signal.CorrelationID),
-				})
-				continue
-			}
-
-			// create correlated signal master record
-			signalID, signalErr = s.queries.WithTx(tx).CreateOrUpdateSignalWithCorrelationID(r.Context(), database.CreateOrUpdateSignalWithCorrelationIDParams{
-				AccountID:      claims.AccountID,
-				LocalRef:       signal.LocalRef,
-				CorrelationID:  *signal.CorrelationID,
-				IsnSlug:        isnSlug,
-				SignalTypeSlug: signalTypeSlug,
-				SemVer:         semVer,
-			})
-		}
-
-		// unexpected error creating signals master record
-		if signalErr != nil {
-			// Rollback this transaction
-			if err := tx.Rollback(r.Context()); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
-				// Log the error but don't try to respond since the request may have already timed out
-				logger.ContextWithLogAttrs(r.Context(),
-					slog.String("rollback_error", err.Error()),
-				)
-
-				continue
-			}
-
-			// The insert checks that the signal type is enabled for the ISN - if not it returns ErrNoRows
-			errMsg := fmt.Sprintf("failed to create signal master record: %v", signalErr)
-			if errors.Is(signalErr, pgx.ErrNoRows) {
-				errMsg = "failed to create signal master record - no rows affected (possibly the signal type was disabled afer the acccess token was issued)"
-			}
-
-			// record database errors in the failed signals array
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
-				LocalRef:     signal.LocalRef,
-				ErrorCode:    string(apperrors.ErrCodeMalformedBody),
-				ErrorMessage: errMsg,
-			})
-			continue
-		}
-
-		// Signal creation succeeded, now create the signal_version entry
-		versionResult, versionErr := s.queries.WithTx(tx).CreateSignalVersion(r.Context(), database.CreateSignalVersionParams{
-			AccountID:      claims.AccountID,
-			SignalBatchID:  batch.ID,
-			Content:        signal.Content,
-			LocalRef:       signal.LocalRef,
-			SignalTypeSlug: signalTypeSlug,
-			SemVer:         semVer,
-		})
-
-		if versionErr != nil {
-			// Rollback this transaction
-			if err := tx.Rollback(r.Context()); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
-				// Log the error but don't try to respond since the request may have already timed out
-				logger.ContextWithLogAttrs(r.Context(),
-					slog.String("rollback_error", err.Error()),
-				)
-
-			}
-
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
-				LocalRef:     signal.LocalRef,
-				ErrorCode:    string(apperrors.ErrCodeDatabaseError),
-				ErrorMessage: fmt.Sprintf("failed to create signal version: %v", versionErr),
-			})
-			continue
-		}
-
-		// commit this transaction
-		if err := tx.Commit(r.Context()); err != nil {
-			result.FailedSignals = append(result.FailedSignals, FailedSignal{
-				LocalRef:     signal.LocalRef,
-				ErrorCode:    string(apperrors.ErrCodeDatabaseError),
-				ErrorMessage: "failed to commit transaction",
-			})
-			continue
-		}
-
-		// Success - add to stored signals array
-		result.StoredSignals = append(result.StoredSignals, StoredSignal{
-			LocalRef:        signal.LocalRef,
-			SignalID:        signalID,
-			SignalVersionID: versionResult.ID,
-			VersionNumber:   versionResult.VersionNumber,
-		})
-	}
-
-	// Update summary counts
-	createSignalsResponse.Summary.StoredCount = len(result.StoredSignals)
-	createSignalsResponse.Summary.RejectedCount = len(result.FailedSignals)
-
-	// Log signal processing summary with failure details for debugging
-	if createSignalsResponse.Summary.RejectedCount > 0 {
-		reqLogger := logger.ContextRequestLogger(r.Context())
-		failureErrors := make(map[string]int) // Track error types for summary
-		for _, failed := range result.FailedSignals {
-			failureErrors[failed.ErrorMessage]++
-		}
-		reqLogger.Warn("Signal processing failures",
-			slog.Int("stored_count", createSignalsResponse.Summary.StoredCount),
-			slog.Int("rejected_count", createSignalsResponse.Summary.RejectedCount),
-			slog.Int("unique_errors", len(failureErrors)),
-		)
-		for errMsg, count := range failureErrors {
-			reqLogger.Warn("Failure type",
-				slog.String("error_message", errMsg),
-				slog.Int("count", count),
-			)
-		}
-	}
-
-	// Log individual failures for batch tracking
-	if len(result.FailedSignals) > 0 {
-		for _, failed := range result.FailedSignals {
-			_, err := s.queries.CreateSignalProcessingFailureDetail(r.Context(), database.CreateSignalProcessingFailureDetailParams{
-				SignalBatchID:    batch.ID,
-				SignalTypeSlug:   signalTypeSlug,
-				SignalTypeSemVer: semVer,
-				LocalRef:         failed.LocalRef,
-				ErrorCode:        failed.ErrorCode,
-				ErrorMessage:     failed.ErrorMessage,
-			})
-			if err != nil {
-				// Log the error but don't fail the operation
-				logger.ContextWithLogAttrs(r.Context(),
-					slog.String("local_ref", failed.LocalRef),
-					slog.String("error", err.Error()),
-				)
-
-			}
-		}
-	}
-
-	createSignalsResponse.Results = []IsnResult{result}
-
-	var httpStatus int
-	switch {
-	case createSignalsResponse.Summary.StoredCount == 0:
-		// All signals failed
-		httpStatus = http.StatusUnprocessableEntity
-	case createSignalsResponse.Summary.RejectedCount > 0:
-		// Partial success
-		httpStatus = http.StatusMultiStatus
-	default:
-		// All signals processed successfully
-		httpStatus = http.StatusOK
-	}
-	return responses.JSON(w, httpStatus, createSignalsResponse)
+	httpStatus, response := submission.complete(ctx)
+	return httpStatus, response, nil
 }
 
 // SearchPublicSignals godocs
@@ -758,6 +907,29 @@ func (s *SignalsHandler) CreateSignals(w http.ResponseWriter, r *http.Request) e
 //	@Description	Search for signals in public ISNs (no authentication required).
 //	@Description
 //	@Description	Note the endpoint returns the latest version of each signal.
+//	@Description	Use `include_history=true` to include the lifecycle events of each signal (when it was created, updated, withdrawn or reinstated, by which account and why).
+//	@Description
+//	@Description	Results are returned in pages of up to `limit` signals, ordered by version_created_at.
+//	@Description	When there are more results the response includes a `Signalsd-Next-Page-Token` header - repeat the search with this value in the `page_token` param to get the next page.
+//	@Description
+//	@Description	**Content filters**
+//	@Description
+//	@Description	Signals can be filtered on the fields in their content using query params of the form `content.<field_path>=value` or `content.<field_path>__<operator>=value`, where field_path is a dot-separated path to a field in the signal schema, e.g.
+//	@Description	- `content.payload.PortOfEntry=Felixstowe`
+//	@Description	- `content.status__in=approved,rejected`
+//	@Description	- `content.payload.weight__gte=1000`
+//	@Description
+//	@Description	Operators: `eq` (the default), `ne`, `gt`, `gte`, `lt`, `lte` and `in` (comma-separated list of values).
+//	@Description	Numeric values are compared as numbers when the content field is a JSON number - other values are compared as text (ISO 8601 dates can be compared with gt/lt).
+//	@Description	When more than one filter is supplied only signals matching all the filters are returned. Array elements can't be used in filters.
+//	@Description
+//	@Description	**Exports**
+//	@Description
+//	@Description	Use the `Accept` header to export all the matching signals in a single response:
+//	@Description	- `Accept: application/x-ndjson` - one signal per line, in the same format as the json response.
+//	@Description	- `Accept: text/csv` - one row per signal. The signal content is flattened into a column for each field in the signal type schema (e.g. `content.payload.PortOfEntry`) - arrays and objects that can't be expanded are returned as json.
+//	@Description
+//	@Description	Exports are streamed as the signals are read and are not subject to the request timeout. The `limit`, `page_token`, `include_correlated`, `include_previous_versions` and `include_history` params can't be used with exports.
 //
 //	@Param			start_date					query		string	false	"Start date"															example(2006-01-02T15:05:00Z)
 //	@Param			end_date					query		string	false	"End date"																example(2006-01-02T15:15:00Z)
@@ -767,8 +939,16 @@ func (s *SignalsHandler) CreateSignals(w http.ResponseWriter, r *http.Request) e
 //	@Param			include_withdrawn			query		string	false	"Include withdrawn signals (default: false)"							example(true)
 //	@Param			include_correlated			query		string	false	"Include signals that link to each returned signal (default: false)"	example(true)
 //	@Param			include_previous_versions	query		string	false	"Include previous versions of each returned signal (default: false)"	example(true)
+//	@Param			include_history				query		string	false	"Include the lifecycle events of each returned signal (default: false)"	example(true)
+//	@Param			limit						query		int		false	"Max number of signals to return (default and max: 1000)"				example(100)
+//	@Param			order						query		string	false	"Sort order by version_created_at (default: asc)"						Enums(asc, desc)
+//	@Param			page_token					query		string	false	"Signalsd-Next-Page-Token header value from the previous page of results"
+//	@Param			Accept						header		string	false	"Response format (default: application/json)"							Enums(application/json, application/x-ndjson, text/csv)
+//
+//	@Produce		json,application/x-ndjson,text/csv
 //
 //	@Success		200							{array}		handlers.SearchSignalResponse
+//	@Header			200							{string}	Signalsd-Next-Page-Token	"Supplied when there are more results - use as the page_token param to get the next page"
 //	@Failure		400							{object}	responses.ErrorResponse	"invalid_url_param"
 //	@Failure		404							{object}	responses.ErrorResponse	"resource_not_found"
 //	@Failure		500							{object}	responses.ErrorResponse	"database_error"
@@ -801,17 +981,21 @@ func (s *SignalsHandler) SearchPublicSignals(w http.ResponseWriter, r *http.Requ
 		return apperrors.InvalidURLParam("invalid search parameters", err)
 	}
 
-	returnedSignals, err := s.queries.GetSignalsWithOptionalFilters(r.Context(), database.GetSignalsWithOptionalFiltersParams{
-		IsnSlug:          searchParams.isnSlug,
-		SignalTypeSlug:   searchParams.signalTypeSlug,
-		SemVer:           searchParams.semVer,
-		StartDate:        searchParams.startDate,
-		EndDate:          searchParams.endDate,
-		AccountID:        searchParams.accountID,
-		SignalID:         searchParams.signalID,
-		LocalRef:         searchParams.localRef,
-		IncludeWithdrawn: &searchParams.includeWithdrawn,
-	})
+	if err := s.validateContentFilters(searchParams); err != nil {
+		return err
+	}
+
+	// ndjson and csv exports are streamed to the client (email addresses are not included for public ISNs)
+	if format := SearchExportFormat(r); format != "" {
+		return s.exportSignals(w, r, searchParams, format, false)
+	}
+
+	queryParams, err := searchParams.getSignalsParams()
+	if err != nil {
+		return apperrors.InternalError("could not create search query", err)
+	}
+
+	returnedSignals, err := s.queries.GetSignalsWithOptionalFilters(r.Context(), queryParams)
 	if err != nil {
 		logger.ContextWithLogAttrs(r.Context(),
 			slog.String("isn_slug", searchParams.isnSlug),
@@ -819,6 +1003,7 @@ func (s *SignalsHandler) SearchPublicSignals(w http.ResponseWriter, r *http.Requ
 
 		return apperrors.DatabaseError("database error", err)
 	}
+	returnedSignals = paginateSearchResults(w, returnedSignals, searchParams.limit)
 
 	response := make([]SearchSignalWithCorrelationsAndVersions, 0, len(returnedSignals))
 
@@ -826,7 +1011,7 @@ func (s *SignalsHandler) SearchPublicSignals(w http.ResponseWriter, r *http.Requ
 	// ...not very efficient but assumption is that these options will most likely be used with individual signals rather than in bulk (needs monitoring to confirm)
 	signalIDs := make([]uuid.UUID, 0, len(returnedSignals))
 
-	if searchParams.includeCorrelated || searchParams.includePreviousSignalVersions {
+	if searchParams.includeCorrelated || searchParams.includePreviousSignalVersions || searchParams.includeHistory {
 		for _, row := range returnedSignals {
 			signalIDs = append(signalIDs, row.SignalID)
 		}
@@ -849,6 +1034,13 @@ func (s *SignalsHandler) SearchPublicSignals(w http.ResponseWriter, r *http.Requ
 			return apperrors.DatabaseError("database error", err)
 		}
 	}
+	var historyBySignalID map[uuid.UUID][]SignalHistoryEvent
+	if searchParams.includeHistory {
+		historyBySignalID, err = s.getSignalHistory(r.Context(), signalIDs, false)
+		if err != nil {
+			return apperrors.DatabaseError("database error", err)
+		}
+	}
 
 	for _, returnedSignal := range returnedSignals {
 		signal := SearchSignalWithCorrelationsAndVersions{
@@ -880,6 +1072,11 @@ func (s *SignalsHandler) SearchPublicSignals(w http.ResponseWriter, r *http.Requ
 			}
 		}
 
+		// add the signal history
+		if searchParams.includeHistory {
+			signal.History = historyBySignalID[returnedSignal.SignalID]
+		}
+
 		response = append(response, signal)
 	}
 	return responses.JSON(w, http.StatusOK, response)
@@ -893,6 +1090,29 @@ func (s *SignalsHandler) SearchPublicSignals(w http.ResponseWriter, r *http.Requ
 //	@Description	Search for signals by date or account in private ISNs (authentication required - only accounts with read or write permissions to the ISN can access signals).
 //	@Description
 //	@Description	Note the endpoint returns the latest version of each signal.
+//	@Description	Use `include_history=true` to include the lifecycle events of each signal (when it was created, updated, withdrawn or reinstated, by which account and why).
+//	@Description
+//	@Description	Results are returned in pages of up to `limit` signals, ordered by version_created_at.
+//	@Description	When there are more results the response includes a `Signalsd-Next-Page-Token` header - repeat the search with this value in the `page_token` param to get the next page.
+//	@Description
+//	@Description	**Content filters**
+//	@Description
+//	@Description	Signals can be filtered on the fields in their content using query params of the form `content.<field_path>=value` or `content.<field_path>__<operator>=value`, where field_path is a dot-separated path to a field in the signal schema, e.g.
+//	@Description	- `content.payload.PortOfEntry=Felixstowe`
+//	@Description	- `content.status__in=approved,rejected`
+//	@Description	- `content.payload.weight__gte=1000`
+//	@Description
+//	@Description	Operators: `eq` (the default), `ne`, `gt`, `gte`, `lt`, `lte` and `in` (comma-separated list of values).
+//	@Description	Numeric values are compared as numbers when the content field is a JSON number - other values are compared as text (ISO 8601 dates can be compared with gt/lt).
+//	@Description	When more than one filter is supplied only signals matching all the filters are returned. Array elements can't be used in filters.
+//	@Description
+//	@Description	**Exports**
+//	@Description
+//	@Description	Use the `Accept` header to export all the matching signals in a single response:
+//	@Description	- `Accept: application/x-ndjson` - one signal per line, in the same format as the json response.
+//	@Description	- `Accept: text/csv` - one row per signal. The signal content is flattened into a column for each field in the signal type schema (e.g. `content.payload.PortOfEntry`) - arrays and objects that can't be expanded are returned as json.
+//	@Description
+//	@Description	Exports are streamed as the signals are read and are not subject to the request timeout. The `limit`, `page_token`, `include_correlated`, `include_previous_versions` and `include_history` params can't be used with exports.
 //
 //	@Param			start_date					query		string	false	"Start date"															example(2006-01-02T15:05:00Z)
 //	@Param			end_date					query		string	false	"End date"																example(2006-01-02T15:15:00Z)
@@ -902,8 +1122,16 @@ func (s *SignalsHandler) SearchPublicSignals(w http.ResponseWriter, r *http.Requ
 //	@Param			include_withdrawn			query		string	false	"Include withdrawn signals (default: false)"							example(true)
 //	@Param			include_correlated			query		string	false	"Include signals that link to each returned signal (default: false)"	example(true)
 //	@Param			include_previous_versions	query		string	false	"Include previous versions of each returned signal (default: false)"	example(true)
+//	@Param			include_history				query		string	false	"Include the lifecycle events of each returned signal (default: false)"	example(true)
+//	@Param			limit						query		int		false	"Max number of signals to return (default and max: 1000)"				example(100)
+//	@Param			order						query		string	false	"Sort order by version_created_at (default: asc)"						Enums(asc, desc)
+//	@Param			page_token					query		string	false	"Signalsd-Next-Page-Token header value from the previous page of results"
+//	@Param			Accept						header		string	false	"Response format (default: application/json)"							Enums(application/json, application/x-ndjson, text/csv)
+//
+//	@Produce		json,application/x-ndjson,text/csv
 //
 //	@Success		200							{array}		handlers.SearchSignalResponse
+//	@Header			200							{string}	Signalsd-Next-Page-Token	"Supplied when there are more results - use as the page_token param to get the next page"
 //	@Failure		400							{object}	responses.ErrorResponse	"invalid_url_param"
 //	@Failure		401							{object}	responses.ErrorResponse	"authentication_error"
 //	@Failure		500							{object}	responses.ErrorResponse	"database_error"
@@ -934,46 +1162,36 @@ func (s *SignalsHandler) SearchPrivateSignals(w http.ResponseWriter, r *http.Req
 		return apperrors.InvalidURLParam("invalid search parameters", err)
 	}
 
-	returnedSignals, err := s.queries.GetSignalsWithOptionalFilters(r.Context(), database.GetSignalsWithOptionalFiltersParams{
-		IsnSlug:          searchParams.isnSlug,
-		SignalTypeSlug:   searchParams.signalTypeSlug,
-		SemVer:           searchParams.semVer,
-		StartDate:        searchParams.startDate,
-		EndDate:          searchParams.endDate,
-		AccountID:        searchParams.accountID,
-		SignalID:         searchParams.signalID,
-		LocalRef:         searchParams.localRef,
-		IncludeWithdrawn: &searchParams.includeWithdrawn,
-	})
-	if err != nil {
-		logger.ContextWithLogAttrs(r.Context(),
-			slog.String("isn_slug", searchParams.isnSlug),
-		)
-
-		return apperrors.DatabaseError("database error", err)
+	if err := s.validateContentFilters(searchParams); err != nil {
+		return err
 	}
 
-	// Apply permission-based filtering for write-only accounts
 	// Write-only accounts can only see signals they created
-	accountID, _ := auth.ContextAccountID(r.Context())
 	isnPerms := claims.IsnPerms[searchParams.isnSlug]
 	if !isnPerms.CanRead && isnPerms.CanWrite {
-		// Build a set of signal IDs created by this account
-		createdSignalIDs := make(map[uuid.UUID]bool)
-		for _, signal := range returnedSignals {
-			if signal.AccountID == accountID {
-				createdSignalIDs[signal.SignalID] = true
-			}
-		}
+		accountID, _ := auth.ContextAccountID(r.Context())
+		searchParams.writeOnlyAccountID = &accountID
+	}
 
-		filtered := make([]database.GetSignalsWithOptionalFiltersRow, 0, len(returnedSignals))
-		for _, signal := range returnedSignals {
-			if signal.AccountID == accountID {
-				filtered = append(filtered, signal)
-			}
-		}
-		returnedSignals = filtered
+	// ndjson and csv exports are streamed to the client
+	if format := SearchExportFormat(r); format != "" {
+		return s.exportSignals(w, r, searchParams, format, true)
+	}
+
+	queryParams, err := searchParams.getSignalsParams()
+	if err != nil {
+		return apperrors.InternalError("could not create search query", err)
+	}
+
+	returnedSignals, err := s.queries.GetSignalsWithOptionalFilters(r.Context(), queryParams)
+	if err != nil {
+		logger.ContextWithLogAttrs(r.Context(),
+			slog.String("isn_slug", searchParams.isnSlug),
+		)
+
+		return apperrors.DatabaseError("database error", err)
 	}
+	returnedSignals = paginateSearchResults(w, returnedSignals, searchParams.limit)
 
 	response := make([]SearchSignalWithCorrelationsAndVersions, 0, len(returnedSignals))
 
@@ -981,7 +1199,7 @@ func (s *SignalsHandler) SearchPrivateSignals(w http.ResponseWriter, r *http.Req
 	// ...not very efficient but assumption is that these options will most likely be used with individual signals rather than in bulk (needs monitoring to confirm)
 	signalIDs := make([]uuid.UUID, 0, len(returnedSignals))
 
-	if searchParams.includeCorrelated || searchParams.includePreviousSignalVersions {
+	if searchParams.includeCorrelated || searchParams.includePreviousSignalVersions || searchParams.includeHistory {
 		for _, row := range returnedSignals {
 			signalIDs = append(signalIDs, row.SignalID)
 		}
@@ -1004,6 +1222,13 @@ func (s *SignalsHandler) SearchPrivateSignals(w http.ResponseWriter, r *http.Req
 			return apperrors.DatabaseError("database error", err)
 		}
 	}
+	var historyBySignalID map[uuid.UUID][]SignalHistoryEvent
+	if searchParams.includeHistory {
+		historyBySignalID, err = s.getSignalHistory(r.Context(), signalIDs, true)
+		if err != nil {
+			return apperrors.DatabaseError("database error", err)
+		}
+	}
 
 	for _, returnedSignal := range returnedSignals {
 		signal := SearchSignalWithCorrelationsAndVersions{
@@ -1035,6 +1260,11 @@ func (s *SignalsHandler) SearchPrivateSignals(w http.ResponseWriter, r *http.Req
 				signal.PreviousSignalVersions = previousVersions
 			}
 		}
+
+		// add the signal history
+		if searchParams.includeHistory {
+			signal.History = historyBySignalID[returnedSignal.SignalID]
+		}
 		response = append(response, signal)
 	}
 	return responses.JSON(w, http.StatusOK, response)
@@ -1047,7 +1277,9 @@ func (s *SignalsHandler) SearchPrivateSignals(w http.ResponseWriter, r *http.Req
 //	@Description
 //	@Description	Withdrawn signals are hidden from search results by default but remain in the database.
 //	@Description	Signals can only be withdrawn by the account that created the signal.
-//	@Description	To reactivate a signal resupply it with the same local_ref using the 'create signals' end point.
+//	@Description	The optional reason is recorded with the withdrawal.
+//	@Description	To reactivate a signal use the _Reinstate Signals_ endpoint or resupply it with the same local_ref using the 'create signals' end point.
+//	@Description	To withdraw more than one signal (or signals created by other accounts, if you are an ISN admin) use the _Withdraw Signals in Bulk_ endpoint.
 //
 //	@Tags			Signal Exchange
 //
@@ -1089,12 +1321,17 @@ func (s *SignalsHandler) WithdrawSignal(w http.ResponseWriter, r *http.Request)
 		return apperrors.MalformedBody("you must supply a local_ref", nil)
 	}
 
+	if req.Reason != nil && len(*req.Reason) > signalsd.SignalStatusChangeReasonMaxLength {
+		return apperrors.MalformedBody(fmt.Sprintf("reason can't be longer than %d characters", signalsd.SignalStatusChangeReasonMaxLength), nil)
+	}
+
 	// Get the signal
 	signal, err := s.queries.GetSignalByAccountAndLocalRef(r.Context(), database.GetSignalByAccountAndLocalRefParams{
-		AccountID: accountID,
-		Slug:      signalTypeSlug,
-		SemVer:    semVer,
-		LocalRef:  *req.LocalRef,
+		AccountID:      accountID,
+		IsnSlug:        isnSlug,
+		SignalTypeSlug: signalTypeSlug,
+		SemVer:         semVer,
+		LocalRef:       *req.LocalRef,
 	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -1119,6 +1356,7 @@ func (s *SignalsHandler) WithdrawSignal(w http.ResponseWriter, r *http.Request)
 		SemVer:    semVer,
 		IsnSlug:   isnSlug,
 		LocalRef:  *req.LocalRef,
+		Reason:    req.Reason,
 	})
 
 	if err != nil {
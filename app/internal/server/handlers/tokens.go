@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/information-sharing-networks/signalsd/app/internal/database"
 	"github.com/information-sharing-networks/signalsd/app/internal/responses"
 	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/information-sharing-networks/signalsd/app/internal/signingkeys"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
@@ -24,19 +26,38 @@ type ServiceAccountRotateResponse struct {
 	ExpiresIn    int    `json:"expires_in" example:"31536000"` // seconds (1 year)
 }
 
+// AuthorizationServerMetadata describes the OAuth endpoints and the features they support (RFC 8414)
+type AuthorizationServerMetadata struct {
+	Issuer                                     string   `json:"issuer" example:"https://signalsd.example.com"`
+	TokenEndpoint                              string   `json:"token_endpoint" example:"https://signalsd.example.com/oauth/token"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported" example:"client_secret_basic,client_secret_post"`
+	RevocationEndpoint                         string   `json:"revocation_endpoint" example:"https://signalsd.example.com/oauth/revoke"`
+	RevocationEndpointAuthMethodsSupported     []string `json:"revocation_endpoint_auth_methods_supported" example:"client_secret_basic,client_secret_post"`
+	IntrospectionEndpoint                      string   `json:"introspection_endpoint" example:"https://signalsd.example.com/oauth/introspect"`
+	IntrospectionEndpointAuthMethodsSupported  []string `json:"introspection_endpoint_auth_methods_supported" example:"client_secret_basic,client_secret_post"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported" example:"RS256,ES256,EdDSA"`
+	TLSClientCertificateBoundAccessTokens      bool     `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+	JwksURI                                    string   `json:"jwks_uri,omitempty" example:"https://signalsd.example.com/.well-known/jwks.json"`
+	GrantTypesSupported                        []string `json:"grant_types_supported" example:"client_credentials,refresh_token"`
+	ResponseTypesSupported                     []string `json:"response_types_supported"`
+	ServiceDocumentation                       string   `json:"service_documentation" example:"https://signalsd.example.com/docs"`
+}
+
 type TokenHandler struct {
-	queries     *database.Queries
-	authService *auth.AuthService
-	pool        *pgxpool.Pool
-	environment string
+	queries       *database.Queries
+	authService   *auth.AuthService
+	pool          *pgxpool.Pool
+	environment   string
+	publicBaseURL string
 }
 
-func NewTokenHandler(queries *database.Queries, authService *auth.AuthService, pool *pgxpool.Pool, environment string) *TokenHandler {
+func NewTokenHandler(queries *database.Queries, authService *auth.AuthService, pool *pgxpool.Pool, environment string, publicBaseURL string) *TokenHandler {
 	return &TokenHandler{
-		queries:     queries,
-		authService: authService,
-		pool:        pool,
-		environment: environment,
+		queries:       queries,
+		authService:   authService,
+		pool:          pool,
+		environment:   environment,
+		publicBaseURL: publicBaseURL,
 	}
 }
 
@@ -57,8 +78,21 @@ func NewTokenHandler(queries *database.Queries, authService *auth.AuthService, p
 //	@Description
 //	@Description	Alternatively, credentials may be supplied via HTTP Basic Auth (`Authorization: Basic base64(client_id:client_secret)`).
 //	@Description
+//	@Description	Service accounts with a registered public key (see POST /api/auth/service-accounts/{client_id}/keys) can authenticate without a client secret:
+//	@Description	- `private_key_jwt` (RFC 7523): supply `client_assertion_type=urn:ietf:params:oauth:client-assertion-type:jwt-bearer` and a `client_assertion` JWT signed with the private key.
+//	@Description	The JWT must have `iss` and `sub` set to the client ID, `aud` set to the token endpoint URL, a unique `jti` and an `exp` no more than 5 minutes in the future.
+//	@Description	Set the `kid` header to the key ID if more than one key is registered. Each assertion can only be used once.
+//	@Description	- `self_signed_tls_client_auth` (RFC 8705): supply `client_id` and present a TLS client certificate for the registered key (only when mutual TLS is enabled).
+//	@Description	The access token is bound to the certificate and can only be used on requests made with the same certificate.
+//	@Description
 //	@Description	Returns a new access token in the response body. Access tokens expire after 30 minutes.
 //	@Description
+//	@Description	By default the token has all the permissions granted to the service account.
+//	@Description	Use the optional `scope` field to limit the token to specific ISNs and permissions - a space separated list of
+//	@Description	`isn:{isn_slug}:read` and `isn:{isn_slug}:write` values, e.g `scope=isn:port-isn:write isn:customs-isn:read`.
+//	@Description	The token can only be used with the ISNs in the scope, so a compromised token does not give access to everything the account can see.
+//	@Description	The scope can't include access that has not been granted to the account (`invalid_scope`).
+//	@Description
 //	@Description	---
 //	@Description
 //	@Description	**Web Users — `refresh_token` grant**
@@ -76,12 +110,15 @@ func NewTokenHandler(queries *database.Queries, authService *auth.AuthService, p
 //	@Tags		auth
 //	@Accept		x-www-form-urlencoded
 //
-//	@Param		grant_type		formData	string	true	"Grant type"	Enums(client_credentials, refresh_token)
-//	@Param		client_id		formData	string	false	"Client ID (client_credentials grant only)"
-//	@Param		client_secret	formData	string	false	"Client secret (client_credentials grant only)"
+//	@Param		grant_type				formData	string	true	"Grant type"	Enums(client_credentials, refresh_token)
+//	@Param		client_id				formData	string	false	"Client ID (client_credentials grant only)"
+//	@Param		client_secret			formData	string	false	"Client secret (client_credentials grant only)"
+//	@Param		client_assertion_type	formData	string	false	"Client assertion type (private_key_jwt authentication only)"	Enums(urn:ietf:params:oauth:client-assertion-type:jwt-bearer)
+//	@Param		client_assertion		formData	string	false	"JWT signed with a key registered for the service account (private_key_jwt authentication only)"
+//	@Param		scope					formData	string	false	"Space separated list of ISN permissions to include in the token (client_credentials grant only)"
 //
 //	@Success	200				{object}	auth.AccessTokenResponse
-//	@Failure	400				{object}	responses.OAuthErrorResponse	"unsupported_grant_type · invalid_request · invalid_grant"
+//	@Failure	400				{object}	responses.OAuthErrorResponse	"unsupported_grant_type · invalid_request · invalid_grant · invalid_scope"
 //	@Failure	401				{object}	responses.OAuthErrorResponse	"invalid_client — wrong, expired, or revoked client secret. If your secret has expired, use POST /api/auth/service-accounts/rotate-secret to self-serve a new."
 //	@Failure	500				{object}	responses.OAuthErrorResponse	"server_error"
 //
@@ -97,9 +134,21 @@ func (a *TokenHandler) RefreshAccessToken(w http.ResponseWriter, r *http.Request
 		return apperrors.OAuthServerError("did not receive accountType from middleware", apperrors.ErrCodeInternalError, nil)
 	}
 
-	// create new access token refresh
-	accessTokenResponse, err := a.authService.CreateAccessToken(r.Context())
+	// scoped tokens (service accounts only)
+	scope, err := auth.ParseScope(r.PostFormValue("scope"))
 	if err != nil {
+		return apperrors.OAuthInvalidScope(err.Error(), apperrors.ErrCodeInvalidScope, nil)
+	}
+	if scope != nil && accountType != "service_account" {
+		return apperrors.OAuthInvalidScope("scopes can only be requested by service accounts", apperrors.ErrCodeInvalidScope, nil)
+	}
+
+	// create new access token
+	accessTokenResponse, err := a.authService.CreateScopedAccessToken(r.Context(), scope)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidScope) {
+			return apperrors.OAuthInvalidScope(err.Error(), apperrors.ErrCodeInvalidScope, nil)
+		}
 		return apperrors.OAuthServerError("error creating access token", apperrors.ErrCodeFailedToCreateToken, err)
 	}
 
@@ -188,7 +237,7 @@ func (a *TokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) error
 	return a.RevokeClientSecret(w, r)
 }
 
-// RevokeClientSecret revokes ALL client secrets for a service account - called by the wrapper handler for /oauth/revoke (RevokeToken)
+// RevokeClientSecret revokes ALL client secrets and public keys for a service account - called by the wrapper handler for /oauth/revoke (RevokeToken)
 // This effectively disables the service account until an admin re-registers it via POST /api/auth/service-accounts/register
 // TODO this is currently handled on /oauth/revoke but probably belongs on a differnt endpoint
 func (a *TokenHandler) RevokeClientSecret(w http.ResponseWriter, r *http.Request) error {
@@ -197,10 +246,13 @@ func (a *TokenHandler) RevokeClientSecret(w http.ResponseWriter, r *http.Request
 		return apperrors.OAuthServerError("middleware did not supply a serverAccountID", apperrors.ErrCodeInternalError, nil)
 	}
 
-	// Revoke all client secrets for this account (disables the service account).
+	// Revoke all client secrets and keys for this account (disables the service account).
 	if _, err := a.queries.RevokeAllClientSecretsForAccount(r.Context(), serverAccountID); err != nil {
 		return apperrors.OAuthServerError("database error", apperrors.ErrCodeDatabaseError, err)
 	}
+	if _, err := a.queries.RevokeAllServiceAccountKeys(r.Context(), serverAccountID); err != nil {
+		return apperrors.OAuthServerError("database error", apperrors.ErrCodeDatabaseError, err)
+	}
 
 	return responses.NoContent(w, http.StatusOK)
 }
@@ -324,3 +376,132 @@ func (a *TokenHandler) RotateServiceAccountSecret(w http.ResponseWriter, r *http
 		ExpiresIn:    int(signalsd.ClientSecretExpiry.Seconds()),
 	})
 }
+
+// JWKS godoc
+//
+//	@Summary	Get Access Token Signing Keys
+//	@Description
+//	@Description	Returns the public keys used to verify signalsd access tokens in JSON Web Key Set format (RFC 7517).
+//	@Description
+//	@Description	API gateways and partner services can use the keys to verify access tokens offline - use the `kid` header in the token to select the key.
+//	@Description	Keys are published before they are used to sign tokens and remain in the set until the tokens signed with them have expired,
+//	@Description	so verifiers that refresh the key set at least every 15 minutes will always have the keys they need.
+//	@Description
+//	@Description	The key set is empty when the service signs access tokens with HS256 (the default) - these tokens can only be verified by signalsd.
+//	@Tags		auth
+//
+//	@Success	200	{object}	signingkeys.JWKSet
+//	@Failure	500	{object}	responses.ErrorResponse	"internal_error"
+//
+//	@Router		/.well-known/jwks.json [get]
+func (a *TokenHandler) JWKS(w http.ResponseWriter, r *http.Request) error {
+	jwks := signingkeys.JWKSet{Keys: []signingkeys.JWK{}}
+
+	if signingKeys := a.authService.SigningKeys(); signingKeys != nil {
+		var err error
+		jwks, err = signingKeys.JWKS()
+		if err != nil {
+			return apperrors.InternalError("could not encode signing keys", err)
+		}
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(signalsd.JWKSCacheMaxAge.Seconds())))
+	return responses.JSON(w, http.StatusOK, jwks)
+}
+
+// IntrospectToken godoc
+//
+//	@Summary	Introspect Token
+//	@Description
+//	@Description	Reports whether an access token or refresh token is active and describes the account it was issued to (RFC 7662).
+//	@Description	The request body must be `application/x-www-form-urlencoded`.
+//	@Description
+//	@Description	The caller must authenticate with service account client credentials - either in the request body (`client_id` and `client_secret`)
+//	@Description	or via HTTP Basic Auth (`Authorization: Basic base64(client_id:client_secret)`).
+//	@Description
+//	@Description	A token is active when:
+//	@Description	- access tokens: the token is correctly signed and has not expired, the account is active and the credential used to obtain the token
+//	@Description	(the service account client secret or the user's refresh token) has not been revoked.
+//	@Description	- refresh tokens: the token has not expired or been revoked and the account is active.
+//	@Description
+//	@Description	Access tokens issued from a revoked credential are reported as inactive even though signalsd would accept them until they expire -
+//	@Description	resource servers that need to act on revocation immediately should introspect the token rather than verifying it offline.
+//	@Description
+//	@Description	The response for tokens that are not active only contains `"active": false`.
+//	@Description	The ISN permissions (`isn_perms`) are those in the access token claims and are not returned for refresh tokens.
+//	@Description
+//	@Tags		auth
+//	@Accept		x-www-form-urlencoded
+//
+//	@Param		token			formData	string	true	"The access token or refresh token"
+//	@Param		token_type_hint	formData	string	false	"The type of token (the other type is tried if the token is not found)"	Enums(access_token, refresh_token)
+//	@Param		client_id		formData	string	false	"Client ID (if not using HTTP Basic Auth)"
+//	@Param		client_secret	formData	string	false	"Client secret (if not using HTTP Basic Auth)"
+//
+//	@Success	200	{object}	auth.TokenIntrospection
+//	@Failure	400	{object}	responses.OAuthErrorResponse	"invalid_request (missing token or client credentials)"
+//	@Failure	401	{object}	responses.OAuthErrorResponse	"invalid_client"
+//	@Failure	500	{object}	responses.OAuthErrorResponse	"server_error"
+//
+//	@Router		/oauth/introspect [post]
+func (a *TokenHandler) IntrospectToken(w http.ResponseWriter, r *http.Request) error {
+	token := r.PostFormValue("token")
+	if token == "" {
+		return apperrors.OAuthInvalidRequest("token is required", apperrors.ErrCodeInvalidRequest, nil)
+	}
+
+	tokenTypeHint := r.PostFormValue("token_type_hint")
+
+	introspection, err := a.authService.IntrospectToken(r.Context(), token, tokenTypeHint)
+	if err != nil {
+		return apperrors.OAuthServerError("database error", apperrors.ErrCodeDatabaseError, err)
+	}
+
+	logger.ContextWithLogAttrs(r.Context(),
+		slog.Bool("token_active", introspection.Active),
+	)
+
+	// RFC 7662 section 3 - the response must not be cached
+	w.Header().Set("Cache-Control", "no-store")
+	return responses.JSON(w, http.StatusOK, introspection)
+}
+
+// AuthorizationServerMetadata godoc
+//
+//	@Summary	Get OAuth Authorization Server Metadata
+//	@Description
+//	@Description	Returns the OAuth endpoints and the features they support (RFC 8414).
+//	@Description
+//	@Description	`jwks_uri` is only included when access tokens are signed with an asymmetric algorithm (see `/.well-known/jwks.json`).
+//	@Description
+//	@Description	`self_signed_tls_client_auth` and `tls_client_certificate_bound_access_tokens` are only included when mutual TLS client authentication is enabled (TLS_CLIENT_CERT_HEADER).
+//	@Tags		auth
+//
+//	@Success	200	{object}	handlers.AuthorizationServerMetadata
+//
+//	@Router		/.well-known/oauth-authorization-server [get]
+func (a *TokenHandler) AuthorizationServerMetadata(w http.ResponseWriter, r *http.Request) error {
+	clientAuthMethods := a.authService.ClientAuthMethods()
+
+	metadata := AuthorizationServerMetadata{
+		Issuer:                                     a.publicBaseURL,
+		TokenEndpoint:                              a.publicBaseURL + "/oauth/token",
+		TokenEndpointAuthMethodsSupported:          clientAuthMethods,
+		RevocationEndpoint:                         a.publicBaseURL + "/oauth/revoke",
+		RevocationEndpointAuthMethodsSupported:     clientAuthMethods,
+		IntrospectionEndpoint:                      a.publicBaseURL + "/oauth/introspect",
+		IntrospectionEndpointAuthMethodsSupported:  clientAuthMethods,
+		TokenEndpointAuthSigningAlgValuesSupported: auth.ClientAssertionSigningAlgs,
+		TLSClientCertificateBoundAccessTokens:      a.authService.MutualTLSEnabled(),
+		GrantTypesSupported:                        []string{"client_credentials", "refresh_token"},
+		ResponseTypesSupported:                     []string{},
+		ServiceDocumentation:                       a.publicBaseURL + "/docs",
+	}
+
+	if signingKeys := a.authService.SigningKeys(); signingKeys != nil && signingKeys.Algorithm() != signingkeys.HS256 {
+		metadata.JwksURI = a.publicBaseURL + "/.well-known/jwks.json"
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(signalsd.JWKSCacheMaxAge.Seconds())))
+	return responses.JSON(w, http.StatusOK, metadata)
+}
@@ -0,0 +1,364 @@
+package handlers
+
+// unroutable signals.
+// signals submitted to the signal router that don't match any routing rule (and the routing config has no fallback ISN) are stored in the unroutable_signals table.
+// site admins can list and inspect the stored signals and re-route them once the routing rules have been fixed.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	"github.com/information-sharing-networks/signalsd/app/internal/responses"
+	"github.com/information-sharing-networks/signalsd/app/internal/router"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/jackc/pgx/v5"
+)
+
+// UnroutableSignal is a signal submitted to the signal router that did not match any routing rule
+type UnroutableSignal struct {
+	ID             uuid.UUID `json:"id" example:"0198e3a4-5b6c-7d8e-9f01-234567890abc"`
+	CreatedAt      time.Time `json:"created_at" example:"2026-04-02T09:30:00Z"`
+	AccountID      uuid.UUID `json:"account_id" example:"a38c99ed-c75c-4a4a-a901-c9485cf93cf3"`
+	BatchRef       string    `json:"batch_ref" example:"daily-sync-2026-04-02"`
+	SignalTypePath string    `json:"signal_type_path" example:"sample-signal-type/v1.0.0"`
+	LocalRef       string    `json:"local_ref" example:"item_id_#1"`
+	ErrorMessage   string    `json:"error_message" example:"no routing rule matched for signal type sample-signal-type/v1.0.0"`
+
+	// ReroutedAt and ReroutedByAccountID are set when the signal has been re-routed by a site admin
+	ReroutedAt          *time.Time `json:"rerouted_at,omitempty"`
+	ReroutedByAccountID *uuid.UUID `json:"rerouted_by_account_id,omitempty"`
+}
+
+// UnroutableSignalDetails includes the content of the unroutable signal and the sender details and request headers used by the routing rules
+// (the routing context is omitted for signals stored before routing contexts were kept).
+type UnroutableSignalDetails struct {
+	UnroutableSignal
+	Content        json.RawMessage        `json:"content" swaggertype:"object"`
+	RoutingContext *router.RoutingContext `json:"routing_context,omitempty"`
+}
+
+// storeUnroutableSignal keeps a signal that did not match any routing rule so it can be re-routed later.
+// The claims are stored with the signal so the scope of the original access token is applied when the signal is re-routed (the account's permissions are reloaded),
+// and the routing context is stored so that rules using the sender or request headers are applied in the same way when the signal is re-routed.
+// Only the request headers used by the routing rules when the signal was submitted are kept in the routing context (see router.NewRoutingContext).
+func (s *SignalRouter) storeUnroutableSignal(ctx context.Context, claims *auth.Claims, routingContext router.RoutingContext, batchID uuid.UUID, signalTypeSlug, semVer string, signal Signal, errMsg string) (uuid.UUID, error) {
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return uuid.Nil, apperrors.InternalError("could not marshal claims", err)
+	}
+
+	routingContextJSON, err := json.Marshal(routingContext)
+	if err != nil {
+		return uuid.Nil, apperrors.InternalError("could not marshal routing context", err)
+	}
+
+	id, err := s.queries.CreateUnroutableSignal(ctx, database.CreateUnroutableSignalParams{
+		AccountID:      claims.AccountID,
+		SignalBatchID:  batchID,
+		SignalTypeSlug: signalTypeSlug,
+		SemVer:         semVer,
+		LocalRef:       signal.LocalRef,
+		Content:        signal.Content,
+		Claims:         claimsJSON,
+		RoutingContext: routingContextJSON,
+		ErrorMessage:   errMsg,
+	})
+	if err != nil {
+		return uuid.Nil, apperrors.DatabaseError("database error", err)
+	}
+	return id, nil
+}
+
+// ListUnroutableSignals godoc
+//
+//	@Summary		List Unroutable Signals
+//
+//	@Description	Lists the signals submitted to the signal router that did not match any routing rule (newest first).
+//	@Description	Signals that have been re-routed are only included when include_rerouted=true.
+//
+//	@Tags			Signals Routing
+//
+//	@Param			signal_type_slug	query		string	false	"signal type slug"	example(sample-signal-type)
+//	@Param			sem_ver				query		string	false	"version"			example(1.0.0)
+//	@Param			include_rerouted	query		bool	false	"include signals that have been re-routed"
+//	@Param			limit				query		int		false	"max number of signals returned (default 100, max 1000)"
+//
+//	@Success		200					{array}		handlers.UnroutableSignal
+//	@Failure		400					{object}	responses.ErrorResponse	"invalid_url_param"
+//	@Failure		500					{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/admin/unroutable-signals [get]
+//
+// Should only be used with RequireRole (siteadmin) middleware.
+func (s *SignalRouter) ListUnroutableSignals(w http.ResponseWriter, r *http.Request) error {
+	params := database.GetUnroutableSignalsParams{
+		IncludeRerouted: r.URL.Query().Get("include_rerouted") == "true",
+		RowLimit:        signalsd.UnroutableSignalsDefaultLimit,
+	}
+	if signalTypeSlug := r.URL.Query().Get("signal_type_slug"); signalTypeSlug != "" {
+		params.SignalTypeSlug = &signalTypeSlug
+	}
+	if semVer := r.URL.Query().Get("sem_ver"); semVer != "" {
+		params.SemVer = &semVer
+	}
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > signalsd.UnroutableSignalsMaxLimit {
+			return apperrors.InvalidURLParam(fmt.Sprintf("limit must be a number between 1 and %d", signalsd.UnroutableSignalsMaxLimit), err)
+		}
+		params.RowLimit = int32(limit)
+	}
+
+	rows, err := s.queries.GetUnroutableSignals(r.Context(), params)
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	res := make([]UnroutableSignal, len(rows))
+	for i, row := range rows {
+		res[i] = UnroutableSignal{
+			ID:                  row.ID,
+			CreatedAt:           row.CreatedAt,
+			AccountID:           row.AccountID,
+			BatchRef:            row.BatchRef,
+			SignalTypePath:      fmt.Sprintf("%s/v%s", row.SignalTypeSlug, row.SemVer),
+			LocalRef:            row.LocalRef,
+			ErrorMessage:        row.ErrorMessage,
+			ReroutedAt:          row.ReroutedAt,
+			ReroutedByAccountID: row.ReroutedByAccountID,
+		}
+	}
+
+	return responses.JSON(w, http.StatusOK, res)
+}
+
+// GetUnroutableSignal godoc
+//
+//	@Summary		Get Unroutable Signal
+//
+//	@Description	Returns an unroutable signal, including the signal content.
+//
+//	@Tags			Signals Routing
+//
+//	@Param			unroutable_signal_id	path		string	true	"unroutable signal id"	example(0198e3a4-5b6c-7d8e-9f01-234567890abc)
+//
+//	@Success		200						{object}	handlers.UnroutableSignalDetails
+//	@Failure		400						{object}	responses.ErrorResponse	"invalid_url_param"
+//	@Failure		404						{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure		500						{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/admin/unroutable-signals/{unroutable_signal_id} [get]
+//
+// Should only be used with RequireRole (siteadmin) middleware.
+func (s *SignalRouter) GetUnroutableSignal(w http.ResponseWriter, r *http.Request) error {
+	row, err := s.getUnroutableSignal(r)
+	if err != nil {
+		return err
+	}
+
+	var routingContext *router.RoutingContext
+	if row.RoutingContext != nil {
+		if err := json.Unmarshal(row.RoutingContext, &routingContext); err != nil {
+			return apperrors.InternalError("could not unmarshal routing context", err)
+		}
+	}
+
+	return responses.JSON(w, http.StatusOK, UnroutableSignalDetails{
+		UnroutableSignal: UnroutableSignal{
+			ID:                  row.ID,
+			CreatedAt:           row.CreatedAt,
+			AccountID:           row.AccountID,
+			BatchRef:            row.BatchRef,
+			SignalTypePath:      fmt.Sprintf("%s/v%s", row.SignalTypeSlug, row.SemVer),
+			LocalRef:            row.LocalRef,
+			ErrorMessage:        row.ErrorMessage,
+			ReroutedAt:          row.ReroutedAt,
+			ReroutedByAccountID: row.ReroutedByAccountID,
+		},
+		Content:        row.Content,
+		RoutingContext: routingContext,
+	})
+}
+
+// RerouteUnroutableSignal godoc
+//
+//	@Summary		Re-route Unroutable Signal
+//
+//	@Description	Submits an unroutable signal to the signal router again - use this once the routing rules for the signal type have been fixed.
+//	@Description
+//	@Description	The signal is processed in the same way as a signal submitted to the _Submit Signals via Router_ endpoint,
+//	@Description	using the account, batch and request headers of the original request, and the response has the same format.
+//	@Description	The write permissions are checked using the account's current permissions (a 400 error is returned if the account has been disabled).
+//	@Description	The signal is marked as re-routed once it has been stored in at least one ISN.
+//	@Description
+//	@Description	A 400 error is returned if the signal still does not match any routing rule (the signal is left unchanged).
+//	@Description	A 409 error is returned if the signal has already been re-routed (or is being re-routed by another request).
+//
+//	@Tags			Signals Routing
+//
+//	@Param			unroutable_signal_id	path		string	true	"unroutable signal id"	example(0198e3a4-5b6c-7d8e-9f01-234567890abc)
+//
+//	@Success		200						{object}	handlers.SignalSubmissionResponse	"signal stored"
+//	@Success		207						{object}	handlers.SignalSubmissionResponse	"signal stored in some of the target ISNs"
+//	@Success		422						{object}	handlers.SignalSubmissionResponse	"signal could not be stored in any of the target ISNs"
+//	@Failure		400						{object}	responses.ErrorResponse				"invalid_url_param | invalid_request"
+//	@Failure		404						{object}	responses.ErrorResponse				"resource_not_found"
+//	@Failure		409						{object}	responses.ErrorResponse				"resource_already_exists"
+//	@Failure		500						{object}	responses.ErrorResponse				"database_error | internal_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/admin/unroutable-signals/{unroutable_signal_id}/reroute [post]
+//
+// Should only be used with RequireRole (siteadmin) middleware.
+func (s *SignalRouter) RerouteUnroutableSignal(w http.ResponseWriter, r *http.Request) error {
+	adminAccountID, ok := auth.ContextAccountID(r.Context())
+	if !ok {
+		return apperrors.InternalError("could not get accountID from context", nil)
+	}
+
+	row, err := s.getUnroutableSignal(r)
+	if err != nil {
+		return err
+	}
+
+	// claim the signal before it is processed so it can't be re-routed twice.
+	// The claim is only committed if the signal is stored - concurrent requests wait for the outcome and then get a 409.
+	tx, err := s.pool.BeginTx(r.Context(), pgx.TxOptions{})
+	if err != nil {
+		return apperrors.DatabaseError("failed to begin transaction", err)
+	}
+	defer func() {
+		if err := tx.Rollback(r.Context()); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logger.ContextWithLogAttrs(r.Context(),
+				slog.String("rollback_error", err.Error()),
+			)
+		}
+	}()
+
+	if _, err := s.queries.WithTx(tx).MarkUnroutableSignalRerouted(r.Context(), database.MarkUnroutableSignalReroutedParams{
+		ReroutedByAccountID: &adminAccountID,
+		ID:                  row.ID,
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apperrors.AlreadyExists(fmt.Sprintf("unroutable signal %s has already been re-routed", row.ID), nil)
+		}
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	// the signal is stored using the account's current permissions (access may have been revoked since the signal was submitted)
+	var storedClaims auth.Claims
+	if err := json.Unmarshal(row.Claims, &storedClaims); err != nil {
+		return apperrors.InternalError("could not unmarshal claims", err)
+	}
+	claims, err := s.authService.CurrentClaims(r.Context(), row.AccountID, storedClaims.Scope)
+	if err != nil {
+		if errors.Is(err, auth.ErrAccountInactive) {
+			return apperrors.InvalidRequest(fmt.Sprintf("the account that submitted the signal (%s) has been disabled", row.AccountID), nil)
+		}
+		return apperrors.InternalError("could not load the account permissions", err)
+	}
+
+	routingContext, err := storedRoutingContext(r.Context(), s.queries, claims, row.RoutingContext)
+	if err != nil {
+		return err
+	}
+
+	// reload the routing rules so recent changes to the routing config are used
+	if err := s.signalRouterCache.Load(r.Context()); err != nil {
+		return apperrors.InternalError("could not load routing rules", err)
+	}
+
+	signalTypePath := fmt.Sprintf("%s/v%s", row.SignalTypeSlug, row.SemVer)
+	if len(s.signalRouterCache.Resolve(signalTypePath, row.Content, routingContext)) == 0 {
+		return apperrors.InvalidRequest(fmt.Sprintf("the signal still does not match any routing rule for %s - update the routing config before re-routing the signal", signalTypePath), nil)
+	}
+
+	httpStatus, response, err := s.processRoutedSignals(r.Context(), claims, routingContext, row.SignalTypeSlug, row.SemVer, CreateSignalsRequest{
+		BatchRef: row.BatchRef,
+		Signals: []Signal{
+			{LocalRef: row.LocalRef, Content: row.Content},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// the signal is left unchanged if it was not stored in any ISN
+	if response.Summary.StoredCount > 0 {
+		if err := tx.Commit(r.Context()); err != nil {
+			return apperrors.DatabaseError("failed to commit transaction", err)
+		}
+	}
+
+	return responses.JSON(w, httpStatus, response)
+}
+
+// DeleteUnroutableSignal godoc
+//
+//	@Summary		Delete Unroutable Signal
+//
+//	@Description	Discards an unroutable signal.
+//
+//	@Tags			Signals Routing
+//
+//	@Param			unroutable_signal_id	path	string	true	"unroutable signal id"	example(0198e3a4-5b6c-7d8e-9f01-234567890abc)
+//
+//	@Success		204
+//	@Failure		400	{object}	responses.ErrorResponse	"invalid_url_param"
+//	@Failure		404	{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure		500	{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/admin/unroutable-signals/{unroutable_signal_id} [delete]
+//
+// Should only be used with RequireRole (siteadmin) middleware.
+func (s *SignalRouter) DeleteUnroutableSignal(w http.ResponseWriter, r *http.Request) error {
+	id, err := uuid.Parse(r.PathValue("unroutable_signal_id"))
+	if err != nil {
+		return apperrors.InvalidURLParam("invalid unroutable_signal_id", nil)
+	}
+
+	rowsAffected, err := s.queries.DeleteUnroutableSignal(r.Context(), id)
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+	if rowsAffected == 0 {
+		return apperrors.NotFound(fmt.Sprintf("unroutable signal %s not found", id), nil)
+	}
+
+	return responses.NoContent(w, http.StatusNoContent)
+}
+
+// getUnroutableSignal returns the unroutable signal identified by the unroutable_signal_id path param
+func (s *SignalRouter) getUnroutableSignal(r *http.Request) (database.GetUnroutableSignalByIDRow, error) {
+	id, err := uuid.Parse(r.PathValue("unroutable_signal_id"))
+	if err != nil {
+		return database.GetUnroutableSignalByIDRow{}, apperrors.InvalidURLParam("invalid unroutable_signal_id", nil)
+	}
+
+	row, err := s.queries.GetUnroutableSignalByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.GetUnroutableSignalByIDRow{}, apperrors.NotFound(fmt.Sprintf("unroutable signal %s not found", id), nil)
+		}
+		return database.GetUnroutableSignalByIDRow{}, apperrors.DatabaseError("database error", err)
+	}
+	return row, nil
+}
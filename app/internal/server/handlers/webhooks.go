@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/logger"
+	"github.com/information-sharing-networks/signalsd/app/internal/responses"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/jackc/pgx/v5"
+)
+
+type WebhooksHandler struct {
+	queries     *database.Queries
+	authService *auth.AuthService
+	environment string
+}
+
+func NewWebhooksHandler(queries *database.Queries, authService *auth.AuthService, environment string) *WebhooksHandler {
+	return &WebhooksHandler{
+		queries:     queries,
+		authService: authService,
+		environment: environment,
+	}
+}
+
+type CreateWebhookRequest struct {
+	TargetURL string `json:"target_url" example:"https://example.com/signals/webhook"`
+}
+
+// CreateWebhookResponse includes the signing secret - this is only shown once
+type CreateWebhookResponse struct {
+	ID            uuid.UUID `json:"id" example:"68fb5f5b-e3f5-4a96-8d35-cd2203a06f73"`
+	TargetURL     string    `json:"target_url" example:"https://example.com/signals/webhook"`
+	SigningSecret string    `json:"signing_secret" example:"Tb7ZNp1a8J0x3l6Vq2y5R4gH9sW_mKcD-fEoUiLjXn0="`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type Webhook struct {
+	ID        uuid.UUID `json:"id" example:"68fb5f5b-e3f5-4a96-8d35-cd2203a06f73"`
+	TargetURL string    `json:"target_url" example:"https://example.com/signals/webhook"`
+	IsActive  bool      `json:"is_active" example:"true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID                 uuid.UUID  `json:"id" example:"0198d1a4-5c1e-7e55-a0c6-5e3b1f1d6a10"`
+	SignalVersionID    uuid.UUID  `json:"signal_version_id" example:"835788bd-789d-4091-96e3-db0f51ccbabc"`
+	Status             string     `json:"status" enums:"pending,delivered,failed" example:"delivered"`
+	AttemptCount       int32      `json:"attempt_count" example:"1"`
+	NextAttemptAt      *time.Time `json:"next_attempt_at,omitempty"` // only included for pending deliveries
+	LastAttemptAt      *time.Time `json:"last_attempt_at,omitempty"`
+	LastResponseStatus *int32     `json:"last_response_status,omitempty" example:"200"`
+	LastError          *string    `json:"last_error,omitempty" example:"subscriber responded with status 503 Service Unavailable"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// CreateWebhook godoc
+//
+//	@Summary		Create webhook subscription
+//	@Tags			Signal Exchange
+//
+//	@Description	Register a URL that will receive every new signal version stored on the ISN for the signal type.
+//	@Description
+//	@Description	Signals are sent as HTTP POST requests within a few seconds of being stored (new signals and new versions of existing signals are sent).
+//	@Description	The request body contains the ISN slug, signal type path and the signal (in the same format as the signal search response).
+//	@Description
+//	@Description	Each request includes a `Signalsd-Signature` header that should be used to verify the request came from this service:
+//	@Description	```
+//	@Description	Signalsd-Signature: t=<unix timestamp>,v1=<hex encoded HMAC-SHA256 of "<unix timestamp>.<request body>">
+//	@Description	```
+//	@Description	The HMAC key is the `signing_secret` returned by this endpoint. **The secret is only shown once** - store it securely.
+//	@Description
+//	@Description	Deliveries that do not receive a 2xx response are retried with exponential backoff. Redirects are not followed.
+//	@Description	The delivery log for each subscription can be viewed with the **Get webhook deliveries** endpoint.
+//	@Description
+//	@Description	The target URL must use https (http is only permitted in the dev and test environments).
+//	@Description
+//	@Description	You must have read permission on the ISN to create a webhook. Deliveries stop if the read permission is revoked.
+//
+//	@Param			isn_slug			path		string							true	"isn slug"				example(sample-isn--example-org)
+//	@Param			signal_type_slug	path		string							true	"signal type slug"		example(sample-signal--example-org)
+//	@Param			sem_ver				path		string							true	"signal type version"	example(0.0.1)
+//	@Param			request				body		handlers.CreateWebhookRequest	true	"webhook details"
+//
+//	@Success		201					{object}	handlers.CreateWebhookResponse
+//	@Failure		400					{object}	responses.ErrorResponse	"malformed_body"
+//	@Failure		403					{object}	responses.ErrorResponse	"forbidden"
+//	@Failure		404					{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure		409					{object}	responses.ErrorResponse	"resource_already_exists"
+//	@Failure		500					{object}	responses.ErrorResponse	"database_error | internal_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/webhooks [post]
+//
+// This function should be called after the RequireAccessPermission middleware has checked the account has read permission for the ISN
+func (wh *WebhooksHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) error {
+	var req CreateWebhookRequest
+
+	claims, ok := auth.ContextClaims(r.Context())
+	if !ok {
+		return apperrors.InternalError("could not get claims from context", nil)
+	}
+
+	isnSlug := r.PathValue("isn_slug")
+	signalTypeSlug := r.PathValue("signal_type_slug")
+	semVer := r.PathValue("sem_ver")
+
+	defer r.Body.Close()
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apperrors.MalformedBody("invalid JSON body", err)
+	}
+
+	if req.TargetURL == "" {
+		return apperrors.MalformedBody("target_url is required", nil)
+	}
+
+	if err := wh.validateTargetURL(req.TargetURL); err != nil {
+		return apperrors.MalformedBody(err.Error(), nil)
+	}
+
+	exists, err := wh.queries.ExistsWebhookSubscription(r.Context(), database.ExistsWebhookSubscriptionParams{
+		AccountID:      claims.AccountID,
+		IsnSlug:        isnSlug,
+		SignalTypeSlug: signalTypeSlug,
+		SemVer:         semVer,
+		TargetURL:      req.TargetURL,
+	})
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+	if exists {
+		return apperrors.AlreadyExists("you already have a webhook for this target_url", nil)
+	}
+
+	signingSecret, err := wh.authService.GenerateSecureToken(signalsd.WebhookSigningSecretSize)
+	if err != nil {
+		return apperrors.InternalError("internal server error", err)
+	}
+
+	webhook, err := wh.queries.CreateWebhookSubscription(r.Context(), database.CreateWebhookSubscriptionParams{
+		AccountID:      claims.AccountID,
+		TargetURL:      req.TargetURL,
+		SigningSecret:  signingSecret,
+		IsnSlug:        isnSlug,
+		SignalTypeSlug: signalTypeSlug,
+		SemVer:         semVer,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apperrors.NotFound("signal type not found on this ISN", nil)
+		}
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	logger.ContextWithLogAttrs(r.Context(),
+		slog.String("webhook_subscription_id", webhook.ID.String()),
+	)
+
+	return responses.JSON(w, http.StatusCreated, CreateWebhookResponse{
+		ID:            webhook.ID,
+		TargetURL:     webhook.TargetURL,
+		SigningSecret: webhook.SigningSecret,
+		CreatedAt:     webhook.CreatedAt,
+	})
+}
+
+// GetWebhooks godoc
+//
+//	@Summary		Get webhook subscriptions
+//	@Tags			Signal Exchange
+//
+//	@Description	Returns the webhook subscriptions your account has created for the ISN and signal type (the signing secret is not included)
+//
+//	@Param			isn_slug			path	string	true	"isn slug"				example(sample-isn--example-org)
+//	@Param			signal_type_slug	path	string	true	"signal type slug"		example(sample-signal--example-org)
+//	@Param			sem_ver				path	string	true	"signal type version"	example(0.0.1)
+//
+//	@Success		200					{array}		handlers.Webhook
+//	@Failure		403					{object}	responses.ErrorResponse	"forbidden"
+//	@Failure		404					{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure		500					{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/webhooks [get]
+//
+// This function should be called after the RequireAccessPermission middleware has checked the account has read permission for the ISN
+func (wh *WebhooksHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) error {
+	claims, ok := auth.ContextClaims(r.Context())
+	if !ok {
+		return apperrors.InternalError("could not get claims from context", nil)
+	}
+
+	rows, err := wh.queries.GetWebhookSubscriptionsByAccountAndSignalType(r.Context(), database.GetWebhookSubscriptionsByAccountAndSignalTypeParams{
+		AccountID:      claims.AccountID,
+		IsnSlug:        r.PathValue("isn_slug"),
+		SignalTypeSlug: r.PathValue("signal_type_slug"),
+		SemVer:         r.PathValue("sem_ver"),
+	})
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	res := make([]Webhook, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, Webhook{
+			ID:        row.ID,
+			TargetURL: row.TargetURL,
+			IsActive:  row.IsActive,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+		})
+	}
+
+	return responses.JSON(w, http.StatusOK, res)
+}
+
+// DeleteWebhook godoc
+//
+//	@Summary		Delete webhook subscription
+//	@Tags			Signal Exchange
+//
+//	@Description	Deletes the webhook subscription and its delivery log. Pending deliveries are not sent.
+//
+//	@Param			isn_slug			path	string	true	"isn slug"				example(sample-isn--example-org)
+//	@Param			signal_type_slug	path	string	true	"signal type slug"		example(sample-signal--example-org)
+//	@Param			sem_ver				path	string	true	"signal type version"	example(0.0.1)
+//	@Param			webhook_id			path	string	true	"webhook ID"			example(68fb5f5b-e3f5-4a96-8d35-cd2203a06f73)
+//
+//	@Success		204
+//	@Failure		400	{object}	responses.ErrorResponse	"invalid_url_param"
+//	@Failure		403	{object}	responses.ErrorResponse	"forbidden"
+//	@Failure		404	{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure		500	{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/webhooks/{webhook_id} [delete]
+//
+// This function should be called after the RequireAccessPermission middleware has checked the account has read permission for the ISN
+func (wh *WebhooksHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) error {
+	webhook, claims, err := wh.getOwnWebhook(r)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := wh.queries.DeleteWebhookSubscription(r.Context(), database.DeleteWebhookSubscriptionParams{
+		ID:        webhook.ID,
+		AccountID: claims.AccountID,
+	})
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+	if rowsAffected == 0 {
+		return apperrors.NotFound("webhook not found", nil)
+	}
+
+	return responses.NoContent(w, http.StatusNoContent)
+}
+
+// GetWebhookDeliveries godoc
+//
+//	@Summary		Get webhook deliveries
+//	@Tags			Signal Exchange
+//
+//	@Description	Returns the delivery log for the webhook subscription (most recent deliveries first).
+//	@Description
+//	@Description	Delivery status:
+//	@Description	- `pending`: not yet delivered - the delivery will be attempted (or retried) at `next_attempt_at`
+//	@Description	- `delivered`: the subscriber responded with a 2xx status
+//	@Description	- `failed`: the maximum number of attempts was reached without a successful delivery
+//
+//	@Param			isn_slug			path	string	true	"isn slug"				example(sample-isn--example-org)
+//	@Param			signal_type_slug	path	string	true	"signal type slug"		example(sample-signal--example-org)
+//	@Param			sem_ver				path	string	true	"signal type version"	example(0.0.1)
+//	@Param			webhook_id			path	string	true	"webhook ID"			example(68fb5f5b-e3f5-4a96-8d35-cd2203a06f73)
+//
+//	@Success		200					{array}		handlers.WebhookDelivery
+//	@Failure		400					{object}	responses.ErrorResponse	"invalid_url_param"
+//	@Failure		403					{object}	responses.ErrorResponse	"forbidden"
+//	@Failure		404					{object}	responses.ErrorResponse	"resource_not_found"
+//	@Failure		500					{object}	responses.ErrorResponse	"database_error"
+//
+//	@Security		BearerAccessToken
+//
+//	@Router			/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/webhooks/{webhook_id}/deliveries [get]
+//
+// This function should be called after the RequireAccessPermission middleware has checked the account has read permission for the ISN
+func (wh *WebhooksHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) error {
+	webhook, _, err := wh.getOwnWebhook(r)
+	if err != nil {
+		return err
+	}
+
+	rows, err := wh.queries.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{
+		WebhookSubscriptionID: webhook.ID,
+		RowLimit:              signalsd.WebhookDeliveryLogLimit,
+	})
+	if err != nil {
+		return apperrors.DatabaseError("database error", err)
+	}
+
+	res := make([]WebhookDelivery, 0, len(rows))
+	for _, row := range rows {
+		delivery := WebhookDelivery{
+			ID:                 row.ID,
+			SignalVersionID:    row.SignalVersionID,
+			Status:             row.Status,
+			AttemptCount:       row.AttemptCount,
+			LastAttemptAt:      row.LastAttemptAt,
+			LastResponseStatus: row.LastResponseStatus,
+			LastError:          row.LastError,
+			CreatedAt:          row.CreatedAt,
+		}
+		if row.Status == "pending" {
+			delivery.NextAttemptAt = &row.NextAttemptAt
+		}
+		res = append(res, delivery)
+	}
+
+	return responses.JSON(w, http.StatusOK, res)
+}
+
+// getOwnWebhook returns the webhook identified by the webhook_id URL param.
+// An error is returned if the webhook does not belong to the account or is not registered for the ISN and signal type in the URL.
+func (wh *WebhooksHandler) getOwnWebhook(r *http.Request) (database.GetWebhookSubscriptionByIDRow, *auth.Claims, error) {
+	claims, ok := auth.ContextClaims(r.Context())
+	if !ok {
+		return database.GetWebhookSubscriptionByIDRow{}, nil, apperrors.InternalError("could not get claims from context", nil)
+	}
+
+	webhookID, err := uuid.Parse(r.PathValue("webhook_id"))
+	if err != nil {
+		return database.GetWebhookSubscriptionByIDRow{}, nil, apperrors.InvalidURLParam("invalid webhook_id", nil)
+	}
+
+	logger.ContextWithLogAttrs(r.Context(),
+		slog.String("webhook_subscription_id", webhookID.String()),
+	)
+
+	webhook, err := wh.queries.GetWebhookSubscriptionByID(r.Context(), database.GetWebhookSubscriptionByIDParams{
+		ID:             webhookID,
+		AccountID:      claims.AccountID,
+		IsnSlug:        r.PathValue("isn_slug"),
+		SignalTypeSlug: r.PathValue("signal_type_slug"),
+		SemVer:         r.PathValue("sem_ver"),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.GetWebhookSubscriptionByIDRow{}, nil, apperrors.NotFound("webhook not found", nil)
+		}
+		return database.GetWebhookSubscriptionByIDRow{}, nil, apperrors.DatabaseError("database error", err)
+	}
+
+	return webhook, claims, nil
+}
+
+// validateTargetURL checks the webhook target is an absolute http(s) URL.
+// https is required outside of the dev and test environments.
+func (wh *WebhooksHandler) validateTargetURL(targetURL string) error {
+	u, err := url.ParseRequestURI(targetURL)
+	if err != nil {
+		return fmt.Errorf("target_url is not a valid URL")
+	}
+
+	if u.Hostname() == "" {
+		return fmt.Errorf("target_url does not include a host")
+	}
+
+	if u.User != nil {
+		return fmt.Errorf("target_url must not include credentials")
+	}
+
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if wh.environment == "dev" || wh.environment == "test" {
+			return nil
+		}
+		return fmt.Errorf("target_url must use https")
+	default:
+		return fmt.Errorf("target_url must use https")
+	}
+}
@@ -12,4 +12,7 @@
 //
 //   - RateLimit: enforces a global token-bucket rate limit (requests/sec + burst).
 //     Disabled when requestsPerSecond <= 0.
+//
+//   - Timeout: cancels the request context after a timeout (chi's middleware.Timeout), except for
+//     long-lived streaming requests (signal streams and streamed search exports).
 package middleware
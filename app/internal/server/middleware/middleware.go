@@ -5,7 +5,9 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/jub0bs/cors"
 	"golang.org/x/time/rate"
 
@@ -105,3 +107,21 @@ func RateLimit(requestsPerSecond int32, burst int32) func(http.Handler) http.Han
 		})
 	}
 }
+
+// Timeout cancels the request context after the timeout and responds with a 504 if the handler has not written a response (see chi's middleware.Timeout).
+//
+// Long-lived streaming requests (requests where isLongLived returns true) are not subject to the timeout.
+// These handlers are responsible for clearing the server write deadline and stopping when the client disconnects.
+func Timeout(timeout time.Duration, isLongLived func(r *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withTimeout := chimiddleware.Timeout(timeout)(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isLongLived != nil && isLongLived(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			withTimeout.ServeHTTP(w, r)
+		})
+	}
+}
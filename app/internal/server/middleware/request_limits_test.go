@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -135,3 +136,39 @@ func TestRateLimitDisabled(t *testing.T) {
 		})
 	}
 }
+
+func TestTimeoutLongLivedRequests(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		expectDeadline bool
+	}{
+		{"Timeout applied", "/api/isn/sample-isn/signals/search", true},
+		{"Long-lived request", "/api/isn/sample-isn/signals/stream", false},
+	}
+
+	isLongLived := func(r *http.Request) bool {
+		return strings.HasSuffix(r.URL.Path, "/signals/stream")
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := chi.NewRouter()
+			router.Use(Timeout(time.Minute, isLongLived))
+			router.Get("/*", func(w http.ResponseWriter, r *http.Request) {
+				if _, hasDeadline := r.Context().Deadline(); hasDeadline != tt.expectDeadline {
+					t.Errorf("request context deadline set = %v, want %v", hasDeadline, tt.expectDeadline)
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("got status %d, want %d", rr.Code, http.StatusOK)
+			}
+		})
+	}
+}
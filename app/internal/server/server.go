@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/information-sharing-networks/signalsd/app/internal/auth"
 	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/ingestion"
+	"github.com/information-sharing-networks/signalsd/app/internal/invalidation"
 	"github.com/information-sharing-networks/signalsd/app/internal/logger"
 	"github.com/information-sharing-networks/signalsd/app/internal/publicisns"
 	"github.com/information-sharing-networks/signalsd/app/internal/responses"
@@ -21,6 +24,7 @@ import (
 	"github.com/information-sharing-networks/signalsd/app/internal/server/middleware"
 	"github.com/information-sharing-networks/signalsd/app/internal/ui/config"
 	uiserver "github.com/information-sharing-networks/signalsd/app/internal/ui/server"
+	"github.com/information-sharing-networks/signalsd/app/internal/webhooks"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -47,16 +51,29 @@ type Server struct {
 	router *chi.Mux
 
 	// schemaCache is the JSON schema cache for signal types - used to validate incoming signals
-	// refreshed by polling the database every CachePollInterval.
+	// refreshed when a cache invalidation notification is received and by polling the database every CachePollInterval.
 	schemaCache *schemas.Cache
 
 	// publicIsnCache is the cache of public ISNs and their signal types - used by the public signal search endpoint
-	// refreshed by polling the database every CachePollInterval.
+	// refreshed when a cache invalidation notification is received and by polling the database every CachePollInterval.
 	publicIsnCache *publicisns.Cache
 
 	// signalRouterCache holds the compiled Signals Routing Rules. It is loaded at startup and
-	// refreshed by polling the database every CachePollInterval.
+	// refreshed when a cache invalidation notification is received and by polling the database every CachePollInterval.
 	signalRouterCache *router.Cache
+
+	// webhookDispatcher sends new signal versions to webhook subscribers.
+	// Only created when the signal write routes are registered (nil otherwise).
+	webhookDispatcher *webhooks.Dispatcher
+
+	// signalSubmissionWorker processes the signal submissions that were queued for async processing (Prefer: respond-async).
+	// Only created when the signal write routes are registered (nil otherwise).
+	signalSubmissionWorker *ingestion.Worker
+
+	// shutdownCtx is cancelled when the HTTP server starts shutting down.
+	// http.Server.Shutdown waits for active requests to complete, so it is used to close long-lived signal streams.
+	shutdownCtx    context.Context
+	shutdownCancel context.CancelFunc
 }
 
 func NewServer(
@@ -82,6 +99,7 @@ func NewServer(
 		publicIsnCache:    publicIsnCache,
 		signalRouterCache: signalRouterCache,
 	}
+	server.shutdownCtx, server.shutdownCancel = context.WithCancel(context.Background())
 
 	server.setupMiddleware()
 	server.registerCommonRoutes()
@@ -122,12 +140,40 @@ func (s *Server) Start(ctx context.Context) error {
 		WriteTimeout: s.config.WriteTimeout,
 		IdleTimeout:  s.config.IdleTimeout,
 	}
+	httpServer.RegisterOnShutdown(s.shutdownCancel)
 
-	// Start polling for cache changes.
+	// Reload the caches when other instances (or this one) notify that the cached data has changed.
+	caches := map[invalidation.CacheName]invalidation.Loader{
+		invalidation.RouterCache:    s.signalRouterCache,
+		invalidation.PublicIsnCache: s.publicIsnCache,
+		invalidation.SchemaCache:    s.schemaCache,
+	}
+	signingKeys := s.authService.SigningKeys()
+	if signingKeys != nil {
+		caches[invalidation.SigningKeys] = signingKeys
+	}
+	invalidation.NewListener(s.pool, caches).Start(ctx, signalsd.CacheListenerRetryInterval)
+
+	// Start polling for cache changes (fallback in case invalidation notifications are missed).
 	s.signalRouterCache.StartPolling(ctx, signalsd.CachePollInterval)
 	s.publicIsnCache.StartPolling(ctx, signalsd.CachePollInterval)
 	s.schemaCache.StartPolling(ctx, signalsd.CachePollInterval)
 
+	// Start the access token signing key rotation checks (these also reload the key set)
+	if signingKeys != nil {
+		signingKeys.StartRotation(ctx, signalsd.SigningKeyRotationCheckInterval)
+	}
+
+	// Start sending webhook deliveries
+	if s.webhookDispatcher != nil {
+		s.webhookDispatcher.Start(ctx, signalsd.WebhookPollInterval)
+	}
+
+	// Start processing queued signal submissions
+	if s.signalSubmissionWorker != nil {
+		s.signalSubmissionWorker.Start(ctx, signalsd.SignalSubmissionPollInterval)
+	}
+
 	serverErrors := make(chan error, 1)
 
 	// Start HTTP server
@@ -189,7 +235,18 @@ func (s *Server) setupMiddleware() {
 	s.router.Use(middleware.RateLimit(s.config.RateLimitRPS, s.config.RateLimitBurst))
 	// Cancel r.Context() just before WriteTimeout drops the TCP connection,
 	// so in-flight DB queries and goroutines are abandoned cleanly.
-	s.router.Use(chimiddleware.Timeout(s.config.WriteTimeout - time.Second))
+	// Signal streams and streamed search exports hold the connection open and are excluded.
+	s.router.Use(middleware.Timeout(s.config.WriteTimeout-time.Second, isLongLivedRequest))
+}
+
+// isLongLivedRequest reports whether the request is for a signal stream or a streamed (ndjson/csv) signal search export.
+// These requests are not subject to the server write timeout.
+func isLongLivedRequest(r *http.Request) bool {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if strings.HasSuffix(path, "/signals/stream") {
+		return true
+	}
+	return strings.HasSuffix(path, "/signals/search") && handlers.SearchExportFormat(r) != ""
 }
 
 func (s *Server) registerAdminRoutes() {
@@ -197,7 +254,8 @@ func (s *Server) registerAdminRoutes() {
 	users := handlers.NewUserHandler(s.queries, s.authService, s.pool)
 	serviceAccounts := handlers.NewServiceAccountHandler(s.queries, s.authService, s.pool, s.config.PublicBaseURL)
 	login := handlers.NewLoginHandler(s.queries, s.authService, s.config.Environment)
-	tokens := handlers.NewTokenHandler(s.queries, s.authService, s.pool, s.config.Environment)
+	oidcLogin := handlers.NewOIDCLoginHandler(s.queries, s.authService, s.pool, s.config.Environment, s.config.PublicBaseURL, s.config.OIDCProviders)
+	tokens := handlers.NewTokenHandler(s.queries, s.authService, s.pool, s.config.Environment, s.config.PublicBaseURL)
 
 	// site admin handlers
 	admin := handlers.NewAdminHandler(s.queries, s.pool, s.authService, s.config.PublicBaseURL)
@@ -205,7 +263,8 @@ func (s *Server) registerAdminRoutes() {
 	// isn definition handlers
 	isn := handlers.NewIsnHandler(s.queries, s.pool, s.config.PublicBaseURL)
 	signalTypes := handlers.NewSignalTypeHandler(s.queries)
-	isnRouter := handlers.NewRoutingConfigHandler(s.queries, s.pool, s.signalRouterCache, s.schemaCache)
+	isnRouter := handlers.NewRoutingConfigHandler(s.queries, s.pool, s.authService, s.signalRouterCache, s.schemaCache)
+	unroutableSignals := handlers.NewSignalRouter(s.queries, s.pool, s.authService, s.schemaCache, s.signalRouterCache)
 
 	// isn permissions
 	isnAccount := handlers.NewIsnAccountHandler(s.queries)
@@ -228,6 +287,13 @@ func (s *Server) registerAdminRoutes() {
 				// revoke a client secret (service accounts) or refresh token (web users)
 				r.Post("/revoke", responses.Wrap(tokens.RevokeToken))
 			})
+
+			r.Group(func(r chi.Router) {
+				// token introspection is available to service accounts (e.g resource servers checking tokens presented to them)
+				r.Use(s.authService.RequireValidClientCredentials)
+
+				r.Post("/introspect", responses.Wrap(tokens.IntrospectToken))
+			})
 		})
 
 		// api routes used to administer the ISNs (excluding signal ingestion)
@@ -248,6 +314,9 @@ func (s *Server) registerAdminRoutes() {
 
 					r.Post("/service-accounts/register", responses.Wrap(serviceAccounts.RegisterServiceAccount))
 					r.Post("/service-accounts/reissue-credentials", responses.Wrap(serviceAccounts.ReissueServiceAccountCredentials))
+					r.Post("/service-accounts/{client_id}/keys", responses.Wrap(serviceAccounts.RegisterServiceAccountKey))
+					r.Get("/service-accounts/{client_id}/keys", responses.Wrap(serviceAccounts.GetServiceAccountKeys))
+					r.Delete("/service-accounts/{client_id}/keys/{key_id}", responses.Wrap(serviceAccounts.RevokeServiceAccountKey))
 				})
 
 				r.Group(func(r chi.Router) {
@@ -259,6 +328,10 @@ func (s *Server) registerAdminRoutes() {
 				// no authentication required
 				r.Post("/register", responses.Wrap(users.RegisterUser))
 				r.Post("/login", responses.Wrap(login.Login))
+				r.Get("/oidc/providers", responses.Wrap(oidcLogin.GetIdentityProviders))
+				r.Get("/oidc/{provider_id}/login", responses.Wrap(oidcLogin.Login))
+				r.Get("/oidc/{provider_id}/callback", responses.Wrap(oidcLogin.Callback))
+				r.Post("/oidc/token", responses.Wrap(oidcLogin.Token))
 				r.Get("/service-accounts/setup/{setup_id}", serviceAccounts.SetupServiceAccount)
 				r.Get("/password-reset/{token_id}", users.PasswordResetTokenPage)
 				r.Post("/password-reset/{token_id}", responses.Wrap(users.PasswordResetToken))
@@ -341,6 +414,18 @@ func (s *Server) registerAdminRoutes() {
 				r.Get("/signal-types/{signal_type_slug}/v{sem_ver}/routes", responses.Wrap(isnRouter.GetSignalRoutingConfig))
 				r.Put("/signal-types/{signal_type_slug}/v{sem_ver}/routes", responses.Wrap(isnRouter.UpdateSignalRoutingConfig))
 				r.Delete("/signal-types/{signal_type_slug}/v{sem_ver}/routes", responses.Wrap(isnRouter.DeleteSignalRoutingConfig))
+				r.Post("/signal-types/{signal_type_slug}/v{sem_ver}/routes/dry-run", responses.Wrap(isnRouter.DryRunSignalRoutingConfig))
+
+				// routing config revision history
+				r.Get("/signal-types/{signal_type_slug}/v{sem_ver}/routes/revisions", responses.Wrap(isnRouter.ListSignalRoutingConfigRevisions))
+				r.Get("/signal-types/{signal_type_slug}/v{sem_ver}/routes/revisions/{revision}", responses.Wrap(isnRouter.GetSignalRoutingConfigRevision))
+				r.Post("/signal-types/{signal_type_slug}/v{sem_ver}/routes/revisions/{revision}/rollback", responses.Wrap(isnRouter.RollbackSignalRoutingConfig))
+
+				// signals submitted to the signal router that did not match any routing rule
+				r.Get("/unroutable-signals", responses.Wrap(unroutableSignals.ListUnroutableSignals))
+				r.Get("/unroutable-signals/{unroutable_signal_id}", responses.Wrap(unroutableSignals.GetUnroutableSignal))
+				r.Post("/unroutable-signals/{unroutable_signal_id}/reroute", responses.Wrap(unroutableSignals.RerouteUnroutableSignal))
+				r.Delete("/unroutable-signals/{unroutable_signal_id}", responses.Wrap(unroutableSignals.DeleteUnroutableSignal))
 			})
 
 			// shared site-admin and isn-admin operations
@@ -364,7 +449,13 @@ func (s *Server) registerAdminRoutes() {
 func (s *Server) registerSignalWriteRoutes() {
 	signals := handlers.NewSignalsHandler(s.queries, s.pool, s.schemaCache, s.publicIsnCache)
 	signalBatches := handlers.NewSignalsBatchHandler(s.queries)
-	routerSignals := handlers.NewSignalRouter(s.queries, s.pool, s.schemaCache, s.signalRouterCache)
+	routerSignals := handlers.NewSignalRouter(s.queries, s.pool, s.authService, s.schemaCache, s.signalRouterCache)
+
+	// webhook deliveries are queued when signals are stored - subscribers on internal addresses are only permitted in dev/test
+	s.webhookDispatcher = webhooks.NewDispatcher(s.queries, s.config.Environment == "dev" || s.config.Environment == "test")
+
+	// submissions made with a Prefer: respond-async header are queued by the handlers and processed in the background
+	s.signalSubmissionWorker = ingestion.NewWorker(s.queries, handlers.NewSignalSubmissionProcessor(s.authService, signals, routerSignals))
 
 	s.router.Group(func(r chi.Router) {
 		r.Use(middleware.CORS(s.corsConfigs.Protected))
@@ -375,6 +466,11 @@ func (s *Server) registerSignalWriteRoutes() {
 		// direct ISN signal post and withdrawal
 		r.Post("/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/signals", responses.Wrap(signals.CreateSignals))
 		r.Put("/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/signals/withdraw", responses.Wrap(signals.WithdrawSignal))
+		r.Put("/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/signals/bulk-withdraw", responses.Wrap(signals.BulkWithdrawSignals))
+		r.Put("/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/signals/bulk-reinstate", responses.Wrap(signals.BulkReinstateSignals))
+
+		// multi signal type submission - the signal types are checked in the handler
+		r.Post("/api/isn/{isn_slug}/signals", responses.Wrap(signals.CreateBulkSignals))
 
 		// batch status endpoints
 		r.Get("/api/batches/search", responses.Wrap(signalBatches.SearchBatches))
@@ -395,6 +491,7 @@ func (s *Server) registerSignalWriteRoutes() {
 // registerSignalReadRoutes registers signal read routes
 func (s *Server) registerSignalReadRoutes() {
 	signals := handlers.NewSignalsHandler(s.queries, s.pool, s.schemaCache, s.publicIsnCache)
+	webhookSubscriptions := handlers.NewWebhooksHandler(s.queries, s.authService, s.config.Environment)
 
 	// Public ISN signal search - no authentication required
 	s.router.Group(func(r chi.Router) {
@@ -402,21 +499,49 @@ func (s *Server) registerSignalReadRoutes() {
 		r.Get("/api/public/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/signals/search", responses.Wrap(signals.SearchPublicSignals))
 	})
 
-	// Private ISN signal search - any ISN member (read or write) may call this endpoint.
+	// Private ISN signal search, changes feed and stream - any ISN member (read or write) may call these endpoints.
 	// Write-only accounts receive only the signals they created; visibility filtering is applied in the handler.
 	s.router.Group(func(r chi.Router) {
 		r.Use(middleware.CORS(s.corsConfigs.Protected))
 		r.Use(s.authService.RequireValidAccessToken)
 		r.Use(s.authService.RequireIsnMembership)
 		r.Get("/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/signals/search", responses.Wrap(signals.SearchPrivateSignals))
+		r.Get("/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/signals/changes", responses.Wrap(signals.GetSignalChanges))
+		r.With(s.cancelOnShutdown).Get("/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/signals/stream", responses.Wrap(signals.StreamSignals))
+	})
+
+	// Webhook subscriptions - accounts must have read permission on the ISN
+	s.router.Group(func(r chi.Router) {
+		r.Use(middleware.CORS(s.corsConfigs.Protected))
+		r.Use(middleware.RequestSizeLimit(s.config.MaxAPIRequestSize))
+		r.Use(s.authService.RequireValidAccessToken)
+		r.Use(s.authService.RequireAccessPermission("read"))
+		r.Post("/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/webhooks", responses.Wrap(webhookSubscriptions.CreateWebhook))
+		r.Get("/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/webhooks", responses.Wrap(webhookSubscriptions.GetWebhooks))
+		r.Delete("/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/webhooks/{webhook_id}", responses.Wrap(webhookSubscriptions.DeleteWebhook))
+		r.Get("/api/isn/{isn_slug}/signal-types/{signal_type_slug}/v{sem_ver}/webhooks/{webhook_id}/deliveries", responses.Wrap(webhookSubscriptions.GetWebhookDeliveries))
 	})
 
 }
 
+// cancelOnShutdown cancels the request context when the server starts shutting down (used for long-lived streaming requests)
+func (s *Server) cancelOnShutdown(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		stop := context.AfterFunc(s.shutdownCtx, cancel)
+		defer stop()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // registerCommonRoutes registers routes that are always available regardless of service mode
 // These routes include health checks and version information
 func (s *Server) registerCommonRoutes() {
 	admin := handlers.NewAdminHandler(s.queries, s.pool, s.authService, s.config.PublicBaseURL)
+	tokens := handlers.NewTokenHandler(s.queries, s.authService, s.pool, s.config.Environment, s.config.PublicBaseURL)
 
 	// Health check endpoints
 	s.router.Route("/health", func(r chi.Router) {
@@ -434,6 +559,13 @@ func (s *Server) registerCommonRoutes() {
 		r.Use(middleware.CORS(s.corsConfigs.Public))
 		r.Get("/", responses.Wrap(admin.Version))
 	})
+
+	// OAuth metadata and the public keys used to verify access tokens (JSON Web Key Set)
+	s.router.Route("/.well-known", func(r chi.Router) {
+		r.Use(middleware.CORS(s.corsConfigs.Public))
+		r.Get("/oauth-authorization-server", responses.Wrap(tokens.AuthorizationServerMetadata))
+		r.Get("/jwks.json", responses.Wrap(tokens.JWKS))
+	})
 }
 
 // registerApiDocoRoutes serves public static assets and API documentation
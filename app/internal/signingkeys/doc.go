@@ -0,0 +1,26 @@
+// Package signingkeys manages the asymmetric keys used to sign access tokens.
+//
+// By default access tokens are signed with HS256 using SECRET_KEY, which means only signalsd can verify them.
+// When ACCESS_TOKEN_SIGNING_ALG is set to RS256, ES256 or EdDSA the tokens are signed with a private key stored in the signing_keys table
+// and the public keys are published at /.well-known/jwks.json so that other services (API gateways, partner services) can verify the tokens offline.
+// The kid header in each token is the id of the key used to sign it.
+//
+// # Rotation
+//
+// Each instance calls [KeySet.Rotate] at start up and every SigningKeyRotationCheckInterval (an advisory lock ensures only one instance makes changes at a time).
+// The next key is created SigningKeyPublishLeadTime before it is used so that it is in the published key set before any tokens are signed with it,
+// and the current key is retired when the next key activates. Retired keys stay in the key set until the tokens signed with them have expired.
+//
+// When the algorithm is changed the existing keys are retired immediately and a key for the new algorithm is created
+// (tokens signed with the old keys remain valid until they expire).
+// When the service switches from HS256 to an asymmetric algorithm, HS256 tokens are accepted until the access token lifetime has passed
+// (see [KeySet.HS256AcceptedUntil]) and are rejected after that.
+//
+// The other instances are told to reload the key set using a cache invalidation notification (see the invalidation package).
+//
+// # Private keys
+//
+// Private keys are stored encrypted with AES-256-GCM using a key derived from SECRET_KEY.
+// If SECRET_KEY is changed the existing keys can no longer be used to sign tokens - they are retired and a new key is created
+// (the public keys are stored unencrypted so tokens already issued can still be verified).
+package signingkeys
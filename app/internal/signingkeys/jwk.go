@@ -0,0 +1,133 @@
+package signingkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// JWKSet is a JSON Web Key Set (RFC 7517) - the response from the /.well-known/jwks.json endpoint
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is the public part of a signing key in JSON Web Key format.
+// The key parameters depend on the key type: n and e (RSA), crv, x and y (EC) and crv and x (OKP - used for Ed25519 keys).
+type JWK struct {
+	Kty string `json:"kty" enums:"RSA,EC,OKP" example:"EC"`
+	Use string `json:"use" example:"sig"`
+	Alg string `json:"alg" enums:"RS256,ES256,EdDSA" example:"ES256"`
+	Kid string `json:"kid" example:"0199d3f4-3b4e-7c1a-9f1e-2d9a8e4c5b6a"`
+	Crv string `json:"crv,omitempty" example:"P-256"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty" example:"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU"`
+	Y   string `json:"y,omitempty" example:"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"`
+}
+
+// NewJWK returns the public key in JWK format
+func NewJWK(key *Key) (JWK, error) {
+	jwk := JWK{
+		Use: "sig",
+		Alg: key.Algorithm,
+		Kid: key.ID.String(),
+	}
+
+	switch publicKey := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encode(publicKey.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		if publicKey.Curve != elliptic.P256() {
+			return JWK{}, fmt.Errorf("key %s: unsupported curve %s", jwk.Kid, publicKey.Curve.Params().Name)
+		}
+		// uncompressed point: 0x04 || x || y
+		point, err := publicKey.Bytes()
+		if err != nil {
+			return JWK{}, fmt.Errorf("key %s: %v", jwk.Kid, err)
+		}
+		size := (len(point) - 1) / 2
+		jwk.Kty = "EC"
+		jwk.Crv = "P-256"
+		jwk.X = encode(point[1 : 1+size])
+		jwk.Y = encode(point[1+size:])
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encode(publicKey)
+	default:
+		return JWK{}, fmt.Errorf("key %s: unsupported key type %T", jwk.Kid, key.PublicKey)
+	}
+	return jwk, nil
+}
+
+// PublicKey returns the public key described by the JWK (used to verify tokens signed by other services - e.g. OpenID Connect id_tokens).
+// RSA, EC (P-256, P-384 and P-521) and OKP (Ed25519) keys are supported.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decode(j.N)
+		if err != nil || len(n) == 0 {
+			return nil, fmt.Errorf("key %s: invalid n parameter", j.Kid)
+		}
+		e, err := decode(j.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("key %s: invalid e parameter", j.Kid)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("key %s: unsupported curve %s", j.Kid, j.Crv)
+		}
+		x, errX := decode(j.X)
+		y, errY := decode(j.Y)
+		size := (curve.Params().BitSize + 7) / 8
+		if errX != nil || errY != nil || len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("key %s: invalid x or y parameter", j.Kid)
+		}
+		// uncompressed point: 0x04 || x || y
+		point := append([]byte{4}, append(x, y...)...)
+		publicKey, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", j.Kid, err)
+		}
+		return publicKey, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("key %s: unsupported curve %s", j.Kid, j.Crv)
+		}
+		x, err := decode(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("key %s: invalid x parameter", j.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("key %s: unsupported key type %s", j.Kid, j.Kty)
+	}
+}
+
+// encode returns the base64url encoding (without padding) used for JWK parameters
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
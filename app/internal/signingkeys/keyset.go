@@ -0,0 +1,543 @@
+package signingkeys
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/invalidation"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// the signing algorithms (ACCESS_TOKEN_SIGNING_ALG)
+const (
+	HS256 = "HS256" // tokens are signed with SECRET_KEY (no signing keys are used)
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
+
+// encryptionKeyInfo is the HKDF info string used to derive the private key encryption key from SECRET_KEY
+const encryptionKeyInfo = "signalsd access token signing keys"
+
+// Key is a signing key loaded from the database
+type Key struct {
+	ID          uuid.UUID
+	Algorithm   string
+	ActivatesAt time.Time
+	RetiresAt   *time.Time
+	PublicKey   crypto.PublicKey
+
+	// privateKey is nil when the key could not be decrypted (SECRET_KEY has changed since the key was created)
+	privateKey crypto.Signer
+}
+
+// SigningMethod returns the JWT signing method for the key
+func (k *Key) SigningMethod() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// PrivateKey returns the key used to sign tokens (nil if the key can't be used for signing)
+func (k *Key) PrivateKey() crypto.Signer {
+	return k.privateKey
+}
+
+// canSign reports whether the key is used to sign tokens at time t
+func (k *Key) canSign(t time.Time) bool {
+	return k.privateKey != nil && !k.ActivatesAt.After(t) && (k.RetiresAt == nil || k.RetiresAt.After(t))
+}
+
+// canVerify reports whether tokens signed with the key can still be valid at time t
+func (k *Key) canVerify(t time.Time) bool {
+	return k.RetiresAt == nil || k.RetiresAt.Add(signalsd.AccessTokenExpiry).After(t)
+}
+
+// KeySet holds the signing keys in memory.
+//
+// The key set is loaded at start up and is reloaded after each rotation check and when a cache invalidation notification is received.
+type KeySet struct {
+	pool             *pgxpool.Pool
+	queries          *database.Queries
+	algorithm        string
+	rotationInterval time.Duration
+	encryptionKey    []byte
+
+	mu   sync.RWMutex
+	keys []*Key // ordered by activation time
+}
+
+// NewKeySet creates a key set that signs tokens with algorithm and creates a new key every rotationInterval.
+//
+// When algorithm is HS256 no keys are created, but keys created previously are published until the tokens signed with them have expired.
+func NewKeySet(pool *pgxpool.Pool, queries *database.Queries, secretKey string, algorithm string, rotationInterval time.Duration) (*KeySet, error) {
+	if !signalsd.ValidAccessTokenSigningAlgs[algorithm] {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	encryptionKey, err := hkdf.Key(sha256.New, []byte(secretKey), nil, encryptionKeyInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("could not derive the signing key encryption key: %v", err)
+	}
+
+	return &KeySet{
+		pool:             pool,
+		queries:          queries,
+		algorithm:        algorithm,
+		rotationInterval: rotationInterval,
+		encryptionKey:    encryptionKey,
+	}, nil
+}
+
+// Algorithm returns the algorithm used to sign new tokens
+func (s *KeySet) Algorithm() string {
+	return s.algorithm
+}
+
+// Load loads the keys that can be used to verify tokens from the database and replaces the key set
+func (s *KeySet) Load(ctx context.Context) error {
+	rows, err := s.queries.GetSigningKeys(ctx, time.Now().Add(-signalsd.AccessTokenExpiry))
+	if err != nil {
+		return fmt.Errorf("failed to get signing keys from database: %v", err)
+	}
+
+	keys, err := s.parseKeys(rows)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of keys in the key set
+func (s *KeySet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// SigningKey returns the key used to sign new tokens (nil if there is no usable key for the configured algorithm)
+func (s *KeySet) SigningKey() *Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return currentKey(s.keys, s.algorithm, time.Now())
+}
+
+// HS256AcceptedUntil returns the time after which HS256 tokens are rejected.
+//
+// HS256 tokens are still accepted after switching to an asymmetric algorithm so that the tokens issued before the switch remain valid,
+// but only until the longest lived of those tokens has expired (the switch time plus the access token lifetime).
+// ok is false when HS256 tokens are accepted without a deadline (the algorithm is HS256 or no signing key has been created yet).
+func (s *KeySet) HS256AcceptedUntil() (until time.Time, ok bool) {
+	if s.algorithm == HS256 {
+		return time.Time{}, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switchedAt := switchTime(s.keys)
+	if switchedAt.IsZero() {
+		return time.Time{}, false
+	}
+	return switchedAt.Add(signalsd.AccessTokenExpiry), true
+}
+
+// VerificationKey returns the public key identified by kid.
+// An error is returned if the key is not in the key set, has expired or was not created for alg.
+func (s *KeySet) VerificationKey(kid string, alg string) (crypto.PublicKey, error) {
+	id, err := uuid.Parse(kid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kid: %q", kid)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	for _, key := range s.keys {
+		if key.ID != id {
+			continue
+		}
+		if key.Algorithm != alg {
+			return nil, fmt.Errorf("key %s is not a %s key", kid, alg)
+		}
+		if !key.canVerify(now) {
+			return nil, fmt.Errorf("key %s has expired", kid)
+		}
+		return key.PublicKey, nil
+	}
+	return nil, fmt.Errorf("unknown key: %s", kid)
+}
+
+// JWKS returns the public keys in JSON Web Key Set format.
+// Keys that are not active yet are included so that verifiers have them before they are used.
+func (s *KeySet) JWKS() (JWKSet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	now := time.Now()
+	for _, key := range s.keys {
+		if !key.canVerify(now) {
+			continue
+		}
+		jwk, err := NewJWK(key)
+		if err != nil {
+			return JWKSet{}, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// Rotate creates and retires keys as needed and then reloads the key set.
+//
+// Only one instance makes changes at a time (the changes are made in a transaction holding an advisory lock).
+// The other instances are notified when the keys change.
+func (s *KeySet) Rotate(ctx context.Context) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("could not start transaction: %v", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			slog.Error("signing keys: rollback failed", slog.String("error", err.Error()))
+		}
+	}()
+
+	txQueries := s.queries.WithTx(tx)
+
+	if err := txQueries.LockSigningKeys(ctx); err != nil {
+		return fmt.Errorf("could not lock signing keys: %v", err)
+	}
+
+	now := time.Now()
+	rows, err := txQueries.GetSigningKeys(ctx, now.Add(-signalsd.AccessTokenExpiry))
+	if err != nil {
+		return fmt.Errorf("failed to get signing keys from database: %v", err)
+	}
+	keys, err := s.parseKeys(rows)
+	if err != nil {
+		return err
+	}
+
+	plan := planRotation(keys, s.algorithm, s.rotationInterval, signalsd.SigningKeyPublishLeadTime, now)
+
+	changed := false
+	for id, retiresAt := range plan.retire {
+		n, err := txQueries.RetireSigningKey(ctx, database.RetireSigningKeyParams{
+			ID:        id,
+			RetiresAt: retiresAt,
+		})
+		if err != nil {
+			return fmt.Errorf("could not retire signing key %s: %v", id, err)
+		}
+		changed = changed || n > 0
+	}
+
+	if plan.newKeyActivatesAt != nil {
+		params, err := s.newKey(*plan.newKeyActivatesAt)
+		if err != nil {
+			return err
+		}
+		if _, err := txQueries.CreateSigningKey(ctx, params); err != nil {
+			return fmt.Errorf("could not create signing key: %v", err)
+		}
+		changed = true
+		slog.Info("signing keys: created new key",
+			slog.String("kid", params.ID.String()),
+			slog.String("algorithm", params.Algorithm),
+			slog.Time("activates_at", params.ActivatesAt),
+		)
+	}
+
+	deleted, err := txQueries.DeleteExpiredSigningKeys(ctx, now.Add(-signalsd.AccessTokenExpiry))
+	if err != nil {
+		return fmt.Errorf("could not delete expired signing keys: %v", err)
+	}
+
+	if changed || deleted > 0 {
+		invalidation.Notify(ctx, txQueries, invalidation.SigningKeys)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("could not commit signing key changes: %v", err)
+	}
+
+	return s.Load(ctx)
+}
+
+// StartRotation starts a background goroutine that calls Rotate every interval.
+// Errors are logged but do not stop the loop. The goroutine exits when ctx is cancelled.
+func (s *KeySet) StartRotation(ctx context.Context, interval time.Duration) {
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Rotate(ctx); err != nil {
+					slog.Error("signing keys: rotation failed", slog.String("error", err.Error()))
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// rotationPlan lists the changes needed to the signing keys
+type rotationPlan struct {
+	newKeyActivatesAt *time.Time              // set when a new key is needed
+	retire            map[uuid.UUID]time.Time // key id -> retires_at
+}
+
+// planRotation works out which keys to create and retire at time now.
+//
+//   - when there is no usable key for the algorithm a new key is created that activates immediately and all the other keys are retired.
+//   - when the current key is within leadTime of the end of the rotation interval the next key is created and the current key is retired when the next key activates.
+//   - when the algorithm is HS256 all the keys are retired.
+func planRotation(keys []*Key, algorithm string, interval time.Duration, leadTime time.Duration, now time.Time) rotationPlan {
+	plan := rotationPlan{retire: make(map[uuid.UUID]time.Time)}
+
+	retireOthers := func(keep *Key) {
+		for _, key := range keys {
+			if key != keep && key.RetiresAt == nil {
+				plan.retire[key.ID] = now
+			}
+		}
+	}
+
+	if algorithm == HS256 {
+		retireOthers(nil)
+		return plan
+	}
+
+	current := currentKey(keys, algorithm, now)
+	if current == nil {
+		plan.newKeyActivatesAt = &now
+		retireOthers(nil)
+		return plan
+	}
+
+	// keys for other algorithms and keys that can't be decrypted are not used
+	var next *Key
+	for _, key := range keys {
+		if key == current || key.RetiresAt != nil {
+			continue
+		}
+		if key.Algorithm == algorithm && key.privateKey != nil && key.ActivatesAt.After(current.ActivatesAt) {
+			next = key
+			continue
+		}
+		plan.retire[key.ID] = now
+	}
+
+	if next != nil {
+		if current.RetiresAt == nil {
+			plan.retire[current.ID] = next.ActivatesAt
+		}
+		return plan
+	}
+
+	rotateAt := current.ActivatesAt.Add(interval)
+	if current.RetiresAt != nil && current.RetiresAt.Before(rotateAt) {
+		rotateAt = *current.RetiresAt
+	}
+	if now.Before(rotateAt.Add(-leadTime)) {
+		return plan
+	}
+
+	activatesAt := rotateAt
+	if earliest := now.Add(leadTime); activatesAt.Before(earliest) {
+		activatesAt = earliest
+	}
+	plan.newKeyActivatesAt = &activatesAt
+	if current.RetiresAt == nil || current.RetiresAt.Before(activatesAt) {
+		plan.retire[current.ID] = activatesAt
+	}
+	return plan
+}
+
+// currentKey returns the most recently activated key for the algorithm that can be used to sign tokens at time t
+func currentKey(keys []*Key, algorithm string, t time.Time) *Key {
+	var current *Key
+	for _, key := range keys {
+		if key.Algorithm == algorithm && key.canSign(t) {
+			current = key
+		}
+	}
+	return current
+}
+
+// switchTime returns the time the service switched from HS256 to signing tokens with the keys (zero if there are no keys).
+//
+// This is the activation time of the first key in the current run of keys - keys that were used one after another without a gap.
+// A gap means no key was in use (the algorithm was changed to HS256 and back again) so HS256 tokens may have been issued until the later key activated.
+// The keys must be ordered by activation time.
+func switchTime(keys []*Key) time.Time {
+	var (
+		switchedAt time.Time
+		usedUntil  *time.Time // nil when the run of keys has not ended
+	)
+	for _, key := range keys {
+		if switchedAt.IsZero() || (usedUntil != nil && usedUntil.Before(key.ActivatesAt)) {
+			switchedAt = key.ActivatesAt
+			usedUntil = key.RetiresAt
+			continue
+		}
+		if usedUntil != nil && (key.RetiresAt == nil || key.RetiresAt.After(*usedUntil)) {
+			usedUntil = key.RetiresAt
+		}
+	}
+	return switchedAt
+}
+
+// newKey generates a key pair for the configured algorithm
+func (s *KeySet) newKey(activatesAt time.Time) (database.CreateSigningKeyParams, error) {
+	var (
+		privateKey crypto.Signer
+		err        error
+	)
+	switch s.algorithm {
+	case RS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, signalsd.SigningKeyRSABits)
+	case ES256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case EdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return database.CreateSigningKeyParams{}, fmt.Errorf("signing keys are not used with %s", s.algorithm)
+	}
+	if err != nil {
+		return database.CreateSigningKeyParams{}, fmt.Errorf("could not generate %s key: %v", s.algorithm, err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return database.CreateSigningKeyParams{}, fmt.Errorf("could not encode public key: %v", err)
+	}
+	privateKeyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return database.CreateSigningKeyParams{}, fmt.Errorf("could not encode private key: %v", err)
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return database.CreateSigningKeyParams{}, fmt.Errorf("could not create key id: %v", err)
+	}
+	encryptedPrivateKey, err := s.encrypt(id, privateKeyDER)
+	if err != nil {
+		return database.CreateSigningKeyParams{}, err
+	}
+
+	return database.CreateSigningKeyParams{
+		ID:                  id,
+		Algorithm:           s.algorithm,
+		PublicKey:           publicKey,
+		EncryptedPrivateKey: encryptedPrivateKey,
+		ActivatesAt:         activatesAt,
+	}, nil
+}
+
+// parseKeys decodes the keys loaded from the database.
+// Keys with private keys that can't be decrypted are kept (they can still be used to verify tokens) but are not used for signing.
+func (s *KeySet) parseKeys(rows []database.SigningKey) ([]*Key, error) {
+	keys := make([]*Key, 0, len(rows))
+	for _, row := range rows {
+		publicKey, err := x509.ParsePKIXPublicKey(row.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse public key %s: %v", row.ID, err)
+		}
+
+		key := &Key{
+			ID:          row.ID,
+			Algorithm:   row.Algorithm,
+			ActivatesAt: row.ActivatesAt,
+			RetiresAt:   row.RetiresAt,
+			PublicKey:   publicKey,
+		}
+
+		privateKey, err := s.decryptPrivateKey(row.ID, row.EncryptedPrivateKey)
+		if err != nil {
+			slog.Warn("signing keys: private key can't be used for signing", slog.String("kid", row.ID.String()), slog.String("error", err.Error()))
+		} else {
+			key.privateKey = privateKey
+		}
+
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// encrypt encrypts a private key - the key id is used as additional data so the encrypted key can't be copied to another row
+func (s *KeySet) encrypt(id uuid.UUID, plaintext []byte) ([]byte, error) {
+	gcm, err := s.gcm()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("could not generate nonce: %v", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, id[:]), nil
+}
+
+// decryptPrivateKey decrypts and parses a private key
+func (s *KeySet) decryptPrivateKey(id uuid.UUID, ciphertext []byte) (crypto.Signer, error) {
+	gcm, err := s.gcm()
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("encrypted private key is too short")
+	}
+
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	der, err := gcm.Open(nil, nonce, sealed, id[:])
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt private key (has SECRET_KEY changed?): %v", err)
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key: %v", err)
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unexpected private key type %T", privateKey)
+	}
+	return signer, nil
+}
+
+func (s *KeySet) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,355 @@
+package signingkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+)
+
+func newTestKeySet(t *testing.T, secretKey string, algorithm string) *KeySet {
+	t.Helper()
+	keySet, err := NewKeySet(nil, nil, secretKey, algorithm, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	return keySet
+}
+
+// newTestKey generates a key and loads it as it would be loaded from the database
+func newTestKey(t *testing.T, keySet *KeySet, activatesAt time.Time) *Key {
+	t.Helper()
+	params, err := keySet.newKey(activatesAt)
+	if err != nil {
+		t.Fatalf("newKey() error = %v", err)
+	}
+	keys, err := keySet.parseKeys([]database.SigningKey{{
+		ID:                  params.ID,
+		Algorithm:           params.Algorithm,
+		PublicKey:           params.PublicKey,
+		EncryptedPrivateKey: params.EncryptedPrivateKey,
+		ActivatesAt:         params.ActivatesAt,
+	}})
+	if err != nil {
+		t.Fatalf("parseKeys() error = %v", err)
+	}
+	return keys[0]
+}
+
+func TestSignAndVerify(t *testing.T) {
+	for _, algorithm := range []string{RS256, ES256, EdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			keySet := newTestKeySet(t, "test-secret", algorithm)
+			key := newTestKey(t, keySet, time.Now().Add(-time.Minute))
+			keySet.keys = []*Key{key}
+
+			if keySet.SigningKey() != key {
+				t.Fatal("expected the key to be used for signing")
+			}
+
+			token := jwt.NewWithClaims(key.SigningMethod(), jwt.RegisteredClaims{Subject: "test"})
+			token.Header["kid"] = key.ID.String()
+			signed, err := token.SignedString(key.PrivateKey())
+			if err != nil {
+				t.Fatalf("SignedString() error = %v", err)
+			}
+
+			claims := &jwt.RegisteredClaims{}
+			_, err = jwt.ParseWithClaims(signed, claims, func(token *jwt.Token) (any, error) {
+				return keySet.VerificationKey(token.Header["kid"].(string), token.Method.Alg())
+			}, jwt.WithValidMethods([]string{algorithm}))
+			if err != nil {
+				t.Fatalf("ParseWithClaims() error = %v", err)
+			}
+			if claims.Subject != "test" {
+				t.Errorf("subject = %q, want test", claims.Subject)
+			}
+
+			// the key can't be used with another algorithm
+			if _, err := keySet.VerificationKey(key.ID.String(), HS256); err == nil {
+				t.Error("expected an error when the algorithm does not match the key")
+			}
+			if _, err := keySet.VerificationKey(uuid.New().String(), algorithm); err == nil {
+				t.Error("expected an error for an unknown kid")
+			}
+		})
+	}
+}
+
+func TestPrivateKeyEncryption(t *testing.T) {
+	keySet := newTestKeySet(t, "test-secret", ES256)
+	params, err := keySet.newKey(time.Now())
+	if err != nil {
+		t.Fatalf("newKey() error = %v", err)
+	}
+
+	if _, err := keySet.decryptPrivateKey(params.ID, params.EncryptedPrivateKey); err != nil {
+		t.Fatalf("decryptPrivateKey() error = %v", err)
+	}
+
+	// the encrypted key is bound to the key id
+	if _, err := keySet.decryptPrivateKey(uuid.New(), params.EncryptedPrivateKey); err == nil {
+		t.Error("expected an error decrypting the key with another key id")
+	}
+
+	// keys can't be decrypted after SECRET_KEY is changed, but the public key can still be used
+	otherKeySet := newTestKeySet(t, "another-secret", ES256)
+	keys, err := otherKeySet.parseKeys([]database.SigningKey{{
+		ID:                  params.ID,
+		Algorithm:           params.Algorithm,
+		PublicKey:           params.PublicKey,
+		EncryptedPrivateKey: params.EncryptedPrivateKey,
+		ActivatesAt:         params.ActivatesAt,
+	}})
+	if err != nil {
+		t.Fatalf("parseKeys() error = %v", err)
+	}
+	if keys[0].PrivateKey() != nil || keys[0].PublicKey == nil {
+		t.Errorf("expected only the public key to be loaded, got %+v", keys[0])
+	}
+}
+
+func TestNewJWK(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		kty       string
+		crv       string
+	}{
+		{RS256, "RSA", ""},
+		{ES256, "EC", "P-256"},
+		{EdDSA, "OKP", "Ed25519"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			key := newTestKey(t, newTestKeySet(t, "test-secret", tt.algorithm), time.Now())
+
+			jwk, err := NewJWK(key)
+			if err != nil {
+				t.Fatalf("NewJWK() error = %v", err)
+			}
+			if jwk.Kty != tt.kty || jwk.Crv != tt.crv || jwk.Alg != tt.algorithm || jwk.Use != "sig" || jwk.Kid != key.ID.String() {
+				t.Fatalf("unexpected JWK %+v", jwk)
+			}
+
+			decode := func(s string) []byte {
+				b, err := base64.RawURLEncoding.DecodeString(s)
+				if err != nil {
+					t.Fatalf("invalid base64url value %q: %v", s, err)
+				}
+				return b
+			}
+
+			// check the JWK parameters match the public key
+			switch publicKey := key.PublicKey.(type) {
+			case *rsa.PublicKey:
+				if string(decode(jwk.N)) != string(publicKey.N.Bytes()) || jwk.E != "AQAB" {
+					t.Errorf("RSA parameters do not match the public key")
+				}
+			case *ecdsa.PublicKey:
+				point := append([]byte{4}, append(decode(jwk.X), decode(jwk.Y)...)...)
+				want, _ := publicKey.Bytes()
+				if string(point) != string(want) {
+					t.Errorf("EC parameters do not match the public key")
+				}
+			case ed25519.PublicKey:
+				if string(decode(jwk.X)) != string(publicKey) {
+					t.Errorf("OKP parameters do not match the public key")
+				}
+			}
+
+			der, _ := x509.MarshalPKIXPublicKey(key.PublicKey)
+			if len(der) == 0 {
+				t.Error("expected the public key to be encodable")
+			}
+
+			// check the JWK can be converted back to the public key
+			publicKey, err := jwk.PublicKey()
+			if err != nil {
+				t.Fatalf("PublicKey() error = %v", err)
+			}
+			if !publicKey.(interface{ Equal(crypto.PublicKey) bool }).Equal(key.PublicKey) {
+				t.Error("PublicKey() does not match the original key")
+			}
+		})
+	}
+}
+
+func TestPlanRotation(t *testing.T) {
+	const (
+		interval = 30 * 24 * time.Hour
+		leadTime = time.Hour
+	)
+	now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	usable := func(algorithm string, activatesAt time.Time, retiresAt *time.Time) *Key {
+		return &Key{ID: uuid.New(), Algorithm: algorithm, ActivatesAt: activatesAt, RetiresAt: retiresAt, privateKey: ed25519.PrivateKey{}}
+	}
+
+	t.Run("first_key", func(t *testing.T) {
+		plan := planRotation(nil, ES256, interval, leadTime, now)
+		if plan.newKeyActivatesAt == nil || !plan.newKeyActivatesAt.Equal(now) {
+			t.Errorf("expected a key activating now, got %v", plan.newKeyActivatesAt)
+		}
+	})
+
+	t.Run("current_key_not_due", func(t *testing.T) {
+		current := usable(ES256, now.Add(-24*time.Hour), nil)
+		plan := planRotation([]*Key{current}, ES256, interval, leadTime, now)
+		if plan.newKeyActivatesAt != nil || len(plan.retire) != 0 {
+			t.Errorf("expected no changes, got %+v", plan)
+		}
+	})
+
+	t.Run("next_key_published_before_rotation", func(t *testing.T) {
+		current := usable(ES256, now.Add(-interval).Add(30*time.Minute), nil)
+		plan := planRotation([]*Key{current}, ES256, interval, leadTime, now)
+
+		// the next key activates at the end of the interval (30 mins from now) - but not before it has been published for leadTime
+		want := now.Add(leadTime)
+		if plan.newKeyActivatesAt == nil || !plan.newKeyActivatesAt.Equal(want) {
+			t.Fatalf("expected a key activating at %v, got %v", want, plan.newKeyActivatesAt)
+		}
+		if retiresAt, ok := plan.retire[current.ID]; !ok || !retiresAt.Equal(want) {
+			t.Errorf("expected the current key to retire when the next key activates, got %v", plan.retire)
+		}
+	})
+
+	t.Run("next_key_already_created", func(t *testing.T) {
+		current := usable(ES256, now.Add(-interval).Add(30*time.Minute), ptr(now.Add(leadTime)))
+		next := usable(ES256, now.Add(leadTime), nil)
+		plan := planRotation([]*Key{current, next}, ES256, interval, leadTime, now)
+		if plan.newKeyActivatesAt != nil || len(plan.retire) != 0 {
+			t.Errorf("expected no changes, got %+v", plan)
+		}
+	})
+
+	t.Run("algorithm_changed", func(t *testing.T) {
+		old := usable(RS256, now.Add(-24*time.Hour), nil)
+		plan := planRotation([]*Key{old}, ES256, interval, leadTime, now)
+		if plan.newKeyActivatesAt == nil || !plan.newKeyActivatesAt.Equal(now) {
+			t.Errorf("expected a key activating now, got %v", plan.newKeyActivatesAt)
+		}
+		if retiresAt, ok := plan.retire[old.ID]; !ok || !retiresAt.Equal(now) {
+			t.Errorf("expected the old key to be retired now, got %v", plan.retire)
+		}
+	})
+
+	t.Run("private_key_unavailable", func(t *testing.T) {
+		undecryptable := &Key{ID: uuid.New(), Algorithm: ES256, ActivatesAt: now.Add(-24 * time.Hour)}
+		plan := planRotation([]*Key{undecryptable}, ES256, interval, leadTime, now)
+		if plan.newKeyActivatesAt == nil {
+			t.Error("expected a new key")
+		}
+		if _, ok := plan.retire[undecryptable.ID]; !ok {
+			t.Error("expected the key to be retired")
+		}
+	})
+
+	t.Run("hs256", func(t *testing.T) {
+		current := usable(ES256, now.Add(-24*time.Hour), nil)
+		plan := planRotation([]*Key{current}, HS256, interval, leadTime, now)
+		if plan.newKeyActivatesAt != nil {
+			t.Error("expected no new key")
+		}
+		if retiresAt, ok := plan.retire[current.ID]; !ok || !retiresAt.Equal(now) {
+			t.Errorf("expected the key to be retired now, got %v", plan.retire)
+		}
+	})
+}
+
+func TestKeyExpiry(t *testing.T) {
+	keySet := newTestKeySet(t, "test-secret", EdDSA)
+	retired := newTestKey(t, keySet, time.Now().Add(-48*time.Hour))
+	retiresAt := time.Now().Add(-24 * time.Hour)
+	retired.RetiresAt = &retiresAt
+	keySet.keys = []*Key{retired}
+
+	if keySet.SigningKey() != nil {
+		t.Error("retired keys should not be used for signing")
+	}
+	if _, err := keySet.VerificationKey(retired.ID.String(), EdDSA); err == nil {
+		t.Error("expected an error for a key retired before the access token lifetime")
+	}
+	if jwks, _ := keySet.JWKS(); len(jwks.Keys) != 0 {
+		t.Errorf("expected expired keys to be left out of the JWKS, got %+v", jwks.Keys)
+	}
+}
+
+func TestSwitchTime(t *testing.T) {
+	now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	ptr := func(t time.Time) *time.Time { return &t }
+	key := func(activatesAt time.Time, retiresAt *time.Time) *Key {
+		return &Key{ID: uuid.New(), Algorithm: ES256, ActivatesAt: activatesAt, RetiresAt: retiresAt}
+	}
+
+	tests := []struct {
+		name string
+		keys []*Key
+		want time.Time
+	}{
+		{
+			name: "no_keys",
+		},
+		{
+			name: "first_key",
+			keys: []*Key{key(now, nil)},
+			want: now,
+		},
+		{
+			name: "rotated_keys",
+			keys: []*Key{
+				key(now.Add(-48*time.Hour), ptr(now.Add(-24*time.Hour))),
+				key(now.Add(-24*time.Hour), ptr(now.Add(time.Hour))),
+				key(now.Add(time.Hour), nil),
+			},
+			want: now.Add(-48 * time.Hour),
+		},
+		{
+			// the keys were retired when the algorithm was changed to HS256 and a new key was created when it was changed back
+			name: "switched_back_from_hs256",
+			keys: []*Key{
+				key(now.Add(-48*time.Hour), ptr(now.Add(-24*time.Hour))),
+				key(now.Add(-time.Hour), nil),
+			},
+			want: now.Add(-time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := switchTime(tt.keys); !got.Equal(tt.want) {
+				t.Errorf("switchTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHS256AcceptedUntil(t *testing.T) {
+	keySet := newTestKeySet(t, "test-secret", ES256)
+	if _, ok := keySet.HS256AcceptedUntil(); ok {
+		t.Error("expected no deadline before the first key is created")
+	}
+
+	first := newTestKey(t, keySet, time.Now())
+	keySet.keys = []*Key{first}
+	until, ok := keySet.HS256AcceptedUntil()
+	if !ok || !until.Equal(first.ActivatesAt.Add(signalsd.AccessTokenExpiry)) {
+		t.Errorf("expected HS256 tokens to be accepted until %v, got %v (ok %v)", first.ActivatesAt.Add(signalsd.AccessTokenExpiry), until, ok)
+	}
+
+	hs256 := newTestKeySet(t, "test-secret", HS256)
+	hs256.keys = []*Key{first}
+	if _, ok := hs256.HS256AcceptedUntil(); ok {
+		t.Error("expected no deadline when the algorithm is HS256")
+	}
+}
@@ -43,6 +43,68 @@ func (c *Client) Login(ctx context.Context, email, password string) (*types.Acce
 		return nil, nil, NewClientApiError(res)
 	}
 
+	return decodeLoginResponse(res)
+}
+
+// GetIdentityProviders returns the OpenID Connect identity providers users can sign in with
+func (c *Client) GetIdentityProviders(ctx context.Context) ([]types.IdentityProvider, error) {
+	url := fmt.Sprintf("%s/api/auth/oidc/providers", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, NewClientInternalError(err, "creating identity providers request")
+	}
+
+	setRequestID(req, ctx)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, NewClientConnectionError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, NewClientApiError(res)
+	}
+
+	var providers []types.IdentityProvider
+	if err := json.NewDecoder(res.Body).Decode(&providers); err != nil {
+		return nil, NewClientInternalError(err, "decoding identity providers response")
+	}
+
+	return providers, nil
+}
+
+// CompleteSSOLogin exchanges the one-time code issued at the end of a login with an identity provider for the user's tokens
+func (c *Client) CompleteSSOLogin(ctx context.Context, code string) (*types.AccessTokenDetails, *http.Cookie, error) {
+	jsonData, err := json.Marshal(map[string]string{"code": code})
+	if err != nil {
+		return nil, nil, NewClientInternalError(err, "marshaling sso login request")
+	}
+
+	url := fmt.Sprintf("%s/api/auth/oidc/token", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, nil, NewClientInternalError(err, "creating sso login request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	setRequestID(req, ctx)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, NewClientConnectionError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, nil, NewClientApiError(res)
+	}
+
+	return decodeLoginResponse(res)
+}
+
+// decodeLoginResponse returns the access token details and refresh token cookie from a signalsd login response
+func decodeLoginResponse(res *http.Response) (*types.AccessTokenDetails, *http.Cookie, error) {
 	var accessTokenDetails types.AccessTokenDetails
 	if err := json.NewDecoder(res.Body).Decode(&accessTokenDetails); err != nil {
 		return nil, nil, NewClientInternalError(err, "decoding access token response")
@@ -11,7 +11,7 @@ import (
 // SignalRoutingRule is the mapping between a pattern and a isn.
 type SignalRoutingRule struct {
 	MatchPattern     string `json:"match_pattern" example:"*felixstowe*"`
-	Operator         string `json:"operator" enums:"matches,equals,does_not_match,does_not_equal" example:"matches"`
+	Operator         string `json:"operator" enums:"matches,equals,does_not_match,does_not_equal,starts_with,ends_with,regex,in,gt,gte,lt,lte,exists,not_exists" example:"matches"`
 	IsCaseInsensitve bool   `json:"is_case_insensitive" example:"true"`
 	IsnSlug          string `json:"isn_slug" example:"felixstowe-isn"`
 	Sequence         int32  `json:"sequence" example:"1"`
@@ -19,15 +19,70 @@ type SignalRoutingRule struct {
 
 // UpdateSignalRoutingConfigRequest is the body for setting routing rules.
 type UpdateSignalRoutingConfigRequest struct {
-	RoutingField string              `json:"routing_field" example:"payload.portOfEntry"`
-	RoutingRules []SignalRoutingRule `json:"routing_rules"`
+	RoutingField    string              `json:"routing_field" example:"payload.portOfEntry"`
+	RoutingMode     string              `json:"routing_mode" enums:"first_match,all_matches" example:"first_match"`
+	RoutingRules    []SignalRoutingRule `json:"routing_rules"`
+	FallbackIsnSlug string              `json:"fallback_isn_slug,omitempty" example:"unmatched-signals-isn"`
 }
 
 // SignalRoutingConfigResponse is the response from GET or PUT routing rules.
 type SignalRoutingConfigResponse struct {
-	SignalTypePath string              `json:"signal_type_path"`
-	RoutingField   string              `json:"routing_field"`
-	RoutingRules   []SignalRoutingRule `json:"routing_rules"`
+	SignalTypePath  string              `json:"signal_type_path"`
+	RoutingField    string              `json:"routing_field"`
+	RoutingMode     string              `json:"routing_mode"`
+	RoutingRules    []SignalRoutingRule `json:"routing_rules"`
+	FallbackIsnSlug string              `json:"fallback_isn_slug"`
+}
+
+// SignalRoutingDryRunRequest is the body for testing a draft routing config against sample signals.
+type SignalRoutingDryRunRequest struct {
+	RoutingConfig *UpdateSignalRoutingConfigRequest `json:"routing_config,omitempty"`
+	Signals       []SignalRoutingDryRunSignal       `json:"signals"`
+	Sender        *SignalRoutingDryRunSender        `json:"sender,omitempty"`
+}
+
+// SignalRoutingDryRunSender is the sender used to test rules that route on the sender (@sender and @header fields).
+type SignalRoutingDryRunSender struct {
+	AccountType        string            `json:"account_type,omitempty"`
+	ClientOrganization string            `json:"client_organization,omitempty"`
+	Headers            map[string]string `json:"headers,omitempty"`
+}
+
+// SignalRoutingDryRunSignal is a sample signal used in a routing dry run.
+type SignalRoutingDryRunSignal struct {
+	LocalRef string          `json:"local_ref,omitempty"`
+	Content  json.RawMessage `json:"content"`
+}
+
+// SignalRoutingDryRunResponse reports where each sample signal would be routed.
+type SignalRoutingDryRunResponse struct {
+	SignalTypePath string                      `json:"signal_type_path"`
+	ConfigSource   string                      `json:"config_source"`
+	RoutingMode    string                      `json:"routing_mode"`
+	Results        []SignalRoutingDryRunResult `json:"results"`
+}
+
+// SignalRoutingDryRunResult is the routing outcome for a sample signal.
+type SignalRoutingDryRunResult struct {
+	LocalRef     string                `json:"local_ref"`
+	FieldValues  []RoutingFieldValue   `json:"field_values"`
+	MatchedRules []int32               `json:"matched_rules"`
+	UsedFallback bool                  `json:"used_fallback"`
+	Targets      []RoutingDryRunTarget `json:"targets"`
+}
+
+// RoutingFieldValue is the value of a field used by the routing rules.
+type RoutingFieldValue struct {
+	Field string          `json:"field"`
+	Found bool            `json:"found"`
+	Value json.RawMessage `json:"value"`
+}
+
+// RoutingDryRunTarget is an ISN a sample signal would be routed to.
+type RoutingDryRunTarget struct {
+	IsnSlug  string `json:"isn_slug"`
+	CanWrite bool   `json:"can_write"`
+	Reason   string `json:"reason"`
 }
 
 // GetIsnRouting fetches the routing rule for a signal type version.
@@ -90,12 +145,48 @@ func (c *Client) UpdateSignalRoutingConfig(ctx context.Context, accessToken, slu
 	}
 
 	return &SignalRoutingConfigResponse{
-		SignalTypePath: fmt.Sprintf("%s/v%s", slug, semVer),
-		RoutingField:   req.RoutingField,
-		RoutingRules:   req.RoutingRules,
+		SignalTypePath:  fmt.Sprintf("%s/v%s", slug, semVer),
+		RoutingField:    req.RoutingField,
+		RoutingMode:     req.RoutingMode,
+		RoutingRules:    req.RoutingRules,
+		FallbackIsnSlug: req.FallbackIsnSlug,
 	}, nil
 }
 
+// DryRunSignalRoutingConfig shows where the sample signals would be routed using the draft routing config (nothing is saved).
+func (c *Client) DryRunSignalRoutingConfig(ctx context.Context, accessToken, slug, semVer string, req SignalRoutingDryRunRequest) (*SignalRoutingDryRunResponse, error) {
+	url := fmt.Sprintf("%s/api/admin/signal-types/%s/v%s/routes/dry-run", c.baseURL, slug, semVer)
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, NewClientInternalError(err, "marshaling routing dry run request")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, NewClientInternalError(err, "creating routing dry run request")
+	}
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	httpReq.Header.Set("Content-Type", "application/json")
+	setRequestID(httpReq, ctx)
+
+	res, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, NewClientConnectionError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, NewClientApiError(res)
+	}
+
+	var result SignalRoutingDryRunResponse
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, NewClientInternalError(err, "decoding routing dry run response")
+	}
+	return &result, nil
+}
+
 // DeleteSignalRoutingConfig removes all routing rules for a signal type version.
 func (c *Client) DeleteSignalRoutingConfig(ctx context.Context, accessToken, slug, semVer string) error {
 	url := fmt.Sprintf("%s/api/admin/signal-types/%s/v%s/routes", c.baseURL, slug, semVer)
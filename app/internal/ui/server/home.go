@@ -3,6 +3,7 @@ package server
 import (
 	"log/slog"
 	"net/http"
+	"net/url"
 
 	"github.com/a-h/templ"
 	"github.com/information-sharing-networks/signalsd/app/internal/logger"
@@ -10,6 +11,7 @@ import (
 	"github.com/information-sharing-networks/signalsd/app/internal/ui/client"
 	uiconfig "github.com/information-sharing-networks/signalsd/app/internal/ui/config"
 	"github.com/information-sharing-networks/signalsd/app/internal/ui/templates"
+	"github.com/information-sharing-networks/signalsd/app/internal/ui/types"
 )
 
 // HomePage handles the root path and redirects to the dashboard if authenticated, login if not
@@ -38,7 +40,68 @@ func (s *Server) HomePage(w http.ResponseWriter, r *http.Request) {
 //	@Success	200	"HTML page"
 //	@Router		/login [get]
 func (s *Server) LoginPage(w http.ResponseWriter, r *http.Request) {
-	templ.Handler(templates.LoginPageWithEnvironment(s.config.Environment)).ServeHTTP(w, r)
+	templ.Handler(templates.LoginPageWithEnvironment(s.config.Environment, s.identityProviders(r), "")).ServeHTTP(w, r)
+}
+
+// SSOLogin godoc
+//
+//	@Summary		Complete a login with an identity provider
+//	@Description	The signalsd API redirects users here when a login with an identity provider (see the login page) completes.
+//	@Description	The one-time code is exchanged for the user's tokens and the session cookies are set. If the login failed, the login page is displayed with the error.
+//	@Tags			UI Pages
+//	@Param			code	query	string	false	"one-time login code"
+//	@Param			error	query	string	false	"login error"
+//	@Success		200		"HTML page"
+//	@Router			/login/sso [get]
+func (s *Server) SSOLogin(w http.ResponseWriter, r *http.Request) {
+	reqLogger := logger.ContextRequestLogger(r.Context())
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		msg := "Login failed. Please try again."
+		if loginError := r.URL.Query().Get("error"); loginError != "" {
+			msg = "Login failed: " + loginError
+		}
+		templ.Handler(templates.LoginPageWithEnvironment(s.config.Environment, s.identityProviders(r), msg)).ServeHTTP(w, r)
+		return
+	}
+
+	accessTokenDetails, refreshTokenCookie, clientError := s.apiClient.CompleteSSOLogin(r.Context(), code)
+	if clientError != nil {
+		reqLogger.Error("SSO login failed", slog.String("error", clientError.Error()))
+		templ.Handler(templates.LoginPageWithEnvironment(s.config.Environment, s.identityProviders(r), client.UserMessage(clientError))).ServeHTTP(w, r)
+		return
+	}
+
+	if err := s.authService.SetAuthCookies(w, accessTokenDetails, refreshTokenCookie); err != nil {
+		reqLogger.Error("Failed to set authentication cookies", slog.String("error", err.Error()))
+		templ.Handler(templates.LoginPageWithEnvironment(s.config.Environment, s.identityProviders(r), "An error occurred. Please try again.")).ServeHTTP(w, r)
+		return
+	}
+
+	//  add account log attribute to context so it is included in the final request log
+	_ = logger.ContextWithLogAttrs(r.Context(),
+		slog.String("account_id", accessTokenDetails.AccountID),
+	)
+
+	templ.Handler(templates.SSOLoginComplete()).ServeHTTP(w, r)
+}
+
+// identityProviders returns the identity providers displayed on the login page.
+// The login URLs include a return_to param so the user is sent back to the SSOLogin handler when the login completes.
+// Errors are logged and the login page is displayed without the providers.
+func (s *Server) identityProviders(r *http.Request) []types.IdentityProvider {
+	providers, err := s.apiClient.GetIdentityProviders(r.Context())
+	if err != nil {
+		reqLogger := logger.ContextRequestLogger(r.Context())
+		reqLogger.Error("Failed to get identity providers", slog.String("error", err.Error()))
+		return nil
+	}
+
+	for i := range providers {
+		providers[i].LoginURL += "?return_to=" + url.QueryEscape("/login/sso")
+	}
+	return providers
 }
 
 // Helper method for redirecting to login
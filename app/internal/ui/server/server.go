@@ -66,6 +66,7 @@ func (s *Server) RegisterRoutes() {
 	s.router.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir("./web/static/"))))
 	s.router.Get("/login", s.LoginPage)
 	s.router.Post("/login", s.Login)
+	s.router.Get("/login/sso", s.SSOLogin)
 	s.router.Post("/logout", s.Logout)
 	s.router.Get("/register", s.RegisterPage)
 	s.router.Post("/register", s.Register)
@@ -186,6 +187,7 @@ func (s *Server) RegisterRoutes() {
 			r.Get("/ui-api/signal-types/routing", s.LoadSignalRoutingForm)
 			r.Put("/ui-api/signal-types/routing", s.SaveSignalRoutingConfig)
 			r.Delete("/ui-api/signal-types/routing", s.DeleteSignalRoutingConfig)
+			r.Post("/ui-api/signal-types/routing/dry-run", s.DryRunSignalRoutingConfig)
 			// table management in routing rules page
 			r.Get("/ui-api/signal-types/routing/add-row", s.AddRoutingRow)
 			r.Get("/ui-api/signal-types/routing/remove-row", s.RemoveRoutingRow)
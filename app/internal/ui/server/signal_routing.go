@@ -2,6 +2,8 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -113,10 +115,12 @@ func (s *Server) renderRoutingForm(w http.ResponseWriter, r *http.Request, slug,
 //	@Param			signal-type-slug	formData	string		true	"signal type slug"				example(sample-signal-type)
 //	@Param			sem-ver				formData	string		true	"version"						example(1.0.0)
 //	@Param			routing-field		formData	string		true	"gjson path to routing field"	example(payload.portOfEntry)
+//	@Param			routing-mode		formData	string		false	"first_match (default) or all_matches"	enums(first_match,all_matches)
 //	@Param			match-patterns		formData	[]string	true	"match pattern per row"			example(*felixstowe*)
-//	@Param			operators			formData	[]string	true	"operator per row"				enums(matches,equals,does_not_match,does_not_equal)
+//	@Param			operators			formData	[]string	true	"operator per row"				enums(matches,equals,does_not_match,does_not_equal,starts_with,ends_with,regex,in,gt,gte,lt,lte,exists,not_exists)
 //	@Param			case-insensitive	formData	[]string	true	"'true' or 'false' per row"
 //	@Param			isn-slugs			formData	[]string	true	"target ISN slug per row"	example(felixstowe-isn)
+//	@Param			fallback-isn-slug	formData	string		false	"ISN for signals that don't match any rule"	example(unmatched-signals-isn)
 //	@Success		200					"HTML partial"
 //	@Failure		400					"HTML error partial"
 //	@Failure		401					"HTML error partial"
@@ -136,38 +140,9 @@ func (s *Server) SaveSignalRoutingConfig(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	routingField := strings.TrimSpace(r.FormValue("routing-field"))
-	if routingField == "" {
-		templ.Handler(templates.ErrorAlert("Routing field is required.")).ServeHTTP(w, r)
-		return
-	}
-
-	patterns := r.Form["match-patterns"]
-	operators := r.Form["operators"]
-	caseInsensitiveFlags := r.Form["case-insensitive"]
-	isnSlugs := r.Form["isn-slugs"]
-
-	routingRules := make([]client.SignalRoutingRule, 0, len(patterns))
-	var sequence int32
-	for i := range patterns {
-		pattern := strings.TrimSpace(patterns[i])
-		isn := strings.TrimSpace(isnSlugs[i])
-		if pattern == "" || isn == "" {
-			reqLogger.Error("pattern or isn is empty")
-			continue // skip empty rows
-		}
-		sequence++
-		routingRules = append(routingRules, client.SignalRoutingRule{
-			MatchPattern:     pattern,
-			Operator:         strings.TrimSpace(operators[i]),
-			IsCaseInsensitve: caseInsensitiveFlags[i] == "true",
-			IsnSlug:          isn,
-			Sequence:         sequence,
-		})
-	}
-
-	if len(routingRules) == 0 {
-		templ.Handler(templates.ErrorAlert("At least one mapping is required.")).ServeHTTP(w, r)
+	routingConfig, errMsg := routingConfigFromForm(r)
+	if errMsg != "" {
+		templ.Handler(templates.ErrorAlert(errMsg)).ServeHTTP(w, r)
 		return
 	}
 
@@ -177,10 +152,7 @@ func (s *Server) SaveSignalRoutingConfig(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	result, err := s.apiClient.UpdateSignalRoutingConfig(r.Context(), accessTokenDetails.AccessToken, slug, semVer, client.UpdateSignalRoutingConfigRequest{
-		RoutingField: routingField,
-		RoutingRules: routingRules,
-	})
+	result, err := s.apiClient.UpdateSignalRoutingConfig(r.Context(), accessTokenDetails.AccessToken, slug, semVer, routingConfig)
 	if err != nil {
 		reqLogger.Error("failed to save routing rules", slog.String("error", err.Error()))
 		templ.Handler(templates.ErrorAlert(client.UserMessage(err))).ServeHTTP(w, r)
@@ -288,3 +260,174 @@ func (s *Server) AddRoutingRow(w http.ResponseWriter, r *http.Request) {
 	}
 	templ.Handler(templates.RoutingMappingRow(count+1, client.SignalRoutingRule{}, isnOptions)).ServeHTTP(w, r)
 }
+
+// routingConfigFromForm builds the routing config request from the routing form fields.
+// Returns a message for display to the user if the form is not valid (the message is empty otherwise).
+func routingConfigFromForm(r *http.Request) (client.UpdateSignalRoutingConfigRequest, string) {
+	routingField := strings.TrimSpace(r.FormValue("routing-field"))
+	if routingField == "" {
+		return client.UpdateSignalRoutingConfigRequest{}, "Routing field is required."
+	}
+
+	routingMode := strings.TrimSpace(r.FormValue("routing-mode"))
+	fallbackIsnSlug := strings.TrimSpace(r.FormValue("fallback-isn-slug"))
+
+	patterns := r.Form["match-patterns"]
+	operators := r.Form["operators"]
+	caseInsensitiveFlags := r.Form["case-insensitive"]
+	isnSlugs := r.Form["isn-slugs"]
+	if len(operators) != len(patterns) || len(caseInsensitiveFlags) != len(patterns) || len(isnSlugs) != len(patterns) {
+		return client.UpdateSignalRoutingConfigRequest{}, "Invalid form data."
+	}
+
+	routingRules := make([]client.SignalRoutingRule, 0, len(patterns))
+	var sequence int32
+	for i := range patterns {
+		pattern := strings.TrimSpace(patterns[i])
+		operator := strings.TrimSpace(operators[i])
+		isn := strings.TrimSpace(isnSlugs[i])
+		// the exists operators don't use a pattern
+		if isn == "" || (pattern == "" && operator != "exists" && operator != "not_exists") {
+			continue // skip empty rows
+		}
+		sequence++
+		routingRules = append(routingRules, client.SignalRoutingRule{
+			MatchPattern:     pattern,
+			Operator:         operator,
+			IsCaseInsensitve: caseInsensitiveFlags[i] == "true",
+			IsnSlug:          isn,
+			Sequence:         sequence,
+		})
+	}
+
+	if len(routingRules) == 0 {
+		return client.UpdateSignalRoutingConfigRequest{}, "At least one mapping is required."
+	}
+
+	return client.UpdateSignalRoutingConfigRequest{
+		RoutingField:    routingField,
+		RoutingMode:     routingMode,
+		RoutingRules:    routingRules,
+		FallbackIsnSlug: fallbackIsnSlug,
+	}, ""
+}
+
+// DryRunSignalRoutingConfig godoc
+//
+//	@Summary		Test Signals Routing Rules
+//	@Description	HTMX endpoint. Shows where the sample signals would be routed using the rules currently in the form (nothing is saved).
+//	@Description	The sample signals are the signal contents, supplied as a JSON object or an array of JSON objects.
+//	@Tags			HTMX Actions
+//	@Param			signal-type-slug	formData	string		true	"signal type slug"				example(sample-signal-type)
+//	@Param			sem-ver				formData	string		true	"version"						example(1.0.0)
+//	@Param			routing-field		formData	string		true	"gjson path to routing field"	example(payload.portOfEntry)
+//	@Param			routing-mode		formData	string		false	"first_match (default) or all_matches"	enums(first_match,all_matches)
+//	@Param			match-patterns		formData	[]string	true	"match pattern per row"			example(*felixstowe*)
+//	@Param			operators			formData	[]string	true	"operator per row"				enums(matches,equals,does_not_match,does_not_equal,starts_with,ends_with,regex,in,gt,gte,lt,lte,exists,not_exists)
+//	@Param			case-insensitive	formData	[]string	true	"'true' or 'false' per row"
+//	@Param			isn-slugs			formData	[]string	true	"target ISN slug per row"	example(felixstowe-isn)
+//	@Param			fallback-isn-slug	formData	string		false	"ISN for signals that don't match any rule"	example(unmatched-signals-isn)
+//	@Param			sample-signals		formData	string		true	"signal contents (JSON object or array)"	example([{"payload": {"portOfEntry": "felixstowe"}}])
+//	@Param			sample-sender-account-type	formData	string	false	"sender account type (the logged in account is used as the sender when the sender fields are empty)"	enums(user,service_account)
+//	@Param			sample-sender-organization	formData	string	false	"sender service account organization"	example(HMRC)
+//	@Param			sample-sender-headers		formData	string	false	"request headers, one 'Name: value' per line"	example(X-Origin-System: cds)
+//	@Success		200					"HTML partial"
+//	@Failure		400					"HTML error partial"
+//	@Failure		401					"HTML error partial"
+//	@Router			/ui-api/signal-types/routing/dry-run [post]
+func (s *Server) DryRunSignalRoutingConfig(w http.ResponseWriter, r *http.Request) {
+	reqLogger := s.logger.With(slog.String("handler", "DryRunSignalRoutingConfig"))
+
+	if err := r.ParseForm(); err != nil {
+		templ.Handler(templates.ErrorAlert("Invalid form data.")).ServeHTTP(w, r)
+		return
+	}
+
+	slug := strings.TrimSpace(r.FormValue("signal-type-slug"))
+	semVer := strings.TrimSpace(r.FormValue("sem-ver"))
+	if slug == "" || semVer == "" {
+		templ.Handler(templates.ErrorAlert("Signal type and version are required.")).ServeHTTP(w, r)
+		return
+	}
+
+	routingConfig, errMsg := routingConfigFromForm(r)
+	if errMsg != "" {
+		templ.Handler(templates.ErrorAlert(errMsg)).ServeHTTP(w, r)
+		return
+	}
+
+	// the samples can be a single signal content object or an array of them
+	samples := strings.TrimSpace(r.FormValue("sample-signals"))
+	var contents []json.RawMessage
+	var err error
+	if strings.HasPrefix(samples, "[") {
+		err = json.Unmarshal([]byte(samples), &contents)
+	} else {
+		var content json.RawMessage
+		err = json.Unmarshal([]byte(samples), &content)
+		contents = []json.RawMessage{content}
+	}
+	if err != nil || len(contents) == 0 {
+		templ.Handler(templates.ErrorAlert("Sample signals must be a JSON object or an array of JSON objects.")).ServeHTTP(w, r)
+		return
+	}
+
+	signals := make([]client.SignalRoutingDryRunSignal, len(contents))
+	for i, content := range contents {
+		signals[i] = client.SignalRoutingDryRunSignal{Content: content}
+	}
+
+	sender, errMsg := dryRunSenderFromForm(r)
+	if errMsg != "" {
+		templ.Handler(templates.ErrorAlert(errMsg)).ServeHTTP(w, r)
+		return
+	}
+
+	accessTokenDetails, ok := auth.ContextAccessTokenDetails(r.Context())
+	if !ok {
+		templ.Handler(templates.ErrorAlert("Authentication required. Please log in again.")).ServeHTTP(w, r)
+		return
+	}
+
+	result, err := s.apiClient.DryRunSignalRoutingConfig(r.Context(), accessTokenDetails.AccessToken, slug, semVer, client.SignalRoutingDryRunRequest{
+		RoutingConfig: &routingConfig,
+		Signals:       signals,
+		Sender:        sender,
+	})
+	if err != nil {
+		reqLogger.Error("failed to test routing rules", slog.String("error", err.Error()))
+		templ.Handler(templates.ErrorAlert(client.UserMessage(err))).ServeHTTP(w, r)
+		return
+	}
+
+	templ.Handler(templates.SignalRoutingDryRunResults(result)).ServeHTTP(w, r)
+}
+
+// dryRunSenderFromForm reads the optional sample sender from the dry run form.
+// Returns nil when the sender fields are empty (the API uses the logged in account as the sender).
+func dryRunSenderFromForm(r *http.Request) (*client.SignalRoutingDryRunSender, string) {
+	sender := client.SignalRoutingDryRunSender{
+		AccountType:        strings.TrimSpace(r.FormValue("sample-sender-account-type")),
+		ClientOrganization: strings.TrimSpace(r.FormValue("sample-sender-organization")),
+	}
+
+	for line := range strings.SplitSeq(r.FormValue("sample-sender-headers"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Sprintf("Invalid header %q - use one 'Name: value' per line.", line)
+		}
+		if sender.Headers == nil {
+			sender.Headers = make(map[string]string)
+		}
+		sender.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	if sender.AccountType == "" && sender.ClientOrganization == "" && sender.Headers == nil {
+		return nil, ""
+	}
+	return &sender, ""
+}
@@ -32,6 +32,19 @@ type AccessTokenDetails struct {
 	IsnPerms map[string]IsnPerm `json:"isn_perms,omitempty"`
 }
 
+// IdentityProvider is an OpenID Connect identity provider users can sign in with (returned by the signalsd /api/auth/oidc/providers endpoint)
+type IdentityProvider struct {
+
+	// ID identifies the provider in the login URLs
+	ID string `json:"id"`
+
+	// Name is the provider name displayed on the login page
+	Name string `json:"name"`
+
+	// LoginURL starts the login at the identity provider
+	LoginURL string `json:"login_url"`
+}
+
 type SignalType struct {
 
 	// Path is the signal type path in the format "slug/v{version}"
@@ -0,0 +1,252 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/poller"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+)
+
+const (
+	SignatureHeader  = "Signalsd-Signature"
+	DeliveryIDHeader = "Signalsd-Delivery-ID"
+	EventTypeHeader  = "Signalsd-Event"
+
+	// EventTypeSignalVersionCreated is sent when a new signal version is stored (new signals and updates to existing signals)
+	EventTypeSignalVersionCreated = "signal_version.created"
+
+	// deliveries are leased for long enough to work through a full batch before they can be claimed again
+	deliveryLease = 5 * time.Minute
+
+	// only the start of the response body is read (the content is ignored)
+	maxResponseBodyBytes = 64 * 1024
+)
+
+// Event is the JSON payload sent to webhook subscribers
+type Event struct {
+	DeliveryID     uuid.UUID `json:"delivery_id"`
+	EventType      string    `json:"event_type" example:"signal_version.created"`
+	IsnSlug        string    `json:"isn_slug" example:"sample-isn--example-org"`
+	SignalTypePath string    `json:"signal_type_path" example:"sample-signal--example-org/v0.0.1"`
+	Signal         Signal    `json:"signal"`
+}
+
+// Signal contains the signal version that triggered the event (same fields as the signal search response)
+type Signal struct {
+	AccountID            uuid.UUID       `json:"account_id"`
+	AccountType          string          `json:"account_type"`
+	Email                string          `json:"email,omitempty"`
+	SignalID             uuid.UUID       `json:"signal_id"`
+	LocalRef             string          `json:"local_ref"`
+	SignalCreatedAt      time.Time       `json:"signal_created_at"`
+	SignalVersionID      uuid.UUID       `json:"signal_version_id"`
+	VersionNumber        int32           `json:"version_number"`
+	VersionCreatedAt     time.Time       `json:"version_created_at"`
+	CorrelatedToSignalID uuid.UUID       `json:"correlated_to_signal_id"`
+	IsWithdrawn          bool            `json:"is_withdrawn"`
+	Content              json.RawMessage `json:"content" swaggertype:"object"`
+}
+
+// Dispatcher sends pending webhook deliveries to subscribers
+type Dispatcher struct {
+	*poller.Poller[database.ClaimDueWebhookDeliveriesRow]
+	queries *database.Queries
+	client  *http.Client
+}
+
+// retryBackoff is the schedule used to retry failed deliveries
+var retryBackoff = poller.Backoff{
+	BaseDelay: signalsd.WebhookRetryBaseDelay,
+	MaxDelay:  signalsd.WebhookRetryMaxDelay,
+}
+
+// NewDispatcher creates a webhook dispatcher.
+//
+// Unless allowPrivateNetworks is true, the dispatcher refuses to connect to loopback, private and link-local addresses
+// (this prevents subscribers using webhooks to make requests to services on the internal network).
+//
+// Use Start to send the pending deliveries in the background (or ProcessDue to send the deliveries that are currently due).
+func NewDispatcher(queries *database.Queries, allowPrivateNetworks bool) *Dispatcher {
+	dialer := &net.Dialer{
+		Timeout: signalsd.WebhookRequestTimeout,
+	}
+	if !allowPrivateNetworks {
+		dialer.Control = denyPrivateNetworks
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	d := &Dispatcher{
+		queries: queries,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   signalsd.WebhookRequestTimeout,
+			// redirects are not followed - subscribers must register the final URL
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	d.Poller = poller.New("webhook dispatcher", signalsd.WebhookDeliveryBatchSize, signalsd.WebhookDeliveryWorkers, d.claim, d.deliver)
+	return d
+}
+
+// claim claims the deliveries that are due.
+func (d *Dispatcher) claim(ctx context.Context) ([]database.ClaimDueWebhookDeliveriesRow, error) {
+	deliveries, err := d.queries.ClaimDueWebhookDeliveries(ctx, database.ClaimDueWebhookDeliveriesParams{
+		BatchSize:    signalsd.WebhookDeliveryBatchSize,
+		LeaseSeconds: int32(deliveryLease.Seconds()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not claim webhook deliveries: %v", err)
+	}
+	return deliveries, nil
+}
+
+// deliver sends the delivery and records the outcome.
+func (d *Dispatcher) deliver(ctx context.Context, delivery database.ClaimDueWebhookDeliveriesRow) {
+	statusCode, sendErr := d.send(ctx, delivery)
+
+	// the server is shutting down - the delivery will be retried when the lease expires
+	if ctx.Err() != nil {
+		return
+	}
+
+	attempts := delivery.AttemptCount + 1
+	params := database.RecordWebhookDeliveryAttemptParams{
+		ID:            delivery.ID,
+		NextAttemptAt: time.Now(),
+	}
+	if statusCode != 0 {
+		responseStatus := int32(statusCode)
+		params.LastResponseStatus = &responseStatus
+	}
+
+	switch {
+	case sendErr == nil:
+		params.Status = "delivered"
+	case attempts >= signalsd.WebhookMaxAttempts:
+		errMsg := sendErr.Error()
+		params.Status = "failed"
+		params.LastError = &errMsg
+	default:
+		errMsg := sendErr.Error()
+		params.Status = "pending"
+		params.NextAttemptAt = time.Now().Add(retryBackoff.Delay(attempts))
+		params.LastError = &errMsg
+	}
+
+	if err := d.queries.RecordWebhookDeliveryAttempt(ctx, params); err != nil {
+		slog.Error("webhook dispatcher: could not record delivery attempt",
+			slog.String("delivery_id", delivery.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	if sendErr != nil {
+		slog.Warn("webhook delivery attempt failed",
+			slog.String("delivery_id", delivery.ID.String()),
+			slog.String("webhook_subscription_id", delivery.WebhookSubscriptionID.String()),
+			slog.Int("attempt", int(attempts)),
+			slog.String("status", params.Status),
+			slog.String("error", sendErr.Error()),
+		)
+	}
+}
+
+// send posts the event to the subscriber's target URL and returns the response status code (0 if no response was received).
+// An error is returned if the request fails or the subscriber does not respond with a 2xx status.
+func (d *Dispatcher) send(ctx context.Context, delivery database.ClaimDueWebhookDeliveriesRow) (int, error) {
+	event := Event{
+		DeliveryID:     delivery.ID,
+		EventType:      EventTypeSignalVersionCreated,
+		IsnSlug:        delivery.IsnSlug,
+		SignalTypePath: fmt.Sprintf("%s/v%s", delivery.SignalTypeSlug, delivery.SemVer),
+		Signal: Signal{
+			AccountID:            delivery.AccountID,
+			AccountType:          delivery.AccountType,
+			Email:                delivery.Email,
+			SignalID:             delivery.SignalID,
+			LocalRef:             delivery.LocalRef,
+			SignalCreatedAt:      delivery.SignalCreatedAt,
+			SignalVersionID:      delivery.SignalVersionID,
+			VersionNumber:        delivery.VersionNumber,
+			VersionCreatedAt:     delivery.VersionCreatedAt,
+			CorrelatedToSignalID: delivery.CorrelatedToSignalID,
+			IsWithdrawn:          delivery.IsWithdrawn,
+			Content:              delivery.Content,
+		},
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("could not marshal event: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.TargetURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("could not create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "signalsd-webhooks")
+	req.Header.Set(DeliveryIDHeader, delivery.ID.String())
+	req.Header.Set(EventTypeHeader, EventTypeSignalVersionCreated)
+	req.Header.Set(SignatureHeader, Signature(delivery.SigningSecret, time.Now().Unix(), body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxResponseBodyBytes))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("subscriber responded with status %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// Signature returns the Signalsd-Signature header value for the request body.
+//
+// The signature is the hex encoded HMAC-SHA256 of "<timestamp>.<body>" using the subscription's signing secret.
+// The timestamp is included so subscribers can reject replayed requests.
+func Signature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// denyPrivateNetworks is used as the dialer Control function to prevent connections to internal addresses.
+// The check is done after DNS resolution, so it cannot be bypassed with a hostname that resolves to an internal address.
+func denyPrivateNetworks(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %v", address, err)
+	}
+	addr := addrPort.Addr().Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsUnspecified() || addr.IsMulticast() {
+		return fmt.Errorf("webhook target address %s is not allowed", addr)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package webhooks
+
+import (
+	"testing"
+)
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"event_type":"signal_version.created"}`)
+
+	// expected value calculated with: printf '1700000000.{"event_type":"signal_version.created"}' | openssl dgst -sha256 -hmac secret
+	want := "t=1700000000,v1=f2cc531fdb9286dc6f84fa17cb51c1ddfa6cea9c271bf5d9a8bc97ed4cf69a91"
+
+	got := Signature("secret", 1700000000, body)
+	if got != want {
+		t.Fatalf("Signature() = %q, want %q", got, want)
+	}
+
+	if Signature("other-secret", 1700000000, body) == got {
+		t.Error("signature should depend on the secret")
+	}
+	if Signature("secret", 1700000001, body) == got {
+		t.Error("signature should depend on the timestamp")
+	}
+	if Signature("secret", 1700000000, []byte(`{}`)) == got {
+		t.Error("signature should depend on the body")
+	}
+}
+
+func TestDenyPrivateNetworks(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{address: "93.184.215.14:443", allowed: true},
+		{address: "[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443", allowed: true},
+		{address: "127.0.0.1:8080", allowed: false},
+		{address: "10.0.0.5:443", allowed: false},
+		{address: "192.168.1.1:443", allowed: false},
+		{address: "169.254.169.254:80", allowed: false},
+		{address: "0.0.0.0:80", allowed: false},
+		{address: "[::1]:443", allowed: false},
+		{address: "[::ffff:127.0.0.1]:443", allowed: false},
+		{address: "[fd00::1]:443", allowed: false},
+	}
+	for _, tt := range tests {
+		err := denyPrivateNetworks("tcp", tt.address, nil)
+		if tt.allowed && err != nil {
+			t.Errorf("expected %s to be allowed, got %v", tt.address, err)
+		}
+		if !tt.allowed && err == nil {
+			t.Errorf("expected %s to be denied", tt.address)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+// package webhooks delivers new signal versions to the webhook subscriptions registered by ISN members.
+//
+// Deliveries are queued in the webhook_deliveries table in the same transaction that stores the signal version.
+// The Dispatcher polls the table, POSTs each pending delivery to the subscriber's target URL and records the outcome
+// in the delivery log. Failed deliveries are retried with exponential backoff until WebhookMaxAttempts is reached.
+//
+// Each request includes a Signalsd-Signature header that subscribers can use to verify the payload:
+//
+//	Signalsd-Signature: t=<unix timestamp>,v1=<hex encoded HMAC-SHA256 of "<unix timestamp>.<request body>">
+//
+// The HMAC key is the signing secret returned when the subscription was created.
+//
+// Deliveries are claimed with FOR UPDATE SKIP LOCKED, so it is safe to run a dispatcher on every signalsd instance.
+package webhooks
@@ -28,4 +28,27 @@ WHERE id = $1;
 
 -- name: EnableAccount :execrows
 UPDATE accounts SET (updated_at, is_active) = (NOW(), true)
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: IsSessionActive :one
+-- Reports whether the credential identified by the sid claim of an access token is still valid for the account:
+-- the client secret or public key (service accounts) has not been revoked, or the session has a refresh token that has not been revoked (web users).
+-- Used by token introspection: access tokens are reported as inactive once the credential used to obtain them has been revoked.
+SELECT (
+    EXISTS (
+        SELECT 1 FROM client_secrets cs
+        WHERE cs.id = sqlc.arg(session_id)
+            AND cs.service_account_account_id = sqlc.arg(account_id)
+            AND (cs.revoked_at IS NULL OR cs.revoked_at > NOW())
+    ) OR EXISTS (
+        SELECT 1 FROM service_account_keys sak
+        WHERE sak.id = sqlc.arg(session_id)
+            AND sak.service_account_account_id = sqlc.arg(account_id)
+            AND sak.revoked_at IS NULL
+    ) OR EXISTS (
+        SELECT 1 FROM refresh_tokens rt
+        WHERE rt.session_id = sqlc.arg(session_id)
+            AND rt.user_account_id = sqlc.arg(account_id)
+            AND rt.revoked_at IS NULL
+    )
+)::boolean AS is_active;
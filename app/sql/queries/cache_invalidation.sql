@@ -0,0 +1,4 @@
+-- name: NotifyCacheInvalidation :exec
+-- Tells the signalsd instances listening on the signalsd_cache_invalidation channel to reload a cache.
+-- When used in a transaction the notification is only sent if the transaction commits.
+SELECT pg_notify('signalsd_cache_invalidation', sqlc.arg(cache_name)::text);
@@ -0,0 +1,57 @@
+-- name: CreateIdempotencyKey :one
+-- Records a new idempotency key for the account.
+-- No rows are returned when the account has already used the key (use GetIdempotencyKey to get the original request).
+INSERT INTO idempotency_keys (
+    account_id,
+    idempotency_key,
+    created_at,
+    expires_at,
+    request_hash,
+    locked_until
+) VALUES (
+    sqlc.arg(account_id),
+    sqlc.arg(idempotency_key),
+    now(),
+    sqlc.arg(expires_at),
+    sqlc.arg(request_hash),
+    sqlc.arg(locked_until)
+)
+ON CONFLICT (account_id, idempotency_key) DO NOTHING
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT *
+FROM idempotency_keys
+WHERE account_id = sqlc.arg(account_id)
+    AND idempotency_key = sqlc.arg(idempotency_key);
+
+-- name: TakeOverIdempotencyKey :one
+-- Takes over a key when the original request did not complete before its lease expired (e.g. the server stopped while the request was being processed).
+-- No rows are returned when the original request is still in progress, has completed or was made with a different request.
+UPDATE idempotency_keys
+SET locked_until = sqlc.arg(locked_until)
+WHERE account_id = sqlc.arg(account_id)
+    AND idempotency_key = sqlc.arg(idempotency_key)
+    AND request_hash = sqlc.arg(request_hash)
+    AND response_status IS NULL
+    AND locked_until < now()
+RETURNING *;
+
+-- name: CompleteIdempotencyKey :execrows
+-- Stores the response to the original request - repeated requests receive this response.
+UPDATE idempotency_keys
+SET response_status = sqlc.arg(response_status),
+    response_body = sqlc.arg(response_body)
+WHERE account_id = sqlc.arg(account_id)
+    AND idempotency_key = sqlc.arg(idempotency_key);
+
+-- name: DeleteIdempotencyKey :execrows
+-- Releases a key when the original request could not be processed, so the request can be retried with the same key.
+DELETE FROM idempotency_keys
+WHERE account_id = sqlc.arg(account_id)
+    AND idempotency_key = sqlc.arg(idempotency_key);
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE account_id = sqlc.arg(account_id)
+    AND expires_at < now();
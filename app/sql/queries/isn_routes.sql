@@ -5,12 +5,15 @@ INSERT INTO signal_routing_configs (
     id,
     created_at,
     signal_type_id, 
-    routing_field 
-) VALUES (gen_random_uuid(), NOW(), $1, $2)
-RETURNING id, signal_type_id, routing_field, created_at;
+    routing_field,
+    routing_mode,
+    fallback_isn_id
+) VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4)
+RETURNING id, signal_type_id, routing_field, routing_mode, fallback_isn_id, created_at;
 
 -- name: CreateIsnRoute :one
 -- Create a route (match pattern -> isn) for a specific signal type routing field
+-- Compound rules supply a condition instead of the match pattern and operator.
 INSERT INTO routing_rules (
     id,
     created_at,
@@ -19,12 +22,14 @@ INSERT INTO routing_rules (
     operator,
     is_case_insensitive,
     isn_id,
-    rule_sequence
-) VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6)
-RETURNING id, signal_routing_config_id, match_pattern, operator, is_case_insensitive, isn_id, rule_sequence, created_at;
+    rule_sequence,
+    condition
+) VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6, $7)
+RETURNING id, signal_routing_config_id, match_pattern, operator, is_case_insensitive, isn_id, rule_sequence, condition, created_at;
 
 -- name: GetSignalRoutingConfigs :many
--- Loads all signal types that have a routing field and at least one route defined
+-- Loads all signal types that have a routing config and at least one route defined
+-- (the fallback ISN is repeated on each rule row)
 SELECT
     st.slug AS signal_type_slug,
     st.sem_ver,
@@ -34,11 +39,16 @@ SELECT
     ir.is_case_insensitive,
     ir.isn_id,
     i.slug AS isn_slug,
-    ir.rule_sequence
+    ir.rule_sequence,
+    ir.condition,
+    irf.routing_mode,
+    irf.fallback_isn_id,
+    fb.slug AS fallback_isn_slug
 FROM signal_routing_configs irf
 JOIN signal_types st ON st.id = irf.signal_type_id
 JOIN routing_rules ir ON ir.signal_routing_config_id = irf.id
 JOIN isn i ON i.id = ir.isn_id
+LEFT JOIN isn fb ON fb.id = irf.fallback_isn_id
 ORDER BY st.slug, st.sem_ver, ir.rule_sequence ASC;
 
 -- name: GetSignalRoutingConfigBySignalType :one
@@ -57,7 +67,8 @@ SELECT
     ir.operator,
     ir.is_case_insensitive,
     ir.rule_sequence,
-    i.slug AS isn_slug
+    i.slug AS isn_slug,
+    ir.condition
 FROM routing_rules ir
 JOIN isn i ON i.id = ir.isn_id
 WHERE ir.signal_routing_config_id = $1
@@ -0,0 +1,86 @@
+-- name: CreateOIDCLoginRequest :exec
+INSERT INTO oidc_login_requests (
+    id,
+    created_at,
+    provider_id,
+    state,
+    nonce,
+    code_verifier,
+    return_to,
+    expires_at
+) VALUES (
+    sqlc.arg(id),
+    now(),
+    sqlc.arg(provider_id),
+    sqlc.arg(state),
+    sqlc.arg(nonce),
+    sqlc.arg(code_verifier),
+    sqlc.narg(return_to),
+    sqlc.arg(expires_at)
+);
+
+-- name: ConsumeOIDCLoginRequest :one
+-- Deletes and returns the login request so that it can only be used once.
+DELETE FROM oidc_login_requests
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteExpiredOIDCLoginRequests :execrows
+DELETE FROM oidc_login_requests
+WHERE expires_at < now();
+
+-- name: CreateOIDCLoginCode :exec
+INSERT INTO oidc_login_codes (
+    hashed_code,
+    created_at,
+    user_account_id,
+    expires_at
+) VALUES (
+    sqlc.arg(hashed_code),
+    now(),
+    sqlc.arg(user_account_id),
+    sqlc.arg(expires_at)
+);
+
+-- name: ConsumeOIDCLoginCode :one
+-- Deletes and returns the login code so that it can only be used once.
+DELETE FROM oidc_login_codes
+WHERE hashed_code = sqlc.arg(hashed_code)
+RETURNING *;
+
+-- name: DeleteExpiredOIDCLoginCodes :execrows
+DELETE FROM oidc_login_codes
+WHERE expires_at < now();
+
+-- name: GetUserIdentity :one
+SELECT *
+FROM user_identities
+WHERE provider_id = sqlc.arg(provider_id)
+    AND subject = sqlc.arg(subject);
+
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (
+    id,
+    created_at,
+    user_account_id,
+    provider_id,
+    subject,
+    email,
+    last_login_at
+) VALUES (
+    sqlc.arg(id),
+    now(),
+    sqlc.arg(user_account_id),
+    sqlc.arg(provider_id),
+    sqlc.arg(subject),
+    sqlc.arg(email),
+    now()
+)
+RETURNING *;
+
+-- name: UpdateUserIdentityLogin :exec
+-- Records the login and the email address from the latest id_token.
+UPDATE user_identities
+SET last_login_at = now(),
+    email = sqlc.arg(email)
+WHERE id = sqlc.arg(id);
@@ -1,6 +1,7 @@
 -- name: InsertRefreshToken :one
-INSERT INTO refresh_tokens (hashed_token, user_account_id, created_at, updated_at, expires_at)
-VALUES ( $1,$2, NOW(), NOW(), $3)
+-- session_id is the id of the login session (kept when the refresh token is rotated - see the sid access token claim)
+INSERT INTO refresh_tokens (hashed_token, user_account_id, created_at, updated_at, expires_at, session_id)
+VALUES ( $1,$2, NOW(), NOW(), $3, $4)
 RETURNING hashed_token, user_account_id;
 
 -- name: GetValidRefreshTokenByUserAccountId :one
@@ -15,7 +16,7 @@ WHERE user_account_id = $1
 SELECT user_account_id, expires_at, revoked_at FROM refresh_tokens where hashed_token = $1;
 
 -- name: GetValidRefreshTokenByHashedToken :one
-SELECT user_account_id, expires_at
+SELECT user_account_id, expires_at, session_id
 FROM refresh_tokens
 WHERE hashed_token = $1
   AND revoked_at IS NULL
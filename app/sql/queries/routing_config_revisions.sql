@@ -0,0 +1,40 @@
+-- name: CreateSignalRoutingConfigRevision :one
+-- Records a change to the routing config for a signal type (the revision number is the next number for the signal type).
+INSERT INTO signal_routing_config_revisions (
+    id,
+    created_at,
+    signal_type_id,
+    revision,
+    account_id,
+    action,
+    config,
+    rolled_back_to_revision
+) VALUES (
+    uuidv7(),
+    now(),
+    sqlc.arg(signal_type_id),
+    (SELECT COALESCE(MAX(revision), 0) + 1 FROM signal_routing_config_revisions WHERE signal_type_id = sqlc.arg(signal_type_id)),
+    sqlc.narg(account_id),
+    sqlc.arg(action),
+    sqlc.narg(config),
+    sqlc.narg(rolled_back_to_revision)
+)
+RETURNING id, created_at, signal_type_id, revision, account_id, action, config, rolled_back_to_revision;
+
+-- name: GetSignalRoutingConfigRevisions :many
+-- Lists the routing config revisions for a signal type, newest first (the config is not included).
+SELECT
+    r.revision,
+    r.created_at,
+    r.account_id,
+    r.action,
+    r.rolled_back_to_revision
+FROM signal_routing_config_revisions r
+WHERE r.signal_type_id = sqlc.arg(signal_type_id)
+ORDER BY r.revision DESC;
+
+-- name: GetSignalRoutingConfigRevision :one
+SELECT r.*
+FROM signal_routing_config_revisions r
+WHERE r.signal_type_id = sqlc.arg(signal_type_id)
+    AND r.revision = sqlc.arg(revision);
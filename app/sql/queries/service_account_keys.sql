@@ -0,0 +1,54 @@
+-- name: CreateServiceAccountKey :one
+INSERT INTO service_account_keys (
+    id,
+    created_at,
+    service_account_account_id,
+    algorithm,
+    public_key
+) VALUES (
+    sqlc.arg(id),
+    now(),
+    sqlc.arg(service_account_account_id),
+    sqlc.arg(algorithm),
+    sqlc.arg(public_key)
+)
+RETURNING *;
+
+-- name: GetServiceAccountKeys :many
+-- Returns the keys the service account can use to authenticate (revoked keys are not returned).
+SELECT *
+FROM service_account_keys
+WHERE service_account_account_id = sqlc.arg(service_account_account_id)
+    AND revoked_at IS NULL
+ORDER BY created_at, id;
+
+-- name: RevokeServiceAccountKey :execrows
+UPDATE service_account_keys
+SET revoked_at = now()
+WHERE id = sqlc.arg(id)
+    AND service_account_account_id = sqlc.arg(service_account_account_id)
+    AND revoked_at IS NULL;
+
+-- name: RevokeAllServiceAccountKeys :execrows
+UPDATE service_account_keys
+SET revoked_at = now()
+WHERE service_account_account_id = sqlc.arg(service_account_account_id)
+    AND revoked_at IS NULL;
+
+-- name: RecordClientAssertion :execrows
+-- Records the jti of a client assertion - no rows are inserted if the assertion has already been used.
+INSERT INTO client_assertions (
+    service_account_account_id,
+    jti,
+    expires_at
+) VALUES (
+    sqlc.arg(service_account_account_id),
+    sqlc.arg(jti),
+    sqlc.arg(expires_at)
+)
+ON CONFLICT (service_account_account_id, jti) DO NOTHING;
+
+-- name: DeleteExpiredClientAssertions :execrows
+DELETE FROM client_assertions
+WHERE service_account_account_id = sqlc.arg(service_account_account_id)
+    AND expires_at < now();
@@ -51,6 +51,7 @@ WHERE
     AND NOT EXISTS ( -- do not count signals that failed processing and have not been corrected yet
         SELECT 1 FROM signal_processing_failures spf
         WHERE spf.signal_batch_id = $1
+            AND spf.isn_id = s.isn_id
             AND spf.local_ref = s.local_ref
             AND spf.signal_type_slug = st.slug
             AND spf.signal_type_sem_ver = st.sem_ver
@@ -59,8 +60,8 @@ WHERE
 GROUP BY i.slug, st.slug, st.sem_ver;
 
 -- name: GetFailedSignalsByBatchID :many
--- Unresolved failures: failed local_refs that were not subsequently loaded successfully.
--- ISN slug is derived via signals to avoid depending on isn_id on signal_batches.
+-- Unresolved failures: failed local_refs that were not subsequently loaded successfully to the same ISN.
+-- Failures for signals that could not be routed to an ISN (isn_id is null) are not included.
 SELECT DISTINCT
     sb.id as batch_id,
     sb.created_at as batch_created_at,
@@ -73,16 +74,17 @@ SELECT DISTINCT
     spf.error_message
 FROM signal_batches sb
 JOIN signal_processing_failures spf ON spf.signal_batch_id = sb.id
-JOIN signal_types st ON st.slug = spf.signal_type_slug
-    AND st.sem_ver = spf.signal_type_sem_ver
-JOIN signals s ON s.local_ref = spf.local_ref
-    AND s.signal_type_id = st.id
-    AND s.account_id = sb.account_id
-JOIN isn i ON i.id = s.isn_id
+JOIN isn i ON i.id = spf.isn_id
 WHERE sb.id = $1
 AND NOT EXISTS (
-        SELECT 1 FROM signal_versions sv
-        WHERE sv.signal_id = s.id
+        SELECT 1 FROM signals s
+        JOIN signal_types st ON st.id = s.signal_type_id
+        JOIN signal_versions sv ON sv.signal_id = s.id
+        WHERE s.account_id = sb.account_id
+            AND s.isn_id = spf.isn_id
+            AND s.local_ref = spf.local_ref
+            AND st.slug = spf.signal_type_slug
+            AND st.sem_ver = spf.signal_type_sem_ver
             AND sv.created_at > spf.created_at
     );
 
@@ -0,0 +1,85 @@
+-- name: CreateQueuedSignalSubmission :one
+-- isn_slug should be null for submissions made using the signal router.
+-- routing_context is only used for submissions made using the signal router.
+INSERT INTO signal_submission_queue (
+    id,
+    created_at,
+    updated_at,
+    account_id,
+    signal_batch_id,
+    isn_slug,
+    signal_type_slug,
+    sem_ver,
+    claims,
+    request_body,
+    routing_context,
+    status,
+    next_attempt_at
+) VALUES (
+    uuidv7(),
+    now(),
+    now(),
+    sqlc.arg(account_id),
+    sqlc.arg(signal_batch_id),
+    sqlc.narg(isn_slug),
+    sqlc.arg(signal_type_slug),
+    sqlc.arg(sem_ver),
+    sqlc.arg(claims),
+    sqlc.arg(request_body),
+    sqlc.narg(routing_context),
+    'queued',
+    now()
+)
+RETURNING id, created_at;
+
+-- name: ClaimQueuedSignalSubmissions :many
+-- Claims a batch of queued submissions that are due, oldest first.
+-- The next_attempt_at timestamp of the claimed submissions is moved forward by lease_seconds so they are not picked up again while they are being processed
+-- (if the worker stops before recording the outcome, the submission is processed again when the lease expires).
+WITH due AS (
+    SELECT q.id
+    FROM signal_submission_queue q
+    WHERE q.status = 'queued'
+        AND q.next_attempt_at <= now()
+    ORDER BY q.next_attempt_at
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE signal_submission_queue q
+SET next_attempt_at = now() + (sqlc.arg(lease_seconds)::integer * interval '1 second'),
+    updated_at = now()
+FROM due
+WHERE q.id = due.id
+RETURNING q.*;
+
+-- name: RecordQueuedSignalSubmissionAttempt :exec
+-- Records the outcome of an attempt to process a queued submission.
+-- status is 'completed', 'queued' (the submission will be retried at next_attempt_at) or 'failed' (no more retries)
+UPDATE signal_submission_queue
+SET status = sqlc.arg(status),
+    attempt_count = attempt_count + 1,
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    completed_at = sqlc.narg(completed_at),
+    response_status = sqlc.narg(response_status),
+    response_body = sqlc.narg(response_body),
+    last_error = sqlc.narg(last_error),
+    updated_at = now()
+WHERE id = sqlc.arg(id);
+
+-- name: GetQueuedSignalSubmissionsByBatchID :many
+-- Returns the async submissions made in a batch (the request and response bodies are not included).
+SELECT
+    id,
+    created_at,
+    isn_slug,
+    signal_type_slug,
+    sem_ver,
+    jsonb_array_length(request_body->'signals')::integer AS signal_count,
+    status,
+    attempt_count,
+    completed_at,
+    response_status,
+    last_error
+FROM signal_submission_queue
+WHERE signal_batch_id = sqlc.arg(signal_batch_id)
+ORDER BY created_at, id;
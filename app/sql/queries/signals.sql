@@ -1,6 +1,6 @@
 -- name: CreateSignal :one
--- This query creates one row in the signals table for every new combination of account_id, signal_type_id, local_ref.
--- If a withdrawn signal is received again it is reactivated (is_withdrawn = false).
+-- This query creates one row in the signals table for every new combination of account_id, isn_id, signal_type_id, local_ref.
+-- If a withdrawn signal is received again it is reactivated (is_withdrawn = false) and a reinstated event is recorded in signal_events.
 -- Only creates signals if ISN and signal type are in use (this is a defence against stale access tokens).
 -- Returns the new signal_id.
 WITH ids AS (
@@ -15,7 +15,7 @@ WITH ids AS (
         AND st.sem_ver = sqlc.arg(sem_ver)
         AND i.is_in_use = true
         AND ist.is_in_use = true
-)
+), upserted AS (
 INSERT INTO signals (
     id,
     created_at,
@@ -41,19 +41,26 @@ SELECT
 FROM ids
 -- deactivated records (is_withdrawn = true) are reactivated by resubmitting them - the update below ensures the updated_at timestamp is only changed if the record is reactivated
 -- the only other signals field that can be updated is the correlation_id (handled by CreateOrUpdateSignalWithCorrelationID)
-ON CONFLICT (account_id, signal_type_id, local_ref)
+ON CONFLICT (account_id, isn_id, signal_type_id, local_ref)
 DO UPDATE SET
     is_withdrawn = false,
     updated_at = CASE 
         WHEN signals.is_withdrawn = true THEN now()
         ELSE signals.updated_at
     END
-RETURNING id;
+RETURNING id, account_id, old.is_withdrawn AS was_withdrawn
+), reinstated AS (
+    INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type, actor_account_id)
+    SELECT uuidv7(), now(), u.id, u.account_id, 'reinstated', u.account_id
+    FROM upserted u
+    WHERE u.was_withdrawn = true
+)
+SELECT id FROM upserted;
 
 -- name: CreateOrUpdateSignalWithCorrelationID :one
 -- note if there is already a master record for this local_ref, then:
 -- 1. correlation_id is updated with the supplied value (assuming it is different to the existing value)
--- 2. if the signal was withdrawn it is reactivated (is_withdrawn = false).
+-- 2. if the signal was withdrawn it is reactivated (is_withdrawn = false) and a reinstated event is recorded in signal_events.
 -- Only creates/updates signals if ISN and signal type are in use (this is a defence against stale access tokens).
 WITH ids AS (
     SELECT st.id AS signal_type_id,
@@ -67,7 +74,7 @@ WITH ids AS (
         AND st.sem_ver = sqlc.arg(sem_ver)
         AND i.is_in_use = true
         AND ist.is_in_use = true
-)
+), upserted AS (
 INSERT INTO signals (
     id,
     created_at,
@@ -91,7 +98,7 @@ SELECT
     false,
     false
 FROM ids 
-ON CONFLICT (account_id, signal_type_id, local_ref)
+ON CONFLICT (account_id, isn_id, signal_type_id, local_ref)
 DO UPDATE SET
     correlation_id = CASE
         WHEN signals.correlation_id != EXCLUDED.correlation_id THEN EXCLUDED.correlation_id
@@ -102,13 +109,22 @@ DO UPDATE SET
         ELSE signals.is_withdrawn
     END,
     updated_at = now()
-RETURNING id;
+RETURNING id, account_id, old.is_withdrawn AS was_withdrawn
+), reinstated AS (
+    INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type, actor_account_id)
+    SELECT uuidv7(), now(), u.id, u.account_id, 'reinstated', u.account_id
+    FROM upserted u
+    WHERE u.was_withdrawn = true
+)
+SELECT id FROM upserted;
 
 -- name: CreateSignalVersion :one
 -- if there is already a version of this signal, create a new one with an incremented version_number
+-- the new version is recorded in signal_events (created for the first version, otherwise version_created)
 WITH ver AS (
     SELECT 
         st.id AS signal_type_id,
+        i.id AS isn_id,
         COALESCE(
             (SELECT MAX(sv.version_number)
              FROM signal_versions sv
@@ -116,12 +132,14 @@ WITH ver AS (
                 ON s.id = sv.signal_id
              WHERE s.local_ref = sqlc.arg(local_ref)
                 AND s.account_id = sqlc.arg(account_id)
-                AND s.signal_type_id = st.id)
+                AND s.signal_type_id = st.id
+                AND s.isn_id = i.id)
             , 0) + 1 as version_number
     FROM signal_types st
+    JOIN isn i ON i.slug = sqlc.arg(isn_slug)
     WHERE st.slug = sqlc.arg(signal_type_slug)
         AND st.sem_ver = sqlc.arg(sem_ver)
-)
+), new_version AS (
 INSERT INTO signal_versions (
     id,
     created_at,
@@ -142,43 +160,145 @@ SELECT
 FROM ver 
 JOIN signals s 
     ON s.signal_type_id = ver.signal_type_id
+    AND s.isn_id = ver.isn_id
     AND s.account_id = sqlc.arg(account_id)
     AND s.local_ref = sqlc.arg(local_ref)
-RETURNING id, version_number;
+RETURNING id, signal_id, account_id, version_number
+), version_event AS (
+    INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type, signal_version_id, version_number, actor_account_id)
+    SELECT uuidv7(), now(), v.signal_id, v.account_id,
+        CASE WHEN v.version_number = 1 THEN 'created' ELSE 'version_created' END,
+        v.id, v.version_number, v.account_id
+    FROM new_version v
+)
+SELECT id, version_number FROM new_version;
+
+-- name: GetUnchangedSignalVersion :one
+-- returns the latest version of an active signal when the supplied content is the same as the content of that version
+-- (the jsonb comparison ignores differences in key order and whitespace).
+-- When a correlation_id is supplied it must also match the signal's existing correlation_id.
+-- No rows are returned when a new version is required (new, withdrawn or changed signals).
+SELECT s.id AS signal_id,
+    lsv.id AS signal_version_id,
+    lsv.version_number
+FROM signals s
+JOIN signal_types st ON st.id = s.signal_type_id
+JOIN isn i ON i.id = s.isn_id
+JOIN latest_signal_versions lsv ON lsv.signal_id = s.id
+WHERE s.account_id = sqlc.arg(account_id)
+    AND s.local_ref = sqlc.arg(local_ref)
+    AND i.slug = sqlc.arg(isn_slug)
+    AND st.slug = sqlc.arg(signal_type_slug)
+    AND st.sem_ver = sqlc.arg(sem_ver)
+    AND s.is_withdrawn = false
+    AND (sqlc.narg('correlation_id')::uuid IS NULL OR s.correlation_id = sqlc.narg('correlation_id')::uuid)
+    AND lsv.content = sqlc.arg(content)::jsonb;
+
+-- name: SetSignalsWithdrawnStatus :many
+-- Withdraws (is_withdrawn = true) or reinstates (is_withdrawn = false) signals of a signal type in an ISN.
+-- Signals are selected by id, by local ref or by batch (signals that have a version stored in the batch) - use empty arrays/null for the selectors that are not used.
+-- When owner_account_id is supplied only signals owned by that account are changed (local refs and batch refs are only unique per account).
+-- Signals that already have the requested status are not changed.
+-- Only changes signals if the ISN and signal type are in use (this is a defence against stale access tokens).
+-- Each change is recorded in signal_events with the acting account and the reason (the withdrawn/reinstated events are used by the signal changes feed).
+WITH selected AS (
+    SELECT s.id, s.account_id
+    FROM signals s
+    JOIN isn i ON i.id = s.isn_id
+    JOIN signal_types st ON st.id = s.signal_type_id
+    JOIN isn_signal_types ist ON ist.isn_id = i.id AND ist.signal_type_id = st.id
+    WHERE i.slug = sqlc.arg(isn_slug)
+        AND st.slug = sqlc.arg(signal_type_slug)
+        AND st.sem_ver = sqlc.arg(sem_ver)
+        AND i.is_in_use = true
+        AND ist.is_in_use = true
+        AND s.is_withdrawn <> sqlc.arg(is_withdrawn)::boolean
+        AND (sqlc.narg('owner_account_id')::uuid IS NULL OR s.account_id = sqlc.narg('owner_account_id')::uuid)
+        AND (
+            s.id = ANY(sqlc.arg(signal_ids)::uuid[])
+            OR s.local_ref = ANY(sqlc.arg(local_refs)::text[])
+            OR EXISTS (
+                SELECT 1
+                FROM signal_versions sv
+                JOIN signal_batches sb ON sb.id = sv.signal_batch_id
+                WHERE sv.signal_id = s.id
+                    AND sv.account_id = s.account_id
+                    AND sb.account_id = s.account_id
+                    AND sb.batch_ref = sqlc.narg('batch_ref')::text
+            )
+        )
+), changed AS (
+    UPDATE signals s
+    SET is_withdrawn = sqlc.arg(is_withdrawn)::boolean, updated_at = NOW()
+    FROM selected
+    WHERE s.id = selected.id
+        AND s.account_id = selected.account_id
+        AND s.is_withdrawn <> sqlc.arg(is_withdrawn)::boolean
+    RETURNING s.id, s.account_id, s.local_ref
+), recorded AS (
+    INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type, actor_account_id, reason)
+    SELECT uuidv7(), NOW(), c.id, c.account_id,
+        CASE WHEN sqlc.arg(is_withdrawn)::boolean THEN 'withdrawn' ELSE 'reinstated' END,
+        sqlc.arg(changed_by_account_id)::uuid, sqlc.arg(reason)::text
+    FROM changed c
+)
+SELECT c.id AS signal_id, c.account_id, c.local_ref
+FROM changed c
+ORDER BY c.local_ref;
 
 -- name: WithdrawSignalByID :execrows
-UPDATE signals
-SET is_withdrawn = true, updated_at = NOW()
-WHERE id = sqlc.arg(id);
+-- the withdrawal is recorded in signal_events (used by the signal changes feed)
+WITH withdrawn AS (
+    UPDATE signals
+    SET is_withdrawn = true, updated_at = NOW()
+    WHERE id = sqlc.arg(id)
+    RETURNING id, account_id
+)
+INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type)
+SELECT uuidv7(), NOW(), w.id, w.account_id, 'withdrawn'
+FROM withdrawn w;
 
 -- name: WithdrawSignalByLocalRef :execrows
 -- only withdraws if the ISN and signal type are in use
 -- Only withdraws signals if ISN and signal type are in use (this is a defence against stale access tokens).
-UPDATE signals
-SET is_withdrawn = true, updated_at = NOW()
-WHERE account_id = sqlc.arg(account_id)
-    AND isn_id = (
-        SELECT i.id
-        FROM isn i
-        JOIN isn_signal_types ist ON ist.isn_id = i.id
-        JOIN signal_types st ON st.id = ist.signal_type_id
-        WHERE i.slug = sqlc.arg(isn_slug)
-            AND st.slug = sqlc.arg(slug)
-            AND st.sem_ver = sqlc.arg(sem_ver)
-            AND i.is_in_use = true
-            AND ist.is_in_use = true
-    )
-    AND signal_type_id = (
-        SELECT st.id
-        FROM signal_types st
-        WHERE st.slug = sqlc.arg(slug)
-            AND st.sem_ver = sqlc.arg(sem_ver)
-    )
-    AND local_ref = sqlc.arg(local_ref);
+-- the withdrawal is recorded in signal_events (used by the signal changes feed) together with the optional reason
+WITH withdrawn AS (
+    UPDATE signals
+    SET is_withdrawn = true, updated_at = NOW()
+    WHERE account_id = sqlc.arg(account_id)
+        AND isn_id = (
+            SELECT i.id
+            FROM isn i
+            JOIN isn_signal_types ist ON ist.isn_id = i.id
+            JOIN signal_types st ON st.id = ist.signal_type_id
+            WHERE i.slug = sqlc.arg(isn_slug)
+                AND st.slug = sqlc.arg(slug)
+                AND st.sem_ver = sqlc.arg(sem_ver)
+                AND i.is_in_use = true
+                AND ist.is_in_use = true
+        )
+        AND signal_type_id = (
+            SELECT st.id
+            FROM signal_types st
+            WHERE st.slug = sqlc.arg(slug)
+                AND st.sem_ver = sqlc.arg(sem_ver)
+        )
+        AND local_ref = sqlc.arg(local_ref)
+    RETURNING id, account_id
+)
+INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type, actor_account_id, reason)
+SELECT uuidv7(), NOW(), w.id, w.account_id, 'withdrawn', w.account_id, sqlc.narg(reason)::text
+FROM withdrawn w;
 
 -- name: GetSignalsWithOptionalFilters :many
 -- you must supply the isn_slug,signal_type_slug & sem_ver params - other filters are optional
 -- signals for inactive isns or signal_types are not returned (is_in_use = false)
+-- results are ordered by version_created_at (then signal_version_id) - ascending unless order_desc is true.
+-- page_created_at/page_signal_version_id are the values from the last row of the previous page (keyset pagination)
+-- content_filters is a json array of {path, operator, operands, is_number} objects (use [] for no filters) - signals are
+-- only returned when their content matches all the filters. When is_number is true and the content field is a json number
+-- the values are compared numerically, otherwise the comparison uses the text value of the field.
+-- write_only_account_id is used for accounts that only have write permission on the isn (they can only see the signals they created).
 SELECT
  a.id AS account_id,
     a.account_type,
@@ -221,18 +341,55 @@ WHERE
     AND (sqlc.narg('local_ref')::text IS NULL OR s.local_ref = sqlc.narg('local_ref')::text)
     AND (sqlc.narg('start_date')::timestamptz IS NULL OR lsv.created_at >= sqlc.narg('start_date')::timestamptz)
     AND (sqlc.narg('end_date')::timestamptz IS NULL OR lsv.created_at <= sqlc.narg('end_date')::timestamptz)
+    AND (sqlc.narg('page_created_at')::timestamptz IS NULL
+        OR (sqlc.arg('order_desc')::boolean = false
+            AND (lsv.created_at, lsv.id) > (sqlc.narg('page_created_at')::timestamptz, sqlc.narg('page_signal_version_id')::uuid))
+        OR (sqlc.arg('order_desc')::boolean = true
+            AND (lsv.created_at, lsv.id) < (sqlc.narg('page_created_at')::timestamptz, sqlc.narg('page_signal_version_id')::uuid)))
+    AND NOT EXISTS (
+        SELECT 1
+        FROM jsonb_to_recordset(sqlc.arg('content_filters')::jsonb) AS f(path text[], operator text, operands text[], is_number boolean)
+        WHERE NOT COALESCE(
+            CASE
+                WHEN f.operator = 'in' THEN lsv.content #>> f.path = ANY(f.operands)
+                WHEN f.is_number AND jsonb_typeof(lsv.content #> f.path) = 'number' THEN
+                    CASE f.operator
+                        WHEN 'eq' THEN (lsv.content #>> f.path)::numeric = f.operands[1]::numeric
+                        WHEN 'ne' THEN (lsv.content #>> f.path)::numeric <> f.operands[1]::numeric
+                        WHEN 'gt' THEN (lsv.content #>> f.path)::numeric > f.operands[1]::numeric
+                        WHEN 'gte' THEN (lsv.content #>> f.path)::numeric >= f.operands[1]::numeric
+                        WHEN 'lt' THEN (lsv.content #>> f.path)::numeric < f.operands[1]::numeric
+                        WHEN 'lte' THEN (lsv.content #>> f.path)::numeric <= f.operands[1]::numeric
+                    END
+                ELSE
+                    CASE f.operator
+                        WHEN 'eq' THEN lsv.content #>> f.path = f.operands[1]
+                        WHEN 'ne' THEN lsv.content #>> f.path IS DISTINCT FROM f.operands[1]
+                        WHEN 'gt' THEN lsv.content #>> f.path > f.operands[1]
+                        WHEN 'gte' THEN lsv.content #>> f.path >= f.operands[1]
+                        WHEN 'lt' THEN lsv.content #>> f.path < f.operands[1]
+                        WHEN 'lte' THEN lsv.content #>> f.path <= f.operands[1]
+                    END
+            END, false)
+    )
+    AND (sqlc.narg('write_only_account_id')::uuid IS NULL OR a.id = sqlc.narg('write_only_account_id')::uuid)
 ORDER BY
-    s.updated_at ASC;
+    CASE WHEN sqlc.arg('order_desc')::boolean = false THEN lsv.created_at END ASC,
+    CASE WHEN sqlc.arg('order_desc')::boolean = false THEN lsv.id END ASC,
+    CASE WHEN sqlc.arg('order_desc')::boolean = true THEN lsv.created_at END DESC,
+    CASE WHEN sqlc.arg('order_desc')::boolean = true THEN lsv.id END DESC
+LIMIT sqlc.arg(row_limit);
 
 -- name: GetSignalByAccountAndLocalRef :one
 SELECT s.*, i.slug as isn_slug, st.slug as signal_type_slug, st.sem_ver
 FROM signals s
 JOIN signal_types st ON st.id = s.signal_type_id
 JOIN isn i ON i.id = s.isn_id
-WHERE s.account_id = $1
-    AND st.slug = $2
-    AND st.sem_ver = $3
-    AND s.local_ref = $4;
+WHERE s.account_id = sqlc.arg(account_id)
+    AND i.slug = sqlc.arg(isn_slug)
+    AND st.slug = sqlc.arg(signal_type_slug)
+    AND st.sem_ver = sqlc.arg(sem_ver)
+    AND s.local_ref = sqlc.arg(local_ref);
 
 -- name: ValidateCorrelationID :one
 SELECT EXISTS(
@@ -248,6 +405,7 @@ SELECT EXISTS(
 
 -- name: GetSignalCorrelationDetails :one
 -- Get signal with its correlation details for verification during integration tests
+-- (the same local_ref can be used in more than one ISN, so the ISN must be supplied)
 SELECT
     s.id,
     s.local_ref,
@@ -260,10 +418,11 @@ FROM signals s
 JOIN signal_types st ON st.id = s.signal_type_id
 JOIN isn i ON i.id = s.isn_id
 join signals sc on sc.id = s.correlation_id
-WHERE s.account_id = $1
-    AND st.slug = $2
-    AND st.sem_ver = $3
-    AND s.local_ref = $4;
+WHERE s.account_id = sqlc.arg(account_id)
+    AND i.slug = sqlc.arg(isn_slug)
+    AND st.slug = sqlc.arg(slug)
+    AND st.sem_ver = sqlc.arg(sem_ver)
+    AND s.local_ref = sqlc.arg(local_ref);
 
 -- name: GetSignalsByCorrelationIDs :many
 -- Get all signals that correlate to the provided signal IDs (for embedding correlated signals)
@@ -327,3 +486,135 @@ WHERE
     -- exclude the latest version 
     AND sv.id != (SELECT id from latest_signal_versions lsv WHERE lsv.signal_id = sv.signal_id)
     ORDER BY sv.created_at;
+
+-- name: GetSignalEvents :many
+-- get the lifecycle events for the supplied signals, oldest first.
+-- actor_email is the email of the user (or the client contact email of the service account) that made the change.
+SELECT se.signal_id,
+    se.id AS signal_event_id,
+    se.created_at,
+    se.event_type,
+    se.signal_version_id,
+    se.version_number,
+    se.actor_account_id,
+    COALESCE(u.email, si.client_contact_email) AS actor_email,
+    se.reason
+FROM signal_events se
+LEFT OUTER JOIN users u ON u.account_id = se.actor_account_id
+LEFT OUTER JOIN service_accounts si ON si.account_id = se.actor_account_id
+WHERE se.signal_id = ANY(sqlc.slice(signal_ids))
+ORDER BY se.signal_id, se.created_at, se.id;
+
+-- name: GetSignalStreamStartPosition :one
+-- returns the oldest transaction id that may still be in progress.
+-- All signal versions created by later transactions have tx_id >= the returned value.
+SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint AS tx_id;
+
+-- name: GetSignalVersionsAfterPosition :many
+-- returns the signal versions stored after the supplied (tx_id, signal_version_id) position, in the order they were committed.
+-- Versions created by transactions that may still be in progress are excluded (tx_id >= pg_snapshot_xmin) - these are
+-- returned by a later call once all the earlier transactions have completed, so no versions are skipped.
+-- signals for inactive isns or signal_types are not returned (is_in_use = false)
+SELECT
+    sv.tx_id,
+    a.id AS account_id,
+    a.account_type,
+    COALESCE(u.email, si.client_contact_email) AS email,
+    s.id as signal_id,
+    s.local_ref,
+    s.created_at signal_created_at,
+    sv.id AS signal_version_id,
+    sv.version_number,
+    sv.created_at version_created_at,
+    s.correlation_id as correlated_to_signal_id,
+    s.is_withdrawn,
+    sv.content
+FROM
+    signal_versions sv
+JOIN
+    signals s ON s.id = sv.signal_id
+JOIN
+    accounts a ON a.id = s.account_id
+JOIN
+    signal_types st on st.id = s.signal_type_id
+JOIN
+    isn_signal_types ist ON ist.signal_type_id = st.id
+JOIN
+    isn i ON i.id = ist.isn_id
+LEFT OUTER JOIN
+    users u ON u.account_id = a.id
+LEFT OUTER JOIN
+    service_accounts si ON si.account_id = a.id
+WHERE
+    i.slug = sqlc.arg(isn_slug)
+    AND s.isn_id = i.id
+    AND st.slug = sqlc.arg(signal_type_slug)
+    AND st.sem_ver = sqlc.arg(sem_ver)
+    AND i.is_in_use = true
+    AND ist.is_in_use = true
+    AND (sv.tx_id, sv.id) > (sqlc.arg(after_tx_id)::bigint, sqlc.arg(after_signal_version_id)::uuid)
+    AND sv.tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+ORDER BY
+    sv.tx_id,
+    sv.id
+LIMIT sqlc.arg(row_limit);
+
+-- name: GetSignalChanges :many
+-- returns the signal versions and withdrawals stored after the supplied (tx_id, change_id) position, in the order they were committed.
+-- change_type is 'version_created' for new signal versions, or 'withdrawn'/'reinstated' for changes to the signal status (the withdrawn and reinstated signal_events).
+-- Changes made by transactions that may still be in progress are excluded (see GetSignalVersionsAfterPosition).
+-- signals for inactive isns or signal_types are not returned (is_in_use = false)
+SELECT
+    c.tx_id,
+    c.change_id,
+    c.change_type,
+    c.changed_at,
+    a.id AS account_id,
+    a.account_type,
+    COALESCE(u.email, si.client_contact_email) AS email,
+    s.id as signal_id,
+    s.local_ref,
+    s.created_at signal_created_at,
+    sv.id AS signal_version_id,
+    sv.version_number,
+    sv.created_at version_created_at,
+    s.correlation_id as correlated_to_signal_id,
+    s.is_withdrawn,
+    sv.content
+FROM (
+    SELECT v.tx_id, v.id AS change_id, 'version_created'::text AS change_type, v.created_at AS changed_at, v.signal_id, v.id AS signal_version_id
+    FROM signal_versions v
+    UNION ALL
+    SELECT se.tx_id, se.id, se.event_type, se.created_at, se.signal_id, NULL::uuid
+    FROM signal_events se
+    WHERE se.event_type IN ('withdrawn', 'reinstated')
+) c
+JOIN
+    signals s ON s.id = c.signal_id
+JOIN
+    accounts a ON a.id = s.account_id
+JOIN
+    signal_types st on st.id = s.signal_type_id
+JOIN
+    isn_signal_types ist ON ist.signal_type_id = st.id
+JOIN
+    isn i ON i.id = ist.isn_id
+LEFT OUTER JOIN
+    signal_versions sv ON sv.id = c.signal_version_id AND sv.account_id = s.account_id
+LEFT OUTER JOIN
+    users u ON u.account_id = a.id
+LEFT OUTER JOIN
+    service_accounts si ON si.account_id = a.id
+WHERE
+    i.slug = sqlc.arg(isn_slug)
+    AND s.isn_id = i.id
+    AND st.slug = sqlc.arg(signal_type_slug)
+    AND st.sem_ver = sqlc.arg(sem_ver)
+    AND i.is_in_use = true
+    AND ist.is_in_use = true
+    AND (c.tx_id, c.change_id) > (sqlc.arg(after_tx_id)::bigint, sqlc.arg(after_change_id)::uuid)
+    AND c.tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+ORDER BY
+    c.tx_id,
+    c.change_id
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,42 @@
+-- name: GetSigningKeys :many
+-- Returns the keys that can be used to verify access tokens: keys that are not retired and keys retired after verify_after
+-- (callers pass now() minus the access token lifetime so that retired keys are kept until the tokens signed with them expire).
+SELECT *
+FROM signing_keys
+WHERE retires_at IS NULL
+    OR retires_at > sqlc.arg(verify_after)::timestamptz
+ORDER BY activates_at, id;
+
+-- name: CreateSigningKey :one
+INSERT INTO signing_keys (
+    id,
+    created_at,
+    algorithm,
+    public_key,
+    encrypted_private_key,
+    activates_at
+) VALUES (
+    sqlc.arg(id),
+    now(),
+    sqlc.arg(algorithm),
+    sqlc.arg(public_key),
+    sqlc.arg(encrypted_private_key),
+    sqlc.arg(activates_at)
+)
+RETURNING *;
+
+-- name: RetireSigningKey :execrows
+-- Sets the time after which the key is no longer used to sign tokens (retires_at is not changed once set).
+UPDATE signing_keys
+SET retires_at = GREATEST(sqlc.arg(retires_at)::timestamptz, activates_at)
+WHERE id = sqlc.arg(id)
+    AND retires_at IS NULL;
+
+-- name: DeleteExpiredSigningKeys :execrows
+-- Deletes keys that were retired before verify_after (the tokens signed with them have expired).
+DELETE FROM signing_keys
+WHERE retires_at <= sqlc.arg(verify_after)::timestamptz;
+
+-- name: LockSigningKeys :exec
+-- Takes a transaction level advisory lock so that only one signalsd instance rotates the keys at a time.
+SELECT pg_advisory_xact_lock(hashtext('signalsd_signing_keys'));
@@ -0,0 +1,82 @@
+-- name: CreateUnroutableSignal :one
+-- Stores a signal submitted to the signal router that did not match any routing rule.
+INSERT INTO unroutable_signals (
+    id,
+    created_at,
+    account_id,
+    signal_batch_id,
+    signal_type_slug,
+    sem_ver,
+    local_ref,
+    content,
+    claims,
+    routing_context,
+    error_message
+) VALUES (
+    uuidv7(),
+    now(),
+    sqlc.arg(account_id),
+    sqlc.arg(signal_batch_id),
+    sqlc.arg(signal_type_slug),
+    sqlc.arg(sem_ver),
+    sqlc.arg(local_ref),
+    sqlc.arg(content),
+    sqlc.arg(claims),
+    sqlc.narg(routing_context),
+    sqlc.arg(error_message)
+)
+RETURNING id;
+
+-- name: GetUnroutableSignals :many
+-- Lists unroutable signals, newest first. The signal type filters are optional.
+-- Signals that have been re-routed are only included when include_rerouted is true.
+SELECT
+    us.id,
+    us.created_at,
+    us.account_id,
+    sb.batch_ref,
+    us.signal_type_slug,
+    us.sem_ver,
+    us.local_ref,
+    us.error_message,
+    us.rerouted_at,
+    us.rerouted_by_account_id
+FROM unroutable_signals us
+JOIN signal_batches sb ON sb.id = us.signal_batch_id
+WHERE (sqlc.narg('signal_type_slug')::text IS NULL OR us.signal_type_slug = sqlc.narg('signal_type_slug')::text)
+    AND (sqlc.narg('sem_ver')::text IS NULL OR us.sem_ver = sqlc.narg('sem_ver')::text)
+    AND (us.rerouted_at IS NULL OR sqlc.arg(include_rerouted)::boolean = true)
+ORDER BY us.created_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: GetUnroutableSignalByID :one
+SELECT
+    us.id,
+    us.created_at,
+    us.account_id,
+    sb.batch_ref,
+    us.signal_type_slug,
+    us.sem_ver,
+    us.local_ref,
+    us.content,
+    us.claims,
+    us.routing_context,
+    us.error_message,
+    us.rerouted_at,
+    us.rerouted_by_account_id
+FROM unroutable_signals us
+JOIN signal_batches sb ON sb.id = us.signal_batch_id
+WHERE us.id = sqlc.arg(id);
+
+-- name: MarkUnroutableSignalRerouted :one
+-- Claims the unroutable signal for re-routing - no row is returned if the signal has already been re-routed.
+UPDATE unroutable_signals
+SET rerouted_at = now(),
+    rerouted_by_account_id = sqlc.arg(rerouted_by_account_id)
+WHERE id = sqlc.arg(id)
+    AND rerouted_at IS NULL
+RETURNING id;
+
+-- name: DeleteUnroutableSignal :execrows
+DELETE FROM unroutable_signals
+WHERE id = sqlc.arg(id);
@@ -0,0 +1,208 @@
+-- name: CreateWebhookSubscription :one
+-- Creates a webhook subscription for the ISN and signal type path.
+-- Only creates the subscription if the signal type is associated with the ISN.
+INSERT INTO webhook_subscriptions (
+    id,
+    created_at,
+    updated_at,
+    account_id,
+    isn_id,
+    signal_type_id,
+    target_url,
+    signing_secret,
+    is_active
+)
+SELECT
+    uuidv7(),
+    now(),
+    now(),
+    sqlc.arg(account_id),
+    i.id,
+    st.id,
+    sqlc.arg(target_url),
+    sqlc.arg(signing_secret),
+    true
+FROM isn i
+JOIN isn_signal_types ist ON ist.isn_id = i.id
+JOIN signal_types st ON st.id = ist.signal_type_id
+WHERE i.slug = sqlc.arg(isn_slug)
+    AND st.slug = sqlc.arg(signal_type_slug)
+    AND st.sem_ver = sqlc.arg(sem_ver)
+RETURNING *;
+
+-- name: ExistsWebhookSubscription :one
+SELECT EXISTS (
+    SELECT 1
+    FROM webhook_subscriptions ws
+    JOIN isn i ON i.id = ws.isn_id
+    JOIN signal_types st ON st.id = ws.signal_type_id
+    WHERE ws.account_id = sqlc.arg(account_id)
+        AND i.slug = sqlc.arg(isn_slug)
+        AND st.slug = sqlc.arg(signal_type_slug)
+        AND st.sem_ver = sqlc.arg(sem_ver)
+        AND ws.target_url = sqlc.arg(target_url)
+) AS exists;
+
+-- name: GetWebhookSubscriptionsByAccountAndSignalType :many
+-- Returns the webhook subscriptions the account has created for the ISN and signal type path (the signing secret is not returned).
+SELECT
+    ws.id,
+    ws.created_at,
+    ws.updated_at,
+    ws.target_url,
+    ws.is_active
+FROM webhook_subscriptions ws
+JOIN isn i ON i.id = ws.isn_id
+JOIN signal_types st ON st.id = ws.signal_type_id
+WHERE ws.account_id = sqlc.arg(account_id)
+    AND i.slug = sqlc.arg(isn_slug)
+    AND st.slug = sqlc.arg(signal_type_slug)
+    AND st.sem_ver = sqlc.arg(sem_ver)
+ORDER BY ws.created_at;
+
+-- name: GetWebhookSubscriptionByID :one
+-- Returns the webhook subscription if it belongs to the account and is registered for the ISN and signal type path.
+SELECT
+    ws.id,
+    ws.created_at,
+    ws.updated_at,
+    ws.target_url,
+    ws.is_active
+FROM webhook_subscriptions ws
+JOIN isn i ON i.id = ws.isn_id
+JOIN signal_types st ON st.id = ws.signal_type_id
+WHERE ws.id = sqlc.arg(id)
+    AND ws.account_id = sqlc.arg(account_id)
+    AND i.slug = sqlc.arg(isn_slug)
+    AND st.slug = sqlc.arg(signal_type_slug)
+    AND st.sem_ver = sqlc.arg(sem_ver);
+
+-- name: DeleteWebhookSubscription :execrows
+-- Deletes the webhook subscription and its delivery log (requires ON DELETE CASCADE on webhook_deliveries.webhook_subscription_id)
+DELETE FROM webhook_subscriptions
+WHERE id = sqlc.arg(id)
+    AND account_id = sqlc.arg(account_id);
+
+-- name: CreateWebhookDeliveries :execrows
+-- Queues a delivery of the signal version for every active subscription on the signal's ISN and signal type.
+-- Should be called in the same transaction as CreateSignalVersion so that deliveries are only queued for committed signal versions.
+-- Subscriptions are skipped when the subscribing account is disabled or no longer has read access to the ISN.
+INSERT INTO webhook_deliveries (
+    id,
+    created_at,
+    updated_at,
+    webhook_subscription_id,
+    signal_version_id,
+    signal_account_id,
+    status,
+    attempt_count,
+    next_attempt_at
+)
+SELECT
+    uuidv7(),
+    now(),
+    now(),
+    ws.id,
+    sv.id,
+    sv.account_id,
+    'pending',
+    0,
+    now()
+FROM signal_versions sv
+JOIN signals s ON s.id = sv.signal_id AND s.account_id = sv.account_id
+JOIN isn i ON i.id = s.isn_id
+JOIN webhook_subscriptions ws ON ws.isn_id = s.isn_id AND ws.signal_type_id = s.signal_type_id
+JOIN accounts a ON a.id = ws.account_id
+WHERE sv.id = sqlc.arg(signal_version_id)
+    AND sv.account_id = sqlc.arg(account_id)
+    AND ws.is_active = true
+    AND a.is_active = true
+    AND (
+        EXISTS (
+            SELECT 1
+            FROM isn_accounts ia
+            WHERE ia.isn_id = i.id
+                AND ia.account_id = ws.account_id
+                AND ia.can_read = true
+        )
+        OR EXISTS (
+            SELECT 1
+            FROM users u
+            WHERE u.account_id = ws.account_id
+                AND (u.user_role = 'siteadmin' OR (u.user_role = 'isnadmin' AND i.user_account_id = u.account_id))
+        )
+    )
+ON CONFLICT (webhook_subscription_id, signal_version_id) DO NOTHING;
+
+-- name: ClaimDueWebhookDeliveries :many
+-- Claims a batch of pending deliveries that are due and returns the details needed to send them.
+-- The next_attempt_at timestamp of the claimed deliveries is moved forward by lease_seconds so they are not picked up again while in flight
+-- (if the dispatcher stops before recording the outcome, the delivery is retried when the lease expires).
+WITH due AS (
+    SELECT wd.id
+    FROM webhook_deliveries wd
+    WHERE wd.status = 'pending'
+        AND wd.next_attempt_at <= now()
+    ORDER BY wd.next_attempt_at
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+), claimed AS (
+    UPDATE webhook_deliveries wd
+    SET next_attempt_at = now() + (sqlc.arg(lease_seconds)::integer * interval '1 second'),
+        updated_at = now()
+    FROM due
+    WHERE wd.id = due.id
+    RETURNING wd.id, wd.webhook_subscription_id, wd.signal_version_id, wd.signal_account_id, wd.attempt_count
+)
+SELECT
+    c.id,
+    c.attempt_count,
+    ws.id AS webhook_subscription_id,
+    ws.target_url,
+    ws.signing_secret,
+    i.slug AS isn_slug,
+    st.slug AS signal_type_slug,
+    st.sem_ver,
+    a.id AS account_id,
+    a.account_type,
+    COALESCE(u.email, si.client_contact_email) AS email,
+    s.id AS signal_id,
+    s.local_ref,
+    s.created_at AS signal_created_at,
+    sv.id AS signal_version_id,
+    sv.version_number,
+    sv.created_at AS version_created_at,
+    s.correlation_id AS correlated_to_signal_id,
+    s.is_withdrawn,
+    sv.content
+FROM claimed c
+JOIN webhook_subscriptions ws ON ws.id = c.webhook_subscription_id
+JOIN signal_versions sv ON sv.id = c.signal_version_id AND sv.account_id = c.signal_account_id
+JOIN signals s ON s.id = sv.signal_id AND s.account_id = sv.account_id
+JOIN accounts a ON a.id = s.account_id
+JOIN isn i ON i.id = s.isn_id
+JOIN signal_types st ON st.id = s.signal_type_id
+LEFT OUTER JOIN users u ON u.account_id = a.id
+LEFT OUTER JOIN service_accounts si ON si.account_id = a.id
+ORDER BY c.id;
+
+-- name: RecordWebhookDeliveryAttempt :exec
+-- Records the outcome of a delivery attempt.
+-- status is 'delivered', 'pending' (the delivery will be retried at next_attempt_at) or 'failed' (no more retries)
+UPDATE webhook_deliveries
+SET status = sqlc.arg(status),
+    attempt_count = attempt_count + 1,
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    last_attempt_at = now(),
+    last_response_status = sqlc.narg(last_response_status),
+    last_error = sqlc.narg(last_error),
+    updated_at = now()
+WHERE id = sqlc.arg(id);
+
+-- name: GetWebhookDeliveries :many
+-- Returns the most recent deliveries for a webhook subscription (newest first)
+SELECT *
+FROM webhook_deliveries
+WHERE webhook_subscription_id = sqlc.arg(webhook_subscription_id)
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,56 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Webhooks
+-- -------------------------------------------------------------------------
+
+-- webhook_subscriptions: push delivery of new signal versions to a subscriber supplied URL
+-- subscriptions are created by accounts with read access to the ISN and cover a single signal type path
+-- the signing_secret is used to create the HMAC signature sent with each delivery, so it is stored in plain text
+CREATE TABLE webhook_subscriptions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    account_id UUID NOT NULL,
+    isn_id UUID NOT NULL,
+    signal_type_id UUID NOT NULL,
+    target_url TEXT NOT NULL,
+    signing_secret TEXT NOT NULL,
+    is_active BOOLEAN DEFAULT TRUE NOT NULL,
+    CONSTRAINT unique_webhook_subscription_target UNIQUE (account_id, isn_id, signal_type_id, target_url),
+    CONSTRAINT fk_webhook_subscriptions_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_webhook_subscriptions_isn FOREIGN KEY (isn_id) REFERENCES isn(id) ON DELETE CASCADE,
+    CONSTRAINT fk_webhook_subscriptions_signal_type FOREIGN KEY (signal_type_id) REFERENCES signal_types(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_subscriptions_isn_signal_type ON webhook_subscriptions (isn_id, signal_type_id);
+
+-- webhook_deliveries: delivery log - one row per signal version per subscription
+-- rows are created in the same transaction as the signal version and are sent by the webhook dispatcher
+-- failed deliveries are retried (with backoff) until the maximum number of attempts is reached
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    webhook_subscription_id UUID NOT NULL,
+    signal_version_id UUID NOT NULL,
+    signal_account_id UUID NOT NULL,
+    status TEXT NOT NULL,
+    attempt_count INTEGER DEFAULT 0 NOT NULL,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    last_response_status INTEGER,
+    last_error TEXT,
+    CONSTRAINT webhook_delivery_status_check CHECK (status IN ('pending', 'delivered', 'failed')),
+    CONSTRAINT unique_webhook_delivery UNIQUE (webhook_subscription_id, signal_version_id),
+    CONSTRAINT fk_webhook_deliveries_subscription FOREIGN KEY (webhook_subscription_id) REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    CONSTRAINT fk_webhook_deliveries_signal_version FOREIGN KEY (signal_version_id, signal_account_id) REFERENCES signal_versions(id, account_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries (webhook_subscription_id, created_at);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS webhook_deliveries CASCADE;
+DROP TABLE IF EXISTS webhook_subscriptions CASCADE;
@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Signal stream
+-- -------------------------------------------------------------------------
+
+-- tx_id records the id of the transaction that created the signal version.
+-- The signal stream reads versions in (tx_id, id) order and only returns versions from transactions that are older
+-- than the oldest transaction still in progress (pg_snapshot_xmin), so a version that commits late can't be skipped
+-- by a client that has already read past it.
+-- xid8 values are stored as bigint (the 64 bit transaction id does not wrap around)
+ALTER TABLE signal_versions ADD COLUMN tx_id BIGINT DEFAULT (pg_current_xact_id()::text::bigint) NOT NULL;
+
+CREATE INDEX idx_signal_versions_tx_id ON signal_versions (tx_id, id);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_signal_versions_tx_id;
+ALTER TABLE signal_versions DROP COLUMN IF EXISTS tx_id;
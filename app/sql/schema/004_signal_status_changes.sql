@@ -0,0 +1,26 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Signal status changes
+-- -------------------------------------------------------------------------
+
+-- signal_status_changes: records each change to signals.is_withdrawn so withdrawals can be included in the signal changes feed.
+-- is_withdrawn is the status after the change.
+-- tx_id is the id of the transaction that made the change (see signal_versions.tx_id) - the changes feed returns signal versions
+-- and status changes in (tx_id, id) order.
+CREATE TABLE signal_status_changes (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    tx_id BIGINT DEFAULT (pg_current_xact_id()::text::bigint) NOT NULL,
+    signal_id UUID NOT NULL,
+    account_id UUID NOT NULL,
+    is_withdrawn BOOLEAN NOT NULL,
+    CONSTRAINT fk_signal_status_changes_signal FOREIGN KEY (signal_id, account_id) REFERENCES signals(id, account_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_signal_status_changes_tx_id ON signal_status_changes (tx_id, id);
+CREATE INDEX idx_signal_status_changes_signal_id ON signal_status_changes (signal_id);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS signal_status_changes CASCADE;
@@ -0,0 +1,28 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Idempotency keys
+-- -------------------------------------------------------------------------
+
+-- idempotency_keys: the Idempotency-Key header values supplied with signal submission requests (keys are unique per account)
+-- request_hash identifies the request the key was first used with - the key can't be reused with a different request.
+-- response_status and response_body (the original response, stored as sent) are null while the original request is being processed.
+-- The stored response is returned, without processing the request again, when the request is repeated before the key expires.
+-- locked_until is the end of the lease held by the original request: if the request has not completed by then (e.g. the server stopped
+-- while it was being processed) the key can be taken over by a retry.
+CREATE TABLE idempotency_keys (
+    account_id UUID NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    request_hash TEXT NOT NULL,
+    response_status INTEGER,
+    response_body BYTEA,
+    locked_until TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (account_id, idempotency_key),
+    CONSTRAINT fk_idempotency_keys_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS idempotency_keys CASCADE;
@@ -0,0 +1,42 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Async signal submissions
+-- -------------------------------------------------------------------------
+
+-- signal_submission_queue: signal submission requests made with a Prefer: respond-async header.
+-- The request is validated and queued by the handler and the signals are stored by the submission workers.
+-- isn_slug is null for submissions made using the signal router (the ISNs are resolved when the submission is processed).
+-- claims are the access token claims used to make the request (the account's permissions are checked against these when the submission is processed).
+-- Claimed submissions are leased by moving next_attempt_at forward - submissions are retried (with backoff) if processing fails,
+-- until the maximum number of attempts is reached.
+-- response_status and response_body are the outcome that would have been returned by a synchronous request.
+CREATE TABLE signal_submission_queue (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    account_id UUID NOT NULL,
+    signal_batch_id UUID NOT NULL,
+    isn_slug TEXT,
+    signal_type_slug TEXT NOT NULL,
+    sem_ver TEXT NOT NULL,
+    claims JSONB NOT NULL,
+    request_body JSONB NOT NULL,
+    status TEXT NOT NULL,
+    attempt_count INTEGER DEFAULT 0 NOT NULL,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    response_status INTEGER,
+    response_body JSONB,
+    last_error TEXT,
+    CONSTRAINT signal_submission_status_check CHECK (status IN ('queued', 'completed', 'failed')),
+    CONSTRAINT fk_signal_submission_queue_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_signal_submission_queue_batch FOREIGN KEY (signal_batch_id) REFERENCES signal_batches(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_signal_submission_queue_queued ON signal_submission_queue (next_attempt_at) WHERE status = 'queued';
+CREATE INDEX idx_signal_submission_queue_batch ON signal_submission_queue (signal_batch_id, created_at);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS signal_submission_queue CASCADE;
@@ -0,0 +1,33 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Compound routing rules
+-- -------------------------------------------------------------------------
+
+-- routing rules can either compare the routing field defined on the signal routing config with a single match pattern (simple rules)
+-- or supply a condition that combines comparisons on any number of fields with all/any/not groups (compound rules), e.g
+--   {"all": [{"field": "payload.portOfEntry", "operator": "matches", "pattern": "*felixstowe*"}, {"field": "payload.commodityCode", "operator": "matches", "pattern": "0201*"}]}
+-- the routing field is only needed when the config includes simple rules.
+ALTER TABLE signal_routing_configs ALTER COLUMN routing_field DROP NOT NULL;
+
+ALTER TABLE routing_rules
+    ALTER COLUMN match_pattern DROP NOT NULL,
+    ALTER COLUMN operator DROP NOT NULL,
+    ADD COLUMN condition JSONB,
+    ADD CONSTRAINT routing_rule_criteria_check CHECK (
+        (condition IS NULL AND match_pattern IS NOT NULL AND operator IS NOT NULL)
+        OR (condition IS NOT NULL AND match_pattern IS NULL AND operator IS NULL)
+    );
+
+-- +goose Down
+
+DELETE FROM routing_rules WHERE condition IS NOT NULL;
+DELETE FROM signal_routing_configs WHERE routing_field IS NULL;
+
+ALTER TABLE routing_rules
+    DROP CONSTRAINT IF EXISTS routing_rule_criteria_check,
+    DROP COLUMN IF EXISTS condition,
+    ALTER COLUMN match_pattern SET NOT NULL,
+    ALTER COLUMN operator SET NOT NULL;
+
+ALTER TABLE signal_routing_configs ALTER COLUMN routing_field SET NOT NULL;
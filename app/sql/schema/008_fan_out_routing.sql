@@ -0,0 +1,52 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Fan-out routing
+-- -------------------------------------------------------------------------
+
+-- routing_mode controls how the routing rules for a signal type are applied:
+--   first_match - signals are routed to the ISN for the first rule that matches (the original behaviour)
+--   all_matches - signals are routed to the ISNs for every rule that matches
+ALTER TABLE signal_routing_configs
+    ADD COLUMN routing_mode TEXT NOT NULL DEFAULT 'first_match',
+    ADD CONSTRAINT signal_routing_configs_routing_mode_check CHECK (routing_mode IN ('first_match', 'all_matches'));
+
+-- signals routed to more than one ISN are stored as a separate signal in each ISN using the same local_ref,
+-- so the local_ref is now unique per account, ISN and signal type.
+ALTER TABLE signals
+    DROP CONSTRAINT unique_signals_account_signal_type_local_ref,
+    ADD CONSTRAINT unique_signals_account_isn_signal_type_local_ref UNIQUE (account_id, isn_id, signal_type_id, local_ref);
+
+-- the same local_ref can now be submitted to more than one ISN in a batch, so processing failures record the ISN
+-- (isn_id is null for signal router submissions that could not be routed to an ISN)
+ALTER TABLE signal_processing_failures
+    ADD COLUMN isn_id UUID,
+    ADD CONSTRAINT fk_signal_processing_failures_isn FOREIGN KEY (isn_id) REFERENCES isn(id) ON DELETE CASCADE;
+
+-- backfill the ISN for existing failures from the signal stored with the same local_ref
+-- (local_refs were unique per account and signal type before this migration, so there is at most one matching signal).
+-- Failures for local_refs that were never stored successfully can't be matched and are left null.
+UPDATE signal_processing_failures spf
+SET isn_id = s.isn_id
+FROM signal_batches sb
+JOIN signals s ON s.account_id = sb.account_id
+JOIN signal_types st ON st.id = s.signal_type_id
+WHERE sb.id = spf.signal_batch_id
+    AND s.local_ref = spf.local_ref
+    AND st.slug = spf.signal_type_slug
+    AND st.sem_ver = spf.signal_type_sem_ver;
+
+-- +goose Down
+
+ALTER TABLE signal_processing_failures
+    DROP CONSTRAINT IF EXISTS fk_signal_processing_failures_isn,
+    DROP COLUMN IF EXISTS isn_id;
+
+-- note the down migration fails if the same local_ref has been used in more than one ISN
+ALTER TABLE signals
+    DROP CONSTRAINT IF EXISTS unique_signals_account_isn_signal_type_local_ref,
+    ADD CONSTRAINT unique_signals_account_signal_type_local_ref UNIQUE (account_id, signal_type_id, local_ref);
+
+ALTER TABLE signal_routing_configs
+    DROP CONSTRAINT IF EXISTS signal_routing_configs_routing_mode_check,
+    DROP COLUMN IF EXISTS routing_mode;
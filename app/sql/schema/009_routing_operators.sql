@@ -0,0 +1,24 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Routing operators
+-- -------------------------------------------------------------------------
+
+-- routing rules can use string prefix/suffix, regex, set membership (in), numeric/date range and existence operators.
+-- the match_pattern is validated by the server when the config is saved
+-- (exists and not_exists rules don't use a pattern and are stored with an empty match_pattern)
+ALTER TABLE routing_rules
+    DROP CONSTRAINT operator_check,
+    ADD CONSTRAINT operator_check CHECK (operator IN (
+        'matches', 'equals', 'does_not_match', 'does_not_equal',
+        'starts_with', 'ends_with', 'regex', 'in',
+        'gt', 'gte', 'lt', 'lte',
+        'exists', 'not_exists'
+    ));
+
+-- +goose Down
+
+-- note the down migration fails if any routing rules use the new operators
+ALTER TABLE routing_rules
+    DROP CONSTRAINT IF EXISTS operator_check,
+    ADD CONSTRAINT operator_check CHECK (operator IN ('matches', 'equals','does_not_match','does_not_equal'));
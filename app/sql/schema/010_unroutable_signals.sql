@@ -0,0 +1,42 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Fallback ISNs and unroutable signals
+-- -------------------------------------------------------------------------
+
+-- routing configs can specify a fallback ISN that receives signals that don't match any of the routing rules
+ALTER TABLE signal_routing_configs
+    ADD COLUMN fallback_isn_id UUID,
+    ADD CONSTRAINT fk_signal_routing_configs_fallback_isn FOREIGN KEY (fallback_isn_id) REFERENCES isn(id) ON DELETE SET NULL;
+
+-- unroutable_signals: signals submitted to the signal router that did not match any routing rule (and there was no fallback ISN).
+-- The signals are kept so that site admins can re-route them once the routing rules have been fixed.
+-- claims are the access token claims used to submit the signal (re-routed signals are stored with the account's current permissions, narrowed to the scope of the original token).
+-- rerouted_at/rerouted_by_account_id are set when a site admin successfully re-routes the signal.
+CREATE TABLE unroutable_signals (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    account_id UUID NOT NULL,
+    signal_batch_id UUID NOT NULL,
+    signal_type_slug TEXT NOT NULL,
+    sem_ver TEXT NOT NULL,
+    local_ref TEXT NOT NULL,
+    content JSONB NOT NULL,
+    claims JSONB NOT NULL,
+    error_message TEXT NOT NULL,
+    rerouted_at TIMESTAMP WITH TIME ZONE,
+    rerouted_by_account_id UUID,
+    CONSTRAINT fk_unroutable_signals_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_unroutable_signals_batch FOREIGN KEY (signal_batch_id) REFERENCES signal_batches(id) ON DELETE CASCADE,
+    CONSTRAINT fk_unroutable_signals_rerouted_by FOREIGN KEY (rerouted_by_account_id) REFERENCES accounts(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_unroutable_signals_signal_type ON unroutable_signals (signal_type_slug, sem_ver, created_at);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS unroutable_signals CASCADE;
+
+ALTER TABLE signal_routing_configs
+    DROP CONSTRAINT IF EXISTS fk_signal_routing_configs_fallback_isn,
+    DROP COLUMN IF EXISTS fallback_isn_id;
@@ -0,0 +1,60 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Routing config revisions
+-- -------------------------------------------------------------------------
+
+-- signal_routing_config_revisions: an immutable record of every change to the routing config for a signal type.
+-- A revision is added each time the config is saved, deleted or rolled back - revision numbers start at 1 for each signal type.
+-- config is the routing config as it was saved (same format as the Update Signals Routing Config request) and is null for deletions.
+-- account_id is the account that made the change (null for revisions created by this migration or if the account has been deleted).
+-- rolled_back_to_revision is the revision that was restored by a rollback.
+CREATE TABLE signal_routing_config_revisions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    signal_type_id UUID NOT NULL,
+    revision INTEGER NOT NULL,
+    account_id UUID,
+    action TEXT NOT NULL,
+    config JSONB,
+    rolled_back_to_revision INTEGER,
+    CONSTRAINT unique_revision_per_signal_type UNIQUE (signal_type_id, revision),
+    CONSTRAINT signal_routing_config_revisions_action_check CHECK (action IN ('updated', 'deleted', 'rolled_back')),
+    CONSTRAINT signal_routing_config_revisions_config_check CHECK ((action = 'deleted') = (config IS NULL)),
+    CONSTRAINT fk_signal_routing_config_revisions_signal_type FOREIGN KEY (signal_type_id) REFERENCES signal_types(id) ON DELETE CASCADE,
+    CONSTRAINT fk_signal_routing_config_revisions_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE SET NULL
+);
+
+-- record the existing routing configs as the first revision
+INSERT INTO signal_routing_config_revisions (id, created_at, signal_type_id, revision, account_id, action, config)
+SELECT
+    uuidv7(),
+    src.created_at,
+    src.signal_type_id,
+    1,
+    NULL,
+    'updated',
+    jsonb_strip_nulls(jsonb_build_object(
+        'routing_field', src.routing_field,
+        'routing_mode', src.routing_mode,
+        'fallback_isn_slug', fb.slug,
+        'routing_rules', (
+            SELECT jsonb_agg(jsonb_build_object(
+                'match_pattern', rr.match_pattern,
+                'operator', rr.operator,
+                'is_case_insensitive', rr.is_case_insensitive,
+                'condition', rr.condition,
+                'isn_slug', i.slug,
+                'sequence', rr.rule_sequence
+            ) ORDER BY rr.rule_sequence)
+            FROM routing_rules rr
+            JOIN isn i ON i.id = rr.isn_id
+            WHERE rr.signal_routing_config_id = src.id
+        )
+    ))
+FROM signal_routing_configs src
+LEFT JOIN isn fb ON fb.id = src.fallback_isn_id;
+
+-- +goose Down
+
+DROP TABLE IF EXISTS signal_routing_config_revisions CASCADE;
@@ -0,0 +1,23 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Routing on sender identity and request headers
+-- -------------------------------------------------------------------------
+
+-- routing rules can use the sender of a signal (account, account type and service account organization) and the request headers as well as the signal content.
+-- routing_context is the sender and headers of the original request (credentials are not stored) - it is kept with queued router submissions
+-- and unroutable signals so that they are routed in the same way as a synchronous request.
+-- routing_context is null for submissions made before this migration and for submissions made directly to an ISN.
+ALTER TABLE signal_submission_queue
+    ADD COLUMN routing_context JSONB;
+
+ALTER TABLE unroutable_signals
+    ADD COLUMN routing_context JSONB;
+
+-- +goose Down
+
+ALTER TABLE unroutable_signals
+    DROP COLUMN IF EXISTS routing_context;
+
+ALTER TABLE signal_submission_queue
+    DROP COLUMN IF EXISTS routing_context;
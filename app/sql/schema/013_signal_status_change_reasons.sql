@@ -0,0 +1,23 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Withdrawal reasons and reinstatement
+-- -------------------------------------------------------------------------
+
+-- signals can be withdrawn and reinstated in bulk (by local ref, signal id or batch) by the account that owns them or by an ISN admin.
+-- changed_by_account_id is the account that made the change (this is not always the account that owns the signal) and
+-- reason is the reason given for the change (optional when a single signal is withdrawn).
+ALTER TABLE signal_status_changes
+    ADD COLUMN changed_by_account_id UUID,
+    ADD COLUMN reason TEXT,
+    ADD CONSTRAINT fk_signal_status_changes_changed_by FOREIGN KEY (changed_by_account_id) REFERENCES accounts(id) ON DELETE SET NULL;
+
+-- before this migration signals could only be withdrawn by the account that owns them
+UPDATE signal_status_changes SET changed_by_account_id = account_id;
+
+-- +goose Down
+
+ALTER TABLE signal_status_changes
+    DROP CONSTRAINT IF EXISTS fk_signal_status_changes_changed_by,
+    DROP COLUMN IF EXISTS reason,
+    DROP COLUMN IF EXISTS changed_by_account_id;
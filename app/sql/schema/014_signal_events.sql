@@ -0,0 +1,98 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Signal events (audit trail)
+-- -------------------------------------------------------------------------
+
+-- signal_events: the lifecycle of each signal - who created, updated, withdrew, reinstated or archived it, when and why.
+-- The events can be included in search results (include_history) to provide a provenance trail for the signal,
+-- and the withdrawn/reinstated events are included in the signal changes feed.
+-- signal_events replaces signal_status_changes (the existing status changes are moved to this table, keeping their ids so changes feed positions remain valid).
+--
+-- event types:
+--   created              the first version of the signal was stored
+--   version_created      a new version of the signal was stored
+--   withdrawn            the signal was withdrawn
+--   reinstated           the signal was reinstated (using the reinstate endpoint or by resubmitting a withdrawn signal)
+--   archived/unarchived  signals.is_archived was changed (recorded by the signals_archive_events trigger - archiving is not done by the API)
+--
+-- account_id is the account that owns the signal and actor_account_id is the account that made the change (not set when the change was made outside the API).
+-- signal_version_id and version_number are only set for created and version_created events.
+-- tx_id is the id of the transaction that recorded the event (see signal_versions.tx_id) - the changes feed returns signal versions
+-- and status changes in (tx_id, id) order.
+CREATE TABLE signal_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    tx_id BIGINT DEFAULT (pg_current_xact_id()::text::bigint) NOT NULL,
+    signal_id UUID NOT NULL,
+    account_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    signal_version_id UUID,
+    version_number INT,
+    actor_account_id UUID,
+    reason TEXT,
+    CONSTRAINT signal_events_event_type_check CHECK (event_type IN ('created', 'version_created', 'withdrawn', 'reinstated', 'archived', 'unarchived')),
+    CONSTRAINT fk_signal_events_signal FOREIGN KEY (signal_id, account_id) REFERENCES signals(id, account_id) ON DELETE CASCADE,
+    CONSTRAINT fk_signal_events_actor FOREIGN KEY (actor_account_id) REFERENCES accounts(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_signal_events_tx_id ON signal_events (tx_id, id);
+CREATE INDEX idx_signal_events_signal_id ON signal_events (signal_id, created_at, id);
+
+-- +goose StatementBegin
+CREATE FUNCTION record_signal_archive_event() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO signal_events (id, created_at, signal_id, account_id, event_type)
+    VALUES (uuidv7(), now(), NEW.id, NEW.account_id, CASE WHEN NEW.is_archived THEN 'archived' ELSE 'unarchived' END);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER signals_archive_events
+    AFTER UPDATE OF is_archived ON signals
+    FOR EACH ROW
+    WHEN (OLD.is_archived IS DISTINCT FROM NEW.is_archived)
+    EXECUTE FUNCTION record_signal_archive_event();
+
+-- existing signal versions and status changes
+INSERT INTO signal_events (id, created_at, tx_id, signal_id, account_id, event_type, signal_version_id, version_number, actor_account_id)
+SELECT uuidv7(), sv.created_at, sv.tx_id, sv.signal_id, sv.account_id,
+    CASE WHEN sv.version_number = 1 THEN 'created' ELSE 'version_created' END,
+    sv.id, sv.version_number, sv.account_id
+FROM signal_versions sv;
+
+INSERT INTO signal_events (id, created_at, tx_id, signal_id, account_id, event_type, actor_account_id, reason)
+SELECT ssc.id, ssc.created_at, ssc.tx_id, ssc.signal_id, ssc.account_id,
+    CASE WHEN ssc.is_withdrawn THEN 'withdrawn' ELSE 'reinstated' END,
+    ssc.changed_by_account_id, ssc.reason
+FROM signal_status_changes ssc;
+
+DROP TABLE signal_status_changes;
+
+-- +goose Down
+
+CREATE TABLE signal_status_changes (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    tx_id BIGINT DEFAULT (pg_current_xact_id()::text::bigint) NOT NULL,
+    signal_id UUID NOT NULL,
+    account_id UUID NOT NULL,
+    is_withdrawn BOOLEAN NOT NULL,
+    changed_by_account_id UUID,
+    reason TEXT,
+    CONSTRAINT fk_signal_status_changes_signal FOREIGN KEY (signal_id, account_id) REFERENCES signals(id, account_id) ON DELETE CASCADE,
+    CONSTRAINT fk_signal_status_changes_changed_by FOREIGN KEY (changed_by_account_id) REFERENCES accounts(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_signal_status_changes_tx_id ON signal_status_changes (tx_id, id);
+CREATE INDEX idx_signal_status_changes_signal_id ON signal_status_changes (signal_id);
+
+INSERT INTO signal_status_changes (id, created_at, tx_id, signal_id, account_id, is_withdrawn, changed_by_account_id, reason)
+SELECT se.id, se.created_at, se.tx_id, se.signal_id, se.account_id, se.event_type = 'withdrawn', se.actor_account_id, se.reason
+FROM signal_events se
+WHERE se.event_type IN ('withdrawn', 'reinstated');
+
+DROP TRIGGER IF EXISTS signals_archive_events ON signals;
+DROP FUNCTION IF EXISTS record_signal_archive_event();
+DROP TABLE IF EXISTS signal_events CASCADE;
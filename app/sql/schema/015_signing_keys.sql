@@ -0,0 +1,31 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Access token signing keys
+-- -------------------------------------------------------------------------
+
+-- signing_keys: the asymmetric keys used to sign access tokens when ACCESS_TOKEN_SIGNING_ALG is RS256, ES256 or EdDSA.
+-- The public keys are published at /.well-known/jwks.json so that other services can verify access tokens without calling signalsd.
+--
+-- id is used as the JWT kid header.
+-- public_key is the PKIX (DER) encoded public key.
+-- encrypted_private_key is the PKCS #8 (DER) encoded private key encrypted with AES-256-GCM using a key derived from SECRET_KEY.
+--
+-- Keys are rotated by creating the next key in advance (activates_at is in the future) so that it is published before it is used
+-- and setting retires_at on the current key to the time the next key activates.
+-- Retired keys stay in the key set until the last token signed with them has expired.
+CREATE TABLE signing_keys (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    algorithm TEXT NOT NULL,
+    public_key BYTEA NOT NULL,
+    encrypted_private_key BYTEA NOT NULL,
+    activates_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    retires_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT signing_keys_algorithm_check CHECK (algorithm IN ('RS256', 'ES256', 'EdDSA')),
+    CONSTRAINT signing_keys_retires_after_activation CHECK (retires_at IS NULL OR retires_at >= activates_at)
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS signing_keys CASCADE;
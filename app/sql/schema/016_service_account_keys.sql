@@ -0,0 +1,39 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Service account public keys
+-- -------------------------------------------------------------------------
+
+-- service_account_keys: public keys registered by admins that service accounts can use to authenticate instead of a client secret:
+--   - private_key_jwt (RFC 7523): the client signs a JWT client assertion with the private key. id is used as the JWT kid header.
+--   - self_signed_tls_client_auth (RFC 8705): the client presents a TLS client certificate for the key.
+--
+-- public_key is the PKIX (DER) encoded public key and algorithm is the JWT signing algorithm used with the key (set by the key type).
+CREATE TABLE service_account_keys (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    service_account_account_id UUID NOT NULL,
+    algorithm TEXT NOT NULL,
+    public_key BYTEA NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT service_account_keys_algorithm_check CHECK (algorithm IN ('RS256', 'ES256', 'EdDSA')),
+    CONSTRAINT fk_service_account_key_service_account FOREIGN KEY (service_account_account_id) REFERENCES service_accounts(account_id) ON DELETE CASCADE
+);
+
+-- a key can only be registered once per service account (revoked keys can be registered again)
+CREATE UNIQUE INDEX service_account_keys_public_key_unique ON service_account_keys (service_account_account_id, public_key) WHERE revoked_at IS NULL;
+
+-- client_assertions: the jti of each client assertion used to authenticate, so that assertions can't be replayed.
+-- Rows are deleted once the assertion has expired.
+CREATE TABLE client_assertions (
+    service_account_account_id UUID NOT NULL,
+    jti TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (service_account_account_id, jti),
+    CONSTRAINT fk_client_assertion_service_account FOREIGN KEY (service_account_account_id) REFERENCES service_accounts(account_id) ON DELETE CASCADE
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS client_assertions CASCADE;
+DROP TABLE IF EXISTS service_account_keys CASCADE;
@@ -0,0 +1,55 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- OpenID Connect federated login
+-- -------------------------------------------------------------------------
+
+-- user_identities: links users to their accounts at the identity providers configured in OIDC_PROVIDERS.
+-- provider_id is the id of the provider in the config and subject is the sub claim from the provider's id_tokens (unique per provider).
+-- email is the email address from the most recent id_token.
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    user_account_id UUID NOT NULL,
+    provider_id TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NOT NULL,
+    last_login_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CONSTRAINT user_identities_provider_subject_unique UNIQUE (provider_id, subject),
+    CONSTRAINT fk_user_identity_user FOREIGN KEY (user_account_id) REFERENCES users(account_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_identities_user_account_id ON user_identities (user_account_id);
+
+-- oidc_login_requests: logins that have been started but not completed.
+-- The id is stored in a cookie while the user is at the identity provider, and the request is deleted when the provider redirects back to the site (requests can only be used once).
+-- state, nonce and code_verifier are the values sent to the provider (the code verifier is the PKCE secret used to redeem the authorization code).
+-- return_to is the path on the site the user is redirected to after the login (when null the login response is returned as JSON).
+CREATE TABLE oidc_login_requests (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    provider_id TEXT NOT NULL,
+    state TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    return_to TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_oidc_login_requests_expires_at ON oidc_login_requests (expires_at);
+
+-- oidc_login_codes: one-time codes issued to web clients (e.g. the UI) when a login that has a return_to path completes.
+-- The client redeems the code at /api/auth/oidc/token to get the access and refresh tokens (only the hash of the code is stored).
+CREATE TABLE oidc_login_codes (
+    hashed_code TEXT PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    user_account_id UUID NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    CONSTRAINT fk_oidc_login_code_user FOREIGN KEY (user_account_id) REFERENCES users(account_id) ON DELETE CASCADE
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS oidc_login_codes CASCADE;
+DROP TABLE IF EXISTS oidc_login_requests CASCADE;
+DROP TABLE IF EXISTS user_identities CASCADE;
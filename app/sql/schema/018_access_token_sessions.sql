@@ -0,0 +1,33 @@
+-- +goose Up
+
+-- -------------------------------------------------------------------------
+-- Access token sessions
+-- -------------------------------------------------------------------------
+
+-- access tokens include the id of the credential used to obtain them (the sid claim) so that token introspection can check
+-- whether that credential has been revoked:
+--   service accounts: the client_secrets id or the service_account_keys id
+--   web users: the refresh_tokens session_id. The session id is created at login and kept when the refresh token is rotated,
+--   so the access tokens issued in a session stay active until the session's refresh token is revoked (logout or a new login).
+ALTER TABLE client_secrets
+    ADD COLUMN id UUID NOT NULL DEFAULT uuidv7();
+
+CREATE UNIQUE INDEX client_secrets_id_unique ON client_secrets (id);
+
+ALTER TABLE refresh_tokens
+    ADD COLUMN session_id UUID;
+
+-- each existing refresh token is treated as a separate session
+UPDATE refresh_tokens SET session_id = uuidv7();
+
+ALTER TABLE refresh_tokens
+    ALTER COLUMN session_id SET NOT NULL;
+
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens (session_id);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_refresh_tokens_session_id;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS session_id;
+DROP INDEX IF EXISTS client_secrets_id_unique;
+ALTER TABLE client_secrets DROP COLUMN IF EXISTS id;
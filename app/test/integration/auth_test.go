@@ -36,7 +36,7 @@ func TestPermissions(t *testing.T) {
 	ctx := context.Background()
 	testEnv := startInProcessServer(t, "")
 
-	authService := auth.NewAuthService(testEnv.cfg.SecretKey, testEnv.cfg.Environment, testEnv.queries)
+	authService := auth.NewAuthService(testEnv.cfg.SecretKey, testEnv.cfg.Environment, testEnv.queries, testEnv.authService.SigningKeys(), testEnv.cfg.PublicBaseURL, testEnv.cfg.TLSClientCertHeader, testEnv.cfg.TLSClientCertTrustedProxies)
 
 	// Create test accounts
 	siteAdminAccount := createTestAccount(t, ctx, testEnv.queries, "siteadmin", "user", "siteadmin@gmail.com")
@@ -305,7 +305,7 @@ func TestLoginAuth(t *testing.T) {
 	ctx := context.Background()
 	testEnv := startInProcessServer(t, "")
 
-	authService := auth.NewAuthService(testEnv.cfg.SecretKey, testEnv.cfg.Environment, testEnv.queries)
+	authService := auth.NewAuthService(testEnv.cfg.SecretKey, testEnv.cfg.Environment, testEnv.queries, testEnv.authService.SigningKeys(), testEnv.cfg.PublicBaseURL, testEnv.cfg.TLSClientCertHeader, testEnv.cfg.TLSClientCertTrustedProxies)
 
 	// Create test accounts with real hashed passwords
 
@@ -411,6 +411,7 @@ func TestLoginAuth(t *testing.T) {
 			t.Run(tt.name, func(t *testing.T) {
 
 				ctx = auth.ContextWithAccountID(ctx, tt.expectedAccountID)
+				ctx = auth.ContextWithSessionID(ctx, uuid.New())
 
 				user, err := testEnv.queries.GetUserByEmail(ctx, tt.email)
 				if err != nil {
@@ -485,7 +486,7 @@ func TestLoginAuth(t *testing.T) {
 func TestClientCredentialsAuth(t *testing.T) {
 	ctx := context.Background()
 	testEnv := startInProcessServer(t, "")
-	authService := auth.NewAuthService(testEnv.cfg.SecretKey, testEnv.cfg.Environment, testEnv.queries)
+	authService := auth.NewAuthService(testEnv.cfg.SecretKey, testEnv.cfg.Environment, testEnv.queries, testEnv.authService.SigningKeys(), testEnv.cfg.PublicBaseURL, testEnv.cfg.TLSClientCertHeader, testEnv.cfg.TLSClientCertTrustedProxies)
 
 	// Create test service account
 	serviceAccount := createTestAccount(t, ctx, testEnv.queries, "member", "service_account", "service@client.com")
@@ -550,7 +551,7 @@ func TestDisabledAccountAuth(t *testing.T) {
 
 	testEnv := startInProcessServer(t, "")
 
-	authService := auth.NewAuthService(testEnv.cfg.SecretKey, testEnv.cfg.Environment, testEnv.queries)
+	authService := auth.NewAuthService(testEnv.cfg.SecretKey, testEnv.cfg.Environment, testEnv.queries, testEnv.authService.SigningKeys(), testEnv.cfg.PublicBaseURL, testEnv.cfg.TLSClientCertHeader, testEnv.cfg.TLSClientCertTrustedProxies)
 
 	// create test data
 	t.Log("Creating test data...")
@@ -0,0 +1,85 @@
+//go:build integration
+
+package integration
+
+// tests
+// - the caches are reloaded when a cache invalidation notification is received (without waiting for the caches to be polled)
+// - admin handlers send a notification when cached data is changed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/invalidation"
+	"github.com/information-sharing-networks/signalsd/app/internal/router"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+// waitForCache polls until check returns true. When notify is not nil it is called before each check
+// (the listener starts in the background, so notifications sent straight after the server starts can be missed).
+func waitForCache(t *testing.T, description string, notify func(), check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if notify != nil {
+			notify()
+		}
+		if check() {
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", description)
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	siteAdminAccount := createTestAccount(t, ctx, testEnv.queries, "siteadmin", "user", "siteadmin@cache-test.com")
+	siteAdminToken := testEnv.createAuthToken(t, siteAdminAccount.ID)
+
+	t.Run("caches_reloaded_on_notification", func(t *testing.T) {
+		// the ISN and signal type are created directly on the database so the caches are not updated
+		schemaCount := testEnv.schemaCache.Len()
+		isn := createTestISN(t, ctx, testEnv.queries, "cache-public-isn", "Cache Public ISN", siteAdminAccount.ID, "public")
+		createTestSignalType(t, ctx, testEnv.queries, isn.ID, "cache test signal", "")
+
+		if testEnv.publicIsnCache.Contains(isn.Slug) {
+			t.Fatal("did not expect the public ISN to be cached before the notification")
+		}
+
+		waitForCache(t, "schema and public ISN caches to be reloaded",
+			func() {
+				invalidation.Notify(ctx, testEnv.queries, invalidation.SchemaCache, invalidation.PublicIsnCache)
+			},
+			func() bool {
+				return testEnv.schemaCache.Len() == schemaCount+1 && testEnv.publicIsnCache.Contains(isn.Slug)
+			})
+	})
+
+	t.Run("routing_config_update_reloads_router_cache", func(t *testing.T) {
+		isn := createTestISN(t, ctx, testEnv.queries, "cache-router-isn", "Cache Router ISN", siteAdminAccount.ID, "private")
+		signalType := createTestSignalType(t, ctx, testEnv.queries, isn.ID, "cache router signal", "")
+		if err := testEnv.schemaCache.Load(ctx); err != nil {
+			t.Fatalf("Failed to refresh schema cache: %v", err)
+		}
+
+		// the router cache is not reloaded by the test - the handler notifies the listener
+		setRoutingConfig(t, testEnv, siteAdminToken, signalType, handlers.UpdateSignalRoutingConfigRequest{
+			RoutingField: "test",
+			RoutingRules: []handlers.SignalRoutingRule{
+				{MatchPattern: "*", Operator: "matches", IsnSlug: isn.Slug, Sequence: 1},
+			},
+		})
+
+		signalTypePath := fmt.Sprintf("%s/v%s", signalType.Slug, signalType.SemVer)
+		waitForCache(t, "router cache to be reloaded", nil, func() bool {
+			targets := testEnv.routerCache.Resolve(signalTypePath, json.RawMessage(`{"test": "any value"}`), router.RoutingContext{})
+			return len(targets) == 1 && targets[0].IsnSlug == isn.Slug
+		})
+	})
+}
@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"strings"
 	"testing"
@@ -34,10 +35,14 @@ import (
 	"github.com/information-sharing-networks/signalsd/app/internal/schemas"
 	"github.com/information-sharing-networks/signalsd/app/internal/server"
 	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/information-sharing-networks/signalsd/app/internal/signingkeys"
 )
 
 const testSecretKey = "test-secret-key-12345"
 
+// testClientCertHeader is the TLS_CLIENT_CERT_HEADER used by the test server
+const testClientCertHeader = "X-Client-Cert"
+
 // testEnv provides access to test db and server for integration tests
 type testEnv struct {
 	baseURL        string
@@ -97,6 +102,13 @@ func startInProcessServer(t *testing.T, publicBaseURL string) *testEnv {
 		DBMaxConnIdleTime:    30 * time.Minute,
 		DBConnectTimeout:     5 * time.Second,
 		PublicBaseURL:        fmt.Sprintf("http://localhost:%d", port),
+
+		AccessTokenSigningAlg:      "HS256",
+		SigningKeyRotationInterval: 720 * time.Hour,
+
+		// the tests simulate a TLS terminating proxy forwarding client certificates in this header (the test requests come from the loopback address)
+		TLSClientCertHeader:         testClientCertHeader,
+		TLSClientCertTrustedProxies: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")},
 	}
 
 	// publicBaseURL is only used when generating end user facing links like password reset
@@ -109,6 +121,30 @@ func startInProcessServer(t *testing.T, publicBaseURL string) *testEnv {
 		cfg.AllowedOrigins = strings.Split(origins, "|")
 	}
 
+	// Allow tests to use asymmetric access token signing (e.g. JWKS tests)
+	if alg := os.Getenv("ACCESS_TOKEN_SIGNING_ALG"); alg != "" {
+		cfg.AccessTokenSigningAlg = alg
+	}
+
+	// Allow tests to change the sources the client certificate header is accepted from (e.g. forged header tests)
+	if proxies := os.Getenv("TLS_CLIENT_CERT_TRUSTED_PROXIES"); proxies != "" {
+		cfg.TLSClientCertTrustedProxies = nil
+		for proxy := range strings.SplitSeq(proxies, ",") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				t.Fatalf("Failed to parse TLS_CLIENT_CERT_TRUSTED_PROXIES: %v", err)
+			}
+			cfg.TLSClientCertTrustedProxies = append(cfg.TLSClientCertTrustedProxies, prefix)
+		}
+	}
+
+	// Allow tests to configure OpenID Connect identity providers (e.g. federated login tests using a mock IdP)
+	if providers := os.Getenv("OIDC_PROVIDERS"); providers != "" {
+		if err := cfg.OIDCProviders.UnmarshalText([]byte(providers)); err != nil {
+			t.Fatalf("Failed to parse OIDC_PROVIDERS: %v", err)
+		}
+	}
+
 	corsConfigs, err := signalsd.CreateCORSConfigs(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create CORS configs: %v", err)
@@ -120,7 +156,15 @@ func startInProcessServer(t *testing.T, publicBaseURL string) *testEnv {
 		logLevel = logger.ParseLogLevel("Info")
 	}
 
-	testEnv.authService = auth.NewAuthService(testSecretKey, environment, testEnv.queries)
+	signingKeys, err := signingkeys.NewKeySet(testEnv.pool, testEnv.queries, testSecretKey, cfg.AccessTokenSigningAlg, cfg.SigningKeyRotationInterval)
+	if err != nil {
+		t.Fatalf("Failed to create signing key set: %v", err)
+	}
+	if err := signingKeys.Rotate(ctx); err != nil {
+		t.Fatalf("Failed to rotate signing keys: %v", err)
+	}
+
+	testEnv.authService = auth.NewAuthService(testSecretKey, environment, testEnv.queries, signingKeys, cfg.PublicBaseURL, cfg.TLSClientCertHeader, cfg.TLSClientCertTrustedProxies)
 
 	testEnv.schemaCache = schemas.NewCache(testEnv.queries)
 	if err := testEnv.schemaCache.Load(ctx); err != nil {
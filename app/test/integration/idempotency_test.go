@@ -0,0 +1,217 @@
+//go:build integration
+
+package integration
+
+// tests
+// - repeated signal submission requests with the same Idempotency-Key return the original response without creating new signal versions
+// - idempotency keys can't be reused with a different request
+// - idempotency keys are unique per account
+// - keys held by requests that did not complete are taken over by a retry once the lease expires
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+// submitSignalsWithIdempotencyKey submits signals with the supplied Idempotency-Key header and returns the response status, headers and body
+func submitSignalsWithIdempotencyKey(t *testing.T, baseURL string, endpoint testSignalEndpoint, token, idempotencyKey string, payload map[string]any) (int, http.Header, []byte) {
+	t.Helper()
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/api/isn/%s/signal-types/%s/v%s/signals",
+		baseURL, endpoint.isnSlug, endpoint.signalTypeSlug, endpoint.signalTypeSemVer)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to submit signals: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	return resp.StatusCode, resp.Header, body
+}
+
+// storedVersionNumber returns the version number of the single signal stored by a submission request
+func storedVersionNumber(t *testing.T, body []byte) int32 {
+	t.Helper()
+
+	var response handlers.SignalSubmissionResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Results) != 1 || len(response.Results[0].StoredSignals) != 1 {
+		t.Fatalf("expected 1 stored signal, got %s", body)
+	}
+	return response.Results[0].StoredSignals[0].VersionNumber
+}
+
+func TestSignalSubmissionIdempotencyKeys(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	ownerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "owner@idempotency-test.com")
+	otherAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "other@idempotency-test.com")
+
+	isn := createTestISN(t, ctx, testEnv.queries, "idempotency-isn", "Idempotency ISN", ownerAccount.ID, "private")
+	signalType := createTestSignalType(t, ctx, testEnv.queries, isn.ID, "idempotency test signal", "")
+
+	grantPermission(t, ctx, testEnv.queries, isn.ID, ownerAccount.ID, "write")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, otherAccount.ID, "write")
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("Failed to refresh schema cache: %v", err)
+	}
+
+	ownerToken := testEnv.createAuthToken(t, ownerAccount.ID)
+	otherToken := testEnv.createAuthToken(t, otherAccount.ID)
+
+	endpoint := testSignalEndpoint{
+		isnSlug:          isn.Slug,
+		signalTypeSlug:   signalType.Slug,
+		signalTypeSemVer: signalType.SemVer,
+	}
+
+	t.Run("repeated_request_returns_original_response", func(t *testing.T) {
+		payload := createValidSignalPayload("idempotent-signal-1")
+
+		status, headers, body := submitSignalsWithIdempotencyKey(t, testEnv.baseURL, endpoint, ownerToken, "key-1", payload)
+		if status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, status, body)
+		}
+		if headers.Get("Idempotent-Replayed") != "" {
+			t.Errorf("did not expect Idempotent-Replayed header on the original response")
+		}
+		if version := storedVersionNumber(t, body); version != 1 {
+			t.Errorf("expected version 1, got %d", version)
+		}
+
+		replayStatus, replayHeaders, replayBody := submitSignalsWithIdempotencyKey(t, testEnv.baseURL, endpoint, ownerToken, "key-1", payload)
+		if replayStatus != status {
+			t.Errorf("expected replayed status %d, got %d", status, replayStatus)
+		}
+		if replayHeaders.Get("Idempotent-Replayed") != "true" {
+			t.Errorf("expected Idempotent-Replayed header to be true, got %q", replayHeaders.Get("Idempotent-Replayed"))
+		}
+		if !bytes.Equal(bytes.TrimSpace(replayBody), bytes.TrimSpace(body)) {
+			t.Errorf("expected the original response body\noriginal: %s\nreplayed: %s", body, replayBody)
+		}
+
+		// the replay did not create a new version - a request with a new key creates version 2
+		status, _, body = submitSignalsWithIdempotencyKey(t, testEnv.baseURL, endpoint, ownerToken, "key-2", createUpdatedSignalPayload("idempotent-signal-1"))
+		if status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, status, body)
+		}
+		if version := storedVersionNumber(t, body); version != 2 {
+			t.Errorf("expected version 2, got %d", version)
+		}
+	})
+
+	t.Run("key_reused_with_different_request", func(t *testing.T) {
+		status, _, body := submitSignalsWithIdempotencyKey(t, testEnv.baseURL, endpoint, ownerToken, "key-1", createValidSignalPayload("idempotent-signal-2"))
+		if status != http.StatusUnprocessableEntity {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusUnprocessableEntity, status, body)
+		}
+		if !strings.Contains(string(body), "idempotency_key_reused") {
+			t.Errorf("expected idempotency_key_reused error, got %s", body)
+		}
+	})
+
+	t.Run("keys_are_unique_per_account", func(t *testing.T) {
+		status, headers, body := submitSignalsWithIdempotencyKey(t, testEnv.baseURL, endpoint, otherToken, "key-1", createValidSignalPayload("idempotent-signal-1"))
+		if status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, status, body)
+		}
+		if headers.Get("Idempotent-Replayed") != "" {
+			t.Errorf("did not expect a replayed response for a different account")
+		}
+	})
+
+	// simulate a request that was interrupted (e.g. by a server restart) before it completed - the key is left in progress
+	interruptedRequest := func(t *testing.T, key, localRef string, lockedUntil time.Time) map[string]any {
+		t.Helper()
+
+		payload := createValidSignalPayload(localRef)
+		body, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("Failed to marshal payload: %v", err)
+		}
+		hash := sha256.New()
+		fmt.Fprintf(hash, "%s /api/isn/%s/signal-types/%s/v%s/signals\n", http.MethodPost, endpoint.isnSlug, endpoint.signalTypeSlug, endpoint.signalTypeSemVer)
+		hash.Write(body)
+
+		if _, err := testEnv.queries.CreateIdempotencyKey(ctx, database.CreateIdempotencyKeyParams{
+			AccountID:      ownerAccount.ID,
+			IdempotencyKey: key,
+			ExpiresAt:      time.Now().Add(time.Hour),
+			RequestHash:    hex.EncodeToString(hash.Sum(nil)),
+			LockedUntil:    lockedUntil,
+		}); err != nil {
+			t.Fatalf("Failed to create idempotency key: %v", err)
+		}
+		return payload
+	}
+
+	t.Run("request_in_progress", func(t *testing.T) {
+		payload := interruptedRequest(t, "key-in-progress", "idempotent-signal-4", time.Now().Add(time.Minute))
+
+		status, _, body := submitSignalsWithIdempotencyKey(t, testEnv.baseURL, endpoint, ownerToken, "key-in-progress", payload)
+		if status != http.StatusConflict {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusConflict, status, body)
+		}
+		if !strings.Contains(string(body), "idempotency_key_in_progress") {
+			t.Errorf("expected idempotency_key_in_progress error, got %s", body)
+		}
+	})
+
+	t.Run("expired_lease_taken_over_by_retry", func(t *testing.T) {
+		payload := interruptedRequest(t, "key-lease-expired", "idempotent-signal-5", time.Now().Add(-time.Second))
+
+		status, headers, body := submitSignalsWithIdempotencyKey(t, testEnv.baseURL, endpoint, ownerToken, "key-lease-expired", payload)
+		if status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, status, body)
+		}
+		if headers.Get("Idempotent-Replayed") != "" {
+			t.Errorf("did not expect a replayed response when the key is taken over")
+		}
+
+		// the response to the retry is stored against the key
+		replayStatus, replayHeaders, _ := submitSignalsWithIdempotencyKey(t, testEnv.baseURL, endpoint, ownerToken, "key-lease-expired", payload)
+		if replayStatus != http.StatusOK || replayHeaders.Get("Idempotent-Replayed") != "true" {
+			t.Errorf("expected a replayed %d response, got %d (Idempotent-Replayed %q)", http.StatusOK, replayStatus, replayHeaders.Get("Idempotent-Replayed"))
+		}
+	})
+
+	t.Run("key_too_long", func(t *testing.T) {
+		status, _, body := submitSignalsWithIdempotencyKey(t, testEnv.baseURL, endpoint, ownerToken, strings.Repeat("k", 256), createValidSignalPayload("idempotent-signal-3"))
+		if status != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, status, body)
+		}
+	})
+}
@@ -0,0 +1,251 @@
+//go:build integration
+
+package integration
+
+// tests
+// - access tokens are signed with the current signing key when an asymmetric algorithm is configured (kid header set)
+// - the public keys are published at /.well-known/jwks.json and can be used to verify the tokens offline
+// - the next key is published before it is used and tokens signed with the previous key remain valid after rotation
+// - HS256 tokens signed with the secret key are accepted after the switch until the access token lifetime has passed, then rejected
+// - tokens signed with unknown keys are rejected
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/information-sharing-networks/signalsd/app/internal/signingkeys"
+)
+
+// getJWKS fetches the published signing keys
+func getJWKS(t *testing.T, baseURL string) signingkeys.JWKSet {
+	t.Helper()
+	resp, err := http.Get(baseURL + "/.well-known/jwks.json")
+	if err != nil {
+		t.Fatalf("Failed to get JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp.Header.Get("Cache-Control") == "" {
+		t.Error("expected a Cache-Control header")
+	}
+
+	var jwks signingkeys.JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		t.Fatalf("Failed to decode JWKS: %v", err)
+	}
+	return jwks
+}
+
+// verifyWithJWKS verifies the token using only the published keys (as an API gateway would)
+func verifyWithJWKS(t *testing.T, jwks signingkeys.JWKSet, tokenString string) error {
+	t.Helper()
+	_, err := jwt.ParseWithClaims(tokenString, &auth.Claims{}, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		for _, jwk := range jwks.Keys {
+			if jwk.Kid != kid {
+				continue
+			}
+			x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+			if err != nil {
+				return nil, err
+			}
+			y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if err != nil {
+				return nil, err
+			}
+			return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append([]byte{4}, append(x, y...)...))
+		}
+		return nil, fmt.Errorf("kid %s not in the key set", kid)
+	}, jwt.WithValidMethods([]string{"ES256"}), jwt.WithIssuer(signalsd.TokenIssuerName))
+	return err
+}
+
+// getIsnWithToken calls an endpoint that requires a valid access token
+func getIsnWithToken(t *testing.T, baseURL, isnSlug, token string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/isn/%s", baseURL, isnSlug), nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to get ISN: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestJWKS(t *testing.T) {
+	ctx := context.Background()
+
+	t.Setenv("ACCESS_TOKEN_SIGNING_ALG", "ES256")
+	testEnv := startInProcessServer(t, "")
+
+	isnAdminAccount := createTestAccount(t, ctx, testEnv.queries, "isnadmin", "user", "isnadmin@jwks-test.com")
+	isn := createTestISN(t, ctx, testEnv.queries, "jwks-isn", "JWKS ISN", isnAdminAccount.ID, "private")
+
+	signingKeys := testEnv.authService.SigningKeys()
+	firstKey := signingKeys.SigningKey()
+	if firstKey == nil {
+		t.Fatal("expected a signing key to be created at start up")
+	}
+
+	token := testEnv.createAuthToken(t, isnAdminAccount.ID)
+
+	t.Run("token_signed_with_current_key", func(t *testing.T) {
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &auth.Claims{})
+		if err != nil {
+			t.Fatalf("Failed to parse token: %v", err)
+		}
+		if parsed.Method.Alg() != "ES256" || parsed.Header["kid"] != firstKey.ID.String() {
+			t.Errorf("expected an ES256 token with kid %s, got %s %v", firstKey.ID, parsed.Method.Alg(), parsed.Header["kid"])
+		}
+
+		if status := getIsnWithToken(t, testEnv.baseURL, isn.Slug, token); status != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, status)
+		}
+	})
+
+	t.Run("token_verified_with_published_keys", func(t *testing.T) {
+		jwks := getJWKS(t, testEnv.baseURL)
+		if len(jwks.Keys) != 1 {
+			t.Fatalf("expected 1 key, got %+v", jwks.Keys)
+		}
+		if key := jwks.Keys[0]; key.Kty != "EC" || key.Crv != "P-256" || key.Use != "sig" || key.Alg != "ES256" {
+			t.Errorf("unexpected key %+v", key)
+		}
+		if err := verifyWithJWKS(t, jwks, token); err != nil {
+			t.Errorf("could not verify the token with the published keys: %v", err)
+		}
+	})
+
+	// hs256Token returns a token signed with the secret key (as issued before the service switched to ES256)
+	hs256Token := func(t *testing.T) string {
+		t.Helper()
+		claims := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    signalsd.TokenIssuerName,
+				Subject:   isnAdminAccount.ID.String(),
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+			AccountID:   isnAdminAccount.ID,
+			AccountType: "user",
+			Role:        "isnadmin",
+			IsnPerms: map[string]auth.IsnPerm{
+				isn.Slug: {CanRead: true, CanWrite: true, CanAdminister: true, Visibility: "private", InUse: true},
+			},
+		}
+		tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecretKey))
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return tok
+	}
+
+	t.Run("hs256_tokens_accepted_after_switch", func(t *testing.T) {
+		if status := getIsnWithToken(t, testEnv.baseURL, isn.Slug, hs256Token(t)); status != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, status)
+		}
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		// move the current key to the end of the rotation interval so the next key is created
+		if _, err := testEnv.pool.Exec(ctx, "UPDATE signing_keys SET activates_at = activates_at - $1::interval",
+			fmt.Sprintf("%d seconds", int(testEnv.cfg.SigningKeyRotationInterval.Seconds()))); err != nil {
+			t.Fatalf("Failed to update signing key: %v", err)
+		}
+		if err := signingKeys.Rotate(ctx); err != nil {
+			t.Fatalf("Rotate() error = %v", err)
+		}
+
+		// the next key is published before it is used
+		jwks := getJWKS(t, testEnv.baseURL)
+		if len(jwks.Keys) != 2 {
+			t.Fatalf("expected 2 keys after rotation, got %+v", jwks.Keys)
+		}
+		if current := signingKeys.SigningKey(); current == nil || current.ID != firstKey.ID {
+			t.Errorf("expected the first key to be used until the next key activates")
+		}
+
+		// the next key activates when the first key retires
+		if _, err := testEnv.pool.Exec(ctx, "UPDATE signing_keys SET activates_at = now() - interval '1 minute' WHERE id <> $1", firstKey.ID); err != nil {
+			t.Fatalf("Failed to update signing key: %v", err)
+		}
+		if _, err := testEnv.pool.Exec(ctx, "UPDATE signing_keys SET retires_at = now() - interval '1 minute' WHERE id = $1", firstKey.ID); err != nil {
+			t.Fatalf("Failed to update signing key: %v", err)
+		}
+		if err := signingKeys.Load(ctx); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		newToken := testEnv.createAuthToken(t, isnAdminAccount.ID)
+		parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &auth.Claims{})
+		if err != nil {
+			t.Fatalf("Failed to parse token: %v", err)
+		}
+		if parsed.Header["kid"] == firstKey.ID.String() {
+			t.Error("expected new tokens to be signed with the next key")
+		}
+
+		// tokens signed with the retired key are still valid until they expire
+		for _, tok := range []string{token, newToken} {
+			if status := getIsnWithToken(t, testEnv.baseURL, isn.Slug, tok); status != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, status)
+			}
+			if err := verifyWithJWKS(t, getJWKS(t, testEnv.baseURL), tok); err != nil {
+				t.Errorf("could not verify the token with the published keys: %v", err)
+			}
+		}
+	})
+
+	t.Run("hs256_tokens_rejected_after_deadline", func(t *testing.T) {
+		// the rotation test moved the first key's activation back by the rotation interval, so the switch from HS256
+		// was made more than the access token lifetime ago and the tokens issued before it have expired
+		if status := getIsnWithToken(t, testEnv.baseURL, isn.Slug, hs256Token(t)); status != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, status)
+		}
+	})
+
+	t.Run("unknown_key_rejected", func(t *testing.T) {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		forged := jwt.NewWithClaims(jwt.SigningMethodES256, auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    signalsd.TokenIssuerName,
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+			AccountID: isnAdminAccount.ID,
+			Role:      "siteadmin",
+		})
+		for _, kid := range []string{firstKey.ID.String(), uuid.New().String()} {
+			forged.Header["kid"] = kid
+			forgedToken, err := forged.SignedString(privateKey)
+			if err != nil {
+				t.Fatalf("Failed to sign token: %v", err)
+			}
+			if status := getIsnWithToken(t, testEnv.baseURL, isn.Slug, forgedToken); status != http.StatusUnauthorized {
+				t.Errorf("kid %s: expected status %d, got %d", kid, http.StatusUnauthorized, status)
+			}
+		}
+	})
+}
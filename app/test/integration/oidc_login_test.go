@@ -0,0 +1,543 @@
+//go:build integration
+
+package integration
+
+// tests
+// - the configured identity providers are listed
+// - web users can log in with an OpenID Connect identity provider (authorization code flow with PKCE, using a mock IdP)
+// - users are provisioned on first login (auto_provision), linked to existing users by verified email and identified by the provider subject on later logins
+// - provisioned users are members
+// - logins are rejected for unverified emails, email domains that are not allowed, unknown users (when auto_provision is off), disabled accounts and invalid id_tokens
+// - login requests can only be used once and the state param must match
+// - logins started with a return_to path redirect with a one-time code that can be exchanged for tokens (or an error param)
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+	"github.com/information-sharing-networks/signalsd/app/internal/signingkeys"
+)
+
+// mockIdPUser is the user that signs in at the mock IdP
+type mockIdPUser struct {
+	subject       string
+	email         string
+	emailVerified bool
+}
+
+// mockIdP is a minimal OpenID Connect provider.
+// The authorize endpoint signs in the current user without prompting and redirects straight back to the site.
+type mockIdP struct {
+	server       *httptest.Server
+	key          *rsa.PrivateKey
+	clientID     string
+	clientSecret string
+
+	mu   sync.Mutex
+	user mockIdPUser
+
+	// nonce overrides the nonce from the login request (used to test invalid id_tokens)
+	nonce string
+
+	// codes are the issued authorization codes
+	codes map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	user          mockIdPUser
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	idp := &mockIdP{
+		key:          key,
+		clientID:     "signalsd-test",
+		clientSecret: "mock-idp-secret",
+		codes:        make(map[string]mockAuthorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                           idp.server.URL,
+			"authorization_endpoint":           idp.server.URL + "/authorize",
+			"token_endpoint":                   idp.server.URL + "/token",
+			"jwks_uri":                         idp.server.URL + "/jwks",
+			"code_challenge_methods_supported": []string{"S256"},
+		})
+	})
+
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(signingkeys.JWKSet{Keys: []signingkeys.JWK{{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: "mock-key",
+			N:   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	})
+
+	mux.HandleFunc("GET /authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("client_id") != idp.clientID || query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" ||
+			!strings.Contains(query.Get("scope"), "openid") {
+			http.Error(w, "invalid authorization request", http.StatusBadRequest)
+			return
+		}
+
+		idp.mu.Lock()
+		code := uuid.NewString()
+		idp.codes[code] = mockAuthorization{
+			user:          idp.user,
+			nonce:         query.Get("nonce"),
+			codeChallenge: query.Get("code_challenge"),
+			redirectURI:   query.Get("redirect_uri"),
+		}
+		idp.mu.Unlock()
+
+		redirectURI, _ := url.Parse(query.Get("redirect_uri"))
+		params := redirectURI.Query()
+		params.Set("code", code)
+		params.Set("state", query.Get("state"))
+		redirectURI.RawQuery = params.Encode()
+
+		http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+	})
+
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != idp.clientID || clientSecret != idp.clientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+
+		idp.mu.Lock()
+		authorization, ok := idp.codes[r.PostFormValue("code")]
+		delete(idp.codes, r.PostFormValue("code"))
+		nonce := idp.nonce
+		idp.mu.Unlock()
+
+		// PKCE: the code verifier must match the code challenge sent in the authorization request
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if !ok || r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != authorization.redirectURI ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != authorization.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		if nonce == "" {
+			nonce = authorization.nonce
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            idp.server.URL,
+			"sub":            authorization.user.subject,
+			"aud":            idp.clientID,
+			"exp":            time.Now().Add(5 * time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          nonce,
+			"email":          authorization.user.email,
+			"email_verified": authorization.user.emailVerified,
+		})
+		token.Header["kid"] = "mock-key"
+		idToken, err := token.SignedString(idp.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "mock-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   300,
+			"id_token":     idToken,
+		})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+// signInAs sets the user that signs in at the IdP
+func (idp *mockIdP) signInAs(user mockIdPUser) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.user = user
+}
+
+func (idp *mockIdP) setNonce(nonce string) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.nonce = nonce
+}
+
+// providersConfig returns the OIDC_PROVIDERS setting for the mock IdP:
+// mock provisions users from any domain, mock-restricted only links existing users with example.com email addresses
+func (idp *mockIdP) providersConfig(t *testing.T) string {
+	t.Helper()
+
+	providers := []map[string]any{
+		{
+			"id":             "mock",
+			"name":           "Mock IdP",
+			"issuer":         idp.server.URL,
+			"client_id":      idp.clientID,
+			"client_secret":  idp.clientSecret,
+			"auto_provision": true,
+		},
+		{
+			"id":                    "mock-restricted",
+			"name":                  "Mock IdP (restricted)",
+			"issuer":                idp.server.URL,
+			"client_id":             idp.clientID,
+			"client_secret":         idp.clientSecret,
+			"allowed_email_domains": []string{"example.com"},
+		},
+	}
+	data, err := json.Marshal(providers)
+	if err != nil {
+		t.Fatalf("Failed to marshal providers: %v", err)
+	}
+	return string(data)
+}
+
+// newBrowser returns a client that stores cookies and follows redirects (except redirects to the return_to path)
+func newBrowser(t *testing.T, returnTo string) *http.Client {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("Failed to create cookie jar: %v", err)
+	}
+	return &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if returnTo != "" && req.URL.Path == returnTo {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
+
+// oidcLogin starts a login with the provider and follows the redirects through the IdP and back to the callback
+func oidcLogin(t *testing.T, browser *http.Client, baseURL, providerID, returnTo string) *http.Response {
+	t.Helper()
+
+	loginURL := fmt.Sprintf("%s/api/auth/oidc/%s/login", baseURL, providerID)
+	if returnTo != "" {
+		loginURL += "?return_to=" + url.QueryEscape(returnTo)
+	}
+
+	resp, err := browser.Get(loginURL)
+	if err != nil {
+		t.Fatalf("Failed to make login request: %v", err)
+	}
+	return resp
+}
+
+// expectOIDCLoginSuccess checks the login response and returns the access token details
+func expectOIDCLoginSuccess(t *testing.T, resp *http.Response) auth.AccessTokenResponse {
+	t.Helper()
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected status %d, got %d %s", http.StatusOK, resp.StatusCode, body)
+	}
+
+	var accessTokenResponse auth.AccessTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&accessTokenResponse); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if accessTokenResponse.AccessToken == "" {
+		t.Fatal("expected an access token in the response")
+	}
+
+	hasRefreshToken := false
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "refresh_token" && cookie.Value != "" {
+			hasRefreshToken = true
+		}
+	}
+	if !hasRefreshToken {
+		t.Error("expected the refresh token cookie to be set")
+	}
+
+	return accessTokenResponse
+}
+
+func expectOIDCLoginFailure(t *testing.T, resp *http.Response, wantStatus int) {
+	t.Helper()
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected status %d, got %d %s", wantStatus, resp.StatusCode, body)
+	}
+}
+
+func makeOIDCTokenRequest(t *testing.T, baseURL, code string) *http.Response {
+	t.Helper()
+
+	data, err := json.Marshal(handlers.OIDCTokenRequest{Code: code})
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+
+	resp, err := http.Post(baseURL+"/api/auth/oidc/token", "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	return resp
+}
+
+func TestOIDCLogin(t *testing.T) {
+	ctx := context.Background()
+
+	idp := newMockIdP(t)
+	t.Setenv("OIDC_PROVIDERS", idp.providersConfig(t))
+
+	testEnv := startInProcessServer(t, "")
+	baseURL := testEnv.baseURL
+
+	t.Run("identity providers are listed", func(t *testing.T) {
+		resp, err := http.Get(baseURL + "/api/auth/oidc/providers")
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var providers []handlers.IdentityProvider
+		if err := json.NewDecoder(resp.Body).Decode(&providers); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(providers) != 2 || providers[0].ID != "mock" || providers[0].Name != "Mock IdP" ||
+			providers[0].LoginURL != testEnv.cfg.PublicBaseURL+"/api/auth/oidc/mock/login" {
+			t.Errorf("unexpected providers %+v", providers)
+		}
+	})
+
+	t.Run("new users are provisioned as members", func(t *testing.T) {
+		idp.signInAs(mockIdPUser{subject: "new-user", email: "New.User@partner.org", emailVerified: true})
+
+		res := expectOIDCLoginSuccess(t, oidcLogin(t, newBrowser(t, ""), baseURL, "mock", ""))
+
+		if res.Role != "member" || res.AccountType != "user" {
+			t.Errorf("expected a member user account, got %s %s", res.Role, res.AccountType)
+		}
+
+		user, err := testEnv.queries.GetUserByEmail(ctx, "new.user@partner.org")
+		if err != nil {
+			t.Fatalf("expected the user to be created: %v", err)
+		}
+		if user.AccountID != res.AccountID || user.Email != "new.user@partner.org" {
+			t.Errorf("unexpected user %+v", user)
+		}
+
+		// the provider subject identifies the user on later logins, even if their email address changes
+		idp.signInAs(mockIdPUser{subject: "new-user", email: "renamed@partner.org", emailVerified: true})
+
+		again := expectOIDCLoginSuccess(t, oidcLogin(t, newBrowser(t, ""), baseURL, "mock", ""))
+		if again.AccountID != res.AccountID {
+			t.Errorf("expected the same account on the second login, got %s and %s", res.AccountID, again.AccountID)
+		}
+
+		identity, err := testEnv.queries.GetUserIdentity(ctx, database.GetUserIdentityParams{ProviderID: "mock", Subject: "new-user"})
+		if err != nil {
+			t.Fatalf("expected the identity to be linked: %v", err)
+		}
+		if identity.Email != "renamed@partner.org" {
+			t.Errorf("expected the identity email to be updated, got %s", identity.Email)
+		}
+	})
+
+	t.Run("existing users are linked by verified email", func(t *testing.T) {
+		existing := createTestAccount(t, ctx, testEnv.queries, "isnadmin", "user", "existing@example.com")
+
+		idp.signInAs(mockIdPUser{subject: "existing-user", email: "Existing@Example.com", emailVerified: true})
+
+		res := expectOIDCLoginSuccess(t, oidcLogin(t, newBrowser(t, ""), baseURL, "mock-restricted", ""))
+		if res.AccountID != existing.ID || res.Role != "isnadmin" {
+			t.Errorf("expected to log in as the existing user %s, got %s (%s)", existing.ID, res.AccountID, res.Role)
+		}
+	})
+
+	t.Run("unverified email addresses are rejected", func(t *testing.T) {
+		createTestAccount(t, ctx, testEnv.queries, "member", "user", "unverified@example.com")
+
+		idp.signInAs(mockIdPUser{subject: "unverified-user", email: "unverified@example.com", emailVerified: false})
+
+		expectOIDCLoginFailure(t, oidcLogin(t, newBrowser(t, ""), baseURL, "mock", ""), http.StatusUnauthorized)
+	})
+
+	t.Run("email domains that are not allowed are rejected", func(t *testing.T) {
+		createTestAccount(t, ctx, testEnv.queries, "member", "user", "someone@other.org")
+
+		idp.signInAs(mockIdPUser{subject: "other-domain-user", email: "someone@other.org", emailVerified: true})
+
+		expectOIDCLoginFailure(t, oidcLogin(t, newBrowser(t, ""), baseURL, "mock-restricted", ""), http.StatusUnauthorized)
+	})
+
+	t.Run("unknown users are rejected when auto_provision is off", func(t *testing.T) {
+		idp.signInAs(mockIdPUser{subject: "unknown-user", email: "unknown@example.com", emailVerified: true})
+
+		expectOIDCLoginFailure(t, oidcLogin(t, newBrowser(t, ""), baseURL, "mock-restricted", ""), http.StatusUnauthorized)
+
+		if _, err := testEnv.queries.GetUserByEmail(ctx, "unknown@example.com"); err == nil {
+			t.Error("expected the user not to be created")
+		}
+	})
+
+	t.Run("disabled accounts are rejected", func(t *testing.T) {
+		disabled := createTestAccount(t, ctx, testEnv.queries, "member", "user", "disabled@example.com")
+		if _, err := testEnv.queries.DisableAccount(ctx, disabled.ID); err != nil {
+			t.Fatalf("Failed to disable account: %v", err)
+		}
+
+		idp.signInAs(mockIdPUser{subject: "disabled-user", email: "disabled@example.com", emailVerified: true})
+
+		expectOIDCLoginFailure(t, oidcLogin(t, newBrowser(t, ""), baseURL, "mock", ""), http.StatusUnauthorized)
+	})
+
+	t.Run("id_tokens with the wrong nonce are rejected", func(t *testing.T) {
+		idp.signInAs(mockIdPUser{subject: "nonce-user", email: "nonce@partner.org", emailVerified: true})
+		idp.setNonce("not-the-login-request-nonce")
+		defer idp.setNonce("")
+
+		expectOIDCLoginFailure(t, oidcLogin(t, newBrowser(t, ""), baseURL, "mock", ""), http.StatusUnauthorized)
+	})
+
+	t.Run("login requests can only be used once", func(t *testing.T) {
+		idp.signInAs(mockIdPUser{subject: "replay-user", email: "replay@partner.org", emailVerified: true})
+
+		// stop at the callback so it can be replayed
+		browser := newBrowser(t, "")
+		browser.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if strings.HasSuffix(req.URL.Path, "/callback") {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		}
+		resp := oidcLogin(t, browser, baseURL, "mock", "")
+		resp.Body.Close()
+		callbackURL := resp.Header.Get("Location")
+		if resp.StatusCode != http.StatusFound || callbackURL == "" {
+			t.Fatalf("expected a redirect to the callback, got %d", resp.StatusCode)
+		}
+
+		// a different browser (no login request cookie) can't complete the login
+		attacker := newBrowser(t, "")
+		resp, err := attacker.Get(callbackURL)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		expectOIDCLoginFailure(t, resp, http.StatusUnauthorized)
+
+		// the state must match the login request
+		u, _ := url.Parse(callbackURL)
+		params := u.Query()
+		params.Set("state", "wrong-state")
+		u.RawQuery = params.Encode()
+		resp, err = browser.Get(u.String())
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		expectOIDCLoginFailure(t, resp, http.StatusUnauthorized)
+
+		// the failed attempt used up the login request
+		resp, err = browser.Get(callbackURL)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		expectOIDCLoginFailure(t, resp, http.StatusUnauthorized)
+	})
+
+	t.Run("unknown providers are rejected", func(t *testing.T) {
+		expectOIDCLoginFailure(t, oidcLogin(t, newBrowser(t, ""), baseURL, "not-configured", ""), http.StatusNotFound)
+	})
+
+	t.Run("return_to must be a path on the site", func(t *testing.T) {
+		for _, returnTo := range []string{"https://attacker.example.com/", "//attacker.example.com/", "login/sso", "/\\attacker.example.com"} {
+			expectOIDCLoginFailure(t, oidcLogin(t, newBrowser(t, ""), baseURL, "mock", returnTo), http.StatusBadRequest)
+		}
+	})
+
+	t.Run("logins with return_to redirect with a one-time code", func(t *testing.T) {
+		idp.signInAs(mockIdPUser{subject: "ui-user", email: "ui-user@partner.org", emailVerified: true})
+
+		resp := oidcLogin(t, newBrowser(t, "/login/sso"), baseURL, "mock", "/login/sso")
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("expected a redirect to return_to, got %d", resp.StatusCode)
+		}
+		location, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil || location.Path != "/login/sso" {
+			t.Fatalf("expected a redirect to /login/sso, got %s", resp.Header.Get("Location"))
+		}
+		code := location.Query().Get("code")
+		if code == "" {
+			t.Fatalf("expected a code param, got %s", location)
+		}
+
+		res := expectOIDCLoginSuccess(t, makeOIDCTokenRequest(t, baseURL, code))
+		if res.Email != "ui-user@partner.org" {
+			t.Errorf("expected the provisioned user, got %s", res.Email)
+		}
+
+		// the code can only be used once
+		expectOIDCLoginFailure(t, makeOIDCTokenRequest(t, baseURL, code), http.StatusUnauthorized)
+	})
+
+	t.Run("failed logins with return_to redirect with an error", func(t *testing.T) {
+		idp.signInAs(mockIdPUser{subject: "ui-unverified-user", email: "ui-unverified@partner.org", emailVerified: false})
+
+		resp := oidcLogin(t, newBrowser(t, "/login/sso"), baseURL, "mock", "/login/sso")
+		resp.Body.Close()
+
+		location, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil || resp.StatusCode != http.StatusSeeOther || location.Path != "/login/sso" {
+			t.Fatalf("expected a redirect to /login/sso, got %d %s", resp.StatusCode, resp.Header.Get("Location"))
+		}
+		if location.Query().Get("code") != "" || !strings.Contains(location.Query().Get("error"), "verified email") {
+			t.Errorf("expected an error param, got %s", location)
+		}
+	})
+}
@@ -0,0 +1,184 @@
+//go:build integration
+
+package integration
+
+// tests
+// - service accounts can request access tokens limited to specific ISNs and permissions with the scope parameter
+// - scoped tokens can only be used with the ISNs and permissions in the scope
+// - scopes that are malformed or include access not granted to the account are rejected (invalid_scope)
+// - scopes are rejected for web users
+// - the scope is reported by token introspection
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+)
+
+// requestScopedToken requests a client_credentials token with the supplied scope
+func requestScopedToken(t *testing.T, baseURL, clientID, clientSecret, scope string) (int, auth.AccessTokenResponse, map[string]any) {
+	t.Helper()
+
+	resp := makeOAuthTokenRequest(t, baseURL, "client_credentials", map[string]string{
+		"client_id":     clientID,
+		"client_secret": clientSecret,
+		"scope":         scope,
+	}, "")
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse map[string]any
+		if err := json.Unmarshal(body, &errorResponse); err != nil {
+			t.Fatalf("Failed to decode error response: %v", err)
+		}
+		return resp.StatusCode, auth.AccessTokenResponse{}, errorResponse
+	}
+
+	var tokenResponse auth.AccessTokenResponse
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		t.Fatalf("Failed to decode token response: %v", err)
+	}
+	return resp.StatusCode, tokenResponse, nil
+}
+
+func TestScopedAccessTokens(t *testing.T) {
+	ctx := context.Background()
+
+	testEnv := startInProcessServer(t, "")
+
+	owner := createTestAccount(t, ctx, testEnv.queries, "isnadmin", "user", "owner@scope.test")
+	serviceAccount := createTestAccount(t, ctx, testEnv.queries, "member", "service_account", "worker@scope.test")
+	clientID, clientSecret := createTestClientSecret(t, ctx, testEnv, serviceAccount)
+
+	// the service account can write to port-isn, read customs-isn and read and write audit-isn
+	endpoints := make(map[string]testSignalEndpoint)
+	for slug, permission := range map[string]string{
+		"port-isn":    "write",
+		"customs-isn": "read",
+		"audit-isn":   "read-write",
+	} {
+		isn := createTestISN(t, ctx, testEnv.queries, slug, slug, owner.ID, "private")
+		signalType := createTestSignalType(t, ctx, testEnv.queries, isn.ID, slug+" signal", "")
+		grantPermission(t, ctx, testEnv.queries, isn.ID, serviceAccount.ID, permission)
+		endpoints[slug] = testSignalEndpoint{
+			isnSlug:          isn.Slug,
+			signalTypeSlug:   signalType.Slug,
+			signalTypeSemVer: signalType.SemVer,
+		}
+	}
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("Failed to load schema cache: %v", err)
+	}
+
+	t.Run("unscoped_token", func(t *testing.T) {
+		status, tokenResponse, _ := requestScopedToken(t, testEnv.baseURL, clientID, clientSecret, "")
+		if status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+		}
+		if tokenResponse.Scope != "" || len(tokenResponse.IsnPerms) != 3 {
+			t.Errorf("expected all the account's ISNs and no scope, got %q %v", tokenResponse.Scope, tokenResponse.IsnPerms)
+		}
+	})
+
+	t.Run("scoped_token", func(t *testing.T) {
+		status, tokenResponse, errorResponse := requestScopedToken(t, testEnv.baseURL, clientID, clientSecret, "isn:port-isn:write  isn:customs-isn:read")
+		if status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d %v", http.StatusOK, status, errorResponse)
+		}
+
+		if tokenResponse.Scope != "isn:customs-isn:read isn:port-isn:write" {
+			t.Errorf("unexpected scope %q", tokenResponse.Scope)
+		}
+		if len(tokenResponse.IsnPerms) != 2 {
+			t.Fatalf("expected 2 ISNs in the token, got %v", tokenResponse.IsnPerms)
+		}
+		if perm := tokenResponse.IsnPerms["port-isn"]; !perm.CanWrite || perm.CanRead {
+			t.Errorf("expected write only access to port-isn, got %+v", perm)
+		}
+		if perm := tokenResponse.IsnPerms["customs-isn"]; !perm.CanRead || perm.CanWrite {
+			t.Errorf("expected read only access to customs-isn, got %+v", perm)
+		}
+
+		token := tokenResponse.AccessToken
+
+		// access in scope
+		resp := submitCreateSignalRequest(t, testEnv.baseURL, createValidSignalPayload("scoped-001"), token, endpoints["port-isn"])
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("submit to port-isn: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		resp = searchPrivateSignals(t, testEnv.baseURL, endpoints["customs-isn"], token, false, false, false)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("search customs-isn: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		// access granted to the account but not in the scope
+		resp = searchPrivateSignals(t, testEnv.baseURL, endpoints["audit-isn"], token, false, false, false)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("search audit-isn: expected status %d, got %d", http.StatusForbidden, resp.StatusCode)
+		}
+
+		resp = submitCreateSignalRequest(t, testEnv.baseURL, createValidSignalPayload("scoped-002"), token, endpoints["audit-isn"])
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("submit to audit-isn: expected status %d, got %d", http.StatusForbidden, resp.StatusCode)
+		}
+
+		// the scope is reported by token introspection
+		introspectionStatus, body := introspectToken(t, testEnv.baseURL, clientID, clientSecret, token, "")
+		if introspectionStatus != http.StatusOK || body["scope"] != tokenResponse.Scope {
+			t.Errorf("expected scope %q in introspection response, got %d %v", tokenResponse.Scope, introspectionStatus, body)
+		}
+	})
+
+	t.Run("invalid_scope", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			scope string
+		}{
+			{"malformed", "port-isn"},
+			{"unknown_permission", "isn:port-isn:admin"},
+			{"isn_not_granted", "isn:other-isn:read"},
+			{"permission_not_granted", "isn:port-isn:read"},
+			{"one_value_not_granted", "isn:audit-isn:read isn:customs-isn:write"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, _, errorResponse := requestScopedToken(t, testEnv.baseURL, clientID, clientSecret, tt.scope)
+				if status != http.StatusBadRequest {
+					t.Fatalf("expected status %d, got %d", http.StatusBadRequest, status)
+				}
+				if errorResponse["error"] != "invalid_scope" {
+					t.Errorf("expected invalid_scope error, got %v", errorResponse)
+				}
+			})
+		}
+	})
+
+	t.Run("web_users_cannot_request_scopes", func(t *testing.T) {
+		createTestUserWithPassword(t, ctx, testEnv.queries, testEnv.authService, "member", "user@scope.test", "password123")
+		_, refreshToken := loginForTokens(t, testEnv.baseURL, "user@scope.test", "password123")
+
+		resp := makeOAuthTokenRequest(t, testEnv.baseURL, "refresh_token", map[string]string{"scope": "isn:port-isn:read"}, refreshToken)
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "invalid_scope") {
+			t.Errorf("expected invalid_scope, got %d %s", resp.StatusCode, body)
+		}
+	})
+}
@@ -0,0 +1,275 @@
+//go:build integration
+
+package integration
+
+// tests
+// - routing rules can route signals on the sender's account type and service account organization (@sender fields)
+// - routing rules can route signals on request headers (@header fields)
+// - invalid sender fields and credential headers are rejected when the routing config is saved
+// - routing config dry runs use the supplied sender (including the sender's write permissions on the target ISNs)
+// - the request headers used by the routing rules are kept with unroutable signals (other headers are not stored) and are used when the signal is re-routed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/router"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+// submitRouteSignalsWithHeaders submits signals to the signal router with additional request headers
+func submitRouteSignalsWithHeaders(t *testing.T, baseURL string, payload map[string]any, token string, headers map[string]string, signalTypeSlug, semVer string) *http.Response {
+	t.Helper()
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/api/router/signal-types/%s/v%s/signals", baseURL, signalTypeSlug, semVer)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		t.Fatalf("Failed to submit routed signals: %v", err)
+	}
+	return resp
+}
+
+func TestSenderRouting(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	// the signal type is available on the customs and general ISNs and both writers can write to both ISNs.
+	// the service account created by createTestAccount belongs to "test client org"
+	siteAdminAccount := createTestAccount(t, ctx, testEnv.queries, "siteadmin", "user", "siteadmin@sender-routing-test.com")
+	userAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "user@sender-routing-test.com")
+	serviceAccount := createTestAccount(t, ctx, testEnv.queries, "member", "service_account", "service@sender-routing-test.com")
+
+	siteAdminToken := testEnv.createAuthToken(t, siteAdminAccount.ID)
+
+	customsIsn := createTestISN(t, ctx, testEnv.queries, "sender-customs-isn", "Sender Customs ISN", siteAdminAccount.ID, "private")
+	generalIsn := createTestISN(t, ctx, testEnv.queries, "sender-general-isn", "Sender General ISN", siteAdminAccount.ID, "private")
+
+	signalType := createTestSignalType(t, ctx, testEnv.queries, customsIsn.ID, "sender routing signal", "")
+	if err := testEnv.queries.AddSignalTypeToIsn(ctx, database.AddSignalTypeToIsnParams{
+		IsnID:        generalIsn.ID,
+		SignalTypeID: signalType.ID,
+	}); err != nil {
+		t.Fatalf("Failed to add signal type to the general ISN: %v", err)
+	}
+
+	for _, isn := range []database.Isn{customsIsn, generalIsn} {
+		grantPermission(t, ctx, testEnv.queries, isn.ID, userAccount.ID, "write")
+		grantPermission(t, ctx, testEnv.queries, isn.ID, serviceAccount.ID, "write")
+	}
+	userToken := testEnv.createAuthToken(t, userAccount.ID)
+	serviceAccountToken := testEnv.createAuthToken(t, serviceAccount.ID)
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("schemaCache.Load: %v", err)
+	}
+
+	// service accounts from "test client org" and requests with X-Origin-System: cds go to the customs ISN, other user accounts go to the general ISN
+	senderConfig := handlers.UpdateSignalRoutingConfigRequest{
+		RoutingField: "@sender.account_type",
+		RoutingRules: []handlers.SignalRoutingRule{
+			{Condition: &router.Condition{Field: "@sender.client_organization", Operator: "equals", Pattern: "TEST CLIENT ORG", IsCaseInsensitive: true}, IsnSlug: customsIsn.Slug, Sequence: 1},
+			{Condition: &router.Condition{Field: "@header.X-Origin-System", Operator: "equals", Pattern: "cds"}, IsnSlug: customsIsn.Slug, Sequence: 2},
+			{MatchPattern: "user", Operator: "equals", IsnSlug: generalIsn.Slug, Sequence: 3},
+		},
+	}
+	setRoutingConfig(t, testEnv, siteAdminToken, signalType, senderConfig)
+	if err := testEnv.routerCache.Load(ctx); err != nil {
+		t.Fatalf("routerCache.Load: %v", err)
+	}
+
+	t.Run("routing", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			token    string
+			headers  map[string]string
+			localRef string
+			wantIsn  string
+		}{
+			{"service_account_routed_by_organization", serviceAccountToken, nil, "s-sender-1", customsIsn.Slug},
+			{"user_routed_by_account_type", userToken, nil, "s-sender-2", generalIsn.Slug},
+			{"user_routed_by_header", userToken, map[string]string{"X-Origin-System": "cds"}, "s-sender-3", customsIsn.Slug},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := submitRouteSignalsWithHeaders(t, testEnv.baseURL,
+					routerPayload("sender-batch", []map[string]any{routerSignal(tt.localRef, "any value")}),
+					tt.token, tt.headers, signalType.Slug, signalType.SemVer)
+				defer resp.Body.Close()
+
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("status: want 200, got %d", resp.StatusCode)
+				}
+				result := decodeRouterResponse(t, resp)
+				if len(result.Results) != 1 || result.Results[0].IsnSlug != tt.wantIsn || len(result.Results[0].StoredSignals) != 1 {
+					t.Errorf("expected the signal to be stored in %s, got %+v", tt.wantIsn, result.Results)
+				}
+			})
+		}
+	})
+
+	t.Run("invalid_fields", func(t *testing.T) {
+		for _, field := range []string{"@sender.role", "@header.Authorization", "@header.", "@payload.test"} {
+			t.Run(field, func(t *testing.T) {
+				resp := updateSignalRoutingConfig(t, testEnv.baseURL, siteAdminToken, signalType.Slug, signalType.SemVer, handlers.UpdateSignalRoutingConfigRequest{
+					RoutingField: field,
+					RoutingRules: []handlers.SignalRoutingRule{
+						{MatchPattern: "x", Operator: "equals", IsnSlug: customsIsn.Slug, Sequence: 1},
+					},
+				})
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusBadRequest {
+					t.Errorf("status: want 400, got %d", resp.StatusCode)
+				}
+			})
+		}
+	})
+
+	t.Run("dry_run_with_sender", func(t *testing.T) {
+		resp := dryRunSignalRoutingConfig(t, testEnv.baseURL, siteAdminToken, signalType.Slug, signalType.SemVer, handlers.SignalRoutingDryRunRequest{
+			Signals: []handlers.SignalRoutingDryRunSignal{{Content: json.RawMessage(`{"test": "any value"}`)}},
+			Sender: &handlers.SignalRoutingDryRunSender{
+				AccountType:        "service_account",
+				ClientOrganization: "Test Client Org",
+			},
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status: want 200, got %d", resp.StatusCode)
+		}
+
+		var res handlers.SignalRoutingDryRunResponse
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		result := res.Results[0]
+		if len(result.Targets) != 1 || result.Targets[0].IsnSlug != customsIsn.Slug {
+			t.Errorf("expected the signal to be routed to %s, got %+v", customsIsn.Slug, result.Targets)
+		}
+		for _, fieldValue := range result.FieldValues {
+			if fieldValue.Field == "@sender.account_type" && string(fieldValue.Value) != `"service_account"` {
+				t.Errorf("@sender.account_type: want \"service_account\", got %s", fieldValue.Value)
+			}
+		}
+	})
+
+	t.Run("dry_run_checks_sender_permissions", func(t *testing.T) {
+		// the reader can't write to the customs ISN - the site admin running the dry run can
+		readerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "reader@sender-routing-test.com")
+		grantPermission(t, ctx, testEnv.queries, customsIsn.ID, readerAccount.ID, "read")
+
+		tests := []struct {
+			name         string
+			sender       handlers.SignalRoutingDryRunSender
+			wantCanWrite bool
+		}{
+			{"sender_with_write_permission", handlers.SignalRoutingDryRunSender{AccountID: &serviceAccount.ID, AccountType: "service_account", ClientOrganization: "Test Client Org"}, true},
+			{"sender_without_write_permission", handlers.SignalRoutingDryRunSender{AccountID: &readerAccount.ID, AccountType: "service_account", ClientOrganization: "Test Client Org"}, false},
+			{"sender_without_account_id", handlers.SignalRoutingDryRunSender{AccountType: "service_account", ClientOrganization: "Test Client Org"}, false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := dryRunSignalRoutingConfig(t, testEnv.baseURL, siteAdminToken, signalType.Slug, signalType.SemVer, handlers.SignalRoutingDryRunRequest{
+					Signals: []handlers.SignalRoutingDryRunSignal{{Content: json.RawMessage(`{"test": "any value"}`)}},
+					Sender:  &tt.sender,
+				})
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("status: want 200, got %d", resp.StatusCode)
+				}
+
+				var res handlers.SignalRoutingDryRunResponse
+				if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				targets := res.Results[0].Targets
+				if len(targets) != 1 || targets[0].IsnSlug != customsIsn.Slug {
+					t.Fatalf("expected the signal to be routed to %s, got %+v", customsIsn.Slug, targets)
+				}
+				if targets[0].CanWrite != tt.wantCanWrite {
+					t.Errorf("can_write: want %v, got %v (reason: %q)", tt.wantCanWrite, targets[0].CanWrite, targets[0].Reason)
+				}
+			})
+		}
+	})
+
+	t.Run("unroutable_signals_keep_headers", func(t *testing.T) {
+		// only signals from the cds system are routed
+		setRoutingConfig(t, testEnv, siteAdminToken, signalType, handlers.UpdateSignalRoutingConfigRequest{
+			RoutingField: "@header.X-Origin-System",
+			RoutingRules: []handlers.SignalRoutingRule{
+				{MatchPattern: "cds", Operator: "equals", IsnSlug: customsIsn.Slug, Sequence: 1},
+			},
+		})
+		if err := testEnv.routerCache.Load(ctx); err != nil {
+			t.Fatalf("routerCache.Load: %v", err)
+		}
+
+		resp := submitRouteSignalsWithHeaders(t, testEnv.baseURL,
+			routerPayload("sender-unroutable-batch", []map[string]any{routerSignal("s-sender-unroutable", "any value")}),
+			userToken, map[string]string{"X-Origin-System": "chief", "X-Unused-Header": "not used by the routing rules"}, signalType.Slug, signalType.SemVer)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("status: want 422, got %d", resp.StatusCode)
+		}
+		result := decodeRouterResponse(t, resp)
+		if len(result.UnroutableSignals) != 1 || result.UnroutableSignals[0].UnroutableSignalID == nil {
+			t.Fatalf("expected an unroutable signal id in the response, got %+v", result.UnroutableSignals)
+		}
+		unroutableID := result.UnroutableSignals[0].UnroutableSignalID.String()
+
+		// the stored routing context only includes the request headers used by the routing rules
+		detailsResp := unroutableSignalsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodGet, "/"+unroutableID)
+		defer detailsResp.Body.Close()
+		var details handlers.UnroutableSignalDetails
+		if err := json.NewDecoder(detailsResp.Body).Decode(&details); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if details.RoutingContext == nil || details.RoutingContext.Headers.Get("X-Origin-System") != "chief" || details.RoutingContext.AccountType != "user" {
+			t.Fatalf("expected the routing context to be stored, got %+v", details.RoutingContext)
+		}
+		if details.RoutingContext.Headers.Get("Authorization") != "" {
+			t.Error("expected the authorization header not to be stored")
+		}
+		if details.RoutingContext.Headers.Get("X-Unused-Header") != "" {
+			t.Error("expected headers that are not used by the routing rules not to be stored")
+		}
+
+		// once signals from chief are routed, the stored header is used to re-route the signal
+		setRoutingConfig(t, testEnv, siteAdminToken, signalType, handlers.UpdateSignalRoutingConfigRequest{
+			RoutingField: "@header.X-Origin-System",
+			RoutingRules: []handlers.SignalRoutingRule{
+				{MatchPattern: "cds,chief", Operator: "in", IsnSlug: customsIsn.Slug, Sequence: 1},
+			},
+		})
+
+		rerouteResp := unroutableSignalsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodPost, "/"+unroutableID+"/reroute")
+		defer rerouteResp.Body.Close()
+		if rerouteResp.StatusCode != http.StatusOK {
+			t.Fatalf("reroute status: want 200, got %d", rerouteResp.StatusCode)
+		}
+		rerouted := decodeRouterResponse(t, rerouteResp)
+		if isnResult := findIsnResult(rerouted.Results, customsIsn.Slug); isnResult == nil || len(isnResult.StoredSignals) != 1 {
+			t.Errorf("expected the signal to be stored in %s, got %+v", customsIsn.Slug, rerouted.Results)
+		}
+	})
+}
@@ -0,0 +1,486 @@
+//go:build integration
+
+package integration
+
+// tests
+// - admins can register, list and revoke service account public keys
+// - service accounts can get access tokens with private_key_jwt client assertions signed with a registered key
+// - client assertions are rejected if they are replayed, have the wrong audience, live too long or are signed with an unregistered or revoked key
+// - service accounts can get access tokens with a TLS client certificate for a registered key (forwarded by the proxy in TLS_CLIENT_CERT_HEADER)
+// - certificate bound access tokens are rejected on requests made without the certificate
+// - client certificate headers received from sources other than TLS_CLIENT_CERT_TRUSTED_PROXIES are rejected
+// - client secrets issued to another service account are rejected
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+// publicKeyPEM returns the PEM encoded public key
+func publicKeyPEM(t *testing.T, publicKey crypto.PublicKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// serviceAccountKeysRequest calls the service account key endpoints
+func serviceAccountKeysRequest(t *testing.T, baseURL, token, method, path string, body any) *http.Response {
+	t.Helper()
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Failed to marshal request: %v", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, baseURL+"/api/auth/service-accounts/"+path, reqBody)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	return resp
+}
+
+// registerServiceAccountKey registers the public key for the service account and returns the key id
+func registerServiceAccountKey(t *testing.T, baseURL, adminToken, clientID string, publicKey crypto.PublicKey) string {
+	t.Helper()
+
+	resp := serviceAccountKeysRequest(t, baseURL, adminToken, http.MethodPost, clientID+"/keys", handlers.RegisterServiceAccountKeyRequest{
+		PublicKey: publicKeyPEM(t, publicKey),
+	})
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected status %d registering key, got %d %s", http.StatusCreated, resp.StatusCode, body)
+	}
+
+	var key handlers.ServiceAccountKey
+	if err := json.NewDecoder(resp.Body).Decode(&key); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return key.KeyID.String()
+}
+
+// createClientAssertion signs a private_key_jwt client assertion - the claims can be modified before signing with the modify func
+func createClientAssertion(t *testing.T, privateKey crypto.Signer, method jwt.SigningMethod, kid, clientID, audience string, modify func(*jwt.RegisteredClaims)) string {
+	t.Helper()
+
+	claims := &jwt.RegisteredClaims{
+		Issuer:    clientID,
+		Subject:   clientID,
+		Audience:  jwt.ClaimStrings{audience},
+		ID:        uuid.NewString(),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+	if modify != nil {
+		modify(claims)
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to sign client assertion: %v", err)
+	}
+	return signed
+}
+
+// requestTokenWithAssertion requests a client_credentials token using private_key_jwt
+func requestTokenWithAssertion(t *testing.T, baseURL, assertion string) (int, map[string]any) {
+	t.Helper()
+
+	resp := makeOAuthTokenRequest(t, baseURL, "client_credentials", map[string]string{
+		"client_assertion_type": auth.ClientAssertionTypeJWTBearer,
+		"client_assertion":      assertion,
+	}, "")
+	defer resp.Body.Close()
+
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.StatusCode, body
+}
+
+// createSelfSignedCertificate returns a self signed certificate for the key
+func createSelfSignedCertificate(t *testing.T, privateKey crypto.Signer) *x509.Certificate {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "signalsd test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+// clientCertHeaderValue encodes the certificate the way the TLS terminating proxy forwards it (URL encoded PEM)
+func clientCertHeaderValue(cert *x509.Certificate) string {
+	return url.PathEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})))
+}
+
+// requestTokenWithCertificate requests a client_credentials token using self_signed_tls_client_auth
+func requestTokenWithCertificate(t *testing.T, baseURL, clientID string, cert *x509.Certificate) (int, map[string]any) {
+	t.Helper()
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", clientID)
+
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set(testClientCertHeader, clientCertHeaderValue(cert))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.StatusCode, body
+}
+
+// getIsnWithCertificate calls an endpoint that requires a valid access token, presenting the client certificate (if not nil)
+func getIsnWithCertificate(t *testing.T, baseURL, isnSlug, token string, cert *x509.Certificate) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/isn/%s", baseURL, isnSlug), nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if cert != nil {
+		req.Header.Set(testClientCertHeader, clientCertHeaderValue(cert))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to get ISN: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServiceAccountKeys(t *testing.T) {
+	ctx := context.Background()
+
+	testEnv := startInProcessServer(t, "")
+
+	admin := createTestAccount(t, ctx, testEnv.queries, "isnadmin", "user", "admin@keys.test")
+	adminToken := testEnv.createAuthToken(t, admin.ID)
+
+	serviceAccount := createTestAccount(t, ctx, testEnv.queries, "member", "service_account", "worker@keys.test")
+	clientID, _ := createTestClientSecret(t, ctx, testEnv, serviceAccount)
+
+	isn := createTestISN(t, ctx, testEnv.queries, "keys-isn", "keys isn", admin.ID, "private")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, serviceAccount.ID, "read")
+
+	tokenEndpoint := testEnv.baseURL + "/oauth/token"
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	edKeyID := registerServiceAccountKey(t, testEnv.baseURL, adminToken, clientID, edKey.Public())
+	ecKeyID := registerServiceAccountKey(t, testEnv.baseURL, adminToken, clientID, ecKey.Public())
+
+	t.Run("manage_keys", func(t *testing.T) {
+		resp := serviceAccountKeysRequest(t, testEnv.baseURL, adminToken, http.MethodGet, clientID+"/keys", nil)
+		defer resp.Body.Close()
+
+		var keys []handlers.ServiceAccountKey
+		if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(keys) != 2 || keys[0].Algorithm != "EdDSA" || keys[1].Algorithm != "ES256" {
+			t.Errorf("expected an EdDSA and an ES256 key, got %+v", keys)
+		}
+
+		// duplicate key
+		resp = serviceAccountKeysRequest(t, testEnv.baseURL, adminToken, http.MethodPost, clientID+"/keys", handlers.RegisterServiceAccountKeyRequest{
+			PublicKey: publicKeyPEM(t, edKey.Public()),
+		})
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("duplicate key: expected status %d, got %d", http.StatusConflict, resp.StatusCode)
+		}
+
+		// unsupported curve
+		p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		resp = serviceAccountKeysRequest(t, testEnv.baseURL, adminToken, http.MethodPost, clientID+"/keys", handlers.RegisterServiceAccountKeyRequest{
+			PublicKey: publicKeyPEM(t, p384Key.Public()),
+		})
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("P-384 key: expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+		}
+
+		// members can't manage keys
+		memberToken := testEnv.createAuthToken(t, createTestAccount(t, ctx, testEnv.queries, "member", "user", "member@keys.test").ID)
+		resp = serviceAccountKeysRequest(t, testEnv.baseURL, memberToken, http.MethodGet, clientID+"/keys", nil)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("member: expected status %d, got %d", http.StatusForbidden, resp.StatusCode)
+		}
+	})
+
+	t.Run("private_key_jwt", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			key       crypto.Signer
+			method    jwt.SigningMethod
+			kid       string
+			audience  string
+			modify    func(*jwt.RegisteredClaims)
+			expectErr bool
+		}{
+			{"ed25519_with_kid", edKey, jwt.SigningMethodEdDSA, edKeyID, tokenEndpoint, nil, false},
+			{"ec_without_kid", ecKey, jwt.SigningMethodES256, "", tokenEndpoint, nil, false},
+			{"issuer_audience", ecKey, jwt.SigningMethodES256, ecKeyID, testEnv.baseURL, nil, false},
+			{"wrong_kid", edKey, jwt.SigningMethodEdDSA, ecKeyID, tokenEndpoint, nil, true},
+			{"wrong_audience", edKey, jwt.SigningMethodEdDSA, edKeyID, "https://other.example.com/oauth/token", nil, true},
+			{"expired", edKey, jwt.SigningMethodEdDSA, edKeyID, tokenEndpoint, func(c *jwt.RegisteredClaims) {
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+			}, true},
+			{"lifetime_too_long", edKey, jwt.SigningMethodEdDSA, edKeyID, tokenEndpoint, func(c *jwt.RegisteredClaims) {
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+			}, true},
+			{"missing_jti", edKey, jwt.SigningMethodEdDSA, edKeyID, tokenEndpoint, func(c *jwt.RegisteredClaims) {
+				c.ID = ""
+			}, true},
+			{"wrong_subject", edKey, jwt.SigningMethodEdDSA, edKeyID, tokenEndpoint, func(c *jwt.RegisteredClaims) {
+				c.Subject = "someone-else"
+			}, true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				assertion := createClientAssertion(t, tt.key, tt.method, tt.kid, clientID, tt.audience, tt.modify)
+				status, body := requestTokenWithAssertion(t, testEnv.baseURL, assertion)
+
+				if tt.expectErr {
+					if status != http.StatusUnauthorized || body["error"] != "invalid_client" {
+						t.Errorf("expected invalid_client, got %d %v", status, body)
+					}
+					return
+				}
+				if status != http.StatusOK {
+					t.Fatalf("expected status %d, got %d %v", http.StatusOK, status, body)
+				}
+				if getIsnWithToken(t, testEnv.baseURL, isn.Slug, body["access_token"].(string)) != http.StatusOK {
+					t.Error("expected access token to be accepted")
+				}
+			})
+		}
+
+		t.Run("unregistered_key", func(t *testing.T) {
+			_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatalf("Failed to generate key: %v", err)
+			}
+			assertion := createClientAssertion(t, otherKey, jwt.SigningMethodEdDSA, "", clientID, tokenEndpoint, nil)
+			if status, body := requestTokenWithAssertion(t, testEnv.baseURL, assertion); status != http.StatusUnauthorized {
+				t.Errorf("expected status %d, got %d %v", http.StatusUnauthorized, status, body)
+			}
+		})
+
+		t.Run("replayed_assertion", func(t *testing.T) {
+			assertion := createClientAssertion(t, edKey, jwt.SigningMethodEdDSA, edKeyID, clientID, tokenEndpoint, nil)
+			if status, body := requestTokenWithAssertion(t, testEnv.baseURL, assertion); status != http.StatusOK {
+				t.Fatalf("expected status %d, got %d %v", http.StatusOK, status, body)
+			}
+			if status, body := requestTokenWithAssertion(t, testEnv.baseURL, assertion); status != http.StatusUnauthorized {
+				t.Errorf("replay: expected status %d, got %d %v", http.StatusUnauthorized, status, body)
+			}
+		})
+
+		t.Run("client_id_mismatch", func(t *testing.T) {
+			resp := makeOAuthTokenRequest(t, testEnv.baseURL, "client_credentials", map[string]string{
+				"client_id":             "sa_someone-else",
+				"client_assertion_type": auth.ClientAssertionTypeJWTBearer,
+				"client_assertion":      createClientAssertion(t, edKey, jwt.SigningMethodEdDSA, edKeyID, clientID, tokenEndpoint, nil),
+			}, "")
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+			}
+		})
+
+		t.Run("wrong_assertion_type", func(t *testing.T) {
+			resp := makeOAuthTokenRequest(t, testEnv.baseURL, "client_credentials", map[string]string{
+				"client_assertion_type": "urn:ietf:params:oauth:client-assertion-type:saml2-bearer",
+				"client_assertion":      createClientAssertion(t, edKey, jwt.SigningMethodEdDSA, edKeyID, clientID, tokenEndpoint, nil),
+			}, "")
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+			}
+		})
+	})
+
+	t.Run("mutual_tls", func(t *testing.T) {
+		cert := createSelfSignedCertificate(t, ecKey)
+
+		status, body := requestTokenWithCertificate(t, testEnv.baseURL, clientID, cert)
+		if status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d %v", http.StatusOK, status, body)
+		}
+		token := body["access_token"].(string)
+
+		// the token is bound to the certificate
+		claims := &auth.Claims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+			t.Fatalf("Failed to parse access token: %v", err)
+		}
+		if claims.Confirmation == nil || claims.Confirmation.X5tS256 != auth.CertificateThumbprint(cert) {
+			t.Errorf("expected cnf claim with the certificate thumbprint, got %+v", claims.Confirmation)
+		}
+
+		if status := getIsnWithCertificate(t, testEnv.baseURL, isn.Slug, token, cert); status != http.StatusOK {
+			t.Errorf("with certificate: expected status %d, got %d", http.StatusOK, status)
+		}
+		if status := getIsnWithCertificate(t, testEnv.baseURL, isn.Slug, token, nil); status != http.StatusUnauthorized {
+			t.Errorf("without certificate: expected status %d, got %d", http.StatusUnauthorized, status)
+		}
+		if status := getIsnWithCertificate(t, testEnv.baseURL, isn.Slug, token, createSelfSignedCertificate(t, edKey)); status != http.StatusUnauthorized {
+			t.Errorf("with a different certificate: expected status %d, got %d", http.StatusUnauthorized, status)
+		}
+
+		// certificate for an unregistered key
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		if status, body := requestTokenWithCertificate(t, testEnv.baseURL, clientID, createSelfSignedCertificate(t, otherKey)); status != http.StatusUnauthorized {
+			t.Errorf("unregistered key: expected status %d, got %d %v", http.StatusUnauthorized, status, body)
+		}
+	})
+
+	t.Run("client_secret_for_another_account", func(t *testing.T) {
+		otherAccount := createTestAccount(t, ctx, testEnv.queries, "member", "service_account", "other@keys.test")
+		_, otherSecret := createTestClientSecret(t, ctx, testEnv, otherAccount)
+
+		resp := makeOAuthTokenRequest(t, testEnv.baseURL, "client_credentials", map[string]string{
+			"client_id":     clientID,
+			"client_secret": otherSecret,
+		}, "")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+		}
+	})
+
+	t.Run("revoked_key", func(t *testing.T) {
+		resp := serviceAccountKeysRequest(t, testEnv.baseURL, adminToken, http.MethodDelete, clientID+"/keys/"+edKeyID, nil)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected status %d, got %d", http.StatusNoContent, resp.StatusCode)
+		}
+
+		assertion := createClientAssertion(t, edKey, jwt.SigningMethodEdDSA, edKeyID, clientID, tokenEndpoint, nil)
+		if status, body := requestTokenWithAssertion(t, testEnv.baseURL, assertion); status != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d %v", http.StatusUnauthorized, status, body)
+		}
+
+		resp = serviceAccountKeysRequest(t, testEnv.baseURL, adminToken, http.MethodDelete, clientID+"/keys/"+edKeyID, nil)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("revoking again: expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+		}
+	})
+}
+
+func TestForgedClientCertificateHeader(t *testing.T) {
+	ctx := context.Background()
+
+	// the test requests come from the loopback address, which is not one of the trusted proxies
+	t.Setenv("TLS_CLIENT_CERT_TRUSTED_PROXIES", "192.0.2.0/24")
+
+	testEnv := startInProcessServer(t, "")
+
+	admin := createTestAccount(t, ctx, testEnv.queries, "isnadmin", "user", "admin@forged-cert.test")
+	adminToken := testEnv.createAuthToken(t, admin.ID)
+
+	serviceAccount := createTestAccount(t, ctx, testEnv.queries, "member", "service_account", "worker@forged-cert.test")
+	clientID, _ := createTestClientSecret(t, ctx, testEnv, serviceAccount)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	registerServiceAccountKey(t, testEnv.baseURL, adminToken, clientID, key.Public())
+
+	// the certificate is for a registered key, but the header was not set by a trusted proxy
+	status, body := requestTokenWithCertificate(t, testEnv.baseURL, clientID, createSelfSignedCertificate(t, key))
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d %v", http.StatusUnauthorized, status, body)
+	}
+	if body["error"] != "invalid_client" {
+		t.Errorf("expected invalid_client error, got %v", body)
+	}
+}
@@ -0,0 +1,227 @@
+//go:build integration
+
+package integration
+
+// tests
+// - signals of more than one signal type can be submitted to an ISN in a single request
+// - results are grouped by signal type and all the signals are tracked under one batch
+// - signals for signal types that are not available on the ISN are rejected individually
+// - requests with missing or malformed signal type paths are rejected
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+// submitBulkSignals submits signals to the multi signal type endpoint for the ISN
+func submitBulkSignals(t *testing.T, baseURL, isnSlug, token string, signals []map[string]any) *http.Response {
+	t.Helper()
+
+	jsonPayload, err := json.Marshal(map[string]any{"batch_ref": "bulk-batch", "signals": signals})
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/isn/%s/signals", baseURL, isnSlug), bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to submit signals: %v", err)
+	}
+	return resp
+}
+
+func TestBulkSignalSubmission(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	writerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "writer@bulk-test.com")
+	readerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "reader@bulk-test.com")
+
+	isn := createTestISN(t, ctx, testEnv.queries, "bulk-isn", "Bulk ISN", writerAccount.ID, "private")
+	otherIsn := createTestISN(t, ctx, testEnv.queries, "other-bulk-isn", "Other Bulk ISN", writerAccount.ID, "private")
+
+	signalTypeA := createTestSignalType(t, ctx, testEnv.queries, isn.ID, "bulk signal type a", "")
+	signalTypeB := createTestSignalType(t, ctx, testEnv.queries, isn.ID, "bulk signal type b", "")
+	otherSignalType := createTestSignalType(t, ctx, testEnv.queries, otherIsn.ID, "other bulk signal type", "")
+
+	grantPermission(t, ctx, testEnv.queries, isn.ID, writerAccount.ID, "write")
+	grantPermission(t, ctx, testEnv.queries, otherIsn.ID, writerAccount.ID, "write")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, readerAccount.ID, "read")
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("Failed to refresh schema cache: %v", err)
+	}
+
+	writerToken := testEnv.createAuthToken(t, writerAccount.ID)
+	readerToken := testEnv.createAuthToken(t, readerAccount.ID)
+
+	signalTypeAPath := fmt.Sprintf("%s/v%s", signalTypeA.Slug, signalTypeA.SemVer)
+	signalTypeBPath := fmt.Sprintf("%s/v%s", signalTypeB.Slug, signalTypeB.SemVer)
+	otherSignalTypePath := fmt.Sprintf("%s/v%s", otherSignalType.Slug, otherSignalType.SemVer)
+
+	bulkSignal := func(signalTypePath, localRef string) map[string]any {
+		return map[string]any{
+			"signal_type_path": signalTypePath,
+			"local_ref":        localRef,
+			"content":          map[string]any{"test": "valid content for simple schema"},
+		}
+	}
+
+	decodeResponse := func(t *testing.T, resp *http.Response) handlers.SignalSubmissionResponse {
+		t.Helper()
+		var response handlers.SignalSubmissionResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("multiple_signal_types", func(t *testing.T) {
+		resp := submitBulkSignals(t, testEnv.baseURL, isn.Slug, writerToken, []map[string]any{
+			bulkSignal(signalTypeAPath, "bulk-signal-1"),
+			bulkSignal(signalTypeBPath, "bulk-signal-2"),
+			bulkSignal(signalTypeAPath, "bulk-signal-3"),
+		})
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		response := decodeResponse(t, resp)
+		if response.Summary.TotalSubmitted != 3 || response.Summary.StoredCount != 3 {
+			t.Errorf("expected 3 submitted and stored signals, got %+v", response.Summary)
+		}
+
+		expected := []struct {
+			signalTypePath string
+			localRefs      []string
+		}{
+			{signalTypeAPath, []string{"bulk-signal-1", "bulk-signal-3"}},
+			{signalTypeBPath, []string{"bulk-signal-2"}},
+		}
+		if len(response.Results) != len(expected) {
+			t.Fatalf("expected %d results, got %d", len(expected), len(response.Results))
+		}
+		for i, want := range expected {
+			result := response.Results[i]
+			if result.SignalTypePath != want.signalTypePath || result.IsnSlug != isn.Slug {
+				t.Errorf("expected result %d for %s on %s, got %s on %s", i, want.signalTypePath, isn.Slug, result.SignalTypePath, result.IsnSlug)
+			}
+			if len(result.StoredSignals) != len(want.localRefs) {
+				t.Fatalf("expected %d stored signals for %s, got %d", len(want.localRefs), want.signalTypePath, len(result.StoredSignals))
+			}
+			for j, localRef := range want.localRefs {
+				if result.StoredSignals[j].LocalRef != localRef {
+					t.Errorf("expected stored signal %s, got %s", localRef, result.StoredSignals[j].LocalRef)
+				}
+			}
+		}
+
+		// the signals are searchable using the standard endpoints
+		for _, signalType := range []struct{ slug, semVer string }{{signalTypeA.Slug, signalTypeA.SemVer}, {signalTypeB.Slug, signalTypeB.SemVer}} {
+			endpoint := testSignalEndpoint{isnSlug: isn.Slug, signalTypeSlug: signalType.slug, signalTypeSemVer: signalType.semVer}
+			searchResp := searchPrivateSignalsWithParams(t, testEnv.baseURL, endpoint, readerToken, nil)
+			var signals []map[string]any
+			if err := json.NewDecoder(searchResp.Body).Decode(&signals); err != nil {
+				t.Fatalf("Failed to decode search response: %v", err)
+			}
+			searchResp.Body.Close()
+			if len(signals) == 0 {
+				t.Errorf("expected signals for %s/v%s", signalType.slug, signalType.semVer)
+			}
+		}
+
+		// the signals are tracked under one batch
+		batch, err := testEnv.queries.GetSignalBatchByRefAndAccountID(ctx, database.GetSignalBatchByRefAndAccountIDParams{
+			AccountID: writerAccount.ID,
+			BatchRef:  "bulk-batch",
+		})
+		if err != nil {
+			t.Fatalf("Failed to get batch: %v", err)
+		}
+		loaded, err := testEnv.queries.GetLoadedSignalsSummaryByBatchID(ctx, batch.ID)
+		if err != nil {
+			t.Fatalf("Failed to get batch summary: %v", err)
+		}
+		if len(loaded) != 2 {
+			t.Errorf("expected signals for 2 signal types in the batch, got %d", len(loaded))
+		}
+	})
+
+	t.Run("signal_type_not_available_on_isn", func(t *testing.T) {
+		resp := submitBulkSignals(t, testEnv.baseURL, isn.Slug, writerToken, []map[string]any{
+			bulkSignal(signalTypeAPath, "bulk-signal-4"),
+			bulkSignal(otherSignalTypePath, "bulk-signal-5"),
+		})
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusMultiStatus {
+			t.Fatalf("expected status %d, got %d", http.StatusMultiStatus, resp.StatusCode)
+		}
+
+		response := decodeResponse(t, resp)
+		if response.Summary.StoredCount != 1 || response.Summary.RejectedCount != 1 {
+			t.Errorf("expected 1 stored and 1 rejected signal, got %+v", response.Summary)
+		}
+		if len(response.Results) != 2 {
+			t.Fatalf("expected 2 results, got %d", len(response.Results))
+		}
+		failed := response.Results[1].FailedSignals
+		if response.Results[1].SignalTypePath != otherSignalTypePath || len(failed) != 1 || failed[0].LocalRef != "bulk-signal-5" {
+			t.Errorf("expected bulk-signal-5 to fail for %s, got %+v", otherSignalTypePath, response.Results[1])
+		}
+	})
+
+	t.Run("invalid_requests", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			token          string
+			signals        []map[string]any
+			expectedStatus int
+		}{
+			{
+				name:           "missing_signal_type_path",
+				token:          writerToken,
+				signals:        []map[string]any{bulkSignal("", "bulk-signal-6")},
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:           "malformed_signal_type_path",
+				token:          writerToken,
+				signals:        []map[string]any{bulkSignal(signalTypeA.Slug, "bulk-signal-6")},
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:           "no_write_permission",
+				token:          readerToken,
+				signals:        []map[string]any{bulkSignal(signalTypeAPath, "bulk-signal-6")},
+				expectedStatus: http.StatusForbidden,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := submitBulkSignals(t, testEnv.baseURL, isn.Slug, tt.token, tt.signals)
+				defer resp.Body.Close()
+
+				if resp.StatusCode != tt.expectedStatus {
+					t.Errorf("expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+				}
+			})
+		}
+	})
+}
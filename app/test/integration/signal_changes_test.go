@@ -0,0 +1,243 @@
+//go:build integration
+
+package integration
+
+// tests
+// - the signal changes feed returns new signal versions and withdrawals in the order they were made
+// - the cursor and has_more flag can be used to page through the feed
+// - write-only accounts only receive changes to the signals they created
+// - resubmitting a withdrawn signal is included in the feed as a reinstatement
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+func getSignalChanges(t *testing.T, baseURL string, endpoint testSignalEndpoint, token, cursor, limit string) *http.Response {
+	t.Helper()
+
+	changesURL := fmt.Sprintf("%s/api/isn/%s/signal-types/%s/v%s/signals/changes",
+		baseURL, endpoint.isnSlug, endpoint.signalTypeSlug, endpoint.signalTypeSemVer)
+
+	q := url.Values{}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	if limit != "" {
+		q.Set("limit", limit)
+	}
+	if len(q) > 0 {
+		changesURL += "?" + q.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, changesURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	return resp
+}
+
+func getSignalChangesPage(t *testing.T, baseURL string, endpoint testSignalEndpoint, token, cursor, limit string) handlers.SignalChangesResponse {
+	t.Helper()
+
+	resp := getSignalChanges(t, baseURL, endpoint, token, cursor, limit)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d getting signal changes, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var page handlers.SignalChangesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return page
+}
+
+func TestSignalChanges(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	siteAdminAccount := createTestAccount(t, ctx, testEnv.queries, "siteadmin", "user", "siteadmin@changes-test.com")
+	readerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "reader@changes-test.com")
+	writerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "writer@changes-test.com")
+	otherWriterAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "other-writer@changes-test.com")
+
+	isn := createTestISN(t, ctx, testEnv.queries, "changes-isn", "Changes ISN", siteAdminAccount.ID, "private")
+	signalType := createTestSignalType(t, ctx, testEnv.queries, isn.ID, "changes test signal", "")
+
+	grantPermission(t, ctx, testEnv.queries, isn.ID, readerAccount.ID, "read")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, writerAccount.ID, "write")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, otherWriterAccount.ID, "write")
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("schemaCache.Load: %v", err)
+	}
+
+	endpoint := testSignalEndpoint{
+		isnSlug:          isn.Slug,
+		signalTypeSlug:   signalType.Slug,
+		signalTypeSemVer: signalType.SemVer,
+	}
+
+	readerToken := testEnv.createAuthToken(t, readerAccount.ID)
+	writerToken := testEnv.createAuthToken(t, writerAccount.ID)
+	otherWriterToken := testEnv.createAuthToken(t, otherWriterAccount.ID)
+
+	submitSignal := func(t *testing.T, payload map[string]any, token string) {
+		t.Helper()
+		resp := submitCreateSignalRequest(t, testEnv.baseURL, payload, token, endpoint)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d submitting signal, got %d", http.StatusOK, resp.StatusCode)
+		}
+	}
+
+	t.Run("invalid_params", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			cursor string
+			limit  string
+		}{
+			{"invalid_cursor", "not-a-cursor", ""},
+			{"limit_too_small", "", "0"},
+			{"limit_too_large", "", "1001"},
+			{"limit_not_a_number", "", "ten"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := getSignalChanges(t, testEnv.baseURL, endpoint, readerToken, tt.cursor, tt.limit)
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusBadRequest {
+					t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+				}
+			})
+		}
+	})
+
+	t.Run("changes_are_returned_in_order", func(t *testing.T) {
+		page := getSignalChangesPage(t, testEnv.baseURL, endpoint, readerToken, "", "")
+		if len(page.Changes) != 0 || page.HasMore {
+			t.Fatalf("expected empty feed, got %d changes (has_more %v)", len(page.Changes), page.HasMore)
+		}
+		cursor := page.NextCursor
+
+		submitSignal(t, createValidSignalPayload("changes-signal-1"), writerToken)
+		submitSignal(t, createValidSignalPayload("changes-signal-2"), otherWriterToken)
+		submitSignal(t, createUpdatedSignalPayload("changes-signal-1"), writerToken) // new version
+
+		resp := withdrawSignal(t, testEnv.baseURL, endpoint, otherWriterToken, "changes-signal-2")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected status %d withdrawing signal, got %d", http.StatusNoContent, resp.StatusCode)
+		}
+
+		// first page
+		page = getSignalChangesPage(t, testEnv.baseURL, endpoint, readerToken, cursor, "2")
+		if len(page.Changes) != 2 {
+			t.Fatalf("expected 2 changes, got %d", len(page.Changes))
+		}
+		if !page.HasMore {
+			t.Error("expected has_more to be true")
+		}
+		expectSignalChange(t, page.Changes[0], "version_created", "changes-signal-1", 1)
+		expectSignalChange(t, page.Changes[1], "version_created", "changes-signal-2", 1)
+
+		// second page
+		page = getSignalChangesPage(t, testEnv.baseURL, endpoint, readerToken, page.NextCursor, "2")
+		if len(page.Changes) != 2 {
+			t.Fatalf("expected 2 changes, got %d", len(page.Changes))
+		}
+		expectSignalChange(t, page.Changes[0], "version_created", "changes-signal-1", 2)
+		expectSignalChange(t, page.Changes[1], "withdrawn", "changes-signal-2", 0)
+
+		// no further changes - the cursor is unchanged
+		lastCursor := page.NextCursor
+		page = getSignalChangesPage(t, testEnv.baseURL, endpoint, readerToken, lastCursor, "2")
+		if len(page.Changes) != 0 || page.HasMore {
+			t.Errorf("expected no further changes, got %d changes (has_more %v)", len(page.Changes), page.HasMore)
+		}
+		if page.NextCursor != lastCursor {
+			t.Errorf("expected next_cursor %s, got %s", lastCursor, page.NextCursor)
+		}
+
+		// the full feed is returned when no cursor is supplied
+		page = getSignalChangesPage(t, testEnv.baseURL, endpoint, readerToken, "", "")
+		if len(page.Changes) != 4 {
+			t.Errorf("expected 4 changes from the start of the feed, got %d", len(page.Changes))
+		}
+	})
+
+	t.Run("write_only_accounts_only_receive_changes_to_their_own_signals", func(t *testing.T) {
+		page := getSignalChangesPage(t, testEnv.baseURL, endpoint, writerToken, "", "")
+		if len(page.Changes) != 2 {
+			t.Fatalf("expected 2 changes, got %d", len(page.Changes))
+		}
+		for _, change := range page.Changes {
+			if change.AccountID != writerAccount.ID {
+				t.Errorf("expected only changes for account %s, got change for %s (%s)", writerAccount.ID, change.AccountID, change.LocalRef)
+			}
+		}
+	})
+
+	t.Run("resubmitted_withdrawn_signals_are_reinstated", func(t *testing.T) {
+		cursor := getSignalChangesPage(t, testEnv.baseURL, endpoint, readerToken, "", "").NextCursor
+
+		// changes-signal-2 was withdrawn above - resubmitting it reinstates the signal and stores a new version
+		submitSignal(t, createUpdatedSignalPayload("changes-signal-2"), otherWriterToken)
+
+		page := getSignalChangesPage(t, testEnv.baseURL, endpoint, readerToken, cursor, "")
+		if len(page.Changes) != 2 {
+			t.Fatalf("expected 2 changes, got %d", len(page.Changes))
+		}
+		changeTypes := map[string]bool{}
+		for _, change := range page.Changes {
+			if change.LocalRef != "changes-signal-2" {
+				t.Errorf("expected changes for changes-signal-2, got %s change for %s", change.ChangeType, change.LocalRef)
+			}
+			changeTypes[change.ChangeType] = true
+		}
+		if !changeTypes["reinstated"] || !changeTypes["version_created"] {
+			t.Errorf("expected reinstated and version_created changes, got %v", changeTypes)
+		}
+	})
+}
+
+// expectSignalChange checks the change type, local ref and (for version_created changes) the version number
+func expectSignalChange(t *testing.T, change handlers.SignalChange, changeType, localRef string, versionNumber int32) {
+	t.Helper()
+
+	if change.ChangeType != changeType || change.LocalRef != localRef {
+		t.Errorf("expected %s change for %s, got %s change for %s", changeType, localRef, change.ChangeType, change.LocalRef)
+		return
+	}
+
+	if changeType != "version_created" {
+		if change.Signal != nil {
+			t.Errorf("expected no signal for %s change", changeType)
+		}
+		return
+	}
+
+	if change.Signal == nil {
+		t.Fatalf("expected signal for %s change", changeType)
+	}
+	if change.Signal.VersionNumber != versionNumber {
+		t.Errorf("expected version %d for %s, got %d", versionNumber, localRef, change.Signal.VersionNumber)
+	}
+}
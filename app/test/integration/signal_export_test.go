@@ -0,0 +1,289 @@
+//go:build integration
+
+package integration
+
+// tests
+// - signal search results can be exported as ndjson and csv using the Accept header
+// - csv content columns are flattened from the signal type schema
+// - write-only accounts only export the signals they created and public ISN exports do not include email addresses
+// - pagination params can't be used with exports
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+// exportSignalSearch runs a signal search with the supplied Accept header (the search is for signals created in the last hour).
+// The public search endpoint is used when token is empty.
+func exportSignalSearch(t *testing.T, baseURL string, endpoint testSignalEndpoint, token, accept string, params map[string]string) *http.Response {
+	t.Helper()
+
+	searchURL := fmt.Sprintf("%s/api/isn/%s/signal-types/%s/v%s/signals/search",
+		baseURL, endpoint.isnSlug, endpoint.signalTypeSlug, endpoint.signalTypeSemVer)
+	if token == "" {
+		searchURL = fmt.Sprintf("%s/api/public/isn/%s/signal-types/%s/v%s/signals/search",
+			baseURL, endpoint.isnSlug, endpoint.signalTypeSlug, endpoint.signalTypeSemVer)
+	}
+
+	q := url.Values{}
+	now := time.Now()
+	q.Set("start_date", now.Add(-1*time.Hour).Format(time.RFC3339))
+	q.Set("end_date", now.Add(1*time.Hour).Format(time.RFC3339))
+	for key, value := range params {
+		q.Set(key, value)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, searchURL+"?"+q.Encode(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", accept)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	return resp
+}
+
+// readNDJSONExport returns the signals in an ndjson export
+func readNDJSONExport(t *testing.T, resp *http.Response) []handlers.SearchSignal {
+	t.Helper()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Fatalf("expected Content-Type application/x-ndjson, got %s", contentType)
+	}
+
+	var signals []handlers.SearchSignal
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var signal handlers.SearchSignal
+		if err := json.Unmarshal(scanner.Bytes(), &signal); err != nil {
+			t.Fatalf("Failed to decode ndjson line %q: %v", scanner.Text(), err)
+		}
+		signals = append(signals, signal)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Failed to read ndjson export: %v", err)
+	}
+	return signals
+}
+
+func TestSignalSearchExport(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	ownerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "owner@export-test.com")
+	writerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "writer@export-test.com")
+
+	privateISN := createTestISN(t, ctx, testEnv.queries, "export-isn", "Export ISN", ownerAccount.ID, "private")
+	publicISN := createTestISN(t, ctx, testEnv.queries, "public-export-isn", "Public Export ISN", ownerAccount.ID, "public")
+
+	// csv columns are flattened from the schema so this test uses a schema with nested fields
+	signalType, err := testEnv.queries.CreateSignalType(ctx, database.CreateSignalTypeParams{
+		Slug:      "export-signal-type",
+		SchemaURL: "https://github.com/information-sharing-networks/signal-library/blob/main/signalsd-testing/export.json",
+		ReadmeURL: testReadmeURL,
+		Title:     "export signal type",
+		Detail:    testSignalTypeDetail,
+		SemVer:    "1.0.0",
+		SchemaContent: `{"type": "object", "properties": {
+			"status": {"type": "string"},
+			"items": {"type": "array"},
+			"payload": {"type": "object", "properties": {"PortOfEntry": {"type": "string"}, "weight": {"type": "number"}}}
+		}}`,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create signal type: %v", err)
+	}
+	addSignalTypeToIsn(t, ctx, testEnv.queries, privateISN.ID, signalType.ID)
+	addSignalTypeToIsn(t, ctx, testEnv.queries, publicISN.ID, signalType.ID)
+
+	grantPermission(t, ctx, testEnv.queries, privateISN.ID, ownerAccount.ID, "read-write")
+	grantPermission(t, ctx, testEnv.queries, privateISN.ID, writerAccount.ID, "write")
+	grantPermission(t, ctx, testEnv.queries, publicISN.ID, ownerAccount.ID, "read-write")
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("Failed to refresh schema cache: %v", err)
+	}
+	if err := testEnv.publicIsnCache.Load(ctx); err != nil {
+		t.Fatalf("Failed to refresh public ISN cache: %v", err)
+	}
+
+	ownerToken := testEnv.createAuthToken(t, ownerAccount.ID)
+	writerToken := testEnv.createAuthToken(t, writerAccount.ID)
+
+	privateEndpoint := testSignalEndpoint{
+		isnSlug:          privateISN.Slug,
+		signalTypeSlug:   signalType.Slug,
+		signalTypeSemVer: signalType.SemVer,
+	}
+	publicEndpoint := testSignalEndpoint{
+		isnSlug:          publicISN.Slug,
+		signalTypeSlug:   signalType.Slug,
+		signalTypeSemVer: signalType.SemVer,
+	}
+
+	submitSignals := func(t *testing.T, endpoint testSignalEndpoint, token string, signals []map[string]any) {
+		t.Helper()
+		resp := submitCreateSignalRequest(t, testEnv.baseURL, map[string]any{"batch_ref": "test-batch", "signals": signals}, token, endpoint)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("failed to submit signals: %d", resp.StatusCode)
+		}
+	}
+
+	submitSignals(t, privateEndpoint, ownerToken, []map[string]any{
+		{"local_ref": "export-signal-1", "content": map[string]any{"status": "approved", "items": []string{"a", "b"}, "payload": map[string]any{"PortOfEntry": "Felixstowe", "weight": 1500.5}}},
+		{"local_ref": "export-signal-2", "content": map[string]any{"status": "pending, \"awaiting review\""}},
+	})
+	submitSignals(t, privateEndpoint, writerToken, []map[string]any{
+		{"local_ref": "export-signal-3", "content": map[string]any{"status": "approved", "payload": map[string]any{"PortOfEntry": "Dover"}}},
+	})
+	submitSignals(t, publicEndpoint, ownerToken, []map[string]any{
+		{"local_ref": "public-export-signal-1", "content": map[string]any{"status": "approved"}},
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		resp := exportSignalSearch(t, testEnv.baseURL, privateEndpoint, ownerToken, "application/x-ndjson", nil)
+		defer resp.Body.Close()
+
+		signals := readNDJSONExport(t, resp)
+		if len(signals) != 3 {
+			t.Fatalf("expected 3 signals, got %d", len(signals))
+		}
+		for i, localRef := range []string{"export-signal-1", "export-signal-2", "export-signal-3"} {
+			if signals[i].LocalRef != localRef {
+				t.Errorf("expected signal %d to be %s, got %s", i, localRef, signals[i].LocalRef)
+			}
+		}
+		if signals[0].Email != "owner@export-test.com" {
+			t.Errorf("expected email owner@export-test.com, got %s", signals[0].Email)
+		}
+		if !strings.Contains(string(signals[0].Content), "Felixstowe") {
+			t.Errorf("expected signal content to be included, got %s", signals[0].Content)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		resp := exportSignalSearch(t, testEnv.baseURL, privateEndpoint, ownerToken, "text/csv", map[string]string{"content.status": "approved"})
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		if contentType := resp.Header.Get("Content-Type"); contentType != "text/csv" {
+			t.Fatalf("expected Content-Type text/csv, got %s", contentType)
+		}
+
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("Failed to read csv export: %v", err)
+		}
+		if len(records) != 3 {
+			t.Fatalf("expected a header and 2 rows, got %d records", len(records))
+		}
+
+		header := records[0]
+		for _, column := range []string{"signal_id", "local_ref", "version_number", "content.items", "content.payload.PortOfEntry", "content.payload.weight", "content.status"} {
+			if !slices.Contains(header, column) {
+				t.Errorf("expected column %s in header %v", column, header)
+			}
+		}
+
+		row := make(map[string]string)
+		for i, column := range header {
+			row[column] = records[1][i]
+		}
+		expected := map[string]string{
+			"local_ref":                   "export-signal-1",
+			"email":                       "owner@export-test.com",
+			"version_number":              "1",
+			"content.status":              "approved",
+			"content.items":               `["a","b"]`,
+			"content.payload.PortOfEntry": "Felixstowe",
+			"content.payload.weight":      "1500.5",
+		}
+		for column, value := range expected {
+			if row[column] != value {
+				t.Errorf("expected %s to be %q, got %q", column, value, row[column])
+			}
+		}
+
+		// missing fields are empty
+		if records[2][slices.Index(header, "content.payload.weight")] != "" {
+			t.Errorf("expected empty content.payload.weight for export-signal-3, got %q", records[2][slices.Index(header, "content.payload.weight")])
+		}
+	})
+
+	t.Run("write_only_accounts_only_export_their_own_signals", func(t *testing.T) {
+		resp := exportSignalSearch(t, testEnv.baseURL, privateEndpoint, writerToken, "application/x-ndjson", nil)
+		defer resp.Body.Close()
+
+		signals := readNDJSONExport(t, resp)
+		if len(signals) != 1 || signals[0].LocalRef != "export-signal-3" {
+			t.Errorf("expected only export-signal-3, got %v", signals)
+		}
+	})
+
+	t.Run("public_isn_export_does_not_include_email", func(t *testing.T) {
+		resp := exportSignalSearch(t, testEnv.baseURL, publicEndpoint, "", "application/x-ndjson", nil)
+		defer resp.Body.Close()
+
+		signals := readNDJSONExport(t, resp)
+		if len(signals) != 1 {
+			t.Fatalf("expected 1 signal, got %d", len(signals))
+		}
+		if signals[0].Email != "" {
+			t.Errorf("expected no email in public ISN export, got %s", signals[0].Email)
+		}
+	})
+
+	t.Run("json_is_returned_by_default", func(t *testing.T) {
+		resp := exportSignalSearch(t, testEnv.baseURL, privateEndpoint, ownerToken, "application/json, text/csv", nil)
+		defer resp.Body.Close()
+
+		if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+			t.Errorf("expected json response, got Content-Type %s", contentType)
+		}
+	})
+
+	t.Run("invalid_export_params", func(t *testing.T) {
+		invalidParams := []struct {
+			name   string
+			params map[string]string
+		}{
+			{"limit", map[string]string{"limit": "10"}},
+			{"include_previous_versions", map[string]string{"include_previous_versions": "true"}},
+		}
+
+		for _, tt := range invalidParams {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := exportSignalSearch(t, testEnv.baseURL, privateEndpoint, ownerToken, "text/csv", tt.params)
+				defer resp.Body.Close()
+
+				if resp.StatusCode != http.StatusBadRequest {
+					t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,150 @@
+//go:build integration
+
+package integration
+
+// tests
+// - search results include the lifecycle events of each signal when include_history=true
+// - the events record the account that made each change and the reason given
+// - resubmitting a withdrawn signal is recorded as a reinstatement
+// - include_history can't be used with exports
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+func TestSignalHistory(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	isnAdminAccount := createTestAccount(t, ctx, testEnv.queries, "isnadmin", "user", "isnadmin@history-test.com")
+	writerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "writer@history-test.com")
+
+	isn := createTestISN(t, ctx, testEnv.queries, "history-isn", "History ISN", isnAdminAccount.ID, "private")
+	signalType := createTestSignalType(t, ctx, testEnv.queries, isn.ID, "history test signal", "")
+
+	grantPermission(t, ctx, testEnv.queries, isn.ID, writerAccount.ID, "write")
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("schemaCache.Load: %v", err)
+	}
+
+	endpoint := testSignalEndpoint{
+		isnSlug:          isn.Slug,
+		signalTypeSlug:   signalType.Slug,
+		signalTypeSemVer: signalType.SemVer,
+	}
+
+	isnAdminToken := testEnv.createAuthToken(t, isnAdminAccount.ID)
+	writerToken := testEnv.createAuthToken(t, writerAccount.ID)
+
+	submitSignal := func(t *testing.T, payload map[string]any) {
+		t.Helper()
+		resp := submitCreateSignalRequest(t, testEnv.baseURL, payload, writerToken, endpoint)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d submitting signal, got %d", http.StatusOK, resp.StatusCode)
+		}
+	}
+	changeStatus := func(t *testing.T, token, action, reason string) {
+		t.Helper()
+		res := decodeSignalStatusChangeResponse(t, changeSignalsStatus(t, testEnv.baseURL, endpoint, token, action, handlers.SignalStatusChangeRequest{
+			LocalRefs: []string{"h-signal-1"},
+			AccountID: &writerAccount.ID,
+			Reason:    reason,
+		}))
+		if res.ChangedCount != 1 {
+			t.Fatalf("expected 1 signal to be changed, got %+v", res)
+		}
+	}
+
+	// create, update, withdraw, reinstate (ISN admin), withdraw again and resubmit
+	submitSignal(t, createValidSignalPayload("h-signal-1"))
+	submitSignal(t, createUpdatedSignalPayload("h-signal-1"))
+	changeStatus(t, writerToken, "bulk-withdraw", "sent in error")
+	changeStatus(t, isnAdminToken, "bulk-reinstate", "withdrawn by mistake")
+
+	resp := withdrawSignal(t, testEnv.baseURL, endpoint, writerToken, "h-signal-1")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected status %d withdrawing signal, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+
+	submitSignal(t, createValidSignalPayload("h-signal-1"))
+
+	t.Run("history_included_in_search", func(t *testing.T) {
+		resp := searchPrivateSignalsWithParams(t, testEnv.baseURL, endpoint, isnAdminToken, map[string]string{
+			"local_ref":       "h-signal-1",
+			"include_history": "true",
+		})
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		var signals []handlers.SearchSignalWithCorrelationsAndVersions
+		if err := json.NewDecoder(resp.Body).Decode(&signals); err != nil {
+			t.Fatalf("Failed to decode search response: %v", err)
+		}
+		if len(signals) != 1 {
+			t.Fatalf("expected 1 signal, got %d", len(signals))
+		}
+
+		expected := []struct {
+			eventType string
+			actor     uuid.UUID
+			email     string
+			reason    string
+		}{
+			{"created", writerAccount.ID, "writer@history-test.com", ""},
+			{"version_created", writerAccount.ID, "writer@history-test.com", ""},
+			{"withdrawn", writerAccount.ID, "writer@history-test.com", "sent in error"},
+			{"reinstated", isnAdminAccount.ID, "isnadmin@history-test.com", "withdrawn by mistake"},
+			{"withdrawn", writerAccount.ID, "writer@history-test.com", ""},
+			{"reinstated", writerAccount.ID, "writer@history-test.com", ""}, // resubmitted
+			{"version_created", writerAccount.ID, "writer@history-test.com", ""},
+		}
+
+		history := signals[0].History
+		if len(history) != len(expected) {
+			t.Fatalf("expected %d events, got %d: %+v", len(expected), len(history), history)
+		}
+		for i, want := range expected {
+			got := history[i]
+			if got.EventType != want.eventType || got.ActorAccountID == nil || *got.ActorAccountID != want.actor || got.ActorEmail != want.email || got.Reason != want.reason {
+				t.Errorf("event %d: want %s by %s (%q), got %s by %v %s (%q)", i, want.eventType, want.email, want.reason, got.EventType, got.ActorAccountID, got.ActorEmail, got.Reason)
+			}
+		}
+		if history[6].VersionNumber == nil || *history[6].VersionNumber != 3 {
+			t.Errorf("expected the last event to be for version 3, got %v", history[6].VersionNumber)
+		}
+	})
+
+	t.Run("history_not_included_by_default", func(t *testing.T) {
+		resp := searchPrivateSignalsWithParams(t, testEnv.baseURL, endpoint, isnAdminToken, map[string]string{"local_ref": "h-signal-1"})
+		defer resp.Body.Close()
+
+		var signals []handlers.SearchSignalWithCorrelationsAndVersions
+		if err := json.NewDecoder(resp.Body).Decode(&signals); err != nil {
+			t.Fatalf("Failed to decode search response: %v", err)
+		}
+		if len(signals) != 1 || signals[0].History != nil {
+			t.Errorf("expected no history, got %+v", signals)
+		}
+	})
+
+	t.Run("history_not_available_for_exports", func(t *testing.T) {
+		resp := exportSignalSearch(t, testEnv.baseURL, endpoint, isnAdminToken, "application/x-ndjson", map[string]string{
+			"include_history": "true",
+		})
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+		}
+	})
+}
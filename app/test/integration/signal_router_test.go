@@ -8,6 +8,8 @@ package integration
 // - Successful routing to a single ISN
 // - Successful routing to multiple ISNs in a single batch
 // - Correlation ID routing
+// - Fan-out routing (all_matches routing mode) to every matching ISN
+// - Batch status reports fan-out failures against the ISN that rejected the signal
 // - Response structure correctness
 
 import (
@@ -553,6 +555,122 @@ func TestRouteSignals(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("fan_out_routing", func(t *testing.T) {
+		// a second signal type on isn-a and isn-b that uses the all_matches routing mode:
+		//   "*port*"      -> isn-a
+		//   "*commodity*" -> isn-b
+		fanOutSignalType := createTestSignalType(t, ctx, testEnv.queries, isnA.ID, "router fan out signal", "")
+		if err := testEnv.queries.AddSignalTypeToIsn(ctx, database.AddSignalTypeToIsnParams{
+			IsnID:        isnB.ID,
+			SignalTypeID: fanOutSignalType.ID,
+		}); err != nil {
+			t.Fatalf("Failed to add signal type to isn-b: %v", err)
+		}
+		if err := testEnv.schemaCache.Load(ctx); err != nil {
+			t.Fatalf("schemaCache.Load: %v", err)
+		}
+
+		setRoutingConfig(t, testEnv, siteAdminToken, fanOutSignalType,
+			handlers.UpdateSignalRoutingConfigRequest{
+				RoutingField: "test",
+				RoutingMode:  "all_matches",
+				RoutingRules: []handlers.SignalRoutingRule{
+					{MatchPattern: "*port*", Operator: "matches", IsnSlug: isnA.Slug, Sequence: 1},
+					{MatchPattern: "*commodity*", Operator: "matches", IsnSlug: isnB.Slug, Sequence: 2},
+				},
+			})
+		if err := testEnv.routerCache.Load(ctx); err != nil {
+			t.Fatalf("routerCache.Load: %v", err)
+		}
+
+		t.Run("stored_in_every_matching_isn", func(t *testing.T) {
+			token := testEnv.createAuthToken(t, siteAdminAccount.ID)
+			resp := submitRouteSignalsRequest(t, testEnv.baseURL,
+				routerPayload("batch-fan-out", []map[string]any{
+					routerSignal("s-fan-out-both", "port and commodity"),
+					routerSignal("s-fan-out-port", "port only"),
+				}),
+				token, fanOutSignalType.Slug, fanOutSignalType.SemVer)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status: want 200, got %d", resp.StatusCode)
+			}
+			result := decodeRouterResponse(t, resp)
+			if result.Summary.TotalSubmitted != 2 || result.Summary.StoredCount != 3 {
+				t.Errorf("summary: want total=2 stored=3, got total=%d stored=%d",
+					result.Summary.TotalSubmitted, result.Summary.StoredCount)
+			}
+
+			isnResultA := findIsnResult(result.Results, isnA.Slug)
+			isnResultB := findIsnResult(result.Results, isnB.Slug)
+			if isnResultA == nil || isnResultB == nil {
+				t.Fatalf("expected results for isn-a and isn-b, got %+v", result.Results)
+			}
+			if len(isnResultA.StoredSignals) != 2 {
+				t.Errorf("isn-a stored_signals: want 2, got %d", len(isnResultA.StoredSignals))
+			}
+			if len(isnResultB.StoredSignals) != 1 || isnResultB.StoredSignals[0].LocalRef != "s-fan-out-both" {
+				t.Fatalf("isn-b stored_signals: want s-fan-out-both, got %+v", isnResultB.StoredSignals)
+			}
+
+			// the signal is stored separately in each ISN
+			for _, stored := range isnResultA.StoredSignals {
+				if stored.LocalRef == "s-fan-out-both" && stored.SignalID == isnResultB.StoredSignals[0].SignalID {
+					t.Error("expected a separate signal in each ISN")
+				}
+			}
+		})
+
+		t.Run("write_permission_checked_for_each_isn", func(t *testing.T) {
+			// writerAccount can only write to isn-a
+			token := testEnv.createAuthToken(t, writerAccount.ID)
+			resp := submitRouteSignalsRequest(t, testEnv.baseURL,
+				routerPayload("batch-fan-out-writer", []map[string]any{
+					routerSignal("s-fan-out-writer", "port and commodity"),
+				}),
+				token, fanOutSignalType.Slug, fanOutSignalType.SemVer)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusMultiStatus {
+				t.Fatalf("status: want 207, got %d", resp.StatusCode)
+			}
+			result := decodeRouterResponse(t, resp)
+			isnResultA := findIsnResult(result.Results, isnA.Slug)
+			if isnResultA == nil || len(isnResultA.StoredSignals) != 1 {
+				t.Errorf("expected the signal to be stored in isn-a, got %+v", isnResultA)
+			}
+			isnResultB := findIsnResult(result.Results, isnB.Slug)
+			if isnResultB == nil || len(isnResultB.FailedSignals) != 1 || isnResultB.FailedSignals[0].ErrorCode != string(apperrors.ErrCodeForbidden) {
+				t.Errorf("expected the signal to be rejected for isn-b, got %+v", isnResultB)
+			}
+		})
+
+		t.Run("batch_status_reports_failures_for_each_isn", func(t *testing.T) {
+			// s-fan-out-writer was stored in isn-a and rejected for isn-b in the previous test
+			token := testEnv.createAuthToken(t, writerAccount.ID)
+			batchStatus := getBatchStatus(t, testEnv.baseURL, "batch-fan-out-writer", token)
+
+			for _, status := range batchStatus.BatchStatus {
+				switch status.IsnSlug {
+				case isnA.Slug:
+					if status.StoredCount != 1 || status.RejectedCount != 0 {
+						t.Errorf("isn-a: want stored=1 rejected=0, got stored=%d rejected=%d", status.StoredCount, status.RejectedCount)
+					}
+				case isnB.Slug:
+					if status.StoredCount != 0 || len(status.UnresolvedFailures) != 1 || status.UnresolvedFailures[0].LocalRef != "s-fan-out-writer" {
+						t.Errorf("isn-b: want s-fan-out-writer reported as a failure, got %+v", status)
+					}
+				default:
+					t.Errorf("unexpected ISN %s in batch status", status.IsnSlug)
+				}
+			}
+			if len(batchStatus.BatchStatus) != 2 {
+				t.Errorf("expected batch status for isn-a and isn-b, got %+v", batchStatus.BatchStatus)
+			}
+		})
+	})
 }
 
 // submitRouteSignalsRequest posts to the signal router endpoint.
@@ -588,6 +706,31 @@ func submitRouteSignalsRequest(t *testing.T, baseURL string, payload map[string]
 	return resp
 }
 
+// getBatchStatus gets the status of one of the account's batches.
+func getBatchStatus(t *testing.T, baseURL, batchRef, token string) handlers.BatchStatusResponse {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/batches/%s/status", baseURL, batchRef), nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to get batch status: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d from batch status endpoint, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var batchStatus handlers.BatchStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batchStatus); err != nil {
+		t.Fatalf("Failed to decode batch status: %v", err)
+	}
+	return batchStatus
+}
+
 // decodeRouterResponse decodes a SignalSubmissionResponse from an http.Response body.
 func decodeRouterResponse(t *testing.T, resp *http.Response) handlers.SignalSubmissionResponse {
 	t.Helper()
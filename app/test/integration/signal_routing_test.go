@@ -7,6 +7,10 @@ package integration
 // PUT /api/admin/signal-types/{slug}/v{semver}/routes  - create/replace routing config
 // GET /api/admin/signal-types/{slug}/v{semver}/routes  - retrieve routing config
 // DELETE /api/admin/signal-types/{slug}/v{semver}/routes - remove routing config
+// POST /api/admin/signal-types/{slug}/v{semver}/routes/dry-run - test a draft or saved routing config against sample signals
+// GET /api/admin/signal-types/{slug}/v{semver}/routes/revisions - list the routing config revisions
+// GET /api/admin/signal-types/{slug}/v{semver}/routes/revisions/{revision} - view a revision and the changes from the previous revision
+// POST /api/admin/signal-types/{slug}/v{semver}/routes/revisions/{revision}/rollback - restore an earlier revision
 
 import (
 	"bytes"
@@ -14,10 +18,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/router"
 	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
 )
 
@@ -87,6 +93,45 @@ func deleteSignalRoutingConfig(t *testing.T, baseURL, token, slug, semVer string
 	return resp
 }
 
+// dryRunSignalRoutingConfig sends a POST to the routing config dry-run endpoint and returns the raw response.
+func dryRunSignalRoutingConfig(t *testing.T, baseURL, token, slug, semVer string, body any) *http.Response {
+	t.Helper()
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("Failed to marshal request body: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, isnRoutesURL(baseURL, slug, semVer)+"/dry-run", bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	return resp
+}
+
+// routingConfigRevisionsRequest makes a request to the routing config revision endpoints (path is relative to the revisions URL)
+func routingConfigRevisionsRequest(t *testing.T, baseURL, token, method, slug, semVer, path string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, isnRoutesURL(baseURL, slug, semVer)+"/revisions"+path, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	return resp
+}
+
 func isnRoutesURL(baseURL, slug, semVer string) string {
 	return fmt.Sprintf("%s/api/admin/signal-types/%s/v%s/routes", baseURL, slug, semVer)
 }
@@ -127,6 +172,22 @@ func TestSignalRoutingConfig(t *testing.T) {
 		},
 	}
 
+	// compound rules don't use the routing field
+	compoundRequest := handlers.UpdateSignalRoutingConfigRequest{
+		RoutingRules: []handlers.SignalRoutingRule{
+			{
+				Condition: &router.Condition{
+					All: []router.Condition{
+						{Field: "test", Operator: "matches", Pattern: "*value*"},
+						{Not: &router.Condition{Field: "test", Operator: "equals", Pattern: "excluded value"}},
+					},
+				},
+				IsnSlug:  isn.Slug,
+				Sequence: 1,
+			},
+		},
+	}
+
 	t.Run("Update", func(t *testing.T) {
 		testCases := []struct {
 			name           string
@@ -232,6 +293,105 @@ func TestSignalRoutingConfig(t *testing.T) {
 				},
 				expectedStatus: http.StatusNotFound,
 			},
+			{
+				name:   "invalid_routing_mode",
+				token:  siteAdminToken,
+				slug:   slug,
+				semVer: semVer,
+				body: handlers.UpdateSignalRoutingConfigRequest{
+					RoutingField: "test",
+					RoutingMode:  "best_match",
+					RoutingRules: validRequest.RoutingRules,
+				},
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:   "siteadmin_creates_config_with_extended_operators",
+				token:  siteAdminToken,
+				slug:   slug,
+				semVer: semVer,
+				body: handlers.UpdateSignalRoutingConfigRequest{
+					RoutingField: "test",
+					RoutingRules: []handlers.SignalRoutingRule{
+						{MatchPattern: "^value-[0-9]+$", Operator: "regex", IsnSlug: isn.Slug, Sequence: 1},
+						{MatchPattern: "a,b,c", Operator: "in", IsnSlug: isn.Slug, Sequence: 2},
+						{MatchPattern: "2026-04-01", Operator: "gte", IsnSlug: isn.Slug, Sequence: 3},
+						{Operator: "exists", IsnSlug: isn.Slug, Sequence: 4},
+					},
+				},
+				expectedStatus: http.StatusNoContent,
+			},
+			{
+				name:   "invalid_regex_pattern",
+				token:  siteAdminToken,
+				slug:   slug,
+				semVer: semVer,
+				body: handlers.UpdateSignalRoutingConfigRequest{
+					RoutingField: "test",
+					RoutingRules: []handlers.SignalRoutingRule{
+						{MatchPattern: "^(value", Operator: "regex", IsnSlug: isn.Slug, Sequence: 1},
+					},
+				},
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:   "range_operator_with_non_numeric_pattern",
+				token:  siteAdminToken,
+				slug:   slug,
+				semVer: semVer,
+				body: handlers.UpdateSignalRoutingConfigRequest{
+					RoutingField: "test",
+					RoutingRules: []handlers.SignalRoutingRule{
+						{MatchPattern: "heavy", Operator: "gt", IsnSlug: isn.Slug, Sequence: 1},
+					},
+				},
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:           "siteadmin_creates_compound_config",
+				token:          siteAdminToken,
+				slug:           slug,
+				semVer:         semVer,
+				body:           compoundRequest,
+				expectedStatus: http.StatusNoContent,
+			},
+			{
+				name:   "condition_with_match_pattern",
+				token:  siteAdminToken,
+				slug:   slug,
+				semVer: semVer,
+				body: handlers.UpdateSignalRoutingConfigRequest{
+					RoutingField: "test",
+					RoutingRules: []handlers.SignalRoutingRule{
+						{MatchPattern: "*value*", Operator: "matches", Condition: compoundRequest.RoutingRules[0].Condition, IsnSlug: isn.Slug, Sequence: 1},
+					},
+				},
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:   "empty_condition_group",
+				token:  siteAdminToken,
+				slug:   slug,
+				semVer: semVer,
+				body: handlers.UpdateSignalRoutingConfigRequest{
+					RoutingRules: []handlers.SignalRoutingRule{
+						{Condition: &router.Condition{All: []router.Condition{}}, IsnSlug: isn.Slug, Sequence: 1},
+					},
+				},
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:   "condition_field_not_in_schema",
+				token:  siteAdminToken,
+				slug:   slug,
+				semVer: semVer,
+				body: handlers.UpdateSignalRoutingConfigRequest{
+					RoutingRules: []handlers.SignalRoutingRule{
+						{Condition: &router.Condition{Field: "payload.portOfEntry", Operator: "equals", Pattern: "felixstowe"}, IsnSlug: isn.Slug, Sequence: 1},
+					},
+				},
+				expectedStatus: http.StatusBadRequest,
+			},
 			{
 				name:           "unknown_signal_type",
 				token:          siteAdminToken,
@@ -304,6 +464,9 @@ func TestSignalRoutingConfig(t *testing.T) {
 				if result.RoutingField != validRequest.RoutingField {
 					t.Errorf("routing_field: got %q, want %q", result.RoutingField, validRequest.RoutingField)
 				}
+				if result.RoutingMode != "first_match" {
+					t.Errorf("routing_mode: got %q, want first_match", result.RoutingMode)
+				}
 				if len(result.RoutingRules) != 1 {
 					t.Fatalf("Expected 1 route, got %d", len(result.RoutingRules))
 				}
@@ -314,6 +477,347 @@ func TestSignalRoutingConfig(t *testing.T) {
 		}
 	})
 
+	t.Run("compound_config_round_trip", func(t *testing.T) {
+		setRoutingConfig(t, testEnv, siteAdminToken, signalType, compoundRequest)
+
+		resp := getSignalRoutingConfig(t, testEnv.baseURL, siteAdminToken, slug, semVer)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		var result handlers.SignalRoutingConfigResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if result.RoutingField != "" {
+			t.Errorf("routing_field: got %q, want empty", result.RoutingField)
+		}
+		if len(result.RoutingRules) != 1 {
+			t.Fatalf("Expected 1 route, got %d", len(result.RoutingRules))
+		}
+		rule := result.RoutingRules[0]
+		if rule.MatchPattern != "" || rule.Operator != "" {
+			t.Errorf("expected no match_pattern or operator for a compound rule, got %q %q", rule.MatchPattern, rule.Operator)
+		}
+		if !reflect.DeepEqual(rule.Condition, compoundRequest.RoutingRules[0].Condition) {
+			t.Errorf("condition: got %+v, want %+v", rule.Condition, compoundRequest.RoutingRules[0].Condition)
+		}
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		// the site admin token was issued before the ISN was created - get a new one so the claims include the ISN
+		dryRunToken := getAccessToken(t, testEnv.authService, siteAdminAccount.ID)
+
+		// the signal type is not registered on this ISN, so signals can't be written to it
+		otherIsn := createTestISN(t, ctx, testEnv.queries, "dry-run-other-isn", "Dry Run Other ISN", siteAdminAccount.ID, "private")
+
+		signals := []handlers.SignalRoutingDryRunSignal{
+			{LocalRef: "matching", Content: json.RawMessage(`{"test": "a value"}`)},
+			{LocalRef: "not-matching", Content: json.RawMessage(`{"test": "other"}`)},
+			{LocalRef: "missing-field", Content: json.RawMessage(`{}`)},
+		}
+
+		decodeDryRunResponse := func(t *testing.T, resp *http.Response) handlers.SignalRoutingDryRunResponse {
+			t.Helper()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+			var result handlers.SignalRoutingDryRunResponse
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(result.Results) != len(signals) {
+				t.Fatalf("Expected %d results, got %d", len(signals), len(result.Results))
+			}
+			return result
+		}
+
+		t.Run("draft_config", func(t *testing.T) {
+			draft := handlers.UpdateSignalRoutingConfigRequest{
+				RoutingField: "test",
+				RoutingMode:  "all_matches",
+				RoutingRules: []handlers.SignalRoutingRule{
+					{MatchPattern: "*value*", Operator: "matches", IsnSlug: isn.Slug, Sequence: 1},
+					{MatchPattern: "a value", Operator: "equals", IsnSlug: otherIsn.Slug, Sequence: 2},
+				},
+			}
+			resp := dryRunSignalRoutingConfig(t, testEnv.baseURL, dryRunToken, slug, semVer, handlers.SignalRoutingDryRunRequest{
+				RoutingConfig: &draft,
+				Signals:       signals,
+			})
+			defer resp.Body.Close()
+			result := decodeDryRunResponse(t, resp)
+
+			if result.ConfigSource != "draft" || result.RoutingMode != "all_matches" {
+				t.Errorf("expected draft config in all_matches mode, got %q %q", result.ConfigSource, result.RoutingMode)
+			}
+
+			matching := result.Results[0]
+			if matching.LocalRef != "matching" {
+				t.Errorf("local_ref: got %q, want matching", matching.LocalRef)
+			}
+			if !reflect.DeepEqual(matching.MatchedRules, []int32{1, 2}) {
+				t.Errorf("matched_rules: got %v, want [1 2]", matching.MatchedRules)
+			}
+			if len(matching.FieldValues) != 1 || !matching.FieldValues[0].Found || string(matching.FieldValues[0].Value) != `"a value"` {
+				t.Errorf("unexpected field values %+v", matching.FieldValues)
+			}
+			if len(matching.Targets) != 2 {
+				t.Fatalf("Expected 2 targets, got %+v", matching.Targets)
+			}
+			if matching.Targets[0].IsnSlug != isn.Slug || !matching.Targets[0].CanWrite {
+				t.Errorf("expected to be able to write to %s, got %+v", isn.Slug, matching.Targets[0])
+			}
+			if matching.Targets[1].IsnSlug != otherIsn.Slug || matching.Targets[1].CanWrite || matching.Targets[1].Reason == "" {
+				t.Errorf("expected not to be able to write to %s, got %+v", otherIsn.Slug, matching.Targets[1])
+			}
+
+			for _, unroutable := range result.Results[1:] {
+				if len(unroutable.MatchedRules) != 0 || len(unroutable.Targets) != 0 {
+					t.Errorf("expected %s to be unroutable, got %+v", unroutable.LocalRef, unroutable)
+				}
+			}
+			if result.Results[2].FieldValues[0].Found {
+				t.Errorf("expected field not to be found for missing-field, got %+v", result.Results[2].FieldValues)
+			}
+		})
+
+		t.Run("saved_config", func(t *testing.T) {
+			setRoutingConfig(t, testEnv, siteAdminToken, signalType, compoundRequest)
+
+			resp := dryRunSignalRoutingConfig(t, testEnv.baseURL, dryRunToken, slug, semVer, handlers.SignalRoutingDryRunRequest{
+				Signals: signals,
+			})
+			defer resp.Body.Close()
+			result := decodeDryRunResponse(t, resp)
+
+			if result.ConfigSource != "saved" || result.RoutingMode != "first_match" {
+				t.Errorf("expected saved config in first_match mode, got %q %q", result.ConfigSource, result.RoutingMode)
+			}
+			if len(result.Results[0].Targets) != 1 || result.Results[0].Targets[0].IsnSlug != isn.Slug {
+				t.Errorf("expected matching signal to be routed to %s, got %+v", isn.Slug, result.Results[0].Targets)
+			}
+			if len(result.Results[1].Targets) != 0 {
+				t.Errorf("expected not-matching signal to be unroutable, got %+v", result.Results[1].Targets)
+			}
+		})
+
+		invalidDraft := validRequest
+		invalidDraft.RoutingField = "no_such_field"
+
+		testCases := []struct {
+			name           string
+			token          string
+			slug           string
+			body           handlers.SignalRoutingDryRunRequest
+			expectedStatus int
+		}{
+			{
+				name:           "member_cannot_dry_run",
+				token:          memberToken,
+				slug:           slug,
+				body:           handlers.SignalRoutingDryRunRequest{Signals: signals},
+				expectedStatus: http.StatusForbidden,
+			},
+			{
+				name:           "no_signals",
+				token:          dryRunToken,
+				slug:           slug,
+				body:           handlers.SignalRoutingDryRunRequest{RoutingConfig: &validRequest},
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:           "signal_without_content",
+				token:          dryRunToken,
+				slug:           slug,
+				body:           handlers.SignalRoutingDryRunRequest{RoutingConfig: &validRequest, Signals: []handlers.SignalRoutingDryRunSignal{{LocalRef: "empty"}}},
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:           "invalid_draft_config",
+				token:          dryRunToken,
+				slug:           slug,
+				body:           handlers.SignalRoutingDryRunRequest{RoutingConfig: &invalidDraft, Signals: signals},
+				expectedStatus: http.StatusBadRequest,
+			},
+			{
+				name:           "unknown_signal_type",
+				token:          dryRunToken,
+				slug:           "no-such-type",
+				body:           handlers.SignalRoutingDryRunRequest{Signals: signals},
+				expectedStatus: http.StatusNotFound,
+			},
+		}
+
+		for _, tt := range testCases {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := dryRunSignalRoutingConfig(t, testEnv.baseURL, tt.token, tt.slug, semVer, tt.body)
+				defer resp.Body.Close()
+				if resp.StatusCode != tt.expectedStatus {
+					t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+				}
+			})
+		}
+	})
+
+	t.Run("Revisions", func(t *testing.T) {
+		// use a separate signal type so the revision numbers are not affected by the other tests
+		revisionsSignalType := createTestSignalType(t, ctx, testEnv.queries, isn.ID, "revisions signal type", "")
+		if err := testEnv.schemaCache.Load(ctx); err != nil {
+			t.Fatalf("Failed to refresh schema cache: %v", err)
+		}
+		revSlug := revisionsSignalType.Slug
+		revSemVer := revisionsSignalType.SemVer
+
+		// revision 1: validRequest, revision 2: changed pattern and routing mode, revision 3: deleted
+		setRoutingConfig(t, testEnv, siteAdminToken, revisionsSignalType, validRequest)
+		setRoutingConfig(t, testEnv, siteAdminToken, revisionsSignalType, handlers.UpdateSignalRoutingConfigRequest{
+			RoutingField: "test",
+			RoutingMode:  "all_matches",
+			RoutingRules: []handlers.SignalRoutingRule{
+				{MatchPattern: "*other*", Operator: "matches", IsnSlug: isn.Slug, Sequence: 1},
+			},
+		})
+		deleteResp := deleteSignalRoutingConfig(t, testEnv.baseURL, siteAdminToken, revSlug, revSemVer)
+		deleteResp.Body.Close()
+		if deleteResp.StatusCode != http.StatusNoContent {
+			t.Fatalf("delete: want 204, got %d", deleteResp.StatusCode)
+		}
+
+		getRevision := func(t *testing.T, revision int) handlers.RoutingConfigRevisionDetails {
+			t.Helper()
+			resp := routingConfigRevisionsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodGet, revSlug, revSemVer, fmt.Sprintf("/%d", revision))
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("get revision %d: want 200, got %d", revision, resp.StatusCode)
+			}
+			var details handlers.RoutingConfigRevisionDetails
+			if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			return details
+		}
+
+		changeSummary := func(changes []handlers.RoutingConfigChange) []string {
+			summary := make([]string, len(changes))
+			for i, change := range changes {
+				summary[i] = change.Field + " " + change.Change
+			}
+			return summary
+		}
+
+		t.Run("list_revisions", func(t *testing.T) {
+			resp := routingConfigRevisionsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodGet, revSlug, revSemVer, "")
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("want 200, got %d", resp.StatusCode)
+			}
+			var revisions []handlers.RoutingConfigRevision
+			if err := json.NewDecoder(resp.Body).Decode(&revisions); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			var got []string
+			for _, revision := range revisions {
+				got = append(got, fmt.Sprintf("%d %s", revision.Revision, revision.Action))
+				if revision.AccountID == nil || *revision.AccountID != siteAdminAccount.ID {
+					t.Errorf("revision %d: expected account %s, got %v", revision.Revision, siteAdminAccount.ID, revision.AccountID)
+				}
+			}
+			want := []string{"3 deleted", "2 updated", "1 updated"}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("revisions: want %v, got %v", want, got)
+			}
+		})
+
+		t.Run("revision_diff", func(t *testing.T) {
+			first := getRevision(t, 1)
+			if first.Config == nil || len(first.Config.RoutingRules) != 1 || first.Config.RoutingRules[0].MatchPattern != "*value*" {
+				t.Errorf("revision 1: unexpected config %+v", first.Config)
+			}
+			if want := []string{"routing_field added", "routing_mode added", "routing_rule added"}; !reflect.DeepEqual(changeSummary(first.Changes), want) {
+				t.Errorf("revision 1 changes: want %v, got %v", want, changeSummary(first.Changes))
+			}
+
+			second := getRevision(t, 2)
+			if want := []string{"routing_mode changed", "routing_rule changed"}; !reflect.DeepEqual(changeSummary(second.Changes), want) {
+				t.Fatalf("revision 2 changes: want %v, got %v", want, changeSummary(second.Changes))
+			}
+			if second.Changes[0].Previous != "first_match" || second.Changes[0].New != "all_matches" {
+				t.Errorf("routing_mode change: want first_match -> all_matches, got %v -> %v", second.Changes[0].Previous, second.Changes[0].New)
+			}
+			if second.Changes[1].Sequence == nil || *second.Changes[1].Sequence != 1 {
+				t.Errorf("routing_rule change: expected sequence 1, got %v", second.Changes[1].Sequence)
+			}
+
+			third := getRevision(t, 3)
+			if third.Config != nil {
+				t.Errorf("revision 3: expected no config for a deletion, got %+v", third.Config)
+			}
+			if want := []string{"routing_field removed", "routing_mode removed", "routing_rule removed"}; !reflect.DeepEqual(changeSummary(third.Changes), want) {
+				t.Errorf("revision 3 changes: want %v, got %v", want, changeSummary(third.Changes))
+			}
+		})
+
+		t.Run("rollback", func(t *testing.T) {
+			resp := routingConfigRevisionsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodPost, revSlug, revSemVer, "/1/rollback")
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("want 200, got %d", resp.StatusCode)
+			}
+			var revision handlers.RoutingConfigRevision
+			if err := json.NewDecoder(resp.Body).Decode(&revision); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if revision.Revision != 4 || revision.Action != "rolled_back" || revision.RolledBackToRevision == nil || *revision.RolledBackToRevision != 1 {
+				t.Errorf("unexpected rollback revision %+v", revision)
+			}
+
+			// the config from revision 1 is restored
+			getResp := getSignalRoutingConfig(t, testEnv.baseURL, siteAdminToken, revSlug, revSemVer)
+			defer getResp.Body.Close()
+			var config handlers.SignalRoutingConfigResponse
+			if err := json.NewDecoder(getResp.Body).Decode(&config); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if config.RoutingMode != "first_match" || len(config.RoutingRules) != 1 || config.RoutingRules[0].MatchPattern != "*value*" {
+				t.Errorf("expected the revision 1 config to be restored, got %+v", config)
+			}
+
+			// the router cache is reloaded by the rollback
+			targets := testEnv.routerCache.Resolve(fmt.Sprintf("%s/v%s", revSlug, revSemVer), json.RawMessage(`{"test": "some value"}`), router.RoutingContext{})
+			if len(targets) != 1 || targets[0].IsnSlug != isn.Slug {
+				t.Errorf("expected the restored rules to be used by the router, got %+v", targets)
+			}
+		})
+
+		testCases := []struct {
+			name           string
+			token          string
+			method         string
+			slug           string
+			path           string
+			expectedStatus int
+		}{
+			{"member_cannot_list_revisions", memberToken, http.MethodGet, revSlug, "", http.StatusForbidden},
+			{"member_cannot_rollback", memberToken, http.MethodPost, revSlug, "/1/rollback", http.StatusForbidden},
+			{"unknown_signal_type", siteAdminToken, http.MethodGet, "no-such-type", "", http.StatusNotFound},
+			{"invalid_revision_number", siteAdminToken, http.MethodGet, revSlug, "/abc", http.StatusBadRequest},
+			{"unknown_revision", siteAdminToken, http.MethodGet, revSlug, "/99", http.StatusNotFound},
+			{"cannot_rollback_to_a_deletion", siteAdminToken, http.MethodPost, revSlug, "/3/rollback", http.StatusBadRequest},
+		}
+		for _, tt := range testCases {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := routingConfigRevisionsRequest(t, testEnv.baseURL, tt.token, tt.method, tt.slug, revSemVer, tt.path)
+				defer resp.Body.Close()
+				if resp.StatusCode != tt.expectedStatus {
+					t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+				}
+			})
+		}
+	})
+
 	t.Run("Delete", func(t *testing.T) {
 		t.Run("removes_routing_config", func(t *testing.T) {
 			setRoutingConfig(t, testEnv, siteAdminToken, signalType, validRequest)
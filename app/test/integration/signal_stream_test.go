@@ -0,0 +1,264 @@
+//go:build integration
+
+package integration
+
+// tests
+// - the signal stream sends new signal versions as server-sent events
+// - clients can resume the stream using the Last-Event-ID header
+// - write-only accounts only receive the signals they created
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+type signalStreamEvent struct {
+	id     string
+	signal handlers.SearchSignal
+}
+
+// signalStream reads the events sent on an open signal stream
+type signalStream struct {
+	resp   *http.Response
+	events chan signalStreamEvent
+}
+
+func openSignalStream(t *testing.T, baseURL string, endpoint testSignalEndpoint, token, lastEventID string) *http.Response {
+	t.Helper()
+
+	url := fmt.Sprintf("%s/api/isn/%s/signal-types/%s/v%s/signals/stream",
+		baseURL, endpoint.isnSlug, endpoint.signalTypeSlug, endpoint.signalTypeSemVer)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	return resp
+}
+
+// startSignalStream opens the stream and starts reading events in the background. The stream is closed when the test ends.
+func startSignalStream(t *testing.T, baseURL string, endpoint testSignalEndpoint, token, lastEventID string) *signalStream {
+	t.Helper()
+
+	resp := openSignalStream(t, baseURL, endpoint, token, lastEventID)
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		t.Fatalf("expected status %d opening signal stream, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("expected content type text/event-stream, got %s", contentType)
+	}
+
+	stream := &signalStream{
+		resp:   resp,
+		events: make(chan signalStreamEvent, 100),
+	}
+	t.Cleanup(stream.close)
+
+	go func() {
+		defer close(stream.events)
+
+		scanner := bufio.NewScanner(resp.Body)
+		var event signalStreamEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				event.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.signal); err != nil {
+					t.Errorf("could not decode event data %q: %v", line, err)
+				}
+			case line == "" && event.id != "":
+				stream.events <- event
+				event = signalStreamEvent{}
+			}
+		}
+	}()
+
+	return stream
+}
+
+func (s *signalStream) close() {
+	s.resp.Body.Close()
+}
+
+// nextEvent waits for the next event on the stream
+func (s *signalStream) nextEvent(t *testing.T, timeout time.Duration) signalStreamEvent {
+	t.Helper()
+	select {
+	case event, ok := <-s.events:
+		if !ok {
+			t.Fatal("signal stream closed unexpectedly")
+		}
+		return event
+	case <-time.After(timeout):
+		t.Fatal("timed out waiting for signal stream event")
+	}
+	return signalStreamEvent{}
+}
+
+// expectNoEvent checks that no events are received within the wait period
+func (s *signalStream) expectNoEvent(t *testing.T, wait time.Duration) {
+	t.Helper()
+	select {
+	case event, ok := <-s.events:
+		if ok {
+			t.Errorf("expected no events, got signal %s", event.signal.LocalRef)
+		}
+	case <-time.After(wait):
+	}
+}
+
+func TestSignalStream(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	siteAdminAccount := createTestAccount(t, ctx, testEnv.queries, "siteadmin", "user", "siteadmin@stream-test.com")
+	readerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "reader@stream-test.com")
+	writerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "writer@stream-test.com")
+	otherWriterAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "other-writer@stream-test.com")
+	nonMemberAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "non-member@stream-test.com")
+
+	isn := createTestISN(t, ctx, testEnv.queries, "stream-isn", "Stream ISN", siteAdminAccount.ID, "private")
+	signalType := createTestSignalType(t, ctx, testEnv.queries, isn.ID, "stream test signal", "")
+
+	grantPermission(t, ctx, testEnv.queries, isn.ID, readerAccount.ID, "read")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, writerAccount.ID, "write")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, otherWriterAccount.ID, "write")
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("schemaCache.Load: %v", err)
+	}
+
+	endpoint := testSignalEndpoint{
+		isnSlug:          isn.Slug,
+		signalTypeSlug:   signalType.Slug,
+		signalTypeSemVer: signalType.SemVer,
+	}
+
+	readerToken := testEnv.createAuthToken(t, readerAccount.ID)
+	writerToken := testEnv.createAuthToken(t, writerAccount.ID)
+	otherWriterToken := testEnv.createAuthToken(t, otherWriterAccount.ID)
+	nonMemberToken := testEnv.createAuthToken(t, nonMemberAccount.ID)
+
+	submitSignal := func(t *testing.T, payload map[string]any, token string) {
+		t.Helper()
+		resp := submitCreateSignalRequest(t, testEnv.baseURL, payload, token, endpoint)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d submitting signal, got %d", http.StatusOK, resp.StatusCode)
+		}
+	}
+
+	t.Run("stream_access", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			token          string
+			lastEventID    string
+			expectedStatus int
+		}{
+			{"unauthenticated", "", "", http.StatusUnauthorized},
+			{"non_member", nonMemberToken, "", http.StatusForbidden},
+			{"invalid_last_event_id", readerToken, "not-a-valid-id", http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := openSignalStream(t, testEnv.baseURL, endpoint, tt.token, tt.lastEventID)
+				resp.Body.Close()
+				if resp.StatusCode != tt.expectedStatus {
+					t.Errorf("expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+				}
+			})
+		}
+	})
+
+	var lastEventID string
+
+	t.Run("new_signals_are_streamed", func(t *testing.T) {
+		stream := startSignalStream(t, testEnv.baseURL, endpoint, readerToken, "")
+
+		submitSignal(t, createValidSignalPayload("stream-signal-1"), writerToken)
+		submitSignal(t, createValidSignalPayload("stream-signal-2"), otherWriterToken)
+
+		first := stream.nextEvent(t, 10*time.Second)
+		if first.signal.LocalRef != "stream-signal-1" {
+			t.Errorf("expected first event for stream-signal-1, got %s", first.signal.LocalRef)
+		}
+		if first.signal.AccountID != writerAccount.ID {
+			t.Errorf("expected account_id %s, got %s", writerAccount.ID, first.signal.AccountID)
+		}
+		if first.signal.VersionNumber != 1 {
+			t.Errorf("expected version 1, got %d", first.signal.VersionNumber)
+		}
+
+		second := stream.nextEvent(t, 10*time.Second)
+		if second.signal.LocalRef != "stream-signal-2" {
+			t.Errorf("expected second event for stream-signal-2, got %s", second.signal.LocalRef)
+		}
+
+		lastEventID = second.id
+	})
+
+	t.Run("stream_resumes_from_last_event_id", func(t *testing.T) {
+		if lastEventID == "" {
+			t.Skip("no event id from previous test")
+		}
+
+		// signals stored while the client is disconnected
+		submitSignal(t, createValidSignalPayload("stream-signal-3"), writerToken)
+		submitSignal(t, createUpdatedSignalPayload("stream-signal-1"), writerToken) // new version of an existing signal
+
+		stream := startSignalStream(t, testEnv.baseURL, endpoint, readerToken, lastEventID)
+
+		event := stream.nextEvent(t, 10*time.Second)
+		if event.signal.LocalRef != "stream-signal-3" {
+			t.Errorf("expected first resumed event for stream-signal-3, got %s", event.signal.LocalRef)
+		}
+
+		event = stream.nextEvent(t, 10*time.Second)
+		if event.signal.LocalRef != "stream-signal-1" || event.signal.VersionNumber != 2 {
+			t.Errorf("expected stream-signal-1 version 2, got %s version %d", event.signal.LocalRef, event.signal.VersionNumber)
+		}
+
+		stream.expectNoEvent(t, 2*time.Second)
+	})
+
+	t.Run("write_only_accounts_only_receive_their_own_signals", func(t *testing.T) {
+		stream := startSignalStream(t, testEnv.baseURL, endpoint, writerToken, "")
+
+		submitSignal(t, createValidSignalPayload("stream-signal-other"), otherWriterToken)
+		submitSignal(t, createValidSignalPayload("stream-signal-own"), writerToken)
+
+		event := stream.nextEvent(t, 10*time.Second)
+		if event.signal.LocalRef != "stream-signal-own" {
+			t.Errorf("expected event for stream-signal-own, got %s", event.signal.LocalRef)
+		}
+		if event.signal.AccountID != writerAccount.ID {
+			t.Errorf("expected account_id %s, got %s", writerAccount.ID, event.signal.AccountID)
+		}
+
+		stream.expectNoEvent(t, 2*time.Second)
+	})
+}
@@ -0,0 +1,281 @@
+//go:build integration
+
+package integration
+
+// tests
+// - signal submissions made with a Prefer: respond-async header are queued and a 202 is returned with the batch status URL
+// - queued submissions are processed by the ingestion worker and the outcome is reported by the batch status endpoint
+// - signals that fail validation in a queued submission are reported as batch failures
+// - invalid requests are rejected before they are queued
+// - the account's permissions are reloaded when a queued submission is processed (write access revoked after the request was queued is applied)
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+// submitAsyncSignalsRequest submits signals with a Prefer: respond-async header
+func submitAsyncSignalsRequest(t *testing.T, baseURL string, endpoint testSignalEndpoint, token string, payload map[string]any) *http.Response {
+	t.Helper()
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/api/isn/%s/signal-types/%s/v%s/signals",
+		baseURL, endpoint.isnSlug, endpoint.signalTypeSlug, endpoint.signalTypeSemVer)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Prefer", "respond-async")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to submit signals: %v", err)
+	}
+	return resp
+}
+
+// waitForQueuedSubmission polls the batch status endpoint until the queued submission has been processed and returns the final batch status
+func waitForQueuedSubmission(t *testing.T, baseURL, statusURL, token string, submission handlers.QueuedSignalSubmissionResponse) (handlers.BatchStatusResponse, handlers.QueuedSubmissionStatus) {
+	t.Helper()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		req, err := http.NewRequest(http.MethodGet, baseURL+statusURL, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to get batch status: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			t.Fatalf("expected status %d from batch status endpoint, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		var batchStatus handlers.BatchStatusResponse
+		err = json.NewDecoder(resp.Body).Decode(&batchStatus)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode batch status: %v", err)
+		}
+
+		for _, queued := range batchStatus.QueuedSubmissions {
+			if queued.SubmissionID == submission.SubmissionID && queued.Status != "queued" {
+				return batchStatus, queued
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	t.Fatalf("queued submission %s was not processed", submission.SubmissionID)
+	return handlers.BatchStatusResponse{}, handlers.QueuedSubmissionStatus{}
+}
+
+func TestAsyncSignalSubmission(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	writerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "writer@async-test.com")
+	readerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "reader@async-test.com")
+
+	isn := createTestISN(t, ctx, testEnv.queries, "async-isn", "Async ISN", writerAccount.ID, "private")
+	signalType := createTestSignalType(t, ctx, testEnv.queries, isn.ID, "async test signal", "")
+
+	grantPermission(t, ctx, testEnv.queries, isn.ID, writerAccount.ID, "write")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, readerAccount.ID, "read")
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("Failed to refresh schema cache: %v", err)
+	}
+
+	writerToken := testEnv.createAuthToken(t, writerAccount.ID)
+	readerToken := testEnv.createAuthToken(t, readerAccount.ID)
+
+	endpoint := testSignalEndpoint{
+		isnSlug:          isn.Slug,
+		signalTypeSlug:   signalType.Slug,
+		signalTypeSemVer: signalType.SemVer,
+	}
+
+	decodeQueuedResponse := func(t *testing.T, resp *http.Response) handlers.QueuedSignalSubmissionResponse {
+		t.Helper()
+		var response handlers.QueuedSignalSubmissionResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("queued_submission_is_processed", func(t *testing.T) {
+		payload := createValidSignalPayload("async-signal-1")
+		payload["batch_ref"] = "async-batch-1"
+
+		resp := submitAsyncSignalsRequest(t, testEnv.baseURL, endpoint, writerToken, payload)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d", http.StatusAccepted, resp.StatusCode)
+		}
+		if resp.Header.Get("Preference-Applied") != "respond-async" {
+			t.Errorf("expected Preference-Applied header to be respond-async, got %q", resp.Header.Get("Preference-Applied"))
+		}
+
+		submission := decodeQueuedResponse(t, resp)
+		statusURL := "/api/batches/async-batch-1/status"
+		if resp.Header.Get("Location") != statusURL || submission.StatusURL != statusURL {
+			t.Errorf("expected status URL %s, got Location %q and status_url %q", statusURL, resp.Header.Get("Location"), submission.StatusURL)
+		}
+		if submission.BatchRef != "async-batch-1" || submission.Status != "queued" || submission.SignalCount != 1 {
+			t.Errorf("unexpected queued submission response %+v", submission)
+		}
+
+		batchStatus, queued := waitForQueuedSubmission(t, testEnv.baseURL, statusURL, writerToken, submission)
+		if queued.Status != "completed" {
+			t.Fatalf("expected submission to be completed, got %s (last error: %v)", queued.Status, queued.LastError)
+		}
+		if queued.ResponseStatus == nil || *queued.ResponseStatus != http.StatusOK {
+			t.Errorf("expected response status %d, got %v", http.StatusOK, queued.ResponseStatus)
+		}
+		if queued.IsnSlug != isn.Slug {
+			t.Errorf("expected isn %s, got %s", isn.Slug, queued.IsnSlug)
+		}
+		if batchStatus.ContainsFailures {
+			t.Error("did not expect failures in the batch")
+		}
+		if len(batchStatus.BatchStatus) != 1 || batchStatus.BatchStatus[0].StoredCount != 1 {
+			t.Errorf("expected 1 stored signal in the batch, got %+v", batchStatus.BatchStatus)
+		}
+
+		// the signal can be found using the standard search endpoint
+		searchResp := searchPrivateSignalsWithParams(t, testEnv.baseURL, endpoint, readerToken, nil)
+		defer searchResp.Body.Close()
+		var signals []map[string]any
+		if err := json.NewDecoder(searchResp.Body).Decode(&signals); err != nil {
+			t.Fatalf("Failed to decode search response: %v", err)
+		}
+		if len(signals) != 1 || signals[0]["local_ref"] != "async-signal-1" {
+			t.Errorf("expected async-signal-1 in search results, got %v", signals)
+		}
+	})
+
+	t.Run("signal_failures_are_reported_in_batch_status", func(t *testing.T) {
+		payload := createInvalidSignalPayload("async-signal-2")
+		payload["batch_ref"] = "async-batch-2"
+
+		resp := submitAsyncSignalsRequest(t, testEnv.baseURL, endpoint, writerToken, payload)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d", http.StatusAccepted, resp.StatusCode)
+		}
+		submission := decodeQueuedResponse(t, resp)
+
+		batchStatus, queued := waitForQueuedSubmission(t, testEnv.baseURL, submission.StatusURL, writerToken, submission)
+		if queued.Status != "completed" {
+			t.Fatalf("expected submission to be completed, got %s (last error: %v)", queued.Status, queued.LastError)
+		}
+		if queued.ResponseStatus == nil || *queued.ResponseStatus != http.StatusUnprocessableEntity {
+			t.Errorf("expected response status %d, got %v", http.StatusUnprocessableEntity, queued.ResponseStatus)
+		}
+		if !batchStatus.ContainsFailures {
+			t.Error("expected the batch to contain failures")
+		}
+		if len(batchStatus.BatchStatus) != 1 || len(batchStatus.BatchStatus[0].UnresolvedFailures) != 1 || batchStatus.BatchStatus[0].UnresolvedFailures[0].LocalRef != "async-signal-2" {
+			t.Errorf("expected async-signal-2 to be reported as a failure, got %+v", batchStatus.BatchStatus)
+		}
+	})
+
+	t.Run("invalid_requests_are_not_queued", func(t *testing.T) {
+		resp := submitAsyncSignalsRequest(t, testEnv.baseURL, endpoint, writerToken, map[string]any{"batch_ref": "async-batch-3"})
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+		}
+	})
+
+	t.Run("revoked_permission_is_applied_when_processed", func(t *testing.T) {
+		// queue a submission with claims granting write access to an account that no longer has access to the ISN
+		revokedAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "revoked@async-test.com")
+		revokedToken := testEnv.createAuthToken(t, revokedAccount.ID)
+
+		batch, err := testEnv.queries.UpsertSignalBatch(ctx, database.UpsertSignalBatchParams{
+			BatchRef:  "async-batch-4",
+			AccountID: revokedAccount.ID,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create batch: %v", err)
+		}
+
+		claimsJSON, err := json.Marshal(auth.Claims{
+			AccountID:   revokedAccount.ID,
+			AccountType: "user",
+			Role:        "member",
+			IsnPerms: map[string]auth.IsnPerm{
+				isn.Slug: {CanWrite: true, InUse: true},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal claims: %v", err)
+		}
+		payload := createValidSignalPayload("async-signal-4")
+		payload["batch_ref"] = "async-batch-4"
+		requestBody, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("Failed to marshal payload: %v", err)
+		}
+
+		queued, err := testEnv.queries.CreateQueuedSignalSubmission(ctx, database.CreateQueuedSignalSubmissionParams{
+			AccountID:      revokedAccount.ID,
+			SignalBatchID:  batch.ID,
+			IsnSlug:        &isn.Slug,
+			SignalTypeSlug: signalType.Slug,
+			SemVer:         signalType.SemVer,
+			Claims:         claimsJSON,
+			RequestBody:    requestBody,
+		})
+		if err != nil {
+			t.Fatalf("Failed to queue submission: %v", err)
+		}
+
+		submission := handlers.QueuedSignalSubmissionResponse{SubmissionID: queued.ID}
+		_, processed := waitForQueuedSubmission(t, testEnv.baseURL, "/api/batches/async-batch-4/status", revokedToken, submission)
+		if processed.Status != "completed" {
+			t.Fatalf("expected submission to be completed, got %s (last error: %v)", processed.Status, processed.LastError)
+		}
+		if processed.ResponseStatus == nil || *processed.ResponseStatus != http.StatusForbidden {
+			t.Errorf("expected response status %d, got %v", http.StatusForbidden, processed.ResponseStatus)
+		}
+
+		searchResp := searchPrivateSignalsWithParams(t, testEnv.baseURL, endpoint, readerToken, nil)
+		defer searchResp.Body.Close()
+		var signals []map[string]any
+		if err := json.NewDecoder(searchResp.Body).Decode(&signals); err != nil {
+			t.Fatalf("Failed to decode search response: %v", err)
+		}
+		for _, signal := range signals {
+			if signal["local_ref"] == "async-signal-4" {
+				t.Errorf("expected async-signal-4 not to be stored")
+			}
+		}
+	})
+}
@@ -9,6 +9,9 @@ package integration
 // Signal search with authorization controls
 // Public vs private ISN access
 // Withdrawn signal handling
+// Search pagination
+// Search content filters
+// Unchanged signal resubmissions
 // Token validation (expired, malformed, missing)
 // Cross-ISN data leakage prevention
 
@@ -26,6 +29,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
 	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
 )
 
 // TestSignalSubmission tests the signal submission process end-to-end including:
@@ -497,6 +501,7 @@ func TestSignalSubmission(t *testing.T) {
 						// Get the correlated signal details from database
 						correlatedSignal, err := testEnv.queries.GetSignalCorrelationDetails(ctx, database.GetSignalCorrelationDetailsParams{
 							AccountID: tt.accountID,
+							IsnSlug:   tt.endpoint.isnSlug,
 							Slug:      tt.endpoint.signalTypeSlug,
 							SemVer:    tt.endpoint.signalTypeSemVer,
 							LocalRef:  localRef,
@@ -1192,6 +1197,29 @@ func TestWriteOnlyAccountVisibility(t *testing.T) {
 			}
 		}
 	})
+
+	// account 1's signal is the first result in ascending order, so the first page for account 2 would be empty if
+	// the other accounts' signals were removed after the page was selected.
+	t.Run("write-only account pages only include own signals", func(t *testing.T) {
+		response := searchPrivateSignalsWithParams(t, testEnv.baseURL, endpoint, token2, map[string]string{"limit": "1", "order": "asc"})
+		defer response.Body.Close()
+
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Search failed: %d", response.StatusCode)
+		}
+
+		var signals []map[string]any
+		if err := json.NewDecoder(response.Body).Decode(&signals); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		if len(signals) != 1 || signals[0]["local_ref"] != "account2-signal-001" {
+			t.Fatalf("Expected account2-signal-001 on the first page, got %v", signals)
+		}
+		if nextPageToken := response.Header.Get("Signalsd-Next-Page-Token"); nextPageToken != "" {
+			t.Errorf("Expected no next page token, got %q", nextPageToken)
+		}
+	})
 }
 
 // TestCorrelatedAndPreviousVersionsSearch tests the include_correlated and include_previous_versions functionality
@@ -1244,6 +1272,7 @@ func TestCorrelatedAndPreviousVersionsSearch(t *testing.T) {
 	}
 
 	// create a second version of master-002
+	payload = createUpdatedSignalPayload(master002LocalRef)
 	master002SignalResponseV2 := submitCreateSignalRequest(t, testEnv.baseURL, payload, ownerAuthToken, ownerEndpoint)
 	defer master002SignalResponseV2.Body.Close()
 	if master002SignalResponseV2.StatusCode != http.StatusOK {
@@ -1390,6 +1419,413 @@ type testSignalEndpoint struct {
 	signalTypeSemVer string
 }
 
+// TestSignalSearchPagination tests the limit, order and page_token search parameters:
+// - results are returned in pages with a next page token header when there are more results
+// - results can be ordered by version_created_at in ascending or descending order
+// - invalid pagination parameters are rejected
+func TestSignalSearchPagination(t *testing.T) {
+	ctx := context.Background()
+
+	testEnv := startInProcessServer(t, "")
+
+	account := createTestAccount(t, ctx, testEnv.queries, "member", "user", "paging@signal-search.com")
+	isn := createTestISN(t, ctx, testEnv.queries, "pagination-isn", "Pagination ISN", account.ID, "private")
+	signalType := createTestSignalType(t, ctx, testEnv.queries, isn.ID, "pagination signal type", "")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, account.ID, "read-write")
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("Failed to refresh schema cache: %v", err)
+	}
+
+	token := testEnv.createAuthToken(t, account.ID)
+
+	endpoint := testSignalEndpoint{
+		isnSlug:          isn.Slug,
+		signalTypeSlug:   signalType.Slug,
+		signalTypeSemVer: signalType.SemVer,
+	}
+
+	localRefs := []string{"page-signal-1", "page-signal-2", "page-signal-3", "page-signal-4", "page-signal-5"}
+	for _, localRef := range localRefs {
+		resp := submitCreateSignalRequest(t, testEnv.baseURL, createValidSignalPayload(localRef), token, endpoint)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("failed to submit signal %s: %d", localRef, resp.StatusCode)
+		}
+	}
+
+	// searchPage returns the local refs in a page of results and the next page token
+	searchPage := func(t *testing.T, params map[string]string) ([]string, string) {
+		t.Helper()
+
+		resp := searchPrivateSignalsWithParams(t, testEnv.baseURL, endpoint, token, params)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		var signals []map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&signals); err != nil {
+			t.Fatalf("Failed to decode search response: %v", err)
+		}
+
+		refs := make([]string, 0, len(signals))
+		for _, signal := range signals {
+			refs = append(refs, signal["local_ref"].(string))
+		}
+		return refs, resp.Header.Get("Signalsd-Next-Page-Token")
+	}
+
+	tests := []struct {
+		name     string
+		order    string
+		expected []string
+	}{
+		{"ascending", "asc", localRefs},
+		{"descending", "desc", []string{"page-signal-5", "page-signal-4", "page-signal-3", "page-signal-2", "page-signal-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			pageToken := ""
+			for pages := 1; ; pages++ {
+				if pages > 3 {
+					t.Fatal("expected 3 pages of results")
+				}
+
+				params := map[string]string{"limit": "2", "order": tt.order}
+				if pageToken != "" {
+					params["page_token"] = pageToken
+				}
+
+				refs, nextPageToken := searchPage(t, params)
+				got = append(got, refs...)
+
+				if nextPageToken == "" {
+					if pages != 3 {
+						t.Errorf("expected 3 pages of results, got %d", pages)
+					}
+					break
+				}
+				if len(refs) != 2 {
+					t.Errorf("expected 2 signals on page %d, got %d", pages, len(refs))
+				}
+				pageToken = nextPageToken
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected signals %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("no_next_page_token_when_all_results_returned", func(t *testing.T) {
+		refs, nextPageToken := searchPage(t, map[string]string{"limit": "5"})
+		if len(refs) != 5 {
+			t.Errorf("expected 5 signals, got %d", len(refs))
+		}
+		if nextPageToken != "" {
+			t.Errorf("expected no next page token, got %s", nextPageToken)
+		}
+	})
+
+	t.Run("invalid_pagination_params", func(t *testing.T) {
+		invalidParams := []struct {
+			name   string
+			params map[string]string
+		}{
+			{"limit_too_small", map[string]string{"limit": "0"}},
+			{"limit_too_large", map[string]string{"limit": "1001"}},
+			{"limit_not_a_number", map[string]string{"limit": "ten"}},
+			{"invalid_order", map[string]string{"order": "sideways"}},
+			{"invalid_page_token", map[string]string{"page_token": "not-a-token"}},
+		}
+
+		for _, tt := range invalidParams {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := searchPrivateSignalsWithParams(t, testEnv.baseURL, endpoint, token, tt.params)
+				defer resp.Body.Close()
+
+				if resp.StatusCode != http.StatusBadRequest {
+					t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+				}
+			})
+		}
+	})
+}
+
+func TestSignalSearchContentFilters(t *testing.T) {
+	ctx := context.Background()
+
+	testEnv := startInProcessServer(t, "")
+
+	account := createTestAccount(t, ctx, testEnv.queries, "member", "user", "filters@signal-search.com")
+	isn := createTestISN(t, ctx, testEnv.queries, "content-filters-isn", "Content Filters ISN", account.ID, "private")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, account.ID, "read-write")
+
+	// the content filters are checked against the signal type schema so this test needs a schema with nested and numeric fields
+	signalType, err := testEnv.queries.CreateSignalType(ctx, database.CreateSignalTypeParams{
+		Slug:      "content-filters-signal-type",
+		SchemaURL: "https://github.com/information-sharing-networks/signal-library/blob/main/signalsd-testing/content-filters.json",
+		ReadmeURL: testReadmeURL,
+		Title:     "content filters signal type",
+		Detail:    testSignalTypeDetail,
+		SemVer:    "1.0.0",
+		SchemaContent: `{"type": "object", "properties": {
+			"status": {"type": "string"},
+			"payload": {"type": "object", "properties": {"PortOfEntry": {"type": "string"}, "weight": {"type": "number"}}}
+		}}`,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create signal type: %v", err)
+	}
+	addSignalTypeToIsn(t, ctx, testEnv.queries, isn.ID, signalType.ID)
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("Failed to refresh schema cache: %v", err)
+	}
+
+	token := testEnv.createAuthToken(t, account.ID)
+
+	endpoint := testSignalEndpoint{
+		isnSlug:          isn.Slug,
+		signalTypeSlug:   signalType.Slug,
+		signalTypeSemVer: signalType.SemVer,
+	}
+
+	signals := []map[string]any{
+		{"local_ref": "filter-signal-1", "content": map[string]any{"status": "approved", "payload": map[string]any{"PortOfEntry": "Felixstowe", "weight": 500}}},
+		{"local_ref": "filter-signal-2", "content": map[string]any{"status": "rejected", "payload": map[string]any{"PortOfEntry": "Felixstowe", "weight": 1000}}},
+		{"local_ref": "filter-signal-3", "content": map[string]any{"status": "pending", "payload": map[string]any{"PortOfEntry": "Dover", "weight": 12000}}},
+		{"local_ref": "filter-signal-4", "content": map[string]any{"status": "approved"}},
+	}
+	resp := submitCreateSignalRequest(t, testEnv.baseURL, map[string]any{"batch_ref": "test-batch", "signals": signals}, token, endpoint)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to submit signals: %d", resp.StatusCode)
+	}
+
+	tests := []struct {
+		name     string
+		params   map[string]string
+		expected []string
+	}{
+		{"equals", map[string]string{"content.payload.PortOfEntry": "Felixstowe"}, []string{"filter-signal-1", "filter-signal-2"}},
+		{"equals_operator", map[string]string{"content.payload.PortOfEntry__eq": "Dover"}, []string{"filter-signal-3"}},
+		{"not_equals_includes_missing_fields", map[string]string{"content.payload.PortOfEntry__ne": "Felixstowe"}, []string{"filter-signal-3", "filter-signal-4"}},
+		{"in", map[string]string{"content.status__in": "approved,rejected"}, []string{"filter-signal-1", "filter-signal-2", "filter-signal-4"}},
+		{"numeric_gte", map[string]string{"content.payload.weight__gte": "1000"}, []string{"filter-signal-2", "filter-signal-3"}},
+		{"numeric_lt", map[string]string{"content.payload.weight__lt": "1000"}, []string{"filter-signal-1"}},
+		{"numeric_equals", map[string]string{"content.payload.weight": "1000.0"}, []string{"filter-signal-2"}},
+		{"text_gt", map[string]string{"content.status__gt": "pending"}, []string{"filter-signal-2"}},
+		{"all_filters_must_match", map[string]string{"content.status": "approved", "content.payload.PortOfEntry": "Felixstowe"}, []string{"filter-signal-1"}},
+		{"no_matches", map[string]string{"content.payload.PortOfEntry": "Harwich"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := searchPrivateSignalsWithParams(t, testEnv.baseURL, endpoint, token, tt.params)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+
+			var results []map[string]any
+			if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+				t.Fatalf("Failed to decode search response: %v", err)
+			}
+
+			got := make([]string, 0, len(results))
+			for _, result := range results {
+				got = append(got, result["local_ref"].(string))
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected signals %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("invalid_content_filters", func(t *testing.T) {
+		invalidParams := []struct {
+			name   string
+			params map[string]string
+		}{
+			{"field_not_in_schema", map[string]string{"content.payload.PortOfExit": "Felixstowe"}},
+			{"unsupported_operator", map[string]string{"content.status__like": "app%"}},
+			{"missing_field_path", map[string]string{"content.__eq": "approved"}},
+			{"empty_path_segment", map[string]string{"content.payload..weight": "1000"}},
+			{"array_index", map[string]string{"content.payload.0": "1000"}},
+		}
+
+		for _, tt := range invalidParams {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := searchPrivateSignalsWithParams(t, testEnv.baseURL, endpoint, token, tt.params)
+				defer resp.Body.Close()
+
+				if resp.StatusCode != http.StatusBadRequest {
+					t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+				}
+			})
+		}
+	})
+}
+
+// TestUnchangedSignalVersions checks that resubmitting a signal with the same content does not create a new version
+func TestUnchangedSignalVersions(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	account := createTestAccount(t, ctx, testEnv.queries, "member", "user", "owner@unchanged-signals.com")
+	isn := createTestISN(t, ctx, testEnv.queries, "unchanged-signals-isn", "Unchanged Signals ISN", account.ID, "private")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, account.ID, "read-write")
+
+	// the content is compared as jsonb, so this test uses a schema with more than one field to check that key order is ignored
+	signalType, err := testEnv.queries.CreateSignalType(ctx, database.CreateSignalTypeParams{
+		Slug:      "unchanged-signal-type",
+		SchemaURL: "https://github.com/information-sharing-networks/signal-library/blob/main/signalsd-testing/unchanged.json",
+		ReadmeURL: testReadmeURL,
+		Title:     "unchanged signal type",
+		Detail:    testSignalTypeDetail,
+		SemVer:    "1.0.0",
+		SchemaContent: `{"type": "object", "properties": {
+			"status": {"type": "string"},
+			"weight": {"type": "number"}
+		}}`,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create signal type: %v", err)
+	}
+	addSignalTypeToIsn(t, ctx, testEnv.queries, isn.ID, signalType.ID)
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("Failed to refresh schema cache: %v", err)
+	}
+
+	token := testEnv.createAuthToken(t, account.ID)
+
+	endpoint := testSignalEndpoint{
+		isnSlug:          isn.Slug,
+		signalTypeSlug:   signalType.Slug,
+		signalTypeSemVer: signalType.SemVer,
+	}
+
+	// submit returns the stored signal and the unchanged count from the response
+	submit := func(t *testing.T, signal map[string]any) (handlers.StoredSignal, int) {
+		t.Helper()
+		resp := submitCreateSignalRequest(t, testEnv.baseURL, map[string]any{"batch_ref": "test-batch", "signals": []map[string]any{signal}}, token, endpoint)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		var response handlers.SignalSubmissionResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(response.Results) != 1 || len(response.Results[0].StoredSignals) != 1 {
+			t.Fatalf("expected 1 stored signal, got %+v", response.Results)
+		}
+		return response.Results[0].StoredSignals[0], response.Summary.UnchangedCount
+	}
+
+	original, _ := submit(t, map[string]any{
+		"local_ref": "unchanged-signal-1",
+		"content":   json.RawMessage(`{"status": "approved", "weight": 1500}`),
+	})
+	other, _ := submit(t, map[string]any{
+		"local_ref": "unchanged-signal-2",
+		"content":   json.RawMessage(`{"status": "approved"}`),
+	})
+
+	tests := []struct {
+		name              string
+		setup             func(t *testing.T)
+		signal            map[string]any
+		expectedUnchanged bool
+		expectedVersion   int32
+	}{
+		{
+			name: "same_content_with_different_key_order",
+			signal: map[string]any{
+				"local_ref": "unchanged-signal-1",
+				"content":   json.RawMessage(`{"weight": 1500, "status": "approved"}`),
+			},
+			expectedUnchanged: true,
+			expectedVersion:   1,
+		},
+		{
+			name: "changed_content",
+			signal: map[string]any{
+				"local_ref": "unchanged-signal-1",
+				"content":   json.RawMessage(`{"status": "approved", "weight": 1600}`),
+			},
+			expectedUnchanged: false,
+			expectedVersion:   2,
+		},
+		{
+			name: "changed_correlation_id",
+			signal: map[string]any{
+				"local_ref":      "unchanged-signal-1",
+				"correlation_id": other.SignalID,
+				"content":        json.RawMessage(`{"status": "approved", "weight": 1600}`),
+			},
+			expectedUnchanged: false,
+			expectedVersion:   3,
+		},
+		{
+			name: "withdrawn_signal_is_reactivated",
+			setup: func(t *testing.T) {
+				resp := withdrawSignal(t, testEnv.baseURL, endpoint, token, "unchanged-signal-1")
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusNoContent {
+					t.Fatalf("expected status %d withdrawing signal, got %d", http.StatusNoContent, resp.StatusCode)
+				}
+			},
+			signal: map[string]any{
+				"local_ref":      "unchanged-signal-1",
+				"correlation_id": other.SignalID,
+				"content":        json.RawMessage(`{"status": "approved", "weight": 1600}`),
+			},
+			expectedUnchanged: false,
+			expectedVersion:   4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+
+			stored, unchangedCount := submit(t, tt.signal)
+			if stored.Unchanged != tt.expectedUnchanged {
+				t.Errorf("expected unchanged to be %v, got %v", tt.expectedUnchanged, stored.Unchanged)
+			}
+			if stored.VersionNumber != tt.expectedVersion {
+				t.Errorf("expected version %d, got %d", tt.expectedVersion, stored.VersionNumber)
+			}
+			if stored.SignalID != original.SignalID {
+				t.Errorf("expected signal id %s, got %s", original.SignalID, stored.SignalID)
+			}
+
+			expectedUnchangedCount := 0
+			if tt.expectedUnchanged {
+				expectedUnchangedCount = 1
+				if stored.SignalVersionID != original.SignalVersionID {
+					t.Errorf("expected the existing signal version %s, got %s", original.SignalVersionID, stored.SignalVersionID)
+				}
+			}
+			if unchangedCount != expectedUnchangedCount {
+				t.Errorf("expected unchanged_count %d, got %d", expectedUnchangedCount, unchangedCount)
+			}
+		})
+	}
+}
+
 // createValidSignalPayload creates json valid for https://github.com/information-sharing-networks/signal-library/blob/main/signalsd-testing/simple.json
 func createValidSignalPayload(localRef string) map[string]any {
 	return map[string]any{
@@ -1405,6 +1841,21 @@ func createValidSignalPayload(localRef string) map[string]any {
 	}
 }
 
+// createUpdatedSignalPayload returns a payload with different content to createValidSignalPayload (resubmitting unchanged content does not create a new version)
+func createUpdatedSignalPayload(localRef string) map[string]any {
+	return map[string]any{
+		"batch_ref": "test-batch",
+		"signals": []map[string]any{
+			{
+				"local_ref": localRef,
+				"content": map[string]any{
+					"test": "updated content for simple schema",
+				},
+			},
+		},
+	}
+}
+
 func createValidSignalPayloadWithCorrelatedID(localRef string, CorrelationID string) map[string]any {
 	return map[string]any{
 		"batch_ref": "test-batch",
@@ -1653,6 +2104,35 @@ func searchPrivateSignals(t *testing.T, baseURL string, endpoint testSignalEndpo
 	return resp
 }
 
+// searchPrivateSignalsWithParams searches the last hour of signals using the additional query params
+func searchPrivateSignalsWithParams(t *testing.T, baseURL string, endpoint testSignalEndpoint, token string, params map[string]string) *http.Response {
+	url := fmt.Sprintf("%s/api/isn/%s/signal-types/%s/v%s/signals/search",
+		baseURL, endpoint.isnSlug, endpoint.signalTypeSlug, endpoint.signalTypeSemVer)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	q := req.URL.Query()
+	now := time.Now()
+	q.Add("start_date", now.Add(-1*time.Hour).Format(time.RFC3339))
+	q.Add("end_date", now.Add(1*time.Hour).Format(time.RFC3339))
+	for key, value := range params {
+		q.Add(key, value)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to search private signals: %v", err)
+	}
+
+	return resp
+}
+
 // withdrawSignal withdraws a signal by local reference
 func withdrawSignal(t *testing.T, baseURL string, endpoint testSignalEndpoint, token, localRef string) *http.Response {
 	url := fmt.Sprintf("%s/api/isn/%s/signal-types/%s/v%s/signals/withdraw",
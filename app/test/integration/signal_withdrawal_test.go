@@ -0,0 +1,265 @@
+//go:build integration
+
+package integration
+
+// tests
+// - signals can be withdrawn in bulk by local ref, signal id and batch ref, and reinstated
+// - requested signals that were not changed are reported
+// - accounts can only change their own signals - ISN admins can change signals owned by other accounts
+// - invalid requests (no selector, more than one selector, missing reason) are rejected
+// - withdrawals and reinstatements are included in the signal changes feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+// changeSignalsStatus calls the bulk-withdraw or bulk-reinstate endpoint
+func changeSignalsStatus(t *testing.T, baseURL string, endpoint testSignalEndpoint, token, action string, request handlers.SignalStatusChangeRequest) *http.Response {
+	t.Helper()
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/api/isn/%s/signal-types/%s/v%s/signals/%s",
+		baseURL, endpoint.isnSlug, endpoint.signalTypeSlug, endpoint.signalTypeSemVer, action)
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		t.Fatalf("Failed to %s signals: %v", action, err)
+	}
+	return resp
+}
+
+func decodeSignalStatusChangeResponse(t *testing.T, resp *http.Response) handlers.SignalStatusChangeResponse {
+	t.Helper()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status: want 200, got %d", resp.StatusCode)
+	}
+	var res handlers.SignalStatusChangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return res
+}
+
+func TestSignalWithdrawal(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	// the ISN admin owns the ISN - the writers can only change their own signals
+	isnAdminAccount := createTestAccount(t, ctx, testEnv.queries, "isnadmin", "user", "isnadmin@withdrawal-test.com")
+	writerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "writer@withdrawal-test.com")
+	otherWriterAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "other-writer@withdrawal-test.com")
+
+	isn := createTestISN(t, ctx, testEnv.queries, "withdrawal-isn", "Withdrawal ISN", isnAdminAccount.ID, "private")
+	signalType := createTestSignalType(t, ctx, testEnv.queries, isn.ID, "withdrawal test signal", "")
+
+	grantPermission(t, ctx, testEnv.queries, isn.ID, writerAccount.ID, "write")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, otherWriterAccount.ID, "write")
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("schemaCache.Load: %v", err)
+	}
+
+	endpoint := testSignalEndpoint{
+		isnSlug:          isn.Slug,
+		signalTypeSlug:   signalType.Slug,
+		signalTypeSemVer: signalType.SemVer,
+	}
+
+	isnAdminToken := testEnv.createAuthToken(t, isnAdminAccount.ID)
+	writerToken := testEnv.createAuthToken(t, writerAccount.ID)
+	otherWriterToken := testEnv.createAuthToken(t, otherWriterAccount.ID)
+
+	submitSignals := func(t *testing.T, token, batchRef string, localRefs ...string) {
+		t.Helper()
+		for _, localRef := range localRefs {
+			payload := createValidSignalPayload(localRef)
+			payload["batch_ref"] = batchRef
+			resp := submitCreateSignalRequest(t, testEnv.baseURL, payload, token, endpoint)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status %d submitting signal, got %d", http.StatusOK, resp.StatusCode)
+			}
+		}
+	}
+
+	getSignal := func(t *testing.T, accountID uuid.UUID, localRef string) database.GetSignalByAccountAndLocalRefRow {
+		t.Helper()
+		signal, err := testEnv.queries.GetSignalByAccountAndLocalRef(ctx, database.GetSignalByAccountAndLocalRefParams{
+			AccountID:      accountID,
+			IsnSlug:        isn.Slug,
+			SignalTypeSlug: signalType.Slug,
+			SemVer:         signalType.SemVer,
+			LocalRef:       localRef,
+		})
+		if err != nil {
+			t.Fatalf("Failed to get signal %s: %v", localRef, err)
+		}
+		return signal
+	}
+
+	submitSignals(t, writerToken, "withdrawal-batch-1", "w-signal-1", "w-signal-2", "w-signal-3")
+	submitSignals(t, writerToken, "withdrawal-batch-2", "w-signal-4", "w-signal-5")
+	submitSignals(t, otherWriterToken, "withdrawal-batch-1", "w-other-signal-1")
+
+	t.Run("invalid_requests", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			request handlers.SignalStatusChangeRequest
+		}{
+			{"no_selector", handlers.SignalStatusChangeRequest{Reason: "test"}},
+			{"more_than_one_selector", handlers.SignalStatusChangeRequest{LocalRefs: []string{"w-signal-1"}, BatchRef: "withdrawal-batch-1", Reason: "test"}},
+			{"missing_reason", handlers.SignalStatusChangeRequest{LocalRefs: []string{"w-signal-1"}, Reason: " "}},
+			{"account_id_with_signal_ids", handlers.SignalStatusChangeRequest{SignalIDs: []uuid.UUID{uuid.New()}, AccountID: &writerAccount.ID, Reason: "test"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := changeSignalsStatus(t, testEnv.baseURL, endpoint, writerToken, "bulk-withdraw", tt.request)
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusBadRequest {
+					t.Errorf("status: want 400, got %d", resp.StatusCode)
+				}
+			})
+		}
+	})
+
+	t.Run("withdraw_and_reinstate_by_local_ref", func(t *testing.T) {
+		res := decodeSignalStatusChangeResponse(t, changeSignalsStatus(t, testEnv.baseURL, endpoint, writerToken, "bulk-withdraw", handlers.SignalStatusChangeRequest{
+			LocalRefs: []string{"w-signal-1", "w-signal-2", "w-unknown-signal"},
+			Reason:    "sent in error",
+		}))
+		if res.Action != "withdrawn" || res.ChangedCount != 2 {
+			t.Fatalf("expected 2 signals to be withdrawn, got %+v", res)
+		}
+		if !slices.Equal(res.NotChanged, []string{"w-unknown-signal"}) {
+			t.Errorf("not_changed: want [w-unknown-signal], got %v", res.NotChanged)
+		}
+		if !getSignal(t, writerAccount.ID, "w-signal-1").IsWithdrawn {
+			t.Error("expected w-signal-1 to be withdrawn")
+		}
+
+		// withdrawing again does not change the signals
+		res = decodeSignalStatusChangeResponse(t, changeSignalsStatus(t, testEnv.baseURL, endpoint, writerToken, "bulk-withdraw", handlers.SignalStatusChangeRequest{
+			LocalRefs: []string{"w-signal-1"},
+			Reason:    "sent in error",
+		}))
+		if res.ChangedCount != 0 || len(res.NotChanged) != 1 {
+			t.Errorf("expected no signals to be changed, got %+v", res)
+		}
+
+		res = decodeSignalStatusChangeResponse(t, changeSignalsStatus(t, testEnv.baseURL, endpoint, writerToken, "bulk-reinstate", handlers.SignalStatusChangeRequest{
+			LocalRefs: []string{"w-signal-1", "w-signal-2"},
+			Reason:    "withdrawn by mistake",
+		}))
+		if res.Action != "reinstated" || res.ChangedCount != 2 {
+			t.Fatalf("expected 2 signals to be reinstated, got %+v", res)
+		}
+		if getSignal(t, writerAccount.ID, "w-signal-1").IsWithdrawn {
+			t.Error("expected w-signal-1 to be reinstated")
+		}
+	})
+
+	t.Run("withdraw_by_batch_ref", func(t *testing.T) {
+		// only the caller's signals in the batch are withdrawn (the other writer used the same batch ref)
+		res := decodeSignalStatusChangeResponse(t, changeSignalsStatus(t, testEnv.baseURL, endpoint, writerToken, "bulk-withdraw", handlers.SignalStatusChangeRequest{
+			BatchRef: "withdrawal-batch-1",
+			Reason:   "bad batch",
+		}))
+		if res.ChangedCount != 3 {
+			t.Fatalf("expected the 3 signals in the batch to be withdrawn, got %+v", res)
+		}
+		if getSignal(t, otherWriterAccount.ID, "w-other-signal-1").IsWithdrawn {
+			t.Error("did not expect the other account's signal to be withdrawn")
+		}
+		if getSignal(t, writerAccount.ID, "w-signal-4").IsWithdrawn {
+			t.Error("did not expect signals in other batches to be withdrawn")
+		}
+
+		res = decodeSignalStatusChangeResponse(t, changeSignalsStatus(t, testEnv.baseURL, endpoint, writerToken, "bulk-reinstate", handlers.SignalStatusChangeRequest{
+			BatchRef: "withdrawal-batch-1",
+			Reason:   "batch was correct",
+		}))
+		if res.ChangedCount != 3 {
+			t.Errorf("expected the 3 signals in the batch to be reinstated, got %+v", res)
+		}
+	})
+
+	t.Run("signals_owned_by_other_accounts", func(t *testing.T) {
+		otherSignal := getSignal(t, otherWriterAccount.ID, "w-other-signal-1")
+
+		// writers can't change signals created by other accounts
+		resp := changeSignalsStatus(t, testEnv.baseURL, endpoint, writerToken, "bulk-withdraw", handlers.SignalStatusChangeRequest{
+			LocalRefs: []string{"w-other-signal-1"},
+			AccountID: &otherWriterAccount.ID,
+			Reason:    "test",
+		})
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("status: want 403, got %d", resp.StatusCode)
+		}
+
+		res := decodeSignalStatusChangeResponse(t, changeSignalsStatus(t, testEnv.baseURL, endpoint, writerToken, "bulk-withdraw", handlers.SignalStatusChangeRequest{
+			SignalIDs: []uuid.UUID{otherSignal.ID},
+			Reason:    "test",
+		}))
+		if res.ChangedCount != 0 || !slices.Equal(res.NotChanged, []string{otherSignal.ID.String()}) {
+			t.Errorf("expected the other account's signal not to be changed, got %+v", res)
+		}
+
+		// ISN admins can change any signal in the ISN
+		res = decodeSignalStatusChangeResponse(t, changeSignalsStatus(t, testEnv.baseURL, endpoint, isnAdminToken, "bulk-withdraw", handlers.SignalStatusChangeRequest{
+			SignalIDs: []uuid.UUID{otherSignal.ID, getSignal(t, writerAccount.ID, "w-signal-5").ID},
+			Reason:    "removed by ISN admin",
+		}))
+		if res.ChangedCount != 2 {
+			t.Fatalf("expected the ISN admin to withdraw 2 signals, got %+v", res)
+		}
+
+		res = decodeSignalStatusChangeResponse(t, changeSignalsStatus(t, testEnv.baseURL, endpoint, isnAdminToken, "bulk-reinstate", handlers.SignalStatusChangeRequest{
+			LocalRefs: []string{"w-other-signal-1"},
+			AccountID: &otherWriterAccount.ID,
+			Reason:    "reinstated by ISN admin",
+		}))
+		if res.ChangedCount != 1 || res.ChangedSignals[0].AccountID != otherWriterAccount.ID {
+			t.Errorf("expected the ISN admin to reinstate the other account's signal, got %+v", res)
+		}
+	})
+
+	t.Run("changes_feed", func(t *testing.T) {
+		page := getSignalChangesPage(t, testEnv.baseURL, endpoint, isnAdminToken, "", "")
+
+		var withdrawn, reinstated int
+		for _, change := range page.Changes {
+			switch change.ChangeType {
+			case "withdrawn":
+				withdrawn++
+			case "reinstated":
+				reinstated++
+			}
+		}
+		// local ref: 2 withdrawn + 2 reinstated, batch: 3 + 3, ISN admin: 2 withdrawn + 1 reinstated
+		if withdrawn != 7 || reinstated != 6 {
+			t.Errorf("expected 7 withdrawals and 6 reinstatements in the changes feed, got %d and %d", withdrawn, reinstated)
+		}
+	})
+}
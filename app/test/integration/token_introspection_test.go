@@ -0,0 +1,362 @@
+//go:build integration
+
+package integration
+
+// tests
+// - service account access tokens are active until the client secret is revoked
+// - revoking one client secret does not deactivate the access tokens issued with another
+// - web user access tokens and refresh tokens are active until the user logs out (access tokens stay active when the session is refreshed)
+// - tokens issued to disabled accounts are inactive
+// - unknown tokens are inactive and inactive responses only include the active field
+// - callers must authenticate with service account client credentials
+// - the authorization server metadata lists the OAuth endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/auth"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	signalsd "github.com/information-sharing-networks/signalsd/app/internal/server/config"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+// createTestClientSecret creates a client secret for the service account and returns the client id and plaintext secret
+func createTestClientSecret(t *testing.T, ctx context.Context, env *testEnv, account database.GetAccountByIDRow) (string, string) {
+	t.Helper()
+
+	clientSecret, err := env.authService.GenerateSecureToken(32)
+	if err != nil {
+		t.Fatalf("Failed to generate client secret: %v", err)
+	}
+	if _, err := env.queries.CreateClientSecret(ctx, database.CreateClientSecretParams{
+		ServiceAccountAccountID: account.ID,
+		HashedSecret:            env.authService.HashToken(clientSecret),
+		ExpiresAt:               time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("Failed to create client secret: %v", err)
+	}
+
+	serviceAccount, err := env.queries.GetServiceAccountByAccountID(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("Failed to get service account details: %v", err)
+	}
+	return serviceAccount.ClientID, clientSecret
+}
+
+// introspectToken calls /oauth/introspect using HTTP Basic Auth
+func introspectToken(t *testing.T, baseURL, clientID, clientSecret, token, tokenTypeHint string) (int, map[string]any) {
+	t.Helper()
+
+	form := url.Values{}
+	form.Set("token", token)
+	if tokenTypeHint != "" {
+		form.Set("token_type_hint", tokenTypeHint)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/oauth/introspect", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(clientID, clientSecret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to introspect token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.StatusCode, body
+}
+
+// getServiceAccountToken uses the client_credentials grant to get an access token
+func getServiceAccountToken(t *testing.T, baseURL, clientID, clientSecret string) string {
+	t.Helper()
+
+	resp := makeOAuthTokenRequest(t, baseURL, "client_credentials", map[string]string{
+		"client_id":     clientID,
+		"client_secret": clientSecret,
+	}, "")
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var tokenResponse auth.AccessTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		t.Fatalf("Failed to decode token response: %v", err)
+	}
+	return tokenResponse.AccessToken
+}
+
+// loginForTokens logs in and returns the access token and refresh token
+func loginForTokens(t *testing.T, baseURL, email, password string) (string, string) {
+	t.Helper()
+
+	resp := makeLoginRequest(t, baseURL, map[string]string{"email": email, "password": password})
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Login failed: %d", resp.StatusCode)
+	}
+
+	var tokenResponse auth.AccessTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		t.Fatalf("Failed to decode login response: %v", err)
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == signalsd.RefreshTokenCookieName {
+			return tokenResponse.AccessToken, cookie.Value
+		}
+	}
+	t.Fatal("No refresh token cookie found in login response")
+	return "", ""
+}
+
+// checkInactive checks the response only reports the token as inactive
+func checkInactive(t *testing.T, status int, body map[string]any) {
+	t.Helper()
+	if status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+	if active, _ := body["active"].(bool); active || len(body) != 1 {
+		t.Errorf("expected only active=false, got %v", body)
+	}
+}
+
+func TestTokenIntrospection(t *testing.T) {
+	ctx := context.Background()
+
+	testEnv := startInProcessServer(t, "")
+
+	// the resource server calling the introspection endpoint
+	resourceServer := createTestAccount(t, ctx, testEnv.queries, "member", "service_account", "resource-server@introspect.test")
+	rsClientID, rsClientSecret := createTestClientSecret(t, ctx, testEnv, resourceServer)
+
+	serviceAccount := createTestAccount(t, ctx, testEnv.queries, "member", "service_account", "client@introspect.test")
+	clientID, clientSecret := createTestClientSecret(t, ctx, testEnv, serviceAccount)
+
+	user := createTestUserWithPassword(t, ctx, testEnv.queries, testEnv.authService, "isnadmin", "user@introspect.test", "password123")
+	isn := createTestISN(t, ctx, testEnv.queries, "introspect-isn", "Introspection ISN", user.ID, "private")
+
+	t.Run("service_account_token", func(t *testing.T) {
+		token := getServiceAccountToken(t, testEnv.baseURL, clientID, clientSecret)
+
+		status, body := introspectToken(t, testEnv.baseURL, rsClientID, rsClientSecret, token, "")
+		if status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+		}
+		if body["active"] != true || body["token_type"] != "Bearer" || body["client_id"] != clientID ||
+			body["account_id"] != serviceAccount.ID.String() || body["account_type"] != "service_account" || body["role"] != "member" {
+			t.Errorf("unexpected response %v", body)
+		}
+		if _, ok := body["exp"].(float64); !ok {
+			t.Errorf("expected exp in response, got %v", body)
+		}
+
+		// revoking the client secret deactivates the access tokens issued with it
+		resp := makeOAuthRevokeRequest(t, testEnv.baseURL, map[string]string{"client_id": clientID, "client_secret": clientSecret}, "")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("revoke: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		status, body = introspectToken(t, testEnv.baseURL, rsClientID, rsClientSecret, token, "")
+		checkInactive(t, status, body)
+	})
+
+	t.Run("revoked_client_secret", func(t *testing.T) {
+		account := createTestAccount(t, ctx, testEnv.queries, "member", "service_account", "two-secrets@introspect.test")
+		clientID, secretA := createTestClientSecret(t, ctx, testEnv, account)
+		_, secretB := createTestClientSecret(t, ctx, testEnv, account)
+
+		tokenA := getServiceAccountToken(t, testEnv.baseURL, clientID, secretA)
+		tokenB := getServiceAccountToken(t, testEnv.baseURL, clientID, secretB)
+
+		if _, err := testEnv.pool.Exec(ctx, "UPDATE client_secrets SET revoked_at = NOW() WHERE hashed_secret = $1", testEnv.authService.HashToken(secretA)); err != nil {
+			t.Fatalf("Failed to revoke client secret: %v", err)
+		}
+
+		status, body := introspectToken(t, testEnv.baseURL, rsClientID, rsClientSecret, tokenA, "")
+		checkInactive(t, status, body)
+
+		status, body = introspectToken(t, testEnv.baseURL, rsClientID, rsClientSecret, tokenB, "")
+		if status != http.StatusOK || body["active"] != true {
+			t.Errorf("expected the token issued with the unrevoked secret to be active, got %d %v", status, body)
+		}
+	})
+
+	t.Run("user_tokens", func(t *testing.T) {
+		accessToken, refreshToken := loginForTokens(t, testEnv.baseURL, "user@introspect.test", "password123")
+
+		status, body := introspectToken(t, testEnv.baseURL, rsClientID, rsClientSecret, accessToken, "")
+		if status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+		}
+		if body["active"] != true || body["username"] != "user@introspect.test" || body["role"] != "isnadmin" || body["account_type"] != "user" {
+			t.Errorf("unexpected response %v", body)
+		}
+		isnPerms, _ := body["isn_perms"].(map[string]any)
+		if _, ok := isnPerms[isn.Slug]; !ok {
+			t.Errorf("expected permissions for %s, got %v", isn.Slug, body["isn_perms"])
+		}
+
+		// refresh tokens are found regardless of the hint
+		for _, hint := range []string{auth.TokenTypeHintRefreshToken, auth.TokenTypeHintAccessToken, ""} {
+			status, body = introspectToken(t, testEnv.baseURL, rsClientID, rsClientSecret, refreshToken, hint)
+			if status != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+			}
+			if body["active"] != true || body["token_type"] != "refresh_token" || body["account_id"] != user.ID.String() {
+				t.Errorf("hint %q: unexpected response %v", hint, body)
+			}
+			if _, ok := body["isn_perms"]; ok {
+				t.Errorf("hint %q: isn_perms should not be returned for refresh tokens", hint)
+			}
+		}
+
+		// access tokens issued from a refreshed session are active, as are the tokens issued earlier in the session
+		resp := makeOAuthTokenRequest(t, testEnv.baseURL, "refresh_token", nil, refreshToken)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("refresh: expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		var tokenResponse auth.AccessTokenResponse
+		if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+			t.Fatalf("Failed to decode token response: %v", err)
+		}
+		var newRefreshToken string
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == signalsd.RefreshTokenCookieName {
+				newRefreshToken = cookie.Value
+			}
+		}
+
+		for _, token := range []string{tokenResponse.AccessToken, accessToken} {
+			status, body = introspectToken(t, testEnv.baseURL, rsClientID, rsClientSecret, token, "")
+			if status != http.StatusOK || body["active"] != true {
+				t.Errorf("expected the session access tokens to be active, got %d %v", status, body)
+			}
+		}
+
+		// the rotated refresh token is no longer active
+		status, body = introspectToken(t, testEnv.baseURL, rsClientID, rsClientSecret, refreshToken, auth.TokenTypeHintRefreshToken)
+		checkInactive(t, status, body)
+
+		// logging out deactivates the refresh token and the access tokens issued with it
+		revokeResp := makeOAuthRevokeRequest(t, testEnv.baseURL, nil, newRefreshToken)
+		revokeResp.Body.Close()
+		if revokeResp.StatusCode != http.StatusOK {
+			t.Fatalf("logout: expected status %d, got %d", http.StatusOK, revokeResp.StatusCode)
+		}
+
+		for _, token := range []string{accessToken, tokenResponse.AccessToken, newRefreshToken} {
+			status, body = introspectToken(t, testEnv.baseURL, rsClientID, rsClientSecret, token, "")
+			checkInactive(t, status, body)
+		}
+	})
+
+	t.Run("disabled_account", func(t *testing.T) {
+		account := createTestAccount(t, ctx, testEnv.queries, "member", "service_account", "disabled@introspect.test")
+		disabledClientID, disabledClientSecret := createTestClientSecret(t, ctx, testEnv, account)
+		token := getServiceAccountToken(t, testEnv.baseURL, disabledClientID, disabledClientSecret)
+
+		disableAccount(t, ctx, testEnv.queries, account.ID)
+
+		status, body := introspectToken(t, testEnv.baseURL, rsClientID, rsClientSecret, token, "")
+		checkInactive(t, status, body)
+	})
+
+	t.Run("unknown_tokens", func(t *testing.T) {
+		for _, token := range []string{"not-a-token", createExpiredAccessToken(t, user.ID, testSecretKey)} {
+			status, body := introspectToken(t, testEnv.baseURL, rsClientID, rsClientSecret, token, "")
+			checkInactive(t, status, body)
+		}
+	})
+
+	t.Run("client_authentication_required", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			clientID       string
+			clientSecret   string
+			expectedStatus int
+		}{
+			{"no_credentials", "", "", http.StatusBadRequest},
+			{"wrong_secret", rsClientID, "wrong-secret", http.StatusUnauthorized},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := introspectToken(t, testEnv.baseURL, tt.clientID, tt.clientSecret, "not-a-token", "")
+				if status != tt.expectedStatus {
+					t.Errorf("expected status %d, got %d", tt.expectedStatus, status)
+				}
+				if _, ok := body["error"]; !ok {
+					t.Errorf("expected an OAuth error response, got %v", body)
+				}
+			})
+		}
+
+		// the token parameter is required
+		status, body := introspectToken(t, testEnv.baseURL, rsClientID, rsClientSecret, "", "")
+		if status != http.StatusBadRequest || body["error"] != "invalid_request" {
+			t.Errorf("expected invalid_request, got %d %v", status, body)
+		}
+	})
+}
+
+func TestAuthorizationServerMetadata(t *testing.T) {
+	testEnv := startInProcessServer(t, "")
+
+	resp, err := http.Get(testEnv.baseURL + "/.well-known/oauth-authorization-server")
+	if err != nil {
+		t.Fatalf("Failed to get metadata: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var metadata handlers.AuthorizationServerMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		t.Fatalf("Failed to decode metadata: %v", err)
+	}
+
+	base := testEnv.cfg.PublicBaseURL
+	if metadata.Issuer != base {
+		t.Errorf("issuer = %q, want %q", metadata.Issuer, base)
+	}
+	endpoints := map[string]string{
+		"token_endpoint":         metadata.TokenEndpoint,
+		"revocation_endpoint":    metadata.RevocationEndpoint,
+		"introspection_endpoint": metadata.IntrospectionEndpoint,
+	}
+	for name, endpoint := range endpoints {
+		if !strings.HasPrefix(endpoint, base+"/oauth/") {
+			t.Errorf("%s = %q, want an endpoint under %s/oauth", name, endpoint, base)
+		}
+	}
+	if fmt.Sprint(metadata.GrantTypesSupported) != "[client_credentials refresh_token]" {
+		t.Errorf("unexpected grant types %v", metadata.GrantTypesSupported)
+	}
+
+	// HS256 tokens can't be verified with published keys
+	if metadata.JwksURI != "" {
+		t.Errorf("expected no jwks_uri when tokens are signed with HS256, got %q", metadata.JwksURI)
+	}
+}
@@ -0,0 +1,338 @@
+//go:build integration
+
+package integration
+
+// tests
+// - signals submitted to the signal router that don't match any routing rule are stored as unroutable signals
+// - site admins can list, inspect, re-route and delete unroutable signals
+// - re-routed signals are stored using the account's current permissions (signals from disabled accounts can't be re-routed)
+// - signals that don't match any rule are routed to the fallback ISN when the routing config has one
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/database"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+)
+
+// unroutableSignalsRequest makes a request to the unroutable signals admin endpoints (path is relative to /api/admin/unroutable-signals)
+func unroutableSignalsRequest(t *testing.T, baseURL, token, method, path string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, baseURL+"/api/admin/unroutable-signals"+path, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	return resp
+}
+
+func TestUnroutableSignals(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	// the signal type is available on isn-a and isn-b, but the routing config only has a rule for isn-a:
+	//   "*value-a*" -> isn-a
+	siteAdminAccount := createTestAccount(t, ctx, testEnv.queries, "siteadmin", "user", "siteadmin@unroutable-test.com")
+	writerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "writer@unroutable-test.com")
+
+	siteAdminToken := testEnv.createAuthToken(t, siteAdminAccount.ID)
+	writerToken := testEnv.createAuthToken(t, writerAccount.ID)
+
+	isnA := createTestISN(t, ctx, testEnv.queries, "unroutable-isn-a", "Unroutable ISN A", siteAdminAccount.ID, "private")
+	isnB := createTestISN(t, ctx, testEnv.queries, "unroutable-isn-b", "Unroutable ISN B", siteAdminAccount.ID, "private")
+
+	signalType := createTestSignalType(t, ctx, testEnv.queries, isnA.ID, "unroutable test signal", "")
+	if err := testEnv.queries.AddSignalTypeToIsn(ctx, database.AddSignalTypeToIsnParams{
+		IsnID:        isnB.ID,
+		SignalTypeID: signalType.ID,
+	}); err != nil {
+		t.Fatalf("Failed to add signal type to isn-b: %v", err)
+	}
+
+	grantPermission(t, ctx, testEnv.queries, isnA.ID, writerAccount.ID, "write")
+	grantPermission(t, ctx, testEnv.queries, isnB.ID, writerAccount.ID, "write")
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("schemaCache.Load: %v", err)
+	}
+
+	setRoutingConfig(t, testEnv, siteAdminToken, signalType,
+		handlers.UpdateSignalRoutingConfigRequest{
+			RoutingField: "test",
+			RoutingRules: []handlers.SignalRoutingRule{
+				{MatchPattern: "*value-a*", Operator: "matches", IsnSlug: isnA.Slug, Sequence: 1},
+			},
+		})
+	if err := testEnv.routerCache.Load(ctx); err != nil {
+		t.Fatalf("routerCache.Load: %v", err)
+	}
+
+	// submitUnroutable submits a signal that does not match any rule and returns the id of the stored unroutable signal
+	submitUnroutable := func(t *testing.T, batchRef, localRef string) uuid.UUID {
+		t.Helper()
+		resp := submitRouteSignalsRequest(t, testEnv.baseURL,
+			routerPayload(batchRef, []map[string]any{routerSignal(localRef, "value-b")}),
+			writerToken, signalType.Slug, signalType.SemVer)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("status: want 422, got %d", resp.StatusCode)
+		}
+		result := decodeRouterResponse(t, resp)
+		if len(result.UnroutableSignals) != 1 || result.UnroutableSignals[0].UnroutableSignalID == nil {
+			t.Fatalf("expected an unroutable signal id in the response, got %+v", result.UnroutableSignals)
+		}
+		return *result.UnroutableSignals[0].UnroutableSignalID
+	}
+
+	listUnroutable := func(t *testing.T, query string) []handlers.UnroutableSignal {
+		t.Helper()
+		resp := unroutableSignalsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodGet, query)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("list status: want 200, got %d", resp.StatusCode)
+		}
+		var res []handlers.UnroutableSignal
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return res
+	}
+
+	var unroutableID uuid.UUID
+
+	t.Run("unmatched_signal_is_stored", func(t *testing.T) {
+		unroutableID = submitUnroutable(t, "unroutable-batch-1", "s-unroutable-1")
+
+		res := listUnroutable(t, "?signal_type_slug="+signalType.Slug)
+		if len(res) != 1 || res[0].ID != unroutableID {
+			t.Fatalf("expected unroutable signal %s in list, got %+v", unroutableID, res)
+		}
+		if res[0].LocalRef != "s-unroutable-1" || res[0].BatchRef != "unroutable-batch-1" || res[0].AccountID != writerAccount.ID {
+			t.Errorf("unexpected unroutable signal %+v", res[0])
+		}
+		if res[0].SignalTypePath != fmt.Sprintf("%s/v%s", signalType.Slug, signalType.SemVer) {
+			t.Errorf("signal_type_path: want %s/v%s, got %s", signalType.Slug, signalType.SemVer, res[0].SignalTypePath)
+		}
+
+		resp := unroutableSignalsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodGet, "/"+unroutableID.String())
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("get status: want 200, got %d", resp.StatusCode)
+		}
+		var details handlers.UnroutableSignalDetails
+		if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		var content map[string]any
+		if err := json.Unmarshal(details.Content, &content); err != nil || content["test"] != "value-b" {
+			t.Errorf("expected the original signal content, got %s", details.Content)
+		}
+	})
+
+	t.Run("access_and_validation", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			token          string
+			method         string
+			path           string
+			expectedStatus int
+		}{
+			{"member_cannot_list", writerToken, http.MethodGet, "", http.StatusForbidden},
+			{"member_cannot_reroute", writerToken, http.MethodPost, "/" + unroutableID.String() + "/reroute", http.StatusForbidden},
+			{"invalid_limit", siteAdminToken, http.MethodGet, "?limit=0", http.StatusBadRequest},
+			{"invalid_id", siteAdminToken, http.MethodGet, "/not-a-uuid", http.StatusBadRequest},
+			{"unknown_id", siteAdminToken, http.MethodGet, "/" + uuid.New().String(), http.StatusNotFound},
+			{"still_unroutable", siteAdminToken, http.MethodPost, "/" + unroutableID.String() + "/reroute", http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := unroutableSignalsRequest(t, testEnv.baseURL, tt.token, tt.method, tt.path)
+				defer resp.Body.Close()
+				if resp.StatusCode != tt.expectedStatus {
+					t.Errorf("status: want %d, got %d", tt.expectedStatus, resp.StatusCode)
+				}
+			})
+		}
+	})
+
+	t.Run("reroute_after_fixing_rules", func(t *testing.T) {
+		// add a rule for isn-b - the reroute endpoint reloads the routing rules so the cache is not refreshed here
+		setRoutingConfig(t, testEnv, siteAdminToken, signalType,
+			handlers.UpdateSignalRoutingConfigRequest{
+				RoutingField: "test",
+				RoutingRules: []handlers.SignalRoutingRule{
+					{MatchPattern: "*value-a*", Operator: "matches", IsnSlug: isnA.Slug, Sequence: 1},
+					{MatchPattern: "*value-b*", Operator: "matches", IsnSlug: isnB.Slug, Sequence: 2},
+				},
+			})
+
+		resp := unroutableSignalsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodPost, "/"+unroutableID.String()+"/reroute")
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("reroute status: want 200, got %d", resp.StatusCode)
+		}
+		result := decodeRouterResponse(t, resp)
+		isnResultB := findIsnResult(result.Results, isnB.Slug)
+		if isnResultB == nil || len(isnResultB.StoredSignals) != 1 || isnResultB.StoredSignals[0].LocalRef != "s-unroutable-1" {
+			t.Fatalf("expected s-unroutable-1 to be stored in isn-b, got %+v", result.Results)
+		}
+
+		// re-routed signals are only listed when include_rerouted=true
+		if res := listUnroutable(t, ""); len(res) != 0 {
+			t.Errorf("expected no unroutable signals, got %+v", res)
+		}
+		res := listUnroutable(t, "?include_rerouted=true")
+		if len(res) != 1 || res[0].ReroutedAt == nil || res[0].ReroutedByAccountID == nil || *res[0].ReroutedByAccountID != siteAdminAccount.ID {
+			t.Errorf("expected the signal to be marked as re-routed by the site admin, got %+v", res)
+		}
+
+		// the signal can't be re-routed twice
+		againResp := unroutableSignalsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodPost, "/"+unroutableID.String()+"/reroute")
+		defer againResp.Body.Close()
+		if againResp.StatusCode != http.StatusConflict {
+			t.Errorf("second reroute status: want 409, got %d", againResp.StatusCode)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		setRoutingConfig(t, testEnv, siteAdminToken, signalType,
+			handlers.UpdateSignalRoutingConfigRequest{
+				RoutingField: "test",
+				RoutingRules: []handlers.SignalRoutingRule{
+					{MatchPattern: "*value-a*", Operator: "matches", IsnSlug: isnA.Slug, Sequence: 1},
+				},
+			})
+		if err := testEnv.routerCache.Load(ctx); err != nil {
+			t.Fatalf("routerCache.Load: %v", err)
+		}
+
+		id := submitUnroutable(t, "unroutable-batch-2", "s-unroutable-2")
+
+		resp := unroutableSignalsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodDelete, "/"+id.String())
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("delete status: want 204, got %d", resp.StatusCode)
+		}
+
+		resp = unroutableSignalsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodDelete, "/"+id.String())
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("second delete status: want 404, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("fallback_isn", func(t *testing.T) {
+		setRoutingConfig(t, testEnv, siteAdminToken, signalType,
+			handlers.UpdateSignalRoutingConfigRequest{
+				RoutingField: "test",
+				RoutingRules: []handlers.SignalRoutingRule{
+					{MatchPattern: "*value-a*", Operator: "matches", IsnSlug: isnA.Slug, Sequence: 1},
+				},
+				FallbackIsnSlug: isnB.Slug,
+			})
+		if err := testEnv.routerCache.Load(ctx); err != nil {
+			t.Fatalf("routerCache.Load: %v", err)
+		}
+
+		resp := submitRouteSignalsRequest(t, testEnv.baseURL,
+			routerPayload("unroutable-batch-3", []map[string]any{
+				routerSignal("s-fallback-a", "value-a"),
+				routerSignal("s-fallback-other", "no-rule-will-match-this"),
+			}),
+			writerToken, signalType.Slug, signalType.SemVer)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status: want 200, got %d", resp.StatusCode)
+		}
+		result := decodeRouterResponse(t, resp)
+		isnResultA := findIsnResult(result.Results, isnA.Slug)
+		if isnResultA == nil || len(isnResultA.StoredSignals) != 1 || isnResultA.StoredSignals[0].LocalRef != "s-fallback-a" {
+			t.Errorf("expected s-fallback-a to be stored in isn-a, got %+v", isnResultA)
+		}
+		isnResultB := findIsnResult(result.Results, isnB.Slug)
+		if isnResultB == nil || len(isnResultB.StoredSignals) != 1 || isnResultB.StoredSignals[0].LocalRef != "s-fallback-other" {
+			t.Errorf("expected s-fallback-other to be stored in the fallback isn, got %+v", isnResultB)
+		}
+
+		// the fallback is returned with the routing config
+		configResp := getSignalRoutingConfig(t, testEnv.baseURL, siteAdminToken, signalType.Slug, signalType.SemVer)
+		defer configResp.Body.Close()
+		var config handlers.SignalRoutingConfigResponse
+		if err := json.NewDecoder(configResp.Body).Decode(&config); err != nil {
+			t.Fatalf("Failed to decode routing config: %v", err)
+		}
+		if config.FallbackIsnSlug != isnB.Slug {
+			t.Errorf("fallback_isn_slug: want %s, got %q", isnB.Slug, config.FallbackIsnSlug)
+		}
+	})
+	t.Run("reroute_uses_current_permissions", func(t *testing.T) {
+		setRoutingConfig(t, testEnv, siteAdminToken, signalType,
+			handlers.UpdateSignalRoutingConfigRequest{
+				RoutingField: "test",
+				RoutingRules: []handlers.SignalRoutingRule{
+					{MatchPattern: "*value-a*", Operator: "matches", IsnSlug: isnA.Slug, Sequence: 1},
+				},
+			})
+		if err := testEnv.routerCache.Load(ctx); err != nil {
+			t.Fatalf("routerCache.Load: %v", err)
+		}
+		id := submitUnroutable(t, "unroutable-batch-4", "s-unroutable-4")
+
+		// the writer's write permission on isn-b is revoked after the signal was submitted
+		grantPermission(t, ctx, testEnv.queries, isnB.ID, writerAccount.ID, "read")
+		setRoutingConfig(t, testEnv, siteAdminToken, signalType,
+			handlers.UpdateSignalRoutingConfigRequest{
+				RoutingField: "test",
+				RoutingRules: []handlers.SignalRoutingRule{
+					{MatchPattern: "*value-b*", Operator: "matches", IsnSlug: isnB.Slug, Sequence: 1},
+				},
+			})
+
+		resp := unroutableSignalsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodPost, "/"+id.String()+"/reroute")
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatalf("reroute status: want 422, got %d", resp.StatusCode)
+		}
+		result := decodeRouterResponse(t, resp)
+		isnResultB := findIsnResult(result.Results, isnB.Slug)
+		if isnResultB == nil || len(isnResultB.FailedSignals) != 1 || isnResultB.FailedSignals[0].ErrorCode != string(apperrors.ErrCodeForbidden) {
+			t.Errorf("expected the signal to be rejected for isn-b, got %+v", result.Results)
+		}
+
+		// the signal was not stored so it is not marked as re-routed
+		found := false
+		for _, unroutable := range listUnroutable(t, "") {
+			if unroutable.ID == id {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected unroutable signal %s to still be listed", id)
+		}
+
+		// signals submitted by disabled accounts can't be re-routed
+		grantPermission(t, ctx, testEnv.queries, isnB.ID, writerAccount.ID, "write")
+		disableAccount(t, ctx, testEnv.queries, writerAccount.ID)
+
+		disabledResp := unroutableSignalsRequest(t, testEnv.baseURL, siteAdminToken, http.MethodPost, "/"+id.String()+"/reroute")
+		defer disabledResp.Body.Close()
+		if disabledResp.StatusCode != http.StatusBadRequest {
+			t.Errorf("reroute status for a disabled account: want 400, got %d", disabledResp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,360 @@
+//go:build integration
+
+package integration
+
+// tests
+// - webhook subscription management (create, list, delete) and access controls
+// - delivery of new signal versions to subscribers, including the signature header
+// - failed deliveries are recorded in the delivery log and scheduled for retry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/information-sharing-networks/signalsd/app/internal/apperrors"
+	"github.com/information-sharing-networks/signalsd/app/internal/server/handlers"
+	"github.com/information-sharing-networks/signalsd/app/internal/webhooks"
+)
+
+// webhookReceiver records the requests sent by the webhook dispatcher
+type webhookReceiver struct {
+	mu         sync.Mutex
+	statusCode int
+	requests   []receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T, statusCode int) (*webhookReceiver, *httptest.Server) {
+	t.Helper()
+	receiver := &webhookReceiver{statusCode: statusCode}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		receiver.requests = append(receiver.requests, receivedWebhook{header: r.Header.Clone(), body: body})
+		receiver.mu.Unlock()
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+	return receiver, server
+}
+
+// waitForRequests waits until the receiver has received at least n requests
+func (wr *webhookReceiver) waitForRequests(t *testing.T, n int, timeout time.Duration) []receivedWebhook {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		wr.mu.Lock()
+		if len(wr.requests) >= n {
+			requests := append([]receivedWebhook(nil), wr.requests...)
+			wr.mu.Unlock()
+			return requests
+		}
+		wr.mu.Unlock()
+		time.Sleep(200 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d webhook requests", n)
+	return nil
+}
+
+func webhooksURL(baseURL string, endpoint testSignalEndpoint) string {
+	return fmt.Sprintf("%s/api/isn/%s/signal-types/%s/v%s/webhooks",
+		baseURL, endpoint.isnSlug, endpoint.signalTypeSlug, endpoint.signalTypeSemVer)
+}
+
+func makeWebhookRequest(t *testing.T, method, url, token string, body any) *http.Response {
+	t.Helper()
+
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Failed to marshal request: %v", err)
+		}
+		reqBody = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	return resp
+}
+
+func createWebhook(t *testing.T, baseURL string, endpoint testSignalEndpoint, token, targetURL string) handlers.CreateWebhookResponse {
+	t.Helper()
+
+	resp := makeWebhookRequest(t, http.MethodPost, webhooksURL(baseURL, endpoint), token, handlers.CreateWebhookRequest{TargetURL: targetURL})
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected status %d creating webhook, got %d: %s", http.StatusCreated, resp.StatusCode, body)
+	}
+
+	var res handlers.CreateWebhookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return res
+}
+
+func getWebhookDeliveries(t *testing.T, baseURL string, endpoint testSignalEndpoint, token, webhookID string) []handlers.WebhookDelivery {
+	t.Helper()
+
+	resp := makeWebhookRequest(t, http.MethodGet, fmt.Sprintf("%s/%s/deliveries", webhooksURL(baseURL, endpoint), webhookID), token, nil)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d getting deliveries, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var res []handlers.WebhookDelivery
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return res
+}
+
+func TestWebhooks(t *testing.T) {
+	ctx := context.Background()
+	testEnv := startInProcessServer(t, "")
+
+	siteAdminAccount := createTestAccount(t, ctx, testEnv.queries, "siteadmin", "user", "siteadmin@webhook-test.com")
+	readerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "reader@webhook-test.com")
+	otherReaderAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "other-reader@webhook-test.com")
+	writerAccount := createTestAccount(t, ctx, testEnv.queries, "member", "user", "writer@webhook-test.com")
+
+	isn := createTestISN(t, ctx, testEnv.queries, "webhook-isn", "Webhook ISN", siteAdminAccount.ID, "private")
+	signalType := createTestSignalType(t, ctx, testEnv.queries, isn.ID, "webhook test signal", "")
+
+	grantPermission(t, ctx, testEnv.queries, isn.ID, readerAccount.ID, "read")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, otherReaderAccount.ID, "read")
+	grantPermission(t, ctx, testEnv.queries, isn.ID, writerAccount.ID, "write")
+
+	if err := testEnv.schemaCache.Load(ctx); err != nil {
+		t.Fatalf("schemaCache.Load: %v", err)
+	}
+
+	endpoint := testSignalEndpoint{
+		isnSlug:          isn.Slug,
+		signalTypeSlug:   signalType.Slug,
+		signalTypeSemVer: signalType.SemVer,
+	}
+
+	readerToken := testEnv.createAuthToken(t, readerAccount.ID)
+	otherReaderToken := testEnv.createAuthToken(t, otherReaderAccount.ID)
+	writerToken := testEnv.createAuthToken(t, writerAccount.ID)
+
+	t.Run("create_webhook_validation", func(t *testing.T) {
+		tests := []struct {
+			name              string
+			token             string
+			targetURL         string
+			expectedStatus    int
+			expectedErrorCode string
+		}{
+			{
+				name:              "write_only_account",
+				token:             writerToken,
+				targetURL:         "https://example.com/webhook",
+				expectedStatus:    http.StatusForbidden,
+				expectedErrorCode: apperrors.ErrCodeForbidden.String(),
+			},
+			{
+				name:              "missing_target_url",
+				token:             readerToken,
+				targetURL:         "",
+				expectedStatus:    http.StatusBadRequest,
+				expectedErrorCode: apperrors.ErrCodeMalformedBody.String(),
+			},
+			{
+				name:              "relative_target_url",
+				token:             readerToken,
+				targetURL:         "/webhook",
+				expectedStatus:    http.StatusBadRequest,
+				expectedErrorCode: apperrors.ErrCodeMalformedBody.String(),
+			},
+			{
+				name:              "unsupported_scheme",
+				token:             readerToken,
+				targetURL:         "ftp://example.com/webhook",
+				expectedStatus:    http.StatusBadRequest,
+				expectedErrorCode: apperrors.ErrCodeMalformedBody.String(),
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := makeWebhookRequest(t, http.MethodPost, webhooksURL(testEnv.baseURL, endpoint), tt.token, handlers.CreateWebhookRequest{TargetURL: tt.targetURL})
+				defer resp.Body.Close()
+
+				if resp.StatusCode != tt.expectedStatus {
+					t.Fatalf("expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+				}
+
+				var errorResponse map[string]any
+				if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
+					t.Fatalf("Failed to decode error response: %v", err)
+				}
+				if errorResponse["error_code"] != tt.expectedErrorCode {
+					t.Errorf("expected error code %s, got %v", tt.expectedErrorCode, errorResponse["error_code"])
+				}
+			})
+		}
+	})
+
+	t.Run("signals_are_delivered_to_subscribers", func(t *testing.T) {
+		receiver, receiverServer := newWebhookReceiver(t, http.StatusOK)
+
+		webhook := createWebhook(t, testEnv.baseURL, endpoint, readerToken, receiverServer.URL)
+		if webhook.SigningSecret == "" {
+			t.Fatal("expected signing secret in create webhook response")
+		}
+
+		// duplicate subscriptions are rejected
+		resp := makeWebhookRequest(t, http.MethodPost, webhooksURL(testEnv.baseURL, endpoint), readerToken, handlers.CreateWebhookRequest{TargetURL: receiverServer.URL})
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("expected status %d for duplicate webhook, got %d", http.StatusConflict, resp.StatusCode)
+		}
+
+		resp = submitCreateSignalRequest(t, testEnv.baseURL, createValidSignalPayload("webhook-signal-1"), writerToken, endpoint)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d submitting signal, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		requests := receiver.waitForRequests(t, 1, 15*time.Second)
+		received := requests[0]
+
+		// verify the signature
+		signature := received.header.Get(webhooks.SignatureHeader)
+		var timestamp int64
+		if _, err := fmt.Sscanf(strings.SplitN(signature, ",", 2)[0], "t=%d", &timestamp); err != nil {
+			t.Fatalf("could not parse signature header %q: %v", signature, err)
+		}
+		if expected := webhooks.Signature(webhook.SigningSecret, timestamp, received.body); signature != expected {
+			t.Errorf("signature mismatch: got %q, want %q", signature, expected)
+		}
+
+		var event webhooks.Event
+		if err := json.Unmarshal(received.body, &event); err != nil {
+			t.Fatalf("could not decode webhook body: %v", err)
+		}
+		if event.EventType != webhooks.EventTypeSignalVersionCreated {
+			t.Errorf("expected event type %s, got %s", webhooks.EventTypeSignalVersionCreated, event.EventType)
+		}
+		if event.IsnSlug != isn.Slug {
+			t.Errorf("expected isn slug %s, got %s", isn.Slug, event.IsnSlug)
+		}
+		if event.Signal.LocalRef != "webhook-signal-1" {
+			t.Errorf("expected local_ref webhook-signal-1, got %s", event.Signal.LocalRef)
+		}
+		if event.Signal.AccountID != writerAccount.ID {
+			t.Errorf("expected account_id %s, got %s", writerAccount.ID, event.Signal.AccountID)
+		}
+		if received.header.Get(webhooks.DeliveryIDHeader) != event.DeliveryID.String() {
+			t.Errorf("expected delivery id header %s, got %s", event.DeliveryID, received.header.Get(webhooks.DeliveryIDHeader))
+		}
+
+		// check the delivery log
+		deliveries := getWebhookDeliveries(t, testEnv.baseURL, endpoint, readerToken, webhook.ID.String())
+		if len(deliveries) != 1 {
+			t.Fatalf("expected 1 delivery, got %d", len(deliveries))
+		}
+		if deliveries[0].Status != "delivered" {
+			t.Errorf("expected delivery status delivered, got %s", deliveries[0].Status)
+		}
+		if deliveries[0].SignalVersionID != event.Signal.SignalVersionID {
+			t.Errorf("expected signal version %s, got %s", event.Signal.SignalVersionID, deliveries[0].SignalVersionID)
+		}
+
+		// other accounts can't see the webhook
+		resp = makeWebhookRequest(t, http.MethodGet, fmt.Sprintf("%s/%s/deliveries", webhooksURL(testEnv.baseURL, endpoint), webhook.ID), otherReaderToken, nil)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected status %d when reading another account's webhook, got %d", http.StatusNotFound, resp.StatusCode)
+		}
+
+		// delete the webhook
+		resp = makeWebhookRequest(t, http.MethodDelete, fmt.Sprintf("%s/%s", webhooksURL(testEnv.baseURL, endpoint), webhook.ID), readerToken, nil)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected status %d deleting webhook, got %d", http.StatusNoContent, resp.StatusCode)
+		}
+
+		resp = makeWebhookRequest(t, http.MethodGet, webhooksURL(testEnv.baseURL, endpoint), readerToken, nil)
+		defer resp.Body.Close()
+		var webhookList []handlers.Webhook
+		if err := json.NewDecoder(resp.Body).Decode(&webhookList); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(webhookList) != 0 {
+			t.Errorf("expected no webhooks after delete, got %d", len(webhookList))
+		}
+	})
+
+	t.Run("failed_deliveries_are_retried", func(t *testing.T) {
+		receiver, receiverServer := newWebhookReceiver(t, http.StatusServiceUnavailable)
+
+		webhook := createWebhook(t, testEnv.baseURL, endpoint, otherReaderToken, receiverServer.URL)
+
+		resp := submitCreateSignalRequest(t, testEnv.baseURL, createValidSignalPayload("webhook-signal-2"), writerToken, endpoint)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d submitting signal, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		receiver.waitForRequests(t, 1, 15*time.Second)
+
+		// allow the dispatcher to record the outcome
+		var deliveries []handlers.WebhookDelivery
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			deliveries = getWebhookDeliveries(t, testEnv.baseURL, endpoint, otherReaderToken, webhook.ID.String())
+			if len(deliveries) == 1 && deliveries[0].AttemptCount == 1 {
+				break
+			}
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		if len(deliveries) != 1 {
+			t.Fatalf("expected 1 delivery, got %d", len(deliveries))
+		}
+		delivery := deliveries[0]
+		if delivery.Status != "pending" {
+			t.Errorf("expected delivery status pending, got %s", delivery.Status)
+		}
+		if delivery.AttemptCount != 1 {
+			t.Errorf("expected attempt count 1, got %d", delivery.AttemptCount)
+		}
+		if delivery.LastResponseStatus == nil || *delivery.LastResponseStatus != http.StatusServiceUnavailable {
+			t.Errorf("expected last response status %d, got %v", http.StatusServiceUnavailable, delivery.LastResponseStatus)
+		}
+		if delivery.NextAttemptAt == nil || !delivery.NextAttemptAt.After(time.Now()) {
+			t.Errorf("expected next attempt to be scheduled in the future, got %v", delivery.NextAttemptAt)
+		}
+	})
+}